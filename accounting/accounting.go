@@ -185,6 +185,9 @@ const (
 	AUDIT_ACTIONS
 	AUDIT_ACTIONS_FAILED
 
+	RESULT_CACHE_HITS
+	RESULT_CACHE_MISSES
+
 	// unknown is always the last and does not have a corresponding name or metric
 	UNKNOWN
 )
@@ -231,6 +234,9 @@ const (
 	_AUDIT_ACTIONS           = "audit_actions"
 	_AUDIT_ACTIONS_FAILED    = "audit_actions_failed"
 
+	_RESULT_CACHE_HITS   = "result_cache_hits"
+	_RESULT_CACHE_MISSES = "result_cache_misses"
+
 	REQUEST_RATE  = "request_rate"
 	REQUEST_TIMER = "request_timer"
 )
@@ -276,6 +282,9 @@ var metricNames = []string{
 	_AUDIT_REQUESTS_FILTERED,
 	_AUDIT_ACTIONS,
 	_AUDIT_ACTIONS_FAILED,
+
+	_RESULT_CACHE_HITS,
+	_RESULT_CACHE_MISSES,
 }
 
 const (
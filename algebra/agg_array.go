@@ -11,16 +11,16 @@ package algebra
 
 import (
 	"fmt"
-	"sort"
 
 	"github.com/couchbase/query/expression"
 	"github.com/couchbase/query/value"
 )
 
 /*
-This represents the Aggregate function ARRAY_AGG(expr). It returns an
-array of the non-MISSING values in the group, including NULLs. Type
-ArrayAgg is a struct that inherits from AggregateBase.
+This represents the Aggregate function ARRAY_AGG(expr [ORDER BY ...]).
+It returns an array of the non-MISSING values in the group, including
+NULLs, in the given order or else in collation order. Type ArrayAgg is
+a struct that inherits from AggregateBase.
 */
 type ArrayAgg struct {
 	AggregateBase
@@ -81,6 +81,7 @@ func (this *ArrayAgg) Copy() expression.Expression {
 			this.Flags(), expression.Copy(this.Filter()), CopyWindowTerm(this.WindowTerm())),
 	}
 
+	rv.SetOrderBy(CopyOrder(this.OrderBy()))
 	rv.BaseCopy(this)
 	rv.SetExpr(rv)
 	return rv
@@ -107,20 +108,25 @@ func (this *ArrayAgg) CumulateInitial(item, cumulative value.Value, context Cont
 		return cumulative, e
 	}
 
-	item, e := this.Operands()[0].Evaluate(item, context)
+	val, e := this.Operands()[0].Evaluate(item, context)
 	if e != nil {
 		return nil, e
 	}
 
-	if item.Type() <= value.MISSING || item.Type() == value.BINARY {
+	if val.Type() <= value.MISSING || val.Type() == value.BINARY {
 		return cumulative, nil
 	}
 
 	if this.Distinct() {
-		return setAdd(item, cumulative, false), nil
-	} else {
-		return this.cumulatePart(value.NewValue([]interface{}{item}), cumulative, context)
+		return setAdd(val, cumulative, false), nil
+	}
+
+	val, e = this.orderedValue(val, item, context)
+	if e != nil {
+		return nil, e
 	}
+
+	return this.cumulatePart(value.NewValue([]interface{}{val}), cumulative, context)
 }
 
 /*
@@ -135,7 +141,8 @@ func (this *ArrayAgg) CumulateIntermediate(part, cumulative value.Value, context
 }
 
 /*
-Compute the Final result after sorting(post processing).
+Compute the Final result after sorting(post processing), on the
+ORDER BY terms if any.
 */
 func (this *ArrayAgg) ComputeFinal(cumulative value.Value, context Context) (c value.Value, e error) {
 	if cumulative == value.NULL_VALUE {
@@ -149,14 +156,16 @@ func (this *ArrayAgg) ComputeFinal(cumulative value.Value, context Context) (c v
 			return value.NULL_VALUE, nil
 		}
 
-		actuals := set.Actuals()
-		c = value.NewValue(actuals)
-	} else {
-		c = cumulative
+		return value.NewValue(this.sortOrderedValues(set.Actuals())), nil
 	}
 
-	sort.Sort(value.NewSorter(c))
-	return c, nil
+	actual := cumulative.Actual()
+	switch actual := actual.(type) {
+	case []interface{}:
+		return value.NewValue(this.sortOrderedValues(actual)), nil
+	default:
+		return cumulative, nil
+	}
 }
 
 /*
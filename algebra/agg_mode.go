@@ -0,0 +1,154 @@
+//  Copyright (c) 2020 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package algebra
+
+import (
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/value"
+)
+
+/*
+This represents the Aggregate function MODE(expr). It returns the
+most frequent value in the group, the lowest of them in collation
+order if several are equally frequent. Type Mode is a struct that
+inherits from AggregateBase.
+*/
+type Mode struct {
+	AggregateBase
+}
+
+/*
+The function NewMode calls NewAggregateBase to
+create an aggregate function named mode with
+one expression as input.
+*/
+func NewMode(operands expression.Expressions, flags uint32, filter expression.Expression, wTerm *WindowTerm) Aggregate {
+	rv := &Mode{
+		*NewAggregateBase("mode", operands, flags, filter, wTerm),
+	}
+
+	rv.SetExpr(rv)
+	return rv
+}
+
+/*
+It calls the VisitFunction method by passing in the receiver to
+and returns the interface. It is a visitor pattern.
+*/
+func (this *Mode) Accept(visitor expression.Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+/*
+It returns a value of type JSON.
+*/
+func (this *Mode) Type() value.Type { return value.JSON }
+
+/*
+Calls the evaluate method for aggregate functions and passes in the
+receiver, current item and current context.
+*/
+func (this *Mode) Evaluate(item value.Value, context expression.Context) (result value.Value, e error) {
+	return this.evaluate(this, item, context)
+}
+
+/*
+The constructor returns a NewMode with the input operand
+cast to a Function as the FunctionConstructor.
+*/
+func (this *Mode) Constructor() expression.FunctionConstructor {
+	return func(operands ...expression.Expression) expression.Function {
+		return NewMode(operands, uint32(0), nil, nil)
+	}
+}
+
+/*
+Copy of the aggregate function
+*/
+
+func (this *Mode) Copy() expression.Expression {
+	rv := &Mode{
+		*NewAggregateBase(this.Name(), expression.CopyExpressions(this.Operands()),
+			this.Flags(), expression.Copy(this.Filter()), CopyWindowTerm(this.WindowTerm())),
+	}
+
+	rv.BaseCopy(this)
+	rv.SetExpr(rv)
+	return rv
+}
+
+/*
+If no input to the Mode function, then the default value
+returned is a null.
+*/
+func (this *Mode) Default(item value.Value, context Context) (value.Value, error) {
+	return value.NULL_VALUE, nil
+}
+
+/*
+Aggregates input data by evaluating operands. Collect all the
+values other than NULL, MISSING and BINARY as the intermediate
+aggregate value.
+*/
+func (this *Mode) CumulateInitial(item, cumulative value.Value, context Context) (value.Value, error) {
+	// apply filter if any
+	if ok, e := this.evaluateFilter(item, context); e != nil || !ok {
+		return cumulative, e
+	}
+
+	item, e := this.Operands()[0].Evaluate(item, context)
+	if e != nil {
+		return nil, e
+	}
+
+	if item.Type() <= value.NULL || item.Type() == value.BINARY {
+		return cumulative, nil
+	}
+
+	return listAdd(item, cumulative), nil
+}
+
+/*
+Aggregates intermediate results and return them.
+*/
+func (this *Mode) CumulateIntermediate(part, cumulative value.Value, context Context) (value.Value, error) {
+	return cumulateValueLists(part, cumulative)
+}
+
+/*
+Compute the Final. Return NULL if no values exist. Otherwise sort
+the values, so that equal values are adjacent, and return the value
+with the longest run.
+*/
+func (this *Mode) ComputeFinal(cumulative value.Value, context Context) (value.Value, error) {
+	list, e := getList(cumulative)
+	if e != nil || list.Len() == 0 {
+		return value.NULL_VALUE, nil
+	}
+
+	sorted := sortValues(list.Values(), false)
+	mode := value.NewValue(sorted[0])
+	modeCount := 0
+
+	for i := 0; i < len(sorted); {
+		val := value.NewValue(sorted[i])
+		j := i + 1
+		for j < len(sorted) && val.Collate(value.NewValue(sorted[j])) == 0 {
+			j++
+		}
+
+		if j-i > modeCount {
+			mode, modeCount = val, j-i
+		}
+		i = j
+	}
+
+	return mode, nil
+}
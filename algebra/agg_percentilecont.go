@@ -0,0 +1,166 @@
+//  Copyright (c) 2020 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package algebra
+
+import (
+	"math"
+
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/value"
+)
+
+/*
+This represents the ordered-set Aggregate function
+PERCENTILE_CONT(fraction) WITHIN GROUP (ORDER BY expr [ASC|DESC]).
+It returns the value at the given fraction of the ordered number
+values in the group, interpolating linearly between the two nearest
+values. The operands are expr and fraction.
+*/
+type PercentileCont struct {
+	AggregateBase
+}
+
+/*
+The function NewPercentileCont calls NewAggregateBase to
+create an aggregate function named percentile_cont with
+the ordered expression and the fraction as input.
+*/
+func NewPercentileCont(operands expression.Expressions, flags uint32, filter expression.Expression, wTerm *WindowTerm) Aggregate {
+	rv := &PercentileCont{
+		*NewAggregateBase("percentile_cont", operands, flags, filter, wTerm),
+	}
+
+	rv.SetExpr(rv)
+	return rv
+}
+
+/*
+It calls the VisitFunction method by passing in the receiver to
+and returns the interface. It is a visitor pattern.
+*/
+func (this *PercentileCont) Accept(visitor expression.Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+/*
+It returns a value of type NUMBER.
+*/
+func (this *PercentileCont) Type() value.Type { return value.NUMBER }
+
+/*
+Calls the evaluate method for aggregate functions and passes in the
+receiver, current item and current context.
+*/
+func (this *PercentileCont) Evaluate(item value.Value, context expression.Context) (result value.Value, e error) {
+	return this.evaluate(this, item, context)
+}
+
+/*
+Minimum input arguments required is 2.
+*/
+func (this *PercentileCont) MinArgs() int { return 2 }
+
+/*
+Maximum number of input arguments allowed is 2.
+*/
+func (this *PercentileCont) MaxArgs() int { return 2 }
+
+/*
+The constructor returns a NewPercentileCont with the input operand
+cast to a Function as the FunctionConstructor.
+*/
+func (this *PercentileCont) Constructor() expression.FunctionConstructor {
+	return func(operands ...expression.Expression) expression.Function {
+		return NewPercentileCont(operands, uint32(0), nil, nil)
+	}
+}
+
+/*
+Copy of the aggregate function
+*/
+
+func (this *PercentileCont) Copy() expression.Expression {
+	rv := &PercentileCont{
+		*NewAggregateBase(this.Name(), expression.CopyExpressions(this.Operands()),
+			this.Flags(), expression.Copy(this.Filter()), CopyWindowTerm(this.WindowTerm())),
+	}
+
+	rv.BaseCopy(this)
+	rv.SetExpr(rv)
+	return rv
+}
+
+/*
+If no input to the PercentileCont function, then the default value
+returned is a null.
+*/
+func (this *PercentileCont) Default(item value.Value, context Context) (value.Value, error) {
+	return value.NULL_VALUE, nil
+}
+
+/*
+Aggregates input data by evaluating operands. Collect all the
+values of type NUMBER as the intermediate aggregate value.
+*/
+func (this *PercentileCont) CumulateInitial(item, cumulative value.Value, context Context) (value.Value, error) {
+	// apply filter if any
+	if ok, e := this.evaluateFilter(item, context); e != nil || !ok {
+		return cumulative, e
+	}
+
+	item, e := this.Operands()[0].Evaluate(item, context)
+	if e != nil {
+		return nil, e
+	}
+
+	if item.Type() != value.NUMBER {
+		return cumulative, nil
+	}
+
+	return listAdd(item, cumulative), nil
+}
+
+/*
+Aggregates intermediate results and return them.
+*/
+func (this *PercentileCont) CumulateIntermediate(part, cumulative value.Value, context Context) (value.Value, error) {
+	return cumulateValueLists(part, cumulative)
+}
+
+/*
+Compute the Final. Return NULL if no values of type NUMBER exist.
+Otherwise sort the values, and interpolate between the values around
+the position fraction * (count - 1).
+*/
+func (this *PercentileCont) ComputeFinal(cumulative value.Value, context Context) (value.Value, error) {
+	list, e := getList(cumulative)
+	if e != nil || list.Len() == 0 {
+		return value.NULL_VALUE, nil
+	}
+
+	fraction, e := this.fraction(context)
+	if e != nil {
+		return nil, e
+	}
+
+	sorted := sortValues(list.Values(), this.HasFlags(AGGREGATE_DESCENDING))
+	pos := fraction * float64(len(sorted)-1)
+	lower := math.Floor(pos)
+	upper := math.Ceil(pos)
+
+	lowerVal := value.NewValue(sorted[int(lower)])
+	if lower == upper {
+		return lowerVal, nil
+	}
+
+	lowerNum := lowerVal.(value.NumberValue).Float64()
+	upperNum := value.NewValue(sorted[int(upper)]).(value.NumberValue).Float64()
+	return value.NewValue(lowerNum + (pos-lower)*(upperNum-lowerNum)), nil
+}
@@ -45,6 +45,7 @@ const (
 	AGGREGATE_IGNORENULLS
 	AGGREGATE_FROMFIRST
 	AGGREGATE_FROMLAST
+	AGGREGATE_DESCENDING
 )
 
 /*
@@ -67,6 +68,8 @@ const (
 	AGGREGATE_WINDOW_FROMLAST
 	AGGREGATE_WINDOW_2ND_POSINT
 	AGGREGATE_WINDOW_2ND_DYNAMIC
+	AGGREGATE_WITHIN_GROUP
+	AGGREGATE_ALLOWS_ORDER_BY
 )
 
 /*
@@ -80,6 +83,9 @@ const (
 	AGGREGATE_ALLOWS_FL              = AGGREGATE_ALLOWS_WINDOW | AGGREGATE_ALLOWS_WINDOW_FRAME | AGGREGATE_WINDOW_RESPECTNULLS | AGGREGATE_WINDOW_IGNORENULLS
 	AGGREGATE_ALLOWS_NTH             = AGGREGATE_ALLOWS_FL | AGGREGATE_WINDOW_FROMFIRST | AGGREGATE_WINDOW_FROMLAST | AGGREGATE_WINDOW_2ND_POSINT | AGGREGATE_WINDOW_2ND_DYNAMIC
 	AGGREGATE_ALLOWS_LAGLEAD         = AGGREGATE_ALLOWS_WINDOW | AGGREGATE_WINDOW_ORDER | AGGREGATE_WINDOW_RESPECTNULLS | AGGREGATE_WINDOW_IGNORENULLS | AGGREGATE_WINDOW_2ND_POSINT
+	AGGREGATE_ALLOWS_STATISTIC       = AGGREGATE_ALLOWS_REGULAR | AGGREGATE_ALLOWS_WINDOW | AGGREGATE_ALLOWS_WINDOW_FRAME | AGGREGATE_ALLOWS_FILTER
+	AGGREGATE_ALLOWS_ORDERED_SET     = AGGREGATE_ALLOWS_STATISTIC | AGGREGATE_WITHIN_GROUP
+	AGGREGATE_ALLOWS_ALL_ORDER_BY    = AGGREGATE_ALLOWS_ALL | AGGREGATE_ALLOWS_ORDER_BY
 )
 
 /*
@@ -117,14 +123,31 @@ Aggregate functions. The variable represents a map from string to Aggregate Func
 */
 
 var _AGGREGATES = map[string]*AggregateRegistry{
-	"array_agg":       &AggregateRegistry{property: AGGREGATE_ALLOWS_ALL, agg: &ArrayAgg{}},
+	"array_agg":       &AggregateRegistry{property: AGGREGATE_ALLOWS_ALL_ORDER_BY, agg: &ArrayAgg{}},
 	"avg":             &AggregateRegistry{property: AGGREGATE_ALLOWS_ALL_INCREMENTAL, agg: &Avg{}},
 	"count":           &AggregateRegistry{property: AGGREGATE_ALLOWS_ALL_INCREMENTAL, agg: &Count{}},
 	"countn":          &AggregateRegistry{property: AGGREGATE_ALLOWS_ALL_INCREMENTAL, agg: &Countn{}},
+	"corr":            &AggregateRegistry{property: AGGREGATE_ALLOWS_STATISTIC, agg: &Corr{}},
+	"covar_pop":       &AggregateRegistry{property: AGGREGATE_ALLOWS_STATISTIC, agg: &CovarPop{}},
+	"covar_samp":      &AggregateRegistry{property: AGGREGATE_ALLOWS_STATISTIC, agg: &CovarSamp{}},
 	"max":             &AggregateRegistry{property: AGGREGATE_ALLOWS_ALL, agg: &Max{}},
 	"mean":            &AggregateRegistry{property: AGGREGATE_ALLOWS_ALL_INCREMENTAL, agg: &Avg{}},
 	"median":          &AggregateRegistry{property: AGGREGATE_ALLOWS_ALL, agg: &Median{}},
 	"min":             &AggregateRegistry{property: AGGREGATE_ALLOWS_ALL, agg: &Min{}},
+	"listagg":         &AggregateRegistry{property: AGGREGATE_ALLOWS_ALL_ORDER_BY, agg: &StringAgg{}},
+	"mode":            &AggregateRegistry{property: AGGREGATE_ALLOWS_STATISTIC, agg: &Mode{}},
+	"percentile_cont": &AggregateRegistry{property: AGGREGATE_ALLOWS_ORDERED_SET, agg: &PercentileCont{}},
+	"percentile_disc": &AggregateRegistry{property: AGGREGATE_ALLOWS_ORDERED_SET, agg: &PercentileDisc{}},
+	"regr_avgx":       &AggregateRegistry{property: AGGREGATE_ALLOWS_STATISTIC, agg: &RegrAvgx{}},
+	"regr_avgy":       &AggregateRegistry{property: AGGREGATE_ALLOWS_STATISTIC, agg: &RegrAvgy{}},
+	"regr_count":      &AggregateRegistry{property: AGGREGATE_ALLOWS_STATISTIC, agg: &RegrCount{}},
+	"regr_intercept":  &AggregateRegistry{property: AGGREGATE_ALLOWS_STATISTIC, agg: &RegrIntercept{}},
+	"regr_r2":         &AggregateRegistry{property: AGGREGATE_ALLOWS_STATISTIC, agg: &RegrR2{}},
+	"regr_slope":      &AggregateRegistry{property: AGGREGATE_ALLOWS_STATISTIC, agg: &RegrSlope{}},
+	"regr_sxx":        &AggregateRegistry{property: AGGREGATE_ALLOWS_STATISTIC, agg: &RegrSxx{}},
+	"regr_sxy":        &AggregateRegistry{property: AGGREGATE_ALLOWS_STATISTIC, agg: &RegrSxy{}},
+	"regr_syy":        &AggregateRegistry{property: AGGREGATE_ALLOWS_STATISTIC, agg: &RegrSyy{}},
+	"string_agg":      &AggregateRegistry{property: AGGREGATE_ALLOWS_ALL_ORDER_BY, agg: &StringAgg{}},
 	"stddev":          &AggregateRegistry{property: AGGREGATE_ALLOWS_ALL, agg: &Stddev{}},
 	"stddev_pop":      &AggregateRegistry{property: AGGREGATE_ALLOWS_ALL, agg: &StddevPop{}},
 	"stddev_samp":     &AggregateRegistry{property: AGGREGATE_ALLOWS_ALL, agg: &StddevSamp{}},
@@ -0,0 +1,131 @@
+//  Copyright (c) 2020 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package algebra
+
+import (
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/value"
+)
+
+/*
+This represents the Aggregate function REGR_AVGY(expr1, expr2). It
+returns the average of expr1, over the pairs of number values in the
+group.
+Type RegrAvgy is a struct that inherits from AggregateBase.
+*/
+type RegrAvgy struct {
+	AggregateBase
+}
+
+/*
+The function NewRegrAvgy calls NewAggregateBase to
+create an aggregate function named regr_avgy with
+two expressions as input.
+*/
+func NewRegrAvgy(operands expression.Expressions, flags uint32, filter expression.Expression, wTerm *WindowTerm) Aggregate {
+	rv := &RegrAvgy{
+		*NewAggregateBase("regr_avgy", operands, flags, filter, wTerm),
+	}
+
+	rv.SetExpr(rv)
+	return rv
+}
+
+/*
+It calls the VisitFunction method by passing in the receiver to
+and returns the interface. It is a visitor pattern.
+*/
+func (this *RegrAvgy) Accept(visitor expression.Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+/*
+It returns a value of type NUMBER.
+*/
+func (this *RegrAvgy) Type() value.Type { return value.NUMBER }
+
+/*
+Calls the evaluate method for aggregate functions and passes in the
+receiver, current item and current context.
+*/
+func (this *RegrAvgy) Evaluate(item value.Value, context expression.Context) (result value.Value, e error) {
+	return this.evaluate(this, item, context)
+}
+
+/*
+Minimum input arguments required is 2.
+*/
+func (this *RegrAvgy) MinArgs() int { return 2 }
+
+/*
+Maximum number of input arguments allowed is 2.
+*/
+func (this *RegrAvgy) MaxArgs() int { return 2 }
+
+/*
+The constructor returns a NewRegrAvgy with the input operand
+cast to a Function as the FunctionConstructor.
+*/
+func (this *RegrAvgy) Constructor() expression.FunctionConstructor {
+	return func(operands ...expression.Expression) expression.Function {
+		return NewRegrAvgy(operands, uint32(0), nil, nil)
+	}
+}
+
+/*
+Copy of the aggregate function
+*/
+
+func (this *RegrAvgy) Copy() expression.Expression {
+	rv := &RegrAvgy{
+		*NewAggregateBase(this.Name(), expression.CopyExpressions(this.Operands()),
+			this.Flags(), expression.Copy(this.Filter()), CopyWindowTerm(this.WindowTerm())),
+	}
+
+	rv.BaseCopy(this)
+	rv.SetExpr(rv)
+	return rv
+}
+
+/*
+If no input to the RegrAvgy function, then the default value
+returned is a null.
+*/
+func (this *RegrAvgy) Default(item value.Value, context Context) (value.Value, error) {
+	return value.NULL_VALUE, nil
+}
+
+/*
+Aggregates input data by evaluating operands. Pairs where either
+value is not a NUMBER are skipped.
+*/
+func (this *RegrAvgy) CumulateInitial(item, cumulative value.Value, context Context) (value.Value, error) {
+	return this.cumulatePair(item, cumulative, context)
+}
+
+/*
+Aggregates intermediate results and return them.
+*/
+func (this *RegrAvgy) CumulateIntermediate(part, cumulative value.Value, context Context) (value.Value, error) {
+	return cumulateBivariate(part, cumulative), nil
+}
+
+/*
+Return the average of expr1 as the final, or NULL if no pairs of
+type NUMBER exist.
+*/
+func (this *RegrAvgy) ComputeFinal(cumulative value.Value, context Context) (value.Value, error) {
+	n, meany, _, _, _, _ := bivariateStats(cumulative)
+	if n == 0.0 {
+		return value.NULL_VALUE, nil
+	}
+
+	return value.NewValue(meany), nil
+}
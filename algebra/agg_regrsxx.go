@@ -0,0 +1,131 @@
+//  Copyright (c) 2020 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package algebra
+
+import (
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/value"
+)
+
+/*
+This represents the Aggregate function REGR_SXX(expr1, expr2). It
+returns the sum of squares of the deviations of expr2 from its average,
+over the pairs of number values in the group.
+Type RegrSxx is a struct that inherits from AggregateBase.
+*/
+type RegrSxx struct {
+	AggregateBase
+}
+
+/*
+The function NewRegrSxx calls NewAggregateBase to
+create an aggregate function named regr_sxx with
+two expressions as input.
+*/
+func NewRegrSxx(operands expression.Expressions, flags uint32, filter expression.Expression, wTerm *WindowTerm) Aggregate {
+	rv := &RegrSxx{
+		*NewAggregateBase("regr_sxx", operands, flags, filter, wTerm),
+	}
+
+	rv.SetExpr(rv)
+	return rv
+}
+
+/*
+It calls the VisitFunction method by passing in the receiver to
+and returns the interface. It is a visitor pattern.
+*/
+func (this *RegrSxx) Accept(visitor expression.Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+/*
+It returns a value of type NUMBER.
+*/
+func (this *RegrSxx) Type() value.Type { return value.NUMBER }
+
+/*
+Calls the evaluate method for aggregate functions and passes in the
+receiver, current item and current context.
+*/
+func (this *RegrSxx) Evaluate(item value.Value, context expression.Context) (result value.Value, e error) {
+	return this.evaluate(this, item, context)
+}
+
+/*
+Minimum input arguments required is 2.
+*/
+func (this *RegrSxx) MinArgs() int { return 2 }
+
+/*
+Maximum number of input arguments allowed is 2.
+*/
+func (this *RegrSxx) MaxArgs() int { return 2 }
+
+/*
+The constructor returns a NewRegrSxx with the input operand
+cast to a Function as the FunctionConstructor.
+*/
+func (this *RegrSxx) Constructor() expression.FunctionConstructor {
+	return func(operands ...expression.Expression) expression.Function {
+		return NewRegrSxx(operands, uint32(0), nil, nil)
+	}
+}
+
+/*
+Copy of the aggregate function
+*/
+
+func (this *RegrSxx) Copy() expression.Expression {
+	rv := &RegrSxx{
+		*NewAggregateBase(this.Name(), expression.CopyExpressions(this.Operands()),
+			this.Flags(), expression.Copy(this.Filter()), CopyWindowTerm(this.WindowTerm())),
+	}
+
+	rv.BaseCopy(this)
+	rv.SetExpr(rv)
+	return rv
+}
+
+/*
+If no input to the RegrSxx function, then the default value
+returned is a null.
+*/
+func (this *RegrSxx) Default(item value.Value, context Context) (value.Value, error) {
+	return value.NULL_VALUE, nil
+}
+
+/*
+Aggregates input data by evaluating operands. Pairs where either
+value is not a NUMBER are skipped.
+*/
+func (this *RegrSxx) CumulateInitial(item, cumulative value.Value, context Context) (value.Value, error) {
+	return this.cumulatePair(item, cumulative, context)
+}
+
+/*
+Aggregates intermediate results and return them.
+*/
+func (this *RegrSxx) CumulateIntermediate(part, cumulative value.Value, context Context) (value.Value, error) {
+	return cumulateBivariate(part, cumulative), nil
+}
+
+/*
+Return the sum of squares of expr2 as the final, or NULL if no pairs
+of type NUMBER exist.
+*/
+func (this *RegrSxx) ComputeFinal(cumulative value.Value, context Context) (value.Value, error) {
+	n, _, _, _, sxx, _ := bivariateStats(cumulative)
+	if n == 0.0 {
+		return value.NULL_VALUE, nil
+	}
+
+	return value.NewValue(sxx), nil
+}
@@ -0,0 +1,198 @@
+//  Copyright (c) 2020 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package algebra
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/value"
+)
+
+/*
+This represents the Aggregate function
+STRING_AGG(expr [, separator] [ORDER BY ...]), also named LISTAGG.
+It returns the concatenation of the string values in the group,
+separated by the separator, in the given order or else in collation
+order. Type StringAgg is a struct that inherits from AggregateBase.
+*/
+type StringAgg struct {
+	AggregateBase
+}
+
+/*
+The function NewStringAgg calls NewAggregateBase to
+create an aggregate function named STRING_AGG with
+the expression and the optional separator as input.
+*/
+func NewStringAgg(operands expression.Expressions, flags uint32, filter expression.Expression, wTerm *WindowTerm) Aggregate {
+	rv := &StringAgg{
+		*NewAggregateBase("string_agg", operands, flags, filter, wTerm),
+	}
+
+	rv.SetExpr(rv)
+	return rv
+}
+
+/*
+It calls the VisitFunction method by passing in the receiver to
+and returns the interface. It is a visitor pattern.
+*/
+func (this *StringAgg) Accept(visitor expression.Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+/*
+It returns a value of type STRING.
+*/
+func (this *StringAgg) Type() value.Type { return value.STRING }
+
+/*
+Calls the evaluate method for aggregate functions and passes in the
+receiver, current item and current context.
+*/
+func (this *StringAgg) Evaluate(item value.Value, context expression.Context) (result value.Value, e error) {
+	return this.evaluate(this, item, context)
+}
+
+/*
+Minimum input arguments required is 1.
+*/
+func (this *StringAgg) MinArgs() int { return 1 }
+
+/*
+Maximum number of input arguments allowed is 2.
+*/
+func (this *StringAgg) MaxArgs() int { return 2 }
+
+/*
+The constructor returns a NewStringAgg with the input operand
+cast to a Function as the FunctionConstructor.
+*/
+func (this *StringAgg) Constructor() expression.FunctionConstructor {
+	return func(operands ...expression.Expression) expression.Function {
+		return NewStringAgg(operands, uint32(0), nil, nil)
+	}
+}
+
+/*
+Copy of the aggregate function
+*/
+
+func (this *StringAgg) Copy() expression.Expression {
+	rv := &StringAgg{
+		*NewAggregateBase(this.Name(), expression.CopyExpressions(this.Operands()),
+			this.Flags(), expression.Copy(this.Filter()), CopyWindowTerm(this.WindowTerm())),
+	}
+
+	rv.SetOrderBy(CopyOrder(this.OrderBy()))
+	rv.BaseCopy(this)
+	rv.SetExpr(rv)
+	return rv
+}
+
+/*
+If no input to the STRING_AGG function, then the default value
+returned is a null.
+*/
+func (this *StringAgg) Default(item value.Value, context Context) (value.Value, error) {
+	return value.NULL_VALUE, nil
+}
+
+/*
+Aggregates input data by evaluating operands. Values other than
+strings are skipped. Collect the strings, paired with their ORDER BY
+values if any, as the intermediate aggregate value.
+*/
+func (this *StringAgg) CumulateInitial(item, cumulative value.Value, context Context) (value.Value, error) {
+	// apply filter if any
+	if ok, e := this.evaluateFilter(item, context); e != nil || !ok {
+		return cumulative, e
+	}
+
+	val, e := this.Operands()[0].Evaluate(item, context)
+	if e != nil {
+		return nil, e
+	}
+
+	if val.Type() != value.STRING {
+		return cumulative, nil
+	}
+
+	if this.Distinct() {
+		return setAdd(val, cumulative, false), nil
+	}
+
+	val, e = this.orderedValue(val, item, context)
+	if e != nil {
+		return nil, e
+	}
+
+	return listAdd(val, cumulative), nil
+}
+
+/*
+Aggregates intermediate results and return them.
+*/
+func (this *StringAgg) CumulateIntermediate(part, cumulative value.Value, context Context) (value.Value, error) {
+	if this.Distinct() {
+		return cumulateSets(part, cumulative)
+	} else {
+		return cumulateValueLists(part, cumulative)
+	}
+}
+
+/*
+Compute the Final. Return NULL if no strings exist. Otherwise sort
+the strings, on the ORDER BY terms if any, and join them with the
+separator.
+*/
+func (this *StringAgg) ComputeFinal(cumulative value.Value, context Context) (value.Value, error) {
+	var vals []interface{}
+	if this.Distinct() {
+		set, e := getSet(cumulative)
+		if e != nil || set.Len() == 0 {
+			return value.NULL_VALUE, nil
+		}
+		vals = set.Actuals()
+	} else {
+		list, e := getList(cumulative)
+		if e != nil || list.Len() == 0 {
+			return value.NULL_VALUE, nil
+		}
+
+		vals = make([]interface{}, list.Len())
+		for i, v := range list.Values() {
+			vals[i] = v
+		}
+	}
+
+	separator := ""
+	if len(this.Operands()) > 1 {
+		sep, e := this.Operands()[1].Evaluate(value.NULL_VALUE, context)
+		if e != nil {
+			return nil, e
+		}
+
+		if sep.Type() != value.STRING {
+			return nil, fmt.Errorf("Invalid %v separator %v, it must be a string.", this.Name(), sep)
+		}
+		separator = sep.Actual().(string)
+	}
+
+	vals = this.sortOrderedValues(vals)
+	strs := make([]string, len(vals))
+	for i, v := range vals {
+		strs[i] = value.NewValue(v).Actual().(string)
+	}
+
+	return value.NewValue(strings.Join(strs, separator)), nil
+}
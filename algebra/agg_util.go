@@ -58,17 +58,19 @@ func setAdd(item, cumulative value.Value, numeric bool) value.AnnotatedValue {
 }
 
 /*
-Aggregate distinct intermediate results and return them.
+Aggregate distinct intermediate results and return them. Either
+part may still be the default of a stream that has not added any
+value.
 */
-func cumulateSets(part, cumulative value.Value) (value.AnnotatedValue, error) {
+func cumulateSets(part, cumulative value.Value) (value.Value, error) {
 	pset, e := getSet(part)
 	if e != nil {
-		return nil, e
+		return cumulative, nil
 	}
 
 	cset, e := getSet(cumulative)
 	if e != nil {
-		return nil, e
+		return part, nil
 	}
 
 	// Add smaller set to bigger
@@ -174,6 +176,146 @@ func cumulateLists(part, cumulative value.Value) (value.AnnotatedValue, error) {
 	return av, nil
 }
 
+/*
+Aggregate intermediate lists, where either part may still be the
+default of a stream that has not added any value.
+*/
+func cumulateValueLists(part, cumulative value.Value) (value.Value, error) {
+	if _, e := getList(part); e != nil {
+		return cumulative, nil
+	} else if _, e := getList(cumulative); e != nil {
+		return part, nil
+	}
+
+	return cumulateLists(part, cumulative)
+}
+
+/*
+Sort a copy of the values in the collation order, or in the reverse
+collation order when descending.
+*/
+func sortValues(vals value.Values, descending bool) []interface{} {
+	sorted := value.NewValue(vals)
+	sort.Sort(value.NewSorter(sorted))
+
+	array, _ := sorted.Actual().([]interface{})
+	if descending {
+		for i, j := 0, len(array)-1; i < j; i, j = i+1, j-1 {
+			array[i], array[j] = array[j], array[i]
+		}
+	}
+
+	return array
+}
+
+/*
+Pair the value to aggregate with the values of the ORDER BY terms of
+the aggregate, to sort on in the final. MISSING sort values are
+ordered as NULL. The values of DISTINCT aggregates are sorted on
+themselves, and are not paired.
+*/
+func (this *AggregateBase) orderedValue(val, item value.Value, context Context) (value.Value, error) {
+	if this.orderBy == nil || this.Distinct() {
+		return val, nil
+	}
+
+	terms := this.orderBy.Terms()
+	keys := make([]interface{}, len(terms))
+	for i, term := range terms {
+		key, e := term.Expression().Evaluate(item, context)
+		if e != nil {
+			return nil, e
+		}
+
+		if key.Type() == value.MISSING {
+			key = value.NULL_VALUE
+		}
+		keys[i] = key
+	}
+
+	return value.NewValue([]interface{}{keys, val}), nil
+}
+
+/*
+Sort the aggregated values in the final. Without ORDER BY the values
+are sorted in collation order. Otherwise they are sorted on the ORDER
+BY terms, and the values paired with their sort values are unpaired.
+*/
+func (this *AggregateBase) sortOrderedValues(vals []interface{}) []interface{} {
+	if this.orderBy == nil {
+		sort.Sort(value.NewSorter(value.NewValue(vals)))
+		return vals
+	}
+
+	terms := this.orderBy.Terms()
+	if this.Distinct() {
+		sort.SliceStable(vals, func(i, j int) bool {
+			return compareSortTerm(terms[0], value.NewValue(vals[i]), value.NewValue(vals[j])) < 0
+		})
+		return vals
+	}
+
+	keys := make([]value.Value, len(vals))
+	for i, v := range vals {
+		pair := value.NewValue(v)
+		keys[i], _ = pair.Index(0)
+		vals[i], _ = pair.Index(1)
+	}
+
+	sort.Stable(&orderedValues{terms: terms, keys: keys, vals: vals})
+	return vals
+}
+
+/*
+Sorts the aggregated values on their ORDER BY values.
+*/
+type orderedValues struct {
+	terms SortTerms
+	keys  []value.Value
+	vals  []interface{}
+}
+
+func (this *orderedValues) Len() int {
+	return len(this.vals)
+}
+
+func (this *orderedValues) Less(i, j int) bool {
+	for t, term := range this.terms {
+		k1, _ := this.keys[i].Index(t)
+		k2, _ := this.keys[j].Index(t)
+		if c := compareSortTerm(term, k1, k2); c != 0 {
+			return c < 0
+		}
+	}
+
+	return false
+}
+
+func (this *orderedValues) Swap(i, j int) {
+	this.keys[i], this.keys[j] = this.keys[j], this.keys[i]
+	this.vals[i], this.vals[j] = this.vals[j], this.vals[i]
+}
+
+/*
+Compare two values on a sort term, with its direction and NULLS
+position, as the ORDER BY of a query does.
+*/
+func compareSortTerm(term *SortTerm, v1, v2 value.Value) int {
+	var c int
+	if !term.NullsPos() || (v1.Type() <= value.NULL) == (v2.Type() <= value.NULL) {
+		c = v1.Collate(v2)
+	} else if v1.Type() <= value.NULL {
+		c = 1
+	} else {
+		c = -1
+	}
+
+	if term.Descending() {
+		return -c
+	}
+	return c
+}
+
 /*
 Linear time algorithm to compute the kth smallest value in an unsorted list.
 It devides the list into sublists of size 5 and finds the approximate median in each of the sublists.
@@ -390,6 +532,100 @@ func computeVariance(cumulative value.Value, distinct, samp bool, delta float64)
 	return value.NewValue(variance / (count - delta)), nil
 }
 
+/*
+Add a pair of numbers to the cumulative of the bivariate aggregates
+CORR, COVAR_* and REGR_*. The cumulative holds the count, the means
+of y and x, the sums of squared deviations of y and x from their
+means, and the sum of the products of the deviations.
+*/
+func addBivariate(y, x, cumulative value.Value) value.Value {
+	part := value.NewValue(map[string]interface{}{
+		"count": 1.0,
+		"meany": y.(value.NumberValue).Float64(),
+		"meanx": x.(value.NumberValue).Float64(),
+		"syy":   0.0,
+		"sxx":   0.0,
+		"sxy":   0.0,
+	})
+
+	return cumulateBivariate(part, cumulative)
+}
+
+/*
+Aggregate intermediate results of the bivariate aggregates, merging
+the means and the sums of deviations of both parts.
+*/
+func cumulateBivariate(part, cumulative value.Value) value.Value {
+	if part.Type() != value.OBJECT {
+		return cumulative
+	} else if cumulative.Type() != value.OBJECT {
+		return part
+	}
+
+	pn, pmy, pmx, psyy, psxx, psxy := bivariateStats(part)
+	cn, cmy, cmx, csyy, csxx, csxy := bivariateStats(cumulative)
+
+	n := cn + pn
+	dy := pmy - cmy
+	dx := pmx - cmx
+	f := cn * pn / n
+
+	cumulative.SetField("count", n)
+	cumulative.SetField("meany", cmy+dy*pn/n)
+	cumulative.SetField("meanx", cmx+dx*pn/n)
+	cumulative.SetField("syy", csyy+psyy+dy*dy*f)
+	cumulative.SetField("sxx", csxx+psxx+dx*dx*f)
+	cumulative.SetField("sxy", csxy+psxy+dx*dy*f)
+	return cumulative
+}
+
+/*
+Return the count, the means and the sums of deviations of the
+bivariate cumulative. The count is zero if no pair was added.
+*/
+func bivariateStats(cumulative value.Value) (n, meany, meanx, syy, sxx, sxy float64) {
+	if cumulative.Type() != value.OBJECT {
+		return
+	}
+
+	stat := func(name string) float64 {
+		v, _ := cumulative.Field(name)
+		if v.Type() != value.NUMBER {
+			return 0.0
+		}
+		return v.(value.NumberValue).Float64()
+	}
+
+	return stat("count"), stat("meany"), stat("meanx"), stat("syy"), stat("sxx"), stat("sxy")
+}
+
+/*
+Evaluate the pair of operands (y, x) of a bivariate aggregate, and
+add it to the cumulative if both are numbers.
+*/
+func (this *AggregateBase) cumulatePair(item, cumulative value.Value, context Context) (value.Value, error) {
+	// apply filter if any
+	if ok, e := this.evaluateFilter(item, context); e != nil || !ok {
+		return cumulative, e
+	}
+
+	y, e := this.Operands()[0].Evaluate(item, context)
+	if e != nil {
+		return nil, e
+	}
+
+	x, e := this.Operands()[1].Evaluate(item, context)
+	if e != nil {
+		return nil, e
+	}
+
+	if y.Type() != value.NUMBER || x.Type() != value.NUMBER {
+		return cumulative, nil
+	}
+
+	return addBivariate(y, x, cumulative), nil
+}
+
 /*
 Return Window attachment
 */
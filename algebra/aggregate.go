@@ -55,6 +55,16 @@ type Aggregate interface {
 	*/
 	WindowTerm() *WindowTerm
 
+	/*
+	   Return ORDER BY of the aggregated values.
+	*/
+	OrderBy() *Order
+
+	/*
+	   Set ORDER BY of the aggregated values.
+	*/
+	SetOrderBy(oby *Order)
+
 	/*
 	   Return Flags
 	*/
@@ -117,9 +127,10 @@ Base class for Aggregate functions.
 It inherits from expressions FunctionBase, and has
      text           which represents the function name.
      flags          which represents the modifers/flags
-                         DISTINCT, INCREMENTAL, RESPECT|IGNORE NULLS, FROM FIRST|LAST
+                         DISTINCT, INCREMENTAL, RESPECT|IGNORE NULLS, FROM FIRST|LAST, WITHIN GROUP (ORDER BY ... DESC)
      filter         include those objects that filter condition is true in aggregation
      windowTerm     which represents the Window information
+     orderBy        which represents the order of the aggregated values
 */
 
 type AggregateBase struct {
@@ -128,6 +139,7 @@ type AggregateBase struct {
 	flags      uint32
 	filter     expression.Expression
 	windowTerm *WindowTerm
+	orderBy    *Order
 }
 
 /*
@@ -179,6 +191,13 @@ func (this *AggregateBase) SetAggregateModifiers(flags uint32, filter expression
 	}
 }
 
+/*
+Sets ORDER BY of the aggregated values
+*/
+func (this *AggregateBase) SetOrderBy(oby *Order) {
+	this.orderBy = oby
+}
+
 /*
 Helper functions
 */
@@ -190,6 +209,7 @@ func (this *AggregateBase) Flags() uint32                 { return this.flags }
 func (this *AggregateBase) MinArgs() int                  { return 1 }
 func (this *AggregateBase) MaxArgs() int                  { return 1 }
 func (this *AggregateBase) Filter() expression.Expression { return this.filter }
+func (this *AggregateBase) OrderBy() *Order               { return this.orderBy }
 
 /*
 If Incremental aggregation is possible or not
@@ -230,7 +250,20 @@ func (this *AggregateBase) String() string {
 		buf.WriteString("DISTINCT ")
 	}
 
-	for i, op := range this.Operands() {
+	// ordered-set aggregates take the argument first, and the
+	// value they order in WITHIN GROUP
+	ops := this.Operands()
+	if AggregateHasProperty(this.Name(), AGGREGATE_WITHIN_GROUP) && len(ops) == 2 {
+		buf.WriteString(stringer.Visit(ops[1]))
+		buf.WriteString(") WITHIN GROUP (ORDER BY ")
+		buf.WriteString(stringer.Visit(ops[0]))
+		if this.HasFlags(AGGREGATE_DESCENDING) {
+			buf.WriteString(" DESC")
+		}
+		ops = nil
+	}
+
+	for i, op := range ops {
 		if i > 0 {
 			buf.WriteString(", ")
 		}
@@ -243,6 +276,10 @@ func (this *AggregateBase) String() string {
 		}
 	}
 
+	if this.orderBy != nil {
+		buf.WriteString(this.orderBy.String())
+	}
+
 	buf.WriteString(")")
 
 	if this.Filter() != nil {
@@ -343,10 +380,13 @@ func (this *AggregateBase) EquivalentTo(other expression.Expression) bool {
 func EqualAggregateModifiers(agg1, agg2 Aggregate) bool {
 	wTerm1 := agg1.WindowTerm()
 	wTerm2 := agg2.WindowTerm()
+	oby1 := agg1.OrderBy()
+	oby2 := agg2.OrderBy()
 
 	return agg1.Flags() == agg2.Flags() &&
 		expression.Equivalent(agg1.Filter(), agg2.Filter()) &&
 		expression.Equivalents(agg1.Operands(), agg2.Operands()) &&
+		((oby1 == oby2) || (oby1 != nil && oby2 != nil && oby1.String() == oby2.String())) &&
 		((wTerm1 == wTerm2) || (wTerm1 != nil && wTerm2 != nil && wTerm1.String() == wTerm2.String()))
 }
 
@@ -369,6 +409,10 @@ func (this *AggregateBase) Children() expression.Expressions {
 		}
 	}
 
+	if this.orderBy != nil {
+		rv = append(rv, this.orderBy.Expressions()...)
+	}
+
 	if this.Filter() != nil {
 		rv = append(rv, this.Filter())
 	}
@@ -403,6 +447,13 @@ func (this *AggregateBase) MapChildren(mapper expression.Mapper) error {
 		}
 	}
 
+	if this.orderBy != nil {
+		err := this.orderBy.MapExpressions(mapper)
+		if err != nil {
+			return err
+		}
+	}
+
 	if this.Filter() != nil {
 		expr, err := mapper.Map(this.Filter())
 		if err != nil {
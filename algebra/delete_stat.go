@@ -0,0 +1,101 @@
+//  Copyright (c) 2019 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package algebra
+
+import (
+	"encoding/json"
+
+	"github.com/couchbase/query/auth"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/value"
+)
+
+/*
+Represents DELETE STATISTICS statement. No terms means all the
+statistics of the keyspace.
+*/
+type DeleteStatistics struct {
+	statementBase
+
+	keyspace *KeyspaceRef           `json:"keyspace"`
+	terms    expression.Expressions `json:"terms"`
+}
+
+func NewDeleteStatistics(keyspace *KeyspaceRef, terms expression.Expressions) *DeleteStatistics {
+	rv := &DeleteStatistics{
+		keyspace: keyspace,
+		terms:    terms,
+	}
+
+	rv.stmt = rv
+	return rv
+}
+
+func (this *DeleteStatistics) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitDeleteStatistics(this)
+}
+
+func (this *DeleteStatistics) Signature() value.Value {
+	return nil
+}
+
+func (this *DeleteStatistics) Formalize() error {
+	f := expression.NewKeyspaceFormalizer(this.keyspace.Keyspace(), nil)
+	return this.MapExpressions(f)
+}
+
+func (this *DeleteStatistics) MapExpressions(mapper expression.Mapper) (err error) {
+	for i, term := range this.Expressions() {
+		this.terms[i], err = mapper.Map(term)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+func (this *DeleteStatistics) Expressions() expression.Expressions {
+	return this.terms
+}
+
+func (this *DeleteStatistics) Privileges() (*auth.Privileges, errors.Error) {
+	privs, err := privilegesFromPath(this.keyspace.path)
+	if err != nil {
+		return privs, err
+	}
+
+	for _, term := range this.terms {
+		privs.AddAll(term.Privileges())
+	}
+
+	return privs, nil
+}
+
+func (this *DeleteStatistics) Keyspace() *KeyspaceRef {
+	return this.keyspace
+}
+
+func (this *DeleteStatistics) Terms() expression.Expressions {
+	return this.terms
+}
+
+func (this *DeleteStatistics) MarshalJSON() ([]byte, error) {
+	r := map[string]interface{}{"type": "deleteStatistics"}
+	r["keyspaceRef"] = this.keyspace
+	r["terms"] = this.terms
+
+	return json.Marshal(r)
+}
+
+func (this *DeleteStatistics) Type() string {
+	return "DELETE_STATISTICS"
+}
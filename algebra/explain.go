@@ -23,18 +23,22 @@ a struct that represents the explain json statement.
 type Explain struct {
 	statementBase
 
-	stmt Statement `json:"stmt"`
-	text string    `json:"text"`
+	stmt    Statement `json:"stmt"`
+	text    string    `json:"text"`
+	analyze bool
 }
 
 /*
 The function NewExplain returns a pointer to the Explain
 struct that has its field stmt set to the input Statement.
+With analyze set, the statement is executed and the plan is
+returned with the runtime statistics of each operator.
 */
-func NewExplain(stmt Statement, text string) *Explain {
+func NewExplain(stmt Statement, text string, analyze bool) *Explain {
 	rv := &Explain{
-		stmt: stmt,
-		text: text,
+		stmt:    stmt,
+		text:    text,
+		analyze: analyze,
 	}
 
 	rv.statementBase.stmt = rv
@@ -99,6 +103,13 @@ func (this *Explain) Text() string {
 	return this.text
 }
 
+/*
+Return whether the statement is executed, as for EXPLAIN ANALYZE
+*/
+func (this *Explain) Analyze() bool {
+	return this.analyze
+}
+
 func (this *Explain) Type() string {
 	return "EXPLAIN"
 }
@@ -17,6 +17,28 @@ import (
 	"github.com/couchbase/query/expression"
 )
 
+type JoinType int
+
+const (
+	JOIN_INNER JoinType = iota
+	JOIN_LEFT_OUTER
+	JOIN_RIGHT_OUTER
+	JOIN_FULL_OUTER
+)
+
+func (this JoinType) String() string {
+	switch this {
+	case JOIN_LEFT_OUTER:
+		return "left outer"
+	case JOIN_RIGHT_OUTER:
+		return "right outer"
+	case JOIN_FULL_OUTER:
+		return "full outer"
+	default:
+		return "inner"
+	}
+}
+
 /*
 Represents the ANSI JOIN clause. AnsiJoins create new input objects by
 combining two or more source objects.  They can be chained.
@@ -24,18 +46,30 @@ combining two or more source objects.  They can be chained.
 type AnsiJoin struct {
 	left      FromTerm
 	right     SimpleFromTerm
-	outer     bool
+	joinType  JoinType
 	onclause  expression.Expression
 	hintError string
 }
 
 func NewAnsiJoin(left FromTerm, outer bool, right SimpleFromTerm, onclause expression.Expression) *AnsiJoin {
-	return &AnsiJoin{left, right, outer, onclause, ""}
+	joinType := JOIN_INNER
+	if outer {
+		joinType = JOIN_LEFT_OUTER
+	}
+	return &AnsiJoin{left, right, joinType, onclause, ""}
+}
+
+/*
+RIGHT and FULL OUTER JOIN following other joins, i.e. with the left-hand
+side not (only) preserved.
+*/
+func NewAnsiOuterJoin(left FromTerm, joinType JoinType, right SimpleFromTerm, onclause expression.Expression) *AnsiJoin {
+	return &AnsiJoin{left, right, joinType, onclause, ""}
 }
 
 func NewAnsiRightJoin(left SimpleFromTerm, right SimpleFromTerm, onclause expression.Expression) *AnsiJoin {
 	TransferJoinHint(left, right)
-	return &AnsiJoin{right, left, true, onclause, ""}
+	return &AnsiJoin{right, left, JOIN_LEFT_OUTER, onclause, ""}
 }
 
 func TransferJoinHint(left SimpleFromTerm, right SimpleFromTerm) {
@@ -111,8 +145,8 @@ func (this *AnsiJoin) Privileges() (*auth.Privileges, errors.Error) {
 func (this *AnsiJoin) String() string {
 	s := this.left.String()
 
-	if this.outer {
-		s += " left outer join "
+	if this.joinType != JOIN_INNER {
+		s += " " + this.joinType.String() + " join "
 	} else {
 		s += " join "
 	}
@@ -176,11 +210,26 @@ func (this *AnsiJoin) Right() SimpleFromTerm {
 }
 
 /*
-Returns boolean value based on if it is
-an outer or inner JOIN.
+Returns boolean value based on if the right source
+is optional, i.e. for LEFT and FULL OUTER JOIN.
 */
 func (this *AnsiJoin) Outer() bool {
-	return this.outer
+	return this.joinType == JOIN_LEFT_OUTER || this.joinType == JOIN_FULL_OUTER
+}
+
+/*
+Returns boolean value based on if the left source
+is optional, i.e. for RIGHT and FULL OUTER JOIN.
+*/
+func (this *AnsiJoin) RightOuter() bool {
+	return this.joinType == JOIN_RIGHT_OUTER || this.joinType == JOIN_FULL_OUTER
+}
+
+/*
+Returns the kind of JOIN.
+*/
+func (this *AnsiJoin) JoinType() JoinType {
+	return this.joinType
 }
 
 /*
@@ -191,10 +240,45 @@ func (this *AnsiJoin) Onclause() expression.Expression {
 }
 
 /*
-Set outer
+Set whether the right source is optional. A FULL OUTER JOIN
+whose right source is no longer optional is a RIGHT OUTER JOIN.
 */
 func (this *AnsiJoin) SetOuter(outer bool) {
-	this.outer = outer
+	switch this.joinType {
+	case JOIN_INNER, JOIN_LEFT_OUTER:
+		if outer {
+			this.joinType = JOIN_LEFT_OUTER
+		} else {
+			this.joinType = JOIN_INNER
+		}
+	case JOIN_RIGHT_OUTER, JOIN_FULL_OUTER:
+		if outer {
+			this.joinType = JOIN_FULL_OUTER
+		} else {
+			this.joinType = JOIN_RIGHT_OUTER
+		}
+	}
+}
+
+/*
+Set whether the left source is optional. A FULL OUTER JOIN
+whose left source is no longer optional is a LEFT OUTER JOIN.
+*/
+func (this *AnsiJoin) SetRightOuter(rightOuter bool) {
+	switch this.joinType {
+	case JOIN_INNER, JOIN_RIGHT_OUTER:
+		if rightOuter {
+			this.joinType = JOIN_RIGHT_OUTER
+		} else {
+			this.joinType = JOIN_INNER
+		}
+	case JOIN_LEFT_OUTER, JOIN_FULL_OUTER:
+		if rightOuter {
+			this.joinType = JOIN_FULL_OUTER
+		} else {
+			this.joinType = JOIN_LEFT_OUTER
+		}
+	}
 }
 
 /*
@@ -227,7 +311,10 @@ func (this *AnsiJoin) MarshalJSON() ([]byte, error) {
 	r := map[string]interface{}{"type": "AnsiJoin"}
 	r["left"] = this.left
 	r["right"] = this.right
-	r["outer"] = this.outer
+	r["outer"] = this.Outer()
+	if this.RightOuter() {
+		r["joinType"] = this.joinType.String()
+	}
 	r["onclause"] = this.onclause
 	return json.Marshal(r)
 }
@@ -49,16 +49,17 @@ type KeyspaceTerm struct {
 	joinKeys expression.Expression
 	joinHint JoinHint
 	property uint32
+	useCache bool
 }
 
 func NewKeyspaceTerm(namespace, keyspace string, as string,
 	keys expression.Expression, indexes IndexRefs) *KeyspaceTerm {
-	return &KeyspaceTerm{NewPathShort(namespace, keyspace), as, keys, indexes, nil, JOIN_HINT_NONE, 0}
+	return &KeyspaceTerm{NewPathShort(namespace, keyspace), as, keys, indexes, nil, JOIN_HINT_NONE, 0, false}
 }
 
 func NewKeyspaceTermFromPath(path *Path, as string,
 	keys expression.Expression, indexes IndexRefs) *KeyspaceTerm {
-	return &KeyspaceTerm{path, as, keys, indexes, nil, JOIN_HINT_NONE, 0}
+	return &KeyspaceTerm{path, as, keys, indexes, nil, JOIN_HINT_NONE, 0, false}
 }
 
 func (this *KeyspaceTerm) Accept(visitor NodeVisitor) (interface{}, error) {
@@ -131,7 +132,7 @@ func privilegesFromPath(path *Path) (*auth.Privileges, errors.Error) {
 		switch bucket {
 		case "user_info", "applicable_roles":
 			privs.Add(fullKeyspace, auth.PRIV_SECURITY_READ)
-		case "keyspaces", "indexes", "my_user_info":
+		case "keyspaces", "scopes", "indexes", "my_user_info":
 			// Do nothing. These tables handle security internally, by
 			// filtering the results.
 		case "datastores", "namespaces", "dual":
@@ -175,6 +176,10 @@ func (this *KeyspaceTerm) String() string {
 		s += " use nl"
 	}
 
+	if this.useCache {
+		s += " use cache"
+	}
+
 	return s
 }
 
@@ -403,6 +408,17 @@ func (this *KeyspaceTerm) SetJoinHint(joinHint JoinHint) {
 	this.joinHint = joinHint
 }
 
+/*
+Returns whether the keyspace has a USE CACHE hint.
+*/
+func (this *KeyspaceTerm) UseCache() bool {
+	return this.useCache
+}
+
+func (this *KeyspaceTerm) SetUseCache(useCache bool) {
+	this.useCache = useCache
+}
+
 /*
 Set property
 */
@@ -10,6 +10,9 @@
 package algebra
 
 import (
+	"math/bits"
+	"sort"
+
 	"github.com/couchbase/query/expression"
 )
 
@@ -26,18 +29,21 @@ type Group struct {
 	by      expression.Expressions `json:by`
 	letting expression.Bindings    `json:"letting"`
 	having  expression.Expression  `json:"having"`
+	sets    [][]int
 }
 
 /*
 The function NewGroup returns a pointer to the Group
 struct that has its field sort terms set to the input
-argument expressions.
+argument expressions. ROLLUP, CUBE and GROUPING SETS
+terms are expanded into grouping sets over the distinct
+group by expressions.
 */
 func NewGroup(by GroupTerms, letting expression.Bindings, having expression.Expression) *Group {
 	rv := &Group{
-		by:     by.Expressions(),
 		having: having,
 	}
+	rv.by, rv.sets = by.groupingSets()
 
 	var byAlias expression.Bindings
 	for _, g := range by {
@@ -109,6 +115,25 @@ func (this *Group) MapExpressions(mapper expression.Mapper) (err error) {
 	return
 }
 
+/*
+This method maps the letting and having clauses, which are
+evaluated on the groups rather than on the input.
+*/
+func (this *Group) MapGroupedExpressions(mapper expression.Mapper) (err error) {
+	if this.letting != nil {
+		err = this.letting.MapExpressions(mapper)
+		if err != nil {
+			return
+		}
+	}
+
+	if this.having != nil {
+		this.having, err = mapper.Map(this.having)
+	}
+
+	return
+}
+
 /*
    Returns all contained Expressions.
 */
@@ -136,7 +161,27 @@ func (this *Group) Expressions() expression.Expressions {
 func (this *Group) String() string {
 	s := ""
 
-	if this.by != nil {
+	if this.sets != nil {
+		s += " group by grouping sets ("
+
+		for i, set := range this.sets {
+			if i > 0 {
+				s += ", "
+			}
+
+			s += "("
+			for j, k := range set {
+				if j > 0 {
+					s += ", "
+				}
+
+				s += this.by[k].String()
+			}
+			s += ")"
+		}
+
+		s += ")"
+	} else if this.by != nil {
 		s += " group by "
 
 		for i, b := range this.by {
@@ -166,6 +211,15 @@ func (this *Group) By() expression.Expressions {
 	return this.by
 }
 
+/*
+Returns the grouping sets of ROLLUP, CUBE or GROUPING SETS, as
+the positions of their expressions in the group by expressions,
+or nil for a plain group by.
+*/
+func (this *Group) GroupingSets() [][]int {
+	return this.sets
+}
+
 /*
 Returns the letting expression bindings.
 */
@@ -192,9 +246,79 @@ func (this GroupTerms) Expressions() expression.Expressions {
 	return exprs
 }
 
+/*
+Expand the terms into the distinct group by expressions and the
+grouping sets over them. The grouping sets of successive terms are
+combined by cross product, a plain term counting as a single set.
+The grouping sets are nil if there is no ROLLUP, CUBE or GROUPING
+SETS term.
+*/
+func (this GroupTerms) groupingSets() (expression.Expressions, [][]int) {
+	grouping := false
+	for _, b := range this {
+		if b.sets != nil {
+			grouping = true
+			break
+		}
+	}
+
+	if !grouping {
+		return this.Expressions(), nil
+	}
+
+	exprs := make(expression.Expressions, 0, len(this))
+	position := func(expr expression.Expression) int {
+		for i, e := range exprs {
+			if e.EquivalentTo(expr) {
+				return i
+			}
+		}
+
+		exprs = append(exprs, expr)
+		return len(exprs) - 1
+	}
+
+	sets := [][]int{[]int{}}
+	for _, b := range this {
+		termSets := b.sets
+		if termSets == nil {
+			termSets = []expression.Expressions{expression.Expressions{b.expr}}
+		}
+
+		product := make([][]int, 0, len(sets)*len(termSets))
+		for _, set := range sets {
+			for _, termSet := range termSets {
+				next := append(make([]int, 0, len(set)+len(termSet)), set...)
+				for _, expr := range termSet {
+					pos := position(expr)
+					found := false
+					for _, p := range next {
+						if p == pos {
+							found = true
+							break
+						}
+					}
+
+					if !found {
+						next = append(next, pos)
+					}
+				}
+
+				sort.Ints(next)
+				product = append(product, next)
+			}
+		}
+
+		sets = product
+	}
+
+	return exprs, sets
+}
+
 type GroupTerm struct {
 	expr expression.Expression `json:"expr"`
 	as   string                `json:"as"`
+	sets []expression.Expressions
 }
 
 func NewGroupTerm(expr expression.Expression, as string) *GroupTerm {
@@ -204,11 +328,70 @@ func NewGroupTerm(expr expression.Expression, as string) *GroupTerm {
 	}
 }
 
+/*
+ROLLUP(expr1, expr2, ...) groups by each prefix of the expressions,
+from all of them down to none.
+*/
+func NewRollupTerm(exprs expression.Expressions) *GroupTerm {
+	sets := make([]expression.Expressions, 0, len(exprs)+1)
+	for i := len(exprs); i >= 0; i-- {
+		sets = append(sets, exprs[:i])
+	}
+
+	return &GroupTerm{
+		sets: sets,
+	}
+}
+
+/*
+CUBE(expr1, expr2, ...) groups by each subset of the expressions,
+from all of them down to none.
+*/
+func NewCubeTerm(exprs expression.Expressions) *GroupTerm {
+	n := len(exprs)
+	sets := make([]expression.Expressions, 0, 1<<uint(n))
+	for size := n; size >= 0; size-- {
+		for mask := (1 << uint(n)) - 1; mask >= 0; mask-- {
+			if bits.OnesCount(uint(mask)) != size {
+				continue
+			}
+
+			set := make(expression.Expressions, 0, size)
+			for i, expr := range exprs {
+				if mask&(1<<uint(n-1-i)) != 0 {
+					set = append(set, expr)
+				}
+			}
+			sets = append(sets, set)
+		}
+	}
+
+	return &GroupTerm{
+		sets: sets,
+	}
+}
+
+/*
+GROUPING SETS((expr1, expr2, ...), ...) groups by each of the
+listed sets.
+*/
+func NewGroupingSetsTerm(sets []expression.Expressions) *GroupTerm {
+	return &GroupTerm{
+		sets: sets,
+	}
+}
+
 func (this *GroupTerm) MapExpression(mapper expression.Mapper) (err error) {
 	if this.expr != nil {
 		this.expr, err = mapper.Map(this.expr)
 	}
 
+	for _, set := range this.sets {
+		if err == nil {
+			err = set.MapExpressions(mapper)
+		}
+	}
+
 	return
 }
 
@@ -217,6 +400,24 @@ func (this *GroupTerm) String() string {
 
 	if this.expr != nil {
 		s = this.expr.String()
+	} else if this.sets != nil {
+		s = "grouping sets ("
+		for i, set := range this.sets {
+			if i > 0 {
+				s += ", "
+			}
+
+			s += "("
+			for j, expr := range set {
+				if j > 0 {
+					s += ", "
+				}
+
+				s += expr.String()
+			}
+			s += ")"
+		}
+		s += ")"
 	}
 
 	if this.as != "" {
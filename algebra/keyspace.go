@@ -35,6 +35,14 @@ func NewKeyspaceRef(namespace, keyspace, as string) *KeyspaceRef {
 	return &KeyspaceRef{NewPathShort(namespace, keyspace), as}
 }
 
+/*
+The function NewKeyspaceRefFromPath returns a pointer to a
+KeyspaceRef for a keyspace path, which can be a collection.
+*/
+func NewKeyspaceRefFromPath(path *Path, as string) *KeyspaceRef {
+	return &KeyspaceRef{path, as}
+}
+
 /*
 Qualify identifiers for the keyspace. It also makes sure that the
 keyspace term contains a name or alias.
@@ -75,6 +83,13 @@ func (this *KeyspaceRef) Keyspace() string {
 	return this.path.Keyspace()
 }
 
+/*
+Returns the keyspace path.
+*/
+func (this *KeyspaceRef) Path() *Path {
+	return this.path
+}
+
 /*
 Returns the AS alias string.
 */
@@ -109,7 +124,12 @@ func (this *KeyspaceRef) MarshalJSON() ([]byte, error) {
 
 /*
 Returns the full keyspace name, including the namespace.
+Collections use the privileges of their bucket, so for
+them this is the full bucket name.
 */
 func (this *KeyspaceRef) FullName() string {
+	if this.path.IsCollection() {
+		return NewPathShort(this.path.Namespace(), this.path.Bucket()).SimpleString()
+	}
 	return this.path.SimpleString()
 }
@@ -786,13 +786,14 @@ func (this *MergeDelete) Where() expression.Expression {
 
 /*
 Represents the merge insert merge actions statement.
-Type MergeInsert is a struct that contains the value
-and where condition expressions.
+Type MergeInsert is a struct that contains the key, value,
+options and where condition expressions.
 */
 type MergeInsert struct {
-	key   expression.Expression `json:"key"`
-	value expression.Expression `json:"value"`
-	where expression.Expression `json:"where"`
+	key     expression.Expression `json:"key"`
+	value   expression.Expression `json:"value"`
+	options expression.Expression `json:"options"`
+	where   expression.Expression `json:"where"`
 }
 
 /*
@@ -800,12 +801,12 @@ The function NewMergeInsert returns a pointer to the MergeInsert
 struct by assigning the input attributes to the fields of the
 struct.
 */
-func NewMergeInsert(key, value, where expression.Expression) *MergeInsert {
-	return &MergeInsert{key, value, where}
+func NewMergeInsert(key, value, options, where expression.Expression) *MergeInsert {
+	return &MergeInsert{key, value, options, where}
 }
 
 /*
-Apply mapper to value and where expressions.
+Apply mapper to key, value, options and where expressions.
 */
 func (this *MergeInsert) MapExpressions(mapper expression.Mapper) (err error) {
 	if this.key != nil {
@@ -822,6 +823,13 @@ func (this *MergeInsert) MapExpressions(mapper expression.Mapper) (err error) {
 		}
 	}
 
+	if this.options != nil {
+		this.options, err = mapper.Map(this.options)
+		if err != nil {
+			return
+		}
+	}
+
 	if this.where != nil {
 		this.where, err = mapper.Map(this.where)
 	}
@@ -833,7 +841,7 @@ func (this *MergeInsert) MapExpressions(mapper expression.Mapper) (err error) {
 Returns all contained Expressions.
 */
 func (this *MergeInsert) Expressions() expression.Expressions {
-	exprs := make(expression.Expressions, 0, 3)
+	exprs := make(expression.Expressions, 0, 4)
 
 	if this.key != nil {
 		exprs = append(exprs, this.key)
@@ -843,6 +851,10 @@ func (this *MergeInsert) Expressions() expression.Expressions {
 		exprs = append(exprs, this.value)
 	}
 
+	if this.options != nil {
+		exprs = append(exprs, this.options)
+	}
+
 	if this.where != nil {
 		exprs = append(exprs, this.where)
 	}
@@ -869,6 +881,13 @@ func (this *MergeInsert) Formalize(f *expression.Formalizer) (err error) {
 		}
 	}
 
+	if this.options != nil {
+		this.options, err = f.Map(this.options)
+		if err != nil {
+			return
+		}
+	}
+
 	if this.where != nil {
 		this.where, err = f.Map(this.where)
 	}
@@ -890,6 +909,13 @@ func (this *MergeInsert) Value() expression.Expression {
 	return this.value
 }
 
+/*
+Return the merge insert options expression.
+*/
+func (this *MergeInsert) Options() expression.Expression {
+	return this.options
+}
+
 /*
 Return the where clause exppression condition.
 */
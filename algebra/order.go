@@ -42,6 +42,16 @@ func (this *Order) Copy() *Order {
 	}
 }
 
+/*
+Copy Order, if any
+*/
+func CopyOrder(oby *Order) *Order {
+	if oby == nil {
+		return nil
+	}
+	return oby.Copy()
+}
+
 /*
 Map expressions for the terms by calling MapExpressions.
 */
@@ -24,11 +24,12 @@ type Pairs []*Pair
 
 /*
 Type Pair is a struct that contains key and value
-expressions.
+expressions, and an optional options expression.
 */
 type Pair struct {
-	Key   expression.Expression
-	Value expression.Expression
+	Key     expression.Expression
+	Value   expression.Expression
+	Options expression.Expression
 }
 
 func NewPair(key, value expression.Expression) *Pair {
@@ -49,7 +50,7 @@ func MapPairs(pairs Pairs) map[expression.Expression]expression.Expression {
 }
 
 /*
-Applies mapper to the key and value expressions.
+Applies mapper to the key, value and options expressions.
 */
 func (this *Pair) MapExpressions(mapper expression.Mapper) (err error) {
 	this.Key, err = mapper.Map(this.Key)
@@ -58,6 +59,13 @@ func (this *Pair) MapExpressions(mapper expression.Mapper) (err error) {
 	}
 
 	this.Value, err = mapper.Map(this.Value)
+	if err != nil {
+		return
+	}
+
+	if this.Options != nil {
+		this.Options, err = mapper.Map(this.Options)
+	}
 	return
 }
 
@@ -65,15 +73,18 @@ func (this *Pair) MapExpressions(mapper expression.Mapper) (err error) {
 Returns all contained Expressions.
 */
 func (this *Pair) Expressions() expression.Expressions {
+	if this.Options != nil {
+		return expression.Expressions{this.Key, this.Value, this.Options}
+	}
 	return expression.Expressions{this.Key, this.Value}
 }
 
 /*
 Creates and returns a new array construct containing
-the key value pair.
+the key value pair, and the options if present.
 */
 func (this *Pair) Expression() expression.Expression {
-	return expression.NewArrayConstruct(this.Key, this.Value)
+	return expression.NewArrayConstruct(this.Expressions()...)
 }
 
 /*
@@ -101,11 +112,10 @@ func (this Pairs) MapExpressions(mapper expression.Mapper) (err error) {
 Returns all contained Expressions.
 */
 func (this Pairs) Expressions() expression.Expressions {
-	exprs := make(expression.Expressions, len(this)*2)
+	exprs := make(expression.Expressions, 0, len(this)*2)
 
-	for i, pair := range this {
-		exprs[i*2] = pair.Key
-		exprs[i*2+1] = pair.Value
+	for _, pair := range this {
+		exprs = append(exprs, pair.Expressions()...)
 	}
 
 	return exprs
@@ -152,7 +162,8 @@ func NewValuesPairs(array *expression.ArrayConstruct) (pairs Pairs, err error) {
 
 /*
 Create a key value pair using the operands of the input
-expression Array construct and return.
+expression Array construct and return. A third operand,
+if present, holds the options.
 */
 func NewValuesPair(expr expression.Expression) (*Pair, error) {
 	array, ok := expr.(*expression.ArrayConstruct)
@@ -161,7 +172,7 @@ func NewValuesPair(expr expression.Expression) (*Pair, error) {
 	}
 
 	operands := array.Operands()
-	if len(operands) != 2 {
+	if len(operands) != 2 && len(operands) != 3 {
 		return nil, fmt.Errorf("Invalid VALUES expression %s", expr.String())
 	}
 
@@ -170,5 +181,9 @@ func NewValuesPair(expr expression.Expression) (*Pair, error) {
 		Value: operands[1],
 	}
 
+	if len(operands) == 3 {
+		pair.Options = operands[2]
+	}
+
 	return pair, nil
 }
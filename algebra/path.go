@@ -18,6 +18,8 @@ import (
 //    system:prepareds
 //    default:prepareds
 //    default:myBucket.myScope.myCollection
+// Scope DDL also uses paths to scopes:
+//    default:myBucket.myScope
 type Path struct {
 	elements                []string `json:"elements"`
 	firstElementIsNamespace bool     `json:"firstElementIsNamespace"`
@@ -38,6 +40,13 @@ func NewPathLong(namespace, bucket, scope, keyspace string) *Path {
 	}
 }
 
+func NewPathScope(namespace, bucket, scope string) *Path {
+	return &Path{
+		elements:                []string{namespace, bucket, scope},
+		firstElementIsNamespace: true,
+	}
+}
+
 // This isn't quite right, but it will do for now.
 func (path *Path) Namespace() string {
 	if path.firstElementIsNamespace {
@@ -48,7 +57,7 @@ func (path *Path) Namespace() string {
 }
 
 func (path *Path) Bucket() string {
-	if len(path.elements) == 4 {
+	if len(path.elements) >= 3 {
 		return path.elements[1]
 	} else {
 		return ""
@@ -56,7 +65,7 @@ func (path *Path) Bucket() string {
 }
 
 func (path *Path) Scope() string {
-	if len(path.elements) == 4 {
+	if len(path.elements) >= 3 {
 		return path.elements[2]
 	} else {
 		return ""
@@ -0,0 +1,116 @@
+//  Copyright (c) 2020 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package algebra
+
+import (
+	"encoding/json"
+
+	"github.com/couchbase/query/auth"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/value"
+)
+
+/*
+Represents the Create scope ddl statement. Type CreateScope is
+a struct that contains the path of the scope, namely its namespace, bucket and name.
+*/
+type CreateScope struct {
+	statementBase
+
+	path *Path
+}
+
+/*
+The function NewCreateScope returns a pointer to the
+CreateScope struct with the input argument values as fields.
+*/
+func NewCreateScope(path *Path) *CreateScope {
+	rv := &CreateScope{
+		path: path,
+	}
+
+	rv.stmt = rv
+	return rv
+}
+
+/*
+It calls the VisitCreateScope method by passing in the
+receiver and returns the interface. It is a visitor
+pattern.
+*/
+func (this *CreateScope) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitCreateScope(this)
+}
+
+/*
+Returns nil.
+*/
+func (this *CreateScope) Signature() value.Value {
+	return nil
+}
+
+/*
+Returns nil.
+*/
+func (this *CreateScope) Formalize() error {
+	return nil
+}
+
+/*
+Returns nil.
+*/
+func (this *CreateScope) MapExpressions(mapper expression.Mapper) error {
+	return nil
+}
+
+/*
+Returns all contained Expressions.
+*/
+func (this *CreateScope) Expressions() expression.Expressions {
+	return nil
+}
+
+/*
+Returns all required privileges.
+*/
+func (this *CreateScope) Privileges() (*auth.Privileges, errors.Error) {
+	privs := auth.NewPrivileges()
+	fullName := NewPathShort(this.path.Namespace(), this.path.Bucket()).SimpleString()
+	privs.Add(fullName, auth.PRIV_QUERY_MANAGE_SCOPES)
+	return privs, nil
+}
+
+/*
+Return the path of the scope.
+*/
+func (this *CreateScope) Path() *Path {
+	return this.path
+}
+
+/*
+Set the default namespace.
+*/
+func (this *CreateScope) SetDefaultNamespace(namespace string) {
+	this.path.SetDefaultNamespace(namespace)
+}
+
+/*
+Marshals input receiver into byte array.
+*/
+func (this *CreateScope) MarshalJSON() ([]byte, error) {
+	r := map[string]interface{}{"type": "createScope"}
+	r["path"] = this.path.SimpleString()
+	return json.Marshal(r)
+}
+
+func (this *CreateScope) Type() string {
+	return "CREATE_SCOPE"
+}
@@ -312,6 +312,55 @@ func (this *Select) SetCorrelated() {
 	this.correlated = true
 }
 
+/*
+Returns whether the statement carries a USE CACHE hint, ie
+its results can be served from the result cache. Only hints on
+the keyspaces of the outermost query block count, hints in
+subqueries are ignored.
+*/
+func (this *Select) UseCache() bool {
+	return subresultUseCache(this.subresult)
+}
+
+func subresultUseCache(subresult Subresult) bool {
+	switch subresult := subresult.(type) {
+	case *Subselect:
+		return subresult.From() != nil && fromUseCache(subresult.From())
+	case *SelectTerm:
+		return subresultUseCache(subresult.Select().Subresult())
+	case interface {
+		First() Subresult
+		Second() Subresult
+	}:
+		return subresultUseCache(subresult.First()) || subresultUseCache(subresult.Second())
+	}
+	return false
+}
+
+func fromUseCache(term FromTerm) bool {
+	switch term := term.(type) {
+	case *KeyspaceTerm:
+		return term.UseCache()
+	case *ExpressionTerm:
+		return term.IsKeyspace() && term.KeyspaceTerm().UseCache()
+	case *Join:
+		return fromUseCache(term.Left()) || fromUseCache(term.Right())
+	case *IndexJoin:
+		return fromUseCache(term.Left()) || fromUseCache(term.Right())
+	case *AnsiJoin:
+		return fromUseCache(term.Left()) || fromUseCache(term.Right())
+	case *Nest:
+		return fromUseCache(term.Left()) || fromUseCache(term.Right())
+	case *IndexNest:
+		return fromUseCache(term.Left()) || fromUseCache(term.Right())
+	case *AnsiNest:
+		return fromUseCache(term.Left()) || fromUseCache(term.Right())
+	case *Unnest:
+		return fromUseCache(term.Left())
+	}
+	return false
+}
+
 /*
 The Subresult interface represents the intermediate result of a
 select statement. It inherits from Node.
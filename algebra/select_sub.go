@@ -77,6 +77,18 @@ expressions and calls PushBindings for the let clause.
 func (this *Subselect) Formalize(parent *expression.Formalizer) (f *expression.Formalizer, err error) {
 	if this.with != nil {
 		f = expression.NewFormalizer("", parent)
+
+		// recursive terms refer to themselves from within their own subqueries
+		recursive := make(expression.Bindings, 0, len(this.with))
+		for _, b := range this.with {
+			if b.Recursive() {
+				recursive = append(recursive, b)
+			}
+		}
+		if len(recursive) > 0 {
+			f.SetPermanentWiths(recursive)
+		}
+
 		err = f.PushBindings(this.with, false)
 		if err != nil {
 			return nil, err
@@ -0,0 +1,190 @@
+//  Copyright (c) 2020 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package algebra
+
+import (
+	"encoding/json"
+
+	"github.com/couchbase/query/auth"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/value"
+)
+
+/*
+Transaction statements carry no expressions, and require no privileges
+of their own: the statements run within the transaction are checked
+as they execute.
+*/
+type transactionBase struct {
+	statementBase
+}
+
+func (this *transactionBase) Formalize() error {
+	return nil
+}
+
+func (this *transactionBase) MapExpressions(mapper expression.Mapper) error {
+	return nil
+}
+
+func (this *transactionBase) Expressions() expression.Expressions {
+	return nil
+}
+
+func (this *transactionBase) Privileges() (*auth.Privileges, errors.Error) {
+	return auth.NewPrivileges(), nil
+}
+
+/*
+Represents the BEGIN WORK / START TRANSACTION statement, which returns
+the id of the new transaction, to be passed as the txid request
+parameter of the statements that belong to it.
+*/
+type StartTransaction struct {
+	transactionBase
+}
+
+func NewStartTransaction() *StartTransaction {
+	rv := &StartTransaction{}
+	rv.stmt = rv
+	return rv
+}
+
+func (this *StartTransaction) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitStartTransaction(this)
+}
+
+func (this *StartTransaction) Signature() value.Value {
+	return value.NewValue(map[string]interface{}{
+		"txid": value.STRING.String(),
+	})
+}
+
+func (this *StartTransaction) MarshalJSON() ([]byte, error) {
+	r := map[string]interface{}{"type": "startTransaction"}
+	return json.Marshal(r)
+}
+
+func (this *StartTransaction) Type() string {
+	return "START_TRANSACTION"
+}
+
+/*
+Represents the COMMIT [WORK | TRANSACTION] statement.
+*/
+type CommitTransaction struct {
+	transactionBase
+}
+
+func NewCommitTransaction() *CommitTransaction {
+	rv := &CommitTransaction{}
+	rv.stmt = rv
+	return rv
+}
+
+func (this *CommitTransaction) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitCommitTransaction(this)
+}
+
+func (this *CommitTransaction) Signature() value.Value {
+	return nil
+}
+
+func (this *CommitTransaction) MarshalJSON() ([]byte, error) {
+	r := map[string]interface{}{"type": "commitTransaction"}
+	return json.Marshal(r)
+}
+
+func (this *CommitTransaction) Type() string {
+	return "COMMIT"
+}
+
+/*
+Represents the ROLLBACK [WORK | TRANSACTION] [TO SAVEPOINT name]
+statement. Without a savepoint the whole transaction is rolled back.
+*/
+type RollbackTransaction struct {
+	transactionBase
+
+	savepoint string `json:"savepoint"`
+}
+
+func NewRollbackTransaction(savepoint string) *RollbackTransaction {
+	rv := &RollbackTransaction{
+		savepoint: savepoint,
+	}
+	rv.stmt = rv
+	return rv
+}
+
+func (this *RollbackTransaction) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitRollbackTransaction(this)
+}
+
+func (this *RollbackTransaction) Signature() value.Value {
+	return nil
+}
+
+func (this *RollbackTransaction) Savepoint() string {
+	return this.savepoint
+}
+
+func (this *RollbackTransaction) MarshalJSON() ([]byte, error) {
+	r := map[string]interface{}{"type": "rollbackTransaction"}
+	if this.savepoint != "" {
+		r["savepoint"] = this.savepoint
+	}
+	return json.Marshal(r)
+}
+
+func (this *RollbackTransaction) Type() string {
+	return "ROLLBACK"
+}
+
+/*
+Represents the SAVEPOINT name statement. Setting an existing savepoint
+moves it to the current state of the transaction.
+*/
+type Savepoint struct {
+	transactionBase
+
+	name string `json:"name"`
+}
+
+func NewSavepoint(name string) *Savepoint {
+	rv := &Savepoint{
+		name: name,
+	}
+	rv.stmt = rv
+	return rv
+}
+
+func (this *Savepoint) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitSavepoint(this)
+}
+
+func (this *Savepoint) Signature() value.Value {
+	return nil
+}
+
+func (this *Savepoint) Name() string {
+	return this.name
+}
+
+func (this *Savepoint) MarshalJSON() ([]byte, error) {
+	r := map[string]interface{}{"type": "savepoint"}
+	r["name"] = this.name
+	return json.Marshal(r)
+}
+
+func (this *Savepoint) Type() string {
+	return "SAVEPOINT"
+}
@@ -171,6 +171,21 @@ func (this *SetTerm) Value() expression.Expression {
 	return this.value
 }
 
+/*
+Returns the name of the document meta data field set by the
+term, as in SET META().expiration = ..., or "" if the term
+sets a path of the document.
+*/
+func (this *SetTerm) MetaField() string {
+	if field, ok := this.path.(*expression.Field); ok {
+		if _, ok = field.First().(*expression.Meta); ok {
+			return field.Alias()
+		}
+	}
+
+	return ""
+}
+
 /*
 Returns the update-for clause in the SET clause.
 */
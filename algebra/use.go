@@ -28,10 +28,11 @@ type Use struct {
 	keys     expression.Expression
 	indexes  IndexRefs
 	joinHint JoinHint
+	cache    bool
 }
 
 func NewUse(keys expression.Expression, indexes IndexRefs, joinHint JoinHint) *Use {
-	return &Use{keys, indexes, joinHint}
+	return &Use{keys, indexes, joinHint, false}
 }
 
 func (this *Use) Keys() expression.Expression {
@@ -58,6 +59,14 @@ func (this *Use) SetJoinHint(joinHint JoinHint) {
 	this.joinHint = joinHint
 }
 
+func (this *Use) Cache() bool {
+	return this.cache
+}
+
+func (this *Use) SetCache(cache bool) {
+	this.cache = cache
+}
+
 // Hint Errors
 
 const HASH_JOIN_EE_ONLY = "HASH JOIN is not supported in Community Edition"
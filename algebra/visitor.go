@@ -37,6 +37,14 @@ type Visitor interface {
 	VisitAlterIndex(stmt *AlterIndex) (interface{}, error)
 	VisitBuildIndexes(stmt *BuildIndexes) (interface{}, error)
 
+	/*
+	   Visitor for scope and collection DDL statements.
+	*/
+	VisitCreateScope(stmt *CreateScope) (interface{}, error)
+	VisitDropScope(stmt *DropScope) (interface{}, error)
+	VisitCreateCollection(stmt *CreateCollection) (interface{}, error)
+	VisitDropCollection(stmt *DropCollection) (interface{}, error)
+
 	/*
 	   Visitor for ROLES statements.
 	*/
@@ -76,9 +84,18 @@ type Visitor interface {
 	VisitExecuteFunction(stmt *ExecuteFunction) (interface{}, error)
 
 	/*
-	   Visitor for UPDATE STATISTICS statements.
+	   Visitor for UPDATE STATISTICS and DELETE STATISTICS statements.
 	*/
 	VisitUpdateStatistics(stmt *UpdateStatistics) (interface{}, error)
+	VisitDeleteStatistics(stmt *DeleteStatistics) (interface{}, error)
+
+	/*
+	   Visitor for transaction statements.
+	*/
+	VisitStartTransaction(stmt *StartTransaction) (interface{}, error)
+	VisitCommitTransaction(stmt *CommitTransaction) (interface{}, error)
+	VisitRollbackTransaction(stmt *RollbackTransaction) (interface{}, error)
+	VisitSavepoint(stmt *Savepoint) (interface{}, error)
 }
 
 type NodeVisitor interface {
@@ -0,0 +1,140 @@
+//  Copyright (c) 2020 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package algebra
+
+import (
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/expression"
+)
+
+/*
+Represents a term of a WITH RECURSIVE clause of the form
+
+	alias AS (anchor UNION [ALL] recursive)
+
+where the recursive part refers to alias. The anchor is evaluated
+once, and the recursive part is then evaluated repeatedly, with alias
+bound to the documents produced by the previous iteration, until no
+more documents are produced. The results of the recursive part change
+with every iteration, so they must never be cached.
+*/
+type RecursiveWith struct {
+	binding   *expression.Binding
+	query     *Select
+	anchor    *Select
+	recursive *Select
+	all       bool
+}
+
+/*
+Returns the recursive form of a WITH binding, or nil if the binding
+is not recursive, i.e. it is not part of a WITH RECURSIVE clause or
+does not refer to itself. A binding that refers to itself, but is
+not of the form anchor UNION [ALL] recursive, is an error.
+*/
+func NewRecursiveWith(binding *expression.Binding) (*RecursiveWith, error) {
+	if !binding.Recursive() || !referencesVariable(binding.Expression(), binding.Variable()) {
+		return nil, nil
+	}
+
+	subq, ok := binding.Expression().(*Subquery)
+	if !ok {
+		return nil, errors.NewRecursiveWithSemanticError(binding.Variable(), "must be a subquery.",
+			"semantics.with.recursive_subquery")
+	}
+
+	query := subq.Select()
+	rv := &RecursiveWith{
+		binding: binding,
+		query:   query,
+	}
+
+	var first, second Subresult
+	switch union := query.Subresult().(type) {
+	case *Union:
+		first, second = union.First(), union.Second()
+	case *UnionAll:
+		first, second = union.First(), union.Second()
+		rv.all = true
+	default:
+		return nil, errors.NewRecursiveWithSemanticError(binding.Variable(), "must be of the form anchor UNION [ALL] recursive.",
+			"semantics.with.recursive_union")
+	}
+
+	if ReferencesVariable(first, binding.Variable()) {
+		return nil, errors.NewRecursiveWithSemanticError(binding.Variable(), "cannot refer to itself in its anchor.",
+			"semantics.with.recursive_anchor")
+	}
+
+	rv.anchor = NewSelect(first, nil, nil, nil)
+	rv.recursive = NewSelect(second, nil, nil, nil)
+	if query.IsCorrelated() {
+		rv.anchor.SetCorrelated()
+		rv.recursive.SetCorrelated()
+	}
+	return rv, nil
+}
+
+func (this *RecursiveWith) Binding() *expression.Binding {
+	return this.binding
+}
+
+func (this *RecursiveWith) Variable() string {
+	return this.binding.Variable()
+}
+
+/*
+The whole anchor UNION [ALL] recursive query.
+*/
+func (this *RecursiveWith) Query() *Select {
+	return this.query
+}
+
+func (this *RecursiveWith) Anchor() *Select {
+	return this.anchor
+}
+
+func (this *RecursiveWith) Recursive() *Select {
+	return this.recursive
+}
+
+/*
+UNION ALL keeps duplicates, UNION discards documents already produced,
+which also stops the recursion on cycles.
+*/
+func (this *RecursiveWith) All() bool {
+	return this.all
+}
+
+/*
+Returns true if any expression of the subresult refers to the variable.
+Subqueries nested in the subresult are not inspected.
+*/
+func ReferencesVariable(subresult Subresult, variable string) bool {
+	for _, expr := range subresult.Expressions() {
+		if referencesVariable(expr, variable) {
+			return true
+		}
+	}
+	return false
+}
+
+func referencesVariable(expr expression.Expression, variable string) bool {
+	if ident, ok := expr.(*expression.Identifier); ok && ident.Identifier() == variable {
+		return true
+	}
+
+	for _, child := range expr.Children() {
+		if child != nil && referencesVariable(child, variable) {
+			return true
+		}
+	}
+	return false
+}
@@ -30,6 +30,8 @@ const (
 //	PRIV_QUERY_CREATE_FUNCTION  Privilege = 17 // Ability to run CREATE FUNCTION statements.
 //	PRIV_QUERY_DROP_FUNCTION    Privilege = 18 // Ability to run DROP FUNCTION statements.
 //	PRIV_QUERY_EXECUTE_FUNCTION Privilege = 19 // Ability to run EXECUTE FUNCTION statements.
+	PRIV_QUERY_MANAGE_SCOPES             Privilege = 20 // Ability to run CREATE and DROP SCOPE and COLLECTION statements.
+	PRIV_QUERY_MANAGE_FUNCTIONS_EXTERNAL Privilege = 21 // Ability to add and remove javascript function libraries.
 )
 
 func IsStatementTypePrivilege(priv Privilege) bool {
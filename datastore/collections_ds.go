@@ -217,6 +217,22 @@ func (cb *CollectionsBucket) ScopeByName(name string) (Scope, errors.Error) {
 	return nil, errors.NewCbScopeNotFoundError(nil, name)
 }
 
+func (cb *CollectionsBucket) CreateScope(name string) errors.Error {
+	if _, ok := cb.scopes[name]; ok {
+		return errors.NewCbScopesDDLError(nil, "scope "+name+" already exists")
+	}
+	cb.AddScope(NewCollectionsScope(name))
+	return nil
+}
+
+func (cb *CollectionsBucket) DropScope(name string) errors.Error {
+	if _, ok := cb.scopes[name]; !ok {
+		return errors.NewCbScopeNotFoundError(nil, name)
+	}
+	delete(cb.scopes, name)
+	return nil
+}
+
 type CollectionsScope struct {
 	id     string
 	bucket *CollectionsBucket
@@ -290,6 +306,22 @@ func (cs *CollectionsScope) KeyspaceByName(name string) (Keyspace, errors.Error)
 	return nil, errors.NewCbKeyspaceNotFoundError(nil, name)
 }
 
+func (cs *CollectionsScope) CreateCollection(name string) errors.Error {
+	if _, ok := cs.keyspaces[name]; ok {
+		return errors.NewCbScopesDDLError(nil, "collection "+name+" already exists")
+	}
+	cs.AddKeyspace(NewCollectionsKeyspace(name))
+	return nil
+}
+
+func (cs *CollectionsScope) DropCollection(name string) errors.Error {
+	if _, ok := cs.keyspaces[name]; !ok {
+		return errors.NewCbKeyspaceNotFoundError(nil, name)
+	}
+	delete(cs.keyspaces, name)
+	return nil
+}
+
 type CollectionsKeyspace struct {
 	id        string
 	namespace *CollectionsNamespace
@@ -331,6 +363,13 @@ func (ks *CollectionsKeyspace) Name() string {
 	return ks.id
 }
 
+func (ks *CollectionsKeyspace) QualifiedName() string {
+	if ks.scope == nil {
+		return QualifiedKeyspaceName(ks.NamespaceId(), "", "", ks.id)
+	}
+	return QualifiedKeyspaceName(ks.scope.bucket.NamespaceId(), ks.scope.BucketId(), ks.scope.Id(), ks.id)
+}
+
 func (ks *CollectionsKeyspace) NamespaceId() string {
 	if ks.namespace == nil {
 		return ""
@@ -350,6 +389,9 @@ func (ks *CollectionsKeyspace) ScopeId() string {
 }
 
 func (ks *CollectionsKeyspace) Scope() Scope {
+	if ks.scope == nil {
+		return nil
+	}
 	return ks.scope
 }
 
@@ -73,6 +73,10 @@ func privilegeString(namespace, bucket string, requested auth.Privilege) (string
 		permission = joinStrings("cluster.bucket[", bucket, "].n1ql.index!list")
 	case auth.PRIV_QUERY_EXTERNAL_ACCESS:
 		permission = "cluster.n1ql.curl!execute"
+	case auth.PRIV_QUERY_MANAGE_SCOPES:
+		permission = joinStrings("cluster.bucket[", bucket, "].collections!write")
+	case auth.PRIV_QUERY_MANAGE_FUNCTIONS_EXTERNAL:
+		permission = "cluster.n1ql.udf_external!manage"
 	default:
 		return "", fmt.Errorf("Invalid Privileges")
 	}
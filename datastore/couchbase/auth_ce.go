@@ -57,6 +57,12 @@ func messageForDeniedPrivilege(pair auth.PrivilegePair) string {
 	case auth.PRIV_QUERY_EXTERNAL_ACCESS:
 		privilege = "queries using the CURL() function"
 		role = "admin"
+	case auth.PRIV_QUERY_MANAGE_SCOPES:
+		privilege = "scope and collection operations"
+		role = fmt.Sprintf("bucket_admin on %s", keyspace)
+	case auth.PRIV_QUERY_MANAGE_FUNCTIONS_EXTERNAL:
+		privilege = "javascript function library operations"
+		role = "admin"
 	default:
 		privilege = "this type of query"
 		role = "admin"
@@ -11,6 +11,11 @@ package couchbase
 
 import (
 	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
 
 	cb "github.com/couchbase/go-couchbase"
 	"github.com/couchbase/gomemcached"
@@ -21,10 +26,6 @@ import (
 	"github.com/couchbase/query/value"
 )
 
-var _COLLECTIONS_SUPPORTED bool = false
-
-var _COLLECTIONS_NOT_SUPPORTED string = "Collections are not yet supported."
-
 type scope struct {
 	id     string
 	bucket *keyspace
@@ -120,6 +121,13 @@ func (coll *collection) Name() string {
 	return coll.id
 }
 
+func (coll *collection) QualifiedName() string {
+	if coll.scope == nil {
+		return datastore.QualifiedKeyspaceName(coll.NamespaceId(), "", "", coll.id)
+	}
+	return datastore.QualifiedKeyspaceName(coll.scope.bucket.NamespaceId(), coll.scope.BucketId(), coll.scope.Id(), coll.id)
+}
+
 func (coll *collection) NamespaceId() string {
 	if coll.namespace == nil {
 		return ""
@@ -277,3 +285,70 @@ func buildScopesAndCollections(mani *cb.Manifest, bucket *keyspace) map[string]*
 	}
 	return scopes
 }
+
+// Scope and collection DDL goes through the cluster manager REST API, after
+// which the collections manifest of the bucket is reloaded.
+
+func (ks *keyspace) CreateScope(name string) errors.Error {
+	return ks.manifestRequest("POST", "", url.Values{"name": {name}})
+}
+
+func (ks *keyspace) DropScope(name string) errors.Error {
+	return ks.manifestRequest("DELETE", "/"+url.PathEscape(name), nil)
+}
+
+func (sc *scope) CreateCollection(name string) errors.Error {
+	return sc.bucket.manifestRequest("POST", "/"+url.PathEscape(sc.id), url.Values{"name": {name}})
+}
+
+func (sc *scope) DropCollection(name string) errors.Error {
+	return sc.bucket.manifestRequest("DELETE", "/"+url.PathEscape(sc.id)+"/"+url.PathEscape(name), nil)
+}
+
+func (ks *keyspace) manifestRequest(method, path string, form url.Values) errors.Error {
+	u := ks.namespace.store.connectionUrl + "/pools/default/buckets/" + url.PathEscape(ks.name) + "/collections" + path
+
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	}
+	req, err := http.NewRequest(method, u, body)
+	if err != nil {
+		return errors.NewCbScopesDDLError(err, "bucket "+ks.name)
+	}
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	// the client transport is wrapped by cbauth, which supplies the credentials
+	resp, err := cb.HTTPClient.Do(req)
+	if err != nil {
+		return errors.NewCbScopesDDLError(err, "bucket "+ks.name)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return errors.NewCbScopesDDLError(nil, fmt.Sprintf("bucket %s: %s %s", ks.name, resp.Status, msg))
+	}
+
+	ks.refreshScopes()
+	return nil
+}
+
+func (ks *keyspace) refreshScopes() {
+	mani, err := ks.cbbucket.GetCollectionsManifest()
+	if err != nil {
+		logging.Infof("Unable to retrieve collections info for bucket %s: %v", ks.name, err)
+		return
+	}
+	scopes := buildScopesAndCollections(mani, ks)
+	ks.scopesLock.Lock()
+	ks.collectionsManifestUid = uint32(mani.Uid)
+	ks.scopes = scopes
+	ks.scopesLock.Unlock()
+
+	// scopes and collections have changed, force full auto reprepare check
+	ks.namespace.nslock.Lock()
+	ks.namespace.version++
+	ks.namespace.nslock.Unlock()
+}
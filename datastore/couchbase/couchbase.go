@@ -852,30 +852,18 @@ func (p *namespace) MetadataVersion() uint64 {
 }
 
 func (p *namespace) BucketIds() ([]string, errors.Error) {
-	if !_COLLECTIONS_SUPPORTED {
-		return datastore.NO_STRINGS, nil
-	}
 	return p.KeyspaceIds()
 }
 
 func (p *namespace) BucketNames() ([]string, errors.Error) {
-	if !_COLLECTIONS_SUPPORTED {
-		return datastore.NO_STRINGS, nil
-	}
 	return p.KeyspaceNames()
 }
 
 func (p *namespace) BucketById(name string) (datastore.Bucket, errors.Error) {
-	if !_COLLECTIONS_SUPPORTED {
-		return nil, errors.NewNotImplemented(_COLLECTIONS_NOT_SUPPORTED)
-	}
 	return p.keyspaceByName(name)
 }
 
 func (p *namespace) BucketByName(name string) (datastore.Bucket, errors.Error) {
-	if !_COLLECTIONS_SUPPORTED {
-		return nil, errors.NewNotImplemented(_COLLECTIONS_NOT_SUPPORTED)
-	}
 	return p.keyspaceByName(name)
 }
 
@@ -1059,6 +1047,7 @@ type keyspace struct {
 	ftsIndexer  datastore.Indexer // FTS index provider
 	chkIndex    *chkIndexDict
 
+	scopesLock             sync.RWMutex // lock to guard the scopes and the manifest uid
 	collectionsManifestUid uint32
 	scopes                 map[string]*scope // scopes by id
 }
@@ -1105,15 +1094,14 @@ func newKeyspace(p *namespace, name string) (*keyspace, errors.Error) {
 	// Initialize index providers
 	rv.viewIndexer = newViewIndexer(rv)
 
+	// clusters that predate collections have no manifest, and the bucket no scopes
 	rv.scopes = _NO_SCOPES
-	if _COLLECTIONS_SUPPORTED {
-		mani, err := cbbucket.GetCollectionsManifest()
-		if err == nil {
-			rv.collectionsManifestUid = uint32(mani.Uid)
-			rv.scopes = buildScopesAndCollections(mani, rv)
-		} else {
-			logging.Infof("Unable to retrieve collections info for bucket %s: %v", name, err)
-		}
+	mani, err := cbbucket.GetCollectionsManifest()
+	if err == nil {
+		rv.collectionsManifestUid = uint32(mani.Uid)
+		rv.scopes = buildScopesAndCollections(mani, rv)
+	} else {
+		logging.Infof("Unable to retrieve collections info for bucket %s: %v", name, err)
 	}
 
 	logging.Infof("Created New Bucket %s", name)
@@ -1180,6 +1168,10 @@ func (b *keyspace) Name() string {
 	return b.name
 }
 
+func (b *keyspace) QualifiedName() string {
+	return datastore.QualifiedKeyspaceName(b.namespace.Id(), "", "", b.name)
+}
+
 func (b *keyspace) Count(context datastore.QueryContext) (int64, errors.Error) {
 	count, err := b.cbbucket.GetCount(true)
 	if err != nil {
@@ -1433,6 +1425,52 @@ func getSubDocFetchResults(k string, v *gomemcached.MCResponse, subPaths []strin
 	return val
 }
 
+// The expirations of the documents about to be updated that don't set one.
+// GETs do not return it, so the meta of the documents fetched without their
+// $document virtual xattr has none, and it is then looked up in the xattrs
+// of all of those documents at once
+func (b *keyspace) getExpirations(updates []value.Pair) (map[string]uint32, error) {
+	rv := make(map[string]uint32, len(updates))
+	keys := make([]string, 0, len(updates))
+	for _, kv := range updates {
+		if _, ok, _ := datastore.GetExpiration(kv.Options); ok {
+			continue
+		}
+		if an, ok := kv.Value.(value.AnnotatedValue); ok {
+			meta, _ := an.GetAttachment("meta").(map[string]interface{})
+			if exptime, _ := meta["expiration"].(uint32); exptime != 0 {
+				rv[kv.Name] = exptime
+				continue
+			}
+		}
+		keys = append(keys, kv.Name)
+	}
+	if len(keys) == 0 {
+		return rv, nil
+	}
+
+	subPaths := []string{"$document"}
+	bulkResponse, err := b.cbbucket.GetBulk(keys, time.Time{}, subPaths)
+	defer b.cbbucket.ReleaseGetBulkPools(bulkResponse)
+	if err != nil {
+		b.checkRefresh(err)
+
+		// missing documents fail the update later on
+		if !isNotFoundError(err) {
+			return nil, err
+		}
+	}
+	for k, v := range bulkResponse {
+		rv[k] = subDocExpiration(k, v, subPaths)
+	}
+	return rv, nil
+}
+
+func subDocExpiration(key string, mcr *gomemcached.MCResponse, subPaths []string) uint32 {
+	meta := getSubDocFetchResults(key, mcr, subPaths, true).GetAttachment("meta").(map[string]interface{})
+	return meta["expiration"].(uint32)
+}
+
 const (
 	INSERT = 0x01
 	UPDATE = 0x02
@@ -1499,19 +1537,32 @@ func (b *keyspace) performOp(op int, inserts []value.Pair) ([]value.Pair, errors
 	insertedKeys := make([]value.Pair, 0, len(inserts))
 	var err error
 
+	// updates preserve the document expiration, unless told otherwise
+	var expirations map[string]uint32
+	if op == UPDATE {
+		expirations, err = b.getExpirations(inserts)
+		if err != nil {
+			logging.Errorf("Failed to get expirations for Keyspace %s. Error - %v", b.Name(), err)
+			return nil, errors.NewCbDMLError(err, "Failed to perform "+opToString(op))
+		}
+	}
+
 	for _, kv := range inserts {
 		key := kv.Name
 		val := kv.Value.ActualForIndex()
+		exptime, hasExptime, expErr := datastore.GetExpiration(kv.Options)
+		if expErr != nil {
+			err = expErr
+			logging.Errorf("Failed to perform <ud>%s</ud> on key <ud>%s</ud> for Keyspace %s. Error - %v", opToString(op), key, b.Name(), err)
+			continue
+		}
 
-		//mv := kv.Value.GetAttachment("meta")
-
-		// TODO Need to also set meta
 		switch op {
 
 		case INSERT:
 			var added bool
 			// add the key to the backend
-			added, err = b.cbbucket.Add(key, 0, val)
+			added, err = b.cbbucket.Add(key, int(exptime), val)
 			b.checkRefresh(err)
 			if added == false {
 				// false & err == nil => given key aready exists in the bucket
@@ -1532,18 +1583,21 @@ func (b *keyspace) performOp(op int, inserts []value.Pair) ([]value.Pair, errors
 			meta = an.GetAttachment("meta").(map[string]interface{})
 
 			cas, flags, err = getMeta(key, meta)
+			if !hasExptime {
+				exptime = expirations[key]
+			}
 			if err != nil {
 				// Don't perform the update if the meta values are not found
 				logging.Errorf("Failed to get meta values for key <ud>%v</ud>, error %v", key, err)
 			} else {
 
 				logging.Debugf("CAS Value (Update) for key <ud>%v</ud> is %v flags <ud>%v</ud> value <ud>%v</ud>", key, uint64(cas), flags, val)
-				_, _, err = b.cbbucket.CasWithMeta(key, int(flags), 0, uint64(cas), val)
+				_, _, err = b.cbbucket.CasWithMeta(key, int(flags), int(exptime), uint64(cas), val)
 				b.checkRefresh(err)
 			}
 
 		case UPSERT:
-			err = b.cbbucket.Set(key, 0, val)
+			err = b.cbbucket.Set(key, int(exptime), val)
 			b.checkRefresh(err)
 		}
 
@@ -1652,6 +1706,8 @@ func (b *keyspace) ScopeId() string {
 }
 
 func (ks *keyspace) ScopeIds() ([]string, errors.Error) {
+	ks.scopesLock.RLock()
+	defer ks.scopesLock.RUnlock()
 	ids := make([]string, len(ks.scopes))
 	ix := 0
 	for k := range ks.scopes {
@@ -1662,6 +1718,8 @@ func (ks *keyspace) ScopeIds() ([]string, errors.Error) {
 }
 
 func (ks *keyspace) ScopeNames() ([]string, errors.Error) {
+	ks.scopesLock.RLock()
+	defer ks.scopesLock.RUnlock()
 	ids := make([]string, len(ks.scopes))
 	ix := 0
 	for _, v := range ks.scopes {
@@ -1672,7 +1730,9 @@ func (ks *keyspace) ScopeNames() ([]string, errors.Error) {
 }
 
 func (ks *keyspace) ScopeById(id string) (datastore.Scope, errors.Error) {
+	ks.scopesLock.RLock()
 	scope := ks.scopes[id]
+	ks.scopesLock.RUnlock()
 	if scope == nil {
 		return nil, errors.NewCbScopeNotFoundError(nil, id)
 	}
@@ -1680,6 +1740,8 @@ func (ks *keyspace) ScopeById(id string) (datastore.Scope, errors.Error) {
 }
 
 func (ks *keyspace) ScopeByName(name string) (datastore.Scope, errors.Error) {
+	ks.scopesLock.RLock()
+	defer ks.scopesLock.RUnlock()
 	for _, v := range ks.scopes {
 		if name == v.Name() {
 			return v, nil
@@ -17,11 +17,13 @@ package.
 package couchbase
 
 import (
+	"encoding/binary"
 	"fmt"
 	//"reflect"
 	"math"
 	"testing"
 
+	"github.com/couchbase/gomemcached"
 	"github.com/couchbase/query/datastore"
 	"github.com/couchbase/query/logging"
 	log_resolver "github.com/couchbase/query/logging/resolver"
@@ -134,3 +136,44 @@ func TestServer(t *testing.T) {
 		}
 	}
 }
+
+func TestUpdateExpiration(t *testing.T) {
+
+	// GETs return the flags, but not the expiration
+	extras := make([]byte, 4)
+	binary.BigEndian.PutUint32(extras, 0x02000006)
+	meta := doFetch("k1", &gomemcached.MCResponse{Extras: extras, Body: []byte(`{"a":1}`)}).GetAttachment("meta").(map[string]interface{})
+	if meta["flags"] != uint32(0x02000006) || meta["expiration"] != uint32(0) {
+		t.Errorf("unexpected meta %v", meta)
+	}
+
+	// the $document virtual xattr has it
+	body := subDocBody(`{"exptime":1893456000,"flags":33554438}`, `{"a":1}`)
+	exptime := subDocExpiration("k1", &gomemcached.MCResponse{Body: body}, []string{"$document"})
+	if exptime != 1893456000 {
+		t.Errorf("expected expiration 1893456000, got %v", exptime)
+	}
+
+	// it is only looked up when neither the fetched meta nor the options have one
+	b := &keyspace{}
+	doc := value.NewAnnotatedValue(map[string]interface{}{"a": 1})
+	doc.SetAttachment("meta", map[string]interface{}{"expiration": uint32(60)})
+	expirations, err := b.getExpirations([]value.Pair{
+		value.Pair{Name: "k1", Value: doc},
+		value.Pair{Name: "k2", Value: doc, Options: value.NewValue(map[string]interface{}{"expiration": 10})},
+	})
+	if err != nil || len(expirations) != 1 || expirations["k1"] != 60 {
+		t.Errorf("expected expiration 60 for k1 only, got %v, error %v", expirations, err)
+	}
+}
+
+// Body of a sub-document lookup response, made of status, length and value of each path
+func subDocBody(values ...string) []byte {
+	var body []byte
+	for _, v := range values {
+		header := make([]byte, 6)
+		binary.BigEndian.PutUint32(header[2:], uint32(len(v)))
+		body = append(append(body, header...), v...)
+	}
+	return body
+}
@@ -23,6 +23,7 @@ plans before query Beta / GA.
 package datastore
 
 import (
+	"fmt"
 	"net/http"
 
 	"github.com/couchbase/query/auth"
@@ -135,12 +136,41 @@ type Scope interface {
 	KeyspaceByName(name string) (Keyspace, errors.Error) // Find a keyspace in this scope using the keyspace's name
 }
 
+// Optional interface for buckets whose scopes can be created and dropped
+type ScopeManager interface {
+	CreateScope(name string) errors.Error // Create a scope in this bucket
+	DropScope(name string) errors.Error   // Drop a scope, and all its collections, from this bucket
+}
+
+// Optional interface for scopes whose collections can be created and dropped
+type CollectionManager interface {
+	CreateCollection(name string) errors.Error // Create a collection in this scope
+	DropCollection(name string) errors.Error   // Drop a collection from this scope
+}
+
+// Optional interface for datastores that keep the definitions of user defined functions
+// themselves, rather than in the cluster metadata store. Definitions are JSON documents
+// keyed by function key, kept alongside the sources of the javascript libraries
+// that the functions are defined in
+type FunctionsStorage interface {
+	FunctionKeys() ([]string, errors.Error)                 // Keys of all stored functions
+	GetFunction(key string) ([]byte, errors.Error)          // Definition of a function, nil if not found
+	AddFunction(key string, definition []byte) errors.Error // Store a function, failing if it exists
+	DeleteFunction(key string) errors.Error                 // Remove a function, failing if it does not exist
+
+	LibraryNames() ([]string, errors.Error)             // Names of all stored libraries
+	GetLibrary(name string) ([]byte, errors.Error)      // Source of a library, nil if not found
+	SetLibrary(name string, source []byte) errors.Error // Store a library, replacing any previous source
+	DeleteLibrary(name string) errors.Error             // Remove a library, failing if it does not exist
+}
+
 // Keyspace is a map of key-value entries (typically key-document, but
 // also key-counter, key-blob, etc.). Keys are unique within a
 // keyspace.
 type Keyspace interface {
-	Id() string   // Id of this keyspace
-	Name() string // Name of this keyspace
+	Id() string            // Id of this keyspace
+	Name() string          // Name of this keyspace
+	QualifiedName() string // Fully qualified path of this keyspace, see QualifiedKeyspaceName
 
 	// A keyspace is found either directly under a namespace or under a scope.
 	// If the keyspace is directly under a namespace, the ScopeId() returns "" and Scope() returns nil,
@@ -170,6 +200,17 @@ type Keyspace interface {
 	Release() // Release any resources held by this object
 }
 
+// QualifiedKeyspaceName returns the fully qualified path of a keyspace,
+// namespace:keyspace, or namespace:bucket.scope.keyspace for a keyspace
+// under a scope. Bucket and scope are empty for keyspaces directly under
+// a namespace.
+func QualifiedKeyspaceName(namespace, bucket, scope, keyspace string) string {
+	if bucket != "" {
+		return namespace + ":" + bucket + "." + scope + "." + keyspace
+	}
+	return namespace + ":" + keyspace
+}
+
 // Globally accessible Datastore instance
 var _DATASTORE Datastore
 var _SYSTEMSTORE Datastore
@@ -190,7 +231,42 @@ func GetSystemstore() Datastore {
 	return _SYSTEMSTORE
 }
 
-func GetKeyspace(namespace, keyspace string) (Keyspace, errors.Error) {
+// GetKeyspace finds a keyspace by path: either namespace and keyspace, or
+// namespace, bucket, scope and collection.
+func GetKeyspace(elems ...string) (Keyspace, errors.Error) {
+	if len(elems) != 2 && len(elems) != 4 {
+		return nil, errors.NewError(nil, fmt.Sprintf("Invalid keyspace path %v.", elems))
+	}
+
+	if len(elems) == 2 {
+		ns, err := getNamespace(elems[0])
+		if err != nil {
+			return nil, err
+		}
+		return ns.KeyspaceByName(elems[1])
+	}
+
+	bucket, err := GetBucket(elems[0], elems[1])
+	if err != nil {
+		return nil, err
+	}
+	scope, err := bucket.ScopeByName(elems[2])
+	if err != nil {
+		return nil, err
+	}
+	return scope.KeyspaceByName(elems[3])
+}
+
+// GetBucket finds a bucket by namespace and name.
+func GetBucket(namespace, bucket string) (Bucket, errors.Error) {
+	ns, err := getNamespace(namespace)
+	if err != nil {
+		return nil, err
+	}
+	return ns.BucketByName(bucket)
+}
+
+func getNamespace(namespace string) (Namespace, errors.Error) {
 	var datastore Datastore
 
 	if namespace == "#system" {
@@ -207,12 +283,7 @@ func GetKeyspace(namespace, keyspace string) (Keyspace, errors.Error) {
 		namespace = "default"
 	}
 
-	ns, err := datastore.NamespaceByName(namespace)
-	if err != nil {
-		return nil, err
-	}
-
-	return ns.KeyspaceByName(keyspace)
+	return datastore.NamespaceByName(namespace)
 }
 
 // These structures are generic representations of users and their roles.
@@ -0,0 +1,290 @@
+//  Copyright (c) 2020 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+)
+
+// Scopes and collections map onto the directory hierarchy: each keyspace
+// directory of a namespace is also a bucket, its subdirectories are scopes,
+// and the subdirectories of a scope are collections, which are keyspaces
+// like any other.
+
+// bucket is a file-based Bucket.
+type bucket struct {
+	sync.RWMutex
+	namespace *namespace
+	name      string
+	scopes    map[string]*scope
+}
+
+func (b *bucket) Id() string {
+	return b.Name()
+}
+
+func (b *bucket) Name() string {
+	return b.name
+}
+
+func (b *bucket) NamespaceId() string {
+	return b.namespace.Id()
+}
+
+func (b *bucket) Namespace() datastore.Namespace {
+	return b.namespace
+}
+
+func (b *bucket) ScopeIds() ([]string, errors.Error) {
+	return b.ScopeNames()
+}
+
+func (b *bucket) ScopeNames() ([]string, errors.Error) {
+	b.RLock()
+	defer b.RUnlock()
+	rv := make([]string, 0, len(b.scopes))
+	for _, s := range b.scopes {
+		rv = append(rv, s.name)
+	}
+	sort.Strings(rv)
+	return rv, nil
+}
+
+func (b *bucket) ScopeById(id string) (datastore.Scope, errors.Error) {
+	return b.ScopeByName(id)
+}
+
+func (b *bucket) ScopeByName(name string) (datastore.Scope, errors.Error) {
+	b.RLock()
+	defer b.RUnlock()
+	s, ok := b.scopes[strings.ToUpper(name)]
+	if !ok {
+		return nil, errors.NewFileScopeNotFoundError(nil, b.name+"."+name)
+	}
+	return s, nil
+}
+
+func (b *bucket) CreateScope(name string) errors.Error {
+	if e := checkName(name); e != nil {
+		return e
+	}
+
+	b.Lock()
+	defer b.Unlock()
+	nameu := strings.ToUpper(name)
+	if _, ok := b.scopes[nameu]; ok {
+		return errors.NewFileDuplicateScopeError(nil, b.name+"."+name)
+	}
+
+	if er := os.Mkdir(filepath.Join(b.path(), name), 0777); er != nil {
+		return errors.NewFileDatastoreError(er, "")
+	}
+	s, e := newScope(b, name)
+	if e != nil {
+		return e
+	}
+	b.scopes[nameu] = s
+	return nil
+}
+
+func (b *bucket) DropScope(name string) errors.Error {
+	b.Lock()
+	defer b.Unlock()
+	nameu := strings.ToUpper(name)
+	s, ok := b.scopes[nameu]
+	if !ok {
+		return errors.NewFileScopeNotFoundError(nil, b.name+"."+name)
+	}
+
+	// the collections, and their index and expiration files, are all within the scope
+	if er := os.RemoveAll(s.path()); er != nil {
+		return errors.NewFileDatastoreError(er, "")
+	}
+	delete(b.scopes, nameu)
+	return nil
+}
+
+func (b *bucket) path() string {
+	return filepath.Join(b.namespace.path(), b.name)
+}
+
+// newBucket creates a new bucket from a keyspace directory.
+func newBucket(p *namespace, dir string) (b *bucket, e errors.Error) {
+	b = new(bucket)
+	b.namespace = p
+	b.name = dir
+
+	dirEntries, er := ioutil.ReadDir(b.path())
+	if er != nil {
+		return nil, errors.NewFileDatastoreError(er, "")
+	}
+
+	b.scopes = make(map[string]*scope)
+	for _, dirEntry := range dirEntries {
+		if dirEntry.IsDir() {
+			diru := strings.ToUpper(dirEntry.Name())
+			if _, ok := b.scopes[diru]; ok {
+				return nil, errors.NewFileDuplicateScopeError(nil, b.name+"."+dirEntry.Name())
+			}
+
+			s, e := newScope(b, dirEntry.Name())
+			if e != nil {
+				return nil, e
+			}
+			b.scopes[diru] = s
+		}
+	}
+	return
+}
+
+// scope is a file-based Scope.
+type scope struct {
+	sync.RWMutex
+	bucket    *bucket
+	name      string
+	keyspaces map[string]*keyspace
+}
+
+func (s *scope) Id() string {
+	return s.Name()
+}
+
+func (s *scope) Name() string {
+	return s.name
+}
+
+func (s *scope) BucketId() string {
+	return s.bucket.Id()
+}
+
+func (s *scope) Bucket() datastore.Bucket {
+	return s.bucket
+}
+
+func (s *scope) KeyspaceIds() ([]string, errors.Error) {
+	return s.KeyspaceNames()
+}
+
+func (s *scope) KeyspaceNames() ([]string, errors.Error) {
+	s.RLock()
+	defer s.RUnlock()
+	rv := make([]string, 0, len(s.keyspaces))
+	for _, ks := range s.keyspaces {
+		rv = append(rv, ks.name)
+	}
+	sort.Strings(rv)
+	return rv, nil
+}
+
+func (s *scope) KeyspaceById(id string) (datastore.Keyspace, errors.Error) {
+	return s.KeyspaceByName(id)
+}
+
+func (s *scope) KeyspaceByName(name string) (datastore.Keyspace, errors.Error) {
+	s.RLock()
+	defer s.RUnlock()
+	ks, ok := s.keyspaces[strings.ToUpper(name)]
+	if !ok {
+		return nil, errors.NewFileKeyspaceNotFoundError(nil, s.bucket.name+"."+s.name+"."+name)
+	}
+	return ks, nil
+}
+
+func (s *scope) CreateCollection(name string) errors.Error {
+	if e := checkName(name); e != nil {
+		return e
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	nameu := strings.ToUpper(name)
+	if _, ok := s.keyspaces[nameu]; ok {
+		return errors.NewFileDuplicateKeyspaceError(nil, s.bucket.name+"."+s.name+"."+name)
+	}
+
+	if er := os.Mkdir(filepath.Join(s.path(), name), 0777); er != nil {
+		return errors.NewFileDatastoreError(er, "")
+	}
+	ks, e := newKeyspace(s.bucket.namespace, s, name)
+	if e != nil {
+		return e
+	}
+	s.keyspaces[nameu] = ks
+	return nil
+}
+
+func (s *scope) DropCollection(name string) errors.Error {
+	s.Lock()
+	defer s.Unlock()
+	nameu := strings.ToUpper(name)
+	ks, ok := s.keyspaces[nameu]
+	if !ok {
+		return errors.NewFileKeyspaceNotFoundError(nil, s.bucket.name+"."+s.name+"."+name)
+	}
+
+	ks.fileLock.Lock()
+	defer ks.fileLock.Unlock()
+	for _, path := range []string{ks.path(), ks.fi.indexesPath(), ks.expirationsPath()} {
+		if er := os.RemoveAll(path); er != nil {
+			return errors.NewFileDatastoreError(er, "")
+		}
+	}
+	delete(s.keyspaces, nameu)
+	return nil
+}
+
+func (s *scope) path() string {
+	return filepath.Join(s.bucket.path(), s.name)
+}
+
+// newScope creates a new scope from a directory within a bucket.
+func newScope(b *bucket, dir string) (s *scope, e errors.Error) {
+	s = new(scope)
+	s.bucket = b
+	s.name = dir
+
+	dirEntries, er := ioutil.ReadDir(s.path())
+	if er != nil {
+		return nil, errors.NewFileDatastoreError(er, "")
+	}
+
+	s.keyspaces = make(map[string]*keyspace)
+	for _, dirEntry := range dirEntries {
+		if dirEntry.IsDir() {
+			diru := strings.ToUpper(dirEntry.Name())
+			if _, ok := s.keyspaces[diru]; ok {
+				return nil, errors.NewFileDuplicateKeyspaceError(nil, s.bucket.name+"."+s.name+"."+dirEntry.Name())
+			}
+
+			ks, e := newKeyspace(b.namespace, s, dirEntry.Name())
+			if e != nil {
+				return nil, e
+			}
+			s.keyspaces[diru] = ks
+		}
+	}
+	return
+}
+
+// scope and collection names become directory names
+func checkName(name string) errors.Error {
+	if name == "" || strings.HasPrefix(name, ".") || strings.ContainsAny(name, "/\\") {
+		return errors.NewFileDatastoreError(nil, "Invalid scope or collection name "+name)
+	}
+	return nil
+}
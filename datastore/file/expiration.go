@@ -0,0 +1,144 @@
+//  Copyright (c) 2020 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package file
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/value"
+)
+
+// Document expirations are kept, like index definitions, in a file next to
+// the keyspace directory, as absolute unix times in seconds.
+// Scans skip expired documents, which are removed when they are next read
+// or counted.
+const _EXPIRATION_FILE_SUFFIX = ".expirations.json"
+
+func (b *keyspace) expirationsPath() string {
+	return filepath.Join(b.parentPath(), b.name+_EXPIRATION_FILE_SUFFIX)
+}
+
+func (b *keyspace) loadExpirations() errors.Error {
+	b.expirations = make(map[string]uint32)
+	bytes, er := ioutil.ReadFile(b.expirationsPath())
+	if er != nil {
+		if os.IsNotExist(er) {
+			return nil
+		}
+		return errors.NewFileDatastoreError(er, "")
+	}
+
+	er = json.Unmarshal(bytes, &b.expirations)
+	if er != nil {
+		return errors.NewFileDatastoreError(er, "Invalid expirations "+b.expirationsPath())
+	}
+	return nil
+}
+
+// saveExpirations must be called with the expirations locked
+func (b *keyspace) saveExpirations() error {
+	path := b.expirationsPath()
+	if len(b.expirations) == 0 {
+		if er := os.Remove(path); er != nil && !os.IsNotExist(er) {
+			return er
+		}
+		return nil
+	}
+
+	bytes, er := json.Marshal(b.expirations)
+	if er != nil {
+		return er
+	}
+	return ioutil.WriteFile(path, bytes, 0666)
+}
+
+// expiration returns the absolute expiration of a document, 0 if none
+func (b *keyspace) expiration(key string) uint32 {
+	b.expLock.Lock()
+	defer b.expLock.Unlock()
+	return b.expirations[key]
+}
+
+func (b *keyspace) expired(key string) bool {
+	exp := b.expiration(key)
+	return exp != 0 && int64(exp) <= time.Now().Unix()
+}
+
+// setExpiration sets the expiration of a document from the write options,
+// as returned by datastore.GetExpiration: updates keep the current expiration
+// unless the options set one, other writes clear it.
+func (b *keyspace) setExpiration(key string, exp uint32, set bool, update bool) error {
+	if !set && update {
+		return nil
+	}
+
+	var abs uint32
+	if t := datastore.ExpirationTime(exp, time.Now()); !t.IsZero() {
+		abs = uint32(t.Unix())
+	}
+	return b.writeExpiration(key, abs)
+}
+
+func (b *keyspace) writeExpiration(key string, abs uint32) error {
+	b.expLock.Lock()
+	defer b.expLock.Unlock()
+	if b.expirations[key] == abs {
+		return nil
+	}
+	if abs == 0 {
+		delete(b.expirations, key)
+	} else {
+		b.expirations[key] = abs
+	}
+	return b.saveExpirations()
+}
+
+// expire removes a document if it has expired.
+// The caller must hold the keyspace lock.
+func (b *keyspace) expire(key string) error {
+	if !b.expired(key) {
+		return nil
+	}
+	if err := b.writeDoc(key, nil); err != nil {
+		return err
+	}
+	return b.writeExpiration(key, 0)
+}
+
+// expireAll removes all the expired documents.
+// The caller must hold the keyspace lock.
+func (b *keyspace) expireAll() error {
+	now := time.Now().Unix()
+	keys := make([]string, 0)
+	b.expLock.Lock()
+	for key, exp := range b.expirations {
+		if int64(exp) <= now {
+			keys = append(keys, key)
+		}
+	}
+	b.expLock.Unlock()
+
+	for _, key := range keys {
+		if err := b.expire(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func expirationOptions(abs uint32) value.Value {
+	return value.NewValue(map[string]interface{}{datastore.OPT_EXPIRATION: abs})
+}
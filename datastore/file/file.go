@@ -8,10 +8,8 @@
 //  and limitations under the License.
 
 /*
-
 Package file provides a file-based implementation of the datastore
 package.
-
 */
 package file
 
@@ -31,17 +29,21 @@ import (
 	"github.com/couchbase/query/errors"
 	"github.com/couchbase/query/expression"
 	"github.com/couchbase/query/logging"
+	"github.com/couchbase/query/statistics"
 	"github.com/couchbase/query/timestamp"
 	"github.com/couchbase/query/util"
 	"github.com/couchbase/query/value"
 )
 
+const _STATISTICS_FILE = "statistics.json"
+
 // datastore is the root for the file-based Datastore.
 type store struct {
 	path           string
 	namespaces     map[string]*namespace
 	namespaceNames []string
-	inferencer     datastore.Inferencer // what we use to infer schemas
+	inferencer     datastore.Inferencer           // what we use to infer schemas
+	statUpdater    *statistics.DefaultStatUpdater // what we use to update statistics
 
 	users map[string]*datastore.User
 }
@@ -116,7 +118,7 @@ func (s *store) Inferencers() ([]datastore.Inferencer, errors.Error) {
 }
 
 func (s *store) StatUpdater() (datastore.StatUpdater, errors.Error) {
-	return nil, errors.NewOtherNotImplementedError(nil, "UPDATE STATISTICS")
+	return s.statUpdater, nil
 }
 
 func (s *store) SetConnectionSecurityConfig(conSecConfig *datastore.ConnectionSecurityConfig) {
@@ -180,6 +182,13 @@ func NewDatastore(path string) (s datastore.Datastore, e errors.Error) {
 		return nil, err
 	}
 
+	// statistics are kept next to the namespaces, and are what the optimizer uses
+	fs.statUpdater, err = statistics.NewDefaultStatUpdater(fs, filepath.Join(path, _STATISTICS_FILE))
+	if err != nil {
+		return nil, err
+	}
+	datastore.SetDictionary(fs.statUpdater)
+
 	s = fs
 	return
 }
@@ -195,7 +204,7 @@ func (s *store) loadNamespaces() (e errors.Error) {
 
 	var p *namespace
 	for _, dirEntry := range dirEntries {
-		if dirEntry.IsDir() {
+		if dirEntry.IsDir() && dirEntry.Name() != _FUNCTIONS_DIR {
 			s.namespaceNames = append(s.namespaceNames, dirEntry.Name())
 			diru := strings.ToUpper(dirEntry.Name())
 			if _, ok := s.namespaces[diru]; ok {
@@ -220,6 +229,7 @@ type namespace struct {
 	name          string
 	keyspaces     map[string]*keyspace
 	keyspaceNames []string
+	buckets       map[string]*bucket
 }
 
 func (p *namespace) DatastoreId() string {
@@ -285,6 +295,7 @@ func (p *namespace) loadKeyspaces() (e errors.Error) {
 
 	p.keyspaces = make(map[string]*keyspace, len(dirEntries))
 	p.keyspaceNames = make([]string, 0, len(dirEntries))
+	p.buckets = make(map[string]*bucket, len(dirEntries))
 
 	var b *keyspace
 	for _, dirEntry := range dirEntries {
@@ -294,13 +305,19 @@ func (p *namespace) loadKeyspaces() (e errors.Error) {
 				return errors.NewFileDuplicateKeyspaceError(nil, dirEntry.Name())
 			}
 
-			b, e = newKeyspace(p, dirEntry.Name())
+			b, e = newKeyspace(p, nil, dirEntry.Name())
 			if e != nil {
 				return
 			}
 
 			p.keyspaces[diru] = b
 			p.keyspaceNames = append(p.keyspaceNames, b.Name())
+
+			// every keyspace directory is also a bucket for the scopes below it
+			p.buckets[diru], e = newBucket(p, dirEntry.Name())
+			if e != nil {
+				return
+			}
 		}
 	}
 
@@ -308,27 +325,34 @@ func (p *namespace) loadKeyspaces() (e errors.Error) {
 }
 
 func (p *namespace) BucketIds() ([]string, errors.Error) {
-	return datastore.NO_STRINGS, nil
+	return p.BucketNames()
 }
 
 func (p *namespace) BucketNames() ([]string, errors.Error) {
-	return datastore.NO_STRINGS, nil
+	return p.keyspaceNames, nil
 }
 
 func (p *namespace) BucketById(name string) (datastore.Bucket, errors.Error) {
-	return nil, errors.NewOtherNoBuckets("file")
+	return p.BucketByName(name)
 }
 
 func (p *namespace) BucketByName(name string) (datastore.Bucket, errors.Error) {
-	return nil, errors.NewOtherNoBuckets("file")
+	b, ok := p.buckets[strings.ToUpper(name)]
+	if !ok {
+		return nil, errors.NewFileBucketNotFoundError(nil, name)
+	}
+	return b, nil
 }
 
 // keyspace is a file-based keyspace.
 type keyspace struct {
-	namespace *namespace
-	name      string
-	fi        datastore.Indexer
-	fileLock  sync.Mutex
+	namespace   *namespace
+	scope       *scope
+	name        string
+	fi          *fileIndexer
+	fileLock    sync.Mutex
+	expLock     sync.Mutex
+	expirations map[string]uint32
 }
 
 func (b *keyspace) NamespaceId() string {
@@ -347,20 +371,46 @@ func (b *keyspace) Name() string {
 	return b.name
 }
 
+func (b *keyspace) QualifiedName() string {
+	if b.scope == nil {
+		return datastore.QualifiedKeyspaceName(b.namespace.Name(), "", "", b.name)
+	}
+	return datastore.QualifiedKeyspaceName(b.namespace.Name(), b.scope.BucketId(), b.scope.Name(), b.name)
+}
+
 func (b *keyspace) Scope() datastore.Scope {
-	return nil
+	if b.scope == nil {
+		return nil
+	}
+	return b.scope
 }
 
 func (b *keyspace) ScopeId() string {
-	return ""
+	if b.scope == nil {
+		return ""
+	}
+	return b.scope.Id()
 }
 
 func (b *keyspace) Count(context datastore.QueryContext) (int64, errors.Error) {
+	b.fileLock.Lock()
+	er := b.expireAll()
+	b.fileLock.Unlock()
+	if er != nil {
+		return 0, errors.NewFileDatastoreError(er, "")
+	}
+
 	dirEntries, er := ioutil.ReadDir(b.path())
 	if er != nil {
 		return 0, errors.NewFileDatastoreError(er, "")
 	}
-	return int64(len(dirEntries)), nil
+	var count int64
+	for _, ent := range dirEntries {
+		if !ent.IsDir() {
+			count++
+		}
+	}
+	return count, nil
 }
 
 func (b *keyspace) Size(context datastore.QueryContext) (int64, errors.Error) {
@@ -370,7 +420,9 @@ func (b *keyspace) Size(context datastore.QueryContext) (int64, errors.Error) {
 	}
 	var size int64
 	for _, ent := range dirEntries {
-		size += ent.Size()
+		if !ent.IsDir() {
+			size += ent.Size()
+		}
 	}
 	return size, nil
 }
@@ -388,6 +440,16 @@ func (b *keyspace) Fetch(keys []string, keysMap map[string]value.AnnotatedValue,
 	var errs []errors.Error
 
 	for _, k := range keys {
+		if b.expired(k) {
+			b.fileLock.Lock()
+			er := b.expire(k)
+			b.fileLock.Unlock()
+			if er != nil {
+				errs = append(errs, errors.NewFileDatastoreError(er, ""))
+			}
+			continue
+		}
+
 		item, e := b.fetchOne(k)
 
 		if e != nil {
@@ -404,7 +466,8 @@ func (b *keyspace) Fetch(keys []string, keysMap map[string]value.AnnotatedValue,
 
 		if item != nil {
 			item.SetAttachment("meta", map[string]interface{}{
-				"id": k,
+				"id":         k,
+				"expiration": b.expiration(k),
 			})
 			item.SetId(k)
 		}
@@ -466,6 +529,17 @@ func (b *keyspace) performOp(op int, kvPairs []value.Pair) ([]value.Pair, errors
 		value, _ := json.Marshal(kv.Value.Actual())
 		filename := filepath.Join(b.path(), key+".json")
 
+		exp, setExp, expErr := datastore.GetExpiration(kv.Options)
+		if expErr != nil {
+			returnErr = errors.NewFileDMLError(expErr, opToString(op)+" Failed "+expErr.Error())
+			continue
+		}
+
+		if err = b.expire(key); err != nil {
+			returnErr = errors.NewFileDMLError(returnErr, opToString(op)+" Failed "+err.Error())
+			continue
+		}
+
 		switch op {
 
 		case INSERT:
@@ -497,10 +571,15 @@ func (b *keyspace) performOp(op int, kvPairs []value.Pair) ([]value.Pair, errors
 			}
 		}
 
+		if err == nil {
+			err = b.setExpiration(key, exp, setExp, op == UPDATE)
+		}
+
 		if err != nil {
 			returnErr = errors.NewFileDMLError(returnErr, opToString(op)+" Failed "+err.Error())
 		} else {
 			insertedKeys = append(insertedKeys, kv)
+			b.fi.mutate(key, value)
 		}
 	}
 
@@ -532,6 +611,10 @@ func (b *keyspace) Delete(deletes []string, context datastore.QueryContext) ([]s
 			}
 		} else {
 			deleted = append(deleted, key)
+			b.fi.mutate(key, nil)
+			if err = b.writeExpiration(key, 0); err != nil {
+				fileError = append(fileError, err.Error())
+			}
 		}
 	}
 
@@ -543,17 +626,117 @@ func (b *keyspace) Delete(deletes []string, context datastore.QueryContext) ([]s
 	return deleted, nil
 }
 
+// ApplyMutations applies the mutations of a committing transaction under the
+// keyspace lock. All the preconditions are checked before anything is written,
+// and the documents already written are restored should a later write fail.
+func (b *keyspace) ApplyMutations(mutations datastore.Mutations) (datastore.Mutations, errors.Error) {
+	b.fileLock.Lock()
+	defer b.fileLock.Unlock()
+
+	before := make([][]byte, len(mutations))
+	beforeExp := make([]uint32, len(mutations))
+	exps := make([]uint32, len(mutations))
+	setExps := make([]bool, len(mutations))
+	for i, m := range mutations {
+		var expErr errors.Error
+		exps[i], setExps[i], expErr = datastore.GetExpiration(m.Options)
+		if expErr != nil {
+			return nil, expErr
+		}
+		if err := b.expire(m.Key); err != nil {
+			return nil, errors.NewFileDatastoreError(err, "")
+		}
+		beforeExp[i] = b.expiration(m.Key)
+		bytes, err := ioutil.ReadFile(filepath.Join(b.path(), m.Key+".json"))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, errors.NewFileDatastoreError(err, "")
+		}
+		if (err == nil) == (m.Op == datastore.MUTATE_INSERT) {
+			return nil, errors.NewTransactionConflictError(b.name, m.Key, m.Op.String())
+		}
+		before[i] = bytes
+	}
+
+	for i, m := range mutations {
+		var bytes []byte
+		if m.Value != nil {
+			bytes, _ = json.Marshal(m.Value.Actual())
+		}
+		err := b.writeDoc(m.Key, bytes)
+		if err == nil {
+			if bytes != nil {
+				err = b.setExpiration(m.Key, exps[i], setExps[i], m.Op == datastore.MUTATE_UPDATE)
+			} else {
+				err = b.writeExpiration(m.Key, 0)
+			}
+		}
+		if err != nil {
+			for j := i; j >= 0; j-- {
+				b.writeDoc(mutations[j].Key, before[j])
+				b.writeExpiration(mutations[j].Key, beforeExp[j])
+			}
+			return nil, errors.NewFileDMLError(err, m.Op.String()+" Failed "+err.Error())
+		}
+	}
+
+	undo := make(datastore.Mutations, len(mutations))
+	for i, m := range mutations {
+		undo[i].Key = m.Key
+		switch m.Op {
+		case datastore.MUTATE_INSERT:
+			undo[i].Op = datastore.MUTATE_DELETE
+		case datastore.MUTATE_UPDATE:
+			undo[i].Op = datastore.MUTATE_UPDATE
+			undo[i].Value = value.NewValue(before[i])
+			undo[i].Options = expirationOptions(beforeExp[i])
+		case datastore.MUTATE_DELETE:
+			undo[i].Op = datastore.MUTATE_INSERT
+			undo[i].Value = value.NewValue(before[i])
+			undo[i].Options = expirationOptions(beforeExp[i])
+		}
+	}
+	return undo, nil
+}
+
+// writeDoc replaces the document with the given bytes, or removes it if
+// there are none, and maintains the secondary indexes.
+// The caller must hold the keyspace lock.
+func (b *keyspace) writeDoc(key string, bytes []byte) error {
+	filename := filepath.Join(b.path(), key+".json")
+	if bytes == nil {
+		err := os.Remove(filename)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	} else if err := ioutil.WriteFile(filename, bytes, 0666); err != nil {
+		return err
+	}
+	b.fi.mutate(key, bytes)
+	return nil
+}
+
 func (b *keyspace) Release() {
 }
 
 func (b *keyspace) path() string {
-	return filepath.Join(b.namespace.path(), b.name)
+	return filepath.Join(b.parentPath(), b.name)
+}
+
+// parentPath is the directory of the keyspace directory and of its
+// index and expiration files: the namespace's, or the scope's for a
+// collection.
+func (b *keyspace) parentPath() string {
+	if b.scope != nil {
+		return b.scope.path()
+	}
+	return b.namespace.path()
 }
 
-// newKeyspace creates a new keyspace.
-func newKeyspace(p *namespace, dir string) (b *keyspace, e errors.Error) {
+// newKeyspace creates a new keyspace, in a scope if one is given.
+func newKeyspace(p *namespace, s *scope, dir string) (b *keyspace, e errors.Error) {
 	b = new(keyspace)
 	b.namespace = p
+	b.scope = s
 	b.name = dir
 
 	fi, er := os.Stat(b.path())
@@ -565,23 +748,37 @@ func newKeyspace(p *namespace, dir string) (b *keyspace, e errors.Error) {
 		return nil, errors.NewFileKeyspaceNotDirError(nil, "Keyspace path "+dir)
 	}
 
+	e = b.loadExpirations()
+	if e != nil {
+		return nil, e
+	}
+
 	b.fi = newFileIndexer(b)
 	b.fi.CreatePrimaryIndex("", "#primary", nil)
 
+	e = b.fi.loadIndexes()
+	if e != nil {
+		return nil, e
+	}
+
 	return
 }
 
 type fileIndexer struct {
-	keyspace *keyspace
-	indexes  map[string]datastore.Index
-	primary  datastore.PrimaryIndex
+	sync.RWMutex
+	keyspace  *keyspace
+	indexes   map[string]datastore.Index
+	primary   datastore.PrimaryIndex
+	secondary map[string]*secondaryIndex
+	version   uint64
 }
 
-func newFileIndexer(keyspace *keyspace) datastore.Indexer {
+func newFileIndexer(keyspace *keyspace) *fileIndexer {
 
 	return &fileIndexer{
-		keyspace: keyspace,
-		indexes:  make(map[string]datastore.Index),
+		keyspace:  keyspace,
+		indexes:   make(map[string]datastore.Index),
+		secondary: make(map[string]*secondaryIndex),
 	}
 }
 
@@ -594,6 +791,8 @@ func (fi *fileIndexer) Name() datastore.IndexType {
 }
 
 func (fi *fileIndexer) IndexIds() ([]string, errors.Error) {
+	fi.RLock()
+	defer fi.RUnlock()
 	rv := make([]string, 0, len(fi.indexes))
 	for name, _ := range fi.indexes {
 		rv = append(rv, name)
@@ -602,6 +801,8 @@ func (fi *fileIndexer) IndexIds() ([]string, errors.Error) {
 }
 
 func (fi *fileIndexer) IndexNames() ([]string, errors.Error) {
+	fi.RLock()
+	defer fi.RUnlock()
 	rv := make([]string, 0, len(fi.indexes))
 	for name, _ := range fi.indexes {
 		rv = append(rv, name)
@@ -614,6 +815,8 @@ func (fi *fileIndexer) IndexById(id string) (datastore.Index, errors.Error) {
 }
 
 func (fi *fileIndexer) IndexByName(name string) (datastore.Index, errors.Error) {
+	fi.RLock()
+	defer fi.RUnlock()
 	index, ok := fi.indexes[name]
 	if !ok {
 		return nil, errors.NewFileIdxNotFound(nil, name)
@@ -626,11 +829,19 @@ func (fi *fileIndexer) PrimaryIndexes() ([]datastore.PrimaryIndex, errors.Error)
 }
 
 func (fi *fileIndexer) Indexes() ([]datastore.Index, errors.Error) {
-	return []datastore.Index{fi.primary}, nil
+	fi.RLock()
+	defer fi.RUnlock()
+	rv := make([]datastore.Index, 0, len(fi.indexes))
+	for _, index := range fi.indexes {
+		rv = append(rv, index)
+	}
+	return rv, nil
 }
 
 func (fi *fileIndexer) CreatePrimaryIndex(requestId, name string, with value.Value) (
 	datastore.PrimaryIndex, errors.Error) {
+	fi.Lock()
+	defer fi.Unlock()
 	if fi.primary == nil {
 		pi := new(primaryIndex)
 		fi.primary = pi
@@ -643,28 +854,130 @@ func (fi *fileIndexer) CreatePrimaryIndex(requestId, name string, with value.Val
 	return fi.primary, nil
 }
 
-func (b *fileIndexer) CreateIndex(requestId, name string, seekKey, rangeKey expression.Expressions,
+func (fi *fileIndexer) CreatePrimaryIndex3(requestId, name string, indexPartition *datastore.IndexPartition,
+	with value.Value) (datastore.PrimaryIndex, errors.Error) {
+	if indexPartition != nil {
+		return nil, errors.NewPartitionIndexNotSupportedError()
+	}
+	return fi.CreatePrimaryIndex(requestId, name, with)
+}
+
+func (fi *fileIndexer) CreateIndex(requestId, name string, seekKey, rangeKey expression.Expressions,
 	where expression.Expression, with value.Value) (datastore.Index, errors.Error) {
-	return nil, errors.NewFileNotSupported(nil, "CREATE INDEX is not supported for file-based datastore.")
+	keys := make(datastore.IndexKeys, 0, len(rangeKey))
+	for _, expr := range rangeKey {
+		keys = append(keys, &datastore.IndexKey{Expr: expr})
+	}
+	return fi.createIndex(name, keys, where, with)
+}
+
+func (fi *fileIndexer) CreateIndex2(requestId, name string, seekKey expression.Expressions,
+	rangeKey datastore.IndexKeys, where expression.Expression, with value.Value) (datastore.Index, errors.Error) {
+	return fi.createIndex(name, rangeKey, where, with)
 }
 
-func (b *fileIndexer) BuildIndexes(requestId string, names ...string) errors.Error {
-	return errors.NewFileNotSupported(nil, "BUILD INDEXES is not supported for file-based datastore.")
+func (fi *fileIndexer) CreateIndex3(requestId, name string, rangeKey datastore.IndexKeys,
+	indexPartition *datastore.IndexPartition, where expression.Expression, with value.Value) (
+	datastore.Index, errors.Error) {
+	if indexPartition != nil {
+		return nil, errors.NewPartitionIndexNotSupportedError()
+	}
+	return fi.createIndex(name, rangeKey, where, with)
 }
 
-func (b *fileIndexer) Refresh() errors.Error {
+// Indexes are built as part of their creation, unless
+// WITH {"defer_build": true} is specified.
+func (fi *fileIndexer) createIndex(name string, rangeKey datastore.IndexKeys, where expression.Expression,
+	with value.Value) (datastore.Index, errors.Error) {
+	deferred := false
+	if with != nil {
+		if v, ok := with.Field("defer_build"); ok {
+			deferred = v.Truth()
+		}
+	}
+
+	fi.Lock()
+	defer fi.Unlock()
+	if _, ok := fi.indexes[name]; ok {
+		return nil, errors.NewIndexAlreadyExistsError(name)
+	}
+
+	index := newSecondaryIndex(fi, name, rangeKey, where)
+	if !deferred {
+		if e := index.build(); e != nil {
+			return nil, e
+		}
+	}
+	fi.indexes[name] = index
+	fi.secondary[name] = index
+	fi.version++
+	return index, fi.saveIndexes()
+}
+
+func (fi *fileIndexer) dropIndex(name string) errors.Error {
+	fi.Lock()
+	defer fi.Unlock()
+	if _, ok := fi.secondary[name]; !ok {
+		return errors.NewFileIdxNotFound(nil, name)
+	}
+
+	delete(fi.indexes, name)
+	delete(fi.secondary, name)
+	fi.version++
+	return fi.saveIndexes()
+}
+
+func (fi *fileIndexer) BuildIndexes(requestId string, names ...string) errors.Error {
+	fi.Lock()
+	defer fi.Unlock()
+	for _, name := range names {
+		index, ok := fi.secondary[name]
+		if !ok {
+			return errors.NewFileIdxNotFound(nil, name)
+		}
+		if state, _, _ := index.State(); state == datastore.DEFERRED {
+			if e := index.build(); e != nil {
+				return e
+			}
+		}
+	}
+	fi.version++
+	return fi.saveIndexes()
+}
+
+// mutate brings secondary indexes up to date with a document that has
+// been written, or removed if bytes is nil
+func (fi *fileIndexer) mutate(id string, bytes []byte) {
+	fi.RLock()
+	defer fi.RUnlock()
+	if len(fi.secondary) == 0 {
+		return
+	}
+
+	var doc value.AnnotatedValue
+	if bytes != nil {
+		doc = newDocument(id, bytes)
+	}
+	for _, index := range fi.secondary {
+		index.update(id, doc)
+	}
+}
+
+func (fi *fileIndexer) Refresh() errors.Error {
 	return nil
 }
 
-func (b *fileIndexer) MetadataVersion() uint64 {
-	return 0
+func (fi *fileIndexer) MetadataVersion() uint64 {
+	fi.RLock()
+	defer fi.RUnlock()
+	return fi.version
 }
 
-func (b *fileIndexer) SetLogLevel(level logging.Level) {
+func (fi *fileIndexer) SetLogLevel(level logging.Level) {
 	// No-op, uses query engine logger
 }
 
-func (b *fileIndexer) SetConnectionSecurityConfig(conSecConfig *datastore.ConnectionSecurityConfig) {
+func (fi *fileIndexer) SetConnectionSecurityConfig(conSecConfig *datastore.ConnectionSecurityConfig) {
 	// Do nothing.
 }
 
@@ -787,7 +1100,7 @@ func (pi *primaryIndex) Scan(requestId string, span *datastore.Span, distinct bo
 			break
 		}
 
-		if !dirEntry.IsDir() {
+		if !dirEntry.IsDir() && !pi.keyspace.expired(id) {
 			entry := datastore.IndexEntry{PrimaryKey: id}
 			conn.Sender().SendEntry(&entry)
 			n++
@@ -805,13 +1118,16 @@ func (pi *primaryIndex) ScanEntries(requestId string, limit int64, cons datastor
 		return
 	}
 
-	for i, dirEntry := range dirEntries {
-		if limit > 0 && int64(i) > limit {
+	var n int64
+	for _, dirEntry := range dirEntries {
+		if limit > 0 && n > limit {
 			break
 		}
-		if !dirEntry.IsDir() {
-			entry := datastore.IndexEntry{PrimaryKey: documentPathToId(dirEntry.Name())}
+		id := documentPathToId(dirEntry.Name())
+		if !dirEntry.IsDir() && !pi.keyspace.expired(id) {
+			entry := datastore.IndexEntry{PrimaryKey: id}
 			conn.Sender().SendEntry(&entry)
+			n++
 		}
 	}
 }
@@ -822,14 +1138,17 @@ func fetch(path string) (item value.AnnotatedValue, e errors.Error) {
 		return nil, errors.NewFileDatastoreError(er, "")
 	}
 
-	doc := value.NewAnnotatedValue(value.NewValue(bytes))
-	doc.SetAttachment("meta", map[string]interface{}{"id": documentPathToId(path)})
-	doc.SetId(documentPathToId(path))
-	item = doc
-
+	item = newDocument(documentPathToId(path), bytes)
 	return
 }
 
+func newDocument(id string, bytes []byte) value.AnnotatedValue {
+	doc := value.NewAnnotatedValue(value.NewValue(bytes))
+	doc.SetAttachment("meta", map[string]interface{}{"id": id})
+	doc.SetId(id)
+	return doc
+}
+
 func documentPathToId(p string) string {
 	_, file := filepath.Split(p)
 	ext := filepath.Ext(file)
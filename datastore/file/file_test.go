@@ -11,11 +11,18 @@ package file
 
 import (
 	"fmt"
+	"io/ioutil"
 	"math"
+	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/couchbase/query/datastore"
 	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/parser/n1ql"
 	"github.com/couchbase/query/value"
 )
 
@@ -185,6 +192,473 @@ func TestFile(t *testing.T) {
 
 }
 
+func TestSecondaryIndex(t *testing.T) {
+	dir, er := ioutil.TempDir("", "filestore")
+	if er != nil {
+		t.Fatalf("failed to create store directory: %v", er)
+	}
+	defer os.RemoveAll(dir)
+
+	docs := map[string]string{
+		"ann":  `{"name": "ann", "age": 31, "tags": ["a", "b", "a"]}`,
+		"bob":  `{"name": "bob", "age": 25, "tags": ["b"]}`,
+		"carl": `{"name": "carl", "age": 40}`,
+		"dora": `{"name": "dora", "age": 25, "tags": []}`,
+		"eve":  `{"name": "eve"}`,
+	}
+	path := filepath.Join(dir, "default", "people")
+	if er = os.MkdirAll(path, 0777); er != nil {
+		t.Fatalf("failed to create keyspace directory: %v", er)
+	}
+	for id, doc := range docs {
+		if er = ioutil.WriteFile(filepath.Join(path, id+".json"), []byte(doc), 0666); er != nil {
+			t.Fatalf("failed to write %s: %v", id, er)
+		}
+	}
+
+	indexer := testIndexer(t, dir)
+	age, _ := n1ql.ParseExpression("age")
+	name, _ := n1ql.ParseExpression("name")
+	tags, _ := n1ql.ParseExpression("ARRAY t FOR t IN tags END")
+
+	_, err := indexer.CreateIndex3("", "ix_age", datastore.IndexKeys{&datastore.IndexKey{Expr: age, Desc: true},
+		&datastore.IndexKey{Expr: name}}, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create index: %v", err)
+	}
+	_, err = indexer.CreateIndex3("", "ix_age", datastore.IndexKeys{&datastore.IndexKey{Expr: age}}, nil, nil, nil)
+	if err == nil {
+		t.Errorf("expected duplicate index error")
+	}
+	_, err = indexer.CreateIndex3("", "ix_tags", datastore.IndexKeys{&datastore.IndexKey{Expr: expression.NewAll(tags, true)}},
+		nil, nil, value.NewValue(map[string]interface{}{"defer_build": true}))
+	if err != nil {
+		t.Fatalf("failed to create index: %v", err)
+	}
+
+	index, _ := indexer.IndexByName("ix_age")
+	ix := index.(datastore.Index3)
+	all := datastore.Spans2{&datastore.Span2{Ranges: datastore.Ranges2{&datastore.Range2{}}}}
+	span := datastore.Spans2{&datastore.Span2{Ranges: datastore.Ranges2{&datastore.Range2{
+		Low: value.NewValue(25), High: value.NewValue(35), Inclusion: datastore.BOTH}}}}
+
+	// descending leading key, documents without it are not indexed
+	testScan(t, ix, all, false, nil, 0, 0, []string{"carl", "ann", "bob", "dora"})
+	testScan(t, ix, span, false, nil, 0, 0, []string{"ann", "bob", "dora"})
+	testScan(t, ix, span, true, nil, 1, 1, []string{"bob"})
+
+	// overlapping spans return entries once
+	twoSpans := append(datastore.Spans2{&datastore.Span2{Ranges: datastore.Ranges2{&datastore.Range2{
+		Low: value.NewValue(30), Inclusion: datastore.LOW}}}}, span...)
+	testScan(t, ix, twoSpans, false, nil, 0, 0, []string{"carl", "ann", "bob", "dora"})
+
+	// projection and distinct
+	projection := &datastore.IndexProjection{EntryKeys: []int{0}}
+	conn := datastore.NewIndexConnection(&testingContext{t})
+	go ix.Scan2("", span, false, true, true, projection, 0, 0, datastore.UNBOUNDED, nil, conn)
+	entries := testEntries(conn)
+	if len(entries) != 2 || len(entries[1].EntryKey) != 1 || !entries[1].EntryKey[0].Equals(value.NewValue(25)).Truth() {
+		t.Errorf("unexpected distinct projection %v", entries)
+	}
+
+	count, _ := ix.(datastore.CountIndex2).Count2("", span, datastore.UNBOUNDED, nil)
+	distinct, _ := ix.(datastore.CountIndex2).CountDistinct("", span, datastore.UNBOUNDED, nil)
+	if count != 3 || distinct != 2 {
+		t.Errorf("expected count 3 and distinct count 2, got %v and %v", count, distinct)
+	}
+
+	// group and aggregates
+	groupAggs := &datastore.IndexGroupAggregates{
+		Group: datastore.IndexGroupKeys{&datastore.IndexGroupKey{EntryKeyId: 0, KeyPos: 0, Expr: age}},
+		Aggregates: datastore.IndexAggregates{&datastore.IndexAggregate{Operation: datastore.AGG_COUNT,
+			EntryKeyId: 3, KeyPos: -1, Expr: expression.ONE_EXPR}},
+		IndexKeyNames: []string{"(`people`.`age`)", "(`people`.`name`)", "(meta(`people`).`id`)"},
+	}
+	conn = datastore.NewIndexConnection(&testingContext{t})
+	go ix.Scan3("", all, false, false, &datastore.IndexProjection{EntryKeys: []int{0, 3}}, 0, 0,
+		groupAggs, nil, datastore.UNBOUNDED, nil, conn)
+	var groups []interface{}
+	for _, entry := range testEntries(conn) {
+		groups = append(groups, []interface{}{entry.EntryKey[0], entry.EntryKey[1]})
+	}
+	expected := []interface{}{[]interface{}{40, 1}, []interface{}{31, 1}, []interface{}{25, 2}}
+	if !value.NewValue(groups).Equals(value.NewValue(expected)).Truth() {
+		t.Errorf("expected groups %v, got %v", expected, groups)
+	}
+
+	// mutations are reflected in built indexes
+	ks := indexer.(*fileIndexer).keyspace
+	ks.Upsert([]value.Pair{value.Pair{Name: "eve", Value: value.NewValue(map[string]interface{}{"age": 30})}})
+	ks.Delete([]string{"bob"}, datastore.NULL_QUERY_CONTEXT)
+	testScan(t, ix, span, false, nil, 0, 0, []string{"ann", "eve", "dora"})
+
+	// definitions survive a restart, deferred indexes until they are built
+	indexer = testIndexer(t, dir)
+	index, err = indexer.IndexByName("ix_tags")
+	if err != nil {
+		t.Fatalf("index ix_tags not found after reload: %v", err)
+	}
+	if state, _, _ := index.State(); state != datastore.DEFERRED {
+		t.Errorf("expected deferred index, got %v", state)
+	}
+	if err = indexer.BuildIndexes("", "ix_tags"); err != nil {
+		t.Fatalf("failed to build index: %v", err)
+	}
+	testScan(t, index.(datastore.Index3), all, false, nil, 0, 0, []string{"ann", "ann"})
+
+	index, _ = indexer.IndexByName("ix_age")
+	testScan(t, index.(datastore.Index3), span, false, nil, 0, 0, []string{"ann", "eve", "dora"})
+	if err = index.Drop(""); err != nil {
+		t.Errorf("failed to drop index: %v", err)
+	}
+	if _, err = indexer.IndexByName("ix_age"); err == nil {
+		t.Errorf("index ix_age not dropped")
+	}
+}
+
+func TestExpiration(t *testing.T) {
+	dir, er := ioutil.TempDir("", "filestore")
+	if er != nil {
+		t.Fatalf("failed to create store directory: %v", er)
+	}
+	defer os.RemoveAll(dir)
+	if er = os.MkdirAll(filepath.Join(dir, "default", "people"), 0777); er != nil {
+		t.Fatalf("failed to create keyspace directory: %v", er)
+	}
+
+	doc := value.NewValue(map[string]interface{}{"name": "ann"})
+	expiration := func(exp interface{}) value.Value {
+		return value.NewValue(map[string]interface{}{"expiration": exp})
+	}
+	past := time.Now().Unix() - 10
+
+	keyspace := testKeyspace(t, dir)
+	_, err := keyspace.Insert([]value.Pair{
+		value.Pair{Name: "old", Value: doc, Options: expiration(past)},
+		value.Pair{Name: "ann", Value: doc, Options: expiration(3600)},
+		value.Pair{Name: "bob", Value: doc},
+	})
+	if err != nil {
+		t.Fatalf("failed to insert: %v", err)
+	}
+
+	exp := testExpiration(t, keyspace, "ann")
+	if exp < time.Now().Unix()+3590 || testExpiration(t, keyspace, "bob") != 0 {
+		t.Errorf("unexpected expiration %v", exp)
+	}
+	if testExpiration(t, keyspace, "old") != -1 {
+		t.Errorf("expected expired document to be gone")
+	}
+	if count, _ := keyspace.Count(datastore.NULL_QUERY_CONTEXT); count != 2 {
+		t.Errorf("expected 2 documents, got %v", count)
+	}
+
+	// invalid expirations are rejected, and nothing is written
+	for _, invalid := range []interface{}{-1, int64(1) << 33, "soon"} {
+		_, err = keyspace.Insert([]value.Pair{value.Pair{Name: "bad", Value: doc, Options: expiration(invalid)}})
+		if err == nil || testExpiration(t, keyspace, "bad") != -1 {
+			t.Errorf("expected expiration %v to be rejected, got %v", invalid, err)
+		}
+	}
+
+	// scans skip documents that expired since
+	keyspace.Insert([]value.Pair{value.Pair{Name: "cat", Value: doc}})
+	indexer, _ := keyspace.Indexer(datastore.DEFAULT)
+	primaries, _ := indexer.PrimaryIndexes()
+	primary := primaries[0].(*primaryIndex)
+	primary.keyspace.writeExpiration("cat", uint32(past))
+	conn := datastore.NewIndexConnection(&testingContext{t})
+	go primary.ScanEntries("", 0, datastore.UNBOUNDED, nil, conn)
+	if entries := testEntries(conn); len(entries) != 2 {
+		t.Errorf("expected expired document to be skipped, got %v entries", len(entries))
+	}
+	keyspace.Delete([]string{"cat"}, datastore.NULL_QUERY_CONTEXT)
+
+	// updates keep the expiration, unless they set one
+	keyspace.Update([]value.Pair{value.Pair{Name: "ann", Value: doc}})
+	if testExpiration(t, keyspace, "ann") != exp {
+		t.Errorf("expected expiration to be kept")
+	}
+	keyspace.Update([]value.Pair{value.Pair{Name: "ann", Value: doc, Options: expiration(0)}})
+	if testExpiration(t, keyspace, "ann") != 0 {
+		t.Errorf("expected expiration to be cleared")
+	}
+
+	// other writes clear it
+	keyspace.Upsert([]value.Pair{value.Pair{Name: "bob", Value: doc, Options: expiration(3600)}})
+	keyspace.Upsert([]value.Pair{value.Pair{Name: "ann", Value: doc, Options: expiration(3600)}})
+	keyspace.Upsert([]value.Pair{value.Pair{Name: "ann", Value: doc}})
+	if testExpiration(t, keyspace, "ann") != 0 {
+		t.Errorf("expected expiration to be cleared")
+	}
+
+	// expirations persist
+	keyspace = testKeyspace(t, dir)
+	if testExpiration(t, keyspace, "bob") == 0 {
+		t.Errorf("expected expiration to persist")
+	}
+}
+
+func TestCollections(t *testing.T) {
+	dir, er := ioutil.TempDir("", "filestore")
+	if er != nil {
+		t.Fatalf("failed to create store directory: %v", er)
+	}
+	defer os.RemoveAll(dir)
+	if er = os.MkdirAll(filepath.Join(dir, "default", "people"), 0777); er != nil {
+		t.Fatalf("failed to create keyspace directory: %v", er)
+	}
+
+	store, err := NewDatastore(dir)
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	namespace, _ := store.NamespaceByName("default")
+	bucket, err := namespace.BucketByName("people")
+	if err != nil {
+		t.Fatalf("failed to get bucket: %v", err)
+	}
+
+	err = bucket.(datastore.ScopeManager).CreateScope("s1")
+	if err != nil {
+		t.Fatalf("failed to create scope: %v", err)
+	}
+	if err = bucket.(datastore.ScopeManager).CreateScope("s1"); err == nil {
+		t.Errorf("expected duplicate scope error")
+	}
+	scope, err := bucket.ScopeByName("s1")
+	if err != nil {
+		t.Fatalf("failed to get scope: %v", err)
+	}
+	err = scope.(datastore.CollectionManager).CreateCollection("c1")
+	if err != nil {
+		t.Fatalf("failed to create collection: %v", err)
+	}
+
+	collection, err := scope.KeyspaceByName("c1")
+	if err != nil {
+		t.Fatalf("failed to get collection: %v", err)
+	}
+	if collection.Scope() == nil || collection.ScopeId() != "s1" {
+		t.Errorf("expected collection to belong to scope s1")
+	}
+	_, err = collection.Insert([]value.Pair{
+		value.Pair{Name: "ann", Value: value.NewValue(map[string]interface{}{"name": "ann"})},
+	})
+	if err != nil {
+		t.Fatalf("failed to insert: %v", err)
+	}
+
+	// collections are reloaded from disk, and do not show up as documents of the bucket
+	store, _ = NewDatastore(dir)
+	namespace, _ = store.NamespaceByName("default")
+	bucket, _ = namespace.BucketByName("people")
+	scope, err = bucket.ScopeByName("s1")
+	if err != nil {
+		t.Fatalf("failed to reload scope: %v", err)
+	}
+	collection, err = scope.KeyspaceByName("c1")
+	if err != nil {
+		t.Fatalf("failed to reload collection: %v", err)
+	}
+	if count, _ := collection.Count(datastore.NULL_QUERY_CONTEXT); count != 1 {
+		t.Errorf("expected 1 document in collection, got %v", count)
+	}
+	keyspace, _ := namespace.KeyspaceByName("people")
+	if count, _ := keyspace.Count(datastore.NULL_QUERY_CONTEXT); count != 0 {
+		t.Errorf("expected 0 documents in bucket, got %v", count)
+	}
+
+	err = scope.(datastore.CollectionManager).DropCollection("c1")
+	if err != nil {
+		t.Fatalf("failed to drop collection: %v", err)
+	}
+	if _, err = scope.KeyspaceByName("c1"); err == nil {
+		t.Errorf("expected collection to be dropped")
+	}
+	err = bucket.(datastore.ScopeManager).DropScope("s1")
+	if err != nil {
+		t.Fatalf("failed to drop scope: %v", err)
+	}
+	if _, er = os.Stat(filepath.Join(dir, "default", "people", "s1")); !os.IsNotExist(er) {
+		t.Errorf("expected scope directory to be removed")
+	}
+}
+
+func TestFunctions(t *testing.T) {
+	dir, er := ioutil.TempDir("", "filestore")
+	if er != nil {
+		t.Fatalf("failed to create store directory: %v", er)
+	}
+	defer os.RemoveAll(dir)
+	if er = os.MkdirAll(filepath.Join(dir, "default", "people"), 0777); er != nil {
+		t.Fatalf("failed to create keyspace directory: %v", er)
+	}
+
+	store, err := NewDatastore(dir)
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	storage := store.(datastore.FunctionsStorage)
+	definition := []byte(`{"identity":{"name":"f/1"},"definition":{"#language":"inline"}}`)
+	if err = storage.AddFunction("default:f/1", definition); err != nil {
+		t.Fatalf("failed to add function: %v", err)
+	}
+	if err = storage.AddFunction("default:f/1", definition); err == nil {
+		t.Errorf("expected duplicate function error")
+	}
+	if body, _ := storage.GetFunction("default:f2"); body != nil {
+		t.Errorf("expected missing function")
+	}
+
+	// functions persist, and are not mistaken for a namespace
+	store, err = NewDatastore(dir)
+	if err != nil {
+		t.Fatalf("failed to reload store: %v", err)
+	}
+	if names, _ := store.NamespaceNames(); len(names) != 1 {
+		t.Errorf("expected 1 namespace, got %v", names)
+	}
+	storage = store.(datastore.FunctionsStorage)
+	keys, err := storage.FunctionKeys()
+	if err != nil || !reflect.DeepEqual(keys, []string{"default:f/1"}) {
+		t.Errorf("unexpected function keys %v %v", keys, err)
+	}
+	body, err := storage.GetFunction("default:f/1")
+	if err != nil || string(body) != string(definition) {
+		t.Errorf("unexpected function definition %s %v", body, err)
+	}
+
+	if err = storage.DeleteFunction("default:f/1"); err != nil {
+		t.Fatalf("failed to delete function: %v", err)
+	}
+	if err = storage.DeleteFunction("default:f/1"); err == nil {
+		t.Errorf("expected missing function error")
+	}
+}
+
+func TestLibraries(t *testing.T) {
+	dir, er := ioutil.TempDir("", "filestore")
+	if er != nil {
+		t.Fatalf("failed to create store directory: %v", er)
+	}
+	defer os.RemoveAll(dir)
+	if er = os.MkdirAll(filepath.Join(dir, "default", "people"), 0777); er != nil {
+		t.Fatalf("failed to create keyspace directory: %v", er)
+	}
+
+	store, err := NewDatastore(dir)
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	storage := store.(datastore.FunctionsStorage)
+	definition := []byte(`{"identity":{"name":"f"},"definition":{"#language":"javascript"}}`)
+	if err = storage.AddFunction("default:f", definition); err != nil {
+		t.Fatalf("failed to add function: %v", err)
+	}
+	if err = storage.SetLibrary("math/v1", []byte("function add(a, b) { return a + b; }")); err != nil {
+		t.Fatalf("failed to add library: %v", err)
+	}
+	source := []byte("function add(a, b) { return b + a; }")
+	if err = storage.SetLibrary("math/v1", source); err != nil {
+		t.Fatalf("failed to replace library: %v", err)
+	}
+
+	// libraries persist, next to but separate from the functions
+	store, err = NewDatastore(dir)
+	if err != nil {
+		t.Fatalf("failed to reload store: %v", err)
+	}
+	storage = store.(datastore.FunctionsStorage)
+	if keys, _ := storage.FunctionKeys(); !reflect.DeepEqual(keys, []string{"default:f"}) {
+		t.Errorf("unexpected function keys %v", keys)
+	}
+	names, err := storage.LibraryNames()
+	if err != nil || !reflect.DeepEqual(names, []string{"math/v1"}) {
+		t.Errorf("unexpected library names %v %v", names, err)
+	}
+	body, err := storage.GetLibrary("math/v1")
+	if err != nil || string(body) != string(source) {
+		t.Errorf("unexpected library source %s %v", body, err)
+	}
+
+	if err = storage.DeleteLibrary("math/v1"); err != nil {
+		t.Fatalf("failed to delete library: %v", err)
+	}
+	if err = storage.DeleteLibrary("math/v1"); err == nil {
+		t.Errorf("expected missing library error")
+	}
+	if body, _ = storage.GetLibrary("math/v1"); body != nil {
+		t.Errorf("expected missing library")
+	}
+}
+
+func testKeyspace(t *testing.T, dir string) datastore.Keyspace {
+	store, err := NewDatastore(dir)
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	namespace, _ := store.NamespaceByName("default")
+	keyspace, err := namespace.KeyspaceByName("people")
+	if err != nil {
+		t.Fatalf("failed to get keyspace: %v", err)
+	}
+	return keyspace
+}
+
+// testExpiration returns the expiration of a document, -1 if it is missing
+func testExpiration(t *testing.T, keyspace datastore.Keyspace, key string) int64 {
+	docs := make(map[string]value.AnnotatedValue, 1)
+	errs := keyspace.Fetch([]string{key}, docs, datastore.NULL_QUERY_CONTEXT, nil)
+	if len(errs) > 0 {
+		t.Fatalf("failed to fetch %s: %v", key, errs[0])
+	}
+	if docs[key] == nil {
+		return -1
+	}
+	meta := docs[key].GetAttachment("meta").(map[string]interface{})
+	return int64(meta["expiration"].(uint32))
+}
+
+func testIndexer(t *testing.T, dir string) datastore.Indexer3 {
+	store, err := NewDatastore(dir)
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	namespace, _ := store.NamespaceByName("default")
+	keyspace, err := namespace.KeyspaceByName("people")
+	if err != nil {
+		t.Fatalf("failed to get keyspace: %v", err)
+	}
+	indexer, _ := keyspace.Indexer(datastore.GSI)
+	return indexer.(datastore.Indexer3)
+}
+
+func testScan(t *testing.T, index datastore.Index3, spans datastore.Spans2, reverse bool,
+	projection *datastore.IndexProjection, offset, limit int64, expected []string) {
+	conn := datastore.NewIndexConnection(&testingContext{t})
+	go index.Scan3("", spans, reverse, false, projection, offset, limit, nil, nil, datastore.UNBOUNDED, nil, conn)
+
+	var ids []string
+	for _, entry := range testEntries(conn) {
+		ids = append(ids, entry.PrimaryKey)
+	}
+	if !reflect.DeepEqual(ids, expected) {
+		t.Errorf("expected %v, got %v", expected, ids)
+	}
+}
+
+func testEntries(conn *datastore.IndexConnection) []*datastore.IndexEntry {
+	var rv []*datastore.IndexEntry
+	for {
+		entry, ok := conn.Sender().GetEntry()
+		if !ok || entry == nil {
+			return rv
+		}
+		rv = append(rv, entry)
+	}
+}
+
 type testingContext struct {
 	t *testing.T
 }
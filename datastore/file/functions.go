@@ -0,0 +1,167 @@
+//  Copyright (c) 2020 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package file
+
+import (
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/couchbase/query/errors"
+)
+
+// user defined functions are kept next to the namespaces, one JSON file per function,
+// so that standalone engines do not need a cluster metadata store, and the javascript
+// libraries they are defined in alongside them, one source file per library
+const _FUNCTIONS_DIR = ".functions"
+const _FUNCTION_FILE_SUFFIX = ".json"
+const _LIBRARY_FILE_SUFFIX = ".js"
+
+func (s *store) functionsPath() string {
+	return filepath.Join(s.path, _FUNCTIONS_DIR)
+}
+
+// function keys contain the namespace separator, and names can be any identifier
+func (s *store) functionPath(key string) string {
+	return filepath.Join(s.functionsPath(), url.PathEscape(key)+_FUNCTION_FILE_SUFFIX)
+}
+
+func (s *store) libraryPath(name string) string {
+	return filepath.Join(s.functionsPath(), url.PathEscape(name)+_LIBRARY_FILE_SUFFIX)
+}
+
+func (s *store) FunctionKeys() ([]string, errors.Error) {
+	return s.functionsDirKeys(_FUNCTION_FILE_SUFFIX)
+}
+
+// the keys of the files of the functions directory with the given suffix
+func (s *store) functionsDirKeys(suffix string) ([]string, errors.Error) {
+	dirEntries, er := ioutil.ReadDir(s.functionsPath())
+	if os.IsNotExist(er) {
+		return nil, nil
+	} else if er != nil {
+		return nil, errors.NewFileDatastoreError(er, "")
+	}
+
+	keys := make([]string, 0, len(dirEntries))
+	for _, dirEntry := range dirEntries {
+		name := dirEntry.Name()
+		if dirEntry.IsDir() || !strings.HasSuffix(name, suffix) {
+			continue
+		}
+		key, er := url.PathUnescape(strings.TrimSuffix(name, suffix))
+		if er == nil {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (s *store) GetFunction(key string) ([]byte, errors.Error) {
+	return readFunctionsFile(s.functionPath(key))
+}
+
+func readFunctionsFile(path string) ([]byte, errors.Error) {
+	bytes, er := ioutil.ReadFile(path)
+	if os.IsNotExist(er) {
+		return nil, nil
+	} else if er != nil {
+		return nil, errors.NewFileDatastoreError(er, "")
+	}
+	return bytes, nil
+}
+
+func (s *store) AddFunction(key string, definition []byte) errors.Error {
+
+	// write the definition to a temporary file first, and link it in place, so that the
+	// definition is never seen half written and concurrent creations cannot both succeed
+	tmp, err := s.writeFunctionsTmpFile(definition)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+
+	er := os.Link(tmp, s.functionPath(key))
+	if os.IsExist(er) {
+		return errors.NewDuplicateFunctionError(key)
+	} else if er != nil {
+		return errors.NewFileDatastoreError(er, "")
+	}
+	return nil
+}
+
+func (s *store) DeleteFunction(key string) errors.Error {
+	er := os.Remove(s.functionPath(key))
+	if os.IsNotExist(er) {
+		return errors.NewMissingFunctionError(key)
+	} else if er != nil {
+		return errors.NewFileDatastoreError(er, "")
+	}
+	return nil
+}
+
+func (s *store) writeFunctionsTmpFile(contents []byte) (string, errors.Error) {
+	if er := os.MkdirAll(s.functionsPath(), 0777); er != nil {
+		return "", errors.NewFileDatastoreError(er, "")
+	}
+
+	tmp, er := ioutil.TempFile(s.functionsPath(), "tmp")
+	if er != nil {
+		return "", errors.NewFileDatastoreError(er, "")
+	}
+
+	_, er = tmp.Write(contents)
+	if er == nil {
+		er = tmp.Close()
+	} else {
+		tmp.Close()
+	}
+	if er != nil {
+		os.Remove(tmp.Name())
+		return "", errors.NewFileDatastoreError(er, "")
+	}
+	return tmp.Name(), nil
+}
+
+func (s *store) LibraryNames() ([]string, errors.Error) {
+	return s.functionsDirKeys(_LIBRARY_FILE_SUFFIX)
+}
+
+func (s *store) GetLibrary(name string) ([]byte, errors.Error) {
+	return readFunctionsFile(s.libraryPath(name))
+}
+
+// libraries are replaced by renaming, so that the source is never seen half written
+func (s *store) SetLibrary(name string, source []byte) errors.Error {
+	tmp, err := s.writeFunctionsTmpFile(source)
+	if err != nil {
+		return err
+	}
+
+	if er := os.Rename(tmp, s.libraryPath(name)); er != nil {
+		os.Remove(tmp)
+		return errors.NewFileDatastoreError(er, "")
+	}
+	return nil
+}
+
+func (s *store) DeleteLibrary(name string) errors.Error {
+	er := os.Remove(s.libraryPath(name))
+	if os.IsNotExist(er) {
+		return errors.NewMissingLibraryError(name)
+	} else if er != nil {
+		return errors.NewFileDatastoreError(er, "")
+	}
+	return nil
+}
@@ -0,0 +1,958 @@
+//  Copyright (c) 2019 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package file
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/parser/n1ql"
+	"github.com/couchbase/query/timestamp"
+	"github.com/couchbase/query/value"
+)
+
+// Secondary index definitions are kept in a file next to the keyspace
+// directory, so that they do not show up as keyspaces or documents.
+// Index entries are not persisted: they are rebuilt when the keyspace
+// is loaded.
+const _INDEX_FILE_SUFFIX = ".indexes.json"
+
+type indexDefinition struct {
+	Name     string               `json:"name"`
+	Keys     []indexKeyDefinition `json:"keys"`
+	Where    string               `json:"where,omitempty"`
+	Deferred bool                 `json:"deferred,omitempty"`
+}
+
+type indexKeyDefinition struct {
+	Expr string `json:"expr"`
+	Desc bool   `json:"desc,omitempty"`
+}
+
+// secondaryIndex is an in-process GSI-like index on a file keyspace.
+// Entries are kept sorted in index key order, descending keys collating
+// in reverse, with ties broken by document key.
+type secondaryIndex struct {
+	sync.RWMutex
+	name     string
+	keyspace *keyspace
+	indexer  *fileIndexer
+	rangeKey datastore.IndexKeys
+	where    expression.Expression
+	state    datastore.IndexState
+	entries  []*indexEntry
+	docs     map[string][]*indexEntry
+}
+
+type indexEntry struct {
+	key value.Values
+	id  string
+}
+
+func newSecondaryIndex(indexer *fileIndexer, name string, rangeKey datastore.IndexKeys,
+	where expression.Expression) *secondaryIndex {
+	return &secondaryIndex{
+		name:     name,
+		keyspace: indexer.keyspace,
+		indexer:  indexer,
+		rangeKey: rangeKey,
+		where:    where,
+		state:    datastore.DEFERRED,
+		docs:     make(map[string][]*indexEntry),
+	}
+}
+
+func (si *secondaryIndex) KeyspaceId() string {
+	return si.keyspace.Id()
+}
+
+func (si *secondaryIndex) Id() string {
+	return si.Name()
+}
+
+func (si *secondaryIndex) Name() string {
+	return si.name
+}
+
+func (si *secondaryIndex) Type() datastore.IndexType {
+	return datastore.GSI
+}
+
+func (si *secondaryIndex) Indexer() datastore.Indexer {
+	return si.indexer
+}
+
+func (si *secondaryIndex) SeekKey() expression.Expressions {
+	return nil
+}
+
+func (si *secondaryIndex) RangeKey() expression.Expressions {
+	rv := make(expression.Expressions, len(si.rangeKey))
+	for i, k := range si.rangeKey {
+		rv[i] = k.Expr
+	}
+	return rv
+}
+
+func (si *secondaryIndex) RangeKey2() datastore.IndexKeys {
+	return si.rangeKey
+}
+
+func (si *secondaryIndex) Condition() expression.Expression {
+	return si.where
+}
+
+func (si *secondaryIndex) IsPrimary() bool {
+	return false
+}
+
+func (si *secondaryIndex) State() (state datastore.IndexState, msg string, err errors.Error) {
+	si.RLock()
+	defer si.RUnlock()
+	return si.state, "", nil
+}
+
+func (si *secondaryIndex) Statistics(requestId string, span *datastore.Span) (
+	datastore.Statistics, errors.Error) {
+	return nil, nil
+}
+
+func (si *secondaryIndex) Drop(requestId string) errors.Error {
+	return si.indexer.dropIndex(si.name)
+}
+
+func (si *secondaryIndex) CreateAggregate(requestId string, groupAggs *datastore.IndexGroupAggregates,
+	with value.Value) errors.Error {
+	return errors.NewFileNotSupported(nil, "CREATE AGGREGATE is not supported for file-based datastore.")
+}
+
+func (si *secondaryIndex) DropAggregate(requestId, name string) errors.Error {
+	return errors.NewFileNotSupported(nil, "DROP AGGREGATE is not supported for file-based datastore.")
+}
+
+func (si *secondaryIndex) Aggregates() ([]datastore.IndexGroupAggregates, errors.Error) {
+	return nil, nil
+}
+
+func (si *secondaryIndex) PartitionKeys() (*datastore.IndexPartition, errors.Error) {
+	return nil, nil
+}
+
+func (si *secondaryIndex) Alter(requestId string, with value.Value) (datastore.Index, errors.Error) {
+	return nil, errors.NewFileNotSupported(nil, "ALTER INDEX is not supported for file-based datastore.")
+}
+
+func (si *secondaryIndex) Scan(requestId string, span *datastore.Span, distinct bool, limit int64,
+	cons datastore.ScanConsistency, vector timestamp.Vector, conn *datastore.IndexConnection) {
+	defer conn.Sender().Close()
+
+	var seen map[string]bool
+	if distinct {
+		seen = make(map[string]bool)
+	}
+
+	var n int64
+	for _, e := range si.lookup(func(key value.Values) bool { return matchSpan(key, span) }) {
+		if limit > 0 && n >= limit {
+			break
+		}
+		if distinct {
+			if seen[e.id] {
+				continue
+			}
+			seen[e.id] = true
+		}
+		if !conn.Sender().SendEntry(&datastore.IndexEntry{EntryKey: e.key, PrimaryKey: e.id}) {
+			return
+		}
+		n++
+	}
+}
+
+func (si *secondaryIndex) Scan2(requestId string, spans datastore.Spans2, reverse, distinctAfterProjection,
+	ordered bool, projection *datastore.IndexProjection, offset, limit int64,
+	cons datastore.ScanConsistency, vector timestamp.Vector, conn *datastore.IndexConnection) {
+	defer conn.Sender().Close()
+
+	si.send(si.lookup2(spans), reverse, distinctAfterProjection, projection, offset, limit, conn)
+}
+
+// Entries are always returned in index order, which is the only order the
+// planner pushes down, so the requested index key orders need no extra work.
+func (si *secondaryIndex) Scan3(requestId string, spans datastore.Spans2, reverse, distinctAfterProjection bool,
+	projection *datastore.IndexProjection, offset, limit int64,
+	groupAggs *datastore.IndexGroupAggregates, indexOrders datastore.IndexKeyOrders,
+	cons datastore.ScanConsistency, vector timestamp.Vector, conn *datastore.IndexConnection) {
+	defer conn.Sender().Close()
+
+	entries := si.lookup2(spans)
+	if groupAggs == nil {
+		si.send(entries, reverse, distinctAfterProjection, projection, offset, limit, conn)
+		return
+	}
+
+	rows, err := si.aggregate(entries, reverse, projection, groupAggs)
+	if err != nil {
+		conn.Error(err)
+		return
+	}
+
+	var n int64
+	for _, row := range rows {
+		if offset > 0 {
+			offset--
+			continue
+		}
+		if limit > 0 && n >= limit {
+			break
+		}
+		if !conn.Sender().SendEntry(row) {
+			return
+		}
+		n++
+	}
+}
+
+func (si *secondaryIndex) Count(span *datastore.Span, cons datastore.ScanConsistency,
+	vector timestamp.Vector) (int64, errors.Error) {
+	entries := si.lookup(func(key value.Values) bool { return matchSpan(key, span) })
+	return int64(len(entries)), nil
+}
+
+func (si *secondaryIndex) Count2(requestId string, spans datastore.Spans2, cons datastore.ScanConsistency,
+	vector timestamp.Vector) (int64, errors.Error) {
+	return int64(len(si.lookup2(spans))), nil
+}
+
+func (si *secondaryIndex) CanCountDistinct() bool {
+	return true
+}
+
+// counts the distinct non null values of the leading key
+func (si *secondaryIndex) CountDistinct(requestId string, spans datastore.Spans2, cons datastore.ScanConsistency,
+	vector timestamp.Vector) (int64, errors.Error) {
+	var n int64
+	var last value.Value
+
+	// entries are sorted on the leading key first, so equal values are adjacent
+	for _, e := range si.lookup2(spans) {
+		k := e.key[0]
+		if k.Type() <= value.NULL || (last != nil && k.Collate(last) == 0) {
+			continue
+		}
+		last = k
+		n++
+	}
+	return n, nil
+}
+
+func (si *secondaryIndex) send(entries []*indexEntry, reverse, distinct bool,
+	projection *datastore.IndexProjection, offset, limit int64, conn *datastore.IndexConnection) {
+
+	var seen map[string]bool
+	if distinct {
+		seen = make(map[string]bool)
+	}
+
+	var n int64
+	for i := range entries {
+		e := entries[i]
+		if reverse {
+			e = entries[len(entries)-1-i]
+		}
+
+		entry := project(e, projection)
+		if distinct {
+			k := distinctKey(entry, projection)
+			if seen[k] {
+				continue
+			}
+			seen[k] = true
+		}
+		if offset > 0 {
+			offset--
+			continue
+		}
+		if limit > 0 && n >= limit {
+			break
+		}
+		if !conn.Sender().SendEntry(entry) {
+			return
+		}
+		n++
+	}
+}
+
+func project(e *indexEntry, projection *datastore.IndexProjection) *datastore.IndexEntry {
+	if projection == nil {
+		return &datastore.IndexEntry{EntryKey: e.key, PrimaryKey: e.id}
+	}
+
+	key := make(value.Values, 0, len(projection.EntryKeys))
+	for _, p := range projection.EntryKeys {
+		if p >= 0 && p < len(e.key) {
+			key = append(key, e.key[p])
+		} else {
+			key = append(key, value.NewValue(e.id))
+		}
+	}
+
+	// the document key is always returned, it is needed to fetch the document
+	return &datastore.IndexEntry{EntryKey: key, PrimaryKey: e.id}
+}
+
+func distinctKey(entry *datastore.IndexEntry, projection *datastore.IndexProjection) string {
+	var buf bytes.Buffer
+
+	for _, k := range entry.EntryKey {
+		b, _ := k.MarshalJSON()
+		buf.WriteByte(byte(k.Type()))
+		buf.Write(b)
+		buf.WriteByte(0)
+	}
+	if projection == nil || projection.PrimaryKey {
+		buf.WriteString(entry.PrimaryKey)
+	}
+	return buf.String()
+}
+
+// lookup returns a snapshot of the entries satisfying match, in index order.
+func (si *secondaryIndex) lookup(match func(key value.Values) bool) []*indexEntry {
+	si.RLock()
+	defer si.RUnlock()
+
+	var rv []*indexEntry
+	for _, e := range si.entries {
+		if match(e.key) && !si.keyspace.expired(e.id) {
+			rv = append(rv, e)
+		}
+	}
+	return rv
+}
+
+// lookup2 narrows each span down on the leading key, then filters the union
+// of the resulting ranges, so that entries matching more than one span are
+// returned once, and in index order.
+func (si *secondaryIndex) lookup2(spans datastore.Spans2) []*indexEntry {
+	si.RLock()
+	defer si.RUnlock()
+
+	bounds := make([][2]int, 0, len(spans))
+	for _, span := range spans {
+		low, high := 0, len(si.entries)
+		if len(span.Ranges) > 0 {
+			low, high = si.leadingBounds(span.Ranges[0])
+		}
+		if low < high {
+			bounds = append(bounds, [2]int{low, high})
+		}
+	}
+	sort.Slice(bounds, func(i, j int) bool { return bounds[i][0] < bounds[j][0] })
+
+	var rv []*indexEntry
+	next := 0
+	for _, b := range bounds {
+		if b[0] < next {
+			b[0] = next
+		}
+		for i := b[0]; i < b[1]; i++ {
+			e := si.entries[i]
+			if si.keyspace.expired(e.id) {
+				continue
+			}
+			for _, span := range spans {
+				if matchSpan2(e.key, span) {
+					rv = append(rv, e)
+					break
+				}
+			}
+		}
+		if b[1] > next {
+			next = b[1]
+		}
+	}
+	return rv
+}
+
+func (si *secondaryIndex) leadingBounds(rng *datastore.Range2) (int, int) {
+	n := len(si.entries)
+	below := func(i int) bool { return belowLow(si.entries[i].key[0], rng) }
+	above := func(i int) bool { return aboveHigh(si.entries[i].key[0], rng) }
+
+	if si.rangeKey[0].Desc {
+		return sort.Search(n, func(i int) bool { return !above(i) }), sort.Search(n, below)
+	}
+	return sort.Search(n, func(i int) bool { return !below(i) }), sort.Search(n, above)
+}
+
+// spans are expressed in terms of values, whatever the key collation
+func matchSpan2(key value.Values, span *datastore.Span2) bool {
+	for i, s := range span.Seek {
+		if i >= len(key) || key[i].Collate(s) != 0 {
+			return false
+		}
+	}
+	for i, rng := range span.Ranges {
+		if i >= len(key) {
+			break
+		}
+		if belowLow(key[i], rng) || aboveHigh(key[i], rng) {
+			return false
+		}
+	}
+	return true
+}
+
+func belowLow(v value.Value, rng *datastore.Range2) bool {
+	if rng.Low == nil {
+		return false
+	}
+	c := v.Collate(rng.Low)
+	return c < 0 || (c == 0 && rng.Inclusion&datastore.LOW == 0)
+}
+
+func aboveHigh(v value.Value, rng *datastore.Range2) bool {
+	if rng.High == nil {
+		return false
+	}
+	c := v.Collate(rng.High)
+	return c > 0 || (c == 0 && rng.Inclusion&datastore.HIGH == 0)
+}
+
+// the pre Index2 API has composite bounds
+func matchSpan(key value.Values, span *datastore.Span) bool {
+	if len(span.Seek) > 0 {
+		return compareKeys(key, span.Seek) == 0
+	}
+	if len(span.Range.Low) > 0 {
+		c := compareKeys(key, span.Range.Low)
+		if c < 0 || (c == 0 && span.Range.Inclusion&datastore.LOW == 0) {
+			return false
+		}
+	}
+	if len(span.Range.High) > 0 {
+		c := compareKeys(key, span.Range.High)
+		if c > 0 || (c == 0 && span.Range.Inclusion&datastore.HIGH == 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// compares the leading keys to a bound of possibly fewer values
+func compareKeys(key, bound value.Values) int {
+	for i, b := range bound {
+		if i >= len(key) {
+			return -1
+		}
+		if c := key[i].Collate(b); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+func (si *secondaryIndex) compare(a, b *indexEntry) int {
+	for i, k := range si.rangeKey {
+		c := a.key[i].Collate(b.key[i])
+		if c != 0 {
+			if k.Desc {
+				return -c
+			}
+			return c
+		}
+	}
+	return strings.Compare(a.id, b.id)
+}
+
+// evaluate returns the entries of a document: none if the document does not
+// satisfy the index condition or has no leading key, one per element of an
+// array index key.
+//
+// As with GSI, index keys and condition are relative to the document.
+func (si *secondaryIndex) evaluate(id string, doc value.AnnotatedValue) []*indexEntry {
+	context := expression.NewIndexContext()
+
+	if si.where != nil {
+		cond, err := si.where.Evaluate(doc, context)
+		if err != nil || !cond.Truth() {
+			return nil
+		}
+	}
+
+	keys := []value.Values{make(value.Values, 0, len(si.rangeKey))}
+	for i, k := range si.rangeKey {
+		v, vals, err := k.Expr.EvaluateForIndex(doc, context)
+		if err != nil {
+			return nil
+		}
+		if vals == nil {
+			vals = value.Values{v}
+		} else if len(vals) == 0 {
+			vals = value.Values{value.MISSING_VALUE}
+		}
+
+		next := make([]value.Values, 0, len(keys)*len(vals))
+		for _, v := range vals {
+			if i == 0 && v.Type() == value.MISSING {
+				continue
+			}
+			for _, key := range keys {
+				nkey := make(value.Values, i, len(si.rangeKey))
+				copy(nkey, key)
+				next = append(next, append(nkey, v))
+			}
+		}
+		if len(next) == 0 {
+			return nil
+		}
+		keys = next
+	}
+
+	rv := make([]*indexEntry, 0, len(keys))
+	for _, key := range keys {
+		rv = append(rv, &indexEntry{key: key, id: id})
+	}
+	if len(rv) == 1 {
+		return rv
+	}
+
+	// repeated array elements yield the same entry
+	sort.Slice(rv, func(i, j int) bool { return si.compare(rv[i], rv[j]) < 0 })
+	n := 1
+	for _, e := range rv[1:] {
+		if si.compare(rv[n-1], e) != 0 {
+			rv[n] = e
+			n++
+		}
+	}
+	return rv[:n]
+}
+
+func (si *secondaryIndex) search(e *indexEntry) int {
+	return sort.Search(len(si.entries), func(i int) bool { return si.compare(si.entries[i], e) >= 0 })
+}
+
+func (si *secondaryIndex) add(id string, doc value.AnnotatedValue) {
+	entries := si.evaluate(id, doc)
+	for _, e := range entries {
+		i := si.search(e)
+		si.entries = append(si.entries, nil)
+		copy(si.entries[i+1:], si.entries[i:])
+		si.entries[i] = e
+	}
+	if len(entries) > 0 {
+		si.docs[id] = entries
+	}
+}
+
+func (si *secondaryIndex) remove(id string) {
+	for _, e := range si.docs[id] {
+		i := si.search(e)
+		if i < len(si.entries) && si.entries[i] == e {
+			si.entries = append(si.entries[:i], si.entries[i+1:]...)
+		}
+	}
+	delete(si.docs, id)
+}
+
+// update replaces the entries of a document, a nil document removes them
+func (si *secondaryIndex) update(id string, doc value.AnnotatedValue) {
+	si.Lock()
+	defer si.Unlock()
+
+	// deferred indexes pick up all documents when they are built
+	if si.state != datastore.ONLINE {
+		return
+	}
+	si.remove(id)
+	if doc != nil {
+		si.add(id, doc)
+	}
+}
+
+// build computes the entries of all the documents in the keyspace
+func (si *secondaryIndex) build() errors.Error {
+	si.Lock()
+	defer si.Unlock()
+
+	dirEntries, er := ioutil.ReadDir(si.keyspace.path())
+	if er != nil {
+		return errors.NewFileDatastoreError(er, "")
+	}
+
+	entries := make([]*indexEntry, 0, len(dirEntries))
+	docs := make(map[string][]*indexEntry, len(dirEntries))
+	for _, dirEntry := range dirEntries {
+		if dirEntry.IsDir() {
+			continue
+		}
+		doc, e := fetch(filepath.Join(si.keyspace.path(), dirEntry.Name()))
+		if e != nil {
+			return e
+		}
+		id := documentPathToId(dirEntry.Name())
+		if docEntries := si.evaluate(id, doc); len(docEntries) > 0 {
+			docs[id] = docEntries
+			entries = append(entries, docEntries...)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return si.compare(entries[i], entries[j]) < 0 })
+
+	si.entries = entries
+	si.docs = docs
+	si.state = datastore.ONLINE
+	return nil
+}
+
+// aggregate computes the groups and aggregates pushed down by the planner.
+// Aggregates are always complete, which also makes them valid partial ones.
+func (si *secondaryIndex) aggregate(entries []*indexEntry, reverse bool, projection *datastore.IndexProjection,
+	groupAggs *datastore.IndexGroupAggregates) ([]*datastore.IndexEntry, errors.Error) {
+
+	for _, agg := range groupAggs.Aggregates {
+		switch agg.Operation {
+		case datastore.AGG_COUNT, datastore.AGG_COUNTN, datastore.AGG_SUM, datastore.AGG_AVG,
+			datastore.AGG_MIN, datastore.AGG_MAX:
+		default:
+			return nil, errors.NewFileNotSupported(nil, "Index aggregate "+string(agg.Operation)+
+				" is not supported for file-based datastore.")
+		}
+	}
+
+	// expressions over index keys are evaluated against covers
+	// of the index key values
+	covers := make([]*expression.Cover, len(groupAggs.IndexKeyNames))
+	mapped := make([]*expression.Cover, 0, len(covers))
+	for i, name := range groupAggs.IndexKeyNames {
+		if expr, err := n1ql.ParseExpression(name); err == nil {
+			covers[i] = expression.NewCover(expr)
+			mapped = append(mapped, covers[i])
+		}
+	}
+	coverer := expression.NewCoverer(mapped, nil)
+	cover := func(expr expression.Expression) expression.Expression {
+		if expr == nil {
+			return nil
+		}
+		rv, err := coverer.Map(expr.Copy())
+		if err != nil {
+			return expr
+		}
+		return rv
+	}
+
+	groupExprs := make(expression.Expressions, len(groupAggs.Group))
+	for i, g := range groupAggs.Group {
+		groupExprs[i] = cover(g.Expr)
+	}
+	aggExprs := make(expression.Expressions, len(groupAggs.Aggregates))
+	for i, agg := range groupAggs.Aggregates {
+		aggExprs[i] = cover(agg.Expr)
+	}
+
+	context := expression.NewIndexContext()
+	evaluate := func(e *indexEntry, item value.AnnotatedValue, keyPos int, expr expression.Expression) value.Value {
+		if keyPos >= 0 && keyPos < len(e.key) {
+			return e.key[keyPos]
+		}
+		if expr == nil {
+			return value.MISSING_VALUE
+		}
+		v, err := expr.Evaluate(item, context)
+		if err != nil {
+			return value.MISSING_VALUE
+		}
+		return v
+	}
+
+	var seen map[string]bool
+	if groupAggs.OneForPrimaryKey {
+		seen = make(map[string]bool)
+	}
+
+	groups := make(map[string]*indexGroup)
+	var order []*indexGroup
+	for i := range entries {
+		e := entries[i]
+		if reverse {
+			e = entries[len(entries)-1-i]
+		}
+		if seen != nil {
+			if seen[e.id] {
+				continue
+			}
+			seen[e.id] = true
+		}
+
+		item := value.NewAnnotatedValue(map[string]interface{}{})
+		for j, c := range covers {
+			if c == nil {
+				continue
+			}
+			if j < len(e.key) {
+				item.SetCover(c.Text(), e.key[j])
+			} else {
+				item.SetCover(c.Text(), value.NewValue(e.id))
+			}
+		}
+
+		key := make(value.Values, len(groupAggs.Group))
+		for j, g := range groupAggs.Group {
+			key[j] = evaluate(e, item, g.KeyPos, groupExprs[j])
+		}
+		k := distinctKey(&datastore.IndexEntry{EntryKey: key}, &datastore.IndexProjection{})
+		group, ok := groups[k]
+		if !ok {
+			group = newIndexGroup(key, len(groupAggs.Aggregates))
+			groups[k] = group
+			order = append(order, group)
+		}
+		for j, agg := range groupAggs.Aggregates {
+			group.aggs[j].cumulate(agg, evaluate(e, item, agg.KeyPos, aggExprs[j]))
+		}
+	}
+
+	// without GROUP BY, aggregates over nothing still produce a row
+	if len(order) == 0 && len(groupAggs.Group) == 0 {
+		order = append(order, newIndexGroup(nil, len(groupAggs.Aggregates)))
+	}
+
+	var ids []int
+	if projection != nil {
+		ids = projection.EntryKeys
+	} else {
+		for _, g := range groupAggs.Group {
+			ids = append(ids, g.EntryKeyId)
+		}
+		for _, agg := range groupAggs.Aggregates {
+			ids = append(ids, agg.EntryKeyId)
+		}
+	}
+
+	rows := make([]*datastore.IndexEntry, 0, len(order))
+	for _, group := range order {
+		key := make(value.Values, 0, len(ids))
+		for _, id := range ids {
+			key = append(key, group.value(id, groupAggs))
+		}
+		rows = append(rows, &datastore.IndexEntry{EntryKey: key})
+	}
+	return rows, nil
+}
+
+type indexGroup struct {
+	key  value.Values
+	aggs []*aggregateState
+}
+
+func newIndexGroup(key value.Values, naggs int) *indexGroup {
+	rv := &indexGroup{key: key, aggs: make([]*aggregateState, naggs)}
+	for i := range rv.aggs {
+		rv.aggs[i] = &aggregateState{}
+	}
+	return rv
+}
+
+func (this *indexGroup) value(id int, groupAggs *datastore.IndexGroupAggregates) value.Value {
+	for i, g := range groupAggs.Group {
+		if g.EntryKeyId == id {
+			return this.key[i]
+		}
+	}
+	for i, agg := range groupAggs.Aggregates {
+		if agg.EntryKeyId == id {
+			return this.aggs[i].result(agg.Operation)
+		}
+	}
+	return value.MISSING_VALUE
+}
+
+type aggregateState struct {
+	count int64
+	sum   value.NumberValue
+	val   value.Value
+	set   *value.Set
+}
+
+func (this *aggregateState) cumulate(agg *datastore.IndexAggregate, v value.Value) {
+	switch agg.Operation {
+	case datastore.AGG_COUNTN, datastore.AGG_SUM, datastore.AGG_AVG:
+		if v.Type() != value.NUMBER {
+			return
+		}
+	default:
+		if v.Type() <= value.NULL {
+			return
+		}
+	}
+
+	if agg.Distinct {
+		if this.set == nil {
+			this.set = value.NewSet(0, true, false)
+		}
+		this.set.Add(v)
+		return
+	}
+	this.add(agg.Operation, v)
+}
+
+func (this *aggregateState) add(op datastore.AggregateType, v value.Value) {
+	this.count++
+	switch op {
+	case datastore.AGG_SUM, datastore.AGG_AVG:
+		if this.sum == nil {
+			this.sum = value.AsNumberValue(v)
+		} else {
+			this.sum = this.sum.Add(value.AsNumberValue(v))
+		}
+	case datastore.AGG_MIN:
+		if this.val == nil || v.Collate(this.val) < 0 {
+			this.val = v
+		}
+	case datastore.AGG_MAX:
+		if this.val == nil || v.Collate(this.val) > 0 {
+			this.val = v
+		}
+	}
+}
+
+func (this *aggregateState) result(op datastore.AggregateType) value.Value {
+	if this.set != nil {
+		for _, v := range this.set.Values() {
+			this.add(op, v)
+		}
+		this.set = nil
+	}
+
+	switch op {
+	case datastore.AGG_COUNT, datastore.AGG_COUNTN:
+		return value.NewValue(this.count)
+	case datastore.AGG_SUM:
+		if this.sum == nil {
+			return value.NULL_VALUE
+		}
+		return this.sum
+	case datastore.AGG_AVG:
+		if this.count == 0 {
+			return value.NULL_VALUE
+		}
+		return value.NewValue(this.sum.Float64() / float64(this.count))
+	default:
+		if this.val == nil {
+			return value.NULL_VALUE
+		}
+		return this.val
+	}
+}
+
+func (fi *fileIndexer) indexesPath() string {
+	return filepath.Join(fi.keyspace.parentPath(), fi.keyspace.name+_INDEX_FILE_SUFFIX)
+}
+
+// loadIndexes recreates the secondary indexes defined on the keyspace
+func (fi *fileIndexer) loadIndexes() errors.Error {
+	bytes, er := ioutil.ReadFile(fi.indexesPath())
+	if er != nil {
+		if os.IsNotExist(er) {
+			return nil
+		}
+		return errors.NewFileDatastoreError(er, "")
+	}
+
+	var defs []*indexDefinition
+	er = json.Unmarshal(bytes, &defs)
+	if er != nil {
+		return errors.NewFileDatastoreError(er, "Invalid index definitions "+fi.indexesPath())
+	}
+
+	fi.Lock()
+	defer fi.Unlock()
+	for _, def := range defs {
+		rangeKey := make(datastore.IndexKeys, 0, len(def.Keys))
+		for _, key := range def.Keys {
+			expr, er := n1ql.ParseExpression(key.Expr)
+			if er != nil {
+				return errors.NewFileDatastoreError(er, "Invalid key of index "+def.Name)
+			}
+			rangeKey = append(rangeKey, &datastore.IndexKey{Expr: expr, Desc: key.Desc})
+		}
+
+		var where expression.Expression
+		if def.Where != "" {
+			where, er = n1ql.ParseExpression(def.Where)
+			if er != nil {
+				return errors.NewFileDatastoreError(er, "Invalid condition of index "+def.Name)
+			}
+		}
+
+		index := newSecondaryIndex(fi, def.Name, rangeKey, where)
+		if !def.Deferred {
+			if e := index.build(); e != nil {
+				return e
+			}
+		}
+		fi.indexes[def.Name] = index
+		fi.secondary[def.Name] = index
+	}
+	return nil
+}
+
+// saveIndexes writes out the definitions of the secondary indexes,
+// it must be called with the indexer locked
+func (fi *fileIndexer) saveIndexes() errors.Error {
+	path := fi.indexesPath()
+	if len(fi.secondary) == 0 {
+		if er := os.Remove(path); er != nil && !os.IsNotExist(er) {
+			return errors.NewFileDatastoreError(er, "")
+		}
+		return nil
+	}
+
+	names := make([]string, 0, len(fi.secondary))
+	for name, _ := range fi.secondary {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	defs := make([]*indexDefinition, 0, len(names))
+	for _, name := range names {
+		index := fi.secondary[name]
+		def := &indexDefinition{Name: name, Keys: make([]indexKeyDefinition, 0, len(index.rangeKey))}
+		for _, key := range index.rangeKey {
+			def.Keys = append(def.Keys, indexKeyDefinition{Expr: key.Expr.String(), Desc: key.Desc})
+		}
+		if index.where != nil {
+			def.Where = index.where.String()
+		}
+		if state, _, _ := index.State(); state == datastore.DEFERRED {
+			def.Deferred = true
+		}
+		defs = append(defs, def)
+	}
+
+	bytes, er := json.MarshalIndent(defs, "", "    ")
+	if er == nil {
+		er = ioutil.WriteFile(path, bytes, 0666)
+	}
+	if er != nil {
+		return errors.NewFileDatastoreError(er, "")
+	}
+	return nil
+}
@@ -20,9 +20,12 @@ package mock
 import (
 	"encoding/json"
 	"fmt"
+	"math"
 	"net/http"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/couchbase/query/auth"
 	"github.com/couchbase/query/datastore"
@@ -30,6 +33,7 @@ import (
 	"github.com/couchbase/query/errors"
 	"github.com/couchbase/query/expression"
 	"github.com/couchbase/query/logging"
+	"github.com/couchbase/query/statistics"
 	"github.com/couchbase/query/timestamp"
 	"github.com/couchbase/query/value"
 )
@@ -46,6 +50,10 @@ type store struct {
 	namespaces     map[string]*namespace
 	namespaceNames []string
 	params         map[string]int
+	statUpdater    *statistics.DefaultStatUpdater
+	functionsLock  sync.RWMutex
+	functions      map[string][]byte
+	libraries      map[string][]byte
 }
 
 func (s *store) Id() string {
@@ -102,13 +110,91 @@ func (s *store) Inferencers() ([]datastore.Inferencer, errors.Error) {
 }
 
 func (s *store) StatUpdater() (datastore.StatUpdater, errors.Error) {
-	return nil, errors.NewOtherNotImplementedError(nil, "UPDATE STATISTICS")
+	return s.statUpdater, nil
 }
 
 func (s *store) SetConnectionSecurityConfig(conSecConfig *datastore.ConnectionSecurityConfig) {
 	// Do nothing
 }
 
+// user defined functions and their libraries are kept in memory only
+func (s *store) FunctionKeys() ([]string, errors.Error) {
+	s.functionsLock.RLock()
+	defer s.functionsLock.RUnlock()
+	keys := make([]string, 0, len(s.functions))
+	for key, _ := range s.functions {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (s *store) GetFunction(key string) ([]byte, errors.Error) {
+	s.functionsLock.RLock()
+	defer s.functionsLock.RUnlock()
+	return s.functions[key], nil
+}
+
+func (s *store) AddFunction(key string, definition []byte) errors.Error {
+	s.functionsLock.Lock()
+	defer s.functionsLock.Unlock()
+	if _, ok := s.functions[key]; ok {
+		return errors.NewDuplicateFunctionError(key)
+	}
+	if s.functions == nil {
+		s.functions = make(map[string][]byte)
+	}
+	s.functions[key] = definition
+	return nil
+}
+
+func (s *store) DeleteFunction(key string) errors.Error {
+	s.functionsLock.Lock()
+	defer s.functionsLock.Unlock()
+	if _, ok := s.functions[key]; !ok {
+		return errors.NewMissingFunctionError(key)
+	}
+	delete(s.functions, key)
+	return nil
+}
+
+func (s *store) LibraryNames() ([]string, errors.Error) {
+	s.functionsLock.RLock()
+	defer s.functionsLock.RUnlock()
+	names := make([]string, 0, len(s.libraries))
+	for name, _ := range s.libraries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (s *store) GetLibrary(name string) ([]byte, errors.Error) {
+	s.functionsLock.RLock()
+	defer s.functionsLock.RUnlock()
+	return s.libraries[name], nil
+}
+
+func (s *store) SetLibrary(name string, source []byte) errors.Error {
+	s.functionsLock.Lock()
+	defer s.functionsLock.Unlock()
+	if s.libraries == nil {
+		s.libraries = make(map[string][]byte)
+	}
+	s.libraries[name] = source
+	return nil
+}
+
+func (s *store) DeleteLibrary(name string) errors.Error {
+	s.functionsLock.Lock()
+	defer s.functionsLock.Unlock()
+	if _, ok := s.libraries[name]; !ok {
+		return errors.NewMissingLibraryError(name)
+	}
+	delete(s.libraries, name)
+	return nil
+}
+
 func (s *store) AuditInfo() (*datastore.AuditInfo, errors.Error) {
 	return nil, errors.NewOtherNotImplementedError(nil, "AuditInfo")
 }
@@ -209,11 +295,15 @@ func (p *namespace) BucketByName(name string) (datastore.Bucket, errors.Error) {
 }
 
 // keyspace is a mock-based keyspace.
+// Documents are generated on the fly: the mutations committed by
+// transactions are kept in memory, over the generated documents.
 type keyspace struct {
+	sync.RWMutex
 	namespace *namespace
 	name      string
 	nitems    int
 	mi        datastore.Indexer
+	docs      map[string]value.Value // written documents, nil if deleted
 }
 
 func (b *keyspace) NamespaceId() string {
@@ -240,8 +330,22 @@ func (b *keyspace) Name() string {
 	return b.name
 }
 
+func (b *keyspace) QualifiedName() string {
+	return datastore.QualifiedKeyspaceName(b.namespace.Name(), "", "", b.name)
+}
+
 func (b *keyspace) Count(context datastore.QueryContext) (int64, errors.Error) {
-	return int64(b.nitems), nil
+	b.RLock()
+	defer b.RUnlock()
+	count := int64(b.nitems)
+	for k, v := range b.docs {
+		if v == nil {
+			count--
+		} else if !b.generated(k) {
+			count++
+		}
+	}
+	return count, nil
 }
 
 func (b *keyspace) Size(context datastore.QueryContext) (int64, errors.Error) {
@@ -260,8 +364,23 @@ func (b *keyspace) Fetch(keys []string, keysMap map[string]value.AnnotatedValue,
 	context datastore.QueryContext, subPaths []string) []errors.Error {
 	var errs []errors.Error
 
+	b.RLock()
+	defer b.RUnlock()
 	for _, k := range keys {
-		item, e := b.fetchOne(k)
+		var item value.AnnotatedValue
+		var e errors.Error
+
+		doc, ok := b.docs[k]
+		if ok {
+			if doc == nil {
+
+				// deleted => key denotes non-existent doc => ignore it
+				continue
+			}
+			item = value.NewAnnotatedValue(doc.CopyForUpdate())
+		} else {
+			item, e = b.fetchOne(k)
+		}
 		if e != nil {
 			if errs == nil {
 				errs = make([]errors.Error, 0, 1)
@@ -324,6 +443,93 @@ func (b *keyspace) Delete(deletes []string, context datastore.QueryContext) ([]s
 	return nil, errors.NewOtherNotImplementedError(nil, "for Mock datastore")
 }
 
+// ApplyMutations applies the mutations of a committing transaction under the
+// keyspace lock, once all the preconditions have been checked.
+func (b *keyspace) ApplyMutations(mutations datastore.Mutations) (datastore.Mutations, errors.Error) {
+	b.Lock()
+	defer b.Unlock()
+	for _, m := range mutations {
+		if b.exists(m.Key) == (m.Op == datastore.MUTATE_INSERT) {
+			return nil, errors.NewTransactionConflictError(b.name, m.Key, m.Op.String())
+		}
+	}
+
+	undo := make(datastore.Mutations, len(mutations))
+	for i, m := range mutations {
+		undo[i].Key = m.Key
+		switch m.Op {
+		case datastore.MUTATE_INSERT:
+			undo[i].Op = datastore.MUTATE_DELETE
+		case datastore.MUTATE_UPDATE:
+			undo[i].Op = datastore.MUTATE_UPDATE
+			undo[i].Value = b.current(m.Key)
+		case datastore.MUTATE_DELETE:
+			undo[i].Op = datastore.MUTATE_INSERT
+			undo[i].Value = b.current(m.Key)
+		}
+		b.write(m.Key, m.Value)
+	}
+	return undo, nil
+}
+
+// generated returns true for the keys of the generated documents.
+func (b *keyspace) generated(key string) bool {
+	i, e := strconv.Atoi(key)
+	return e == nil && i >= 0 && i < b.nitems && strconv.Itoa(i) == key
+}
+
+// The caller must hold the keyspace lock for exists, current and write.
+func (b *keyspace) exists(key string) bool {
+	doc, ok := b.docs[key]
+	if ok {
+		return doc != nil
+	}
+	return b.generated(key)
+}
+
+func (b *keyspace) current(key string) value.Value {
+	doc, ok := b.docs[key]
+	if ok {
+		return doc
+	}
+	if !b.generated(key) {
+		return nil
+	}
+	i, _ := strconv.Atoi(key)
+	item, _ := genItem(i, b.nitems)
+	return item.GetValue()
+}
+
+func (b *keyspace) write(key string, doc value.Value) {
+	if b.docs == nil {
+		b.docs = make(map[string]value.Value)
+	}
+	if doc != nil {
+		doc = doc.CopyForUpdate()
+	}
+	b.docs[key] = doc
+}
+
+// scanKeys returns the keys of the deleted documents, and the sorted keys
+// of the documents inserted in addition to the generated ones, for scans
+// to apply to the generated keys.
+func (b *keyspace) scanKeys() (map[string]bool, []string) {
+	var added []string
+
+	b.RLock()
+	defer b.RUnlock()
+	deleted := make(map[string]bool)
+	for k, v := range b.docs {
+		if v == nil {
+			deleted[k] = true
+		} else if !b.generated(k) {
+			added = append(added, k)
+		}
+	}
+	sort.Strings(added)
+	return deleted, added
+}
+
 func (b *keyspace) Release() {
 }
 
@@ -466,6 +672,14 @@ func NewDatastore(path string) (datastore.Datastore, errors.Error) {
 		s.namespaces[p.name] = p
 		s.namespaceNames = append(s.namespaceNames, p.name)
 	}
+
+	// statistics are kept in memory only
+	statUpdater, err := statistics.NewDefaultStatUpdater(s, "")
+	if err != nil {
+		return nil, err
+	}
+	s.statUpdater = statUpdater
+	datastore.SetDictionary(statUpdater)
 	return s, nil
 }
 
@@ -566,10 +780,13 @@ func (pi *primaryIndex) Scan(requestId string, span *datastore.Span, distinct bo
 	}
 
 	if limit == 0 {
-		limit = int64(pi.keyspace.nitems)
+		limit = math.MaxInt64
 	}
 
-	for i := 0; i < pi.keyspace.nitems && int64(i) < limit; i++ {
+	deleted, added := pi.keyspace.scanKeys()
+	sent := int64(0)
+	lowKey := low
+	for i := 0; i < pi.keyspace.nitems && sent < limit; i++ {
 		id := strconv.Itoa(i)
 
 		if low != "" &&
@@ -586,8 +803,32 @@ func (pi *primaryIndex) Scan(requestId string, span *datastore.Span, distinct bo
 			break
 		}
 
+		if deleted[id] {
+			continue
+		}
 		entry := datastore.IndexEntry{PrimaryKey: id}
 		conn.Sender().SendEntry(&entry)
+		sent++
+	}
+
+	// documents inserted in addition to the generated ones
+	for _, id := range added {
+		if sent >= limit {
+			break
+		}
+		if lowKey != "" &&
+			(id < lowKey ||
+				(id == lowKey && (span.Range.Inclusion&datastore.LOW == 0))) {
+			continue
+		}
+		if high != "" &&
+			(id > high ||
+				(id == high && (span.Range.Inclusion&datastore.HIGH == 0))) {
+			break
+		}
+		entry := datastore.IndexEntry{PrimaryKey: id}
+		conn.Sender().SendEntry(&entry)
+		sent++
 	}
 }
 
@@ -596,11 +837,26 @@ func (pi *primaryIndex) ScanEntries(requestId string, limit int64, cons datastor
 	defer conn.Sender().Close()
 
 	if limit == 0 {
-		limit = int64(pi.keyspace.nitems)
+		limit = math.MaxInt64
 	}
 
-	for i := 0; i < pi.keyspace.nitems && int64(i) < limit; i++ {
-		entry := datastore.IndexEntry{PrimaryKey: strconv.Itoa(i)}
+	deleted, added := pi.keyspace.scanKeys()
+	sent := int64(0)
+	for i := 0; i < pi.keyspace.nitems && sent < limit; i++ {
+		id := strconv.Itoa(i)
+		if deleted[id] {
+			continue
+		}
+		entry := datastore.IndexEntry{PrimaryKey: id}
+		conn.Sender().SendEntry(&entry)
+		sent++
+	}
+	for _, id := range added {
+		if sent >= limit {
+			break
+		}
+		entry := datastore.IndexEntry{PrimaryKey: id}
 		conn.Sender().SendEntry(&entry)
+		sent++
 	}
 }
@@ -15,6 +15,7 @@ import (
 
 	"github.com/couchbase/query/datastore"
 	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/expression"
 	"github.com/couchbase/query/value"
 )
 
@@ -259,3 +260,115 @@ func doIndexScan(t *testing.T, b datastore.Keyspace, span *datastore.Span) (
 
 	return
 }
+
+func TestMockMutations(t *testing.T) {
+	s, err := NewDatastore("mock:")
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+
+	p, err := s.NamespaceById("p0")
+	if err != nil || p == nil {
+		t.Fatalf("expected namespace p0")
+	}
+
+	b, err := p.KeyspaceById("b0")
+	if err != nil || b == nil {
+		t.Fatalf("expected keyspace b0")
+	}
+
+	atomic := b.(datastore.AtomicKeyspace)
+	_, err = atomic.ApplyMutations(datastore.Mutations{
+		datastore.Mutation{Op: datastore.MUTATE_INSERT, Key: "new", Value: value.NewValue(map[string]interface{}{"id": "new"})},
+		datastore.Mutation{Op: datastore.MUTATE_DELETE, Key: "1"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error applying mutations: %v", err)
+	}
+
+	c, err := b.Count(datastore.NULL_QUERY_CONTEXT)
+	if err != nil || c != int64(DEFAULT_NUM_ITEMS) {
+		t.Fatalf("expected num items, got %v", c)
+	}
+
+	vs := make(map[string]value.AnnotatedValue, 2)
+	b.Fetch([]string{"new", "1"}, vs, datastore.NULL_QUERY_CONTEXT, nil)
+	if vs["new"] == nil || vs["1"] != nil {
+		t.Fatalf("unexpected fetch result: %v", vs)
+	}
+
+	// the update of a missing document fails, and no mutation is applied
+	_, err = atomic.ApplyMutations(datastore.Mutations{
+		datastore.Mutation{Op: datastore.MUTATE_DELETE, Key: "2"},
+		datastore.Mutation{Op: datastore.MUTATE_UPDATE, Key: "1", Value: value.NewValue(1)},
+	})
+	if err == nil || err.Code() != errors.TRANSACTION_CONFLICT {
+		t.Fatalf("expected conflict, got %v", err)
+	}
+	vs = make(map[string]value.AnnotatedValue, 1)
+	b.Fetch([]string{"2"}, vs, datastore.NULL_QUERY_CONTEXT, nil)
+	if vs["2"] == nil {
+		t.Fatalf("expected item 2")
+	}
+
+	// applying the undo mutations restores the keyspace
+	undo, err := atomic.ApplyMutations(datastore.Mutations{
+		datastore.Mutation{Op: datastore.MUTATE_DELETE, Key: "2"},
+		datastore.Mutation{Op: datastore.MUTATE_INSERT, Key: "1", Value: value.NewValue(1)},
+	})
+	if err != nil || len(undo) != 2 {
+		t.Fatalf("unexpected apply result: %v %v", undo, err)
+	}
+	_, err = atomic.ApplyMutations(undo)
+	if err != nil {
+		t.Fatalf("unexpected error in undo: %v", err)
+	}
+	vs = make(map[string]value.AnnotatedValue, 2)
+	b.Fetch([]string{"1", "2"}, vs, datastore.NULL_QUERY_CONTEXT, nil)
+	if vs["1"] != nil || vs["2"] == nil {
+		t.Fatalf("unexpected fetch result after undo: %v", vs)
+	}
+}
+
+func TestMockStatistics(t *testing.T) {
+	s, err := NewDatastore("mock:namespaces=2,keyspaces=1,items=10")
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+
+	updater, err := s.StatUpdater()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	terms := expression.Expressions{expression.NewIdentifier("id")}
+
+	// b0 exists in both namespaces, its statistics are kept apart
+	for _, ns := range []string{"p0", "p1"} {
+		p, _ := s.NamespaceByName(ns)
+		b, err := p.KeyspaceByName("b0")
+		if err != nil {
+			t.Fatalf("expected keyspace b0")
+		}
+		if b.QualifiedName() != ns+":b0" {
+			t.Fatalf("unexpected qualified name %v", b.QualifiedName())
+		}
+
+		conn := datastore.NewValueConnection(datastore.NULL_CONTEXT)
+		updater.UpdateStatistics(b, terms, nil, conn, nil)
+	}
+
+	dictionary := datastore.GetDictionary()
+	if ks := dictionary.Keyspaces(); len(ks) != 2 || ks[0] != "p0:b0" || ks[1] != "p1:b0" {
+		t.Fatalf("unexpected keyspaces with statistics %v", ks)
+	}
+	if dictionary.Histogram("p1:b0", "(`p1:b0`.`id`)") == nil {
+		t.Fatalf("expected histogram on p1:b0")
+	}
+
+	p, _ := s.NamespaceByName("p0")
+	b, _ := p.KeyspaceByName("b0")
+	updater.(datastore.StatDeleter).DeleteStatistics(b, nil, datastore.NewValueConnection(datastore.NULL_CONTEXT), nil)
+	if dictionary.KeyspaceInfo("p0:b0") != nil || dictionary.KeyspaceInfo("p1:b0") == nil {
+		t.Fatalf("unexpected keyspaces with statistics %v", dictionary.Keyspaces())
+	}
+}
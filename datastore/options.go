@@ -0,0 +1,75 @@
+//  Copyright (c) 2020 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package datastore
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/value"
+)
+
+// Write options carried by value.Pair
+const OPT_EXPIRATION = "expiration"
+
+// As in the KV protocol, expirations up to 30 days are relative to the
+// time of the write, larger ones are absolute unix times in seconds.
+const MAX_RELATIVE_EXPIRATION = 30 * 24 * 60 * 60
+
+// GetExpiration returns the document expiration set in the write options,
+// in KV form, and whether the options set one at all.
+// An expiration of 0 means the document does not expire.
+func GetExpiration(options value.Value) (uint32, bool, errors.Error) {
+	if options == nil || options.Type() != value.OBJECT {
+		return 0, false, nil
+	}
+	exp, ok := options.Field(OPT_EXPIRATION)
+	if !ok || exp.Type() <= value.NULL {
+		return 0, false, nil
+	}
+	if exp.Type() != value.NUMBER {
+		return 0, false, invalidExpiration(exp)
+	}
+	e := value.AsNumberValue(exp).Int64()
+	if e < 0 || e > math.MaxUint32 {
+		return 0, false, invalidExpiration(exp)
+	}
+	return uint32(e), true, nil
+}
+
+func invalidExpiration(exp value.Value) errors.Error {
+	return errors.NewInvalidValueError(fmt.Sprintf("Invalid %s %v: must be a number of seconds between 0 and %d",
+		OPT_EXPIRATION, exp, uint32(math.MaxUint32)))
+}
+
+// ExpirationTime returns the absolute time of a KV expiration written at
+// the given time, or the zero time if the document does not expire.
+func ExpirationTime(exp uint32, now time.Time) time.Time {
+	switch {
+	case exp == 0:
+		return time.Time{}
+	case exp <= MAX_RELATIVE_EXPIRATION:
+		return now.Add(time.Duration(exp) * time.Second)
+	default:
+		return time.Unix(int64(exp), 0)
+	}
+}
+
+// ExpirationOptions returns the write options that restore the expiration
+// of a document, as reported in its meta data.
+func ExpirationOptions(item value.AnnotatedValue) value.Value {
+	var exp uint32
+	if meta, ok := item.GetAttachment("meta").(map[string]interface{}); ok {
+		exp, _, _ = GetExpiration(value.NewValue(map[string]interface{}{OPT_EXPIRATION: meta[OPT_EXPIRATION]}))
+	}
+	return value.NewValue(map[string]interface{}{OPT_EXPIRATION: exp})
+}
@@ -26,6 +26,7 @@ const NAMESPACE_NAME = "#system"
 const KEYSPACE_NAME_DATASTORES = "datastores"
 const KEYSPACE_NAME_NAMESPACES = "namespaces"
 const KEYSPACE_NAME_KEYSPACES = "keyspaces"
+const KEYSPACE_NAME_SCOPES = "scopes"
 const KEYSPACE_NAME_INDEXES = "indexes"
 const KEYSPACE_NAME_DUAL = "dual"
 const KEYSPACE_NAME_PREPAREDS = "prepareds"
@@ -38,6 +39,9 @@ const KEYSPACE_NAME_MY_USER_INFO = "my_user_info"
 const KEYSPACE_NAME_NODES = "nodes"
 const KEYSPACE_NAME_APPLICABLE_ROLES = "applicable_roles"
 const KEYSPACE_NAME_TASKS_CACHE = "tasks_cache"
+const KEYSPACE_NAME_DICTIONARY = "dictionary"
+const KEYSPACE_NAME_TRANSACTIONS = "transactions"
+const KEYSPACE_NAME_RESULT_CACHE = "result_cache"
 
 // TODO, sync with fetch timeout
 const scanTimeout = 30 * time.Second
@@ -44,6 +44,10 @@ func (b *activeRequestsKeyspace) Name() string {
 	return b.name
 }
 
+func (b *activeRequestsKeyspace) QualifiedName() string {
+	return datastore.QualifiedKeyspaceName(b.namespace.Name(), "", "", b.name)
+}
+
 func (b *activeRequestsKeyspace) Count(context datastore.QueryContext) (int64, errors.Error) {
 	var count int
 
@@ -141,6 +145,16 @@ func (b *activeRequestsKeyspace) Fetch(keys []string, keysMap map[string]value.A
 				if p != nil {
 					item.SetField("phaseTimes", p)
 				}
+				usedMemory := request.UsedMemory()
+				if usedMemory != 0 {
+					item.SetField("usedMemory", usedMemory)
+				}
+				if request.WorkloadGroup() != "" {
+					item.SetField("workloadGroup", request.WorkloadGroup())
+				}
+				if request.QueueWaitTime() != 0 {
+					item.SetField("queueWaitTime", request.QueueWaitTime().String())
+				}
 
 				if request.Prepared() != nil {
 					p := request.Prepared()
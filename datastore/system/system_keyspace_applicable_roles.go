@@ -42,6 +42,10 @@ func (b *applicableRolesKeyspace) Name() string {
 	return b.name
 }
 
+func (b *applicableRolesKeyspace) QualifiedName() string {
+	return datastore.QualifiedKeyspaceName(b.namespace.Name(), "", "", b.name)
+}
+
 func (b *applicableRolesKeyspace) Count(context datastore.QueryContext) (int64, errors.Error) {
 	users, err := datastore.GetDatastore().GetUserInfoAll()
 	if err != nil {
@@ -38,6 +38,10 @@ func (b *storeKeyspace) Name() string {
 	return b.name
 }
 
+func (b *storeKeyspace) QualifiedName() string {
+	return datastore.QualifiedKeyspaceName(b.namespace.Name(), "", "", b.name)
+}
+
 func (b *storeKeyspace) Count(context datastore.QueryContext) (int64, errors.Error) {
 	return 1, nil
 }
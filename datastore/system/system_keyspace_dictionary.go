@@ -0,0 +1,260 @@
+//  Copyright (c) 2019 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package system
+
+import (
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/timestamp"
+	"github.com/couchbase/query/value"
+)
+
+// The optimizer statistics, one document per keyspace
+type dictionaryKeyspace struct {
+	keyspaceBase
+	name string
+	di   datastore.Indexer
+}
+
+func (b *dictionaryKeyspace) Release() {
+}
+
+func (b *dictionaryKeyspace) NamespaceId() string {
+	return b.namespace.Id()
+}
+
+func (b *dictionaryKeyspace) Id() string {
+	return b.Name()
+}
+
+func (b *dictionaryKeyspace) Name() string {
+	return b.name
+}
+
+func (b *dictionaryKeyspace) QualifiedName() string {
+	return datastore.QualifiedKeyspaceName(b.namespace.Name(), "", "", b.name)
+}
+
+func (b *dictionaryKeyspace) Count(context datastore.QueryContext) (int64, errors.Error) {
+	dictionary := datastore.GetDictionary()
+	if dictionary == nil {
+		return 0, nil
+	}
+	return int64(len(dictionary.Keyspaces())), nil
+}
+
+func (b *dictionaryKeyspace) Size(context datastore.QueryContext) (int64, errors.Error) {
+	return -1, nil
+}
+
+func (b *dictionaryKeyspace) Indexer(name datastore.IndexType) (datastore.Indexer, errors.Error) {
+	return b.di, nil
+}
+
+func (b *dictionaryKeyspace) Indexers() ([]datastore.Indexer, errors.Error) {
+	return []datastore.Indexer{b.di}, nil
+}
+
+func (b *dictionaryKeyspace) Fetch(keys []string, keysMap map[string]value.AnnotatedValue,
+	context datastore.QueryContext, subPaths []string) (errs []errors.Error) {
+	dictionary := datastore.GetDictionary()
+	if dictionary == nil {
+		return
+	}
+
+	for _, k := range keys {
+		info := dictionary.KeyspaceInfo(k)
+		if info == nil {
+			continue
+		}
+
+		histograms := dictionary.Histograms(k)
+		keys := make([]interface{}, 0, len(histograms))
+		distributions := make([]interface{}, 0, len(histograms))
+		for _, hist := range histograms {
+			keys = append(keys, hist.Key().String())
+			distributions = append(distributions, histogramValue(hist))
+		}
+
+		item := value.NewAnnotatedValue(map[string]interface{}{
+			"keyspace":         k,
+			"docCount":         info.DocCount,
+			"avgDocSize":       info.AvgDocSize,
+			"distributionKeys": keys,
+			"distributions":    distributions,
+		})
+		item.SetAttachment("meta", map[string]interface{}{
+			"id": k,
+		})
+		item.SetId(k)
+		keysMap[k] = item
+	}
+	return
+}
+
+func histogramValue(hist *datastore.Histogram) map[string]interface{} {
+	distrib := make([]interface{}, 0, len(hist.Distrib()))
+	for _, bin := range hist.Distrib() {
+		distrib = append(distrib, map[string]interface{}{
+			"size":     bin.Size(),
+			"distinct": bin.Distinct(),
+			"max":      bin.Max(),
+		})
+	}
+
+	ovrflow := make([]interface{}, 0, len(hist.Ovrflow()))
+	for _, bin := range hist.Ovrflow() {
+		ovrflow = append(ovrflow, map[string]interface{}{
+			"size": bin.Size(),
+			"val":  bin.Val(),
+		})
+	}
+
+	rv := map[string]interface{}{
+		"key":          hist.Key().String(),
+		"sampleSize":   hist.SampleSize(),
+		"resolution":   hist.Resolution(),
+		"distinctFrac": hist.Fdistincts(),
+		"distribution": distrib,
+		"overflow":     ovrflow,
+	}
+	if hist.AvgArrayLen() > 0 {
+		rv["avgArrayLen"] = hist.AvgArrayLen()
+	}
+	return rv
+}
+
+func (b *dictionaryKeyspace) Insert(inserts []value.Pair) ([]value.Pair, errors.Error) {
+	return nil, errors.NewSystemNotImplementedError(nil, "")
+}
+
+func (b *dictionaryKeyspace) Update(updates []value.Pair) ([]value.Pair, errors.Error) {
+	return nil, errors.NewSystemNotImplementedError(nil, "")
+}
+
+func (b *dictionaryKeyspace) Upsert(upserts []value.Pair) ([]value.Pair, errors.Error) {
+	return nil, errors.NewSystemNotImplementedError(nil, "")
+}
+
+func (b *dictionaryKeyspace) Delete(deletes []string, context datastore.QueryContext) ([]string, errors.Error) {
+	return nil, errors.NewSystemNotImplementedError(nil, "")
+}
+
+func newDictionaryKeyspace(p *namespace) (*dictionaryKeyspace, errors.Error) {
+	b := new(dictionaryKeyspace)
+	setKeyspaceBase(&b.keyspaceBase, p)
+	b.name = KEYSPACE_NAME_DICTIONARY
+
+	primary := &dictionaryIndex{name: "#primary", keyspace: b}
+	b.di = newSystemIndexer(b, primary)
+	setIndexBase(&primary.indexBase, b.di)
+
+	return b, nil
+}
+
+type dictionaryIndex struct {
+	indexBase
+	name     string
+	keyspace *dictionaryKeyspace
+}
+
+func (pi *dictionaryIndex) KeyspaceId() string {
+	return pi.keyspace.Id()
+}
+
+func (pi *dictionaryIndex) Id() string {
+	return pi.Name()
+}
+
+func (pi *dictionaryIndex) Name() string {
+	return pi.name
+}
+
+func (pi *dictionaryIndex) Type() datastore.IndexType {
+	return datastore.SYSTEM
+}
+
+func (pi *dictionaryIndex) SeekKey() expression.Expressions {
+	return nil
+}
+
+func (pi *dictionaryIndex) RangeKey() expression.Expressions {
+	return nil
+}
+
+func (pi *dictionaryIndex) Condition() expression.Expression {
+	return nil
+}
+
+func (pi *dictionaryIndex) IsPrimary() bool {
+	return true
+}
+
+func (pi *dictionaryIndex) State() (state datastore.IndexState, msg string, err errors.Error) {
+	return datastore.ONLINE, "", nil
+}
+
+func (pi *dictionaryIndex) Statistics(requestId string, span *datastore.Span) (
+	datastore.Statistics, errors.Error) {
+	return nil, nil
+}
+
+func (pi *dictionaryIndex) Drop(requestId string) errors.Error {
+	return errors.NewSystemIdxNoDropError(nil, "")
+}
+
+func (pi *dictionaryIndex) Scan(requestId string, span *datastore.Span, distinct bool, limit int64,
+	cons datastore.ScanConsistency, vector timestamp.Vector, conn *datastore.IndexConnection) {
+	if span == nil {
+		pi.ScanEntries(requestId, limit, cons, vector, conn)
+		return
+	}
+
+	defer conn.Sender().Close()
+
+	spanEvaluator, err := compileSpan(span)
+	if err != nil {
+		conn.Error(err)
+		return
+	}
+
+	dictionary := datastore.GetDictionary()
+	if dictionary == nil {
+		return
+	}
+	for _, name := range dictionary.Keyspaces() {
+		if spanEvaluator.evaluate(name) {
+			entry := datastore.IndexEntry{PrimaryKey: name}
+			if !sendSystemKey(conn, &entry) {
+				return
+			}
+		}
+	}
+}
+
+func (pi *dictionaryIndex) ScanEntries(requestId string, limit int64, cons datastore.ScanConsistency,
+	vector timestamp.Vector, conn *datastore.IndexConnection) {
+	defer conn.Sender().Close()
+
+	dictionary := datastore.GetDictionary()
+	if dictionary == nil {
+		return
+	}
+	for i, name := range dictionary.Keyspaces() {
+		if limit > 0 && int64(i) >= limit {
+			break
+		}
+		entry := datastore.IndexEntry{PrimaryKey: name}
+		if !sendSystemKey(conn, &entry) {
+			return
+		}
+	}
+}
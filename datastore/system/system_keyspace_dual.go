@@ -38,6 +38,10 @@ func (b *dualKeyspace) Name() string {
 	return b.name
 }
 
+func (b *dualKeyspace) QualifiedName() string {
+	return datastore.QualifiedKeyspaceName(b.namespace.Name(), "", "", b.name)
+}
+
 func (b *dualKeyspace) Count(context datastore.QueryContext) (int64, errors.Error) {
 	return 1, nil
 }
@@ -41,9 +41,24 @@ func (b *functionsKeyspace) Name() string {
 	return b.name
 }
 
+func (b *functionsKeyspace) QualifiedName() string {
+	return datastore.QualifiedKeyspaceName(b.namespace.Name(), "", "", b.name)
+}
+
+// datastores can keep function definitions themselves, rather than in metakv
+func (b *functionsKeyspace) localStorage() datastore.FunctionsStorage {
+	storage, _ := b.namespace.store.actualStore.(datastore.FunctionsStorage)
+	return storage
+}
+
 func (b *functionsKeyspace) Count(context datastore.QueryContext) (int64, errors.Error) {
+	if storage := b.localStorage(); storage != nil {
+		keys, err := storage.FunctionKeys()
+		return int64(len(keys)), err
+	}
+
 	children, err := metakv.ListAllChildren(_FUNC_PATH)
-	if err != nil {
+	if err == nil {
 		return int64(len(children)), nil
 	} else {
 		return 0, errors.NewMetaKVError("Count", err)
@@ -87,6 +102,17 @@ func (b *functionsKeyspace) Fetch(keys []string, keysMap map[string]value.Annota
 }
 
 func (b *functionsKeyspace) fetchOne(key string) (value.AnnotatedValue, errors.Error) {
+	if storage := b.localStorage(); storage != nil {
+		body, err := storage.GetFunction(key)
+		if err == nil && body == nil {
+			return nil, errors.NewSystemDatastoreError(nil, "Key Not Found "+key)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return value.NewAnnotatedValue(value.NewParsedValue(body, false)), nil
+	}
+
 	body, _, err := metakv.Get(_FUNC_PATH + key)
 
 	// get does not return is not found, but nil, nil instead
@@ -191,6 +217,32 @@ func (pi *functionsIndex) Scan(requestId string, span *datastore.Span, distinct
 	cons datastore.ScanConsistency, vector timestamp.Vector, conn *datastore.IndexConnection) {
 	if span == nil {
 		pi.ScanEntries(requestId, limit, cons, vector, conn)
+	} else if storage := pi.keyspace.localStorage(); storage != nil {
+		defer conn.Sender().Close()
+
+		spanEvaluator, err := compileSpan(span)
+		if err != nil {
+			conn.Error(err)
+			return
+		}
+		keys, err := storage.FunctionKeys()
+		if err != nil {
+			conn.Error(err)
+			return
+		}
+		var numProduced int64 = 0
+		for _, key := range keys {
+			if spanEvaluator.evaluate(key) {
+				entry := datastore.IndexEntry{PrimaryKey: key}
+				if !sendSystemKey(conn, &entry) {
+					return
+				}
+				numProduced++
+				if limit > 0 && numProduced >= limit {
+					break
+				}
+			}
+		}
 	} else {
 		spanEvaluator, err := compileSpan(span)
 		if err != nil {
@@ -207,6 +259,24 @@ func (pi *functionsIndex) ScanEntries(requestId string, limit int64, cons datast
 	vector timestamp.Vector, conn *datastore.IndexConnection) {
 	defer conn.Sender().Close()
 
+	if storage := pi.keyspace.localStorage(); storage != nil {
+		keys, err := storage.FunctionKeys()
+		if err != nil {
+			conn.Error(err)
+			return
+		}
+		for i, key := range keys {
+			if limit > 0 && int64(i) >= limit {
+				break
+			}
+			entry := datastore.IndexEntry{PrimaryKey: key}
+			if !sendSystemKey(conn, &entry) {
+				return
+			}
+		}
+		return
+	}
+
 	err := metakv.IterateChildren(_FUNC_PATH, func(path string, value []byte, rev interface{}) error {
 		entry := datastore.IndexEntry{PrimaryKey: path[len(_FUNC_PATH):]}
 		sendSystemKey(conn, &entry)
@@ -43,6 +43,10 @@ func (b *functionsCacheKeyspace) Name() string {
 	return b.name
 }
 
+func (b *functionsCacheKeyspace) QualifiedName() string {
+	return datastore.QualifiedKeyspaceName(b.namespace.Name(), "", "", b.name)
+}
+
 func (b *functionsCacheKeyspace) Count(context datastore.QueryContext) (int64, errors.Error) {
 	var count int
 
@@ -44,6 +44,10 @@ func (b *indexKeyspace) Name() string {
 	return b.name
 }
 
+func (b *indexKeyspace) QualifiedName() string {
+	return datastore.QualifiedKeyspaceName(b.namespace.Name(), "", "", b.name)
+}
+
 func (b *indexKeyspace) Count(context datastore.QueryContext) (int64, errors.Error) {
 	count := int64(0)
 	namespaceIds, excp := b.namespace.store.actualStore.NamespaceIds()
@@ -42,6 +42,10 @@ func (b *keyspaceKeyspace) Name() string {
 	return b.name
 }
 
+func (b *keyspaceKeyspace) QualifiedName() string {
+	return datastore.QualifiedKeyspaceName(b.namespace.Name(), "", "", b.name)
+}
+
 func canAccessSystemTables(context datastore.QueryContext) bool {
 	privs := auth.NewPrivileges()
 	privs.Add("", auth.PRIV_SYSTEM_READ)
@@ -59,24 +63,13 @@ func (b *keyspaceKeyspace) Count(context datastore.QueryContext) (int64, errors.
 		for _, namespaceId := range namespaceIds {
 			namespace, excp := b.namespace.store.actualStore.NamespaceById(namespaceId)
 			if excp == nil {
-				keyspaceIds, excp := namespace.KeyspaceIds()
+				keyspaceIds, excp := namespaceKeyspaceIds(namespace)
 				if excp == nil {
 					for _, keyspaceId := range keyspaceIds {
-						excludeResult := !canAccessAll && !canRead(context, namespaceId, keyspaceId)
-
-						// The list of keyspace ids can include memcached buckets.
-						// We do not want to include them in the count of
-						// of queryable buckets. Attempting to retrieve the keyspace
-						// record of a memcached bucket returns an error,
-						// which allows us to distinguish these buckets, and exclude them.
-						// See MB-19364 for more info.
-						_, err := namespace.KeyspaceByName(keyspaceId)
-						if err == nil {
-							if excludeResult {
-								context.Warning(errors.NewSystemFilteredRowsWarning("system:keyspaces"))
-							} else {
-								count++
-							}
+						if !canAccessAll && !canRead(context, namespaceId, bucketOf(keyspaceId)) {
+							context.Warning(errors.NewSystemFilteredRowsWarning("system:keyspaces"))
+						} else {
+							count++
 						}
 					}
 				} else {
@@ -91,6 +84,77 @@ func (b *keyspaceKeyspace) Count(context datastore.QueryContext) (int64, errors.
 	return 0, errors.NewSystemDatastoreError(excp, "")
 }
 
+// namespaceKeyspaceIds returns the ids of the queryable keyspaces of a namespace,
+// followed by the ids of its collections, in the form bucket/scope/collection
+func namespaceKeyspaceIds(namespace datastore.Namespace) ([]string, errors.Error) {
+	keyspaceIds, err := namespace.KeyspaceIds()
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(keyspaceIds))
+	for _, keyspaceId := range keyspaceIds {
+
+		// The list of keyspace ids can include memcached buckets.
+		// We do not want to include them in the list
+		// of queryable buckets. Attempting to retrieve the keyspace
+		// record of a memcached bucket returns an error,
+		// which allows us to distinguish these buckets, and exclude them.
+		// See MB-19364 for more info.
+		_, err := namespace.KeyspaceByName(keyspaceId)
+		if err == nil {
+			ids = append(ids, keyspaceId)
+		}
+	}
+
+	// namespaces without buckets have no collections
+	bucketIds, err := namespace.BucketIds()
+	if err != nil {
+		return ids, nil
+	}
+	for _, bucketId := range bucketIds {
+		bucket, err := namespace.BucketById(bucketId)
+		if err != nil {
+			continue
+		}
+		scopeIds, err := bucket.ScopeIds()
+		if err != nil {
+			continue
+		}
+		for _, scopeId := range scopeIds {
+			scope, err := bucket.ScopeById(scopeId)
+			if err != nil {
+				continue
+			}
+			collectionIds, err := scope.KeyspaceIds()
+			if err != nil {
+				continue
+			}
+			for _, collectionId := range collectionIds {
+				ids = append(ids, bucketId+"/"+scopeId+"/"+collectionId)
+			}
+		}
+	}
+	return ids, nil
+}
+
+func collectionById(namespace datastore.Namespace, bucketId, scopeId, collectionId string) (
+	datastore.Keyspace, errors.Error) {
+	bucket, err := namespace.BucketById(bucketId)
+	if err != nil {
+		return nil, err
+	}
+	scope, err := bucket.ScopeById(scopeId)
+	if err != nil {
+		return nil, err
+	}
+	return scope.KeyspaceById(collectionId)
+}
+
+// privileges on collections are granted at the bucket level
+func bucketOf(keyspaceId string) string {
+	return strings.SplitN(keyspaceId, "/", 2)[0]
+}
+
 func (b *keyspaceKeyspace) Size(context datastore.QueryContext) (int64, errors.Error) {
 	return -1, nil
 }
@@ -112,7 +176,7 @@ func (b *keyspaceKeyspace) Fetch(keys []string, keysMap map[string]value.Annotat
 			errs = append(errs, err)
 			continue
 		}
-		if !canAccessAll && !canRead(context, ns, ks) {
+		if !canAccessAll && !canRead(context, ns, bucketOf(ks)) {
 			context.Warning(errors.NewSystemFilteredRowsWarning("system:keyspaces"))
 			continue
 		}
@@ -139,6 +203,25 @@ func (b *keyspaceKeyspace) Fetch(keys []string, keysMap map[string]value.Annotat
 func (b *keyspaceKeyspace) fetchOne(ns string, ks string) (value.AnnotatedValue, errors.Error) {
 	namespace, err := b.namespace.store.actualStore.NamespaceById(ns)
 	if namespace != nil {
+
+		// collections are identified by bucket/scope/collection
+		path := strings.Split(ks, "/")
+		if len(path) == 3 {
+			keyspace, err := collectionById(namespace, path[0], path[1], path[2])
+			if keyspace != nil {
+				doc := value.NewAnnotatedValue(map[string]interface{}{
+					"id":           keyspace.Id(),
+					"name":         keyspace.Name(),
+					"bucket":       path[0],
+					"scope":        path[1],
+					"namespace_id": namespace.Id(),
+					"datastore_id": b.namespace.store.actualStore.Id(),
+				})
+				return doc, nil
+			}
+			return nil, err
+		}
+
 		keyspace, err := namespace.KeyspaceById(ks)
 		if keyspace != nil {
 			doc := value.NewAnnotatedValue(map[string]interface{}{
@@ -272,20 +355,9 @@ func (pi *keyspaceIndex) Scan(requestId string, span *datastore.Span, distinct b
 			for _, namespaceId := range namespaceIds {
 				namespace, err := pi.keyspace.namespace.store.actualStore.NamespaceById(namespaceId)
 				if err == nil {
-					keyspaceIds, err := namespace.KeyspaceIds()
+					keyspaceIds, err := namespaceKeyspaceIds(namespace)
 					if err == nil {
 						for _, keyspaceId := range keyspaceIds {
-							// The list of keyspace ids can include memcached buckets.
-							// We do not want to include them in the list
-							// of queryable buckets. Attempting to retrieve the keyspace
-							// record of a memcached bucket returns an error,
-							// which allows us to distinguish these buckets, and exclude them.
-							// See MB-19364 for more info.
-							_, err := namespace.KeyspaceByName(keyspaceId)
-							if err != nil {
-								continue
-							}
-
 							id := makeId(namespaceId, keyspaceId)
 							if spanEvaluator.evaluate(id) {
 								entry := datastore.IndexEntry{PrimaryKey: id}
@@ -317,19 +389,9 @@ func (pi *keyspaceIndex) ScanEntries(requestId string, limit int64, cons datasto
 		for _, namespaceId := range namespaceIds {
 			namespace, err := pi.keyspace.namespace.store.actualStore.NamespaceById(namespaceId)
 			if err == nil {
-				keyspaceIds, err := namespace.KeyspaceIds()
+				keyspaceIds, err := namespaceKeyspaceIds(namespace)
 				if err == nil {
 					for _, keyspaceId := range keyspaceIds {
-						// The list of keyspace ids can include memcached buckets.
-						// We do not want to include them in the list
-						// of queryable buckets. Attempting to retrieve the keyspace
-						// record of a memcached bucket returns an error,
-						// which allows us to distinguish these buckets, and exclude them.
-						// See MB-19364 for more info.
-						_, err := namespace.KeyspaceByName(keyspaceId)
-						if err != nil {
-							continue
-						}
 						id := makeId(namespaceId, keyspaceId)
 						entry := datastore.IndexEntry{PrimaryKey: id}
 						if !sendSystemKey(conn, &entry) {
@@ -38,6 +38,10 @@ func (b *myUserInfoKeyspace) Name() string {
 	return b.name
 }
 
+func (b *myUserInfoKeyspace) QualifiedName() string {
+	return datastore.QualifiedKeyspaceName(b.namespace.Name(), "", "", b.name)
+}
+
 func (b *myUserInfoKeyspace) Count(context datastore.QueryContext) (int64, errors.Error) {
 	authUsers := context.AuthenticatedUsers()
 	approverFunc := func(id string) bool {
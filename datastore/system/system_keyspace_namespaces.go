@@ -38,6 +38,10 @@ func (b *namespaceKeyspace) Name() string {
 	return b.name
 }
 
+func (b *namespaceKeyspace) QualifiedName() string {
+	return datastore.QualifiedKeyspaceName(b.namespace.Name(), "", "", b.name)
+}
+
 func (b *namespaceKeyspace) Count(context datastore.QueryContext) (int64, errors.Error) {
 	namespaceIds, excp := b.namespace.store.actualStore.NamespaceIds()
 	if excp == nil {
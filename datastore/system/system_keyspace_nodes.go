@@ -38,6 +38,10 @@ func (b *nodeKeyspace) Name() string {
 	return b.name
 }
 
+func (b *nodeKeyspace) QualifiedName() string {
+	return datastore.QualifiedKeyspaceName(b.namespace.Name(), "", "", b.name)
+}
+
 func (b *nodeKeyspace) Count(context datastore.QueryContext) (int64, errors.Error) {
 	var err errors.Error
 
@@ -44,6 +44,10 @@ func (b *preparedsKeyspace) Name() string {
 	return b.name
 }
 
+func (b *preparedsKeyspace) QualifiedName() string {
+	return datastore.QualifiedKeyspaceName(b.namespace.Name(), "", "", b.name)
+}
+
 func (b *preparedsKeyspace) Count(context datastore.QueryContext) (int64, errors.Error) {
 	var count int
 
@@ -116,6 +120,12 @@ func (b *preparedsKeyspace) Fetch(keys []string, keysMap map[string]value.Annota
 				if node != "" {
 					itemMap["node"] = node
 				}
+				if prepareds.PreparedsPersisting() {
+					itemMap["persisted"] = prepareds.PreparedPersisted(localKey)
+				}
+				if entry.Restored() {
+					itemMap["restored"] = true
+				}
 
 				// only give times for entries that have completed at least one execution
 				if entry.Uses > 0 && entry.RequestTime > 0 {
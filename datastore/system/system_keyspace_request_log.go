@@ -43,6 +43,10 @@ func (b *requestLogKeyspace) Name() string {
 	return b.name
 }
 
+func (b *requestLogKeyspace) QualifiedName() string {
+	return datastore.QualifiedKeyspaceName(b.namespace.Name(), "", "", b.name)
+}
+
 func (b *requestLogKeyspace) Count(context datastore.QueryContext) (int64, errors.Error) {
 	var count int
 
@@ -133,6 +137,15 @@ func (b *requestLogKeyspace) Fetch(keys []string, keysMap map[string]value.Annot
 				if entry.Mutations != 0 {
 					item.SetField("mutations", entry.Mutations)
 				}
+				if entry.UsedMemory != 0 {
+					item.SetField("usedMemory", entry.UsedMemory)
+				}
+				if entry.WorkloadGroup != "" {
+					item.SetField("workloadGroup", entry.WorkloadGroup)
+				}
+				if entry.QueueWaitTime != 0 {
+					item.SetField("queueWaitTime", entry.QueueWaitTime.String())
+				}
 				if entry.PhaseTimes != nil {
 					item.SetField("phaseTimes", entry.PhaseTimes)
 				}
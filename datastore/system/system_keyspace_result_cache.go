@@ -0,0 +1,227 @@
+//  Copyright (c) 2020 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package system
+
+import (
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/resultcache"
+	"github.com/couchbase/query/timestamp"
+	"github.com/couchbase/query/value"
+)
+
+// the result cache is local to each node,
+// so this keyspace only lists the local entries
+type resultCacheKeyspace struct {
+	keyspaceBase
+	name    string
+	indexer datastore.Indexer
+}
+
+func (b *resultCacheKeyspace) Release() {
+}
+
+func (b *resultCacheKeyspace) NamespaceId() string {
+	return b.namespace.Id()
+}
+
+func (b *resultCacheKeyspace) Id() string {
+	return b.Name()
+}
+
+func (b *resultCacheKeyspace) Name() string {
+	return b.name
+}
+
+func (b *resultCacheKeyspace) QualifiedName() string {
+	return datastore.QualifiedKeyspaceName(b.namespace.Name(), "", "", b.name)
+}
+
+func (b *resultCacheKeyspace) Count(context datastore.QueryContext) (int64, errors.Error) {
+	return int64(resultcache.Count()), nil
+}
+
+func (b *resultCacheKeyspace) Size(context datastore.QueryContext) (int64, errors.Error) {
+	return -1, nil
+}
+
+func (b *resultCacheKeyspace) Indexer(name datastore.IndexType) (datastore.Indexer, errors.Error) {
+	return b.indexer, nil
+}
+
+func (b *resultCacheKeyspace) Indexers() ([]datastore.Indexer, errors.Error) {
+	return []datastore.Indexer{b.indexer}, nil
+}
+
+func (b *resultCacheKeyspace) Fetch(keys []string, keysMap map[string]value.AnnotatedValue,
+	context datastore.QueryContext, subPaths []string) (errs []errors.Error) {
+
+	for _, key := range keys {
+		resultcache.ResultCacheDo(key, func(entry *resultcache.Entry) {
+			if entry.Stale() {
+				return
+			}
+			itemMap := map[string]interface{}{
+				"key":       key,
+				"statement": entry.Statement,
+				"namespace": entry.Namespace,
+				"results":   len(entry.Results),
+				"size":      entry.Size,
+				"hits":      entry.Hits,
+				"created":   entry.Created.String(),
+				"expires":   entry.Expires.String(),
+			}
+			if entry.Name != "" {
+				itemMap["name"] = entry.Name
+			}
+			if entry.Hits > 0 {
+				itemMap["lastUse"] = entry.LastUse.String()
+			}
+			if len(entry.Users) > 0 {
+				users := make([]interface{}, len(entry.Users))
+				for i, u := range entry.Users {
+					users[i] = u
+				}
+				itemMap["users"] = users
+			}
+			if len(entry.Keyspaces) > 0 {
+				keyspaces := make([]interface{}, len(entry.Keyspaces))
+				for i, k := range entry.Keyspaces {
+					keyspaces[i] = k
+				}
+				itemMap["keyspaces"] = keyspaces
+			}
+
+			item := value.NewAnnotatedValue(itemMap)
+			item.SetAttachment("meta", map[string]interface{}{
+				"id": key,
+			})
+			item.SetId(key)
+			keysMap[key] = item
+		})
+	}
+	return
+}
+
+func (b *resultCacheKeyspace) Insert(inserts []value.Pair) ([]value.Pair, errors.Error) {
+	// FIXME
+	return nil, errors.NewSystemNotImplementedError(nil, "")
+}
+
+func (b *resultCacheKeyspace) Update(updates []value.Pair) ([]value.Pair, errors.Error) {
+	// FIXME
+	return nil, errors.NewSystemNotImplementedError(nil, "")
+}
+
+func (b *resultCacheKeyspace) Upsert(upserts []value.Pair) ([]value.Pair, errors.Error) {
+	// FIXME
+	return nil, errors.NewSystemNotImplementedError(nil, "")
+}
+
+// deleting an entry evicts it from the cache
+func (b *resultCacheKeyspace) Delete(deletes []string, context datastore.QueryContext) ([]string, errors.Error) {
+	deleted := make([]string, 0, len(deletes))
+	for _, key := range deletes {
+		if resultcache.Delete(key) {
+			deleted = append(deleted, key)
+		}
+	}
+	return deleted, nil
+}
+
+func newResultCacheKeyspace(p *namespace) (*resultCacheKeyspace, errors.Error) {
+	b := new(resultCacheKeyspace)
+	setKeyspaceBase(&b.keyspaceBase, p)
+	b.name = KEYSPACE_NAME_RESULT_CACHE
+
+	primary := &resultCacheIndex{name: "#primary", keyspace: b}
+	b.indexer = newSystemIndexer(b, primary)
+	setIndexBase(&primary.indexBase, b.indexer)
+
+	return b, nil
+}
+
+type resultCacheIndex struct {
+	indexBase
+	name     string
+	keyspace *resultCacheKeyspace
+}
+
+func (pi *resultCacheIndex) KeyspaceId() string {
+	return pi.keyspace.Id()
+}
+
+func (pi *resultCacheIndex) Id() string {
+	return pi.Name()
+}
+
+func (pi *resultCacheIndex) Name() string {
+	return pi.name
+}
+
+func (pi *resultCacheIndex) Type() datastore.IndexType {
+	return datastore.SYSTEM
+}
+
+func (pi *resultCacheIndex) SeekKey() expression.Expressions {
+	return nil
+}
+
+func (pi *resultCacheIndex) RangeKey() expression.Expressions {
+	return nil
+}
+
+func (pi *resultCacheIndex) Condition() expression.Expression {
+	return nil
+}
+
+func (pi *resultCacheIndex) IsPrimary() bool {
+	return true
+}
+
+func (pi *resultCacheIndex) State() (state datastore.IndexState, msg string, err errors.Error) {
+	return datastore.ONLINE, "", nil
+}
+
+func (pi *resultCacheIndex) Statistics(requestId string, span *datastore.Span) (
+	datastore.Statistics, errors.Error) {
+	return nil, nil
+}
+
+func (pi *resultCacheIndex) Drop(requestId string) errors.Error {
+	return errors.NewSystemIdxNoDropError(nil, "")
+}
+
+func (pi *resultCacheIndex) Scan(requestId string, span *datastore.Span, distinct bool, limit int64,
+	cons datastore.ScanConsistency, vector timestamp.Vector, conn *datastore.IndexConnection) {
+	pi.ScanEntries(requestId, limit, cons, vector, conn)
+}
+
+func (pi *resultCacheIndex) ScanEntries(requestId string, limit int64, cons datastore.ScanConsistency,
+	vector timestamp.Vector, conn *datastore.IndexConnection) {
+	var entry *datastore.IndexEntry
+
+	defer conn.Sender().Close()
+
+	resultcache.ResultCacheForeach(func(key string, cached *resultcache.Entry) bool {
+		if cached.Stale() {
+			entry = nil
+		} else {
+			entry = &datastore.IndexEntry{PrimaryKey: key}
+		}
+		return true
+	}, func() bool {
+		if entry == nil {
+			return true
+		}
+		return sendSystemKey(conn, entry)
+	})
+}
@@ -0,0 +1,308 @@
+//  Copyright (c) 2020 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package system
+
+import (
+	"strings"
+
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/timestamp"
+	"github.com/couchbase/query/value"
+)
+
+type scopeKeyspace struct {
+	keyspaceBase
+	name    string
+	indexer datastore.Indexer
+}
+
+func (b *scopeKeyspace) Release() {
+}
+
+func (b *scopeKeyspace) NamespaceId() string {
+	return b.namespace.Id()
+}
+
+func (b *scopeKeyspace) Id() string {
+	return b.Name()
+}
+
+func (b *scopeKeyspace) Name() string {
+	return b.name
+}
+
+func (b *scopeKeyspace) QualifiedName() string {
+	return datastore.QualifiedKeyspaceName(b.namespace.Name(), "", "", b.name)
+}
+
+func (b *scopeKeyspace) Count(context datastore.QueryContext) (int64, errors.Error) {
+	count := int64(0)
+	namespaceIds, excp := b.namespace.store.actualStore.NamespaceIds()
+	canAccessAll := canAccessSystemTables(context)
+	if excp == nil {
+		for _, namespaceId := range namespaceIds {
+			namespace, excp := b.namespace.store.actualStore.NamespaceById(namespaceId)
+			if excp == nil {
+				for _, scopeId := range namespaceScopeIds(namespace) {
+					if !canAccessAll && !canRead(context, namespaceId, bucketOf(scopeId)) {
+						context.Warning(errors.NewSystemFilteredRowsWarning("system:scopes"))
+					} else {
+						count++
+					}
+				}
+			} else {
+				return 0, errors.NewSystemDatastoreError(excp, "")
+			}
+		}
+		return count, nil
+	}
+	return 0, errors.NewSystemDatastoreError(excp, "")
+}
+
+// namespaceScopeIds returns the ids of the scopes of a namespace, in the form bucket/scope
+func namespaceScopeIds(namespace datastore.Namespace) []string {
+	var ids []string
+
+	bucketIds, err := namespace.BucketIds()
+	if err != nil {
+		return nil
+	}
+	for _, bucketId := range bucketIds {
+		bucket, err := namespace.BucketById(bucketId)
+		if err != nil {
+			continue
+		}
+		scopeIds, err := bucket.ScopeIds()
+		if err != nil {
+			continue
+		}
+		for _, scopeId := range scopeIds {
+			ids = append(ids, bucketId+"/"+scopeId)
+		}
+	}
+	return ids
+}
+
+func (b *scopeKeyspace) Size(context datastore.QueryContext) (int64, errors.Error) {
+	return -1, nil
+}
+
+func (b *scopeKeyspace) Indexer(name datastore.IndexType) (datastore.Indexer, errors.Error) {
+	return b.indexer, nil
+}
+
+func (b *scopeKeyspace) Indexers() ([]datastore.Indexer, errors.Error) {
+	return []datastore.Indexer{b.indexer}, nil
+}
+
+func (b *scopeKeyspace) Fetch(keys []string, keysMap map[string]value.AnnotatedValue,
+	context datastore.QueryContext, subPaths []string) (errs []errors.Error) {
+	canAccessAll := canAccessSystemTables(context)
+	for _, k := range keys {
+		ids := strings.SplitN(k, "/", 3)
+		if len(ids) != 3 {
+			errs = append(errs, errors.NewSystemMalformedKeyError(k, "system:scopes"))
+			continue
+		}
+		if !canAccessAll && !canRead(context, ids[0], ids[1]) {
+			context.Warning(errors.NewSystemFilteredRowsWarning("system:scopes"))
+			continue
+		}
+		item, e := b.fetchOne(ids[0], ids[1], ids[2])
+
+		if e != nil {
+			errs = append(errs, e)
+			continue
+		}
+
+		if item != nil {
+			item.SetAttachment("meta", map[string]interface{}{
+				"id": k,
+			})
+			item.SetId(k)
+		}
+
+		keysMap[k] = item
+	}
+
+	return
+}
+
+func (b *scopeKeyspace) fetchOne(ns, bucketId, scopeId string) (value.AnnotatedValue, errors.Error) {
+	namespace, err := b.namespace.store.actualStore.NamespaceById(ns)
+	if err != nil {
+		return nil, err
+	}
+	bucket, err := namespace.BucketById(bucketId)
+	if err != nil {
+		return nil, err
+	}
+	scope, err := bucket.ScopeById(scopeId)
+	if err != nil {
+		return nil, err
+	}
+	doc := value.NewAnnotatedValue(map[string]interface{}{
+		"id":           scope.Id(),
+		"name":         scope.Name(),
+		"bucket":       bucket.Name(),
+		"namespace_id": namespace.Id(),
+		"datastore_id": b.namespace.store.actualStore.Id(),
+	})
+	return doc, nil
+}
+
+func (b *scopeKeyspace) Insert(inserts []value.Pair) ([]value.Pair, errors.Error) {
+	return nil, errors.NewSystemNotImplementedError(nil, "")
+}
+
+func (b *scopeKeyspace) Update(updates []value.Pair) ([]value.Pair, errors.Error) {
+	return nil, errors.NewSystemNotImplementedError(nil, "")
+}
+
+func (b *scopeKeyspace) Upsert(upserts []value.Pair) ([]value.Pair, errors.Error) {
+	return nil, errors.NewSystemNotImplementedError(nil, "")
+}
+
+func (b *scopeKeyspace) Delete(deletes []string, context datastore.QueryContext) ([]string, errors.Error) {
+	return nil, errors.NewSystemNotImplementedError(nil, "")
+}
+
+func newScopesKeyspace(p *namespace) (*scopeKeyspace, errors.Error) {
+	b := new(scopeKeyspace)
+	setKeyspaceBase(&b.keyspaceBase, p)
+	b.name = KEYSPACE_NAME_SCOPES
+
+	primary := &scopeIndex{name: "#primary", keyspace: b}
+	b.indexer = newSystemIndexer(b, primary)
+	setIndexBase(&primary.indexBase, b.indexer)
+
+	return b, nil
+}
+
+type scopeIndex struct {
+	indexBase
+	name     string
+	keyspace *scopeKeyspace
+}
+
+func (pi *scopeIndex) KeyspaceId() string {
+	return pi.keyspace.Id()
+}
+
+func (pi *scopeIndex) Id() string {
+	return pi.Name()
+}
+
+func (pi *scopeIndex) Name() string {
+	return pi.name
+}
+
+func (pi *scopeIndex) Type() datastore.IndexType {
+	return datastore.SYSTEM
+}
+
+func (pi *scopeIndex) SeekKey() expression.Expressions {
+	return nil
+}
+
+func (pi *scopeIndex) RangeKey() expression.Expressions {
+	return nil
+}
+
+func (pi *scopeIndex) Condition() expression.Expression {
+	return nil
+}
+
+func (pi *scopeIndex) IsPrimary() bool {
+	return true
+}
+
+func (pi *scopeIndex) State() (state datastore.IndexState, msg string, err errors.Error) {
+	return datastore.ONLINE, "", nil
+}
+
+func (pi *scopeIndex) Statistics(requestId string, span *datastore.Span) (
+	datastore.Statistics, errors.Error) {
+	return nil, nil
+}
+
+func (pi *scopeIndex) Drop(requestId string) errors.Error {
+	return errors.NewSystemIdxNoDropError(nil, "")
+}
+
+func (pi *scopeIndex) Scan(requestId string, span *datastore.Span, distinct bool, limit int64,
+	cons datastore.ScanConsistency, vector timestamp.Vector, conn *datastore.IndexConnection) {
+	if span == nil {
+		pi.ScanEntries(requestId, limit, cons, vector, conn)
+	} else {
+		defer conn.Sender().Close()
+
+		spanEvaluator, err := compileSpan(span)
+		if err != nil {
+			conn.Error(err)
+			return
+		}
+
+		var numProduced int64 = 0
+		namespaceIds, err := pi.keyspace.namespace.store.actualStore.NamespaceIds()
+		if err == nil {
+
+		loop:
+			for _, namespaceId := range namespaceIds {
+				namespace, err := pi.keyspace.namespace.store.actualStore.NamespaceById(namespaceId)
+				if err == nil {
+					for _, scopeId := range namespaceScopeIds(namespace) {
+						id := makeId(namespaceId, scopeId)
+						if spanEvaluator.evaluate(id) {
+							entry := datastore.IndexEntry{PrimaryKey: id}
+							if !sendSystemKey(conn, &entry) {
+								return
+							}
+							numProduced++
+							if limit > 0 && numProduced >= limit {
+								break loop
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func (pi *scopeIndex) ScanEntries(requestId string, limit int64, cons datastore.ScanConsistency,
+	vector timestamp.Vector, conn *datastore.IndexConnection) {
+	defer conn.Sender().Close()
+
+	var numProduced int64 = 0
+	namespaceIds, err := pi.keyspace.namespace.store.actualStore.NamespaceIds()
+	if err == nil {
+
+	loop:
+		for _, namespaceId := range namespaceIds {
+			namespace, err := pi.keyspace.namespace.store.actualStore.NamespaceById(namespaceId)
+			if err == nil {
+				for _, scopeId := range namespaceScopeIds(namespace) {
+					id := makeId(namespaceId, scopeId)
+					entry := datastore.IndexEntry{PrimaryKey: id}
+					if !sendSystemKey(conn, &entry) {
+						return
+					}
+					numProduced++
+					if limit > 0 && numProduced >= limit {
+						break loop
+					}
+				}
+			}
+		}
+	}
+}
@@ -41,6 +41,10 @@ func (b *tasksCacheKeyspace) Name() string {
 	return b.name
 }
 
+func (b *tasksCacheKeyspace) QualifiedName() string {
+	return datastore.QualifiedKeyspaceName(b.namespace.Name(), "", "", b.name)
+}
+
 func (b *tasksCacheKeyspace) Count(context datastore.QueryContext) (int64, errors.Error) {
 	var count int
 
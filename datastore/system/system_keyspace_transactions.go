@@ -0,0 +1,215 @@
+//  Copyright (c) 2020 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package system
+
+import (
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/timestamp"
+	"github.com/couchbase/query/transactions"
+	"github.com/couchbase/query/value"
+)
+
+// transactions are local to the node that started them,
+// so this keyspace only lists the local ones
+type transactionsKeyspace struct {
+	keyspaceBase
+	name    string
+	indexer datastore.Indexer
+}
+
+func (b *transactionsKeyspace) Release() {
+}
+
+func (b *transactionsKeyspace) NamespaceId() string {
+	return b.namespace.Id()
+}
+
+func (b *transactionsKeyspace) Id() string {
+	return b.Name()
+}
+
+func (b *transactionsKeyspace) Name() string {
+	return b.name
+}
+
+func (b *transactionsKeyspace) QualifiedName() string {
+	return datastore.QualifiedKeyspaceName(b.namespace.Name(), "", "", b.name)
+}
+
+func (b *transactionsKeyspace) Count(context datastore.QueryContext) (int64, errors.Error) {
+	return int64(transactions.CountTransactions()), nil
+}
+
+func (b *transactionsKeyspace) Size(context datastore.QueryContext) (int64, errors.Error) {
+	return -1, nil
+}
+
+func (b *transactionsKeyspace) Indexer(name datastore.IndexType) (datastore.Indexer, errors.Error) {
+	return b.indexer, nil
+}
+
+func (b *transactionsKeyspace) Indexers() ([]datastore.Indexer, errors.Error) {
+	return []datastore.Indexer{b.indexer}, nil
+}
+
+func (b *transactionsKeyspace) Fetch(keys []string, keysMap map[string]value.AnnotatedValue,
+	context datastore.QueryContext, subPaths []string) (errs []errors.Error) {
+
+	for _, key := range keys {
+		transactions.TransactionDo(key, func(txn *transactions.Transaction) {
+			itemMap := map[string]interface{}{
+				"txid":       txn.Id(),
+				"state":      string(txn.State()),
+				"startTime":  txn.StartTime().String(),
+				"lastUse":    txn.LastUse().String(),
+				"timeout":    txn.Timeout().String(),
+				"statements": txn.Statements(),
+				"inUse":      txn.InUse(),
+			}
+			mutations := txn.Mutations()
+			if len(mutations) > 0 {
+				counts := make(map[string]interface{}, len(mutations))
+				for ks, c := range mutations {
+					counts[ks] = c
+				}
+				itemMap["mutations"] = counts
+			}
+			savepoints := txn.Savepoints()
+			if len(savepoints) > 0 {
+				names := make([]interface{}, len(savepoints))
+				for i, s := range savepoints {
+					names[i] = s
+				}
+				itemMap["savepoints"] = names
+			}
+
+			item := value.NewAnnotatedValue(itemMap)
+			item.SetAttachment("meta", map[string]interface{}{
+				"id": key,
+			})
+			item.SetId(key)
+			keysMap[key] = item
+		})
+	}
+	return
+}
+
+func (b *transactionsKeyspace) Insert(inserts []value.Pair) ([]value.Pair, errors.Error) {
+	// FIXME
+	return nil, errors.NewSystemNotImplementedError(nil, "")
+}
+
+func (b *transactionsKeyspace) Update(updates []value.Pair) ([]value.Pair, errors.Error) {
+	// FIXME
+	return nil, errors.NewSystemNotImplementedError(nil, "")
+}
+
+func (b *transactionsKeyspace) Upsert(upserts []value.Pair) ([]value.Pair, errors.Error) {
+	// FIXME
+	return nil, errors.NewSystemNotImplementedError(nil, "")
+}
+
+// deleting a transaction rolls it back
+func (b *transactionsKeyspace) Delete(deletes []string, context datastore.QueryContext) ([]string, errors.Error) {
+	deleted := make([]string, 0, len(deletes))
+	for _, name := range deletes {
+		err := transactions.RollbackTransaction(name)
+		if err != nil {
+			context.Warning(err)
+		} else {
+			deleted = append(deleted, name)
+		}
+	}
+	return deleted, nil
+}
+
+func newTransactionsKeyspace(p *namespace) (*transactionsKeyspace, errors.Error) {
+	b := new(transactionsKeyspace)
+	setKeyspaceBase(&b.keyspaceBase, p)
+	b.name = KEYSPACE_NAME_TRANSACTIONS
+
+	primary := &transactionsIndex{name: "#primary", keyspace: b}
+	b.indexer = newSystemIndexer(b, primary)
+	setIndexBase(&primary.indexBase, b.indexer)
+
+	return b, nil
+}
+
+type transactionsIndex struct {
+	indexBase
+	name     string
+	keyspace *transactionsKeyspace
+}
+
+func (pi *transactionsIndex) KeyspaceId() string {
+	return pi.keyspace.Id()
+}
+
+func (pi *transactionsIndex) Id() string {
+	return pi.Name()
+}
+
+func (pi *transactionsIndex) Name() string {
+	return pi.name
+}
+
+func (pi *transactionsIndex) Type() datastore.IndexType {
+	return datastore.SYSTEM
+}
+
+func (pi *transactionsIndex) SeekKey() expression.Expressions {
+	return nil
+}
+
+func (pi *transactionsIndex) RangeKey() expression.Expressions {
+	return nil
+}
+
+func (pi *transactionsIndex) Condition() expression.Expression {
+	return nil
+}
+
+func (pi *transactionsIndex) IsPrimary() bool {
+	return true
+}
+
+func (pi *transactionsIndex) State() (state datastore.IndexState, msg string, err errors.Error) {
+	return datastore.ONLINE, "", nil
+}
+
+func (pi *transactionsIndex) Statistics(requestId string, span *datastore.Span) (
+	datastore.Statistics, errors.Error) {
+	return nil, nil
+}
+
+func (pi *transactionsIndex) Drop(requestId string) errors.Error {
+	return errors.NewSystemIdxNoDropError(nil, "")
+}
+
+func (pi *transactionsIndex) Scan(requestId string, span *datastore.Span, distinct bool, limit int64,
+	cons datastore.ScanConsistency, vector timestamp.Vector, conn *datastore.IndexConnection) {
+	pi.ScanEntries(requestId, limit, cons, vector, conn)
+}
+
+func (pi *transactionsIndex) ScanEntries(requestId string, limit int64, cons datastore.ScanConsistency,
+	vector timestamp.Vector, conn *datastore.IndexConnection) {
+	var entry *datastore.IndexEntry
+
+	defer conn.Sender().Close()
+
+	transactions.TransactionsForeach(func(id string, txn *transactions.Transaction) bool {
+		entry = &datastore.IndexEntry{PrimaryKey: id}
+		return true
+	}, func() bool {
+		return sendSystemKey(conn, entry)
+	})
+}
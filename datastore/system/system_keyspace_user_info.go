@@ -41,6 +41,10 @@ func (b *userInfoKeyspace) Name() string {
 	return b.name
 }
 
+func (b *userInfoKeyspace) QualifiedName() string {
+	return datastore.QualifiedKeyspaceName(b.namespace.Name(), "", "", b.name)
+}
+
 func getUserInfoList(s *store) ([]interface{}, errors.Error) {
 	val, err := s.UserInfo()
 	if err != nil {
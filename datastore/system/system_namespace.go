@@ -100,6 +100,12 @@ func (p *namespace) loadKeyspaces() (e errors.Error) {
 	}
 	p.keyspaces[bb.Name()] = bb
 
+	scb, e := newScopesKeyspace(p)
+	if e != nil {
+		return e
+	}
+	p.keyspaces[scb.Name()] = scb
+
 	db, e := newDualKeyspace(p)
 	if e != nil {
 		return e
@@ -137,6 +143,24 @@ func (p *namespace) loadKeyspaces() (e errors.Error) {
 
 	p.keyspaces[tasksCache.Name()] = tasksCache
 
+	txns, e := newTransactionsKeyspace(p)
+	if e != nil {
+		return e
+	}
+	p.keyspaces[txns.Name()] = txns
+
+	resultCache, e := newResultCacheKeyspace(p)
+	if e != nil {
+		return e
+	}
+	p.keyspaces[resultCache.Name()] = resultCache
+
+	dictionary, e := newDictionaryKeyspace(p)
+	if e != nil {
+		return e
+	}
+	p.keyspaces[dictionary.Name()] = dictionary
+
 	reqs, e := newRequestsKeyspace(p)
 	if e != nil {
 		return e
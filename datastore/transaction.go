@@ -0,0 +1,56 @@
+//  Copyright (c) 2020 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package datastore
+
+import (
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/value"
+)
+
+type MutationOp int
+
+const (
+	MUTATE_INSERT MutationOp = iota // the document must not exist
+	MUTATE_UPDATE                   // the document must exist
+	MUTATE_DELETE                   // the document must exist
+)
+
+func (op MutationOp) String() string {
+	switch op {
+	case MUTATE_INSERT:
+		return "insert"
+	case MUTATE_UPDATE:
+		return "update"
+	case MUTATE_DELETE:
+		return "delete"
+	}
+	return "unknown operation"
+}
+
+// A single document mutation, as staged by a transaction
+type Mutation struct {
+	Op      MutationOp
+	Key     string
+	Value   value.Value // nil for MUTATE_DELETE
+	Options value.Value // write options, as in value.Pair
+}
+
+type Mutations []Mutation
+
+// Keyspaces that can apply a set of mutations atomically, as required to
+// commit a transaction: either all the mutations are applied, or none is,
+// and no other writer can observe an intermediate state.
+// A mutation whose precondition does not hold, e.g. an insert of an existing
+// document, fails the whole set.
+// On success, the mutations that revert the ones applied are returned, so
+// that the caller can undo them should the commit fail on another keyspace.
+type AtomicKeyspace interface {
+	ApplyMutations(mutations Mutations) (Mutations, errors.Error)
+}
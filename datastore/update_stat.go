@@ -24,3 +24,35 @@ type StatUpdater interface {
 	Name() StatUpdaterType
 	UpdateStatistics(ks Keyspace, terms expression.Expressions, with value.Value, conn *ValueConnection, exContext interface{}) // The StatUpdater should populate the connection.
 }
+
+// StatUpdaters that can also remove statistics, as required by DELETE STATISTICS.
+// No terms means all the statistics of the keyspace.
+type StatDeleter interface {
+	DeleteStatistics(ks Keyspace, terms expression.Expressions, conn *ValueConnection, exContext interface{}) // The StatDeleter should close the connection.
+}
+
+// Optimizer statistics, as gathered by UPDATE STATISTICS. Keyspaces are
+// identified by their QualifiedName, and histograms by the string form of the
+// key expression formalized against it, e.g. (`default:travel`.`country`).
+type Dictionary interface {
+	KeyspaceInfo(keyspace string) *KeyspaceInfo
+	Histogram(keyspace, key string) *Histogram
+	Keyspaces() []string                     // keyspaces with statistics
+	Histograms(keyspace string) []*Histogram // all histograms of a keyspace
+}
+
+type KeyspaceInfo struct {
+	DocCount   int64 // number of documents when statistics were gathered
+	AvgDocSize int64 // average document size, in bytes
+}
+
+// Globally accessible statistics dictionary, consulted by the optimizer
+var _DICTIONARY Dictionary
+
+func SetDictionary(dictionary Dictionary) {
+	_DICTIONARY = dictionary
+}
+
+func GetDictionary() Dictionary {
+	return _DICTIONARY
+}
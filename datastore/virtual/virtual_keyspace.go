@@ -38,6 +38,10 @@ func (this *virtualKeyspace) Name() string {
 	return this.name
 }
 
+func (this *virtualKeyspace) QualifiedName() string {
+	return datastore.QualifiedKeyspaceName(this.namespace.Name(), "", "", this.name)
+}
+
 // Virtual keyspace will be directly under a namespace.
 func (this *virtualKeyspace) NamespaceId() string {
 	return this.namespace.Id()
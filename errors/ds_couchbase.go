@@ -126,3 +126,8 @@ func NewCbSecurityConfigNotProvided(bucket string) Error {
 	return &err{level: EXCEPTION, ICode: 12023, IKey: "datastore.couchbase.security_config_not_provided",
 		InternalMsg: "Connection security config not provided. Unable to load bucket " + bucket, InternalCaller: CallerN(1), retry: true}
 }
+
+func NewCbScopesDDLError(e error, msg string) Error {
+	return &err{level: EXCEPTION, ICode: 12024, IKey: "datastore.couchbase.scopes_ddl_error", ICause: e,
+		InternalMsg: "Error in scope or collection DDL " + msg, InternalCaller: CallerN(1)}
+}
@@ -72,3 +72,18 @@ func NewFilePrimaryIdxNoDropError(e error, msg string) Error {
 	return &err{level: EXCEPTION, ICode: 15011, IKey: "datastore.file.primary_idx_no_drop", ICause: e,
 		InternalMsg: "Primary Index cannot be dropped " + msg, InternalCaller: CallerN(1)}
 }
+
+func NewFileBucketNotFoundError(e error, msg string) Error {
+	return &err{level: EXCEPTION, ICode: 15012, IKey: "datastore.file.bucket_not_found", ICause: e,
+		InternalMsg: "Bucket not found " + msg, InternalCaller: CallerN(1)}
+}
+
+func NewFileScopeNotFoundError(e error, msg string) Error {
+	return &err{level: EXCEPTION, ICode: 15013, IKey: "datastore.file.scope_not_found", ICause: e,
+		InternalMsg: "Scope not found " + msg, InternalCaller: CallerN(1)}
+}
+
+func NewFileDuplicateScopeError(e error, msg string) Error {
+	return &err{level: EXCEPTION, ICode: 15014, IKey: "datastore.file.duplicate_scope", ICause: e,
+		InternalMsg: "Duplicate Scope " + msg, InternalCaller: CallerN(1)}
+}
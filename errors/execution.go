@@ -94,6 +94,12 @@ func NewUpsertKeyTypeError(v value.Value) Error {
 		InternalCaller: CallerN(1)}
 }
 
+func NewDMLOptionsError(op string, v value.Value) Error {
+	return &err{level: EXCEPTION, ICode: 5079, IKey: "execution.dml_options_error",
+		InternalMsg:    fmt.Sprintf("Invalid %s options %v: expected an object with a numeric expiration.", op, v),
+		InternalCaller: CallerN(1)}
+}
+
 func NewDeleteAliasMissingError(alias string) Error {
 	return &err{level: EXCEPTION, ICode: 5080, IKey: "execution.missing_delete_alias",
 		InternalMsg:    fmt.Sprintf("DELETE alias %s not found in item.", alias),
@@ -238,3 +244,21 @@ func NewSubqueryBuildError(e error) Error {
 		InternalMsg:    "Unable to run subquery",
 		InternalCaller: CallerN(1)}
 }
+
+func NewSpillError(e error, op string) Error {
+	return &err{level: EXCEPTION, ICode: 5380, IKey: "execution.spill_error", ICause: e,
+		InternalMsg:    fmt.Sprintf("Error spilling %s to temporary files", op),
+		InternalCaller: CallerN(1)}
+}
+
+func NewMemoryQuotaExceededError(quota uint64) Error {
+	return &err{level: EXCEPTION, ICode: 5390, IKey: "execution.memory_quota_exceeded",
+		InternalMsg:    fmt.Sprintf("Request has exceeded its memory quota of %v MB", quota),
+		InternalCaller: CallerN(1)}
+}
+
+func NewRecursionDepthError(alias string, depth int) Error {
+	return &err{level: EXCEPTION, ICode: 5400, IKey: "execution.recursion_depth_exceeded",
+		InternalMsg:    fmt.Sprintf("WITH RECURSIVE term %s exceeded the maximum recursion depth of %v", alias, depth),
+		InternalCaller: CallerN(1)}
+}
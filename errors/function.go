@@ -110,3 +110,9 @@ func NewFunctionExecutionError(what string, name string, reason error) Error {
 		InternalMsg:    fmt.Sprintf("Error executing function %v %v: %v", name, what, reason),
 		InternalCaller: CallerN(1)}
 }
+
+func NewMissingLibraryError(l string) Error {
+	return &err{level: EXCEPTION, ICode: 10110, IKey: "function.library.missing.error",
+		InternalMsg:    fmt.Sprintf("Library not found %v", l),
+		InternalCaller: CallerN(1)}
+}
@@ -72,6 +72,13 @@ func NewEncodingNameMismatchError(name string) Error {
 		InternalMsg: fmt.Sprintf("Prepared name in encoded plan parameter is not %s", name), InternalCaller: CallerN(1)}
 }
 
+const PREPAREDS_PERSIST = 4095
+
+func NewPreparedsPersistError(e error, dir string) Error {
+	return &err{level: EXCEPTION, ICode: PREPAREDS_PERSIST, IKey: "plan.build_prepared.persist",
+		ICause: e, InternalMsg: fmt.Sprintf("Unable to access prepared statements directory %s", dir), InternalCaller: CallerN(1)}
+}
+
 const NO_INDEX_JOIN = 4100
 
 func NewNoIndexJoinError(alias, op string) Error {
@@ -132,6 +139,14 @@ func NewNoAnsiJoinError(alias, op string) Error {
 		InternalMsg: fmt.Sprintf("No index available for ANSI %s term %s", op, alias), InternalCaller: CallerN(1)}
 }
 
+const OUTER_JOIN_CORRELATED = 4335
+
+func NewOuterJoinCorrelatedError(op, alias string) Error {
+	return &err{level: EXCEPTION, ICode: OUTER_JOIN_CORRELATED, IKey: "plan.ansi_join.outer_join_correlated",
+		InternalMsg:    fmt.Sprintf("%s on %s cannot reference the left-hand side of the join", op, alias),
+		InternalCaller: CallerN(1)}
+}
+
 const PARTITION_INDEX_NOT_SUPPORTED = 4340
 
 func NewPartitionIndexNotSupportedError() Error {
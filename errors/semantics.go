@@ -138,6 +138,30 @@ func NewMHDPOnlyFeature(what, iKey string) Error {
 		InternalCaller: CallerN(1)}
 }
 
+const OUTER_JOIN_JOIN_HINT = 3270
+
+func NewOuterJoinJoinHintError(op string, alias string, hint string, iKey string) Error {
+	return &err{level: EXCEPTION, ICode: OUTER_JOIN_JOIN_HINT, IKey: iKey,
+		InternalMsg:    fmt.Sprintf("%s on %s cannot have join hint %s.", op, alias, hint),
+		InternalCaller: CallerN(1)}
+}
+
+const RECURSIVE_WITH_SEMANTIC_ERROR = 3280
+
+func NewRecursiveWithSemanticError(alias, cause, iKey string) Error {
+	return &err{level: EXCEPTION, ICode: RECURSIVE_WITH_SEMANTIC_ERROR, IKey: iKey,
+		InternalMsg:    fmt.Sprintf("WITH RECURSIVE term %s %s", alias, cause),
+		InternalCaller: CallerN(1)}
+}
+
+const UPDATE_META_FIELD = 3290
+
+func NewUpdateMetaFieldError(field, iKey string) Error {
+	return &err{level: EXCEPTION, ICode: UPDATE_META_FIELD, IKey: iKey,
+		InternalMsg:    fmt.Sprintf("Invalid SET term META().%s, only META().expiration can be set.", field),
+		InternalCaller: CallerN(1)}
+}
+
 /* ---- BEGIN MOVED error numbers ----
    The following error numbers (in the 4000 range) originally reside in plan.go (before the introduction of the semantics package)
    although they are semantic errors. They are moved from plan.go to semantics.go but their original error numbers are kept.
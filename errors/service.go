@@ -111,3 +111,25 @@ func NewServiceErrorHttpMethod(method string) Error {
 	return &err{level: EXCEPTION, ICode: 1170, IKey: "service.io.request.method",
 		InternalMsg: fmt.Sprintf("Unsupported method %s", method), InternalCaller: CallerN(1)}
 }
+
+const WORKLOAD_QUEUE_FULL = 1180
+
+func NewServiceErrorWorkloadQueueFull(group string) Error {
+	return &err{level: EXCEPTION, ICode: WORKLOAD_QUEUE_FULL, IKey: "service.workload.queue_full",
+		InternalMsg: fmt.Sprintf("Request rejected: workload group %v queue is full", group), InternalCaller: CallerN(1)}
+}
+
+const WORKLOAD_QUEUE_TIMEOUT = 1181
+
+func NewServiceErrorWorkloadQueueTimeout(group string, timeout time.Duration) Error {
+	return &err{level: EXCEPTION, ICode: WORKLOAD_QUEUE_TIMEOUT, IKey: "service.workload.queue_timeout",
+		InternalMsg:    fmt.Sprintf("Request rejected: timed out after %v in workload group %v queue", timeout, group),
+		InternalCaller: CallerN(1)}
+}
+
+const RESULT_CACHE_KEY = 1182
+
+func NewResultCacheKeyError(e error) Error {
+	return &err{level: EXCEPTION, ICode: RESULT_CACHE_KEY, IKey: "service.result_cache.key",
+		ICause: e, InternalMsg: "Unable to build result cache key", InternalCaller: CallerN(1)}
+}
@@ -74,6 +74,8 @@ const (
 	BATCH_MODE_MSG      = "Error when running in batch mode for Analytics. Incorrect input value"
 	STRING_WRITE        = 143
 	STRING_WRITE_MSG    = "Cannot write to string buffer. "
+	OUTPUT_FORMAT       = 144
+	OUTPUT_FORMAT_MSG   = "Invalid output format. Possible values : json, table, csv, tsv, vertical"
 
 	//Generic Errors (170 - 199)
 	OPERATION_TIMEOUT           = 170
@@ -216,6 +218,11 @@ func NewShellErrorStringWrite(msg string) Error {
 
 }
 
+func NewShellErrorOutputFormat(msg string) Error {
+	return &err{level: EXCEPTION, ICode: OUTPUT_FORMAT, IKey: "shell.output.format.incorrect.input", InternalMsg: OUTPUT_FORMAT_MSG + msg, InternalCaller: CallerN(1)}
+
+}
+
 //Generic Errors
 
 func NewShellErrorOperationTimeout(msg string) Error {
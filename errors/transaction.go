@@ -0,0 +1,78 @@
+//  Copyright (c) 2020 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package errors
+
+import (
+	"fmt"
+)
+
+// Transaction errors - errors that are created in the transactions package
+
+func NewTransactionError(e error, msg string) Error {
+	return &err{level: EXCEPTION, ICode: 17100, IKey: "transaction.generic.error", ICause: e,
+		InternalMsg: "Transaction error: " + msg, InternalCaller: CallerN(1)}
+}
+
+func NewTransactionNotFoundError(txId string) Error {
+	return &err{level: EXCEPTION, ICode: 17101, IKey: "transaction.not_found",
+		InternalMsg:    fmt.Sprintf("Transaction %v not found", txId),
+		InternalCaller: CallerN(1)}
+}
+
+func NewTransactionExpiredError(txId string) Error {
+	return &err{level: EXCEPTION, ICode: 17102, IKey: "transaction.expired",
+		InternalMsg:    fmt.Sprintf("Transaction %v has timed out and has been rolled back", txId),
+		InternalCaller: CallerN(1)}
+}
+
+func NewTransactionInUseError(txId string) Error {
+	return &err{level: EXCEPTION, ICode: 17103, IKey: "transaction.in_use",
+		InternalMsg:    fmt.Sprintf("Transaction %v is in use by another request", txId),
+		InternalCaller: CallerN(1)}
+}
+
+func NewTransactionInProgressError(txId string) Error {
+	return &err{level: EXCEPTION, ICode: 17104, IKey: "transaction.in_progress",
+		InternalMsg:    fmt.Sprintf("Transaction %v is already in progress", txId),
+		InternalCaller: CallerN(1)}
+}
+
+func NewNoTransactionError(stmt string) Error {
+	return &err{level: EXCEPTION, ICode: 17105, IKey: "transaction.none",
+		InternalMsg:    fmt.Sprintf("%v can only be executed within a transaction", stmt),
+		InternalCaller: CallerN(1)}
+}
+
+func NewTransactionStatementError(stmt string) Error {
+	return &err{level: EXCEPTION, ICode: 17106, IKey: "transaction.statement.not_supported",
+		InternalMsg:    fmt.Sprintf("%v is not supported within a transaction", stmt),
+		InternalCaller: CallerN(1)}
+}
+
+func NewSavepointNotFoundError(name string) Error {
+	return &err{level: EXCEPTION, ICode: 17107, IKey: "transaction.savepoint.not_found",
+		InternalMsg:    fmt.Sprintf("Savepoint %v not found", name),
+		InternalCaller: CallerN(1)}
+}
+
+const TRANSACTION_CONFLICT = 17108
+
+func NewTransactionConflictError(keyspace, key, op string) Error {
+	return &err{level: EXCEPTION, ICode: TRANSACTION_CONFLICT, IKey: "transaction.write_conflict",
+		InternalMsg: fmt.Sprintf("Write conflict on %v: document %v has been modified outside the transaction (%v)",
+			keyspace, key, op),
+		InternalCaller: CallerN(1)}
+}
+
+func NewTransactionCommitError(e error, txId string) Error {
+	return &err{level: EXCEPTION, ICode: 17109, IKey: "transaction.commit.failed", ICause: e,
+		InternalMsg:    fmt.Sprintf("Commit of transaction %v failed, the transaction has been rolled back", txId),
+		InternalCaller: CallerN(1)}
+}
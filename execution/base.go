@@ -22,9 +22,12 @@ import (
 	"time"
 
 	atomic "github.com/couchbase/go-couchbase/platform"
+	"github.com/couchbase/query/algebra"
 	"github.com/couchbase/query/datastore"
 	"github.com/couchbase/query/errors"
 	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/tracing"
+	"github.com/couchbase/query/transactions"
 	"github.com/couchbase/query/util"
 	"github.com/couchbase/query/value"
 )
@@ -122,6 +125,8 @@ type base struct {
 	inDocs         int64
 	outDocs        int64
 	phaseSwitches  int64
+	spills         int64
+	spillSize      int64
 	stopped        bool
 	isRoot         bool
 	bit            uint8
@@ -130,6 +135,8 @@ type base struct {
 	activeCond     sync.Cond
 	activeLock     sync.Mutex
 	opState        opState
+	txScan         *transactions.ScanKeys
+	span           *tracing.Span
 }
 
 const _ITEM_CAP = 512
@@ -170,6 +177,7 @@ func (this *base) getBase() *base {
 func newBase(dest *base, context *Context) {
 	*dest = base{}
 	newValueExchange(&dest.valueExchange, context.GetPipelineCap())
+	dest.memory = context.memory
 	dest.execPhase = PHASES
 	dest.phaseTimes = func(t time.Duration) {}
 	dest.activeCond.L = &dest.activeLock
@@ -212,6 +220,7 @@ func (this *base) setInline() {
 func (this *base) copy(dest *base) {
 	*dest = base{}
 	newValueExchange(&dest.valueExchange, int64(cap(this.valueExchange.items)))
+	dest.memory = this.memory
 	if this.valueExchange.children != nil {
 		dest.trackChildren(cap(this.valueExchange.children))
 	}
@@ -248,6 +257,7 @@ func (this *base) close(context *Context) {
 		}
 	}
 	this.inactive()
+	this.endSpan()
 
 	// operators that never enter a _RUNNING state have to clean after themselves when they finally go
 	if this.opState == _KILLED {
@@ -444,6 +454,7 @@ func (this *base) SerializeOutput(op Operator, context *Context) {
 
 // fork operator
 func (this *base) fork(op Operator, context *Context, parent value.Value) {
+	op.getBase().startSpan(op, this.span, context)
 	if op.getBase().inline {
 		this.switchPhase(_NOTIME)
 		op.RunOnce(context, parent)
@@ -623,9 +634,47 @@ func (this *base) getItemEntry(conn *datastore.IndexConnection) (*datastore.Inde
 		this.stopped = true
 		return nil, false
 	}
+	if this.txScan != nil {
+		return this.transactionEntry(conn, item)
+	}
 	return item, ok
 }
 
+// Within a transaction, scans skip the index entries of the documents
+// the transaction has written, and return the ones it has not deleted
+// once the index is exhausted
+func (this *base) setTransactionScan(term *algebra.KeyspaceTerm, context *Context) {
+	this.txScan = nil
+	if context.Transaction() != nil && term != nil && term.Path() != nil {
+		path := term.Path()
+		this.txScan = context.Transaction().ScanKeys(path.Namespace(), path.Bucket(), path.Scope(),
+			path.Keyspace())
+	}
+}
+
+func (this *base) transactionEntry(conn *datastore.IndexConnection, item *datastore.IndexEntry) (
+	*datastore.IndexEntry, bool) {
+	for item != nil && this.txScan.Skip(item.PrimaryKey) {
+		this.switchPhase(_SERVTIME)
+		next, ok := conn.Sender().GetEntry()
+		this.switchPhase(_EXECTIME)
+		if !ok {
+			this.stopped = true
+			return nil, false
+		}
+		item = next
+	}
+	// chunked primary scans resume after the last entry returned
+	if item != nil || conn.Timeout() {
+		return item, true
+	}
+	key, ok := this.txScan.Next()
+	if !ok {
+		return nil, true
+	}
+	return &datastore.IndexEntry{PrimaryKey: key}, true
+}
+
 func (this *base) getItemChildren() (value.AnnotatedValue, int, bool) {
 	return this.getItemChildrenOp(this.input)
 }
@@ -1061,6 +1110,7 @@ func (this *base) release(context *Context) {
 
 	// release any waiter
 	this.notify()
+	this.endSpan()
 
 	// remove any reference we have about anyone else
 	this.stopChannel = nil
@@ -1094,6 +1144,42 @@ func (this *base) isComplete() bool {
 	return this.opState == _DONE
 }
 
+// tracing
+
+// Operators are traced from the time they are forked to the time they close,
+// as children of the operator that forked them.
+// Operators that are run directly are traced from here
+func TraceOperator(op Operator, context *Context) {
+	op.getBase().startSpan(op, nil, context)
+}
+
+func (this *base) startSpan(op Operator, parent *tracing.Span, context *Context) {
+	if parent == nil {
+		parent = context.traceSpan
+	}
+	if parent == nil || this.span != nil {
+		return
+	}
+	this.span = parent.StartChild(reflect.TypeOf(op).Elem().Name(), tracing.SPAN_INTERNAL)
+}
+
+func (this *base) endSpan() {
+	if this.span == nil {
+		return
+	}
+	this.span.SetAttribute("inDocs", go_atomic.LoadInt64(&this.inDocs))
+	this.span.SetAttribute("outDocs", go_atomic.LoadInt64(&this.outDocs))
+	this.span.End()
+	this.span = nil
+}
+
+// datastore calls are traced as children of the calling operator
+func (this *base) startDatastoreSpan(call string, object string, name string) *tracing.Span {
+	span := this.span.StartChild(call, tracing.SPAN_CLIENT)
+	span.SetAttribute(object, name)
+	return span
+}
+
 // profiling
 
 // phase switching
@@ -1175,6 +1261,10 @@ func (this *base) marshalTimes(r map[string]interface{}) {
 	if this.phaseSwitches != 0 {
 		stats["#phaseSwitches"] = this.phaseSwitches
 	}
+	if this.spills != 0 {
+		stats["#spills"] = this.spills
+		stats["#spillSize"] = this.spillSize
+	}
 
 	execTime := this.execTime
 	chanTime := this.chanTime
@@ -1224,6 +1314,8 @@ func (this *base) accrueTime(copy *base) {
 	this.inDocs += copy.inDocs
 	this.outDocs += copy.outDocs
 	this.phaseSwitches += copy.phaseSwitches
+	this.spills += copy.spills
+	this.spillSize += copy.spillSize
 	this.execTime += copy.execTime
 	this.chanTime += copy.chanTime
 	this.servTime += copy.servTime
@@ -622,6 +622,26 @@ func (this *builder) VisitBuildIndexes(plan *plan.BuildIndexes) (interface{}, er
 	return checkOp(NewBuildIndexes(plan, this.context), this.context)
 }
 
+// CreateScope
+func (this *builder) VisitCreateScope(plan *plan.CreateScope) (interface{}, error) {
+	return checkOp(NewCreateScope(plan, this.context), this.context)
+}
+
+// DropScope
+func (this *builder) VisitDropScope(plan *plan.DropScope) (interface{}, error) {
+	return checkOp(NewDropScope(plan, this.context), this.context)
+}
+
+// CreateCollection
+func (this *builder) VisitCreateCollection(plan *plan.CreateCollection) (interface{}, error) {
+	return checkOp(NewCreateCollection(plan, this.context), this.context)
+}
+
+// DropCollection
+func (this *builder) VisitDropCollection(plan *plan.DropCollection) (interface{}, error) {
+	return checkOp(NewDropCollection(plan, this.context), this.context)
+}
+
 // Prepare
 func (this *builder) VisitPrepare(plan *plan.Prepare) (interface{}, error) {
 	return checkOp(NewPrepare(plan, this.context, plan.Prepared()), this.context)
@@ -629,7 +649,16 @@ func (this *builder) VisitPrepare(plan *plan.Prepare) (interface{}, error) {
 
 // Explain
 func (this *builder) VisitExplain(plan *plan.Explain) (interface{}, error) {
-	return checkOp(NewExplain(plan, this.context), this.context)
+	var child Operator
+	if plan.Analyze() {
+		op, err := plan.Operator().Accept(this)
+		if err != nil {
+			return nil, err
+		}
+		child = op.(Operator)
+	}
+
+	return checkOp(NewExplain(plan, this.context, child), this.context)
 }
 
 // Infer
@@ -672,3 +701,24 @@ func (this *builder) VisitAdvise(plan *plan.Advise) (interface{}, error) {
 func (this *builder) VisitUpdateStatistics(plan *plan.UpdateStatistics) (interface{}, error) {
 	return checkOp(NewUpdateStatistics(plan, this.context), this.context)
 }
+
+func (this *builder) VisitDeleteStatistics(plan *plan.DeleteStatistics) (interface{}, error) {
+	return checkOp(NewDeleteStatistics(plan, this.context), this.context)
+}
+
+// Transactions
+func (this *builder) VisitStartTransaction(plan *plan.StartTransaction) (interface{}, error) {
+	return checkOp(NewStartTransaction(plan, this.context), this.context)
+}
+
+func (this *builder) VisitCommitTransaction(plan *plan.CommitTransaction) (interface{}, error) {
+	return checkOp(NewCommitTransaction(plan, this.context), this.context)
+}
+
+func (this *builder) VisitRollbackTransaction(plan *plan.RollbackTransaction) (interface{}, error) {
+	return checkOp(NewRollbackTransaction(plan, this.context), this.context)
+}
+
+func (this *builder) VisitSavepoint(plan *plan.Savepoint) (interface{}, error) {
+	return checkOp(NewSavepoint(plan, this.context), this.context)
+}
@@ -17,6 +17,7 @@ import (
 	"os"
 	"runtime"
 	"sync"
+	go_atomic "sync/atomic"
 	"time"
 
 	"github.com/couchbase/cbauth"
@@ -29,6 +30,8 @@ import (
 	"github.com/couchbase/query/plan"
 	"github.com/couchbase/query/planner"
 	"github.com/couchbase/query/timestamp"
+	"github.com/couchbase/query/tracing"
+	"github.com/couchbase/query/transactions"
 	"github.com/couchbase/query/value"
 )
 
@@ -60,6 +63,8 @@ const (
 	INFER
 	FTS_SEARCH
 	UPDATE_STAT
+	DELETE_STAT
+	TRANSACTION
 
 	// Server layer
 	INSTANTIATE
@@ -99,6 +104,8 @@ var _PHASE_NAMES = []string{
 	INFER:        "inferKeySpace",
 	FTS_SEARCH:   "ftsSearch",
 	UPDATE_STAT:  "updateStatistics",
+	DELETE_STAT:  "deleteStatistics",
+	TRANSACTION:  "transaction",
 
 	INSTANTIATE: "instantiate",
 	PARSE:       "parse",
@@ -127,6 +134,7 @@ type Output interface {
 	FmtPhaseOperators() map[string]interface{}
 	AddPhaseTime(phase Phases, duration time.Duration)
 	FmtPhaseTimes() map[string]interface{}
+	TrackMemory(size uint64) // Peak memory used by the request, if it has a quota
 }
 
 type Context struct {
@@ -141,6 +149,7 @@ type Context struct {
 	scanCap            int64
 	pipelineCap        int64
 	pipelineBatch      int
+	recursionDepth     int
 	isPrepared         bool
 	reqDeadline        time.Time
 	now                time.Time
@@ -159,6 +168,11 @@ type Context struct {
 	whitelist          map[string]interface{}
 	inlistHashMap      map[*expression.In]*expression.InlistHash
 	inlistHashLock     sync.RWMutex
+	spill              *spillBudget
+	memory             *memoryAccount
+	transaction        *transactions.Transaction
+	txTimeout          time.Duration
+	traceSpan          *tracing.Span
 }
 
 func NewContext(requestId string, datastore, systemstore datastore.Datastore,
@@ -192,6 +206,7 @@ func NewContext(requestId string, datastore, systemstore datastore.Datastore,
 		indexApiVersion:  indexApiVersion,
 		featureControls:  featureControls,
 		inlistHashMap:    nil,
+		spill:            newSpillBudget(),
 	}
 
 	if rv.maxParallelism <= 0 || rv.maxParallelism > runtime.NumCPU() {
@@ -213,12 +228,18 @@ func (this *Context) Copy() *Context {
 		pipelineCap:      this.pipelineCap,
 		pipelineBatch:    this.pipelineBatch,
 		now:              this.now,
+		recursionDepth:   this.recursionDepth,
 		credentials:      this.credentials,
 		consistency:      this.consistency,
 		scanVectorSource: this.scanVectorSource,
 		httpRequest:      this.httpRequest,
 		indexApiVersion:  this.indexApiVersion,
 		featureControls:  this.featureControls,
+		spill:            this.spill,
+		memory:           this.memory,
+		transaction:      this.transaction,
+		txTimeout:        this.txTimeout,
+		traceSpan:        this.traceSpan,
 	}
 }
 
@@ -365,6 +386,154 @@ func (this *Context) SetPipelineCap(pipelineCap int64) {
 	this.pipelineCap = pipelineCap
 }
 
+func (this *Context) spillEnabled() bool {
+	return this.spill != nil
+}
+
+// Whether operators need to charge the memory of the values they buffer,
+// to spill them or to keep within a memory quota
+func (this *Context) trackBuffers() bool {
+	return this.spill != nil || this.memory != nil
+}
+
+// Charge the request with size bytes buffered by an operator holding own
+// bytes in all: false if this takes the request over its memory quota,
+// and otherwise whether the operator should spill
+func (this *Context) chargeBuffer(size, own int64) (bool, bool) {
+	if this.memory != nil && !this.memory.track(size) {
+		return false, false
+	}
+	if this.spill == nil {
+		return true, false
+	}
+	used := go_atomic.AddInt64(&this.spill.used, size)
+	return true, used > this.spill.threshold && own >= this.spill.threshold/_SPILL_SHARE
+}
+
+func (this *Context) releaseBuffer(size int64) {
+	if size == 0 {
+		return
+	}
+	if this.memory != nil {
+		this.memory.release(size)
+	}
+	if this.spill != nil {
+		go_atomic.AddInt64(&this.spill.used, -size)
+	}
+}
+
+// Memory, in bytes, held by the values buffered by the operators of a
+// request that has a memory quota, shared by all its contexts
+type memoryAccount struct {
+	used     int64
+	quota    int64
+	exceeded int32
+	output   Output
+}
+
+const _MB = 1 << 20
+
+// The transaction the request belongs to, if any
+func (this *Context) SetTransaction(transaction *transactions.Transaction) {
+	this.transaction = transaction
+}
+
+func (this *Context) Transaction() *transactions.Transaction {
+	return this.transaction
+}
+
+// Timeout of the transactions started by the request, the default if zero
+func (this *Context) SetTxTimeout(timeout time.Duration) {
+	this.txTimeout = timeout
+}
+
+func (this *Context) TxTimeout() time.Duration {
+	return this.txTimeout
+}
+
+// operators and datastore calls are traced under this span
+func (this *Context) SetTraceSpan(span *tracing.Span) {
+	this.traceSpan = span
+}
+
+func (this *Context) TraceSpan() *tracing.Span {
+	return this.traceSpan
+}
+
+// Within a transaction, keyspaces are accessed through the transaction,
+// which stages the mutations and merges them into what is fetched
+func (this *Context) keyspace(keyspace datastore.Keyspace) datastore.Keyspace {
+	if this.transaction == nil {
+		return keyspace
+	}
+	return this.transaction.Keyspace(keyspace)
+}
+
+// quota is in MB, zero means no quota
+func (this *Context) SetMemoryQuota(quota uint64) {
+	if quota == 0 {
+		this.memory = nil
+	} else {
+		this.memory = &memoryAccount{quota: int64(quota) * _MB, output: this.output}
+	}
+}
+
+func (this *Context) MemoryQuota() uint64 {
+	if this.memory == nil {
+		return 0
+	}
+	return uint64(this.memory.quota / _MB)
+}
+
+// Whether the request has a memory quota that buffered values are charged to
+func (this *Context) UseRequestQuota() bool {
+	return this.memory != nil
+}
+
+func (this *Context) UsedMemory() uint64 {
+	if this.memory == nil {
+		return 0
+	}
+	used := go_atomic.LoadInt64(&this.memory.used)
+	if used < 0 {
+		return 0
+	}
+	return uint64(used)
+}
+
+// Charge the request with size bytes buffered by an operator: the request
+// fails once it goes over its memory quota
+func (this *Context) TrackValueSize(size uint64) bool {
+	if this.memory == nil {
+		return true
+	}
+	return this.memory.track(int64(size))
+}
+
+func (this *Context) ReleaseValueSize(size uint64) {
+	if this.memory != nil && size != 0 {
+		this.memory.release(int64(size))
+	}
+}
+
+func (this *memoryAccount) release(size int64) {
+	go_atomic.AddInt64(&this.used, -size)
+}
+
+func (this *memoryAccount) track(size int64) bool {
+	used := go_atomic.AddInt64(&this.used, size)
+	if used > 0 {
+		this.output.TrackMemory(uint64(used))
+	}
+	if used <= this.quota {
+		return true
+	}
+	if go_atomic.CompareAndSwapInt32(&this.exceeded, 0, 1) {
+		this.output.Fatal(errors.NewMemoryQuotaExceededError(uint64(this.quota / _MB)))
+	}
+	return false
+}
+
 func (this *Context) GetPipelineBatch() int {
 	if this.pipelineBatch > 0 {
 		return this.pipelineBatch
@@ -377,6 +546,20 @@ func (this *Context) PipelineBatch() int {
 	return this.pipelineBatch
 }
 
+// Maximum number of iterations of WITH RECURSIVE terms, the server
+// default if zero
+func (this *Context) SetMaxRecursionDepth(depth int) {
+	this.recursionDepth = depth
+}
+
+func (this *Context) MaxRecursionDepth() int {
+	if this.recursionDepth > 0 {
+		return this.recursionDepth
+	} else {
+		return MaxRecursionDepth()
+	}
+}
+
 func (this *Context) SetPipelineBatch(pipelineBatch int) {
 	this.pipelineBatch = pipelineBatch
 }
@@ -448,13 +631,21 @@ func (this *Context) Warning(wrn errors.Error) {
 // subquery evaluation
 
 func (this *Context) EvaluateSubquery(query *algebra.Select, parent value.Value) (value.Value, error) {
+	return this.evaluateSubquery(query, parent, true)
+}
+
+// cache is false for subqueries whose results depend on more than their
+// parent, such as the recursive part of a WITH RECURSIVE term
+func (this *Context) evaluateSubquery(query *algebra.Select, parent value.Value, cache bool) (value.Value, error) {
 	var subplan interface{}
 	planFound := false
 
 	subresults := this.getSubresults()
-	subresult, ok := subresults.get(query)
-	if ok {
-		return subresult.(value.Value), nil
+	if cache {
+		subresult, ok := subresults.get(query)
+		if ok {
+			return subresult.(value.Value), nil
+		}
 	}
 
 	// MB-34749 make subquery plans a property of the prepared statement
@@ -475,7 +666,7 @@ func (this *Context) EvaluateSubquery(query *algebra.Select, parent value.Value)
 
 				// MB-32140: do not replace named/positional arguments with its value for prepared statements
 				subplan, err = planner.Build(query, this.datastore, this.systemstore, this.namespace, true, false,
-					nil, nil, this.indexApiVersion, this.featureControls)
+					nil, nil, this.indexApiVersion, this.featureControls, this.transaction != nil)
 				if err != nil {
 					this.prepared.Unlock()
 
@@ -497,7 +688,7 @@ func (this *Context) EvaluateSubquery(query *algebra.Select, parent value.Value)
 			var err error
 
 			subplan, err = planner.Build(query, this.datastore, this.systemstore, this.namespace, true, false,
-				this.namedArgs, this.positionalArgs, this.indexApiVersion, this.featureControls)
+				this.namedArgs, this.positionalArgs, this.indexApiVersion, this.featureControls, this.transaction != nil)
 			if err != nil {
 
 				// Generate our own error for this subquery, in addition to whatever the query above is doing.
@@ -530,7 +721,7 @@ func (this *Context) EvaluateSubquery(query *algebra.Select, parent value.Value)
 	sequence.Done()
 
 	// Cache results
-	if !planFound && !query.IsCorrelated() {
+	if cache && !planFound && !query.IsCorrelated() {
 		subresults.set(query, results)
 	}
 
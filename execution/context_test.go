@@ -0,0 +1,70 @@
+package execution
+
+import (
+	"testing"
+
+	"github.com/couchbase/query/errors"
+)
+
+type quotaOutput struct {
+	internalOutput
+	fatal []errors.Error
+	peak  uint64
+}
+
+func (this *quotaOutput) Fatal(err errors.Error) {
+	this.fatal = append(this.fatal, err)
+}
+
+func (this *quotaOutput) TrackMemory(size uint64) {
+	if size > this.peak {
+		this.peak = size
+	}
+}
+
+func TestMemoryQuota(t *testing.T) {
+	output := &quotaOutput{}
+	context := &Context{output: output}
+
+	if !context.TrackValueSize(1 << 30) {
+		t.Errorf("expected no quota to always succeed")
+	}
+	if context.UsedMemory() != 0 || output.peak != 0 {
+		t.Errorf("expected no usage without a quota, got %v", context.UsedMemory())
+	}
+
+	context.SetMemoryQuota(1)
+	if !context.TrackValueSize(_MB/2) || !context.TrackValueSize(_MB/2) {
+		t.Fatalf("expected 1MB to fit a 1MB quota")
+	}
+	context.ReleaseValueSize(_MB / 4)
+	if context.UsedMemory() != 3*_MB/4 || output.peak != _MB {
+		t.Errorf("unexpected usage %v, peak %v", context.UsedMemory(), output.peak)
+	}
+
+	if context.TrackValueSize(_MB/2) || context.TrackValueSize(1) {
+		t.Errorf("expected the quota to be exceeded")
+	}
+	if len(output.fatal) != 1 || output.fatal[0].Code() != 5390 {
+		t.Errorf("expected a single quota error, got %v", output.fatal)
+	}
+}
+
+func TestMaxRecursionDepth(t *testing.T) {
+	defer SetMaxRecursionDepth(0)
+
+	context := &Context{}
+	if context.MaxRecursionDepth() != _MAX_RECURSION_DEPTH {
+		t.Errorf("expected the default depth, got %v", context.MaxRecursionDepth())
+	}
+
+	SetMaxRecursionDepth(10)
+	if context.MaxRecursionDepth() != 10 {
+		t.Errorf("expected the server depth, got %v", context.MaxRecursionDepth())
+	}
+
+	context.SetMaxRecursionDepth(5)
+	if context.MaxRecursionDepth() != 5 || context.Copy().MaxRecursionDepth() != 5 {
+		t.Errorf("expected the request depth, got %v", context.MaxRecursionDepth())
+	}
+}
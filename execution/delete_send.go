@@ -15,6 +15,7 @@ import (
 
 	"github.com/couchbase/query/errors"
 	"github.com/couchbase/query/plan"
+	"github.com/couchbase/query/resultcache"
 	"github.com/couchbase/query/util"
 	"github.com/couchbase/query/value"
 )
@@ -129,12 +130,13 @@ func (this *SendDelete) flushBatch(context *Context) bool {
 
 	this.switchPhase(_SERVTIME)
 
-	deleted_keys, e := this.plan.Keyspace().Delete(keys, context)
+	deleted_keys, e := context.keyspace(this.plan.Keyspace()).Delete(keys, context)
 
 	this.switchPhase(_EXECTIME)
 
 	// Update mutation count with number of deleted docs:
 	context.AddMutationCount(uint64(len(deleted_keys)))
+	resultcache.Invalidate(this.plan.Keyspace())
 
 	if e != nil {
 		context.Error(e)
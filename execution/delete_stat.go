@@ -0,0 +1,99 @@
+//  Copyright (c) 2019 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package execution
+
+import (
+	"encoding/json"
+
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/plan"
+	"github.com/couchbase/query/value"
+)
+
+type DeleteStatistics struct {
+	base
+	plan *plan.DeleteStatistics
+}
+
+func NewDeleteStatistics(plan *plan.DeleteStatistics, context *Context) *DeleteStatistics {
+	rv := &DeleteStatistics{
+		plan: plan,
+	}
+
+	newBase(&rv.base, context)
+	rv.newStopChannel()
+	rv.execPhase = DELETE_STAT
+	rv.output = rv
+	return rv
+}
+
+func (this *DeleteStatistics) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitDeleteStatistics(this)
+}
+
+func (this *DeleteStatistics) Copy() Operator {
+	rv := &DeleteStatistics{plan: this.plan}
+	this.base.copy(&rv.base)
+	return rv
+}
+
+func (this *DeleteStatistics) RunOnce(context *Context, parent value.Value) {
+	this.once.Do(func() {
+		defer context.Recover(&this.base) // Recover from any panic
+		active := this.active()
+		defer this.close(context)
+		this.switchPhase(_EXECTIME)
+		defer func() { this.switchPhase(_NOTIME) }()
+		defer this.notify() // Notify that I have stopped
+		if !active {
+			return
+		}
+
+		updstat, err := context.Datastore().StatUpdater()
+		if err != nil {
+			context.Error(errors.NewStatUpdaterNotFoundError(err))
+			return
+		}
+
+		delstat, ok := updstat.(datastore.StatDeleter)
+		if !ok {
+			context.Error(errors.NewOtherNotImplementedError(nil, "DELETE STATISTICS"))
+			return
+		}
+
+		conn := datastore.NewValueConnection(context)
+		defer notifyConn(conn.StopChannel())
+
+		go delstat.DeleteStatistics(this.plan.Keyspace(), this.plan.Node().Terms(), conn, context)
+
+		ok = true
+		for ok {
+			item, cont := this.getItemValue(conn.ValueChannel())
+			if item != nil && cont {
+				ok = this.sendItem(value.NewAnnotatedValue(item.(value.Value)))
+			} else {
+				break
+			}
+		}
+	})
+}
+
+func (this *DeleteStatistics) MarshalJSON() ([]byte, error) {
+	r := this.plan.MarshalBase(func(r map[string]interface{}) {
+		this.marshalTimes(r)
+	})
+	return json.Marshal(r)
+}
+
+// send a stop
+func (this *DeleteStatistics) SendStop() {
+	this.chanSendStop()
+}
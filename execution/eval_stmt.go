@@ -104,6 +104,10 @@ func (this *internalOutput) FmtPhaseTimes() map[string]interface{} {
 	return nil
 }
 
+func (this *internalOutput) TrackMemory(size uint64) {
+	// empty
+}
+
 func (this *Context) EvaluateStatement(statement string, namedArgs map[string]value.Value, positionalArgs value.Values, subquery, readonly bool) (value.Value, uint64, error) {
 	var outputBuf internalOutput
 	output := &outputBuf
@@ -134,7 +138,7 @@ func (this *Context) EvaluateStatement(statement string, namedArgs map[string]va
 
 	// prep := util.Now()
 	prepared, err := planner.BuildPrepared(stmt, this.datastore, this.systemstore, this.namespace, subquery, false,
-		namedArgs, positionalArgs, this.indexApiVersion, this.featureControls)
+		namedArgs, positionalArgs, this.indexApiVersion, this.featureControls, this.transaction != nil)
 	// output.AddPhaseTime(PLAN, util.Since(prep))
 	if err != nil {
 		return nil, 0, err
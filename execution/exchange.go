@@ -42,6 +42,8 @@ type valueQueue struct {
 	writeWaiters opQueue
 	localValues  [1]value.AnnotatedValue
 	vLock        sync.Mutex
+	memory       *memoryAccount // charged for the items queued, if the request has a quota
+	size         int64          // memory charged for the items queued
 }
 
 type operatorState struct {
@@ -121,6 +123,7 @@ func (this *valueExchange) trackChildren(children int) {
 func (this *valueExchange) reset() {
 	this.stop = false
 	this.closed = false
+	this.releaseSize()
 	for this.itemsCount > 0 {
 		this.items[this.itemsTail].Recycle()
 		this.items[this.itemsTail] = nil
@@ -141,6 +144,7 @@ func (this *valueExchange) reset() {
 func (this *valueExchange) dispose() {
 
 	// MB-28710 ditch values before pooling
+	this.releaseSize()
 	for this.itemsCount > 0 {
 		this.items[this.itemsTail].Recycle()
 		this.items[this.itemsTail] = nil
@@ -192,6 +196,12 @@ func (this *valueExchange) sendItem(op *valueExchange, item value.AnnotatedValue
 	if this.stop {
 		return false
 	}
+
+	// items are sized outside of the locks
+	var size int64
+	if op.memory != nil {
+		size = int64(item.Size())
+	}
 	op.vLock.Lock()
 	this.oLock.Lock()
 	for {
@@ -225,15 +235,40 @@ func (this *valueExchange) sendItem(op *valueExchange, item value.AnnotatedValue
 		op.itemsHead = 0
 	}
 	op.itemsCount++
+	op.size += size
 	op.readWaiters.signal()
 	if op.itemsCount < cap(op.items) {
 		op.writeWaiters.signal()
 	}
 	op.vLock.Unlock()
 
+	if size != 0 {
+		return op.memory.track(size)
+	}
 	return true
 }
 
+// the memory charged for an item leaving the queue
+func (this *valueExchange) dequeuedSize(item value.AnnotatedValue) int64 {
+	if this.size == 0 {
+		return 0
+	}
+	size := int64(item.Size())
+	if size > this.size {
+		size = this.size
+	}
+	this.size -= size
+	return size
+}
+
+// release the memory charged for the items queued
+func (this *valueExchange) releaseSize() {
+	if this.size != 0 {
+		this.memory.release(this.size)
+		this.size = 0
+	}
+}
+
 // channel length
 func (this *valueExchange) queuedItems(op *valueExchange) int {
 	return op.itemsCount
@@ -277,11 +312,15 @@ func (this *valueExchange) getItem(op *valueExchange) (value.AnnotatedValue, boo
 		op.itemsTail = 0
 	}
 	op.itemsCount--
+	size := op.dequeuedSize(val)
 	op.writeWaiters.signal()
 	if op.itemsCount > 0 {
 		op.readWaiters.signal()
 	}
 	op.vLock.Unlock()
+	if size != 0 {
+		op.memory.release(size)
+	}
 	return val, true
 }
 
@@ -332,11 +371,15 @@ func (this *valueExchange) getItemChildren(op *valueExchange) (value.AnnotatedVa
 		op.itemsTail = 0
 	}
 	op.itemsCount--
+	size := op.dequeuedSize(val)
 	op.writeWaiters.signal()
 	if op.itemsCount > 0 {
 		op.readWaiters.signal()
 	}
 	op.vLock.Unlock()
+	if size != 0 {
+		op.memory.release(size)
+	}
 	return val, -1, true
 }
 
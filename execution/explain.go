@@ -19,12 +19,17 @@ import (
 
 type Explain struct {
 	base
-	plan plan.Operator
+	plan  *plan.Explain
+	child Operator
 }
 
-func NewExplain(plan plan.Operator, context *Context) *Explain {
+// statements run by EXPLAIN ANALYZE in a transaction are rolled back to here
+const _EXPLAIN_SAVEPOINT = "#explain_analyze"
+
+func NewExplain(plan *plan.Explain, context *Context, child Operator) *Explain {
 	rv := &Explain{
-		plan: plan,
+		plan:  plan,
+		child: child,
 	}
 
 	newRedirectBase(&rv.base)
@@ -38,6 +43,9 @@ func (this *Explain) Accept(visitor Visitor) (interface{}, error) {
 
 func (this *Explain) Copy() Operator {
 	rv := &Explain{plan: this.plan}
+	if this.child != nil {
+		rv.child = this.child.Copy()
+	}
 	this.base.copy(&rv.base)
 	return rv
 }
@@ -54,7 +62,13 @@ func (this *Explain) RunOnce(context *Context, parent value.Value) {
 			return
 		}
 
-		bytes, err := this.plan.MarshalJSON()
+		var bytes []byte
+		var err error
+		if this.child != nil {
+			bytes, err = this.analyze(context, parent)
+		} else {
+			bytes, err = this.plan.MarshalJSON()
+		}
 		if err != nil {
 			context.Fatal(errors.NewExplainError(err, "EXPLAIN: Error marshaling JSON."))
 			return
@@ -66,6 +80,82 @@ func (this *Explain) RunOnce(context *Context, parent value.Value) {
 	})
 }
 
+/*
+Run the statement, discarding its results, and return the plan with
+the statistics of each operator. Where the optimizer has estimated the
+cardinality of an operator, the actual one is reported next to it.
+Within a transaction, the changes made by the statement are rolled back.
+*/
+func (this *Explain) analyze(context *Context, parent value.Value) ([]byte, error) {
+	txn := context.Transaction()
+	if txn != nil {
+		if err := txn.SetSavepoint(_EXPLAIN_SAVEPOINT); err != nil {
+			return nil, err
+		}
+	}
+
+	discard := NewDiscard(plan.NewDiscard(), context)
+	sequence := NewSequence(plan.NewSequence(), context, this.child, discard)
+
+	this.switchPhase(_NOTIME)
+	sequence.RunOnce(context, parent)
+	discard.waitComplete()
+	this.switchPhase(_EXECTIME)
+
+	bytes, err := json.Marshal(this.child)
+
+	// the sequence disposes of the statement operators
+	this.child = nil
+	sequence.Done()
+
+	if txn != nil {
+		if err := txn.RollbackTo(_EXPLAIN_SAVEPOINT); err != nil {
+			context.Error(err)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var actual interface{}
+	err = json.Unmarshal(bytes, &actual)
+	if err != nil {
+		return nil, err
+	}
+	annotateCardinality(actual)
+
+	r := this.plan.MarshalBase(func(r map[string]interface{}) {
+		r["plan"] = actual
+	})
+	return json.Marshal(r)
+}
+
+func annotateCardinality(op interface{}) {
+	switch op := op.(type) {
+	case map[string]interface{}:
+		if estimate, ok := op["cardinality"]; ok && op["#operator"] != nil {
+			stats, _ := op["#stats"].(map[string]interface{})
+			if stats == nil {
+				stats = make(map[string]interface{}, 2)
+				op["#stats"] = stats
+			}
+			actual, ok := stats["#itemsOut"]
+			if !ok {
+				actual = 0
+			}
+			stats["estimatedCardinality"] = estimate
+			stats["actualCardinality"] = actual
+		}
+		for _, v := range op {
+			annotateCardinality(v)
+		}
+	case []interface{}:
+		for _, v := range op {
+			annotateCardinality(v)
+		}
+	}
+}
+
 func (this *Explain) MarshalJSON() ([]byte, error) {
 	r := this.plan.MarshalBase(func(r map[string]interface{}) {
 		this.marshalTimes(r)
@@ -74,7 +164,20 @@ func (this *Explain) MarshalJSON() ([]byte, error) {
 	return json.Marshal(r)
 }
 
+func (this *Explain) SendStop() {
+	this.baseSendStop()
+	child := this.child
+	if child != nil {
+		child.SendStop()
+	}
+}
+
 func (this *Explain) Done() {
 	this.baseDone()
 	this.plan = nil
+	if this.child != nil {
+		child := this.child
+		this.child = nil
+		child.Done()
+	}
 }
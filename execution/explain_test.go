@@ -0,0 +1,37 @@
+package execution
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestAnnotateCardinality(t *testing.T) {
+	var op interface{}
+	err := json.Unmarshal([]byte(`{"#operator": "Sequence", "~children": [
+		{"#operator": "IndexScan3", "cardinality": 10, "#stats": {"#itemsOut": 7}},
+		{"#operator": "Filter", "cardinality": 2},
+		{"#operator": "InitialProject"}]}`), &op)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	annotateCardinality(op)
+
+	children := op.(map[string]interface{})["~children"].([]interface{})
+	expected := []map[string]interface{}{
+		{"#itemsOut": float64(7), "estimatedCardinality": float64(10), "actualCardinality": float64(7)},
+		{"estimatedCardinality": float64(2), "actualCardinality": 0},
+		nil,
+	}
+	for i, child := range children {
+		stats, _ := child.(map[string]interface{})["#stats"].(map[string]interface{})
+		if len(stats) != len(expected[i]) {
+			t.Errorf("child %v: expected %v, got %v", i, expected[i], stats)
+			continue
+		}
+		for k, v := range expected[i] {
+			if stats[k] != v {
+				t.Errorf("child %v: expected %v, got %v", i, expected[i], stats)
+			}
+		}
+	}
+}
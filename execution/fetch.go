@@ -137,7 +137,10 @@ func (this *Fetch) flushBatch(context *Context) bool {
 	this.switchPhase(_SERVTIME)
 
 	// Fetch
-	errs := this.plan.Keyspace().Fetch(fetchKeys, fetchMap, context, this.plan.SubPaths())
+	span := this.startDatastoreSpan("Fetch", "keyspace", this.plan.Keyspace().Name())
+	span.SetAttribute("keys", len(fetchKeys))
+	errs := context.keyspace(this.plan.Keyspace()).Fetch(fetchKeys, fetchMap, context, this.plan.SubPaths())
+	span.End()
 
 	this.switchPhase(_EXECTIME)
 
@@ -22,12 +22,15 @@ type FinalGroup struct {
 	base
 	plan   *plan.FinalGroup
 	groups map[string]value.AnnotatedValue
+	spill  groupSpill
+	sets   *groupingSets
 }
 
 func NewFinalGroup(plan *plan.FinalGroup, context *Context) *FinalGroup {
 	rv := &FinalGroup{
 		plan:   plan,
 		groups: make(map[string]value.AnnotatedValue),
+		sets:   newGroupingSets(plan.Keys(), plan.GroupingSets()),
 	}
 
 	newBase(&rv.base, context)
@@ -43,6 +46,7 @@ func (this *FinalGroup) Copy() Operator {
 	rv := &FinalGroup{
 		plan:   this.plan,
 		groups: make(map[string]value.AnnotatedValue),
+		sets:   this.sets,
 	}
 	this.base.copy(&rv.base)
 	return rv
@@ -55,7 +59,14 @@ func (this *FinalGroup) RunOnce(context *Context, parent value.Value) {
 func (this *FinalGroup) processItem(item value.AnnotatedValue, context *Context) bool {
 	// Generate the group key
 	var gk string
-	if len(this.plan.Keys()) > 0 {
+	if this.sets != nil {
+		var e error
+		gk, e = this.sets.key(item, groupingSet(item), context)
+		if e != nil {
+			context.Fatal(errors.NewEvaluationError(e, "GROUP key"))
+			return false
+		}
+	} else if len(this.plan.Keys()) > 0 {
 		var e error
 		gk, e = groupKey(item, this.plan.Keys(), context)
 		if e != nil {
@@ -89,7 +100,7 @@ func (this *FinalGroup) processItem(item value.AnnotatedValue, context *Context)
 			aggregates[agg.String()] = v
 		}
 
-		return true
+		return this.spill.charge(&this.base, gv, this.groups, context)
 	default:
 		context.Fatal(errors.NewInvalidValueError(fmt.Sprintf(
 			"Invalid or missing aggregates of type %T.", aggregates)))
@@ -98,25 +109,57 @@ func (this *FinalGroup) processItem(item value.AnnotatedValue, context *Context)
 }
 
 func (this *FinalGroup) afterItems(context *Context) {
-	for _, av := range this.groups {
-		if !this.sendItem(av) {
-			return
+	defer this.spill.release(context)
+	empty := len(this.groups) == 0 && !this.spill.spilled()
+	this.spill.send(&this.base, this.groups, context, this.cumulate)
+
+	// Mo matching inputs, so send default values
+	if !empty {
+		return
+	}
+
+	if this.sets == nil {
+		if len(this.plan.Keys()) == 0 {
+			this.sendItem(this.defaultGroup(context))
 		}
+		return
 	}
 
-	// Mo matching inputs, so send default values
-	if len(this.plan.Keys()) == 0 && len(this.groups) == 0 {
-		av := value.NewAnnotatedValue(nil)
-		aggregates := make(map[string]value.Value, len(this.plan.Aggregates()))
-		av.SetAttachment("aggregates", aggregates)
-		for _, agg := range this.plan.Aggregates() {
-			aggregates[agg.String()], _ = agg.Default(nil, context)
+	// The grand total of grouping sets
+	for n, set := range this.plan.GroupingSets() {
+		if len(set) > 0 {
+			continue
 		}
 
-		this.sendItem(av)
+		av, e := this.sets.seed(this.defaultGroup(context), n, context)
+		if e != nil {
+			context.Fatal(errors.NewEvaluationError(e, "GROUP key"))
+			return
+		}
+
+		if !this.sendItem(av) {
+			return
+		}
 	}
 }
 
+func (this *FinalGroup) defaultGroup(context *Context) value.AnnotatedValue {
+	av := value.NewAnnotatedValue(nil)
+	aggregates := make(map[string]value.Value, len(this.plan.Aggregates()))
+	av.SetAttachment("aggregates", aggregates)
+	for _, agg := range this.plan.Aggregates() {
+		aggregates[agg.String()], _ = agg.Default(nil, context)
+	}
+
+	return av
+}
+
+// Groups are unique by the time they reach here
+func (this *FinalGroup) cumulate(item, gv value.AnnotatedValue, context *Context) bool {
+	context.Fatal(errors.NewDuplicateFinalGroupError())
+	return false
+}
+
 func (this *FinalGroup) MarshalJSON() ([]byte, error) {
 	r := this.plan.MarshalBase(func(r map[string]interface{}) {
 		this.marshalTimes(r)
@@ -127,5 +170,6 @@ func (this *FinalGroup) MarshalJSON() ([]byte, error) {
 func (this *FinalGroup) reopen(context *Context) bool {
 	rv := this.baseReopen(context)
 	this.groups = make(map[string]value.AnnotatedValue)
+	this.spill = groupSpill{}
 	return rv
 }
@@ -23,12 +23,15 @@ type InitialGroup struct {
 	base
 	plan   *plan.InitialGroup
 	groups map[string]value.AnnotatedValue
+	spill  groupSpill
+	sets   *groupingSets
 }
 
 func NewInitialGroup(plan *plan.InitialGroup, context *Context) *InitialGroup {
 	rv := &InitialGroup{
 		plan:   plan,
 		groups: make(map[string]value.AnnotatedValue),
+		sets:   newGroupingSets(plan.Keys(), plan.GroupingSets()),
 	}
 
 	newBase(&rv.base, context)
@@ -44,6 +47,7 @@ func (this *InitialGroup) Copy() Operator {
 	rv := &InitialGroup{
 		plan:   this.plan,
 		groups: make(map[string]value.AnnotatedValue),
+		sets:   this.sets,
 	}
 	this.base.copy(&rv.base)
 	return rv
@@ -54,6 +58,22 @@ func (this *InitialGroup) RunOnce(context *Context, parent value.Value) {
 }
 
 func (this *InitialGroup) processItem(item value.AnnotatedValue, context *Context) bool {
+	// Fan the item out to its group in each grouping set
+	if this.sets != nil {
+		for n, _ := range this.plan.GroupingSets() {
+			gk, e := this.sets.key(item, n, context)
+			if e != nil {
+				context.Fatal(errors.NewEvaluationError(e, "GROUP key"))
+				return false
+			}
+
+			if !this.cumulateItem(gk, item, n, context) {
+				return false
+			}
+		}
+		return true
+	}
+
 	// Generate the group key
 	var gk string
 	if len(this.plan.Keys()) > 0 {
@@ -65,10 +85,24 @@ func (this *InitialGroup) processItem(item value.AnnotatedValue, context *Contex
 		}
 	}
 
+	return this.cumulateItem(gk, item, -1, context)
+}
+
+// Cumulate item into its group, in grouping set n if any
+func (this *InitialGroup) cumulateItem(gk string, item value.AnnotatedValue, n int, context *Context) bool {
 	// Get or seed the group value
 	gv := this.groups[gk]
-	if gv == nil {
+	seeded := gv == nil
+	if seeded {
 		gv = item
+		if n >= 0 {
+			var e error
+			gv, e = this.sets.seed(item, n, context)
+			if e != nil {
+				context.Fatal(errors.NewEvaluationError(e, "GROUP key"))
+				return false
+			}
+		}
 		this.groups[gk] = gv
 
 		aggregates := make(map[string]value.Value, len(this.plan.Aggregates()))
@@ -96,15 +130,19 @@ func (this *InitialGroup) processItem(item value.AnnotatedValue, context *Contex
 		aggregates[agg.String()] = v
 	}
 
+	if seeded {
+		return this.spill.charge(&this.base, gv, this.groups, context)
+	}
 	return true
 }
 
 func (this *InitialGroup) afterItems(context *Context) {
-	for _, av := range this.groups {
-		if !this.sendItem(av) {
-			return
-		}
-	}
+	defer this.spill.release(context)
+	this.spill.send(&this.base, this.groups, context, this.cumulate)
+}
+
+func (this *InitialGroup) cumulate(item, gv value.AnnotatedValue, context *Context) bool {
+	return cumulateGroup(this.plan.Aggregates(), item, gv, context)
 }
 
 func (this *InitialGroup) MarshalJSON() ([]byte, error) {
@@ -117,5 +155,6 @@ func (this *InitialGroup) MarshalJSON() ([]byte, error) {
 func (this *InitialGroup) reopen(context *Context) bool {
 	rv := this.baseReopen(context)
 	this.groups = make(map[string]value.AnnotatedValue)
+	this.spill = groupSpill{}
 	return rv
 }
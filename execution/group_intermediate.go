@@ -11,7 +11,6 @@ package execution
 
 import (
 	"encoding/json"
-	"fmt"
 
 	"github.com/couchbase/query/errors"
 	"github.com/couchbase/query/plan"
@@ -23,12 +22,15 @@ type IntermediateGroup struct {
 	base
 	plan   *plan.IntermediateGroup
 	groups map[string]value.AnnotatedValue
+	spill  groupSpill
+	sets   *groupingSets
 }
 
 func NewIntermediateGroup(plan *plan.IntermediateGroup, context *Context) *IntermediateGroup {
 	rv := &IntermediateGroup{
 		plan:   plan,
 		groups: make(map[string]value.AnnotatedValue),
+		sets:   newGroupingSets(plan.Keys(), plan.GroupingSets()),
 	}
 
 	newBase(&rv.base, context)
@@ -44,6 +46,7 @@ func (this *IntermediateGroup) Copy() Operator {
 	rv := &IntermediateGroup{
 		plan:   this.plan,
 		groups: make(map[string]value.AnnotatedValue),
+		sets:   this.sets,
 	}
 	this.base.copy(&rv.base)
 	return rv
@@ -56,7 +59,14 @@ func (this *IntermediateGroup) RunOnce(context *Context, parent value.Value) {
 func (this *IntermediateGroup) processItem(item value.AnnotatedValue, context *Context) bool {
 	// Generate the group key
 	var gk string
-	if len(this.plan.Keys()) > 0 {
+	if this.sets != nil {
+		var e error
+		gk, e = this.sets.key(item, groupingSet(item), context)
+		if e != nil {
+			context.Fatal(errors.NewEvaluationError(e, "GROUP key"))
+			return false
+		}
+	} else if len(this.plan.Keys()) > 0 {
 		var e error
 		gk, e = groupKey(item, this.plan.Keys(), context)
 		if e != nil {
@@ -70,45 +80,20 @@ func (this *IntermediateGroup) processItem(item value.AnnotatedValue, context *C
 	if gv == nil {
 		gv = item
 		this.groups[gk] = gv
-		return true
+		return this.spill.charge(&this.base, gv, this.groups, context)
 	}
 
 	// Cumulate aggregates
-	part, ok := item.GetAttachment("aggregates").(map[string]value.Value)
-	if !ok {
-		context.Fatal(errors.NewInvalidValueError(
-			fmt.Sprintf("Invalid partial aggregates %v of type %T", part, part)))
-		return false
-	}
-
-	cumulative := gv.GetAttachment("aggregates").(map[string]value.Value)
-	if !ok {
-		context.Fatal(errors.NewInvalidValueError(
-			fmt.Sprintf("Invalid cumulative aggregates %v of type %T", cumulative, cumulative)))
-		return false
-	}
-
-	for _, agg := range this.plan.Aggregates() {
-		a := agg.String()
-		v, e := agg.CumulateIntermediate(part[a], cumulative[a], context)
-		if e != nil {
-			context.Fatal(errors.NewGroupUpdateError(
-				e, "Error updating intermediate GROUP value."))
-			return false
-		}
-
-		cumulative[a] = v
-	}
-
-	return true
+	return cumulateGroup(this.plan.Aggregates(), item, gv, context)
 }
 
 func (this *IntermediateGroup) afterItems(context *Context) {
-	for _, av := range this.groups {
-		if !this.sendItem(av) {
-			return
-		}
-	}
+	defer this.spill.release(context)
+	this.spill.send(&this.base, this.groups, context, this.cumulate)
+}
+
+func (this *IntermediateGroup) cumulate(item, gv value.AnnotatedValue, context *Context) bool {
+	return cumulateGroup(this.plan.Aggregates(), item, gv, context)
 }
 
 func (this *IntermediateGroup) MarshalJSON() ([]byte, error) {
@@ -121,5 +106,6 @@ func (this *IntermediateGroup) MarshalJSON() ([]byte, error) {
 func (this *IntermediateGroup) reopen(context *Context) bool {
 	rv := this.baseReopen(context)
 	this.groups = make(map[string]value.AnnotatedValue)
+	this.spill = groupSpill{}
 	return rv
 }
@@ -10,6 +10,11 @@
 package execution
 
 import (
+	"fmt"
+	"strconv"
+
+	"github.com/couchbase/query/algebra"
+	"github.com/couchbase/query/errors"
 	"github.com/couchbase/query/expression"
 	"github.com/couchbase/query/util"
 	"github.com/couchbase/query/value"
@@ -35,3 +40,277 @@ func groupKey(item value.Value, keys expression.Expressions, context *Context) (
 }
 
 var _GROUP_KEY_POOL = util.NewStringInterfacePool(16)
+
+/*
+The grouping sets of ROLLUP, CUBE and GROUPING SETS. Each item is
+grouped once per set, on the set and the values of the keys in it.
+*/
+type groupingSets struct {
+	keys    expression.Expressions
+	sets    [][]int
+	rollups []value.Value // per set, the keys it rolls up, for GROUPING()
+}
+
+func newGroupingSets(keys expression.Expressions, sets [][]int) *groupingSets {
+	if sets == nil {
+		return nil
+	}
+
+	rollups := make([]value.Value, len(sets))
+	for n, set := range sets {
+		rollup := make(map[string]interface{}, len(keys))
+		for _, key := range keys {
+			rollup[key.String()] = true
+		}
+		for _, i := range set {
+			delete(rollup, keys[i].String())
+		}
+		rollups[n] = value.NewValue(rollup)
+	}
+
+	return &groupingSets{
+		keys:    keys,
+		sets:    sets,
+		rollups: rollups,
+	}
+}
+
+// Generate the group key of item in grouping set n
+func (this *groupingSets) key(item value.Value, n int, context *Context) (string, error) {
+	set := this.sets[n]
+	kvs := _GROUP_KEY_POOL.GetCapped(len(set))
+	defer _GROUP_KEY_POOL.Put(kvs)
+
+	for _, i := range set {
+		k, e := this.keys[i].Evaluate(item, context)
+		if e != nil {
+			return "", e
+		}
+
+		if k.Type() != value.MISSING {
+			kvs[strconv.Itoa(i)] = k
+		}
+	}
+
+	bytes, _ := value.NewValue(kvs).MarshalJSON()
+	return strconv.Itoa(n) + string(bytes), nil
+}
+
+/*
+Seed the group of item in grouping set n. The group covers the keys
+out of the set as NULL, and the others with their values.
+*/
+func (this *groupingSets) seed(item value.AnnotatedValue, n int, context *Context) (
+	value.AnnotatedValue, error) {
+
+	gv := item.Copy().(value.AnnotatedValue)
+	for _, key := range this.keys {
+		gv.SetCover(coverText(key), value.NULL_VALUE)
+	}
+
+	for _, i := range this.sets[n] {
+		k, e := this.keys[i].Evaluate(item, context)
+		if e != nil {
+			return nil, e
+		}
+
+		gv.SetCover(coverText(this.keys[i]), k)
+	}
+
+	gv.SetAttachment("grouping_set", n)
+	gv.SetAttachment("grouping", this.rollups[n])
+	return gv, nil
+}
+
+// Keys covered by an index scan are covered under the text of the index key
+func coverText(key expression.Expression) string {
+	if cover, ok := key.(*expression.Cover); ok {
+		return cover.Text()
+	}
+	return key.String()
+}
+
+// The grouping set of a group seeded by seed()
+func groupingSet(gv value.AnnotatedValue) int {
+	n, _ := gv.GetAttachment("grouping_set").(int)
+	return n
+}
+
+// Cumulate the partial aggregates of item into those of the group value
+func cumulateGroup(aggs algebra.Aggregates, item, gv value.AnnotatedValue, context *Context) bool {
+	part, ok := item.GetAttachment("aggregates").(map[string]value.Value)
+	if !ok {
+		context.Fatal(errors.NewInvalidValueError(
+			fmt.Sprintf("Invalid partial aggregates %v of type %T", part, part)))
+		return false
+	}
+
+	cumulative, ok := gv.GetAttachment("aggregates").(map[string]value.Value)
+	if !ok {
+		context.Fatal(errors.NewInvalidValueError(
+			fmt.Sprintf("Invalid cumulative aggregates %v of type %T", cumulative, cumulative)))
+		return false
+	}
+
+	for _, agg := range aggs {
+		a := agg.String()
+		v, e := agg.CumulateIntermediate(part[a], cumulative[a], context)
+		if e != nil {
+			context.Fatal(errors.NewGroupUpdateError(
+				e, "Error updating intermediate GROUP value."))
+			return false
+		}
+
+		cumulative[a] = v
+	}
+
+	return true
+}
+
+/*
+Groups spilled to temporary files, partitioned on the group key, so
+that each partition can then be aggregated on its own.
+*/
+type groupSpill struct {
+	partitions []*spillFile
+	size       int64 // memory charged for the groups held
+}
+
+// Charge the memory of a new group, and spill the groups held if needed
+func (this *groupSpill) charge(op *base, gv value.AnnotatedValue, groups map[string]value.AnnotatedValue,
+	context *Context) bool {
+
+	if !context.trackBuffers() {
+		return true
+	}
+	size := int64(gv.Size())
+	this.size += size
+	ok, spill := context.chargeBuffer(size, this.size)
+	if ok && spill {
+		return this.spill(op, groups, context)
+	}
+	return ok
+}
+
+func (this *groupSpill) spilled() bool {
+	return this.partitions != nil
+}
+
+// Write out the groups held, and empty the map
+func (this *groupSpill) spill(op *base, groups map[string]value.AnnotatedValue, context *Context) bool {
+	if this.partitions == nil {
+		this.partitions = make([]*spillFile, _SPILL_PARTITIONS)
+		for i, _ := range this.partitions {
+			p, err := newSpillFile("GROUP BY")
+			if err != nil {
+				context.Error(err)
+				return false
+			}
+			this.partitions[i] = p
+		}
+	}
+
+	size := this.spillSize()
+	for gk, gv := range groups {
+		err := this.partitions[spillPartition([]byte(gk))].write(gk, gv)
+		if err != nil {
+			context.Error(err)
+			return false
+		}
+		delete(groups, gk)
+	}
+
+	op.addSpill(this.spillSize() - size)
+	context.releaseBuffer(this.size)
+	this.size = 0
+	return true
+}
+
+func (this *groupSpill) spillSize() int64 {
+	var size int64
+	for _, p := range this.partitions {
+		if p != nil {
+			size += p.size
+		}
+	}
+	return size
+}
+
+/*
+Move the groups of one partition out of the groups held, and add
+those spilled, cumulating any found twice.
+*/
+func (this *groupSpill) partition(n int, groups map[string]value.AnnotatedValue, context *Context,
+	cumulate func(item, gv value.AnnotatedValue, context *Context) bool) (map[string]value.AnnotatedValue, bool) {
+
+	rv := make(map[string]value.AnnotatedValue)
+	for gk, gv := range groups {
+		if spillPartition([]byte(gk)) == n {
+			rv[gk] = gv
+			delete(groups, gk)
+		}
+	}
+
+	p := this.partitions[n]
+	err := p.rewind()
+	for err == nil {
+		var gk string
+		var item value.AnnotatedValue
+
+		gk, item, err = p.read()
+		if err != nil || item == nil {
+			break
+		}
+
+		gv := rv[gk]
+		if gv == nil {
+			rv[gk] = item
+		} else if !cumulate(item, gv, context) {
+			return nil, false
+		}
+	}
+
+	p.close()
+	if err != nil {
+		context.Error(err)
+		return nil, false
+	}
+	return rv, true
+}
+
+// Send the groups held and, if any, those spilled, a partition at a time
+func (this *groupSpill) send(op *base, groups map[string]value.AnnotatedValue, context *Context,
+	cumulate func(item, gv value.AnnotatedValue, context *Context) bool) {
+
+	if this.partitions == nil {
+		for _, av := range groups {
+			if !op.sendItem(av) {
+				return
+			}
+		}
+		return
+	}
+
+	for n, _ := range this.partitions {
+		part, ok := this.partition(n, groups, context, cumulate)
+		if !ok {
+			return
+		}
+		for _, av := range part {
+			if !op.sendItem(av) {
+				return
+			}
+		}
+	}
+}
+
+func (this *groupSpill) release(context *Context) {
+	for _, p := range this.partitions {
+		if p != nil {
+			p.close()
+		}
+	}
+	this.partitions = nil
+	context.releaseBuffer(this.size)
+	this.size = 0
+}
@@ -15,6 +15,7 @@ import (
 
 	"github.com/couchbase/query/errors"
 	"github.com/couchbase/query/plan"
+	"github.com/couchbase/query/resultcache"
 	"github.com/couchbase/query/util"
 	"github.com/couchbase/query/value"
 )
@@ -112,7 +113,8 @@ func (this *SendInsert) flushBatch(context *Context) bool {
 
 	keyExpr := this.plan.Key()
 	valExpr := this.plan.Value()
-	var key, val value.Value
+	optsExpr := this.plan.Options()
+	var key, val, options value.Value
 	var err error
 	var ok bool
 	i := 0
@@ -140,6 +142,17 @@ func (this *SendInsert) flushBatch(context *Context) bool {
 			} else {
 				val = av
 			}
+
+			if optsExpr != nil {
+				options, err = optsExpr.Evaluate(av, context)
+				if err != nil {
+					context.Error(errors.NewEvaluationError(err,
+						fmt.Sprintf("INSERT options for %v", av.GetValue())))
+					continue
+				}
+			} else {
+				options = nil
+			}
 		} else {
 			// INSERT ... VALUES
 			key, ok = av.GetAttachment("key").(value.Value)
@@ -153,6 +166,8 @@ func (this *SendInsert) flushBatch(context *Context) bool {
 				context.Error(errors.NewInsertValueError(av.GetValue()))
 				continue
 			}
+
+			options, _ = av.GetAttachment("options").(value.Value)
 		}
 
 		dpair.Name, ok = key.Actual().(string)
@@ -161,6 +176,11 @@ func (this *SendInsert) flushBatch(context *Context) bool {
 			continue
 		}
 
+		dpair.Options, ok = dmlOptions("INSERT", options, context)
+		if !ok {
+			continue
+		}
+
 		dpair.Value = val
 		i++
 	}
@@ -171,12 +191,13 @@ func (this *SendInsert) flushBatch(context *Context) bool {
 
 	// Perform the actual INSERT
 	var er errors.Error
-	dpairs, er = this.plan.Keyspace().Insert(dpairs)
+	dpairs, er = context.keyspace(this.plan.Keyspace()).Insert(dpairs)
 
 	this.switchPhase(_EXECTIME)
 
 	// Update mutation count with number of inserted docs
 	context.AddMutationCount(uint64(len(dpairs)))
+	resultcache.Invalidate(this.plan.Keyspace())
 
 	if er != nil {
 		context.Error(er)
@@ -77,7 +77,10 @@ func (this *joinBase) joinFetch(keyspace datastore.Keyspace, keyCount map[string
 	}
 
 	this.switchPhase(_SERVTIME)
-	errs := keyspace.Fetch(fetchKeys, pairMap, context, nil)
+	span := this.startDatastoreSpan("Fetch", "keyspace", keyspace.Name())
+	span.SetAttribute("keys", len(fetchKeys))
+	errs := context.keyspace(keyspace).Fetch(fetchKeys, pairMap, context, nil)
+	span.End()
 	this.switchPhase(_EXECTIME)
 
 	fetchOk := true
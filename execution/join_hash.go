@@ -28,6 +28,8 @@ type HashJoin struct {
 	hashTab   *util.HashTable
 	buildVals value.Values
 	probeVals value.Values
+	matched   map[value.AnnotatedValue]bool
+	spill     *joinSpill
 }
 
 func NewHashJoin(plan *plan.HashJoin, context *Context, child Operator, aliasMap map[string]string) *HashJoin {
@@ -85,6 +87,7 @@ func (this *HashJoin) beforeItems(context *Context, parent value.Value) bool {
 
 	// build hash table
 	this.hashTab = util.NewHashTable(util.HASH_TABLE_FOR_HASH_JOIN)
+	this.spill = newJoinSpill(context, true)
 
 	this.buildVals = make(value.Values, len(this.plan.BuildExprs()))
 	this.probeVals = make(value.Values, len(this.plan.ProbeExprs()))
@@ -97,22 +100,36 @@ func (this *HashJoin) beforeItems(context *Context, parent value.Value) bool {
 	this.fork(this.child, context, parent)
 
 	ok := buildHashTab(&(this.base), this.child, this.hashTab,
-		this.plan.BuildExprs(), this.buildVals, context)
+		this.plan.BuildExprs(), this.buildVals, this.spill, context)
 	if !ok {
 		return false
 	}
 
+	// if the build side has been spilled, the probe side follows it
+	// to disk, and the partitions are joined after the probe side is done
+	if this.spill.spilled() {
+		return this.spill.open(this.spill.probe, context)
+	}
+
 	// if the build side is empty and this is not an outer join,
 	// no need to activate the probe side.
 	if this.hashTab.Count() == 0 && !this.plan.Outer() {
 		return false
 	}
 
+	// for full outer join, remember which build side documents
+	// found a match, the others are returned after probing
+	if this.plan.BuildOuter() {
+		this.matched = make(map[value.AnnotatedValue]bool, this.hashTab.Count())
+	}
+
 	return true
 }
 
+// spill, if not nil, charges the memory of the hash table, and partitions
+// the build side when it grows too large
 func buildHashTab(base *base, buildOp Operator, hashTab *util.HashTable,
-	buildExprs expression.Expressions, buildVals value.Values, context *Context) bool {
+	buildExprs expression.Expressions, buildVals value.Values, spill *joinSpill, context *Context) bool {
 	var err error
 	stopped := false
 	n := 1
@@ -135,11 +152,25 @@ loop:
 				} else {
 					buildVal = value.NewValue(buildVals)
 				}
+				if spill.spilled() {
+					if !spill.write(spill.build, buildVal, build_item, context) {
+						return false
+					}
+					continue
+				}
+
 				err = hashTab.Put(buildVal, build_item, value.MarshalValue, value.EqualValue)
 				if err != nil {
 					context.Error(errors.NewHashTablePutError(err))
 					return false
 				}
+
+				if spill != nil {
+					ok, full := spill.charge(build_item, context)
+					if !ok || (full && !spill.spillTable(hashTab, buildExprs, buildVals, context)) {
+						return false
+					}
+				}
 			} else if child >= 0 {
 				n--
 			} else {
@@ -183,6 +214,17 @@ func getProbeVal(item value.AnnotatedValue, probeExprs expression.Expressions,
 }
 
 func (this *HashJoin) processItem(item value.AnnotatedValue, context *Context) bool {
+	if this.spill.spilled() {
+		probeVal := getProbeVal(item, this.plan.ProbeExprs(), this.probeVals, context)
+		if probeVal == nil {
+			return false
+		}
+		return this.spill.write(this.spill.probe, probeVal, item, context)
+	}
+	return this.probe(item, context)
+}
+
+func (this *HashJoin) probe(item value.AnnotatedValue, context *Context) bool {
 	defer this.switchPhase(_EXECTIME)
 
 	var err error
@@ -208,6 +250,9 @@ func (this *HashJoin) processItem(item value.AnnotatedValue, context *Context) b
 				this.plan.BuildAliases(), this.ansiFlags, context, "join")
 			if match && ok {
 				matched = true
+				if this.matched != nil {
+					this.matched[right_item] = true
+				}
 				ok = this.sendItem(joined)
 			}
 		} else {
@@ -230,10 +275,106 @@ func (this *HashJoin) processItem(item value.AnnotatedValue, context *Context) b
 }
 
 func (this *HashJoin) afterItems(context *Context) {
+	if this.spill.spilled() {
+		if !this.stopped {
+			this.joinPartitions(context)
+		}
+	} else if this.matched != nil && !this.stopped {
+		this.sendUnmatched()
+	}
+	this.matched = nil
 	this.dropHashTable()
+	this.spill.release(context)
+	this.spill = nil
 	this.plan.Onclause().ResetMemory(context)
 }
 
+// send the build side documents that did not find a match
+func (this *HashJoin) sendUnmatched() bool {
+	for {
+		outVal := this.hashTab.Iterate()
+		if outVal == nil {
+			return true
+		}
+		if build_item, ok := outVal.(value.AnnotatedValue); ok && !this.matched[build_item] {
+			if !this.sendItem(build_item) {
+				return false
+			}
+		}
+	}
+}
+
+// join the spilled sides one partition at a time
+func (this *HashJoin) joinPartitions(context *Context) {
+	this.addSpill(this.spill.spillSize())
+
+	for n, build := range this.spill.build {
+		this.dropHashTable()
+		this.hashTab = util.NewHashTable(util.HASH_TABLE_FOR_HASH_JOIN)
+
+		// the partitions fit in memory, but still count towards the quota
+		context.ReleaseValueSize(this.spill.partSize)
+		this.spill.partSize = 0
+
+		err := build.rewind()
+		for err == nil {
+			var key string
+			var item value.AnnotatedValue
+
+			key, item, err = build.read()
+			if err != nil || item == nil {
+				break
+			}
+			e := this.hashTab.Put(value.NewValue([]byte(key)), item, value.MarshalValue, value.EqualValue)
+			if e != nil {
+				context.Error(errors.NewHashTablePutError(e))
+				return
+			}
+			size := item.Size()
+			this.spill.partSize += size
+			if !context.TrackValueSize(size) {
+				return
+			}
+		}
+		build.close()
+		if err != nil {
+			context.Error(err)
+			return
+		}
+
+		if this.hashTab.Count() == 0 && !this.plan.Outer() {
+			continue
+		}
+
+		if this.plan.BuildOuter() {
+			this.matched = make(map[value.AnnotatedValue]bool, this.hashTab.Count())
+		}
+
+		probe := this.spill.probe[n]
+		err = probe.rewind()
+		for err == nil {
+			var item value.AnnotatedValue
+
+			_, item, err = probe.read()
+			if err != nil || item == nil {
+				break
+			}
+			if !this.probe(item, context) {
+				return
+			}
+		}
+		probe.close()
+		if err != nil {
+			context.Error(err)
+			return
+		}
+
+		if this.matched != nil && !this.sendUnmatched() {
+			return
+		}
+	}
+}
+
 func (this *HashJoin) dropHashTable() {
 	if this.hashTab != nil {
 		this.hashTab.Drop()
@@ -265,3 +406,150 @@ func (this *HashJoin) Done() {
 		child.Done()
 	}
 }
+
+/*
+A hash join whose build side goes over the spill threshold becomes a
+partitioned one: both sides are written out in hash partitions of
+their join keys, and then joined one partition at a time.
+The hash tables are also charged to the memory quota of the request.
+*/
+type joinSpill struct {
+	build    []*spillFile
+	probe    []*spillFile
+	size     int64  // memory charged for the hash table
+	partSize uint64 // memory charged for the hash table of a partition
+}
+
+// Nil if memory is not being tracked; partition is false for hash tables
+// that are only charged for, and never spilled
+func newJoinSpill(context *Context, partition bool) *joinSpill {
+	if !context.trackBuffers() {
+		return nil
+	}
+	rv := &joinSpill{}
+	if partition && context.spillEnabled() {
+		rv.probe = make([]*spillFile, _SPILL_PARTITIONS)
+	}
+	return rv
+}
+
+func (this *joinSpill) spilled() bool {
+	return this != nil && this.build != nil
+}
+
+// Charge the memory of a build side document: false if the request goes
+// over its memory quota, and otherwise whether to spill
+func (this *joinSpill) charge(item value.AnnotatedValue, context *Context) (bool, bool) {
+	size := int64(item.Size())
+	this.size += size
+	ok, spill := context.chargeBuffer(size, this.size)
+	return ok, spill && this.probe != nil
+}
+
+func (this *joinSpill) open(partitions []*spillFile, context *Context) bool {
+	for i, _ := range partitions {
+		p, err := newSpillFile("HASH JOIN")
+		if err != nil {
+			context.Error(err)
+			return false
+		}
+		partitions[i] = p
+	}
+	return true
+}
+
+// Write a document to the partition of its join key
+func (this *joinSpill) write(partitions []*spillFile, key value.Value, item value.AnnotatedValue,
+	context *Context) bool {
+
+	bytes, e := value.MarshalValue(key)
+	if e != nil {
+		context.Error(errors.NewSpillError(e, "HASH JOIN"))
+		return false
+	}
+
+	err := partitions[spillPartition(bytes)].write(string(bytes), item)
+	if err != nil {
+		context.Error(err)
+		return false
+	}
+	return true
+}
+
+// Move the hash table built so far to the build side partitions
+func (this *joinSpill) spillTable(hashTab *util.HashTable, buildExprs expression.Expressions,
+	buildVals value.Values, context *Context) bool {
+
+	this.build = make([]*spillFile, _SPILL_PARTITIONS)
+	if !this.open(this.build, context) {
+		return false
+	}
+
+	for {
+		outVal := hashTab.Iterate()
+		if outVal == nil {
+			break
+		}
+		build_item, ok := outVal.(value.AnnotatedValue)
+		if !ok {
+			context.Error(errors.NewExecutionInternalError("Hash Table Iterate produced non-Annotated value"))
+			return false
+		}
+
+		var err error
+		for i, be := range buildExprs {
+			buildVals[i], err = be.Evaluate(build_item, context)
+			if err != nil {
+				context.Error(errors.NewEvaluationError(err, "Hash Table Build Expression"))
+				return false
+			}
+		}
+		var buildVal value.Value
+		if len(buildVals) == 1 {
+			buildVal = buildVals[0]
+		} else {
+			buildVal = value.NewValue(buildVals)
+		}
+		if !this.write(this.build, buildVal, build_item, context) {
+			return false
+		}
+	}
+
+	hashTab.Drop()
+	context.releaseBuffer(this.size)
+	this.size = 0
+	return true
+}
+
+func (this *joinSpill) spillSize() int64 {
+	var size int64
+	for _, p := range this.build {
+		size += p.size
+	}
+	for _, p := range this.probe {
+		if p != nil {
+			size += p.size
+		}
+	}
+	return size
+}
+
+func (this *joinSpill) release(context *Context) {
+	if this == nil {
+		return
+	}
+	for _, p := range this.build {
+		if p != nil {
+			p.close()
+		}
+	}
+	for _, p := range this.probe {
+		if p != nil {
+			p.close()
+		}
+	}
+	context.releaseBuffer(this.size)
+	context.ReleaseValueSize(this.partSize)
+	this.size = 0
+	this.partSize = 0
+}
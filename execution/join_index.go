@@ -134,8 +134,10 @@ func (this *IndexJoin) scan(id string, context *Context,
 		consistency = datastore.SCAN_PLUS
 	}
 
+	tspan := this.startDatastoreSpan("Scan", "index", this.plan.Index().Name())
 	this.plan.Index().Scan(context.RequestId(), span, false,
 		math.MaxInt64, consistency, nil, conn)
+	tspan.End()
 	wg.Done()
 }
 
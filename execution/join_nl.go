@@ -27,10 +27,13 @@ const (
 
 type NLJoin struct {
 	base
-	plan      *plan.NLJoin
-	child     Operator
-	aliasMap  map[string]string
-	ansiFlags uint32
+	plan       *plan.NLJoin
+	child      Operator
+	aliasMap   map[string]string
+	ansiFlags  uint32
+	rightItems value.AnnotatedValues
+	matched    []bool
+	size       uint64
 }
 
 func NewNLJoin(plan *plan.NLJoin, context *Context, child Operator, aliasMap map[string]string) *NLJoin {
@@ -86,12 +89,78 @@ func (this *NLJoin) beforeItems(context *Context, parent value.Value) bool {
 		SetSearchInfo(this.aliasMap, parent, context, this.plan.Onclause())
 	}
 
+	// for RIGHT and FULL OUTER JOIN the right-hand side does not depend on the
+	// left-hand side: it is scanned once, and the documents that found no match
+	// are returned once the left-hand side is done
+	if this.plan.RightOuter() {
+		return this.scanRight(context, parent)
+	}
+
+	return true
+}
+
+func (this *NLJoin) scanRight(context *Context, parent value.Value) bool {
+	this.child.SetOutput(this.child)
+	this.child.SetInput(nil)
+	this.child.SetParent(this)
+	this.child.SetStop(nil)
+
+	this.fork(this.child, context, parent)
+
+	stopped := false
+	n := 1
+
+loop:
+	for {
+		right_item, child, cont := this.getItemChildrenOp(this.child)
+		if cont {
+			if right_item != nil {
+				if context.UseRequestQuota() {
+					size := right_item.Size()
+					this.size += size
+					if !context.TrackValueSize(size) {
+						stopped = true
+						break loop
+					}
+				}
+				this.rightItems = append(this.rightItems, right_item)
+			} else if child >= 0 {
+				n--
+			} else {
+				break loop
+			}
+		} else {
+			stopped = true
+			break loop
+		}
+	}
+
+	if n > 0 {
+		notifyChildren(this.child)
+		this.childrenWaitNoStop(n)
+	}
+
+	if stopped {
+		return false
+	}
+
+	this.matched = make([]bool, len(this.rightItems))
 	return true
 }
 
 func (this *NLJoin) processItem(item value.AnnotatedValue, context *Context) bool {
 	defer this.switchPhase(_EXECTIME)
 
+	if this.plan.RightOuter() {
+		if !this.joinRight(item, context) {
+
+			// the join did not complete, don't add the unmatched documents
+			this.matched = nil
+			return false
+		}
+		return true
+	}
+
 	if (this.ansiFlags&ANSI_REOPEN_CHILD) != 0 && this.child != nil && !this.child.reopen(context) {
 
 		// If the reopen failed, we should propagate the stop signal to the inner scan again
@@ -157,7 +226,44 @@ loop:
 	return true
 }
 
+// join with the right-hand side documents kept by scanRight
+func (this *NLJoin) joinRight(item value.AnnotatedValue, context *Context) bool {
+	aliases := []string{this.plan.Alias()}
+	matched := false
+	for i, right_item := range this.rightItems {
+		match, ok, joined := processAnsiExec(item, right_item, this.plan.Onclause(),
+			aliases, this.ansiFlags, context, "join")
+		if !ok {
+			return false
+		}
+		if match {
+			matched = true
+			this.matched[i] = true
+			if !this.sendItem(joined) {
+				return false
+			}
+		}
+	}
+
+	if this.plan.Outer() && !matched {
+		return this.sendItem(item)
+	}
+
+	return true
+}
+
 func (this *NLJoin) afterItems(context *Context) {
+	if this.matched != nil && !this.stopped {
+		for i, right_item := range this.rightItems {
+			if !this.matched[i] && !this.sendItem(right_item) {
+				break
+			}
+		}
+	}
+	context.ReleaseValueSize(this.size)
+	this.size = 0
+	this.rightItems = nil
+	this.matched = nil
 	this.plan.Onclause().ResetMemory(context)
 }
 
@@ -167,7 +167,9 @@ func (this *Merge) processKeyMatch(item value.AnnotatedValue,
 
 	ok = true
 	bvs := make(map[string]value.AnnotatedValue, 1)
-	errs := this.plan.Keyspace().Fetch([]string{k}, bvs, context, nil)
+	span := this.startDatastoreSpan("Fetch", "keyspace", this.plan.Keyspace().Name())
+	errs := context.keyspace(this.plan.Keyspace()).Fetch([]string{k}, bvs, context, nil)
+	span.End()
 
 	this.switchPhase(_EXECTIME)
 
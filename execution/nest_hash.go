@@ -25,6 +25,7 @@ type HashNest struct {
 	aliasMap  map[string]string
 	ansiFlags uint32
 	hashTab   *util.HashTable
+	memory    *joinSpill
 	buildVals value.Values
 	probeVals value.Values
 }
@@ -84,6 +85,7 @@ func (this *HashNest) beforeItems(context *Context, parent value.Value) bool {
 
 	// build hash table
 	this.hashTab = util.NewHashTable(util.HASH_TABLE_FOR_HASH_JOIN)
+	this.memory = newJoinSpill(context, false)
 
 	this.buildVals = make(value.Values, len(this.plan.BuildExprs()))
 	this.probeVals = make(value.Values, len(this.plan.ProbeExprs()))
@@ -96,7 +98,7 @@ func (this *HashNest) beforeItems(context *Context, parent value.Value) bool {
 	this.fork(this.child, context, parent)
 
 	return buildHashTab(&(this.base), this.child, this.hashTab,
-		this.plan.BuildExprs(), this.buildVals, context)
+		this.plan.BuildExprs(), this.buildVals, this.memory, context)
 }
 
 func (this *HashNest) processItem(item value.AnnotatedValue, context *Context) bool {
@@ -151,6 +153,8 @@ func (this *HashNest) processItem(item value.AnnotatedValue, context *Context) b
 
 func (this *HashNest) afterItems(context *Context) {
 	this.dropHashTable()
+	this.memory.release(context)
+	this.memory = nil
 	this.plan.Onclause().ResetMemory(context)
 }
 
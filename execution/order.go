@@ -10,8 +10,10 @@
 package execution
 
 import (
+	"container/heap"
 	"encoding/json"
 
+	"github.com/couchbase/query/errors"
 	"github.com/couchbase/query/plan"
 	"github.com/couchbase/query/sort"
 	"github.com/couchbase/query/value"
@@ -23,6 +25,8 @@ type Order struct {
 	values  value.AnnotatedValues
 	context *Context
 	terms   []string
+	runs    []*spillFile
+	size    int64 // memory charged for the values
 }
 
 const _ORDER_CAP = 1024
@@ -68,9 +72,84 @@ func (this *Order) processItem(item value.AnnotatedValue, context *Context) bool
 	}
 
 	this.values = append(this.values, item)
+
+	if context.trackBuffers() {
+		size := int64(item.Size())
+		this.size += size
+		ok, spill := context.chargeBuffer(size, this.size)
+		if !ok {
+			return false
+		} else if spill {
+			return this.spillRun(context)
+		}
+	}
+	return true
+}
+
+// Sort the values held and write them out as a run of their own
+func (this *Order) spillRun(context *Context) bool {
+	this.setupTerms(context)
+	sort.Sort(this)
+
+	run, err := newSpillFile("ORDER BY")
+	if err != nil {
+		context.Error(err)
+		return false
+	}
+	this.runs = append(this.runs, run)
+
+	for _, av := range this.values {
+
+		// the sort terms are kept with the values, for the merge
+		this.cacheTerms(av)
+		err = run.write("", av)
+		if err != nil {
+			context.Error(err)
+			return false
+		}
+	}
+
+	err = run.rewind()
+	if err != nil {
+		context.Error(err)
+		return false
+	}
+
+	this.addSpill(run.size)
+	context.releaseBuffer(this.size)
+	this.size = 0
+	this.values = this.values[0:0]
+
+	if len(this.runs) >= _SPILL_MAX_RUNS {
+		return this.compactRuns(context)
+	}
 	return true
 }
 
+func (this *Order) cacheTerms(item value.AnnotatedValue) {
+	for i, term := range this.plan.Terms() {
+		getOriginalCachedValue(item, term.Expression(), this.terms[i], this.context)
+	}
+}
+
+// The number of values sorted, held or spilled
+func (this *Order) sortCount() uint64 {
+	count := uint64(len(this.values))
+	for _, run := range this.runs {
+		count += uint64(run.count)
+	}
+	return count
+}
+
+func (this *Order) releaseRuns(context *Context) {
+	for _, run := range this.runs {
+		run.close()
+	}
+	this.runs = nil
+	context.releaseBuffer(this.size)
+	this.size = 0
+}
+
 func (this *Order) setupTerms(context *Context) {
 	this.context = context
 	this.terms = make([]string, len(this.plan.Terms()))
@@ -81,6 +160,7 @@ func (this *Order) setupTerms(context *Context) {
 
 func (this *Order) afterItems(context *Context) {
 	defer this.releaseValues()
+	defer this.releaseRuns(context)
 	defer func() {
 		this.context = nil
 		this.terms = nil
@@ -94,8 +174,14 @@ func (this *Order) afterItems(context *Context) {
 	this.setupTerms(context)
 	sort.Sort(this)
 
-	context.SetSortCount(uint64(this.Len()))
-	context.AddPhaseCount(SORT, uint64(this.Len()))
+	count := this.sortCount()
+	context.SetSortCount(count)
+	context.AddPhaseCount(SORT, count)
+
+	if len(this.runs) > 0 {
+		this.mergeRuns(this.values, this.sendItem, context)
+		return
+	}
 
 	for _, av := range this.values {
 		if !this.sendItem(av) {
@@ -104,6 +190,136 @@ func (this *Order) afterItems(context *Context) {
 	}
 }
 
+// Merge the spilled runs and the sorted values held, in order, into send
+func (this *Order) mergeRuns(values value.AnnotatedValues, send func(value.AnnotatedValue) bool,
+	context *Context) bool {
+
+	merge := &orderMerge{order: this, runs: make([]*orderRun, 0, len(this.runs)+1)}
+	for _, run := range this.runs {
+		merge.runs = append(merge.runs, &orderRun{run: run})
+	}
+	merge.runs = append(merge.runs, &orderRun{})
+
+	runs := merge.runs
+	merge.runs = merge.runs[0:0]
+	for _, run := range runs {
+		ok, err := run.advance(values)
+		if err != nil {
+			context.Error(err)
+			return false
+		}
+		if ok {
+			merge.runs = append(merge.runs, run)
+		}
+	}
+	heap.Init(merge)
+
+	for len(merge.runs) > 0 {
+		run := merge.runs[0]
+		if !send(run.item) {
+			return false
+		}
+
+		ok, err := run.advance(values)
+		if err != nil {
+			context.Error(err)
+			return false
+		}
+		if ok {
+			heap.Fix(merge, 0)
+		} else {
+			heap.Pop(merge)
+		}
+	}
+	return true
+}
+
+// Merge the spilled runs into a single one, to bound the files open at once
+func (this *Order) compactRuns(context *Context) bool {
+	run, err := newSpillFile("ORDER BY")
+	if err != nil {
+		context.Error(err)
+		return false
+	}
+
+	ok := this.mergeRuns(nil, func(av value.AnnotatedValue) bool {
+		err = run.write("", av)
+		if err != nil {
+			context.Error(err)
+			return false
+		}
+		return true
+	}, context)
+	if ok {
+		err = run.rewind()
+		if err != nil {
+			context.Error(err)
+			ok = false
+		} else {
+			this.addSpill(run.size)
+		}
+	}
+
+	for _, r := range this.runs {
+		r.close()
+	}
+	this.runs = append(this.runs[0:0], run)
+	return ok
+}
+
+// A sorted run being merged: a spilled one, or the values held
+type orderRun struct {
+	run  *spillFile
+	item value.AnnotatedValue
+	next int
+}
+
+func (this *orderRun) advance(values value.AnnotatedValues) (bool, errors.Error) {
+	if this.run == nil {
+		if this.next >= len(values) {
+			return false, nil
+		}
+		this.item = values[this.next]
+		this.next++
+		return true, nil
+	}
+
+	_, item, err := this.run.read()
+	if err != nil || item == nil {
+		return false, err
+	}
+	this.item = item
+	return true, nil
+}
+
+type orderMerge struct {
+	order *Order
+	runs  []*orderRun
+}
+
+func (this *orderMerge) Len() int {
+	return len(this.runs)
+}
+
+func (this *orderMerge) Less(i, j int) bool {
+	return this.order.lessThan(this.runs[i].item, this.runs[j].item)
+}
+
+func (this *orderMerge) Swap(i, j int) {
+	this.runs[i], this.runs[j] = this.runs[j], this.runs[i]
+}
+
+func (this *orderMerge) Push(item interface{}) {
+	this.runs = append(this.runs, item.(*orderRun))
+}
+
+func (this *orderMerge) Pop() interface{} {
+	index := len(this.runs) - 1
+	item := this.runs[index]
+	this.runs = this.runs[0:index]
+	return item
+}
+
 func (this *Order) releaseValues() {
 	_ORDER_POOL.Put(this.values)
 	this.values = nil
@@ -174,13 +174,14 @@ func (this *OrderLimit) afterItems(context *Context) {
 
 	// Deal with the case no data item is needed at all:
 	// when offset is too large.
-	len := len(this.values)
+	len := this.sortCount()
 	offset := int64(0)
 	if this.offset != nil {
 		offset = this.offset.offset
 	}
 	if offset >= int64(len) {
 		this.values = this.values[0:0]
+		this.releaseRuns(context)
 	}
 
 	this.Order.afterItems(context)
@@ -55,7 +55,9 @@ func (this *CountScan) RunOnce(context *Context, parent value.Value) {
 		}
 
 		this.switchPhase(_SERVTIME)
-		count, e := this.plan.Keyspace().Count(context)
+		span := this.startDatastoreSpan("Count", "keyspace", this.plan.Keyspace().Name())
+		count, e := context.keyspace(this.plan.Keyspace()).Count(context)
+		span.End()
 		this.switchPhase(_EXECTIME)
 
 		if e != nil {
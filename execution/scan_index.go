@@ -174,6 +174,7 @@ func (this *spanScan) RunOnce(context *Context, parent value.Value) {
 		defer this.conn.Dispose()  // Dispose of the connection
 		defer this.conn.SendStop() // Notify index that I have stopped
 
+		this.setTransactionScan(this.plan.Term(), context)
 		go this.scan(context, this.conn, parent)
 
 		ok := true
@@ -256,8 +257,10 @@ func (this *spanScan) scan(context *Context, conn *datastore.IndexConnection, pa
 
 	keyspaceTerm := this.plan.Term()
 	scanVector := context.ScanVectorSource().ScanVector(keyspaceTerm.Namespace(), keyspaceTerm.Keyspace())
+	span := this.startDatastoreSpan("Scan", "index", this.plan.Index().Name())
 	this.plan.Index().Scan(context.RequestId(), dspan, this.plan.Distinct(), limit,
 		context.ScanConsistency(), scanVector, conn)
+	span.End()
 }
 
 func evalSpan(ps *plan.Span, parent value.Value, context *Context) (*datastore.Span, bool, error) {
@@ -67,6 +67,7 @@ func (this *IndexScan2) RunOnce(context *Context, parent value.Value) {
 		defer this.conn.Dispose()  // Dispose of the connection
 		defer this.conn.SendStop() // Notify index that I have stopped
 
+		this.setTransactionScan(this.plan.Term(), context)
 		go this.scan(context, this.conn, parent)
 
 		ok := true
@@ -173,9 +174,11 @@ func (this *IndexScan2) scan(context *Context, conn *datastore.IndexConnection,
 		indexProjection = &datastore.IndexProjection{EntryKeys: proj.EntryKeys, PrimaryKey: proj.PrimaryKey}
 	}
 
+	span := this.startDatastoreSpan("Scan2", "index", plan.Index().Name())
 	plan.Index().Scan2(context.RequestId(), dspans, plan.Reverse(), plan.Distinct(), plan.Ordered(),
 		indexProjection, offset, limit,
 		context.ScanConsistency(), scanVector, conn)
+	span.End()
 }
 
 func evalSpan2(pspans plan.Spans2, parent value.Value, context *Context) (datastore.Spans2, bool, error) {
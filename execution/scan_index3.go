@@ -65,6 +65,7 @@ func (this *IndexScan3) RunOnce(context *Context, parent value.Value) {
 		defer this.conn.Dispose()  // Dispose of the connection
 		defer this.conn.SendStop() // Notify index that I have stopped
 
+		this.setTransactionScan(this.plan.Term(), context)
 		go this.scan(context, this.conn, parent)
 
 		ok := true
@@ -173,9 +174,11 @@ func (this *IndexScan3) scan(context *Context, conn *datastore.IndexConnection,
 	indexProjection, indexOrder, indexGroupAggs := planToScanMapping(plan.Index(), plan.Projection(),
 		plan.OrderTerms(), plan.GroupAggs(), plan.Covers())
 
+	span := this.startDatastoreSpan("Scan3", "index", plan.Index().Name())
 	plan.Index().Scan3(context.RequestId(), dspans, plan.Reverse(), plan.Distinct(),
 		indexProjection, offset, limit, indexGroupAggs, indexOrder,
 		context.ScanConsistency(), scanVector, conn)
+	span.End()
 }
 
 func evalSpan3(pspans plan.Spans2, parent value.Value, hasDynamicInSpan bool, context *Context) (
@@ -105,7 +105,9 @@ func (this *IndexCountScan) scanCount(span *plan.Span, scanVector timestamp.Vect
 
 	var count int64
 	if err == nil && !empty {
+		span := this.startDatastoreSpan("Count", "index", this.plan.Index().Name())
 		count, err = this.plan.Index().Count(dspan, context.ScanConsistency(), scanVector)
+		span.End()
 	}
 
 	if err != nil {
@@ -61,7 +61,9 @@ func (this *IndexCountScan2) RunOnce(context *Context, parent value.Value) {
 		scanVector := context.ScanVectorSource().ScanVector(keyspaceTerm.Namespace(), keyspaceTerm.Keyspace())
 		dspans, empty, err := evalSpan2(this.plan.Spans(), nil, context)
 		if err == nil && !empty {
+			span := this.startDatastoreSpan("Count2", "index", this.plan.Index().Name())
 			count, err = this.plan.Index().Count2(context.RequestId(), dspans, context.ScanConsistency(), scanVector)
+			span.End()
 		}
 
 		if err != nil {
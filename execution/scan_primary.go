@@ -78,6 +78,7 @@ func (this *PrimaryScan) scanPrimary(context *Context, parent value.Value) {
 
 	limit := evalLimitOffset(this.plan.Limit(), parent, math.MaxInt64, false, context)
 
+	this.setTransactionScan(this.plan.Term(), context)
 	go this.scanEntries(context, this.conn, limit)
 
 	nitems := uint64(0)
@@ -183,7 +184,9 @@ func (this *PrimaryScan) scanEntries(context *Context, conn *datastore.IndexConn
 	scanVector := context.ScanVectorSource().ScanVector(keyspace.NamespaceId(), keyspace.Name())
 
 	index := this.plan.Index()
+	span := this.startDatastoreSpan("ScanEntries", "index", index.Name())
 	index.ScanEntries(context.RequestId(), limit, context.ScanConsistency(), scanVector, conn)
+	span.End()
 }
 
 func (this *PrimaryScan) scanChunk(context *Context, conn *datastore.IndexConnection, limit int64, indexEntry *datastore.IndexEntry) {
@@ -196,8 +199,10 @@ func (this *PrimaryScan) scanChunk(context *Context, conn *datastore.IndexConnec
 	}
 	keyspace := this.plan.Keyspace()
 	scanVector := context.ScanVectorSource().ScanVector(keyspace.NamespaceId(), keyspace.Name())
+	span := this.startDatastoreSpan("Scan", "index", this.plan.Index().Name())
 	this.plan.Index().Scan(context.RequestId(), ds, true, limit,
 		context.ScanConsistency(), scanVector, conn)
+	span.End()
 }
 
 func (this *PrimaryScan) MarshalJSON() ([]byte, error) {
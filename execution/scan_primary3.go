@@ -72,6 +72,7 @@ func (this *PrimaryScan3) scanPrimary(context *Context, parent value.Value) {
 	offset := evalLimitOffset(this.plan.Offset(), parent, int64(0), false, context)
 	limit := evalLimitOffset(this.plan.Limit(), parent, math.MaxInt64, false, context)
 
+	this.setTransactionScan(this.plan.Term(), context)
 	go this.scanEntries(context, this.conn, offset, limit)
 
 	nitems := uint64(0)
@@ -180,8 +181,10 @@ func (this *PrimaryScan3) scanEntries(context *Context, conn *datastore.IndexCon
 	indexProjection, indexOrder, indexGroupAggs := planToScanMapping(index, this.plan.Projection(),
 		this.plan.OrderTerms(), this.plan.GroupAggs(), nil)
 
+	span := this.startDatastoreSpan("ScanEntries3", "index", index.Name())
 	index.ScanEntries3(context.RequestId(), indexProjection, offset, limit, indexGroupAggs, indexOrder,
 		context.ScanConsistency(), scanVector, conn)
+	span.End()
 }
 
 func (this *PrimaryScan3) scanChunk(context *Context, conn *datastore.IndexConnection, limit int64, indexEntry *datastore.IndexEntry) {
@@ -194,8 +197,10 @@ func (this *PrimaryScan3) scanChunk(context *Context, conn *datastore.IndexConne
 	}
 	keyspace := this.plan.Keyspace()
 	scanVector := context.ScanVectorSource().ScanVector(keyspace.NamespaceId(), keyspace.Name())
+	span := this.startDatastoreSpan("Scan", "index", this.plan.Index().Name())
 	this.plan.Index().Scan(context.RequestId(), ds, true, limit,
 		context.ScanConsistency(), scanVector, conn)
+	span.End()
 }
 
 func (this *PrimaryScan3) MarshalJSON() ([]byte, error) {
@@ -75,6 +75,15 @@ func (this *ValueScan) RunOnce(context *Context, parent value.Value) {
 			av.SetAttachment("key", key)
 			av.SetAttachment("value", val)
 
+			if pair.Options != nil {
+				options, err := pair.Options.Evaluate(parent, context)
+				if err != nil {
+					context.Error(errors.NewEvaluationError(err, "VALUES"))
+					return
+				}
+				av.SetAttachment("options", options)
+			}
+
 			if !this.sendItem(av) {
 				return
 			}
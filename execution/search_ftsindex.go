@@ -148,7 +148,9 @@ func (this *IndexFtsSearch) search(context *Context, conn *datastore.IndexConnec
 		return
 	}
 
+	span := this.startDatastoreSpan("Search", "index", index.Name())
 	index.Search(context.RequestId(), indexSearchInfo, context.ScanConsistency(), scanVector, conn)
+	span.End()
 }
 
 func (this *IndexFtsSearch) planToSearchMapping(context *Context,
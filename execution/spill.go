@@ -0,0 +1,580 @@
+//  Copyright (c) 2019 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package execution
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+	go_atomic "sync/atomic"
+
+	atomic "github.com/couchbase/go-couchbase/platform"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/value"
+)
+
+/*
+The operators that hold their whole input before producing any output
+(Order, the group operators and HashJoin) write it out to temporary
+files once the items buffered by the request go over the spill
+threshold, and then process it from there: Order merges sorted runs,
+while the group operators and HashJoin work one hash partition at a
+time.
+*/
+
+// number of hash partitions of spilled groups and hash join sides
+const _SPILL_PARTITIONS = 16
+
+// an operator only spills when it holds at least this fraction of the
+// threshold, so that small operators do not spill on behalf of large ones
+const _SPILL_SHARE = 16
+
+// the most sorted runs kept before they are merged into one
+const _SPILL_MAX_RUNS = 64
+
+var spillThreshold atomic.AlignedInt64
+var tmpSpaceDir go_atomic.Value
+
+// zero or negative disables spilling
+func SetSpillThreshold(threshold int64) {
+	if threshold < 0 {
+		threshold = 0
+	}
+	atomic.StoreInt64(&spillThreshold, threshold)
+}
+
+func GetSpillThreshold() int64 {
+	return atomic.LoadInt64(&spillThreshold)
+}
+
+// empty means the system temporary directory
+func SetTmpSpaceDir(dir string) {
+	tmpSpaceDir.Store(dir)
+}
+
+func GetTmpSpaceDir() string {
+	dir, _ := tmpSpaceDir.Load().(string)
+	if dir == "" {
+		return os.TempDir()
+	}
+	return dir
+}
+
+// Memory, in bytes, buffered by the operators of a request that can spill
+type spillBudget struct {
+	used      int64
+	threshold int64
+}
+
+func newSpillBudget() *spillBudget {
+	threshold := GetSpillThreshold()
+	if threshold <= 0 {
+		return nil
+	}
+	return &spillBudget{threshold: threshold}
+}
+
+// The partition of a hash key or of a group key
+func spillPartition(key []byte) int {
+	h := fnv.New32a()
+	h.Write(key)
+	return int(h.Sum32() % _SPILL_PARTITIONS)
+}
+
+// record a spill in the operator's profile
+func (this *base) addSpill(size int64) {
+	this.spills++
+	this.spillSize += size
+}
+
+/*
+A temporary file of spilled items. Each item is written as its length
+followed by its JSON encoding, keeping its attachments and covers.
+Values that cannot be written out, such as the parent scopes of the
+items, stay in memory and are referenced by position, once each.
+*/
+type spillFile struct {
+	op     string
+	file   *os.File
+	writer *bufio.Writer
+	reader *bufio.Reader
+	refs   []interface{}
+	refIds map[uintptr]int
+	buf    []byte
+	count  int64
+	size   int64
+}
+
+type spillRecord struct {
+	Key  string      `json:"k,omitempty"`
+	Item *spillValue `json:"v"`
+}
+
+type spillValue struct {
+	Kind    string                 `json:"k,omitempty"`
+	Val     json.RawMessage        `json:"v,omitempty"`
+	Items   []*spillValue          `json:"i,omitempty"`
+	Fields  map[string]*spillValue `json:"f,omitempty"`
+	Orig    *spillValue            `json:"o,omitempty"`
+	Attach  map[string]*spillValue `json:"a,omitempty"`
+	Covers  map[string]*spillValue `json:"c,omitempty"`
+	Id      *spillValue            `json:"d,omitempty"`
+	Bit     uint8                  `json:"b,omitempty"`
+	Self    bool                   `json:"s,omitempty"`
+	Numeric bool                   `json:"n,omitempty"`
+	Cap     int                    `json:"p,omitempty"`
+	Ref     int                    `json:"r,omitempty"`
+}
+
+// op names the operation spilling, for errors
+func newSpillFile(op string) (*spillFile, errors.Error) {
+	file, err := ioutil.TempFile(GetTmpSpaceDir(), "query-spill-")
+	if err != nil {
+		return nil, errors.NewSpillError(err, op)
+	}
+	return &spillFile{
+		op:     op,
+		file:   file,
+		writer: bufio.NewWriter(file),
+	}, nil
+}
+
+func (this *spillFile) write(key string, item value.AnnotatedValue) errors.Error {
+	var lenBuf [binary.MaxVarintLen64]byte
+
+	bytes, err := json.Marshal(&spillRecord{Key: key, Item: this.encode(item)})
+	if err == nil {
+		n := binary.PutUvarint(lenBuf[:], uint64(len(bytes)))
+		_, err = this.writer.Write(lenBuf[:n])
+		if err == nil {
+			_, err = this.writer.Write(bytes)
+		}
+		this.size += int64(n + len(bytes))
+	}
+	if err != nil {
+		return errors.NewSpillError(err, this.op)
+	}
+	this.count++
+	return nil
+}
+
+// Switch from writing items to reading them from the start
+func (this *spillFile) rewind() errors.Error {
+	err := this.writer.Flush()
+	if err == nil {
+		_, err = this.file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		return errors.NewSpillError(err, this.op)
+	}
+	this.reader = bufio.NewReader(this.file)
+	return nil
+}
+
+// The next item and its key, or a nil item at the end of the file
+func (this *spillFile) read() (string, value.AnnotatedValue, errors.Error) {
+	l, err := binary.ReadUvarint(this.reader)
+	if err == io.EOF {
+		return "", nil, nil
+	}
+	if err == nil {
+		if uint64(cap(this.buf)) < l {
+			this.buf = make([]byte, l)
+		}
+		_, err = io.ReadFull(this.reader, this.buf[:l])
+	}
+
+	var rec spillRecord
+	if err == nil {
+		err = json.Unmarshal(this.buf[:l], &rec)
+	}
+
+	var item interface{}
+	if err == nil {
+		item, err = this.decode(rec.Item)
+	}
+	if err != nil {
+		return "", nil, errors.NewSpillError(err, this.op)
+	}
+
+	av, ok := item.(value.AnnotatedValue)
+	if !ok {
+		return "", nil, errors.NewSpillError(fmt.Errorf("Invalid spilled item of type %T", item), this.op)
+	}
+	return rec.Key, av, nil
+}
+
+// Close and remove the file
+func (this *spillFile) close() {
+	if this.file != nil {
+		name := this.file.Name()
+		this.file.Close()
+		os.Remove(name)
+		this.file = nil
+	}
+	this.writer = nil
+	this.reader = nil
+	this.refs = nil
+	this.refIds = nil
+}
+
+func (this *spillFile) ref(v interface{}) *spillValue {
+	var id uintptr
+
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.Ptr, reflect.Map:
+		id = rv.Pointer()
+		if ref, ok := this.refIds[id]; ok {
+			return &spillValue{Kind: "ref", Ref: ref}
+		}
+	}
+
+	this.refs = append(this.refs, v)
+	if id != 0 {
+		if this.refIds == nil {
+			this.refIds = make(map[uintptr]int)
+		}
+		this.refIds[id] = len(this.refs)
+	}
+	return &spillValue{Kind: "ref", Ref: len(this.refs)}
+}
+
+func (this *spillFile) encode(v interface{}) *spillValue {
+	switch v := v.(type) {
+	case nil:
+		return &spillValue{Kind: "nil"}
+	case *value.ScopeValue:
+		rv := &spillValue{Kind: "scope", Fields: this.encodeFields(v.GetValue().Fields())}
+		if v.Parent() != nil {
+			rv.Ref = this.ref(v.Parent()).Ref
+		}
+		return rv
+	case value.AnnotatedValue:
+		return this.encodeAnnotated(v)
+	case value.Value:
+		return this.encodeValue(v)
+	case *value.Set:
+		if !v.Collect() {
+			break
+		}
+		vals := v.Values()
+		rv := &spillValue{Kind: "set", Items: make([]*spillValue, len(vals)),
+			Numeric: v.Numeric(), Cap: v.ObjectCap()}
+		for i, val := range vals {
+			if val == nil {
+				rv.Items[i] = &spillValue{Kind: "nil"}
+			} else {
+				rv.Items[i] = this.encode(val)
+			}
+		}
+		return rv
+	case *value.List:
+		vals := v.Values()
+		rv := &spillValue{Kind: "list", Items: make([]*spillValue, len(vals))}
+		for i, val := range vals {
+			rv.Items[i] = this.encode(val)
+		}
+		return rv
+	case map[string]value.Value:
+		rv := &spillValue{Kind: "values", Fields: make(map[string]*spillValue, len(v))}
+		for n, val := range v {
+			rv.Fields[n] = this.encode(val)
+		}
+		return rv
+	case map[string]interface{}:
+		return &spillValue{Kind: "map", Fields: this.encodeFields(v)}
+	case string, bool, int, int64, uint32, uint64, float64:
+		bytes, err := json.Marshal(v)
+		if err == nil {
+			return &spillValue{Kind: fmt.Sprintf("%T", v), Val: bytes}
+		}
+	}
+	return this.ref(v)
+}
+
+func (this *spillFile) encodeFields(fields map[string]interface{}) map[string]*spillValue {
+	rv := make(map[string]*spillValue, len(fields))
+	for n, f := range fields {
+		rv[n] = this.encode(f)
+	}
+	return rv
+}
+
+// Objects and arrays holding annotated values, such as documents
+// with their META() data, are written out element by element
+func (this *spillFile) encodeValue(v value.Value) *spillValue {
+	switch v.Type() {
+	case value.MISSING:
+		return &spillValue{Kind: "missing"}
+	case value.OBJECT:
+		fields := v.Fields()
+		for _, f := range fields {
+			if isAnnotated(f) {
+				return &spillValue{Kind: "object", Fields: this.encodeFields(fields)}
+			}
+		}
+	case value.ARRAY:
+		elems, _ := v.Actual().([]interface{})
+		for _, e := range elems {
+			if isAnnotated(e) {
+				rv := &spillValue{Kind: "array", Items: make([]*spillValue, len(elems))}
+				for i, e := range elems {
+					rv.Items[i] = this.encode(e)
+				}
+				return rv
+			}
+		}
+	}
+
+	bytes, err := v.MarshalJSON()
+	if err != nil {
+		return this.ref(v)
+	}
+	return &spillValue{Val: bytes}
+}
+
+func isAnnotated(v interface{}) bool {
+	switch v.(type) {
+	case value.AnnotatedValue, *value.ScopeValue:
+		return true
+	}
+	return false
+}
+
+func (this *spillFile) encodeAnnotated(av value.AnnotatedValue) *spillValue {
+	rv := &spillValue{
+		Kind:  "annotated",
+		Items: []*spillValue{this.encode(av.GetValue())},
+		Bit:   av.Bit(),
+		Self:  av.Self(),
+	}
+
+	if orig := av.Original(); orig != av {
+		rv.Orig = this.encode(orig.GetValue())
+	}
+
+	if attachments := av.Attachments(); len(attachments) > 0 {
+		rv.Attach = make(map[string]*spillValue, len(attachments))
+		for n, a := range attachments {
+			rv.Attach[n] = this.encode(a)
+		}
+	}
+
+	if covers := av.Covers(); covers != nil {
+		fields := covers.Fields()
+		rv.Covers = make(map[string]*spillValue, len(fields))
+		for n, c := range fields {
+			rv.Covers[n] = this.encode(c)
+		}
+	}
+
+	if id := av.GetId(); id != nil {
+		rv.Id = this.encode(id)
+	}
+	return rv
+}
+
+func (this *spillFile) decode(sv *spillValue) (interface{}, error) {
+	if sv == nil {
+		return nil, fmt.Errorf("Missing spilled value")
+	}
+
+	switch sv.Kind {
+	case "":
+		return value.NewValue([]byte(sv.Val)), nil
+	case "nil":
+		return nil, nil
+	case "missing":
+		return value.MISSING_VALUE, nil
+	case "ref":
+		return this.deref(sv.Ref)
+	case "object":
+		fields, err := this.decodeFields(sv.Fields)
+		if err != nil {
+			return nil, err
+		}
+		return value.NewValue(fields), nil
+	case "array", "list", "set":
+		elems := make([]interface{}, len(sv.Items))
+		for i, e := range sv.Items {
+			var err error
+			elems[i], err = this.decode(e)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		switch sv.Kind {
+		case "list":
+			list := value.NewList(len(elems))
+			for _, e := range elems {
+				val, _ := e.(value.Value)
+				list.Add(val)
+			}
+			return list, nil
+		case "set":
+			set := value.NewSet(sv.Cap, true, sv.Numeric)
+			for _, e := range elems {
+				val, _ := e.(value.Value)
+				set.Add(val)
+			}
+			return set, nil
+		}
+		return value.NewValue(elems), nil
+	case "scope":
+		fields, err := this.decodeFields(sv.Fields)
+		if err != nil {
+			return nil, err
+		}
+		var parent value.Value
+		if sv.Ref > 0 {
+			p, err := this.deref(sv.Ref)
+			if err != nil {
+				return nil, err
+			}
+			parent, _ = p.(value.Value)
+		}
+		return value.NewScopeValue(fields, parent), nil
+	case "annotated":
+		return this.decodeAnnotated(sv)
+	case "values":
+		rv := make(map[string]value.Value, len(sv.Fields))
+		for n, f := range sv.Fields {
+			v, err := this.decode(f)
+			if err != nil {
+				return nil, err
+			}
+			rv[n], _ = v.(value.Value)
+		}
+		return rv, nil
+	case "map":
+		return this.decodeFields(sv.Fields)
+	case "string":
+		var rv string
+		err := json.Unmarshal(sv.Val, &rv)
+		return rv, err
+	case "bool":
+		var rv bool
+		err := json.Unmarshal(sv.Val, &rv)
+		return rv, err
+	case "int":
+		var rv int
+		err := json.Unmarshal(sv.Val, &rv)
+		return rv, err
+	case "int64":
+		var rv int64
+		err := json.Unmarshal(sv.Val, &rv)
+		return rv, err
+	case "uint32":
+		var rv uint32
+		err := json.Unmarshal(sv.Val, &rv)
+		return rv, err
+	case "uint64":
+		var rv uint64
+		err := json.Unmarshal(sv.Val, &rv)
+		return rv, err
+	case "float64":
+		var rv float64
+		err := json.Unmarshal(sv.Val, &rv)
+		return rv, err
+	}
+	return nil, fmt.Errorf("Invalid spilled value of kind %v", sv.Kind)
+}
+
+func (this *spillFile) deref(ref int) (interface{}, error) {
+	if ref <= 0 || ref > len(this.refs) {
+		return nil, fmt.Errorf("Invalid spilled reference %v", ref)
+	}
+	return this.refs[ref-1], nil
+}
+
+func (this *spillFile) decodeFields(fields map[string]*spillValue) (map[string]interface{}, error) {
+	rv := make(map[string]interface{}, len(fields))
+	for n, f := range fields {
+		v, err := this.decode(f)
+		if err != nil {
+			return nil, err
+		}
+		rv[n] = v
+	}
+	return rv, nil
+}
+
+func (this *spillFile) decodeAnnotated(sv *spillValue) (value.AnnotatedValue, error) {
+	if len(sv.Items) != 1 {
+		return nil, fmt.Errorf("Invalid spilled annotated value")
+	}
+
+	val, err := this.decodeValue(sv.Items[0])
+	if err != nil {
+		return nil, err
+	}
+
+	var av value.AnnotatedValue
+	if sv.Orig != nil {
+		orig, err := this.decodeValue(sv.Orig)
+		if err != nil {
+			return nil, err
+		}
+		av = value.NewAnnotatedValue(orig)
+		av.SetProjection(val)
+	} else {
+		av = value.NewAnnotatedValue(val)
+	}
+
+	for n, a := range sv.Attach {
+		attachment, err := this.decode(a)
+		if err != nil {
+			return nil, err
+		}
+		av.SetAttachment(n, attachment)
+	}
+
+	for n, c := range sv.Covers {
+		cover, err := this.decodeValue(c)
+		if err != nil {
+			return nil, err
+		}
+		av.SetCover(n, cover)
+	}
+
+	if sv.Id != nil {
+		id, err := this.decode(sv.Id)
+		if err != nil {
+			return nil, err
+		}
+		av.SetId(id)
+	}
+
+	av.SetBit(sv.Bit)
+	av.SetSelf(sv.Self)
+	return av, nil
+}
+
+func (this *spillFile) decodeValue(sv *spillValue) (value.Value, error) {
+	v, err := this.decode(sv)
+	if err != nil {
+		return nil, err
+	}
+	switch v := v.(type) {
+	case value.Value:
+		return v, nil
+	case nil:
+		return nil, fmt.Errorf("Invalid spilled nil value")
+	default:
+		return value.NewValue(v), nil
+	}
+}
@@ -0,0 +1,104 @@
+package execution
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/couchbase/query/value"
+)
+
+func TestSpillFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spill")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	defer os.RemoveAll(dir)
+	SetTmpSpaceDir(dir)
+	defer SetTmpSpaceDir("")
+
+	parent := value.NewValue(map[string]interface{}{"outer": 1})
+
+	// a joined document with its META() and a projection
+	doc := value.NewAnnotatedValue(map[string]interface{}{"name": "dave", "age": 40})
+	doc.SetAttachment("meta", map[string]interface{}{"id": "k1", "cas": uint64(1 << 60), "flags": uint32(2)})
+	doc.SetId("k1")
+	scope := value.NewScopeValue(map[string]interface{}{"p": doc}, parent)
+	item := value.NewAnnotatedValue(scope)
+	item.SetProjection(value.NewValue(map[string]interface{}{"name": "dave"}))
+	item.SetCover("cover((`p`.`age`))", value.NewValue(40))
+	item.SetAttachment("_sort", value.MISSING_VALUE)
+
+	// a group with DISTINCT and ARRAY_AGG partial aggregates
+	set := value.NewSet(16, true, false)
+	set.Add(value.NewValue("a"))
+	set.Add(value.NULL_VALUE)
+	distinct := value.NewAnnotatedValue(nil)
+	distinct.SetAttachment("set", set)
+	list := value.NewList(2)
+	list.Add(value.NewValue(1))
+	list.Add(value.NewValue(2))
+	array := value.NewAnnotatedValue(nil)
+	array.SetAttachment("list", list)
+	group := value.NewAnnotatedValue(map[string]interface{}{"g": 1})
+	group.SetAttachment("aggregates", map[string]value.Value{"distinct": distinct, "array": array})
+
+	file, e := newSpillFile("test")
+	if e != nil {
+		t.Fatalf("unexpected error %v", e)
+	}
+	defer file.close()
+
+	for i, av := range []value.AnnotatedValue{item, group} {
+		if e = file.write([]string{"a", "b"}[i], av); e != nil {
+			t.Fatalf("unexpected error %v", e)
+		}
+	}
+	if e = file.rewind(); e != nil {
+		t.Fatalf("unexpected error %v", e)
+	}
+
+	key, av, e := file.read()
+	if e != nil || key != "a" || av == nil {
+		t.Fatalf("unexpected item %v, %v, %v", key, av, e)
+	}
+	if !av.Equals(item).Truth() || !av.Original().GetValue().Equals(scope).Truth() {
+		t.Errorf("expected %v, got %v", item, av)
+	}
+	if outer, ok := av.Original().Field("outer"); !ok || outer.Actual() != float64(1) {
+		t.Errorf("expected the parent scope, got %v", outer)
+	}
+	p, _ := av.Original().Field("p")
+	pv, ok := p.(value.AnnotatedValue)
+	if !ok || pv.GetId() != "k1" {
+		t.Fatalf("expected an annotated document, got %v", p)
+	}
+	meta, _ := pv.GetAttachment("meta").(map[string]interface{})
+	if meta["cas"] != uint64(1<<60) || meta["flags"] != uint32(2) {
+		t.Errorf("unexpected META() %v", meta)
+	}
+	if cover := av.GetCover("cover((`p`.`age`))"); cover == nil || cover.Actual() != float64(40) {
+		t.Errorf("unexpected cover %v", cover)
+	}
+	if sort, ok := av.GetAttachment("_sort").(value.Value); !ok || sort.Type() != value.MISSING {
+		t.Errorf("unexpected sort term %v", av.GetAttachment("_sort"))
+	}
+
+	key, av, e = file.read()
+	if e != nil || key != "b" || av == nil {
+		t.Fatalf("unexpected item %v, %v, %v", key, av, e)
+	}
+	aggregates, _ := av.GetAttachment("aggregates").(map[string]value.Value)
+	s, _ := aggregates["distinct"].(value.AnnotatedValue).GetAttachment("set").(*value.Set)
+	if s == nil || s.Len() != 2 || !s.Has(value.NewValue("a")) || !s.Has(value.NULL_VALUE) {
+		t.Errorf("unexpected set %v", s)
+	}
+	l, _ := aggregates["array"].(value.AnnotatedValue).GetAttachment("list").(*value.List)
+	if l == nil || l.Len() != 2 || l.ItemAt(1).Actual() != float64(2) {
+		t.Errorf("unexpected list %v", l)
+	}
+
+	if _, av, e = file.read(); av != nil || e != nil {
+		t.Errorf("expected the end of the file, got %v, %v", av, e)
+	}
+}
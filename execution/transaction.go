@@ -0,0 +1,236 @@
+//  Copyright (c) 2020 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package execution
+
+import (
+	"encoding/json"
+
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/plan"
+	"github.com/couchbase/query/transactions"
+	"github.com/couchbase/query/value"
+)
+
+// Start Transaction
+type StartTransaction struct {
+	base
+	plan *plan.StartTransaction
+}
+
+func NewStartTransaction(plan *plan.StartTransaction, context *Context) *StartTransaction {
+	rv := &StartTransaction{
+		plan: plan,
+	}
+
+	newBase(&rv.base, context)
+	rv.execPhase = TRANSACTION
+	rv.output = rv
+	return rv
+}
+
+func (this *StartTransaction) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitStartTransaction(this)
+}
+
+func (this *StartTransaction) Copy() Operator {
+	rv := &StartTransaction{plan: this.plan}
+	this.base.copy(&rv.base)
+	return rv
+}
+
+func (this *StartTransaction) RunOnce(context *Context, parent value.Value) {
+	this.runTransaction(context, func() {
+		if context.Transaction() != nil {
+			context.Error(errors.NewTransactionInProgressError(context.Transaction().Id()))
+			return
+		}
+		txn, err := transactions.Start(context.TxTimeout())
+		if err != nil {
+			context.Error(err)
+			return
+		}
+		this.sendItem(value.NewAnnotatedValue(map[string]interface{}{
+			"txid": txn.Id(),
+		}))
+	})
+}
+
+func (this *StartTransaction) MarshalJSON() ([]byte, error) {
+	r := this.plan.MarshalBase(func(r map[string]interface{}) {
+		this.marshalTimes(r)
+	})
+	return json.Marshal(r)
+}
+
+// Commit Transaction
+type CommitTransaction struct {
+	base
+	plan *plan.CommitTransaction
+}
+
+func NewCommitTransaction(plan *plan.CommitTransaction, context *Context) *CommitTransaction {
+	rv := &CommitTransaction{
+		plan: plan,
+	}
+
+	newBase(&rv.base, context)
+	rv.execPhase = TRANSACTION
+	rv.output = rv
+	return rv
+}
+
+func (this *CommitTransaction) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitCommitTransaction(this)
+}
+
+func (this *CommitTransaction) Copy() Operator {
+	rv := &CommitTransaction{plan: this.plan}
+	this.base.copy(&rv.base)
+	return rv
+}
+
+func (this *CommitTransaction) RunOnce(context *Context, parent value.Value) {
+	this.runTransaction(context, func() {
+		txn := context.Transaction()
+		if txn == nil {
+			context.Error(errors.NewNoTransactionError("COMMIT"))
+			return
+		}
+		if err := txn.Commit(); err != nil {
+			context.Error(err)
+		}
+	})
+}
+
+func (this *CommitTransaction) MarshalJSON() ([]byte, error) {
+	r := this.plan.MarshalBase(func(r map[string]interface{}) {
+		this.marshalTimes(r)
+	})
+	return json.Marshal(r)
+}
+
+// Rollback Transaction
+type RollbackTransaction struct {
+	base
+	plan *plan.RollbackTransaction
+}
+
+func NewRollbackTransaction(plan *plan.RollbackTransaction, context *Context) *RollbackTransaction {
+	rv := &RollbackTransaction{
+		plan: plan,
+	}
+
+	newBase(&rv.base, context)
+	rv.execPhase = TRANSACTION
+	rv.output = rv
+	return rv
+}
+
+func (this *RollbackTransaction) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitRollbackTransaction(this)
+}
+
+func (this *RollbackTransaction) Copy() Operator {
+	rv := &RollbackTransaction{plan: this.plan}
+	this.base.copy(&rv.base)
+	return rv
+}
+
+func (this *RollbackTransaction) RunOnce(context *Context, parent value.Value) {
+	this.runTransaction(context, func() {
+		txn := context.Transaction()
+		if txn == nil {
+			context.Error(errors.NewNoTransactionError("ROLLBACK"))
+			return
+		}
+
+		var err errors.Error
+		if this.plan.Savepoint() != "" {
+			err = txn.RollbackTo(this.plan.Savepoint())
+		} else {
+			err = txn.Rollback()
+		}
+		if err != nil {
+			context.Error(err)
+		}
+	})
+}
+
+func (this *RollbackTransaction) MarshalJSON() ([]byte, error) {
+	r := this.plan.MarshalBase(func(r map[string]interface{}) {
+		this.marshalTimes(r)
+	})
+	return json.Marshal(r)
+}
+
+// Savepoint
+type Savepoint struct {
+	base
+	plan *plan.Savepoint
+}
+
+func NewSavepoint(plan *plan.Savepoint, context *Context) *Savepoint {
+	rv := &Savepoint{
+		plan: plan,
+	}
+
+	newBase(&rv.base, context)
+	rv.execPhase = TRANSACTION
+	rv.output = rv
+	return rv
+}
+
+func (this *Savepoint) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitSavepoint(this)
+}
+
+func (this *Savepoint) Copy() Operator {
+	rv := &Savepoint{plan: this.plan}
+	this.base.copy(&rv.base)
+	return rv
+}
+
+func (this *Savepoint) RunOnce(context *Context, parent value.Value) {
+	this.runTransaction(context, func() {
+		txn := context.Transaction()
+		if txn == nil {
+			context.Error(errors.NewNoTransactionError("SAVEPOINT"))
+			return
+		}
+		if err := txn.SetSavepoint(this.plan.Name()); err != nil {
+			context.Error(err)
+		}
+	})
+}
+
+func (this *Savepoint) MarshalJSON() ([]byte, error) {
+	r := this.plan.MarshalBase(func(r map[string]interface{}) {
+		this.marshalTimes(r)
+	})
+	return json.Marshal(r)
+}
+
+// transaction statements share the same run sequence, and only differ
+// in what they do with the transaction
+func (this *base) runTransaction(context *Context, f func()) {
+	this.once.Do(func() {
+		defer context.Recover(this) // Recover from any panic
+		active := this.active()
+		defer this.close(context)
+		this.switchPhase(_EXECTIME)
+		defer func() { this.switchPhase(_NOTIME) }()
+		defer this.notify() // Notify that I have stopped
+		if !active {
+			return
+		}
+
+		f()
+	})
+}
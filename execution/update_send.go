@@ -16,6 +16,7 @@ import (
 
 	"github.com/couchbase/query/errors"
 	"github.com/couchbase/query/plan"
+	"github.com/couchbase/query/resultcache"
 	"github.com/couchbase/query/util"
 	"github.com/couchbase/query/value"
 )
@@ -151,6 +152,14 @@ func (this *SendUpdate) flushBatch(context *Context) bool {
 			cav := value.NewAnnotatedValue(cv)
 			cav.SetAnnotations(av)
 			pairs[i].Value = cav
+
+			pairs[i].Options = nil
+			if options, ok := clone.GetAttachment("options").(map[string]interface{}); ok {
+				pairs[i].Options, ok = dmlOptions("UPDATE", value.NewValue(options), context)
+				if !ok {
+					return false
+				}
+			}
 			item.SetField(this.plan.Alias(), cav)
 		default:
 			context.Error(errors.NewInvalidValueError(fmt.Sprintf(
@@ -161,12 +170,13 @@ func (this *SendUpdate) flushBatch(context *Context) bool {
 
 	this.switchPhase(_SERVTIME)
 
-	pairs, e := this.plan.Keyspace().Update(pairs)
+	pairs, e := context.keyspace(this.plan.Keyspace()).Update(pairs)
 
 	this.switchPhase(_EXECTIME)
 
 	// Update mutation count with number of updated docs
 	context.AddMutationCount(uint64(len(pairs)))
+	resultcache.Invalidate(this.plan.Keyspace())
 
 	if e != nil {
 		context.Error(e)
@@ -87,6 +87,10 @@ func (this *Set) processItem(item value.AnnotatedValue, context *Context) bool {
 
 func setPath(t *algebra.SetTerm, clone, item value.AnnotatedValue, context *Context) (
 	value.AnnotatedValue, error) {
+	if t.MetaField() != "" {
+		return setMeta(t, clone, item, context)
+	}
+
 	if t.UpdateFor() != nil {
 		return setFor(t, clone, item, context)
 	}
@@ -100,6 +104,24 @@ func setPath(t *algebra.SetTerm, clone, item value.AnnotatedValue, context *Cont
 	return clone, nil
 }
 
+// meta data fields are not part of the document, they are passed on
+// to the datastore as write options
+func setMeta(t *algebra.SetTerm, clone, item value.AnnotatedValue, context *Context) (
+	value.AnnotatedValue, error) {
+	v, err := t.Value().Evaluate(item, context)
+	if err != nil {
+		return nil, err
+	}
+
+	options, ok := clone.GetAttachment("options").(map[string]interface{})
+	if !ok {
+		options = make(map[string]interface{}, 1)
+		clone.SetAttachment("options", options)
+	}
+	options[t.MetaField()] = v
+	return clone, nil
+}
+
 func setFor(t *algebra.SetTerm, clone, item value.AnnotatedValue, context *Context) (
 	value.AnnotatedValue, error) {
 	ivals, mismatch, err := buildFor(t.UpdateFor(), item, context)
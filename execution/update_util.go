@@ -11,6 +11,8 @@ package execution
 
 import (
 	"github.com/couchbase/query/algebra"
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
 	"github.com/couchbase/query/expression"
 	"github.com/couchbase/query/util"
 	"github.com/couchbase/query/value"
@@ -201,3 +203,23 @@ var _IPAIRS_POOL = util.NewIPairsPool(64)
 const _NAME_CAP = 16
 
 var _NAME_POOL = util.NewStringPool(256)
+
+// dmlOptions checks the write options of a document: MISSING and NULL
+// mean none, anything else must be an object with a valid expiration
+func dmlOptions(op string, options value.Value, context *Context) (value.Value, bool) {
+	if options == nil || options.Type() <= value.NULL {
+		return nil, true
+	}
+
+	if options.Type() == value.OBJECT {
+		_, _, err := datastore.GetExpiration(options)
+		if err == nil {
+			return options, true
+		}
+		context.Error(err)
+		return nil, false
+	}
+
+	context.Error(errors.NewDMLOptionsError(op, options))
+	return nil, false
+}
@@ -15,6 +15,7 @@ import (
 
 	"github.com/couchbase/query/errors"
 	"github.com/couchbase/query/plan"
+	"github.com/couchbase/query/resultcache"
 	"github.com/couchbase/query/value"
 )
 
@@ -73,7 +74,8 @@ func (this *SendUpsert) flushBatch(context *Context) bool {
 
 	keyExpr := this.plan.Key()
 	valExpr := this.plan.Value()
-	var key, val value.Value
+	optsExpr := this.plan.Options()
+	var key, val, options value.Value
 	var err error
 	var ok bool
 	i := 0
@@ -101,6 +103,17 @@ func (this *SendUpsert) flushBatch(context *Context) bool {
 			} else {
 				val = av
 			}
+
+			if optsExpr != nil {
+				options, err = optsExpr.Evaluate(av, context)
+				if err != nil {
+					context.Error(errors.NewEvaluationError(err,
+						fmt.Sprintf("UPSERT options for %v", av.GetValue())))
+					continue
+				}
+			} else {
+				options = nil
+			}
 		} else {
 			// UPSERT ... VALUES
 			key, ok = av.GetAttachment("key").(value.Value)
@@ -114,6 +127,8 @@ func (this *SendUpsert) flushBatch(context *Context) bool {
 				context.Error(errors.NewUpsertValueError(av.GetValue()))
 				continue
 			}
+
+			options, _ = av.GetAttachment("options").(value.Value)
 		}
 
 		dpair.Name, ok = key.Actual().(string)
@@ -122,6 +137,11 @@ func (this *SendUpsert) flushBatch(context *Context) bool {
 			continue
 		}
 
+		dpair.Options, ok = dmlOptions("UPSERT", options, context)
+		if !ok {
+			continue
+		}
+
 		dpair.Value = val
 		i++
 	}
@@ -132,12 +152,13 @@ func (this *SendUpsert) flushBatch(context *Context) bool {
 
 	// Perform the actual UPSERT
 	var er errors.Error
-	dpairs, er = this.plan.Keyspace().Upsert(dpairs)
+	dpairs, er = context.keyspace(this.plan.Keyspace()).Upsert(dpairs)
 
 	this.switchPhase(_EXECTIME)
 
 	// Update mutation count with number of upserted docs
 	context.AddMutationCount(uint64(len(dpairs)))
+	resultcache.Invalidate(this.plan.Keyspace())
 
 	if er != nil {
 		context.Error(er)
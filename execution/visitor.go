@@ -124,6 +124,12 @@ type Visitor interface {
 	VisitAlterIndex(op *AlterIndex) (interface{}, error)
 	VisitBuildIndexes(op *BuildIndexes) (interface{}, error)
 
+	// Scope and collection DDL
+	VisitCreateScope(op *CreateScope) (interface{}, error)
+	VisitDropScope(op *DropScope) (interface{}, error)
+	VisitCreateCollection(op *CreateCollection) (interface{}, error)
+	VisitDropCollection(op *DropCollection) (interface{}, error)
+
 	// Roles
 	VisitGrantRole(op *GrantRole) (interface{}, error)
 	VisitRevokeRole(op *RevokeRole) (interface{}, error)
@@ -148,4 +154,11 @@ type Visitor interface {
 
 	// Update Statistics
 	VisitUpdateStatistics(op *UpdateStatistics) (interface{}, error)
+	VisitDeleteStatistics(op *DeleteStatistics) (interface{}, error)
+
+	// Transactions
+	VisitStartTransaction(op *StartTransaction) (interface{}, error)
+	VisitCommitTransaction(op *CommitTransaction) (interface{}, error)
+	VisitRollbackTransaction(op *RollbackTransaction) (interface{}, error)
+	VisitSavepoint(op *Savepoint) (interface{}, error)
 }
@@ -39,6 +39,7 @@ type WindowAggregate struct {
 	aggs         []*AggregateInfo
 	newPartition bool
 	flags        uint32
+	size         uint64 // memory charged for the values
 }
 
 /*
@@ -1101,6 +1102,14 @@ func (this *WindowAggregate) processWindowPartition(item value.AnnotatedValue) b
 	this.values = append(this.values, item)
 	this.nItems++
 
+	if this.context.UseRequestQuota() {
+		size := item.Size()
+		this.size += size
+		if !this.context.TrackValueSize(size) {
+			return false
+		}
+	}
+
 	return true
 }
 
@@ -1196,6 +1205,8 @@ func (this *WindowAggregate) reopen(context *Context) bool {
 }
 
 func (this *WindowAggregate) recycleValues() {
+	this.context.ReleaseValueSize(this.size)
+	this.size = 0
 	this.values = this.values[0:0]
 	this.nItems = 0
 	this.cItem = 0
@@ -1203,6 +1214,14 @@ func (this *WindowAggregate) recycleValues() {
 }
 
 func (this *WindowAggregate) recycleValue(c int64) {
+	if this.size > 0 {
+		size := this.values[c].Size()
+		if size > this.size {
+			size = this.size
+		}
+		this.context.ReleaseValueSize(size)
+		this.size -= size
+	}
 	this.values = append(this.values[:c], this.values[c+1:]...)
 	this.nItems--
 }
@@ -11,16 +11,45 @@ package execution
 
 import (
 	"encoding/json"
+	"sync"
+	go_atomic "sync/atomic"
 
+	"github.com/couchbase/query/algebra"
 	"github.com/couchbase/query/errors"
 	"github.com/couchbase/query/plan"
 	"github.com/couchbase/query/value"
 )
 
+// Default maximum number of times the recursive part of a WITH RECURSIVE
+// term is evaluated: UNION ALL terms over cyclic data never run out of documents
+const _MAX_RECURSION_DEPTH = 1000
+
+var _RECURSION_DEPTH int64 = _MAX_RECURSION_DEPTH
+
+func SetMaxRecursionDepth(depth int) {
+	if depth < 1 {
+		depth = _MAX_RECURSION_DEPTH
+	}
+	go_atomic.StoreInt64(&_RECURSION_DEPTH, int64(depth))
+}
+
+func MaxRecursionDepth() int {
+	return int(go_atomic.LoadInt64(&_RECURSION_DEPTH))
+}
+
 type With struct {
 	base
-	plan  *plan.With
-	child Operator
+	sync.Mutex
+	plan      *plan.With
+	child     Operator
+	recursion map[string]*recursionStats
+	context   *Context
+	size      uint64
+}
+
+type recursionStats struct {
+	Iterations int `json:"iterations"`
+	Documents  int `json:"documents"`
 }
 
 func NewWith(plan *plan.With, context *Context, child Operator) *With {
@@ -73,10 +102,28 @@ func (this *With) RunOnce(context *Context, parent value.Value) {
 			wv = value.NewAnnotatedValue(make(map[string]interface{}, 1))
 		}
 
-		for _, b := range this.plan.Bindings() {
-			v, e := b.Expression().Evaluate(wv, context)
+		for i, b := range this.plan.Bindings() {
+			var v value.Value
+			var e error
+
+			rw := this.plan.RecursiveWith(i)
+			if rw != nil {
+				v, e = this.evaluateRecursive(rw, wv, context)
+				if v == nil && e == nil {
+
+					// over the memory quota, which has already been reported
+					this.notify()
+					break
+				}
+			} else {
+				v, e = b.Expression().Evaluate(wv, context)
+			}
 			if e != nil {
-				context.Error(errors.NewEvaluationError(e, "WITH"))
+				if err, ok := e.(errors.Error); ok && rw != nil {
+					context.Error(err)
+				} else {
+					context.Error(errors.NewEvaluationError(e, "WITH"))
+				}
 				this.notify()
 
 				// MB-31605 have to start the child for the output and stop
@@ -91,9 +138,100 @@ func (this *With) RunOnce(context *Context, parent value.Value) {
 	})
 }
 
+/*
+Evaluate the anchor once, and then the recursive part over and over,
+with the term bound to the documents produced by the previous iteration,
+until it produces no more documents. UNION discards the documents that
+have already been produced, which also ends the recursion on cycles.
+The documents produced are charged to the memory quota of the request,
+and nil is returned, with no error, once it is exceeded.
+*/
+func (this *With) evaluateRecursive(rw *algebra.RecursiveWith, wv value.AnnotatedValue,
+	context *Context) (value.Value, error) {
+
+	var seen *value.Set
+	if !rw.All() {
+		seen = value.NewSet(int(context.GetPipelineCap()), false, false)
+	}
+
+	// the stats are only published once complete, profiles may be marshalled at any time
+	stats := &recursionStats{}
+	defer func() {
+		this.Lock()
+		if this.recursion == nil {
+			this.recursion = make(map[string]*recursionStats, len(this.plan.Bindings()))
+		}
+		this.recursion[rw.Variable()] = stats
+		this.Unlock()
+	}()
+
+	maxDepth := context.MaxRecursionDepth()
+	docs, err := context.EvaluateSubquery(rw.Anchor(), wv)
+	results := make([]interface{}, 0, 64)
+	for err == nil {
+		working, _ := docs.Actual().([]interface{})
+		if seen != nil {
+			fresh := make([]interface{}, 0, len(working))
+			for _, doc := range working {
+				dv := value.NewValue(doc)
+				if !seen.Has(dv) {
+					seen.Add(dv)
+					fresh = append(fresh, dv)
+				}
+			}
+			working = fresh
+		}
+
+		if len(working) == 0 {
+			break
+		}
+		results = append(results, working...)
+		stats.Documents = len(results)
+
+		if context.UseRequestQuota() {
+			size := uint64(0)
+			for _, doc := range working {
+				size += value.NewValue(doc).Size()
+			}
+			this.context = context
+			this.size += size
+			if !context.TrackValueSize(size) {
+				wv.UnsetField(rw.Variable())
+				return nil, nil
+			}
+		}
+
+		if stats.Iterations >= maxDepth {
+			err = errors.NewRecursionDepthError(rw.Variable(), maxDepth)
+			break
+		}
+		stats.Iterations++
+
+		wv.SetField(rw.Variable(), working)
+		docs, err = context.evaluateSubquery(rw.Recursive(), wv, false)
+	}
+
+	if err != nil {
+
+		// the term is only bound when complete
+		wv.UnsetField(rw.Variable())
+		return nil, err
+	}
+	return value.NewValue(results), nil
+}
+
 func (this *With) MarshalJSON() ([]byte, error) {
 	r := this.plan.MarshalBase(func(r map[string]interface{}) {
 		this.marshalTimes(r)
+		this.Lock()
+		if len(this.recursion) > 0 {
+			recursion := make(map[string]*recursionStats, len(this.recursion))
+			for k, v := range this.recursion {
+				recursion[k] = v
+			}
+			r["#recursion"] = recursion
+		}
+		this.Unlock()
 	})
 	r["~child"] = this.child
 	return json.Marshal(r)
@@ -125,6 +263,11 @@ func (this *With) reopen(context *Context) bool {
 
 func (this *With) Done() {
 	this.baseDone()
+	if this.size != 0 {
+		this.context.ReleaseValueSize(this.size)
+		this.size = 0
+	}
+	this.context = nil
 	if this.child != nil {
 		child := this.child
 		this.child = nil
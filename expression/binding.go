@@ -25,14 +25,15 @@ type Binding struct {
 	expr         Expression `json:"expr"`
 	descend      bool       `json:"desc"`
 	static       bool       `json:"static"`
+	recursive    bool       `json:"recursive"`
 }
 
 func NewBinding(nameVariable, variable string, expr Expression, descend bool) *Binding {
-	return &Binding{nameVariable, variable, expr, descend, false}
+	return &Binding{nameVariable, variable, expr, descend, false, false}
 }
 
 func NewSimpleBinding(variable string, expr Expression) *Binding {
-	return &Binding{"", variable, expr, false, false}
+	return &Binding{"", variable, expr, false, false, false}
 }
 
 func (this *Binding) Copy() *Binding {
@@ -42,6 +43,7 @@ func (this *Binding) Copy() *Binding {
 		expr:         this.expr.Copy(),
 		descend:      this.descend,
 		static:       this.static,
+		recursive:    this.recursive,
 	}
 }
 
@@ -73,6 +75,17 @@ func (this *Binding) SetStatic(s bool) {
 	this.static = s
 }
 
+/*
+A binding of a WITH RECURSIVE clause, which can refer to itself.
+*/
+func (this *Binding) Recursive() bool {
+	return this.recursive
+}
+
+func (this *Binding) SetRecursive(r bool) {
+	this.recursive = r
+}
+
 func (this *Binding) MarshalJSON() ([]byte, error) {
 	r := make(map[string]interface{}, 4)
 	if this.nameVariable != "" {
@@ -86,6 +99,9 @@ func (this *Binding) MarshalJSON() ([]byte, error) {
 	if this.static {
 		r["static"] = this.static
 	}
+	if this.recursive {
+		r["recursive"] = this.recursive
+	}
 
 	return json.Marshal(r)
 }
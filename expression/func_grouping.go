@@ -0,0 +1,140 @@
+//  Copyright (c) 2020 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package expression
+
+import (
+	"math"
+
+	"github.com/couchbase/query/value"
+)
+
+///////////////////////////////////////////////////
+//
+// Grouping
+//
+///////////////////////////////////////////////////
+
+/*
+This represents the function GROUPING(expr1, expr2, ...) over the
+group by expressions of ROLLUP, CUBE and GROUPING SETS. It returns
+one bit per expression, the first being the most significant, set
+if the expression is rolled up in the grouping set of the group.
+*/
+type Grouping struct {
+	FunctionBase
+}
+
+func NewGrouping(operands ...Expression) Function {
+	rv := &Grouping{
+		*NewFunctionBase("grouping", operands...),
+	}
+
+	rv.expr = rv
+	return rv
+}
+
+/*
+Visitor pattern.
+*/
+func (this *Grouping) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+func (this *Grouping) Type() value.Type { return value.NUMBER }
+
+/*
+The operands are not evaluated, only looked up in the rolled up
+expressions of the group.
+*/
+func (this *Grouping) Evaluate(item value.Value, context Context) (value.Value, error) {
+	return groupingBits(this.operands, item), nil
+}
+
+func (this *Grouping) MinArgs() int { return 1 }
+
+func (this *Grouping) MaxArgs() int { return math.MaxInt16 }
+
+/*
+Factory method pattern.
+*/
+func (this *Grouping) Constructor() FunctionConstructor {
+	return NewGrouping
+}
+
+///////////////////////////////////////////////////
+//
+// GroupingId
+//
+///////////////////////////////////////////////////
+
+/*
+This represents the function GROUPING_ID(expr1, expr2, ...). It
+returns the same bits as GROUPING(expr1, expr2, ...).
+*/
+type GroupingId struct {
+	FunctionBase
+}
+
+func NewGroupingId(operands ...Expression) Function {
+	rv := &GroupingId{
+		*NewFunctionBase("grouping_id", operands...),
+	}
+
+	rv.expr = rv
+	return rv
+}
+
+/*
+Visitor pattern.
+*/
+func (this *GroupingId) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+func (this *GroupingId) Type() value.Type { return value.NUMBER }
+
+func (this *GroupingId) Evaluate(item value.Value, context Context) (value.Value, error) {
+	return groupingBits(this.operands, item), nil
+}
+
+func (this *GroupingId) MinArgs() int { return 1 }
+
+func (this *GroupingId) MaxArgs() int { return math.MaxInt16 }
+
+/*
+Factory method pattern.
+*/
+func (this *GroupingId) Constructor() FunctionConstructor {
+	return NewGroupingId
+}
+
+/*
+The group operators attach to each group of a grouping set the
+expressions it rolls up, keyed by their text. Items outside of
+grouping sets have none, so no expression is rolled up.
+*/
+func groupingBits(operands Expressions, item value.Value) value.Value {
+	var rollup value.Value
+	if av, ok := item.(value.AnnotatedValue); ok {
+		rollup, _ = av.GetAttachment("grouping").(value.Value)
+	}
+
+	var bits int64
+	for _, op := range operands {
+		bits <<= 1
+		if rollup != nil {
+			if _, ok := rollup.Field(op.String()); ok {
+				bits |= 1
+			}
+		}
+	}
+
+	return value.NewValue(bits)
+}
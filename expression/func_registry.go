@@ -414,6 +414,9 @@ var _FUNCTIONS = map[string]Function{
 	"tostring":   &ToString{},
 	"decode":     &Decode{},
 
+	// Grouping sets
+	"grouping_id": &GroupingId{},
+
 	// Unnest
 	"unnest_position": &UnnestPosition{},
 	"unnest_pos":      &UnnestPosition{},
@@ -39,10 +39,11 @@ func UnmarshalBinding(body []byte) (*expression.Binding, error) {
 
 func UnmarshalBindings(body []byte) (expression.Bindings, error) {
 	var _unmarshalled []struct {
-		NameVar string `json:"name_var"`
-		Var     string `json:"var"`
-		Expr    string `json:"expr"`
-		Desc    bool   `json:"desc"`
+		NameVar   string `json:"name_var"`
+		Var       string `json:"var"`
+		Expr      string `json:"expr"`
+		Desc      bool   `json:"desc"`
+		Recursive bool   `json:"recursive"`
 	}
 
 	err := json.Unmarshal(body, &_unmarshalled)
@@ -58,6 +59,7 @@ func UnmarshalBindings(body []byte) (expression.Bindings, error) {
 		}
 
 		bindings[i] = expression.NewBinding(binding.NameVar, binding.Var, expr, binding.Desc)
+		bindings[i].SetRecursive(binding.Recursive)
 	}
 
 	return bindings, nil
@@ -1,4 +1,4 @@
-//  Copyright (c) 2019 Couchbase, Inc.
+//  Copyright (c) 2020 Couchbase, Inc.
 //  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
 //  except in compliance with the License. You may obtain a copy of the License at
 //    http://www.apache.org/licenses/LICENSE-2.0
@@ -12,41 +12,389 @@
 package javascript
 
 import (
+	goerrors "errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/couchbase/query/auth"
+	"github.com/couchbase/query/datastore"
 	"github.com/couchbase/query/errors"
 	"github.com/couchbase/query/functions"
+	"github.com/couchbase/query/logging"
 	"github.com/couchbase/query/value"
+	"github.com/dop251/goja"
 	"github.com/gorilla/mux"
 )
 
-// this body is used to fail function creation where not supported
+// The open build runs javascript functions in process, using the goja interpreter
+// (github.com/dop251/goja, which has to be in the build workspace).
+// Each call gets its own runtime, which only has the standard ECMAScript built-ins:
+// there is no module loader, and no file or network access.
+
+const _LIBRARIES_PATH = "/evaluator/v1/libraries"
+
+// calls are bounded by the request deadline, or by this if the request has none
+const _MAX_CALL_TIME = 2 * time.Minute
+
+type javascript struct {
+}
+
 type javascriptBody struct {
+	varNames []string
+	library  string
+	object   string
+}
+
+// a library is a compiled javascript source, defining the functions that can be called
+type library struct {
+	name    string
+	source  string
+	program *goja.Program
+}
+
+var libraries = struct {
+	sync.RWMutex
+	libraries map[string]*library
+}{libraries: make(map[string]*library)}
+
+func Init(mux *mux.Router) {
+	functions.FunctionsNewLanguage(functions.JAVASCRIPT, &javascript{})
+	loadLibraries()
+
+	if mux != nil {
+		mux.HandleFunc(_LIBRARIES_PATH, handleLibraries).Methods("GET")
+		mux.HandleFunc(_LIBRARIES_PATH+"/{library}", handleLibrary).Methods("GET", "POST", "PUT", "DELETE")
+	}
+}
+
+// library management
+
+// datastores that keep function definitions themselves also keep the libraries,
+// otherwise libraries only last as long as the process
+func localStorage() datastore.FunctionsStorage {
+	storage, _ := datastore.GetDatastore().(datastore.FunctionsStorage)
+	return storage
+}
+
+func loadLibraries() {
+	storage := localStorage()
+	if storage == nil {
+		return
+	}
+
+	names, err := storage.LibraryNames()
+	if err != nil {
+		logging.Errorf("Unable to load javascript libraries: %v", err)
+		return
+	}
+	for _, name := range names {
+		source, err := storage.GetLibrary(name)
+		if err == nil && source != nil {
+			var lib *library
+			lib, err = newLibrary(name, string(source))
+			if err == nil {
+				libraries.Lock()
+				libraries.libraries[name] = lib
+				libraries.Unlock()
+			}
+		}
+		if err != nil {
+			logging.Errorf("Unable to load javascript library %v: %v", name, err)
+		}
+	}
+}
+
+func newLibrary(name, source string) (*library, errors.Error) {
+	program, err := goja.Compile(name, source, false)
+	if err != nil {
+		return nil, errors.NewFunctionEncodingError("compile library", name, err)
+	}
+	return &library{name: name, source: source, program: program}, nil
+}
+
+func AddLibrary(name, source string) errors.Error {
+	lib, err := newLibrary(name, source)
+	if err != nil {
+		return err
+	}
+
+	libraries.Lock()
+	defer libraries.Unlock()
+	if storage := localStorage(); storage != nil {
+		if err = storage.SetLibrary(name, []byte(source)); err != nil {
+			return err
+		}
+	}
+	libraries.libraries[name] = lib
+	return nil
+}
+
+func DeleteLibrary(name string) errors.Error {
+	libraries.Lock()
+	defer libraries.Unlock()
+	if storage := localStorage(); storage != nil {
+		if err := storage.DeleteLibrary(name); err != nil {
+			return err
+		}
+	} else if _, ok := libraries.libraries[name]; !ok {
+		return errors.NewMissingLibraryError(name)
+	}
+	delete(libraries.libraries, name)
+	return nil
+}
+
+func getLibrary(name string) *library {
+	libraries.RLock()
+	defer libraries.RUnlock()
+	return libraries.libraries[name]
+}
+
+func handleLibraries(w http.ResponseWriter, req *http.Request) {
+	if !authorize(w, req, "system:functions", auth.PRIV_SYSTEM_READ) {
+		return
+	}
+
+	libraries.RLock()
+	names := make([]string, 0, len(libraries.libraries))
+	for name, _ := range libraries.libraries {
+		names = append(names, name)
+	}
+	libraries.RUnlock()
+	sort.Strings(names)
+
+	list := make([]interface{}, 0, len(names))
+	for _, name := range names {
+		lib := getLibrary(name)
+		if lib != nil {
+			list = append(list, map[string]interface{}{"name": lib.name, "code": lib.source})
+		}
+	}
+	bytes, _ := value.NewValue(list).MarshalJSON()
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(bytes)
+}
+
+func handleLibrary(w http.ResponseWriter, req *http.Request) {
+	name := mux.Vars(req)["library"]
+
+	// http.BasicAuth eats the body, so read it before verifying credentials
+	var source []byte
+	var err error
+	if req.Method == "POST" || req.Method == "PUT" {
+		source, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// reading libraries needs the same privileges as reading function definitions,
+	// changing them the privilege to manage javascript functions
+	if req.Method == "GET" {
+		if !authorize(w, req, "system:functions", auth.PRIV_SYSTEM_READ) {
+			return
+		}
+	} else if !authorize(w, req, "", auth.PRIV_QUERY_MANAGE_FUNCTIONS_EXTERNAL) {
+		return
+	}
+
+	switch req.Method {
+	case "GET":
+		lib := getLibrary(name)
+		if lib == nil {
+			http.Error(w, "library not found "+name, http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/javascript")
+		w.Write([]byte(lib.source))
+	case "POST", "PUT":
+		err := AddLibrary(name, string(source))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		logging.Infof("Javascript library %v stored", name)
+	case "DELETE":
+		if getLibrary(name) == nil {
+			http.Error(w, "library not found "+name, http.StatusNotFound)
+			return
+		}
+		err := DeleteLibrary(name)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		logging.Infof("Javascript library %v deleted", name)
+	}
+}
+
+func authorize(w http.ResponseWriter, req *http.Request, target string, priv auth.Privilege) bool {
+	creds := auth.Credentials{}
+	user, password, ok := req.BasicAuth()
+	if ok {
+		creds[user] = password
+	}
+
+	privs := auth.NewPrivileges()
+	privs.Add(target, priv)
+	_, err := datastore.GetDatastore().Authorize(privs, creds, req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+// execution
+
+func (this *javascript) Execute(name functions.FunctionName, body functions.FunctionBody, modifiers functions.Modifier, values []value.Value, context functions.Context) (value.Value, errors.Error) {
+	funcName := name.Name()
+	funcBody, ok := body.(*javascriptBody)
+
+	if !ok {
+		return nil, errors.NewInternalFunctionError(goerrors.New("Wrong language being executed!"), funcName)
+	}
+
+	if len(funcBody.varNames) != 0 && len(values) != len(funcBody.varNames) {
+		return nil, errors.NewArgumentsMismatchError(funcName)
+	}
+
+	lib := getLibrary(funcBody.library)
+	if lib == nil {
+		return nil, funcBody.execError(fmt.Errorf("library not found"), funcName)
+	}
+
+	// interrupt runaway calls once the request runs out of time
+	timeout := _MAX_CALL_TIME
+	if deadlineContext, ok := context.(interface{ GetReqDeadline() time.Time }); ok {
+		deadline := deadlineContext.GetReqDeadline()
+		if !deadline.IsZero() && time.Until(deadline) < timeout {
+			timeout = time.Until(deadline)
+		}
+	}
+	vm := goja.New()
+	timer := time.AfterFunc(timeout, func() {
+		vm.Interrupt(fmt.Errorf("execution timed out after %v", timeout))
+	})
+	defer timer.Stop()
+
+	_, err := vm.RunProgram(lib.program)
+	if err != nil {
+		return nil, funcBody.execError(err, funcName)
+	}
+	function, ok := goja.AssertFunction(vm.Get(funcBody.object))
+	if !ok {
+		return nil, funcBody.execError(fmt.Errorf("%v is not a function", funcBody.object), funcName)
+	}
+
+	args := make([]goja.Value, len(values))
+	for i, _ := range values {
+		args[i] = toJavascript(vm, values[i].Actual())
+	}
+
+	res, err := function(goja.Undefined(), args...)
+	if err != nil {
+		return nil, funcBody.execError(err, funcName)
+	}
+	if res == nil || goja.IsUndefined(res) {
+		return value.MISSING_VALUE, nil
+	}
+	val, err := fromJavascript(res.Export())
+	if err != nil {
+		return nil, funcBody.execError(err, funcName)
+	}
+	return value.NewValue(val), nil
+}
+
+func (this *javascriptBody) execError(err error, name string) errors.Error {
+	return errors.NewFunctionExecutionError(fmt.Sprintf("(%v:%v)", this.library, this.object), name, err)
+}
+
+// arguments are copied into native javascript values, so that functions cannot alter them
+func toJavascript(vm *goja.Runtime, val interface{}) goja.Value {
+	switch val := val.(type) {
+	case map[string]interface{}:
+		object := vm.NewObject()
+		for k, v := range val {
+			object.Set(k, toJavascript(vm, v))
+		}
+		return object
+	case []interface{}:
+		elems := make([]interface{}, len(val))
+		for i, v := range val {
+			elems[i] = toJavascript(vm, v)
+		}
+		return vm.NewArray(elems...)
+	case value.Value:
+		return toJavascript(vm, val.Actual())
+	default:
+		return vm.ToValue(val)
+	}
 }
 
-func Init(mix *mux.Router) {
+// results are restricted to JSON types
+func fromJavascript(val interface{}) (interface{}, error) {
+	switch val := val.(type) {
+	case nil, bool, string, int64, float64:
+		return val, nil
+	case map[string]interface{}:
+		for k, v := range val {
+			newV, err := fromJavascript(v)
+			if err != nil {
+				return nil, err
+			}
+			val[k] = newV
+		}
+		return val, nil
+	case []interface{}:
+		for i, v := range val {
+			newV, err := fromJavascript(v)
+			if err != nil {
+				return nil, err
+			}
+			val[i] = newV
+		}
+		return val, nil
+	case time.Time:
+		return val.Format(time.RFC3339Nano), nil
+	default:
+		return nil, fmt.Errorf("unsupported result type %T", val)
+	}
 }
 
 func NewJavascriptBody(library, object string) (functions.FunctionBody, errors.Error) {
-	return nil, errors.NewFunctionsNotSupported()
+	return &javascriptBody{library: library, object: object}, nil
 }
 
-func (this *javascriptBody) Lang() functions.Language {
-	return functions.GOLANG
+func (this *javascriptBody) SetVarNames(vars []string) errors.Error {
+	this.varNames = vars
+	return nil
 }
 
-// this will never be called, just a placeholder
-func (this *javascriptBody) Body(object map[string]interface{}) {
-	object["functions_feature_disabled"] = true
+func (this *javascriptBody) Lang() functions.Language {
+	return functions.JAVASCRIPT
 }
 
-//ditto
-func (this *javascriptBody) SetVars(vars []string) {
+func (this *javascriptBody) Body(object map[string]interface{}) {
+	object["#language"] = "javascript"
+	object["library"] = this.library
+	object["object"] = this.object
+	if len(this.varNames) > 0 {
+		vars := make([]value.Value, len(this.varNames))
+		for v, _ := range this.varNames {
+			vars[v] = value.NewValue(this.varNames[v])
+		}
+		object["parameters"] = vars
+	}
 }
 
 func (this *javascriptBody) Indexable() value.Tristate {
-	return value.FALSE
-}
 
-// ditto, for tests
-func MakeJavascript(name functions.FunctionName, body []byte) (functions.FunctionBody, errors.Error) {
-	return nil, errors.NewFunctionsNotSupported()
+	// for now
+	return value.FALSE
 }
@@ -0,0 +1,297 @@
+//  Copyright (c) 2020 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+// +build !enterprise !go1.10
+
+package javascript
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/couchbase/query/auth"
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/functions"
+	"github.com/couchbase/query/value"
+	"github.com/dop251/goja"
+	"github.com/gorilla/mux"
+)
+
+type testName struct {
+	functions.FunctionName
+}
+
+func (this *testName) Name() string {
+	return "jsfunc"
+}
+
+type testContext struct {
+	deadline time.Time
+}
+
+func (this *testContext) Now() time.Time {
+	return time.Now()
+}
+
+func (this *testContext) AuthenticatedUsers() []string {
+	return nil
+}
+
+func (this *testContext) DatastoreVersion() string {
+	return ""
+}
+
+func (this *testContext) EvaluateStatement(statement string, namedArgs map[string]value.Value, positionalArgs value.Values,
+	subquery, readonly bool) (value.Value, uint64, error) {
+	return nil, 0, nil
+}
+
+func (this *testContext) GetReqDeadline() time.Time {
+	return this.deadline
+}
+
+// a datastore that keeps libraries in memory and denies one privilege,
+// only the functions storage and authorization are used
+type testDatastore struct {
+	datastore.Datastore
+	sync.Mutex
+	denied    auth.Privilege
+	libraries map[string][]byte
+}
+
+func (this *testDatastore) Authorize(privs *auth.Privileges, creds auth.Credentials, req *http.Request) (auth.AuthenticatedUsers, errors.Error) {
+	for _, pair := range privs.List {
+		if pair.Priv == this.denied {
+			return nil, errors.NewDatastoreInsufficientCredentials("denied")
+		}
+	}
+	return nil, nil
+}
+
+func (this *testDatastore) FunctionKeys() ([]string, errors.Error) {
+	return nil, nil
+}
+
+func (this *testDatastore) GetFunction(key string) ([]byte, errors.Error) {
+	return nil, nil
+}
+
+func (this *testDatastore) AddFunction(key string, definition []byte) errors.Error {
+	return nil
+}
+
+func (this *testDatastore) DeleteFunction(key string) errors.Error {
+	return nil
+}
+
+func (this *testDatastore) LibraryNames() ([]string, errors.Error) {
+	this.Lock()
+	defer this.Unlock()
+	names := make([]string, 0, len(this.libraries))
+	for name, _ := range this.libraries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (this *testDatastore) GetLibrary(name string) ([]byte, errors.Error) {
+	this.Lock()
+	defer this.Unlock()
+	return this.libraries[name], nil
+}
+
+func (this *testDatastore) SetLibrary(name string, source []byte) errors.Error {
+	this.Lock()
+	defer this.Unlock()
+	this.libraries[name] = source
+	return nil
+}
+
+func (this *testDatastore) DeleteLibrary(name string) errors.Error {
+	this.Lock()
+	defer this.Unlock()
+	if _, ok := this.libraries[name]; !ok {
+		return errors.NewMissingLibraryError(name)
+	}
+	delete(this.libraries, name)
+	return nil
+}
+
+func setup(t *testing.T, denied auth.Privilege) *testDatastore {
+	ds := &testDatastore{denied: denied, libraries: make(map[string][]byte)}
+	datastore.SetDatastore(ds)
+	libraries.Lock()
+	libraries.libraries = make(map[string]*library)
+	libraries.Unlock()
+	return ds
+}
+
+func execute(t *testing.T, lib, object string, vars []string, args []value.Value, context functions.Context) (value.Value, errors.Error) {
+	body, _ := NewJavascriptBody(lib, object)
+	body.SetVarNames(vars)
+	return (&javascript{}).Execute(&testName{}, body, functions.NONE, args, context)
+}
+
+func TestConversions(t *testing.T) {
+	vm := goja.New()
+	vals := []interface{}{
+		nil,
+		true,
+		"abc",
+		int64(42),
+		1.5,
+		[]interface{}{int64(1), "two", []interface{}{false}},
+		map[string]interface{}{"a": int64(1), "b": map[string]interface{}{"c": "d"}},
+	}
+	for _, v := range vals {
+		res, err := fromJavascript(toJavascript(vm, v).Export())
+		if err != nil {
+			t.Errorf("round trip of %v failed: %v", v, err)
+		} else if !reflect.DeepEqual(res, v) {
+			t.Errorf("round trip of %v returned %v", v, res)
+		}
+	}
+
+	res, err := fromJavascript(toJavascript(vm, value.NewValue("abc")).Export())
+	if err != nil || res != "abc" {
+		t.Errorf("round trip of a value returned %v, %v", res, err)
+	}
+
+	now := time.Now()
+	res, err = fromJavascript(now)
+	if err != nil || res != now.Format(time.RFC3339Nano) {
+		t.Errorf("conversion of a date returned %v, %v", res, err)
+	}
+
+	_, err = fromJavascript(func() {})
+	if err == nil {
+		t.Errorf("conversion of a function should have failed")
+	}
+}
+
+func TestExecute(t *testing.T) {
+	setup(t, auth.PRIV_WRITE)
+	err := AddLibrary("math", "function add(a, b) { return a + b; }\nvar notAFunction = 1;\nfunction spin() { while (true) {} }")
+	if err != nil {
+		t.Fatalf("failed to add library: %v", err)
+	}
+	context := &testContext{}
+
+	res, err := execute(t, "math", "add", []string{"a", "b"}, []value.Value{value.NewValue(1), value.NewValue(2)}, context)
+	if err != nil || !res.Equals(value.NewValue(3)).Truth() {
+		t.Errorf("add returned %v, %v", res, err)
+	}
+
+	_, err = execute(t, "math", "add", []string{"a", "b"}, []value.Value{value.NewValue(1)}, context)
+	if err == nil || err.Code() != errors.NewArgumentsMismatchError("").Code() {
+		t.Errorf("expected an argument mismatch, got %v", err)
+	}
+
+	_, err = execute(t, "nolib", "add", nil, nil, context)
+	if err == nil || !strings.Contains(err.Error(), "library not found") {
+		t.Errorf("expected a missing library error, got %v", err)
+	}
+
+	_, err = execute(t, "math", "sub", nil, nil, context)
+	if err == nil || !strings.Contains(err.Error(), "sub is not a function") {
+		t.Errorf("expected a missing object error, got %v", err)
+	}
+
+	_, err = execute(t, "math", "notAFunction", nil, nil, context)
+	if err == nil || !strings.Contains(err.Error(), "notAFunction is not a function") {
+		t.Errorf("expected a missing object error, got %v", err)
+	}
+
+	context.deadline = time.Now().Add(50 * time.Millisecond)
+	_, err = execute(t, "math", "spin", nil, nil, context)
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("expected a timeout, got %v", err)
+	}
+}
+
+func TestLibraryHandlers(t *testing.T) {
+	ds := setup(t, auth.PRIV_QUERY_MANAGE_FUNCTIONS_EXTERNAL)
+	router := mux.NewRouter()
+	Init(router)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	do := func(method, path, body string) (int, string) {
+		req, _ := http.NewRequest(method, server.URL+_LIBRARIES_PATH+path, strings.NewReader(body))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%v %v failed: %v", method, path, err)
+		}
+		defer resp.Body.Close()
+		out, _ := ioutil.ReadAll(resp.Body)
+		return resp.StatusCode, string(out)
+	}
+
+	// changes need the manage privilege
+	for _, method := range []string{"POST", "PUT", "DELETE"} {
+		if code, _ := do(method, "/lib1", "function f() { return 1; }"); code != http.StatusUnauthorized {
+			t.Errorf("%v without privileges returned %v", method, code)
+		}
+	}
+	if code, _ := do("GET", "", ""); code != http.StatusOK {
+		t.Errorf("GET with read privileges returned %v", code)
+	}
+
+	ds.denied = auth.PRIV_WRITE
+	if code, _ := do("POST", "/lib1", "function f() { return 1; }"); code != http.StatusOK {
+		t.Errorf("POST returned %v", code)
+	}
+	if code, _ := do("PUT", "/lib1", "function f() { return 2; }"); code != http.StatusOK {
+		t.Errorf("PUT returned %v", code)
+	}
+	if code, _ := do("PUT", "/lib2", "function {"); code != http.StatusBadRequest {
+		t.Errorf("PUT of invalid javascript returned %v", code)
+	}
+	if code, body := do("GET", "/lib1", ""); code != http.StatusOK || body != "function f() { return 2; }" {
+		t.Errorf("GET returned %v %v", code, body)
+	}
+	if code, body := do("GET", "", ""); code != http.StatusOK || !strings.Contains(body, `"name":"lib1"`) {
+		t.Errorf("GET of all libraries returned %v %v", code, body)
+	}
+
+	// libraries are kept with the function definitions, and reloaded from there
+	source, _ := ds.GetLibrary("lib1")
+	if string(source) != "function f() { return 2; }" {
+		t.Errorf("stored library is %s", source)
+	}
+	libraries.Lock()
+	libraries.libraries = make(map[string]*library)
+	libraries.Unlock()
+	Init(nil)
+	if getLibrary("lib1") == nil {
+		t.Errorf("library was not reloaded")
+	}
+
+	if code, _ := do("DELETE", "/lib1", ""); code != http.StatusOK {
+		t.Errorf("DELETE returned %v", code)
+	}
+	if code, _ := do("DELETE", "/lib1", ""); code != http.StatusNotFound {
+		t.Errorf("DELETE of a missing library returned %v", code)
+	}
+	if code, _ := do("GET", "/lib1", ""); code != http.StatusNotFound {
+		t.Errorf("GET of a missing library returned %v", code)
+	}
+	if names, _ := ds.LibraryNames(); len(names) != 0 {
+		t.Errorf("libraries left in storage: %v", names)
+	}
+}
@@ -30,7 +30,19 @@ const _CHANGE_COUNTER = _CHANGE_COUNTER_PATH + "counter"
 var changeCounter int32
 var whoAmI string
 
+// datastores can keep function definitions themselves, in which case metakv is not used
+func localStorage() datastore.FunctionsStorage {
+	storage, _ := datastore.GetDatastore().(datastore.FunctionsStorage)
+	return storage
+}
+
 func Init() {
+
+	// local storage is not shared with other nodes, so there are no remote changes to monitor
+	if localStorage() != nil {
+		return
+	}
+
 	whoAmI = distributed.RemoteAccess().WhoAmI()
 
 	// setup the change counter if not there
@@ -122,18 +134,13 @@ func (name *globalName) Load() (functions.FunctionBody, errors.Error) {
 	}
 
 	// load the function
-	val, _, err := metakv.Get(_FUNC_PATH + name.Key())
-
-	// Get does not return a not found error - just nil, nil
-	if val == nil && err == nil {
-		return nil, nil
-	} else if err != nil {
-		return nil, errors.NewMetaKVError(name.Name(), err)
+	val, err1 := name.get()
+	if val == nil || err1 != nil {
+		return nil, err1
 	}
-	name.changeCounter = changeCounter
 
 	// unmarshal signature and body
-	err = json.Unmarshal(val, &_unmarshalled)
+	err := json.Unmarshal(val, &_unmarshalled)
 	if err != nil {
 		return nil, errors.NewFunctionEncodingError("decode", name.Name(), err)
 	}
@@ -156,6 +163,10 @@ func (name *globalName) Save(body functions.FunctionBody) errors.Error {
 		return errors.NewFunctionEncodingError("encode", name.Name(), err)
 	}
 
+	if storage := localStorage(); storage != nil {
+		return storage.AddFunction(name.Key(), bytes)
+	}
+
 	err = metakv.Add(_FUNC_PATH+name.Key(), bytes)
 	if err == metakv.ErrRevMismatch {
 		return errors.NewDuplicateFunctionError(name.Name())
@@ -168,6 +179,10 @@ func (name *globalName) Save(body functions.FunctionBody) errors.Error {
 }
 
 func (name *globalName) Delete() errors.Error {
+	if storage := localStorage(); storage != nil {
+		return storage.DeleteFunction(name.Key())
+	}
+
 	// Delete() does not currently throw an error on missing key, so load first
 	val, _, err := metakv.Get(_FUNC_PATH + name.Key())
 
@@ -189,6 +204,24 @@ func (name *globalName) Delete() errors.Error {
 	return nil
 }
 
+// get returns the stored definition, or nil if the function does not exist
+func (name *globalName) get() ([]byte, errors.Error) {
+	if storage := localStorage(); storage != nil {
+		return storage.GetFunction(name.Key())
+	}
+
+	val, _, err := metakv.Get(_FUNC_PATH + name.Key())
+
+	// Get does not return a not found error - just nil, nil
+	if val == nil && err == nil {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.NewMetaKVError(name.Name(), err)
+	}
+	name.changeCounter = changeCounter
+	return val, nil
+}
+
 func (name *globalName) CheckStorage() bool {
 	return name.changeCounter != changeCounter
 }
@@ -121,41 +121,61 @@ func (this *lexer) Lex(lval *yySymType) int {
 		return 0
 	}
 
-	// if we had peeked, return that peeked token
-	if this.hasSaved {
-		rv := this.saved
-		*lval = this.lval
-		this.hasSaved = false
-		return rv
-	}
+	// peeked tokens are classified like any other
+	rv := this.next(lval)
 
-	rv := this.nex.Lex(lval)
+	switch rv {
+
+	// WITHIN GROUP introduces the ordering of an ordered-set
+	// aggregate, as opposed to the WITHIN operator
+	case WITHIN:
+		if this.peek(lval) == GROUP {
+			this.hasSaved = false
+			return WITHIN_GROUP
+		}
+		return WITHIN
 
 	// we are going to treat identifiers specially to resolve
 	// shift reduce conflicts on namespaces
-	if rv != IDENT {
-		return rv
-	}
+	case IDENT:
+
+		// is it a namespace?
+		_, found := namespaces[lval.s]
+		if !found {
+			return IDENT
+		}
 
-	// is it a namespace?
-	_, found := namespaces[lval.s]
-	if !found {
-		return IDENT
+		// not followed by a colon, so we have an identifier
+		if this.peek(lval) != COLON {
+			return IDENT
+		}
+		return NAMESPACE_ID
 	}
 
-	// save the current token value and check the next
-	this.hasSaved = true
-	oldLval := *lval
-	this.saved = this.nex.Lex(lval)
-	this.lval = *lval
-	*lval = oldLval
+	return rv
+}
 
-	// not a colon, so we have an identifier
-	if this.saved != COLON {
-		return IDENT
+// the next token, the one we had peeked if any
+func (this *lexer) next(lval *yySymType) int {
+	if this.hasSaved {
+		*lval = this.lval
+		this.hasSaved = false
+		return this.saved
 	}
-
-	return NAMESPACE_ID
+	return this.nex.Lex(lval)
+}
+
+// check the following token, saving it for the next call,
+// while leaving the current token value untouched
+func (this *lexer) peek(lval *yySymType) int {
+	if !this.hasSaved {
+		oldLval := *lval
+		this.saved = this.nex.Lex(lval)
+		this.lval = *lval
+		*lval = oldLval
+		this.hasSaved = true
+	}
+	return this.saved
 }
 
 func (this *lexer) Remainder(offset int) string {
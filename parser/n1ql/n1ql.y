@@ -65,6 +65,7 @@ indexKeyTerms    algebra.IndexKeyTerms
 partitionTerm   *algebra.IndexPartitionTerm
 groupTerm       *algebra.GroupTerm
 groupTerms       algebra.GroupTerms
+groupingSets     []expression.Expressions
 windowTerm      *algebra.WindowTerm
 windowTerms      algebra.WindowTerms
 windowFrame     *algebra.WindowFrame
@@ -130,6 +131,7 @@ tokOffset	 int
 %token CORRELATED
 %token COVER
 %token CREATE
+%token CUBE
 %token CURRENT
 %token DATABASE
 %token DATASET
@@ -163,10 +165,12 @@ tokOffset	 int
 %token FORCE
 %token FROM
 %token FTS
+%token FULL
 %token FUNCTION
 %token GOLANG
 %token GRANT
 %token GROUP
+%token GROUPING
 %token GROUPS
 %token GSI
 %token HASH
@@ -242,6 +246,7 @@ tokOffset	 int
 %token RANGE
 %token RAW
 %token REALM
+%token RECURSIVE
 %token REDUCE
 %token RENAME
 %token RESPECT
@@ -251,14 +256,18 @@ tokOffset	 int
 %token RIGHT
 %token ROLE
 %token ROLLBACK
+%token ROLLUP
 %token ROW
 %token ROWS
 %token SATISFIES
+%token SAVEPOINT
 %token SCHEMA
+%token SCOPE
 %token SELECT
 %token SELF
 %token SEMI
 %token SET
+%token SETS
 %token SHOW
 %token SOME
 %token START
@@ -296,6 +305,7 @@ tokOffset	 int
 %token WINDOW
 %token WITH
 %token WITHIN
+%token WITHIN_GROUP
 %token WORK
 %token XOR
 
@@ -307,7 +317,7 @@ tokOffset	 int
 /* Precedence: lowest to highest */
 %left           ORDER
 %left           UNION INTERESECT EXCEPT
-%left           JOIN NEST UNNEST FLATTEN INNER LEFT RIGHT
+%left           JOIN NEST UNNEST FLATTEN INNER LEFT RIGHT FULL
 %left           OR
 %left           AND
 %right          NOT
@@ -383,7 +393,7 @@ tokOffset	 int
 %type <b>                opt_join_type opt_quantifier
 %type <path>             path
 %type <s>                namespace_term namespace_name bucket_name scope_name keyspace_name
-%type <use>              opt_use opt_use_del_upd opt_use_merge use_options use_keys use_index join_hint
+%type <use>              opt_use opt_use_del_upd opt_use_merge use_options use_keys use_index join_hint cache_hint
 %type <joinHint>         use_hash_option
 %type <expr>             on_keys on_key
 %type <indexRefs>        index_refs
@@ -401,6 +411,8 @@ tokOffset	 int
 %type <sortTerms>        sort_terms
 %type <groupTerm>        group_term
 %type <groupTerms>       group_terms
+%type <groupingSets>     grouping_sets
+%type <exprs>            grouping_set
 %type <expr>             limit opt_limit
 %type <expr>             offset opt_offset
 %type <b>                dir opt_dir
@@ -408,15 +420,20 @@ tokOffset	 int
 %type <statement>        stmt_body
 %type <statement>        stmt advise explain prepare execute select_stmt dml_stmt ddl_stmt
 %type <statement>        infer infer_keyspace
-%type <statement>        update_statistics
+%type <statement>        update_statistics delete_statistics
+%type <statement>        transaction_stmt start_transaction commit_transaction rollback_transaction savepoint
 %type <statement>        insert upsert delete update merge
 %type <statement>        index_stmt create_index drop_index alter_index build_index
+%type <statement>        scope_stmt create_scope drop_scope
+%type <statement>        collection_stmt create_collection drop_collection
+%type <keyspacePath>     named_scope_ref named_collection_ref
 %type <statement>        role_stmt grant_role revoke_role
 %type <statement>        function_stmt create_function drop_function execute_function
 
 %type <keyspaceRef>      keyspace_ref
 %type <pairs>            values values_list next_values
-%type <expr>             key_expr opt_value_expr value_expr
+%type <expr>             key_expr value_expr options_expr
+%type <pair>             key_val_expr key_val_options_expr key_val_options_expr_header
 %type <projection>       returns returning opt_returning
 %type <set>              set
 %type <setTerm>          set_term
@@ -444,7 +461,7 @@ tokOffset	 int
 %type <indexKeyTerms>    index_terms
 %type <expr>             expr_input all_expr
 
-%type <exprs>            update_stat_terms
+%type <exprs>            update_stat_terms opt_update_stat_terms
 %type <expr>             update_stat_term
 
 %type <inferenceType>    opt_infer_using
@@ -515,9 +532,13 @@ infer
 |
 update_statistics
 |
+delete_statistics
+|
 role_stmt
 |
 function_stmt
+|
+transaction_stmt
 ;
 
 advise:
@@ -539,7 +560,12 @@ INDEX
 explain:
 EXPLAIN stmt
 {
-    $$ = algebra.NewExplain($2, yylex.(*lexer).Remainder($<tokOffset>1))
+    $$ = algebra.NewExplain($2, yylex.(*lexer).Remainder($<tokOffset>1), false)
+}
+|
+EXPLAIN ANALYZE stmt
+{
+    $$ = algebra.NewExplain($3, yylex.(*lexer).Remainder($<tokOffset>2), true)
 }
 ;
 
@@ -681,6 +707,10 @@ merge
 
 ddl_stmt:
 index_stmt
+|
+scope_stmt
+|
+collection_stmt
 ;
 
 role_stmt:
@@ -699,6 +729,18 @@ alter_index
 build_index
 ;
 
+scope_stmt:
+create_scope
+|
+drop_scope
+;
+
+collection_stmt:
+create_collection
+|
+drop_collection
+;
+
 function_stmt:
 create_function
 |
@@ -950,7 +992,7 @@ FROM from_term
 ;
 
 from_term:
-simple_from_term
+simple_from_term %prec UNION  /* shift RIGHT for simple_from_term RIGHT JOIN below */
 {
     if $1.JoinHint() != algebra.JOIN_HINT_NONE {
         yylex.Error(fmt.Sprintf("Join hint (USE HASH or USE NL) cannot be specified on the first from term %s", $1.Alias()))
@@ -1026,6 +1068,18 @@ simple_from_term RIGHT opt_outer JOIN simple_from_term ON expr
     $1.SetAnsiJoin()
     $$ = algebra.NewAnsiRightJoin($1, $5, $7)
 }
+|
+from_term RIGHT opt_outer JOIN simple_from_term ON expr
+{
+    $5.SetAnsiJoin()
+    $$ = algebra.NewAnsiOuterJoin($1, algebra.JOIN_RIGHT_OUTER, $5, $7)
+}
+|
+from_term FULL opt_outer JOIN simple_from_term ON expr
+{
+    $5.SetAnsiJoin()
+    $$ = algebra.NewAnsiOuterJoin($1, algebra.JOIN_FULL_OUTER, $5, $7)
+}
 ;
 
 simple_from_term:
@@ -1044,14 +1098,21 @@ expr opt_as_alias opt_use
               if $3.Keys() != nil || $3.Indexes() != nil {
                    yylex.Error("FROM Subquery cannot have USE KEYS or USE INDEX.")
               }
+              if $3.Cache() {
+                   yylex.Error("FROM Subquery cannot have USE CACHE.")
+              }
               $$ = algebra.NewSubqueryTerm(other.Select(), $2, $3.JoinHint())
          case *expression.Identifier:
               ksterm := algebra.NewKeyspaceTerm("", other.Alias(), $2, $3.Keys(), $3.Indexes())
+              ksterm.SetUseCache($3.Cache())
               $$ = algebra.NewExpressionTerm(other, $2, ksterm, other.Parenthesis() == false, $3.JoinHint())
          default:
               if $3.Keys() != nil || $3.Indexes() != nil {
                   yylex.Error("FROM Expression cannot have USE KEYS or USE INDEX.")
               }
+              if $3.Cache() {
+                  yylex.Error("FROM Expression cannot have USE CACHE.")
+              }
               $$ = algebra.NewExpressionTerm(other, $2, nil, false, $3.JoinHint())
      }
 }
@@ -1070,6 +1131,7 @@ keyspace_path opt_as_alias opt_use
     if $3.JoinHint() != algebra.JOIN_HINT_NONE {
         ksterm.SetJoinHint($3.JoinHint())
     }
+    ksterm.SetUseCache($3.Cache())
     $$ = ksterm
 }
 ;
@@ -1159,6 +1221,19 @@ join_hint use_keys
     $1.SetKeys($2.Keys())
     $$ = $1
 }
+|
+cache_hint
+;
+
+cache_hint:
+IDENT
+{
+    if strings.ToUpper($1) != "CACHE" {
+        yylex.Error(fmt.Sprintf("Invalid USE hint %s", $1))
+    }
+    $$ = algebra.NewUse(nil, nil, algebra.JOIN_HINT_NONE)
+    $$.SetCache(true)
+}
 ;
 
 use_keys:
@@ -1230,6 +1305,8 @@ opt_use
 {
     if $1.JoinHint() != algebra.JOIN_HINT_NONE {
         yylex.Error("Keyspace reference cannot have join hint (USE HASH or USE NL) in DELETE or UPDATE statement")
+    } else if $1.Cache() {
+        yylex.Error("Keyspace reference cannot have USE CACHE hint in DELETE or UPDATE statement")
     }
     $$ = $1
 }
@@ -1328,6 +1405,14 @@ WITH with_list
 {
     $$ = $2
 }
+|
+WITH RECURSIVE with_list
+{
+    for _, b := range $3 {
+        b.SetRecursive(true)
+    }
+    $$ = $3
+}
 ;
 
 with_list:
@@ -1422,6 +1507,50 @@ expr opt_as_alias
 {
     $$ = algebra.NewGroupTerm($1, $2)
 }
+|
+ROLLUP LPAREN exprs RPAREN
+{
+    $$ = algebra.NewRollupTerm($3)
+}
+|
+CUBE LPAREN exprs RPAREN
+{
+    $$ = algebra.NewCubeTerm($3)
+}
+|
+GROUPING SETS LPAREN grouping_sets RPAREN
+{
+    $$ = algebra.NewGroupingSetsTerm($4)
+}
+;
+
+grouping_sets:
+grouping_set
+{
+    $$ = []expression.Expressions{$1}
+}
+|
+grouping_sets COMMA grouping_set
+{
+    $$ = append($1, $3)
+}
+;
+
+grouping_set:
+LPAREN RPAREN
+{
+    $$ = expression.Expressions{}
+}
+|
+LPAREN expr COMMA exprs RPAREN
+{
+    $$ = append(expression.Expressions{$2}, $4...)
+}
+|
+expr
+{
+    $$ = expression.Expressions{$1}
+}
 ;
 
 opt_letting:
@@ -1599,9 +1728,9 @@ INSERT INTO keyspace_ref opt_values_header values_list opt_returning
     $$ = algebra.NewInsertValues($3, $5, $6)
 }
 |
-INSERT INTO keyspace_ref LPAREN key_expr opt_value_expr RPAREN fullselect opt_returning
+INSERT INTO keyspace_ref LPAREN key_val_options_expr_header RPAREN fullselect opt_returning
 {
-    $$ = algebra.NewInsertSelect($3, $5, $6, $8, $9)
+    $$ = algebra.NewInsertSelect($3, $5.Key, $5.Value, $5.Options, $7, $8)
 }
 ;
 
@@ -1611,6 +1740,11 @@ namespace_term keyspace_name opt_as_alias
     $$ = algebra.NewKeyspaceRef($1, $2, $3)
 }
 |
+namespace_term bucket_name DOT scope_name DOT keyspace_name opt_as_alias
+{
+    $$ = algebra.NewKeyspaceRefFromPath(algebra.NewPathLong($1, $2, $4, $6), $7)
+}
+|
 keyspace_name opt_as_alias
 {
     $$ = algebra.NewKeyspaceRef("", $1, $2)
@@ -1623,6 +1757,20 @@ opt_values_header:
 LPAREN KEY COMMA VALUE RPAREN
 |
 LPAREN PRIMARY KEY COMMA VALUE RPAREN
+|
+LPAREN KEY COMMA VALUE COMMA IDENT RPAREN
+{
+    if !strings.EqualFold($6, "options") {
+        yylex.Error(fmt.Sprintf("Invalid VALUES header %s, expected OPTIONS.", $6))
+    }
+}
+|
+LPAREN PRIMARY KEY COMMA VALUE COMMA IDENT RPAREN
+{
+    if !strings.EqualFold($7, "options") {
+        yylex.Error(fmt.Sprintf("Invalid VALUES header %s, expected OPTIONS.", $7))
+    }
+}
 ;
 
 key:
@@ -1645,6 +1793,11 @@ VALUES LPAREN expr COMMA expr RPAREN
 {
     $$ = algebra.Pairs{&algebra.Pair{Key: $3, Value: $5}}
 }
+|
+VALUES LPAREN expr COMMA expr COMMA expr RPAREN
+{
+    $$ = algebra.Pairs{&algebra.Pair{Key: $3, Value: $5, Options: $7}}
+}
 ;
 
 next_values:
@@ -1654,6 +1807,11 @@ LPAREN expr COMMA expr RPAREN
 {
     $$ = algebra.Pairs{&algebra.Pair{Key: $2, Value: $4}}
 }
+|
+LPAREN expr COMMA expr COMMA expr RPAREN
+{
+    $$ = algebra.Pairs{&algebra.Pair{Key: $2, Value: $4, Options: $6}}
+}
 ;
 
 opt_returning:
@@ -1691,23 +1849,52 @@ key expr
 }
 ;
 
-opt_value_expr:
-/* empty */
+value_expr:
+COMMA VALUE expr
 {
-    $$ = nil
+    $$ = $3
+}
+;
+
+options_expr:
+COMMA IDENT expr
+{
+    if !strings.EqualFold($2, "options") {
+        yylex.Error(fmt.Sprintf("Invalid %s, expected OPTIONS.", $2))
+    }
+    $$ = $3
+}
+;
+
+key_val_expr:
+key_expr value_expr
+{
+    $$ = algebra.NewPair($1, $2)
 }
+;
+
+key_val_options_expr:
+key_val_expr
 |
-value_expr
+key_val_expr options_expr
 {
     $$ = $1
+    $$.Options = $2
 }
 ;
 
-value_expr:
-COMMA VALUE expr
+key_val_options_expr_header:
+key_expr
 {
-    $$ = $3
+    $$ = algebra.NewPair($1, nil)
 }
+|
+key_expr options_expr
+{
+    $$ = &algebra.Pair{Key: $1, Options: $2}
+}
+|
+key_val_options_expr
 ;
 
 
@@ -1723,9 +1910,9 @@ UPSERT INTO keyspace_ref opt_values_header values_list opt_returning
     $$ = algebra.NewUpsertValues($3, $5, $6)
 }
 |
-UPSERT INTO keyspace_ref LPAREN key_expr opt_value_expr RPAREN fullselect opt_returning
+UPSERT INTO keyspace_ref LPAREN key_val_options_expr_header RPAREN fullselect opt_returning
 {
-    $$ = algebra.NewUpsertSelect($3, $5, $6, $8, $9)
+    $$ = algebra.NewUpsertSelect($3, $5.Key, $5.Value, $5.Options, $7, $8)
 }
 ;
 
@@ -1791,6 +1978,19 @@ path EQ expr opt_update_for
 {
     $$ = algebra.NewSetTerm($1, $3, $4)
 }
+|
+function_name LPAREN opt_exprs RPAREN DOT IDENT EQ expr
+{
+    if !strings.EqualFold($1, "meta") || len($3) > 1 {
+        yylex.Error(fmt.Sprintf("Invalid SET term %s().%s, only META().expiration can be set.", $1, $6))
+    }
+    field := $6
+    if strings.EqualFold(field, datastore.OPT_EXPIRATION) {
+        field = datastore.OPT_EXPIRATION
+    }
+    path := expression.NewField(expression.NewMeta($3...), expression.NewFieldName(field, false))
+    $$ = algebra.NewSetTerm(path, $8, nil)
+}
 ;
 
 opt_update_for:
@@ -1932,6 +2132,8 @@ opt_use
         yylex.Error("Keyspace reference cannot have USE KEYS hint in MERGE statement.")
     } else if $1.JoinHint() != algebra.JOIN_HINT_NONE {
         yylex.Error("Keyspace reference cannot have join hint (USE HASH or USE NL)in MERGE statement.")
+    } else if $1.Cache() {
+        yylex.Error("Keyspace reference cannot have USE CACHE hint in MERGE statement.")
     }
     $$ = $1
 }
@@ -2027,17 +2229,22 @@ opt_where
 merge_insert:
 expr opt_where
 {
-    $$ = algebra.NewMergeInsert(nil, $1, $2)
+    $$ = algebra.NewMergeInsert(nil, $1, nil, $2)
 }
 |
 LPAREN expr COMMA expr RPAREN opt_where
 {
-    $$ = algebra.NewMergeInsert($2, $4, $6)
+    $$ = algebra.NewMergeInsert($2, $4, nil, $6)
+}
+|
+LPAREN expr COMMA expr COMMA expr RPAREN opt_where
+{
+    $$ = algebra.NewMergeInsert($2, $4, $6, $8)
 }
 |
-LPAREN key_expr value_expr RPAREN opt_where
+LPAREN key_val_options_expr RPAREN opt_where
 {
-    $$ = algebra.NewMergeInsert($2, $3, $5)
+    $$ = algebra.NewMergeInsert($2.Key, $2.Value, $2.Options, $4)
 }
 ;
 
@@ -2368,6 +2575,82 @@ BUILD INDEX ON named_keyspace_ref LPAREN exprs RPAREN opt_index_using
 }
 ;
 
+/*************************************************
+ *
+ * CREATE SCOPE
+ *
+ *************************************************/
+
+create_scope:
+CREATE SCOPE named_scope_ref
+{
+    $$ = algebra.NewCreateScope($3)
+}
+;
+
+named_scope_ref:
+bucket_name DOT scope_name
+{
+    $$ = algebra.NewPathScope("", $1, $3)
+}
+|
+namespace_name bucket_name DOT scope_name
+{
+    $$ = algebra.NewPathScope($1, $2, $4)
+}
+;
+
+/*************************************************
+ *
+ * DROP SCOPE
+ *
+ *************************************************/
+
+drop_scope:
+DROP SCOPE named_scope_ref
+{
+    $$ = algebra.NewDropScope($3)
+}
+;
+
+/*************************************************
+ *
+ * CREATE COLLECTION
+ *
+ *************************************************/
+
+create_collection:
+CREATE COLLECTION named_collection_ref
+{
+    $$ = algebra.NewCreateCollection($3)
+}
+;
+
+named_collection_ref:
+bucket_name DOT scope_name DOT keyspace_name
+{
+    $$ = algebra.NewPathLong("", $1, $3, $5)
+}
+|
+namespace_name bucket_name DOT scope_name DOT keyspace_name
+{
+    $$ = algebra.NewPathLong($1, $2, $4, $6)
+}
+;
+
+/*************************************************
+ *
+ * DROP COLLECTION
+ *
+ *************************************************/
+
+drop_collection:
+DROP COLLECTION named_collection_ref
+{
+    $$ = algebra.NewDropCollection($3)
+}
+;
+
 /*************************************************
  *
  * CREATE FUNCTION
@@ -2487,6 +2770,20 @@ LANGUAGE JAVASCRIPT AS LBRACE STR COMMA STR RBRACE
         $$ = body
     }
 }
+|
+LANGUAGE JAVASCRIPT AS STR IDENT STR
+{
+    if !strings.EqualFold($5, "at") {
+        yylex.Error(fmt.Sprintf("Invalid JAVASCRIPT function body %s, expected AT.", $5))
+    } else {
+        body, err := javascript.NewJavascriptBody($6, $4)
+        if err != nil {
+            yylex.Error(err.Error())
+        } else {
+            $$ = body
+        }
+    }
+}
 ;
 
 /*************************************************
@@ -2550,6 +2847,98 @@ update_stat_term:
 index_term_expr
 ;
 
+opt_update_stat_terms:
+/* empty */
+{
+    $$ = nil
+}
+|
+LPAREN update_stat_terms RPAREN
+{
+    $$ = $2
+}
+;
+
+/*************************************************
+ *
+ * DELETE STATISTICS
+ *
+ *************************************************/
+
+delete_statistics:
+DELETE STATISTICS opt_for named_keyspace_ref opt_update_stat_terms
+{
+    $$ = algebra.NewDeleteStatistics($4, $5)
+}
+;
+
+/*************************************************
+ *
+ * Transactions
+ *
+ *************************************************/
+
+transaction_stmt:
+start_transaction
+|
+commit_transaction
+|
+rollback_transaction
+|
+savepoint
+;
+
+start_transaction:
+start_or_begin transaction
+{
+    $$ = algebra.NewStartTransaction()
+}
+;
+
+commit_transaction:
+COMMIT opt_transaction
+{
+    $$ = algebra.NewCommitTransaction()
+}
+;
+
+rollback_transaction:
+ROLLBACK opt_transaction
+{
+    $$ = algebra.NewRollbackTransaction("")
+}
+|
+ROLLBACK opt_transaction TO SAVEPOINT IDENT
+{
+    $$ = algebra.NewRollbackTransaction($5)
+}
+;
+
+savepoint:
+SAVEPOINT IDENT
+{
+    $$ = algebra.NewSavepoint($2)
+}
+;
+
+start_or_begin:
+START
+|
+BEGIN
+;
+
+transaction:
+WORK
+|
+TRANSACTION
+;
+
+opt_transaction:
+/* empty */
+|
+transaction
+;
+
 /*************************************************
  *
  * Path
@@ -3186,6 +3575,11 @@ ELSE expr
  *************************************************/
 
 function_expr:
+GROUPING LPAREN exprs RPAREN
+{
+    $$ = expression.NewGrouping($3...)
+}
+|
 NTH_VALUE LPAREN exprs RPAREN opt_from_first_last opt_nulls_treatment window_function_details
 {
     $$ = nil
@@ -3221,7 +3615,9 @@ function_name LPAREN opt_exprs RPAREN opt_filter opt_nulls_treatment opt_window_
     }
 
     if ok {
-        if ($6 == algebra.AGGREGATE_RESPECTNULLS && !algebra.AggregateHasProperty($1, algebra.AGGREGATE_WINDOW_RESPECTNULLS)) ||
+        if algebra.AggregateHasProperty($1, algebra.AGGREGATE_WITHIN_GROUP) {
+            yylex.Error(fmt.Sprintf("WITHIN GROUP clause is required for function %s.", $1))
+        } else if ($6 == algebra.AGGREGATE_RESPECTNULLS && !algebra.AggregateHasProperty($1, algebra.AGGREGATE_WINDOW_RESPECTNULLS)) ||
            ($6 == algebra.AGGREGATE_IGNORENULLS && !algebra.AggregateHasProperty($1, algebra.AGGREGATE_WINDOW_IGNORENULLS)) {
             yylex.Error(fmt.Sprintf("RESPECT|IGNORE NULLS syntax is not valid for function %s.", $1))
         } else if ($5 != nil && !algebra.AggregateHasProperty($1, algebra.AGGREGATE_ALLOWS_FILTER)) {
@@ -3258,16 +3654,89 @@ function_name LPAREN opt_exprs RPAREN opt_filter opt_nulls_treatment opt_window_
     }
 }
 |
-function_name LPAREN agg_quantifier expr RPAREN opt_filter opt_window_function
+function_name LPAREN exprs order_by RPAREN opt_filter opt_window_function
 {
-    agg, ok := algebra.GetAggregate($1, $3 == algebra.AGGREGATE_DISTINCT, ($6 != nil), ($7 != nil))
-    if ok {
-        $$ = agg.Constructor()($4)
-        if a, ok := $$.(algebra.Aggregate); ok {
-             a.SetAggregateModifiers($3, $6, $7)
+    $$ = nil
+    if !algebra.AggregateHasProperty($1, algebra.AGGREGATE_ALLOWS_ORDER_BY) {
+        yylex.Error(fmt.Sprintf("ORDER BY clause is not valid for function %s.", $1))
+    } else {
+        agg, ok := algebra.GetAggregate($1, false, ($6 != nil), ($7 != nil))
+        if !ok {
+            yylex.Error(fmt.Sprintf("Invalid aggregate function %s.", $1))
+        } else if len($3) < agg.MinArgs() || len($3) > agg.MaxArgs() {
+            yylex.Error(fmt.Sprintf("Number of arguments to function %s must be between %d and %d.", $1, agg.MinArgs(), agg.MaxArgs()))
+        } else {
+            $$ = agg.Constructor()($3...)
+            if a, ok := $$.(algebra.Aggregate); ok {
+                 a.SetAggregateModifiers(uint32(0), $6, $7)
+                 a.SetOrderBy($4)
+            }
+        }
+    }
+}
+|
+function_name LPAREN opt_exprs RPAREN WITHIN_GROUP LPAREN order_by RPAREN opt_filter opt_window_function
+{
+    $$ = nil
+    if algebra.AggregateHasProperty($1, algebra.AGGREGATE_WITHIN_GROUP) {
+        terms := $7.Terms()
+        if len($3) != 1 {
+            yylex.Error(fmt.Sprintf("Number of arguments to function %s must be 1.", $1))
+        } else if len(terms) != 1 {
+            yylex.Error(fmt.Sprintf("WITHIN GROUP clause of function %s must have one ORDER BY term.", $1))
+        } else {
+            agg, ok := algebra.GetAggregate($1, false, ($9 != nil), ($10 != nil))
+            if ok {
+                $$ = agg.Constructor()(terms[0].Expression(), $3[0])
+                if a, ok := $$.(algebra.Aggregate); ok {
+                     flags := uint32(0)
+                     if terms[0].Descending() {
+                         flags = algebra.AGGREGATE_DESCENDING
+                     }
+                     a.SetAggregateModifiers(flags, $9, $10)
+                }
+            } else {
+                yylex.Error(fmt.Sprintf("Invalid aggregate function %s.", $1))
+            }
+        }
+    } else if algebra.AggregateHasProperty($1, algebra.AGGREGATE_ALLOWS_ORDER_BY) {
+        agg, ok := algebra.GetAggregate($1, false, ($9 != nil), ($10 != nil))
+        if !ok {
+            yylex.Error(fmt.Sprintf("Invalid aggregate function %s.", $1))
+        } else if len($3) < agg.MinArgs() || len($3) > agg.MaxArgs() {
+            yylex.Error(fmt.Sprintf("Number of arguments to function %s must be between %d and %d.", $1, agg.MinArgs(), agg.MaxArgs()))
+        } else {
+            $$ = agg.Constructor()($3...)
+            if a, ok := $$.(algebra.Aggregate); ok {
+                 a.SetAggregateModifiers(uint32(0), $9, $10)
+                 a.SetOrderBy($7)
+            }
         }
     } else {
+        yylex.Error(fmt.Sprintf("WITHIN GROUP clause is not valid for function %s.", $1))
+    }
+}
+|
+function_name LPAREN agg_quantifier exprs opt_order_by RPAREN opt_filter opt_window_function
+{
+    $$ = nil
+    agg, ok := algebra.GetAggregate($1, $3 == algebra.AGGREGATE_DISTINCT, ($7 != nil), ($8 != nil))
+    if !ok {
         yylex.Error(fmt.Sprintf("Invalid aggregate function %s.", $1))
+    } else if $5 != nil && !algebra.AggregateHasProperty($1, algebra.AGGREGATE_ALLOWS_ORDER_BY) {
+        yylex.Error(fmt.Sprintf("ORDER BY clause is not valid for function %s.", $1))
+    } else if len($4) < agg.MinArgs() || len($4) > agg.MaxArgs() {
+        if agg.MinArgs() == agg.MaxArgs() {
+            yylex.Error(fmt.Sprintf("Number of arguments to function %s must be %d.", $1, agg.MaxArgs()))
+        } else {
+            yylex.Error(fmt.Sprintf("Number of arguments to function %s must be between %d and %d.", $1, agg.MinArgs(), agg.MaxArgs()))
+        }
+    } else {
+        $$ = agg.Constructor()($4...)
+        if a, ok := $$.(algebra.Aggregate); ok {
+             a.SetAggregateModifiers($3, $7, $8)
+             a.SetOrderBy($5)
+        }
     }
 }
 |
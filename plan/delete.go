@@ -60,6 +60,7 @@ func (this *SendDelete) MarshalBase(f func(map[string]interface{})) map[string]i
 	r := map[string]interface{}{"#operator": "SendDelete"}
 	r["namespace"] = this.keyspace.NamespaceId()
 	r["keyspace"] = this.keyspace.Name()
+	marshalKeyspacePath(r, this.keyspace)
 	r["alias"] = this.alias
 
 	if this.limit != nil {
@@ -74,11 +75,13 @@ func (this *SendDelete) MarshalBase(f func(map[string]interface{})) map[string]i
 
 func (this *SendDelete) UnmarshalJSON(body []byte) error {
 	var _unmarshalled struct {
-		_     string `json:"#operator"`
-		Names string `json:"namespace"`
-		Keys  string `json:"keyspace"`
-		Alias string `json:"alias"`
-		Limit string `json:"limit"`
+		_      string `json:"#operator"`
+		Names  string `json:"namespace"`
+		Keys   string `json:"keyspace"`
+		Bucket string `json:"bucket"`
+		Scope  string `json:"scope"`
+		Alias  string `json:"alias"`
+		Limit  string `json:"limit"`
 	}
 
 	err := json.Unmarshal(body, &_unmarshalled)
@@ -95,7 +98,7 @@ func (this *SendDelete) UnmarshalJSON(body []byte) error {
 		}
 	}
 
-	this.keyspace, err = datastore.GetKeyspace(_unmarshalled.Names, _unmarshalled.Keys)
+	this.keyspace, err = getKeyspace(_unmarshalled.Names, _unmarshalled.Bucket, _unmarshalled.Scope, _unmarshalled.Keys)
 
 	return err
 }
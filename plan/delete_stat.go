@@ -0,0 +1,114 @@
+//  Copyright (c) 2019 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package plan
+
+import (
+	"encoding/json"
+
+	"github.com/couchbase/query/algebra"
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/expression/parser"
+)
+
+// Delete Statistics
+type DeleteStatistics struct {
+	readonly
+	keyspace datastore.Keyspace
+	node     *algebra.DeleteStatistics
+}
+
+func NewDeleteStatistics(keyspace datastore.Keyspace, node *algebra.DeleteStatistics) *DeleteStatistics {
+	return &DeleteStatistics{
+		keyspace: keyspace,
+		node:     node,
+	}
+}
+
+func (this *DeleteStatistics) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitDeleteStatistics(this)
+}
+
+func (this *DeleteStatistics) New() Operator {
+	return &DeleteStatistics{}
+}
+
+func (this *DeleteStatistics) Keyspace() datastore.Keyspace {
+	return this.keyspace
+}
+
+func (this *DeleteStatistics) Node() *algebra.DeleteStatistics {
+	return this.node
+}
+
+func (this *DeleteStatistics) MarshalJSON() ([]byte, error) {
+	return json.Marshal(this.MarshalBase(nil))
+}
+
+func (this *DeleteStatistics) MarshalBase(f func(map[string]interface{})) map[string]interface{} {
+	r := map[string]interface{}{"#operator": "DeleteStatistics"}
+	r["keyspace"] = this.keyspace.Name()
+	r["namespace"] = this.keyspace.NamespaceId()
+
+	if len(this.node.Terms()) > 0 {
+		terms := make([]interface{}, 0, len(this.node.Terms()))
+		for _, term := range this.node.Terms() {
+			terms = append(terms, expression.NewStringer().Visit(term))
+		}
+		r["terms"] = terms
+	}
+
+	if f != nil {
+		f(r)
+	}
+	return r
+}
+
+func (this *DeleteStatistics) UnmarshalJSON(body []byte) error {
+	var _unmarshalled struct {
+		_      string   `json:"#operator"`
+		Keysp  string   `json:"keyspace"`
+		Namesp string   `json:"namespace"`
+		Terms  []string `json:"terms"`
+	}
+
+	err := json.Unmarshal(body, &_unmarshalled)
+	if err != nil {
+		return err
+	}
+
+	this.keyspace, err = datastore.GetKeyspace(_unmarshalled.Namesp, _unmarshalled.Keysp)
+	if err != nil {
+		return err
+	}
+
+	ksref := algebra.NewKeyspaceRef(_unmarshalled.Namesp, _unmarshalled.Keysp, "")
+
+	var terms expression.Expressions
+	if len(_unmarshalled.Terms) > 0 {
+		terms = make(expression.Expressions, len(_unmarshalled.Terms))
+		for i, term := range _unmarshalled.Terms {
+			terms[i], err = parser.Parse(term)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	this.node = algebra.NewDeleteStatistics(ksref, terms)
+	return nil
+}
+
+func (this *DeleteStatistics) verify(prepared *Prepared) bool {
+	var res bool
+
+	this.keyspace, res = verifyKeyspace(this.keyspace, prepared)
+	return res
+}
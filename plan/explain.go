@@ -15,14 +15,16 @@ import (
 
 type Explain struct {
 	readonly
-	op   Operator
-	text string
+	op      Operator
+	text    string
+	analyze bool
 }
 
-func NewExplain(op Operator, text string) *Explain {
+func NewExplain(op Operator, text string, analyze bool) *Explain {
 	return &Explain{
-		op:   op,
-		text: text,
+		op:      op,
+		text:    text,
+		analyze: analyze,
 	}
 }
 
@@ -38,6 +40,19 @@ func (this *Explain) Operator() Operator {
 	return this.op
 }
 
+func (this *Explain) Analyze() bool {
+	return this.analyze
+}
+
+// EXPLAIN ANALYZE executes the statement
+func (this *Explain) Readonly() bool {
+	return !this.analyze || this.op.Readonly()
+}
+
+func (this *Explain) verify(prepared *Prepared) bool {
+	return !this.analyze || this.op.verify(prepared)
+}
+
 func (this *Explain) MarshalJSON() ([]byte, error) {
 	return json.Marshal(this.MarshalBase(nil))
 }
@@ -46,6 +61,9 @@ func (this *Explain) MarshalBase(f func(map[string]interface{})) map[string]inte
 	r := make(map[string]interface{}, 2)
 	r["plan"] = this.op
 	r["text"] = this.text
+	if this.analyze {
+		r["analyze"] = this.analyze
+	}
 	if f != nil {
 		f(r)
 	} else {
@@ -56,8 +74,9 @@ func (this *Explain) MarshalBase(f func(map[string]interface{})) map[string]inte
 
 func (this *Explain) UnmarshalJSON(body []byte) error {
 	var _unmarshalled struct {
-		Op   json.RawMessage `json:"plan"`
-		Text string          `json:"text"`
+		Op      json.RawMessage `json:"plan"`
+		Text    string          `json:"text"`
+		Analyze bool            `json:"analyze"`
 	}
 
 	var op_type struct {
@@ -75,6 +94,7 @@ func (this *Explain) UnmarshalJSON(body []byte) error {
 	}
 
 	this.text = _unmarshalled.Text
+	this.analyze = _unmarshalled.Analyze
 
 	err = json.Unmarshal(_unmarshalled.Op, &op_type)
 	if err != nil {
@@ -63,6 +63,7 @@ func (this *Fetch) MarshalBase(f func(map[string]interface{})) map[string]interf
 	r := map[string]interface{}{"#operator": "Fetch"}
 	r["namespace"] = this.term.Namespace()
 	r["keyspace"] = this.term.Keyspace()
+	marshalTermPath(r, this.term)
 	if len(this.subPaths) > 0 {
 		r["subpaths"] = this.subPaths
 	}
@@ -93,6 +94,8 @@ func (this *Fetch) UnmarshalJSON(body []byte) error {
 		_           string   `json:"#operator"`
 		Names       string   `json:"namespace"`
 		Keys        string   `json:"keyspace"`
+		Bucket      string   `json:"bucket"`
+		Scope       string   `json:"scope"`
 		As          string   `json:"as"`
 		UnderNL     bool     `json:"nested_loop"`
 		Cost        float64  `json:"cost"`
@@ -110,11 +113,11 @@ func (this *Fetch) UnmarshalJSON(body []byte) error {
 	this.cost = getCost(_unmarshalled.Cost)
 	this.cardinality = getCardinality(_unmarshalled.Cardinality)
 
-	this.term = algebra.NewKeyspaceTerm(_unmarshalled.Names, _unmarshalled.Keys, _unmarshalled.As, nil, nil)
+	this.term = newKeyspaceTerm(_unmarshalled.Names, _unmarshalled.Bucket, _unmarshalled.Scope, _unmarshalled.Keys, _unmarshalled.As)
 	if _unmarshalled.UnderNL {
 		this.term.SetUnderNL()
 	}
-	this.keyspace, err = datastore.GetKeyspace(_unmarshalled.Names, _unmarshalled.Keys)
+	this.keyspace, err = getKeyspace(_unmarshalled.Names, _unmarshalled.Bucket, _unmarshalled.Scope, _unmarshalled.Keys)
 	return err
 }
 
@@ -182,6 +185,7 @@ func (this *DummyFetch) MarshalBase(f func(map[string]interface{})) map[string]i
 	r := map[string]interface{}{"#operator": "DummyFetch"}
 	r["namespace"] = this.term.Namespace()
 	r["keyspace"] = this.term.Keyspace()
+	marshalTermPath(r, this.term)
 	if this.term.As() != "" {
 		r["as"] = this.term.As()
 	}
@@ -208,6 +212,8 @@ func (this *DummyFetch) UnmarshalJSON(body []byte) error {
 		_           string  `json:"#operator"`
 		Names       string  `json:"namespace"`
 		Keys        string  `json:"keyspace"`
+		Bucket      string  `json:"bucket"`
+		Scope       string  `json:"scope"`
 		As          string  `json:"as"`
 		UnderNL     bool    `json:"nested_loop"`
 		Cost        float64 `json:"cost"`
@@ -222,10 +228,10 @@ func (this *DummyFetch) UnmarshalJSON(body []byte) error {
 	this.cost = getCost(_unmarshalled.Cost)
 	this.cardinality = getCardinality(_unmarshalled.Cardinality)
 
-	this.term = algebra.NewKeyspaceTerm(_unmarshalled.Names, _unmarshalled.Keys, _unmarshalled.As, nil, nil)
+	this.term = newKeyspaceTerm(_unmarshalled.Names, _unmarshalled.Bucket, _unmarshalled.Scope, _unmarshalled.Keys, _unmarshalled.As)
 	if _unmarshalled.UnderNL {
 		this.term.SetUnderNL()
 	}
-	this.keyspace, err = datastore.GetKeyspace(_unmarshalled.Names, _unmarshalled.Keys)
+	this.keyspace, err = getKeyspace(_unmarshalled.Names, _unmarshalled.Bucket, _unmarshalled.Scope, _unmarshalled.Keys)
 	return err
 }
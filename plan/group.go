@@ -22,12 +22,14 @@ type InitialGroup struct {
 	readonly
 	keys       expression.Expressions
 	aggregates algebra.Aggregates
+	sets       [][]int
 }
 
-func NewInitialGroup(keys expression.Expressions, aggregates algebra.Aggregates) *InitialGroup {
+func NewInitialGroup(keys expression.Expressions, aggregates algebra.Aggregates, sets [][]int) *InitialGroup {
 	return &InitialGroup{
 		keys:       keys,
 		aggregates: aggregates,
+		sets:       sets,
 	}
 }
 
@@ -47,6 +49,10 @@ func (this *InitialGroup) Aggregates() algebra.Aggregates {
 	return this.aggregates
 }
 
+func (this *InitialGroup) GroupingSets() [][]int {
+	return this.sets
+}
+
 func (this *InitialGroup) MarshalJSON() ([]byte, error) {
 	return json.Marshal(this.MarshalBase(nil))
 }
@@ -58,6 +64,9 @@ func (this *InitialGroup) MarshalBase(f func(map[string]interface{})) map[string
 		keylist = append(keylist, expression.NewStringer().Visit(key))
 	}
 	r["group_keys"] = keylist
+	if this.sets != nil {
+		r["grouping_sets"] = this.sets
+	}
 	s := make([]interface{}, 0, len(this.aggregates))
 	for _, agg := range this.aggregates {
 		s = append(s, expression.NewStringer().Visit(agg))
@@ -74,6 +83,7 @@ func (this *InitialGroup) UnmarshalJSON(body []byte) error {
 		_    string   `json:"#operator"`
 		Keys []string `json:"group_keys"`
 		Aggs []string `json:"aggregates"`
+		Sets [][]int  `json:"grouping_sets"`
 	}
 
 	err := json.Unmarshal(body, &_unmarshalled)
@@ -99,6 +109,7 @@ func (this *InitialGroup) UnmarshalJSON(body []byte) error {
 		this.aggregates[i], _ = agg_expr.(algebra.Aggregate)
 	}
 
+	this.sets = _unmarshalled.Sets
 	return nil
 }
 
@@ -107,12 +118,14 @@ type IntermediateGroup struct {
 	readonly
 	keys       expression.Expressions
 	aggregates algebra.Aggregates
+	sets       [][]int
 }
 
-func NewIntermediateGroup(keys expression.Expressions, aggregates algebra.Aggregates) *IntermediateGroup {
+func NewIntermediateGroup(keys expression.Expressions, aggregates algebra.Aggregates, sets [][]int) *IntermediateGroup {
 	return &IntermediateGroup{
 		keys:       keys,
 		aggregates: aggregates,
+		sets:       sets,
 	}
 }
 
@@ -132,6 +145,10 @@ func (this *IntermediateGroup) Aggregates() algebra.Aggregates {
 	return this.aggregates
 }
 
+func (this *IntermediateGroup) GroupingSets() [][]int {
+	return this.sets
+}
+
 func (this *IntermediateGroup) MarshalJSON() ([]byte, error) {
 	return json.Marshal(this.MarshalBase(nil))
 }
@@ -143,6 +160,9 @@ func (this *IntermediateGroup) MarshalBase(f func(map[string]interface{})) map[s
 		keylist = append(keylist, expression.NewStringer().Visit(key))
 	}
 	r["group_keys"] = keylist
+	if this.sets != nil {
+		r["grouping_sets"] = this.sets
+	}
 	s := make([]interface{}, 0, len(this.aggregates))
 	for _, agg := range this.aggregates {
 		s = append(s, expression.NewStringer().Visit(agg))
@@ -159,6 +179,7 @@ func (this *IntermediateGroup) UnmarshalJSON(body []byte) error {
 		_    string   `json:"#operator"`
 		Keys []string `json:"group_keys"`
 		Aggs []string `json:"aggregates"`
+		Sets [][]int  `json:"grouping_sets"`
 	}
 
 	err := json.Unmarshal(body, &_unmarshalled)
@@ -184,6 +205,7 @@ func (this *IntermediateGroup) UnmarshalJSON(body []byte) error {
 		this.aggregates[i], _ = agg_expr.(algebra.Aggregate)
 	}
 
+	this.sets = _unmarshalled.Sets
 	return nil
 }
 
@@ -192,12 +214,14 @@ type FinalGroup struct {
 	readonly
 	keys       expression.Expressions
 	aggregates algebra.Aggregates
+	sets       [][]int
 }
 
-func NewFinalGroup(keys expression.Expressions, aggregates algebra.Aggregates) *FinalGroup {
+func NewFinalGroup(keys expression.Expressions, aggregates algebra.Aggregates, sets [][]int) *FinalGroup {
 	return &FinalGroup{
 		keys:       keys,
 		aggregates: aggregates,
+		sets:       sets,
 	}
 }
 
@@ -217,6 +241,10 @@ func (this *FinalGroup) Aggregates() algebra.Aggregates {
 	return this.aggregates
 }
 
+func (this *FinalGroup) GroupingSets() [][]int {
+	return this.sets
+}
+
 func (this *FinalGroup) MarshalJSON() ([]byte, error) {
 	return json.Marshal(this.MarshalBase(nil))
 }
@@ -228,6 +256,9 @@ func (this *FinalGroup) MarshalBase(f func(map[string]interface{})) map[string]i
 		keylist = append(keylist, expression.NewStringer().Visit(key))
 	}
 	r["group_keys"] = keylist
+	if this.sets != nil {
+		r["grouping_sets"] = this.sets
+	}
 	s := make([]interface{}, 0, len(this.aggregates))
 	for _, agg := range this.aggregates {
 		s = append(s, expression.NewStringer().Visit(agg))
@@ -244,6 +275,7 @@ func (this *FinalGroup) UnmarshalJSON(body []byte) error {
 		_    string   `json:"#operator"`
 		Keys []string `json:"group_keys"`
 		Aggs []string `json:"aggregates"`
+		Sets [][]int  `json:"grouping_sets"`
 	}
 
 	err := json.Unmarshal(body, &_unmarshalled)
@@ -269,5 +301,6 @@ func (this *FinalGroup) UnmarshalJSON(body []byte) error {
 		this.aggregates[i], _ = agg_expr.(algebra.Aggregate)
 	}
 
+	this.sets = _unmarshalled.Sets
 	return nil
 }
@@ -15,12 +15,28 @@ import (
 	"encoding/json"
 )
 
+const (
+	NO_INDEX_RECOMMENDATION = "No index recommendation at this time."
+	PRIMARY_INDEX_MAY_APPLY = "No index recommendation at this time, primary index may apply."
+)
+
 type IndexAdvice struct {
 	readonly
+	adviceInfos IndexAdviceInfos
 }
 
-func NewIndexAdvice() *IndexAdvice {
-	return &IndexAdvice{}
+func NewIndexAdvice(adviceInfos IndexAdviceInfos) *IndexAdvice {
+	rv := &IndexAdvice{}
+	rv.adviceInfos = make(IndexAdviceInfos, 0, len(adviceInfos))
+
+	// no empty entries for subqueries, unless there is nothing else
+	for i, info := range adviceInfos {
+		if len(adviceInfos) == 1 || !info.Empty() ||
+			(len(rv.adviceInfos) == 0 && i == len(adviceInfos)-1) {
+			rv.adviceInfos = append(rv.adviceInfos, info)
+		}
+	}
+	return rv
 }
 
 func (this *IndexAdvice) Accept(visitor Visitor) (interface{}, error) {
@@ -35,12 +51,17 @@ func (this *IndexAdvice) Operator() Operator {
 	return this
 }
 
+func (this *IndexAdvice) AdviceInfos() IndexAdviceInfos {
+	return this.adviceInfos
+}
+
 func (this *IndexAdvice) MarshalJSON() ([]byte, error) {
 	return json.Marshal(this.MarshalBase(nil))
 }
 
 func (this *IndexAdvice) MarshalBase(f func(map[string]interface{})) map[string]interface{} {
 	r := map[string]interface{}{"#operator": "IndexAdvice"}
+	r["adviseinfo"] = this.adviceInfos
 
 	if f != nil {
 		f(r)
@@ -50,13 +71,119 @@ func (this *IndexAdvice) MarshalBase(f func(map[string]interface{})) map[string]
 
 func (this *IndexAdvice) UnmarshalJSON(body []byte) error {
 	var _unmarshalled struct {
-		_ string `json:"#operator"`
+		_           string           `json:"#operator"`
+		AdviceInfos IndexAdviceInfos `json:"adviseinfo"`
+	}
+
+	err := json.Unmarshal(body, &_unmarshalled)
+	if err != nil {
+		return err
+	}
+
+	this.adviceInfos = _unmarshalled.AdviceInfos
+	return nil
+}
+
+/*
+The advice for one query block: the indexes the current plan uses,
+and the indexes recommended for it.
+*/
+type IndexAdviceInfo struct {
+	curIndexes      AdvisedIndexes
+	indexes         AdvisedIndexes
+	coverIndexes    AdvisedIndexes
+	primaryMayApply bool
+}
+
+type IndexAdviceInfos []*IndexAdviceInfo
+
+func NewIndexAdviceInfo(curIndexes, indexes, coverIndexes AdvisedIndexes, primaryMayApply bool) *IndexAdviceInfo {
+	return &IndexAdviceInfo{
+		curIndexes:      curIndexes,
+		indexes:         indexes,
+		coverIndexes:    coverIndexes,
+		primaryMayApply: primaryMayApply,
+	}
+}
+
+func (this *IndexAdviceInfo) CurIndexes() AdvisedIndexes {
+	return this.curIndexes
+}
+
+func (this *IndexAdviceInfo) Indexes() AdvisedIndexes {
+	return this.indexes
+}
+
+func (this *IndexAdviceInfo) CoverIndexes() AdvisedIndexes {
+	return this.coverIndexes
+}
+
+func (this *IndexAdviceInfo) Empty() bool {
+	return len(this.curIndexes) == 0 && len(this.indexes) == 0 && len(this.coverIndexes) == 0
+}
+
+func (this *IndexAdviceInfo) MarshalJSON() ([]byte, error) {
+	r := make(map[string]interface{}, 2)
+	if len(this.curIndexes) > 0 {
+		r["current_indexes"] = this.curIndexes
+	}
+
+	if len(this.indexes) > 0 || len(this.coverIndexes) > 0 {
+		recommended := make(map[string]interface{}, 2)
+		if len(this.indexes) > 0 {
+			recommended["indexes"] = this.indexes
+		}
+		if len(this.coverIndexes) > 0 {
+			recommended["covering_indexes"] = this.coverIndexes
+		}
+		r["recommended_indexes"] = recommended
+	} else if this.primaryMayApply {
+		r["recommended_indexes"] = PRIMARY_INDEX_MAY_APPLY
+	} else {
+		r["recommended_indexes"] = NO_INDEX_RECOMMENDATION
+	}
+	return json.Marshal(r)
+}
+
+func (this *IndexAdviceInfo) UnmarshalJSON(body []byte) error {
+	var _unmarshalled struct {
+		CurIndexes  AdvisedIndexes  `json:"current_indexes"`
+		Recommended json.RawMessage `json:"recommended_indexes"`
 	}
 
 	err := json.Unmarshal(body, &_unmarshalled)
 	if err != nil {
 		return err
 	}
+	this.curIndexes = _unmarshalled.CurIndexes
+
+	var message string
+	if json.Unmarshal(_unmarshalled.Recommended, &message) == nil {
+		this.primaryMayApply = message == PRIMARY_INDEX_MAY_APPLY
+		return nil
+	}
 
+	var recommended struct {
+		Indexes      AdvisedIndexes `json:"indexes"`
+		CoverIndexes AdvisedIndexes `json:"covering_indexes"`
+	}
+	err = json.Unmarshal(_unmarshalled.Recommended, &recommended)
+	if err != nil {
+		return err
+	}
+	this.indexes = recommended.Indexes
+	this.coverIndexes = recommended.CoverIndexes
 	return nil
 }
+
+/*
+An index, as its CREATE INDEX statement.
+*/
+type AdvisedIndex struct {
+	Statement     string `json:"index_statement"`
+	KeyspaceAlias string `json:"keyspace_alias"`
+	Rule          string `json:"recommending_rule,omitempty"`
+	Status        string `json:"index_status,omitempty"`
+}
+
+type AdvisedIndexes []*AdvisedIndex
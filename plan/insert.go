@@ -23,16 +23,18 @@ type SendInsert struct {
 	alias    string
 	key      expression.Expression
 	value    expression.Expression
+	options  expression.Expression
 	limit    expression.Expression
 }
 
 func NewSendInsert(keyspace datastore.Keyspace, alias string,
-	key, value, limit expression.Expression) *SendInsert {
+	key, value, options, limit expression.Expression) *SendInsert {
 	return &SendInsert{
 		keyspace: keyspace,
 		alias:    alias,
 		key:      key,
 		value:    value,
+		options:  options,
 		limit:    limit,
 	}
 }
@@ -61,6 +63,10 @@ func (this *SendInsert) Value() expression.Expression {
 	return this.value
 }
 
+func (this *SendInsert) Options() expression.Expression {
+	return this.options
+}
+
 func (this *SendInsert) Limit() expression.Expression {
 	return this.limit
 }
@@ -72,6 +78,7 @@ func (this *SendInsert) MarshalJSON() ([]byte, error) {
 func (this *SendInsert) MarshalBase(f func(map[string]interface{})) map[string]interface{} {
 	r := map[string]interface{}{"#operator": "SendInsert"}
 	r["keyspace"] = this.keyspace.Name()
+	marshalKeyspacePath(r, this.keyspace)
 	r["namespace"] = this.keyspace.NamespaceId()
 	r["alias"] = this.alias
 
@@ -87,6 +94,10 @@ func (this *SendInsert) MarshalBase(f func(map[string]interface{})) map[string]i
 		r["value"] = this.value.String()
 	}
 
+	if this.options != nil {
+		r["options"] = this.options.String()
+	}
+
 	if f != nil {
 		f(r)
 	}
@@ -98,7 +109,10 @@ func (this *SendInsert) UnmarshalJSON(body []byte) error {
 		_         string `json:"#operator"`
 		KeyExpr   string `json:"key"`
 		ValueExpr string `json:"value"`
+		OptsExpr  string `json:"options"`
 		Keys      string `json:"keyspace"`
+		Bucket    string `json:"bucket"`
+		Scope     string `json:"scope"`
 		Names     string `json:"namespace"`
 		Alias     string `json:"alias"`
 		Limit     string `json:"limit"`
@@ -123,6 +137,13 @@ func (this *SendInsert) UnmarshalJSON(body []byte) error {
 		}
 	}
 
+	if _unmarshalled.OptsExpr != "" {
+		this.options, err = parser.Parse(_unmarshalled.OptsExpr)
+		if err != nil {
+			return err
+		}
+	}
+
 	this.alias = _unmarshalled.Alias
 
 	if _unmarshalled.Limit != "" {
@@ -132,7 +153,7 @@ func (this *SendInsert) UnmarshalJSON(body []byte) error {
 		}
 	}
 
-	this.keyspace, err = datastore.GetKeyspace(_unmarshalled.Names, _unmarshalled.Keys)
+	this.keyspace, err = getKeyspace(_unmarshalled.Names, _unmarshalled.Bucket, _unmarshalled.Scope, _unmarshalled.Keys)
 	return err
 }
 
@@ -20,6 +20,7 @@ import (
 type HashJoin struct {
 	readonly
 	outer        bool
+	buildOuter   bool
 	onclause     expression.Expression
 	child        Operator
 	buildExprs   expression.Expressions
@@ -33,7 +34,8 @@ type HashJoin struct {
 func NewHashJoin(join *algebra.AnsiJoin, child Operator, buildExprs, probeExprs expression.Expressions,
 	buildAliases []string, cost, cardinality float64) *HashJoin {
 	return &HashJoin{
-		outer:        join.Outer(),
+		outer:        join.JoinType() != algebra.JOIN_INNER,
+		buildOuter:   join.JoinType() == algebra.JOIN_FULL_OUTER,
 		onclause:     join.Onclause(),
 		child:        child,
 		buildExprs:   buildExprs,
@@ -53,10 +55,16 @@ func (this *HashJoin) New() Operator {
 	return &HashJoin{}
 }
 
+// Whether unmatched documents of the probe side are returned
 func (this *HashJoin) Outer() bool {
 	return this.outer
 }
 
+// Whether unmatched documents of the build side are returned, for FULL OUTER JOIN
+func (this *HashJoin) BuildOuter() bool {
+	return this.buildOuter
+}
+
 func (this *HashJoin) Onclause() expression.Expression {
 	return this.onclause
 }
@@ -101,6 +109,10 @@ func (this *HashJoin) MarshalBase(f func(map[string]interface{})) map[string]int
 		r["outer"] = this.outer
 	}
 
+	if this.buildOuter {
+		r["build_outer"] = this.buildOuter
+	}
+
 	buildList := make([]string, 0, len(this.buildExprs))
 	for _, build := range this.buildExprs {
 		buildList = append(buildList, expression.NewStringer().Visit(build))
@@ -140,6 +152,7 @@ func (this *HashJoin) UnmarshalJSON(body []byte) error {
 		_            string          `json:"#operator"`
 		Onclause     string          `json:"on_clause"`
 		Outer        bool            `json:"outer"`
+		BuildOuter   bool            `json:"build_outer"`
 		BuildExprs   []string        `json:"build_exprs"`
 		ProbeExprs   []string        `json:"probe_exprs"`
 		BuildAliases []string        `json:"build_aliases"`
@@ -162,6 +175,7 @@ func (this *HashJoin) UnmarshalJSON(body []byte) error {
 	}
 
 	this.outer = _unmarshalled.Outer
+	this.buildOuter = _unmarshalled.BuildOuter
 
 	this.buildExprs = make(expression.Expressions, len(_unmarshalled.BuildExprs))
 	for i, build := range _unmarshalled.BuildExprs {
@@ -116,6 +116,7 @@ func (this *IndexJoin) MarshalBase(f func(map[string]interface{})) map[string]in
 	r := map[string]interface{}{"#operator": "IndexJoin"}
 	r["namespace"] = this.term.Namespace()
 	r["keyspace"] = this.term.Keyspace()
+	marshalTermPath(r, this.term)
 	r["on_key"] = expression.NewStringer().Visit(this.term.JoinKeys())
 	r["for"] = this.keyFor
 
@@ -155,14 +156,16 @@ func (this *IndexJoin) MarshalBase(f func(map[string]interface{})) map[string]in
 
 func (this *IndexJoin) UnmarshalJSON(body []byte) error {
 	var _unmarshalled struct {
-		_     string `json:"#operator"`
-		Names string `json:"namespace"`
-		Keys  string `json:"keyspace"`
-		On    string `json:"on_key"`
-		Outer bool   `json:"outer"`
-		As    string `json:"as"`
-		For   string `json:"for"`
-		Scan  struct {
+		_      string `json:"#operator"`
+		Names  string `json:"namespace"`
+		Keys   string `json:"keyspace"`
+		Bucket string `json:"bucket"`
+		Scope  string `json:"scope"`
+		On     string `json:"on_key"`
+		Outer  bool   `json:"outer"`
+		As     string `json:"as"`
+		For    string `json:"for"`
+		Scan   struct {
 			Index        string                 `json:"index"`
 			IndexId      string                 `json:"index_id"`
 			Using        datastore.IndexType    `json:"using"`
@@ -190,9 +193,9 @@ func (this *IndexJoin) UnmarshalJSON(body []byte) error {
 		expression.NewMeta(expression.NewIdentifier(this.keyFor)),
 		expression.NewFieldName("id", false))
 
-	this.term = algebra.NewKeyspaceTerm(_unmarshalled.Names, _unmarshalled.Keys, _unmarshalled.As, nil, nil)
+	this.term = newKeyspaceTerm(_unmarshalled.Names, _unmarshalled.Bucket, _unmarshalled.Scope, _unmarshalled.Keys, _unmarshalled.As)
 	this.term.SetJoinKeys(keys_expr)
-	this.keyspace, err = datastore.GetKeyspace(_unmarshalled.Names, _unmarshalled.Keys)
+	this.keyspace, err = getKeyspace(_unmarshalled.Names, _unmarshalled.Bucket, _unmarshalled.Scope, _unmarshalled.Keys)
 	if err != nil {
 		return err
 	}
@@ -20,6 +20,7 @@ import (
 type NLJoin struct {
 	readonly
 	outer       bool
+	rightOuter  bool
 	alias       string
 	onclause    expression.Expression
 	hintError   string
@@ -31,6 +32,7 @@ type NLJoin struct {
 func NewNLJoin(join *algebra.AnsiJoin, child Operator, cost, cardinality float64) *NLJoin {
 	rv := &NLJoin{
 		outer:       join.Outer(),
+		rightOuter:  join.RightOuter(),
 		alias:       join.Alias(),
 		onclause:    join.Onclause(),
 		hintError:   join.HintError(),
@@ -54,6 +56,12 @@ func (this *NLJoin) Outer() bool {
 	return this.outer
 }
 
+// Whether unmatched documents of the right-hand side are returned, for RIGHT
+// and FULL OUTER JOIN
+func (this *NLJoin) RightOuter() bool {
+	return this.rightOuter
+}
+
 func (this *NLJoin) Alias() string {
 	return this.alias
 }
@@ -91,6 +99,10 @@ func (this *NLJoin) MarshalBase(f func(map[string]interface{})) map[string]inter
 		r["outer"] = this.outer
 	}
 
+	if this.rightOuter {
+		r["right_outer"] = this.rightOuter
+	}
+
 	if this.hintError != "" {
 		r["hint_not_followed"] = this.hintError
 	}
@@ -116,6 +128,7 @@ func (this *NLJoin) UnmarshalJSON(body []byte) error {
 		_           string          `json:"#operator"`
 		Onclause    string          `json:"on_clause"`
 		Outer       bool            `json:"outer"`
+		RightOuter  bool            `json:"right_outer"`
 		Alias       string          `json:"alias"`
 		HintError   string          `json:"hint_not_followed"`
 		Cost        float64         `json:"cost"`
@@ -136,6 +149,7 @@ func (this *NLJoin) UnmarshalJSON(body []byte) error {
 	}
 
 	this.outer = _unmarshalled.Outer
+	this.rightOuter = _unmarshalled.RightOuter
 	this.alias = _unmarshalled.Alias
 	this.hintError = _unmarshalled.HintError
 
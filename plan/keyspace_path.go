@@ -0,0 +1,48 @@
+//  Copyright (c) 2020 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package plan
+
+import (
+	"github.com/couchbase/query/algebra"
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+)
+
+// Collections are marshalled with their bucket and scope, besides the
+// namespace and keyspace, so that unmarshalled plans find them again.
+
+func marshalKeyspacePath(r map[string]interface{}, keyspace datastore.Keyspace) {
+	if scope := keyspace.Scope(); scope != nil {
+		r["bucket"] = scope.BucketId()
+		r["scope"] = scope.Id()
+	}
+}
+
+func marshalTermPath(r map[string]interface{}, term *algebra.KeyspaceTerm) {
+	if path := term.Path(); path != nil && path.IsCollection() {
+		r["bucket"] = path.Bucket()
+		r["scope"] = path.Scope()
+	}
+}
+
+func getKeyspace(namespace, bucket, scope, keyspace string) (datastore.Keyspace, errors.Error) {
+	if bucket == "" {
+		return datastore.GetKeyspace(namespace, keyspace)
+	}
+	return datastore.GetKeyspace(namespace, bucket, scope, keyspace)
+}
+
+func newKeyspaceTerm(namespace, bucket, scope, keyspace, as string) *algebra.KeyspaceTerm {
+	if bucket == "" {
+		return algebra.NewKeyspaceTerm(namespace, keyspace, as, nil, nil)
+	}
+	path := algebra.NewPathLong(namespace, bucket, scope, keyspace)
+	return algebra.NewKeyspaceTermFromPath(path, as, nil, nil)
+}
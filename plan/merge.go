@@ -83,6 +83,7 @@ func (this *Merge) MarshalJSON() ([]byte, error) {
 func (this *Merge) MarshalBase(f func(map[string]interface{})) map[string]interface{} {
 	r := map[string]interface{}{"#operator": "Merge"}
 	r["keyspace"] = this.keyspace.Name()
+	marshalKeyspacePath(r, this.keyspace)
 	r["namespace"] = this.keyspace.NamespaceId()
 
 	if this.key != nil {
@@ -113,6 +114,8 @@ func (this *Merge) UnmarshalJSON(body []byte) error {
 	var _unmarshalled struct {
 		_      string          `json:"#operator"`
 		Keys   string          `json:"keyspace"`
+		Bucket string          `json:"bucket"`
+		Scope  string          `json:"scope"`
 		Names  string          `json:"namespace"`
 		As     string          `json:"as"`
 		Key    string          `json:"key"`
@@ -126,12 +129,17 @@ func (this *Merge) UnmarshalJSON(body []byte) error {
 		return err
 	}
 
-	this.keyspace, err = datastore.GetKeyspace(_unmarshalled.Names, _unmarshalled.Keys)
+	this.keyspace, err = getKeyspace(_unmarshalled.Names, _unmarshalled.Bucket, _unmarshalled.Scope, _unmarshalled.Keys)
 	if err != nil {
 		return err
 	}
 
-	this.ref = algebra.NewKeyspaceRef(_unmarshalled.Names, _unmarshalled.Keys, _unmarshalled.As)
+	if _unmarshalled.Bucket != "" {
+		path := algebra.NewPathLong(_unmarshalled.Names, _unmarshalled.Bucket, _unmarshalled.Scope, _unmarshalled.Keys)
+		this.ref = algebra.NewKeyspaceRefFromPath(path, _unmarshalled.As)
+	} else {
+		this.ref = algebra.NewKeyspaceRef(_unmarshalled.Names, _unmarshalled.Keys, _unmarshalled.As)
+	}
 
 	if _unmarshalled.Key != "" {
 		this.key, err = parser.Parse(_unmarshalled.Key)
@@ -69,6 +69,7 @@ func (this *Nest) MarshalBase(f func(map[string]interface{})) map[string]interfa
 	r := map[string]interface{}{"#operator": "Nest"}
 	r["namespace"] = this.term.Namespace()
 	r["keyspace"] = this.term.Keyspace()
+	marshalTermPath(r, this.term)
 	r["on_keys"] = expression.NewStringer().Visit(this.term.JoinKeys())
 
 	if this.outer {
@@ -86,12 +87,14 @@ func (this *Nest) MarshalBase(f func(map[string]interface{})) map[string]interfa
 
 func (this *Nest) UnmarshalJSON(body []byte) error {
 	var _unmarshalled struct {
-		_     string `json:"#operator"`
-		Names string `json:"namespace"`
-		Keys  string `json:"keyspace"`
-		On    string `json:"on_keys"`
-		Outer bool   `json:"outer"`
-		As    string `json:"as"`
+		_      string `json:"#operator"`
+		Names  string `json:"namespace"`
+		Keys   string `json:"keyspace"`
+		Bucket string `json:"bucket"`
+		Scope  string `json:"scope"`
+		On     string `json:"on_keys"`
+		Outer  bool   `json:"outer"`
+		As     string `json:"as"`
 	}
 
 	err := json.Unmarshal(body, &_unmarshalled)
@@ -108,9 +111,9 @@ func (this *Nest) UnmarshalJSON(body []byte) error {
 	}
 
 	this.outer = _unmarshalled.Outer
-	this.term = algebra.NewKeyspaceTerm(_unmarshalled.Names, _unmarshalled.Keys, _unmarshalled.As, nil, nil)
+	this.term = newKeyspaceTerm(_unmarshalled.Names, _unmarshalled.Bucket, _unmarshalled.Scope, _unmarshalled.Keys, _unmarshalled.As)
 	this.term.SetJoinKeys(keys_expr)
-	this.keyspace, err = datastore.GetKeyspace(_unmarshalled.Names, _unmarshalled.Keys)
+	this.keyspace, err = getKeyspace(_unmarshalled.Names, _unmarshalled.Bucket, _unmarshalled.Scope, _unmarshalled.Keys)
 	return err
 
 }
@@ -147,6 +147,12 @@ var _OPERATORS = map[string]Operator{
 	"AlterIndex":         &AlterIndex{},
 	"BuildIndexes":       &BuildIndexes{},
 
+	// Scope and collection DDL
+	"CreateScope":      &CreateScope{},
+	"DropScope":        &DropScope{},
+	"CreateCollection": &CreateCollection{},
+	"DropCollection":   &DropCollection{},
+
 	// Roles
 	"GrantRole":  &GrantRole{},
 	"RevokeRole": &RevokeRole{},
@@ -165,4 +171,14 @@ var _OPERATORS = map[string]Operator{
 	// Index Advisor
 	"AdviseIndex": &Advise{},
 	"IndexAdvice": &IndexAdvice{},
+
+	// Statistics
+	"UpdateStatistics": &UpdateStatistics{},
+	"DeleteStatistics": &DeleteStatistics{},
+
+	// Transactions
+	"StartTransaction":    &StartTransaction{},
+	"CommitTransaction":   &CommitTransaction{},
+	"RollbackTransaction": &RollbackTransaction{},
+	"Savepoint":           &Savepoint{},
 }
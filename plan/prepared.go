@@ -31,6 +31,8 @@ type Prepared struct {
 	indexApiVersion int
 	featureControls uint64
 	namespace       string // TODO change into scope
+	useCache        bool
+	cacheable       bool
 
 	indexers      []idxVersion // for reprepare checking
 	namespaces    []nsVersion
@@ -69,6 +71,12 @@ func (this *Prepared) MarshalBase(f func(map[string]interface{})) map[string]int
 	r["indexApiVersion"] = this.indexApiVersion
 	r["featureControls"] = this.featureControls
 	r["namespace"] = this.namespace
+	if this.useCache {
+		r["useCache"] = this.useCache
+	}
+	if this.cacheable {
+		r["cacheable"] = this.cacheable
+	}
 
 	if f != nil {
 		f(r)
@@ -87,6 +95,8 @@ func (this *Prepared) UnmarshalJSON(body []byte) error {
 		ApiVersion      int             `json:"indexApiVersion"`
 		FeatureControls uint64          `json:"featureControls"`
 		Namespace       string          `json:"namespace"`
+		UseCache        bool            `json:"useCache"`
+		Cacheable       bool            `json:"cacheable"`
 	}
 
 	var op_type struct {
@@ -116,6 +126,8 @@ func (this *Prepared) UnmarshalJSON(body []byte) error {
 	this.indexApiVersion = _unmarshalled.ApiVersion
 	this.featureControls = _unmarshalled.FeatureControls
 	this.namespace = _unmarshalled.Namespace
+	this.useCache = _unmarshalled.UseCache
+	this.cacheable = _unmarshalled.Cacheable
 	this.Operator, err = MakeOperator(op_type.Operator, _unmarshalled.Operator)
 
 	return err
@@ -173,6 +185,24 @@ func (this *Prepared) SetNamespace(namespace string) {
 	this.namespace = namespace
 }
 
+// whether the statement has a USE CACHE hint
+func (this *Prepared) UseCache() bool {
+	return this.useCache
+}
+
+func (this *Prepared) SetUseCache(useCache bool) {
+	this.useCache = useCache
+}
+
+// whether the results of the statement can be kept in the result cache
+func (this *Prepared) Cacheable() bool {
+	return this.cacheable
+}
+
+func (this *Prepared) SetCacheable(cacheable bool) {
+	this.cacheable = cacheable
+}
+
 func (this *Prepared) EncodedPlan() string {
 	return this.encoded_plan
 }
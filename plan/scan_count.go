@@ -54,6 +54,7 @@ func (this *CountScan) MarshalBase(f func(map[string]interface{})) map[string]in
 	r := map[string]interface{}{"#operator": "CountScan"}
 	r["namespace"] = this.term.Namespace()
 	r["keyspace"] = this.term.Keyspace()
+	marshalTermPath(r, this.term)
 	if f != nil {
 		f(r)
 	}
@@ -62,9 +63,11 @@ func (this *CountScan) MarshalBase(f func(map[string]interface{})) map[string]in
 
 func (this *CountScan) UnmarshalJSON(body []byte) error {
 	var _unmarshalled struct {
-		_     string `json:"#operator"`
-		Names string `json:"namespace"`
-		Keys  string `json:"keyspace"`
+		_      string `json:"#operator"`
+		Names  string `json:"namespace"`
+		Keys   string `json:"keyspace"`
+		Bucket string `json:"bucket"`
+		Scope  string `json:"scope"`
 	}
 
 	err := json.Unmarshal(body, &_unmarshalled)
@@ -72,7 +75,7 @@ func (this *CountScan) UnmarshalJSON(body []byte) error {
 		return err
 	}
 
-	this.keyspace, err = datastore.GetKeyspace(_unmarshalled.Names, _unmarshalled.Keys)
+	this.keyspace, err = getKeyspace(_unmarshalled.Names, _unmarshalled.Bucket, _unmarshalled.Scope, _unmarshalled.Keys)
 
 	return err
 }
@@ -214,6 +214,7 @@ func (this *IndexScan3) MarshalBase(f func(map[string]interface{})) map[string]i
 	r["index_id"] = this.index.Id()
 	r["namespace"] = this.term.Namespace()
 	r["keyspace"] = this.term.Keyspace()
+	marshalTermPath(r, this.term)
 	r["using"] = this.index.Type()
 	r["spans"] = this.spans
 
@@ -291,6 +292,8 @@ func (this *IndexScan3) UnmarshalJSON(body []byte) error {
 		IndexId      string                 `json:"index_id"`
 		Namespace    string                 `json:"namespace"`
 		Keyspace     string                 `json:"keyspace"`
+		Bucket       string                 `json:"bucket"`
+		Scope        string                 `json:"scope"`
 		As           string                 `json:"as"`
 		Using        datastore.IndexType    `json:"using"`
 		Spans        Spans2                 `json:"spans"`
@@ -314,12 +317,12 @@ func (this *IndexScan3) UnmarshalJSON(body []byte) error {
 		return err
 	}
 
-	k, err := datastore.GetKeyspace(_unmarshalled.Namespace, _unmarshalled.Keyspace)
+	k, err := getKeyspace(_unmarshalled.Namespace, _unmarshalled.Bucket, _unmarshalled.Scope, _unmarshalled.Keyspace)
 	if err != nil {
 		return err
 	}
 
-	this.term = algebra.NewKeyspaceTerm(_unmarshalled.Namespace, _unmarshalled.Keyspace, _unmarshalled.As, nil, nil)
+	this.term = newKeyspaceTerm(_unmarshalled.Namespace, _unmarshalled.Bucket, _unmarshalled.Scope, _unmarshalled.Keyspace, _unmarshalled.As)
 
 	this.spans = _unmarshalled.Spans
 	flags := uint32(0)
@@ -119,6 +119,7 @@ func (this *IndexCountScan2) MarshalBase(f func(map[string]interface{})) map[str
 	r["index_id"] = this.index.Id()
 	r["namespace"] = this.term.Namespace()
 	r["keyspace"] = this.term.Keyspace()
+	marshalTermPath(r, this.term)
 	r["using"] = this.index.Type()
 	r["spans"] = this.spans
 
@@ -152,6 +153,8 @@ func (this *IndexCountScan2) UnmarshalJSON(body []byte) error {
 		IndexId      string                 `json:"index_id"`
 		Namespace    string                 `json:"namespace"`
 		Keyspace     string                 `json:"keyspace"`
+		Bucket       string                 `json:"bucket"`
+		Scope        string                 `json:"scope"`
 		As           string                 `json:"as"`
 		Using        datastore.IndexType    `json:"using"`
 		Spans        Spans2                 `json:"spans"`
@@ -164,12 +167,12 @@ func (this *IndexCountScan2) UnmarshalJSON(body []byte) error {
 		return err
 	}
 
-	k, err := datastore.GetKeyspace(_unmarshalled.Namespace, _unmarshalled.Keyspace)
+	k, err := getKeyspace(_unmarshalled.Namespace, _unmarshalled.Bucket, _unmarshalled.Scope, _unmarshalled.Keyspace)
 	if err != nil {
 		return err
 	}
 
-	this.term = algebra.NewKeyspaceTerm(_unmarshalled.Namespace, _unmarshalled.Keyspace, _unmarshalled.As, nil, nil)
+	this.term = newKeyspaceTerm(_unmarshalled.Namespace, _unmarshalled.Bucket, _unmarshalled.Scope, _unmarshalled.Keyspace, _unmarshalled.As)
 	this.spans = _unmarshalled.Spans
 
 	if len(_unmarshalled.Covers) > 0 {
@@ -72,6 +72,7 @@ func (this *PrimaryScan) MarshalBase(f func(map[string]interface{})) map[string]
 	r["index"] = this.index.Name()
 	r["namespace"] = this.term.Namespace()
 	r["keyspace"] = this.term.Keyspace()
+	marshalTermPath(r, this.term)
 	r["using"] = this.index.Type()
 
 	if this.term.As() != "" {
@@ -90,13 +91,15 @@ func (this *PrimaryScan) MarshalBase(f func(map[string]interface{})) map[string]
 
 func (this *PrimaryScan) UnmarshalJSON(body []byte) error {
 	var _unmarshalled struct {
-		_     string              `json:"#operator"`
-		Index string              `json:"index"`
-		Names string              `json:"namespace"`
-		Keys  string              `json:"keyspace"`
-		As    string              `json:"as"`
-		Using datastore.IndexType `json:"using"`
-		Limit string              `json:"limit"`
+		_      string              `json:"#operator"`
+		Index  string              `json:"index"`
+		Names  string              `json:"namespace"`
+		Keys   string              `json:"keyspace"`
+		Bucket string              `json:"bucket"`
+		Scope  string              `json:"scope"`
+		As     string              `json:"as"`
+		Using  datastore.IndexType `json:"using"`
+		Limit  string              `json:"limit"`
 	}
 
 	err := json.Unmarshal(body, &_unmarshalled)
@@ -111,12 +114,12 @@ func (this *PrimaryScan) UnmarshalJSON(body []byte) error {
 		}
 	}
 
-	this.keyspace, err = datastore.GetKeyspace(_unmarshalled.Names, _unmarshalled.Keys)
+	this.keyspace, err = getKeyspace(_unmarshalled.Names, _unmarshalled.Bucket, _unmarshalled.Scope, _unmarshalled.Keys)
 	if err != nil {
 		return err
 	}
 
-	this.term = algebra.NewKeyspaceTerm(_unmarshalled.Names, _unmarshalled.Keys, _unmarshalled.As, nil, nil)
+	this.term = newKeyspaceTerm(_unmarshalled.Names, _unmarshalled.Bucket, _unmarshalled.Scope, _unmarshalled.Keys, _unmarshalled.As)
 	this.indexer, err = this.keyspace.Indexer(_unmarshalled.Using)
 	if err != nil {
 		return err
@@ -122,6 +122,7 @@ func (this *PrimaryScan3) MarshalBase(f func(map[string]interface{})) map[string
 	r["index"] = this.index.Name()
 	r["namespace"] = this.term.Namespace()
 	r["keyspace"] = this.term.Keyspace()
+	marshalTermPath(r, this.term)
 	r["using"] = this.index.Type()
 
 	if this.term.As() != "" {
@@ -168,6 +169,8 @@ func (this *PrimaryScan3) UnmarshalJSON(body []byte) error {
 		Index       string                `json:"index"`
 		Names       string                `json:"namespace"`
 		Keys        string                `json:"keyspace"`
+		Bucket      string                `json:"bucket"`
+		Scope       string                `json:"scope"`
 		As          string                `json:"as"`
 		Using       datastore.IndexType   `json:"using"`
 		GroupAggs   *IndexGroupAggregates `json:"index_group_aggs"`
@@ -205,12 +208,12 @@ func (this *PrimaryScan3) UnmarshalJSON(body []byte) error {
 	this.cost = getCost(_unmarshalled.Cost)
 	this.cardinality = getCardinality(_unmarshalled.Cardinality)
 
-	this.keyspace, err = datastore.GetKeyspace(_unmarshalled.Names, _unmarshalled.Keys)
+	this.keyspace, err = getKeyspace(_unmarshalled.Names, _unmarshalled.Bucket, _unmarshalled.Scope, _unmarshalled.Keys)
 	if err != nil {
 		return err
 	}
 
-	this.term = algebra.NewKeyspaceTerm(_unmarshalled.Names, _unmarshalled.Keys, _unmarshalled.As, nil, nil)
+	this.term = newKeyspaceTerm(_unmarshalled.Names, _unmarshalled.Bucket, _unmarshalled.Scope, _unmarshalled.Keys, _unmarshalled.As)
 	this.indexer, err = this.keyspace.Indexer(_unmarshalled.Using)
 	if err != nil {
 		return err
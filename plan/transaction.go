@@ -0,0 +1,187 @@
+//  Copyright (c) 2020 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package plan
+
+import (
+	"encoding/json"
+)
+
+// Start Transaction
+type StartTransaction struct {
+	readonly
+}
+
+func NewStartTransaction() *StartTransaction {
+	return &StartTransaction{}
+}
+
+func (this *StartTransaction) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitStartTransaction(this)
+}
+
+func (this *StartTransaction) New() Operator {
+	return &StartTransaction{}
+}
+
+func (this *StartTransaction) MarshalJSON() ([]byte, error) {
+	return json.Marshal(this.MarshalBase(nil))
+}
+
+func (this *StartTransaction) MarshalBase(f func(map[string]interface{})) map[string]interface{} {
+	r := map[string]interface{}{"#operator": "StartTransaction"}
+	if f != nil {
+		f(r)
+	}
+	return r
+}
+
+func (this *StartTransaction) UnmarshalJSON([]byte) error {
+	return nil
+}
+
+// Commit Transaction
+// Committing applies the staged mutations, so it is not read only
+type CommitTransaction struct {
+	readwrite
+}
+
+func NewCommitTransaction() *CommitTransaction {
+	return &CommitTransaction{}
+}
+
+func (this *CommitTransaction) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitCommitTransaction(this)
+}
+
+func (this *CommitTransaction) New() Operator {
+	return &CommitTransaction{}
+}
+
+func (this *CommitTransaction) MarshalJSON() ([]byte, error) {
+	return json.Marshal(this.MarshalBase(nil))
+}
+
+func (this *CommitTransaction) MarshalBase(f func(map[string]interface{})) map[string]interface{} {
+	r := map[string]interface{}{"#operator": "CommitTransaction"}
+	if f != nil {
+		f(r)
+	}
+	return r
+}
+
+func (this *CommitTransaction) UnmarshalJSON([]byte) error {
+	return nil
+}
+
+// Rollback Transaction, optionally to a savepoint
+type RollbackTransaction struct {
+	readonly
+	savepoint string
+}
+
+func NewRollbackTransaction(savepoint string) *RollbackTransaction {
+	return &RollbackTransaction{
+		savepoint: savepoint,
+	}
+}
+
+func (this *RollbackTransaction) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitRollbackTransaction(this)
+}
+
+func (this *RollbackTransaction) New() Operator {
+	return &RollbackTransaction{}
+}
+
+func (this *RollbackTransaction) Savepoint() string {
+	return this.savepoint
+}
+
+func (this *RollbackTransaction) MarshalJSON() ([]byte, error) {
+	return json.Marshal(this.MarshalBase(nil))
+}
+
+func (this *RollbackTransaction) MarshalBase(f func(map[string]interface{})) map[string]interface{} {
+	r := map[string]interface{}{"#operator": "RollbackTransaction"}
+	if this.savepoint != "" {
+		r["savepoint"] = this.savepoint
+	}
+	if f != nil {
+		f(r)
+	}
+	return r
+}
+
+func (this *RollbackTransaction) UnmarshalJSON(body []byte) error {
+	var _unmarshalled struct {
+		_         string `json:"#operator"`
+		Savepoint string `json:"savepoint"`
+	}
+
+	err := json.Unmarshal(body, &_unmarshalled)
+	if err != nil {
+		return err
+	}
+
+	this.savepoint = _unmarshalled.Savepoint
+	return nil
+}
+
+// Savepoint
+type Savepoint struct {
+	readonly
+	name string
+}
+
+func NewSavepoint(name string) *Savepoint {
+	return &Savepoint{
+		name: name,
+	}
+}
+
+func (this *Savepoint) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitSavepoint(this)
+}
+
+func (this *Savepoint) New() Operator {
+	return &Savepoint{}
+}
+
+func (this *Savepoint) Name() string {
+	return this.name
+}
+
+func (this *Savepoint) MarshalJSON() ([]byte, error) {
+	return json.Marshal(this.MarshalBase(nil))
+}
+
+func (this *Savepoint) MarshalBase(f func(map[string]interface{})) map[string]interface{} {
+	r := map[string]interface{}{"#operator": "Savepoint"}
+	r["name"] = this.name
+	if f != nil {
+		f(r)
+	}
+	return r
+}
+
+func (this *Savepoint) UnmarshalJSON(body []byte) error {
+	var _unmarshalled struct {
+		_    string `json:"#operator"`
+		Name string `json:"name"`
+	}
+
+	err := json.Unmarshal(body, &_unmarshalled)
+	if err != nil {
+		return err
+	}
+
+	this.name = _unmarshalled.Name
+	return nil
+}
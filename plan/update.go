@@ -217,6 +217,7 @@ func (this *SendUpdate) MarshalJSON() ([]byte, error) {
 func (this *SendUpdate) MarshalBase(f func(map[string]interface{})) map[string]interface{} {
 	r := map[string]interface{}{"#operator": "SendUpdate"}
 	r["keyspace"] = this.keyspace.Name()
+	marshalKeyspacePath(r, this.keyspace)
 	r["namespace"] = this.keyspace.NamespaceId()
 	r["alias"] = this.alias
 
@@ -232,11 +233,13 @@ func (this *SendUpdate) MarshalBase(f func(map[string]interface{})) map[string]i
 
 func (this *SendUpdate) UnmarshalJSON(body []byte) error {
 	var _unmarshalled struct {
-		_     string `json:"#operator"`
-		Keys  string `json:"keyspace"`
-		Names string `json:"namespace"`
-		Alias string `json:"alias"`
-		Limit string `json:"limit"`
+		_      string `json:"#operator"`
+		Keys   string `json:"keyspace"`
+		Bucket string `json:"bucket"`
+		Scope  string `json:"scope"`
+		Names  string `json:"namespace"`
+		Alias  string `json:"alias"`
+		Limit  string `json:"limit"`
 	}
 
 	err := json.Unmarshal(body, &_unmarshalled)
@@ -253,7 +256,7 @@ func (this *SendUpdate) UnmarshalJSON(body []byte) error {
 		}
 	}
 
-	this.keyspace, err = datastore.GetKeyspace(_unmarshalled.Names, _unmarshalled.Keys)
+	this.keyspace, err = getKeyspace(_unmarshalled.Names, _unmarshalled.Bucket, _unmarshalled.Scope, _unmarshalled.Keys)
 	return err
 }
 
@@ -23,14 +23,17 @@ type SendUpsert struct {
 	alias    string
 	key      expression.Expression
 	value    expression.Expression
+	options  expression.Expression
 }
 
-func NewSendUpsert(keyspace datastore.Keyspace, alias string, key, value expression.Expression) *SendUpsert {
+func NewSendUpsert(keyspace datastore.Keyspace, alias string,
+	key, value, options expression.Expression) *SendUpsert {
 	return &SendUpsert{
 		keyspace: keyspace,
 		alias:    alias,
 		key:      key,
 		value:    value,
+		options:  options,
 	}
 }
 
@@ -58,6 +61,10 @@ func (this *SendUpsert) Value() expression.Expression {
 	return this.value
 }
 
+func (this *SendUpsert) Options() expression.Expression {
+	return this.options
+}
+
 func (this *SendUpsert) MarshalJSON() ([]byte, error) {
 	return json.Marshal(this.MarshalBase(nil))
 }
@@ -65,6 +72,7 @@ func (this *SendUpsert) MarshalJSON() ([]byte, error) {
 func (this *SendUpsert) MarshalBase(f func(map[string]interface{})) map[string]interface{} {
 	r := map[string]interface{}{"#operator": "SendUpsert"}
 	r["keyspace"] = this.keyspace.Name()
+	marshalKeyspacePath(r, this.keyspace)
 	r["namespace"] = this.keyspace.NamespaceId()
 	r["alias"] = this.alias
 
@@ -76,6 +84,10 @@ func (this *SendUpsert) MarshalBase(f func(map[string]interface{})) map[string]i
 		r["value"] = this.value.String()
 	}
 
+	if this.options != nil {
+		r["options"] = this.options.String()
+	}
+
 	if f != nil {
 		f(r)
 	}
@@ -87,7 +99,10 @@ func (this *SendUpsert) UnmarshalJSON(body []byte) error {
 		_         string `json:"#operator"`
 		KeyExpr   string `json:"key"`
 		ValueExpr string `json:"value"`
+		OptsExpr  string `json:"options"`
 		Keys      string `json:"keyspace"`
+		Bucket    string `json:"bucket"`
+		Scope     string `json:"scope"`
 		Names     string `json:"namespace"`
 		Alias     string `json:"alias"`
 	}
@@ -111,8 +126,15 @@ func (this *SendUpsert) UnmarshalJSON(body []byte) error {
 		}
 	}
 
+	if _unmarshalled.OptsExpr != "" {
+		this.options, err = parser.Parse(_unmarshalled.OptsExpr)
+		if err != nil {
+			return err
+		}
+	}
+
 	this.alias = _unmarshalled.Alias
-	this.keyspace, err = datastore.GetKeyspace(_unmarshalled.Names, _unmarshalled.Keys)
+	this.keyspace, err = getKeyspace(_unmarshalled.Names, _unmarshalled.Bucket, _unmarshalled.Scope, _unmarshalled.Keys)
 	return err
 }
 
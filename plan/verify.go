@@ -13,6 +13,7 @@ package plan
 
 import (
 	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
 )
 
 func verifyIndex(index datastore.Index, indexer datastore.Indexer, prepared *Prepared) bool {
@@ -42,15 +43,22 @@ func verifyIndex(index datastore.Index, indexer datastore.Indexer, prepared *Pre
 }
 
 func verifyKeyspace(keyspace datastore.Keyspace, prepared *Prepared) (datastore.Keyspace, bool) {
+	var ks datastore.Keyspace
+	var err errors.Error
+
 	namespace := keyspace.Namespace()
-	ks, err := namespace.KeyspaceById(keyspace.Id())
+	if scope := keyspace.Scope(); scope != nil {
+		ks, err = scope.KeyspaceById(keyspace.Id())
+	} else {
+		ks, err = namespace.KeyspaceById(keyspace.Id())
+	}
 
 	if ks == nil || err != nil {
 		return keyspace, false
 	}
 
 	// amend prepared statement version so that next time we avoid checks
-	if prepared != nil {
+	if prepared != nil && namespace != nil {
 		prepared.addNamespace(namespace)
 	}
 
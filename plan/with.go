@@ -12,23 +12,41 @@ package plan
 import (
 	"encoding/json"
 
+	"github.com/couchbase/query/algebra"
 	"github.com/couchbase/query/expression"
 	"github.com/couchbase/query/expression/unmarshal"
 )
 
 type With struct {
 	readonly
-	bindings expression.Bindings
-	child    Operator
+	bindings  expression.Bindings
+	recursive []*algebra.RecursiveWith
+	child     Operator
 }
 
 func NewWith(bindings expression.Bindings, child Operator) *With {
 	return &With{
-		bindings: bindings,
-		child:    child,
+		bindings:  bindings,
+		recursive: newRecursiveWiths(bindings),
+		child:     child,
 	}
 }
 
+// nil for the bindings that are not recursive; invalid recursive
+// bindings have already been rejected by semantic checking
+func newRecursiveWiths(bindings expression.Bindings) []*algebra.RecursiveWith {
+	var rv []*algebra.RecursiveWith
+	for i, b := range bindings {
+		if rw, _ := algebra.NewRecursiveWith(b); rw != nil {
+			if rv == nil {
+				rv = make([]*algebra.RecursiveWith, len(bindings))
+			}
+			rv[i] = rw
+		}
+	}
+	return rv
+}
+
 func (this *With) Accept(visitor Visitor) (interface{}, error) {
 	return visitor.VisitWith(this)
 }
@@ -41,6 +59,16 @@ func (this *With) Bindings() expression.Bindings {
 	return this.bindings
 }
 
+/*
+The recursive form of the i-th binding, or nil if it is not recursive.
+*/
+func (this *With) RecursiveWith(i int) *algebra.RecursiveWith {
+	if this.recursive == nil {
+		return nil
+	}
+	return this.recursive[i]
+}
+
 func (this *With) Readonly() bool {
 	return this.child.Readonly()
 }
@@ -84,6 +112,10 @@ func (this *With) UnmarshalJSON(body []byte) error {
 	}
 
 	this.bindings, err = unmarshal.UnmarshalBindings(_unmarshalled.Bindings)
+	if err != nil {
+		return err
+	}
+	this.recursive = newRecursiveWiths(this.bindings)
 
 	err = json.Unmarshal(_unmarshalled.Child, &child_type)
 	if err != nil {
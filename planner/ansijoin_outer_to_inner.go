@@ -23,6 +23,7 @@ type ansijoinOuterToInner struct {
 	baseKeyspaces    map[string]*base.BaseKeyspace
 	unnests          []*algebra.Unnest
 	pushableOnclause expression.Expression
+	aliases          []string // aliases visited so far, i.e. the left-hand side of a join
 }
 
 func newAnsijoinOuterToInner(baseKeyspaces map[string]*base.BaseKeyspace, unnests []*algebra.Unnest) *ansijoinOuterToInner {
@@ -92,6 +93,76 @@ func (this *ansijoinOuterToInner) visitAnsiJoin(left algebra.FromTerm, outer boo
 	return false, nil
 }
 
+// RIGHT and FULL OUTER JOIN: check whether the WHERE clause rejects NULL on the
+// left-hand side. ON-clause filters are not considered, since those of inner
+// joins within the left-hand side are applied before this join.
+func (this *ansijoinOuterToInner) nullRejLeft() bool {
+	chkNullRej := newChkNullRej()
+
+	for _, a := range this.aliases {
+		baseKeyspace, ok := this.baseKeyspaces[a]
+		if !ok {
+			continue
+		}
+
+		chkNullRej.setAlias(a)
+
+		for _, fl := range baseKeyspace.Filters() {
+			if !fl.IsOnclause() && nullRejExpr(chkNullRej, fl.FltrExpr()) {
+				return true
+			}
+		}
+
+		for _, jfl := range baseKeyspace.JoinFilters() {
+			if !jfl.IsOnclause() && nullRejExpr(chkNullRej, jfl.FltrExpr()) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// The left-hand side of a RIGHT or FULL OUTER JOIN is optional, so WHERE clause
+// filters that reference it can only be applied after the join, and must not be
+// used to plan the scans of the left-hand side. ON-clause filters are kept.
+func (this *ansijoinOuterToInner) removeLeftWhereFilters() {
+	left := make(map[string]bool, len(this.aliases))
+	for _, a := range this.aliases {
+		left[a] = true
+	}
+
+	keep := func(fl *base.Filter) bool {
+		if fl.IsOnclause() {
+			return true
+		}
+		for ks, _ := range fl.Keyspaces() {
+			if left[ks] {
+				return false
+			}
+		}
+		return true
+	}
+
+	for _, baseKeyspace := range this.baseKeyspaces {
+		filters := make(base.Filters, 0, len(baseKeyspace.Filters()))
+		for _, fl := range baseKeyspace.Filters() {
+			if keep(fl) {
+				filters = append(filters, fl)
+			}
+		}
+
+		joinfilters := make(base.Filters, 0, len(baseKeyspace.JoinFilters()))
+		for _, jfl := range baseKeyspace.JoinFilters() {
+			if keep(jfl) {
+				joinfilters = append(joinfilters, jfl)
+			}
+		}
+
+		baseKeyspace.SetFilters(filters, joinfilters)
+	}
+}
+
 func (this *ansijoinOuterToInner) visitSetop(first algebra.Subresult, second algebra.Subresult) error {
 	// ansijoinOuterToInner is initialized at FROM clause processing, i.e., for each statement,
 	// and thus we don't expect it'll reach any of the set operations node
@@ -110,14 +181,17 @@ func (this *ansijoinOuterToInner) VisitSubselect(node *algebra.Subselect) (inter
 }
 
 func (this *ansijoinOuterToInner) VisitKeyspaceTerm(node *algebra.KeyspaceTerm) (interface{}, error) {
+	this.aliases = append(this.aliases, node.Alias())
 	return nil, nil
 }
 
 func (this *ansijoinOuterToInner) VisitExpressionTerm(node *algebra.ExpressionTerm) (interface{}, error) {
+	this.aliases = append(this.aliases, node.Alias())
 	return nil, nil
 }
 
 func (this *ansijoinOuterToInner) VisitSubqueryTerm(node *algebra.SubqueryTerm) (interface{}, error) {
+	this.aliases = append(this.aliases, node.Alias())
 	return nil, nil
 }
 
@@ -137,9 +211,26 @@ func (this *ansijoinOuterToInner) VisitAnsiJoin(node *algebra.AnsiJoin) (interfa
 		return nil, err
 	}
 	if aoj2aij {
-		this.addOnclause(node.Onclause())
+		// a FULL OUTER JOIN becomes a RIGHT OUTER JOIN, whose ON-clause
+		// is not pushable
+		if !node.RightOuter() {
+			this.addOnclause(node.Onclause())
+		}
 		node.SetOuter(false)
 	}
+	if node.RightOuter() {
+		// likewise, a RIGHT OUTER JOIN becomes an INNER JOIN, and a FULL OUTER JOIN
+		// a LEFT OUTER JOIN, when the left-hand side is null-rejected
+		if this.nullRejLeft() {
+			if !node.Outer() {
+				this.addOnclause(node.Onclause())
+			}
+			node.SetRightOuter(false)
+		} else {
+			this.removeLeftWhereFilters()
+		}
+	}
+	this.aliases = append(this.aliases, node.Alias())
 	return nil, nil
 }
 
@@ -162,11 +253,17 @@ func (this *ansijoinOuterToInner) VisitAnsiNest(node *algebra.AnsiNest) (interfa
 		this.addOnclause(node.Onclause())
 		node.SetOuter(false)
 	}
+	this.aliases = append(this.aliases, node.Alias())
 	return nil, nil
 }
 
 func (this *ansijoinOuterToInner) VisitUnnest(node *algebra.Unnest) (interface{}, error) {
-	return node.Left().Accept(this)
+	_, err := node.Left().Accept(this)
+	if err != nil {
+		return nil, err
+	}
+	this.aliases = append(this.aliases, node.Alias())
+	return nil, nil
 }
 
 func (this *ansijoinOuterToInner) VisitUnion(node *algebra.Union) (interface{}, error) {
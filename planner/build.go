@@ -25,7 +25,7 @@ import (
 
 func Build(stmt algebra.Statement, datastore, systemstore datastore.Datastore,
 	namespace string, subquery, stream bool, namedArgs map[string]value.Value,
-	positionalArgs value.Values, indexApiVersion int, featureControls uint64) (
+	positionalArgs value.Values, indexApiVersion int, featureControls uint64, inTransaction bool) (
 	plan.Operator, error) {
 
 	// request id in planner is separate from request id in execution context
@@ -38,6 +38,7 @@ func Build(stmt algebra.Statement, datastore, systemstore datastore.Datastore,
 	if distributed.RemoteAccess().Enabled(distributed.NEW_OPTIMIZER) && util.IsFeatureEnabled(featureControls, util.N1QL_CBO) {
 		builder.useCBO = true
 	}
+	builder.inTransaction = inTransaction
 
 	o, err := stmt.Accept(builder)
 
@@ -121,6 +122,7 @@ type builder struct {
 	builderFlags      uint32
 	indexAdvisor      bool
 	useCBO            bool
+	inTransaction     bool // indexes do not see the mutations staged by the transaction
 	hintIndexes       bool
 	lastOp            plan.Operator // last operator built, to get cost/cardinality info
 }
@@ -243,6 +245,15 @@ func getKeyspace(namespace datastore.Namespace, path *algebra.Path) (datastore.K
 	return namespace.KeyspaceByName(path.Keyspace())
 }
 
+// The fully qualified path of a keyspace, as its Keyspace.QualifiedName(),
+// which is what the optimizer statistics are kept by
+func qualifiedKeyspace(path *algebra.Path, namespace string) string {
+	if ns := path.Namespace(); ns != "" {
+		namespace = ns
+	}
+	return datastore.QualifiedKeyspaceName(namespace, path.Bucket(), path.Scope(), path.Keyspace())
+}
+
 func (this *builder) getDocCount(node *algebra.KeyspaceTerm) (float64, error) {
 	keyspace, err := this.getTermKeyspace(node)
 	if err != nil {
@@ -12,7 +12,7 @@
 package planner
 
 import (
-	"fmt"
+	"strings"
 
 	"github.com/couchbase/query/algebra"
 	"github.com/couchbase/query/datastore"
@@ -21,47 +21,166 @@ import (
 	base "github.com/couchbase/query/plannerbase"
 )
 
-type collectQueryInfo struct {
+func (this *builder) VisitAdvise(stmt *algebra.Advise) (interface{}, error) {
+	this.indexAdvisor = true
+	// keys are ranked by predicate type, not by cost, so index selection
+	// during ADVISE does not use the cost based optimizer either
+	this.useCBO = false
+	this.maxParallelism = 1
+	this.queryInfos = make([]*queryInfo, 0, 1)
+	stmt.Statement().Accept(this)
+
+	adviceInfos := make(plan.IndexAdviceInfos, 0, len(this.queryInfos))
+	for _, queryInfo := range this.queryInfos {
+		adviceInfos = append(adviceInfos, queryInfo.adviceInfo())
+	}
+	return plan.NewAdvise(plan.NewIndexAdvice(adviceInfos), stmt.Query()), nil
 }
 
-func (this *builder) VisitAdvise(stmt *algebra.Advise) (interface{}, error) {
-	return nil, fmt.Errorf("Not supported.")
+type collectQueryInfo struct {
+	keyspaceInfos advKeyspaceInfos
+	queryInfo     *queryInfo
+	queryInfos    []*queryInfo
 }
 
 func (this *builder) initialIndexAdvisor(stmt algebra.Statement) {
+	if this.indexAdvisor {
+		if stmt != nil {
+			this.queryInfo = newQueryInfo(stmt.Type())
+			this.keyspaceInfos = nil
+			this.queryInfos = append(this.queryInfos, this.queryInfo)
+		}
+	}
 }
 
+// deliberately a no-op: the rule based advisor takes the filters of each keyspace,
+// from both WHERE and ON clauses, once they have been classified (see collectPredicates)
 func (this *builder) extractPredicates(where, on expression.Expression) {
 }
 
 func (this *builder) extractIndexJoin(index datastore.Index, node *algebra.KeyspaceTerm, cover bool) {
+	if this.indexAdvisor && this.queryInfo != nil {
+		var indexes []datastore.Index
+		if index != nil {
+			indexes = []datastore.Index{index}
+		}
+		this.queryInfo.addKeyspace(node, this.keyspaceInfos, indexes, cover)
+		this.keyspaceInfos = nil
+	}
 }
 
 func (this *builder) appendQueryInfo(scan plan.Operator, node *algebra.KeyspaceTerm, uncovered bool) {
+	if this.indexAdvisor && this.queryInfo != nil {
+		this.queryInfo.addKeyspace(node, this.keyspaceInfos, scanIndexes(scan, nil), !uncovered)
+		this.keyspaceInfos = nil
+	}
 }
 
-func (this *builder) enableUnnest(alias string) {
+func (this *builder) storeCollectQueryInfo() *collectQueryInfo {
+	info := &collectQueryInfo{}
+	info.queryInfo = this.queryInfo
+	info.keyspaceInfos = this.keyspaceInfos
+	return info
 }
 
-func (this *builder) collectPredicates(baseKeyspace *base.BaseKeyspace, keyspace datastore.Keyspace, node *algebra.KeyspaceTerm, pred expression.Expression, ansijoin bool) error {
-	return nil
+func (this *builder) restoreCollectQueryInfo(info *collectQueryInfo) {
+	this.queryInfo = info.queryInfo
+	this.keyspaceInfos = info.keyspaceInfos
 }
 
-func (this *builder) setUnnest() {
+func (this *builder) extractLetGroupProjOrder(let expression.Bindings, group *algebra.Group, projection *algebra.Projection, order *algebra.Order) {
+	if this.indexAdvisor && this.queryInfo != nil {
+		if let != nil {
+			this.queryInfo.let = let
+		}
+		if group != nil {
+			this.queryInfo.groupBy = group.By()
+			this.queryInfo.letting = group.Letting()
+		}
+		if projection != nil {
+			this.queryInfo.projection = projection.Terms()
+		}
+		if order != nil {
+			this.queryInfo.order = order.Terms()
+		}
+	}
 }
 
-func (this *builder) setKeyspaceFound() {
+func (this *builder) enableUnnest(alias string) {
+	if this.indexAdvisor && this.queryInfo != nil {
+		if this.queryInfo.unnest {
+			this.queryInfo.unnests = collectInnerUnnests(this.from, nil)
+			this.queryInfo.unnest = false
+		}
+	}
 }
 
-func (this *builder) processadviseJF(alias string) {
+func (this *builder) collectPredicates(baseKeyspace *base.BaseKeyspace, keyspace datastore.Keyspace, node *algebra.KeyspaceTerm, pred expression.Expression, ansijoin bool) error {
+	if !this.indexAdvisor || this.queryInfo == nil {
+		return nil
+	}
+	//not advise index to system keyspace
+	if strings.ToLower(keyspace.Namespace().Name()) == "#system" {
+		return nil
+	}
+	if baseKeyspace == nil {
+		baseKeyspace = this.baseKeyspaces[node.Alias()]
+	}
+
+	if pred == nil {
+		if _, ok := baseKeyspace.DnfPred().(*expression.Or); !ok {
+			this.keyspaceInfos = append(this.keyspaceInfos,
+				newAdvKeyspaceInfo(keyspace, baseKeyspace.Filters(), baseKeyspace.JoinFilters(), false))
+			return nil
+		}
+		pred = baseKeyspace.DnfPred()
+	}
+
+	// each disjunct is classified on its own, to find the keys they have in common
+	if or, ok := pred.(*expression.Or); ok {
+		orTerms, _ := flattenOr(or)
+		for _, op := range orTerms.Operands() {
+			baseKeyspacesCopy := base.CopyBaseKeyspaces(this.baseKeyspaces)
+			_, err := ClassifyExpr(op, baseKeyspacesCopy, ansijoin, this.useCBO)
+			if err != nil {
+				continue
+			}
+
+			bk, _ := baseKeyspacesCopy[node.Alias()]
+			if !ansijoin {
+				addUnnestPreds(baseKeyspacesCopy, bk)
+			}
+			this.keyspaceInfos = append(this.keyspaceInfos,
+				newAdvKeyspaceInfo(keyspace, bk.Filters(), bk.JoinFilters(), true))
+		}
+		return nil
+	}
+
+	baseKeyspacesCopy := base.CopyBaseKeyspaces(this.baseKeyspaces)
+	_, err := ClassifyExpr(pred, baseKeyspacesCopy, false, this.useCBO)
+	if err != nil {
+		return err
+	}
+	baseKeyspaceCopy, _ := baseKeyspacesCopy[node.Alias()]
+	this.keyspaceInfos = append(this.keyspaceInfos,
+		newAdvKeyspaceInfo(keyspace, baseKeyspaceCopy.Filters(), baseKeyspaceCopy.JoinFilters(), false))
+	return nil
 }
 
-func (this *builder) extractLetGroupProjOrder(let expression.Bindings, group *algebra.Group, projection *algebra.Projection, order *algebra.Order) {
+func (this *builder) setUnnest() {
+	if this.indexAdvisor && this.queryInfo != nil {
+		this.queryInfo.unnest = true
+	}
 }
 
-func (this *builder) storeCollectQueryInfo() *collectQueryInfo {
-	return nil
+func (this *builder) processadviseJF(alias string) {
+	if this.indexAdvisor {
+		this.processKeyspaceDone(alias)
+	}
 }
 
-func (this *builder) restoreCollectQueryInfo(info *collectQueryInfo) {
+func (this *builder) setKeyspaceFound() {
+	if this.indexAdvisor && this.queryInfo != nil {
+		this.queryInfo.keyspaceFound = true
+	}
 }
@@ -20,7 +20,7 @@ func (this *builder) VisitDelete(stmt *algebra.Delete) (interface{}, error) {
 	this.where = stmt.Where()
 
 	ksref := stmt.KeyspaceRef()
-	keyspace, err := this.getNameKeyspace(ksref.Namespace(), ksref.Keyspace())
+	keyspace, err := this.getNameKeyspace(ksref)
 	if err != nil {
 		return nil, err
 	}
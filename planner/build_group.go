@@ -0,0 +1,102 @@
+//  Copyright (c) 2020 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package planner
+
+import (
+	"github.com/couchbase/query/algebra"
+	"github.com/couchbase/query/expression"
+)
+
+/*
+The group operators cover the group keys of each group, as NULL for
+the keys its grouping set rolls up. Replace the keys that are rolled
+up by some grouping set with those covers, in all the expressions
+evaluated on the groups.
+*/
+func (this *builder) coverGroupingSetKeys(node *algebra.Subselect, group *algebra.Group) (err error) {
+	keys := group.By()
+	inSets := make([]int, len(keys))
+	for _, set := range group.GroupingSets() {
+		for _, k := range set {
+			inSets[k]++
+		}
+	}
+
+	covers := make(expression.Covers, 0, len(keys))
+	for i, key := range keys {
+		if inSets[i] < len(group.GroupingSets()) {
+			covers = append(covers, expression.NewCover(key.Copy()))
+		}
+	}
+
+	if len(covers) == 0 {
+		return
+	}
+
+	coverer := NewGroupingSetCoverer(covers)
+	err = node.Projection().MapExpressions(coverer)
+	if err == nil {
+		err = group.MapGroupedExpressions(coverer)
+	}
+
+	if err == nil && this.order != nil {
+		err = this.order.MapExpressions(coverer)
+	}
+
+	return
+}
+
+/*
+Replaces the group keys with their covers, except within the
+aggregates, which are evaluated on the input items, and within
+GROUPING(), which looks up the keys themselves.
+*/
+type GroupingSetCoverer struct {
+	expression.MapperBase
+
+	covers []*expression.Cover
+}
+
+func NewGroupingSetCoverer(covers []*expression.Cover) *GroupingSetCoverer {
+	rv := &GroupingSetCoverer{
+		covers: covers,
+	}
+
+	rv.SetMapper(rv)
+	rv.SetMapFunc(func(expr expression.Expression) (expression.Expression, error) {
+
+		switch expr := expr.(type) {
+		case *expression.Cover, *expression.Grouping, *expression.GroupingId:
+			return expr, nil
+		case algebra.Aggregate:
+			if !expr.IsWindowAggregate() {
+				return expr, nil
+			}
+		}
+
+		for _, c := range rv.covers {
+			if c.Covered().EquivalentTo(expr) {
+				return c, nil
+			}
+		}
+
+		return expr, expr.MapChildren(rv)
+	})
+
+	return rv
+}
+
+func (this *GroupingSetCoverer) VisitNamedParameter(expr expression.NamedParameter) (interface{}, error) {
+	return expr, nil
+}
+
+func (this *GroupingSetCoverer) VisitPositionalParameter(expr expression.PositionalParameter) (interface{}, error) {
+	return expr, nil
+}
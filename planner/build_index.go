@@ -21,7 +21,7 @@ import (
 
 func (this *builder) VisitCreatePrimaryIndex(stmt *algebra.CreatePrimaryIndex) (interface{}, error) {
 	ksref := stmt.Keyspace()
-	keyspace, err := this.getNameKeyspace(ksref.Namespace(), ksref.Keyspace())
+	keyspace, err := this.getNameKeyspace(ksref)
 	if err != nil {
 		return nil, err
 	}
@@ -47,7 +47,7 @@ func (this *builder) VisitCreatePrimaryIndex(stmt *algebra.CreatePrimaryIndex) (
 
 func (this *builder) VisitCreateIndex(stmt *algebra.CreateIndex) (interface{}, error) {
 	ksref := stmt.Keyspace()
-	keyspace, err := this.getNameKeyspace(ksref.Namespace(), ksref.Keyspace())
+	keyspace, err := this.getNameKeyspace(ksref)
 	if err != nil {
 		return nil, err
 	}
@@ -91,7 +91,7 @@ func (this *builder) VisitCreateIndex(stmt *algebra.CreateIndex) (interface{}, e
 
 func (this *builder) VisitDropIndex(stmt *algebra.DropIndex) (interface{}, error) {
 	ksref := stmt.Keyspace()
-	keyspace, err := this.getNameKeyspace(ksref.Namespace(), ksref.Keyspace())
+	keyspace, err := this.getNameKeyspace(ksref)
 	if err != nil {
 		return nil, err
 	}
@@ -116,7 +116,7 @@ func (this *builder) VisitDropIndex(stmt *algebra.DropIndex) (interface{}, error
 
 func (this *builder) VisitAlterIndex(stmt *algebra.AlterIndex) (interface{}, error) {
 	ksref := stmt.Keyspace()
-	keyspace, err := this.getNameKeyspace(ksref.Namespace(), ksref.Keyspace())
+	keyspace, err := this.getNameKeyspace(ksref)
 	if err != nil {
 		return nil, err
 	}
@@ -145,7 +145,7 @@ func (this *builder) VisitAlterIndex(stmt *algebra.AlterIndex) (interface{}, err
 
 func (this *builder) VisitBuildIndexes(stmt *algebra.BuildIndexes) (interface{}, error) {
 	ksref := stmt.Keyspace()
-	keyspace, err := this.getNameKeyspace(ksref.Namespace(), ksref.Keyspace())
+	keyspace, err := this.getNameKeyspace(ksref)
 	if err != nil {
 		return nil, err
 	}
@@ -163,7 +163,21 @@ func (this *builder) VisitBuildIndexes(stmt *algebra.BuildIndexes) (interface{},
 	return plan.NewBuildIndexes(keyspace, stmt), nil
 }
 
-func (this *builder) getNameKeyspace(ns, ks string) (datastore.Keyspace, error) {
+func (this *builder) getNameKeyspace(ksref *algebra.KeyspaceRef) (datastore.Keyspace, error) {
+	namespace, err := this.getNamespace(ksref.Namespace())
+	if err != nil {
+		return nil, err
+	}
+
+	keyspace, err := getKeyspace(namespace, ksref.Path())
+	if err != nil {
+		return nil, err
+	}
+
+	return keyspace, nil
+}
+
+func (this *builder) getNamespace(ns string) (datastore.Namespace, errors.Error) {
 	if ns == "" {
 		ns = this.namespace
 	}
@@ -176,15 +190,5 @@ func (this *builder) getNameKeyspace(ns, ks string) (datastore.Keyspace, error)
 	if strings.ToLower(ns) == "#system" {
 		datastore = this.systemstore
 	}
-	namespace, err := datastore.NamespaceByName(ns)
-	if err != nil {
-		return nil, err
-	}
-
-	keyspace, err := namespace.KeyspaceByName(ks)
-	if err != nil {
-		return nil, err
-	}
-
-	return keyspace, nil
+	return datastore.NamespaceByName(ns)
 }
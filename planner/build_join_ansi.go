@@ -11,6 +11,7 @@ package planner
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/couchbase/query/algebra"
 	"github.com/couchbase/query/errors"
@@ -20,6 +21,10 @@ import (
 )
 
 func (this *builder) buildAnsiJoin(node *algebra.AnsiJoin) (op plan.Operator, err error) {
+	if node.RightOuter() {
+		return this.buildAnsiRightOuterJoin(node)
+	}
+
 	right := node.Right()
 
 	if ksterm := algebra.GetKeyspaceTerm(right); ksterm != nil {
@@ -30,7 +35,7 @@ func (this *builder) buildAnsiJoin(node *algebra.AnsiJoin) (op plan.Operator, er
 
 	switch right := right.(type) {
 	case *algebra.KeyspaceTerm:
-		err := this.processOnclause(right.Alias(), node.Onclause(), node.Outer())
+		err := this.processOnclause(right.Alias(), node.Onclause(), node.Outer(), false)
 		if err != nil {
 			return nil, err
 		}
@@ -118,7 +123,7 @@ func (this *builder) buildAnsiJoin(node *algebra.AnsiJoin) (op plan.Operator, er
 		newKeyspaceTerm.SetJoinKeys(primaryJoinKeys)
 		return plan.NewJoinFromAnsi(keyspace, newKeyspaceTerm, node.Outer()), nil
 	case *algebra.ExpressionTerm, *algebra.SubqueryTerm:
-		err := this.processOnclause(right.Alias(), node.Onclause(), node.Outer())
+		err := this.processOnclause(right.Alias(), node.Onclause(), node.Outer(), false)
 		if err != nil {
 			return nil, err
 		}
@@ -149,6 +154,144 @@ func (this *builder) buildAnsiJoin(node *algebra.AnsiJoin) (op plan.Operator, er
 	}
 }
 
+// RIGHT and FULL OUTER JOIN need the unmatched documents of the right-hand side,
+// hash join returns them from its hash table, nested-loop join scans the right-hand
+// side once and keeps it until the left-hand side is done
+func (this *builder) buildAnsiRightOuterJoin(node *algebra.AnsiJoin) (op plan.Operator, err error) {
+	err = this.processOnclause(node.Alias(), node.Onclause(), true, true)
+	if err != nil {
+		return nil, err
+	}
+
+	this.extractPredicates(nil, node.Onclause())
+
+	right := node.Right()
+	if util.IsFeatureEnabled(this.featureControls, util.N1QL_HASH_JOIN) && !right.PreferNL() {
+		jps := this.saveJoinPlannerState()
+		hjoin, err := this.buildHashJoin(node)
+		if err != nil {
+			return nil, err
+		}
+		if hjoin != nil {
+			return hjoin, nil
+		}
+		this.restoreJoinPlannerState(jps)
+	}
+
+	scans, newOnclause, err := this.buildRightOuterJoinScan(right, node.Onclause())
+	if err != nil {
+		return nil, err
+	}
+
+	if scans == nil {
+		return nil, errors.NewOuterJoinCorrelatedError(strings.ToUpper(node.JoinType().String())+" JOIN", node.Alias())
+	}
+
+	if newOnclause != nil {
+		node.SetOnclause(newOnclause)
+	}
+
+	return plan.NewNLJoin(node, plan.NewSequence(scans...), OPT_COST_NOT_AVAIL, OPT_CARD_NOT_AVAIL), nil
+}
+
+// The right-hand side of a nested-loop RIGHT or FULL OUTER JOIN is scanned once,
+// so, as for hash join, it cannot depend on the left-hand side
+func (this *builder) buildRightOuterJoinScan(right algebra.SimpleFromTerm, onclause expression.Expression) (
+	[]plan.Operator, expression.Expression, error) {
+
+	ksterm := algebra.GetKeyspaceTerm(right)
+	if ksterm != nil {
+		right = ksterm
+	}
+
+	switch right := right.(type) {
+	case *algebra.KeyspaceTerm:
+		if ksterm.Keys() != nil && ksterm.Keys().Static() == nil {
+			return nil, nil, nil
+		}
+	case *algebra.ExpressionTerm:
+		if right.IsCorrelated() {
+			return nil, nil, nil
+		}
+	case *algebra.SubqueryTerm:
+		if right.Subquery().IsCorrelated() {
+			return nil, nil, nil
+		}
+	default:
+		return nil, nil, errors.NewPlanInternalError(fmt.Sprintf("buildRightOuterJoinScan: unexpected right-hand side node type"))
+	}
+
+	baseKeyspace, _ := this.baseKeyspaces[right.Alias()]
+	filters := baseKeyspace.Filters()
+	if filters != nil {
+		filters.ClearPlanFlags()
+	}
+
+	children := this.children
+	subChildren := this.subChildren
+	coveringScans := this.coveringScans
+	countScan := this.countScan
+	orderScan := this.orderScan
+	lastOp := this.lastOp
+	indexPushDowns := this.storeIndexPushDowns()
+	defer func() {
+		this.children = children
+		this.subChildren = subChildren
+		this.countScan = countScan
+		this.orderScan = orderScan
+		this.lastOp = lastOp
+		this.restoreIndexPushDowns(indexPushDowns, true)
+
+		if len(this.coveringScans) > 0 {
+			this.coveringScans = append(coveringScans, this.coveringScans...)
+		} else {
+			this.coveringScans = coveringScans
+		}
+	}()
+
+	this.children = make([]plan.Operator, 0, 16)
+	this.subChildren = nil
+	this.coveringScans = nil
+	this.countScan = nil
+	this.order = nil
+	this.orderScan = nil
+	this.limit = nil
+	this.offset = nil
+	this.lastOp = nil
+
+	// join filters are not used for index selection, as with hash join
+	if ksterm != nil {
+		ksterm.SetUnderHash()
+		defer func() {
+			ksterm.UnsetUnderHash()
+		}()
+	}
+
+	_, err := right.Accept(this)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(this.children) == 0 {
+		return nil, nil, errors.NewNoAnsiJoinError(right.Alias(), "join")
+	}
+
+	// perform cover transformation for ON-clause, for both sides
+	newOnclause := onclause.Copy()
+	for _, ops := range [][]plan.CoveringOperator{this.coveringScans, coveringScans} {
+		for _, op := range ops {
+			coverer := expression.NewCoverer(op.Covers(), op.FilterCovers())
+
+			newOnclause, err = coverer.Map(newOnclause)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	return this.children, newOnclause, nil
+}
+
 func (this *builder) buildAnsiNest(node *algebra.AnsiNest) (op plan.Operator, err error) {
 	right := node.Right()
 
@@ -160,7 +303,7 @@ func (this *builder) buildAnsiNest(node *algebra.AnsiNest) (op plan.Operator, er
 
 	switch right := right.(type) {
 	case *algebra.KeyspaceTerm:
-		err := this.processOnclause(right.Alias(), node.Onclause(), node.Outer())
+		err := this.processOnclause(right.Alias(), node.Onclause(), node.Outer(), false)
 		if err != nil {
 			return nil, err
 		}
@@ -274,7 +417,9 @@ func (this *builder) buildAnsiNest(node *algebra.AnsiNest) (op plan.Operator, er
 	}
 }
 
-func (this *builder) processOnclause(alias string, onclause expression.Expression, outer bool) (err error) {
+// rightOuter is for RIGHT and FULL OUTER JOIN, whose ON-clause must not filter
+// the right-hand side
+func (this *builder) processOnclause(alias string, onclause expression.Expression, outer, rightOuter bool) (err error) {
 	baseKeyspace, ok := this.baseKeyspaces[alias]
 	if !ok {
 		return errors.NewPlanInternalError(fmt.Sprintf("processOnclause: missing baseKeyspace %s", alias))
@@ -300,7 +445,7 @@ func (this *builder) processOnclause(alias string, onclause expression.Expressio
 		}
 	}
 
-	err = CombineFilters(baseKeyspace, true)
+	err = CombineFilters(baseKeyspace, !rightOuter)
 	if err != nil {
 		return err
 	}
@@ -468,7 +613,8 @@ func (this *builder) buildAnsiJoinScan(node *algebra.KeyspaceTerm, onclause expr
 }
 
 func (this *builder) buildHashJoin(node *algebra.AnsiJoin) (hjoin *plan.HashJoin, err error) {
-	child, buildExprs, probeExprs, aliases, newOnclause, cost, cardinality, err := this.buildHashJoinScan(node.Right(), node.Outer(), node.Onclause(), "join")
+	child, buildExprs, probeExprs, aliases, newOnclause, cost, cardinality, err := this.buildHashJoinScan(node.Right(), node.Outer(),
+		node.RightOuter(), node.Onclause(), "join")
 	if err != nil || child == nil {
 		// cannot do hash join
 		return nil, err
@@ -480,7 +626,8 @@ func (this *builder) buildHashJoin(node *algebra.AnsiJoin) (hjoin *plan.HashJoin
 }
 
 func (this *builder) buildHashNest(node *algebra.AnsiNest) (hnest *plan.HashNest, err error) {
-	child, buildExprs, probeExprs, aliases, newOnclause, cost, cardinality, err := this.buildHashJoinScan(node.Right(), node.Outer(), node.Onclause(), "nest")
+	child, buildExprs, probeExprs, aliases, newOnclause, cost, cardinality, err := this.buildHashJoinScan(node.Right(), node.Outer(),
+		false, node.Onclause(), "nest")
 	if err != nil || child == nil {
 		// cannot do hash nest
 		return nil, err
@@ -494,7 +641,7 @@ func (this *builder) buildHashNest(node *algebra.AnsiNest) (hnest *plan.HashNest
 	return plan.NewHashNest(node, child, buildExprs, probeExprs, aliases[0], cost, cardinality), nil
 }
 
-func (this *builder) buildHashJoinScan(right algebra.SimpleFromTerm, outer bool,
+func (this *builder) buildHashJoinScan(right algebra.SimpleFromTerm, outer, rightOuter bool,
 	onclause expression.Expression, op string) (
 	child plan.Operator, buildExprs expression.Expressions, probeExprs expression.Expressions,
 	buildAliases []string, newOnclause expression.Expression, cost, cardinality float64, err error) {
@@ -515,7 +662,7 @@ func (this *builder) buildHashJoinScan(right algebra.SimpleFromTerm, outer bool,
 		if ksterm.Keys() != nil && ksterm.Keys().Static() == nil {
 			return nil, nil, nil, nil, nil, OPT_COST_NOT_AVAIL, OPT_CARD_NOT_AVAIL, nil
 		}
-		keyspace = ksterm.Keyspace()
+		keyspace = qualifiedKeyspace(ksterm.Path(), this.namespace)
 	case *algebra.ExpressionTerm:
 		// hash join cannot handle expression term with any correlated references
 		if right.IsCorrelated() {
@@ -539,13 +686,20 @@ func (this *builder) buildHashJoinScan(right algebra.SimpleFromTerm, outer bool,
 	force := true
 	joinHint := right.JoinHint()
 	if joinHint == algebra.USE_HASH_BUILD {
+		// right outer join cannot build on dominant side either
+		if rightOuter && !outer {
+			return nil, nil, nil, nil, nil, OPT_COST_NOT_AVAIL, OPT_CARD_NOT_AVAIL, nil
+		}
 		buildRight = true
 	} else if joinHint == algebra.USE_HASH_PROBE {
-		// in case of outer join, cannot build on dominant side
+		// in case of outer join, cannot build on dominant side, unless both
+		// sides are dominant (full outer join)
 		// also in case of nest, can only build on right-hand-side
-		if outer || op == "nest" {
+		if (outer && !rightOuter) || op == "nest" {
 			return nil, nil, nil, nil, nil, OPT_COST_NOT_AVAIL, OPT_CARD_NOT_AVAIL, nil
 		}
+	} else if rightOuter && !outer {
+		// for right outer join, must build on left-hand side
 	} else if outer || op == "nest" {
 		// for outer join or nest, must build on right-hand side
 		buildRight = true
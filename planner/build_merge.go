@@ -23,9 +23,14 @@ func (this *builder) VisitMerge(stmt *algebra.Merge) (interface{}, error) {
 	source := stmt.Source()
 
 	this.baseKeyspaces = make(map[string]*base.BaseKeyspace, _MAP_KEYSPACE_CAP)
-	sourceKeyspace := base.NewBaseKeyspace(source.Alias(), source.Keyspace())
+	sourceKeyspaceName := ""
+	if source.From() != nil {
+		sourceKeyspaceName = qualifiedKeyspace(source.From().Path(), this.namespace)
+	}
+	sourceKeyspace := base.NewBaseKeyspace(source.Alias(), sourceKeyspaceName)
 	this.baseKeyspaces[sourceKeyspace.Name()] = sourceKeyspace
-	targetKeyspace := base.NewBaseKeyspace(stmt.KeyspaceRef().Alias(), stmt.KeyspaceRef().Keyspace())
+	targetKeyspace := base.NewBaseKeyspace(stmt.KeyspaceRef().Alias(),
+		qualifiedKeyspace(stmt.KeyspaceRef().Path(), this.namespace))
 	this.baseKeyspaces[targetKeyspace.Name()] = targetKeyspace
 
 	var left algebra.SimpleFromTerm
@@ -80,7 +85,7 @@ func (this *builder) VisitMerge(stmt *algebra.Merge) (interface{}, error) {
 	ksref := stmt.KeyspaceRef()
 	ksref.SetDefaultNamespace(this.namespace)
 
-	keyspace, err := this.getNameKeyspace(ksref.Namespace(), ksref.Keyspace())
+	keyspace, err := this.getNameKeyspace(ksref)
 	if err != nil {
 		return nil, err
 	}
@@ -139,7 +144,7 @@ func (this *builder) VisitMerge(stmt *algebra.Merge) (interface{}, error) {
 		} else {
 			keyExpr = act.Key()
 		}
-		ops = append(ops, plan.NewSendInsert(keyspace, ksref.Alias(), keyExpr, act.Value(), stmt.Limit()))
+		ops = append(ops, plan.NewSendInsert(keyspace, ksref.Alias(), keyExpr, act.Value(), act.Options(), stmt.Limit()))
 		insert = plan.NewSequence(ops...)
 	}
 
@@ -57,7 +57,7 @@ func (this *builder) VisitPrepare(stmt *algebra.Prepare) (interface{}, error) {
 	}
 
 	prep, err = BuildPrepared(stmt.Statement(), this.datastore, this.systemstore, this.namespace, false, true,
-		this.namedArgs, this.positionalArgs, this.indexApiVersion, this.featureControls)
+		this.namedArgs, this.positionalArgs, this.indexApiVersion, this.featureControls, false)
 	if err != nil {
 		return nil, err
 	}
@@ -10,21 +10,108 @@
 package planner
 
 import (
+	"strings"
+
 	"github.com/couchbase/query/algebra"
 	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/expression"
 	"github.com/couchbase/query/plan"
 	"github.com/couchbase/query/value"
 )
 
 func BuildPrepared(stmt algebra.Statement, datastore, systemstore datastore.Datastore,
 	namespace string, subquery, stream bool, namedArgs map[string]value.Value, positionalArgs value.Values,
-	indexApiVersion int, featureControls uint64) (*plan.Prepared, error) {
+	indexApiVersion int, featureControls uint64, inTransaction bool) (*plan.Prepared, error) {
 	operator, err := Build(stmt, datastore, systemstore, namespace, subquery, stream, namedArgs, positionalArgs,
-		indexApiVersion, featureControls)
+		indexApiVersion, featureControls, inTransaction)
 	if err != nil {
 		return nil, err
 	}
 
 	signature := stmt.Signature()
-	return plan.NewPrepared(operator, signature), nil
+	prepared := plan.NewPrepared(operator, signature)
+	if sel, ok := stmt.(*algebra.Select); ok {
+		prepared.SetUseCache(sel.UseCache())
+		prepared.SetCacheable(!readsSystem(sel, namespace) && !hasVolatile(sel.Expressions()))
+	}
+	return prepared, nil
+}
+
+// system keyspaces can't be invalidated, so statements reading
+// from them, anywhere in the statement, can't have their results cached
+func readsSystem(sel *algebra.Select, namespace string) bool {
+	if subresultReadsSystem(sel.Subresult(), namespace) {
+		return true
+	}
+	subqueries, err := expression.ListSubqueries(sel.Expressions(), false)
+	if err != nil {
+		return true
+	}
+	for _, subquery := range subqueries {
+		sq, ok := subquery.(*algebra.Subquery)
+		if !ok || readsSystem(sq.Select(), namespace) {
+			return true
+		}
+	}
+	return false
+}
+
+// functions such as NOW_STR(), RANDOM() or UUID() give different results
+// on every execution, so statements using them can't have their results cached
+func hasVolatile(exprs expression.Expressions) bool {
+	for _, expr := range exprs {
+		if _, ok := expr.(expression.Function); ok && expr.ExprBase().HasExprFlag(expression.EXPR_IS_VOLATILE) {
+			return true
+		}
+		if hasVolatile(expr.Children()) {
+			return true
+		}
+	}
+	return false
+}
+
+func subresultReadsSystem(subresult algebra.Subresult, namespace string) bool {
+	switch subresult := subresult.(type) {
+	case *algebra.Subselect:
+		return subresult.From() != nil && fromReadsSystem(subresult.From(), namespace)
+	case *algebra.SelectTerm:
+		return readsSystem(subresult.Select(), namespace)
+	case interface {
+		First() algebra.Subresult
+		Second() algebra.Subresult
+	}:
+		return subresultReadsSystem(subresult.First(), namespace) ||
+			subresultReadsSystem(subresult.Second(), namespace)
+	}
+	return true
+}
+
+func fromReadsSystem(term algebra.FromTerm, namespace string) bool {
+	switch term := term.(type) {
+	case *algebra.KeyspaceTerm:
+		ns := term.Namespace()
+		if ns == "" {
+			ns = namespace
+		}
+		return strings.ToLower(ns) == "#system"
+	case *algebra.ExpressionTerm:
+		return term.IsKeyspace() && fromReadsSystem(term.KeyspaceTerm(), namespace)
+	case *algebra.SubqueryTerm:
+		return readsSystem(term.Subquery(), namespace)
+	case *algebra.Join:
+		return fromReadsSystem(term.Left(), namespace) || fromReadsSystem(term.Right(), namespace)
+	case *algebra.IndexJoin:
+		return fromReadsSystem(term.Left(), namespace) || fromReadsSystem(term.Right(), namespace)
+	case *algebra.AnsiJoin:
+		return fromReadsSystem(term.Left(), namespace) || fromReadsSystem(term.Right(), namespace)
+	case *algebra.Nest:
+		return fromReadsSystem(term.Left(), namespace) || fromReadsSystem(term.Right(), namespace)
+	case *algebra.IndexNest:
+		return fromReadsSystem(term.Left(), namespace) || fromReadsSystem(term.Right(), namespace)
+	case *algebra.AnsiNest:
+		return fromReadsSystem(term.Left(), namespace) || fromReadsSystem(term.Right(), namespace)
+	case *algebra.Unnest:
+		return fromReadsSystem(term.Left(), namespace)
+	}
+	return true
 }
@@ -55,6 +55,15 @@ func (this *builder) selectScan(keyspace datastore.Keyspace, node *algebra.Keysp
 func (this *builder) buildScan(keyspace datastore.Keyspace, node *algebra.KeyspaceTerm) (
 	secondary plan.Operator, primary plan.Operator, err error) {
 
+	// indexes do not see the mutations staged by a transaction: documents
+	// must be fetched, then filtered, ordered and aggregated
+	if this.inTransaction {
+		cover := this.cover
+		this.cover = nil
+		this.resetPushDowns()
+		defer func() { this.cover = cover }()
+	}
+
 	join := node.IsAnsiJoinOp()
 	hash := node.IsUnderHash()
 
@@ -121,7 +121,8 @@ outer:
 		for c, _ := range covering {
 			entry := indexes[c]
 			if entry.cost <= 0.0 {
-				cost, _, card, e := indexScanCost(entry.index, entry.sargKeys, this.requestId, entry.spans, node.Alias())
+				cost, _, card, e := indexScanCost(entry.index, entry.sargKeys, this.requestId, entry.spans, node.Alias(),
+					baseKeyspace.Keyspace())
 				if e != nil || (cost <= 0.0 || card <= 0.0) {
 					useCBO = false
 				} else {
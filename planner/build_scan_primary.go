@@ -51,7 +51,7 @@ func (this *builder) buildPrimaryScan(keyspace datastore.Keyspace, node *algebra
 		cost := OPT_COST_NOT_AVAIL
 		cardinality := OPT_CARD_NOT_AVAIL
 		if this.useCBO {
-			cost, cardinality = primaryIndexScanCost(primary, this.requestId)
+			cost, cardinality = primaryIndexScanCost(primary, keyspace.QualifiedName(), this.requestId)
 		}
 		return plan.NewPrimaryScan3(primary3, keyspace, node, this.offset, this.limit,
 			plan.NewIndexProjection(0, true), indexOrder, nil, cost, cardinality), nil
@@ -86,9 +86,9 @@ func (this *builder) buildCoveringPrimaryScan(keyspace datastore.Keyspace, node
 	secondaries := map[datastore.Index]*indexEntry{primary: entry}
 
 	pred := expression.NewIsNotNull(id)
-	baseKeyspace := base.NewBaseKeyspace(node.Alias(), node.Keyspace())
+	baseKeyspace := base.NewBaseKeyspace(node.Alias(), qualifiedKeyspace(node.Path(), this.namespace))
 	keyspaces := make(map[string]string, 1)
-	keyspaces[node.Alias()] = node.Keyspace()
+	keyspaces[node.Alias()] = baseKeyspace.Keyspace()
 	newfilter := base.NewFilter(pred, pred, keyspaces, false, false)
 	baseKeyspace.AddFilter(newfilter)
 	baseKeyspace.SetPreds(pred, nil, nil)
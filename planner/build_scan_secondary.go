@@ -395,11 +395,12 @@ func (this *builder) minimalIndexes(sargables map[datastore.Index]*indexEntry, s
 	pred expression.Expression, node *algebra.KeyspaceTerm) map[datastore.Index]*indexEntry {
 
 	alias := node.Alias()
+	keyspace := qualifiedKeyspace(node.Path(), this.namespace)
 	useCBO := this.useCBO
 
 	for s, se := range sargables {
 		if this.useCBO && se.cost <= 0.0 {
-			cost, selec, card, e := indexScanCost(se.index, se.sargKeys, this.requestId, se.spans, alias)
+			cost, selec, card, e := indexScanCost(se.index, se.sargKeys, this.requestId, se.spans, alias, keyspace)
 			if e != nil || (cost <= 0.0 || card <= 0.0) {
 				useCBO = false
 			} else {
@@ -427,7 +428,8 @@ func (this *builder) minimalIndexes(sargables map[datastore.Index]*indexEntry, s
 		}
 	}
 
-	if useCBO && !shortest && len(sargables) > 1 {
+	// choose the intersect scan when the index costs are available
+	if useCBO && shortest == optIntersectAfterSarg() && len(sargables) > 1 {
 		sargables = this.chooseIntersectScan(sargables, node)
 	}
 
@@ -383,7 +383,7 @@ func (this *builder) matchUnnest(node *algebra.KeyspaceTerm, pred expression.Exp
 	baseKeyspace, _ := this.baseKeyspaces[node.Alias()]
 	if this.useCBO {
 		keyspaces := make(map[string]string, 1)
-		keyspaces[node.Alias()] = node.Keyspace()
+		keyspaces[node.Alias()] = baseKeyspace.Keyspace()
 		for _, fl := range baseKeyspace.Filters() {
 			if fl.IsUnnest() {
 				sel := getUnnestPredSelec(fl.FltrExpr(), node.Alias(), unnest.As(), unnest.Expression(), keyspaces)
@@ -427,7 +427,8 @@ func (this *builder) matchUnnest(node *algebra.KeyspaceTerm, pred expression.Exp
 	cardinality := OPT_CARD_NOT_AVAIL
 	selectivity := OPT_SELEC_NOT_AVAIL
 	if this.useCBO {
-		cost, selectivity, cardinality, err = indexScanCost(entry.index, sargKeys, this.requestId, spans, node.Alias())
+		cost, selectivity, cardinality, err = indexScanCost(entry.index, sargKeys, this.requestId, spans, node.Alias(),
+			qualifiedKeyspace(node.Path(), this.namespace))
 		if err != nil {
 			cost = OPT_COST_NOT_AVAIL
 			cardinality = OPT_CARD_NOT_AVAIL
@@ -0,0 +1,69 @@
+//  Copyright (c) 2020 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package planner
+
+import (
+	"github.com/couchbase/query/algebra"
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/plan"
+)
+
+func (this *builder) VisitCreateScope(stmt *algebra.CreateScope) (interface{}, error) {
+	stmt.SetDefaultNamespace(this.namespace)
+	bucket, err := this.getPathBucket(stmt.Path())
+	if err != nil {
+		return nil, err
+	}
+	return plan.NewCreateScope(bucket, stmt), nil
+}
+
+func (this *builder) VisitDropScope(stmt *algebra.DropScope) (interface{}, error) {
+	stmt.SetDefaultNamespace(this.namespace)
+	bucket, err := this.getPathBucket(stmt.Path())
+	if err != nil {
+		return nil, err
+	}
+	return plan.NewDropScope(bucket, stmt), nil
+}
+
+func (this *builder) VisitCreateCollection(stmt *algebra.CreateCollection) (interface{}, error) {
+	stmt.SetDefaultNamespace(this.namespace)
+	scope, err := this.getPathScope(stmt.Path())
+	if err != nil {
+		return nil, err
+	}
+	return plan.NewCreateCollection(scope, stmt), nil
+}
+
+func (this *builder) VisitDropCollection(stmt *algebra.DropCollection) (interface{}, error) {
+	stmt.SetDefaultNamespace(this.namespace)
+	scope, err := this.getPathScope(stmt.Path())
+	if err != nil {
+		return nil, err
+	}
+	return plan.NewDropCollection(scope, stmt), nil
+}
+
+func (this *builder) getPathBucket(path *algebra.Path) (datastore.Bucket, errors.Error) {
+	namespace, err := this.getNamespace(path.Namespace())
+	if err != nil {
+		return nil, err
+	}
+	return namespace.BucketByName(path.Bucket())
+}
+
+func (this *builder) getPathScope(path *algebra.Path) (datastore.Scope, errors.Error) {
+	bucket, err := this.getPathBucket(path)
+	if err != nil {
+		return nil, err
+	}
+	return bucket.ScopeByName(path.Scope())
+}
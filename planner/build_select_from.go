@@ -36,7 +36,7 @@ func (this *builder) visitFrom(node *algebra.Subselect, group *algebra.Group) er
 
 		// gather keyspace references
 		this.baseKeyspaces = make(map[string]*base.BaseKeyspace, _MAP_KEYSPACE_CAP)
-		keyspaceFinder := newKeyspaceFinder(this.baseKeyspaces, this.from.PrimaryTerm().Alias())
+		keyspaceFinder := newKeyspaceFinder(this.baseKeyspaces, this.from.PrimaryTerm().Alias(), this.namespace)
 		_, err := node.From().Accept(keyspaceFinder)
 		if err != nil {
 			return err
@@ -236,7 +236,7 @@ func (this *builder) VisitExpressionTerm(node *algebra.ExpressionTerm) (interfac
 	if this.useCBO {
 		keyspaces := make(map[string]string, len(this.baseKeyspaces))
 		for _, bks := range this.baseKeyspaces {
-			keyspaces[bks.Name()] = keyspaces[bks.Keyspace()]
+			keyspaces[bks.Name()] = bks.Keyspace()
 		}
 		cost, cardinality = getExpressionScanCost(node.ExpressionTerm(), keyspaces)
 	}
@@ -344,7 +344,7 @@ func (this *builder) VisitAnsiJoin(node *algebra.AnsiJoin) (interface{}, error)
 	this.requirePrimaryKey = true
 
 	if term, ok := node.PrimaryTerm().(*algebra.ExpressionTerm); ok && term.IsKeyspace() &&
-		this.group == nil && node.Right().JoinHint() != algebra.USE_HASH_PROBE {
+		this.group == nil && node.Right().JoinHint() != algebra.USE_HASH_PROBE && !node.RightOuter() {
 		this.resetProjection()
 		this.resetIndexGroupAggs()
 		this.resetOffsetLimit()
@@ -362,10 +362,12 @@ func (this *builder) VisitAnsiJoin(node *algebra.AnsiJoin) (interface{}, error)
 		return nil, err
 	}
 
-	switch join := join.(type) {
-	case *plan.NLJoin:
+	// nested-loop join runs in the data-parallel streams, except for RIGHT and
+	// FULL OUTER JOIN, whose unmatched right-hand side documents are only known
+	// once the whole left-hand side has been joined
+	if nljoin, ok := join.(*plan.NLJoin); ok && !nljoin.RightOuter() {
 		this.subChildren = append(this.subChildren, join)
-	case *plan.Join, *plan.HashJoin:
+	} else {
 		if len(this.subChildren) > 0 {
 			parallel := plan.NewParallel(plan.NewSequence(this.subChildren...), this.maxParallelism)
 			this.children = append(this.children, parallel)
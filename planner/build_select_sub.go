@@ -169,6 +169,14 @@ func (this *builder) VisitSubselect(node *algebra.Subselect) (interface{}, error
 		}
 	}
 
+	// Keys rolled up by grouping sets are NULL in the groups of those sets
+	if group != nil && group.GroupingSets() != nil {
+		err = this.coverGroupingSetKeys(node, group)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// Identify aggregates for index pushdown for old releases
 	if len(aggs) == 1 && len(group.By()) == 0 {
 	loop:
@@ -369,11 +377,12 @@ func (this *builder) visitGroup(group *algebra.Group, aggs algebra.Aggregates) {
 
 	if partial {
 		aggv := sortAggregatesSlice(aggs)
-		this.subChildren = append(this.subChildren, plan.NewInitialGroup(group.By(), aggv))
+		sets := group.GroupingSets()
+		this.subChildren = append(this.subChildren, plan.NewInitialGroup(group.By(), aggv, sets))
 		this.children = append(this.children,
 			plan.NewParallel(plan.NewSequence(this.subChildren...), this.maxParallelism))
-		this.children = append(this.children, plan.NewIntermediateGroup(group.By(), aggv))
-		this.children = append(this.children, plan.NewFinalGroup(group.By(), aggv))
+		this.children = append(this.children, plan.NewIntermediateGroup(group.By(), aggv, sets))
+		this.children = append(this.children, plan.NewFinalGroup(group.By(), aggv, sets))
 		this.subChildren = make([]plan.Operator, 0, 8)
 	}
 
@@ -817,6 +826,12 @@ func skipFixedOrderTerms(order *algebra.Order, pred expression.Expression) *alge
 func (this *builder) setIndexGroupAggs(group *algebra.Group, aggs algebra.Aggregates, let expression.Bindings) {
 
 	if group != nil {
+		// Grouping sets group each item more than once, disable pushdowns
+		if group.GroupingSets() != nil {
+			this.resetPushDowns()
+			return
+		}
+
 		// Group or Aggregates Depends on LET disable pushdowns
 		for _, expr := range group.By() {
 			if !expr.IndexAggregatable() || dependsOnLet(expr, let) {
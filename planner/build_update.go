@@ -19,7 +19,7 @@ func (this *builder) VisitUpdate(stmt *algebra.Update) (interface{}, error) {
 	this.node = stmt
 
 	ksref := stmt.KeyspaceRef()
-	keyspace, err := this.getNameKeyspace(ksref.Namespace(), ksref.Keyspace())
+	keyspace, err := this.getNameKeyspace(ksref)
 	if err != nil {
 		return nil, err
 	}
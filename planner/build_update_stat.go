@@ -16,10 +16,20 @@ import (
 
 func (this *builder) VisitUpdateStatistics(stmt *algebra.UpdateStatistics) (interface{}, error) {
 	ksref := stmt.Keyspace()
-	keyspace, err := this.getNameKeyspace(ksref.Namespace(), ksref.Keyspace())
+	keyspace, err := this.getNameKeyspace(ksref)
 	if err != nil {
 		return nil, err
 	}
 
 	return plan.NewUpdateStatistics(keyspace, stmt), nil
 }
+
+func (this *builder) VisitDeleteStatistics(stmt *algebra.DeleteStatistics) (interface{}, error) {
+	ksref := stmt.Keyspace()
+	keyspace, err := this.getNameKeyspace(ksref)
+	if err != nil {
+		return nil, err
+	}
+
+	return plan.NewDeleteStatistics(keyspace, stmt), nil
+}
@@ -0,0 +1,1298 @@
+//  Copyright (c) 2020 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+// +build !enterprise
+
+package planner
+
+import (
+	"fmt"
+	"hash/crc32"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/couchbase/query/algebra"
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/plan"
+	base "github.com/couchbase/query/plannerbase"
+	"github.com/couchbase/query/value"
+)
+
+/*
+Rule based index advisor.
+
+While ADVISE plans the statement, the filters of each keyspace are collected.
+Each sargable filter contributes an index key, ranked by predicate type, and the
+keys are ordered by rank. Equality predicates on string constants become the
+condition of a partial index, as long as there are other keys.
+For disjunctions, the keys all the disjuncts have in common lead the index.
+If the projection, ORDER BY, GROUP BY and the remaining filters of a SELECT can
+be served by the index alone, a covering index is recommended as well.
+*/
+
+const (
+	_RANK_LEADING    = 1
+	_RANK_EQ         = 2
+	_RANK_IN         = 3
+	_RANK_INCL_RANGE = 4
+	_RANK_RANGE      = 5
+	_RANK_ARRAY      = 6
+	_RANK_NOT_NULL   = 8
+	_RANK_FUNCTION   = 9
+	_RANK_JOIN       = 10
+	_RANK_FLAVOR     = 11
+)
+
+var _RANK_RULES = map[int]string{
+	_RANK_LEADING:    "1. leading array index for unnest",
+	_RANK_EQ:         "2. equality/null/missing",
+	_RANK_IN:         "3. in",
+	_RANK_INCL_RANGE: "4. not less than/between/not greater than",
+	_RANK_RANGE:      "5. less than/between/greater than",
+	_RANK_ARRAY:      "6. array predicate",
+	_RANK_NOT_NULL:   "8. not null/not missing/valued",
+	_RANK_FUNCTION:   "9. function index",
+	_RANK_JOIN:       "10. non-static join predicate",
+	_RANK_FLAVOR:     "11. flavor for partial index",
+}
+
+const (
+	_ADVISE_RULE_PREFIX     = "Index keys follow order of predicate types: "
+	_ADVISE_COMMON_LEADING  = "1. Common leading key for disjunction"
+	_ADVISE_OPTIMAL         = "THIS IS AN OPTIMAL INDEX."
+	_ADVISE_OPTIMAL_COVER   = "THIS IS AN OPTIMAL COVERING INDEX."
+	_ADVISE_INDEX_PREFIX    = "adv_"
+	_ADVISE_INDEX_NAME_SIZE = 100
+)
+
+// the filters of a keyspace, or of one disjunct of its predicate
+type advKeyspaceInfo struct {
+	keyspace    datastore.Keyspace
+	filters     base.Filters
+	joinFilters base.Filters
+	disjunct    bool
+}
+
+type advKeyspaceInfos []*advKeyspaceInfo
+
+func newAdvKeyspaceInfo(keyspace datastore.Keyspace, filters, joinFilters base.Filters, disjunct bool) *advKeyspaceInfo {
+	return &advKeyspaceInfo{
+		keyspace:    keyspace,
+		filters:     filters,
+		joinFilters: joinFilters,
+		disjunct:    disjunct,
+	}
+}
+
+// what is collected for a query block, and the advice for it
+type queryInfo struct {
+	stmtType      string
+	let           expression.Bindings
+	groupBy       expression.Expressions
+	letting       expression.Bindings
+	projection    algebra.ResultTerms
+	order         algebra.SortTerms
+	keyspaceFound bool
+	unnest        bool
+	unnests       []*algebra.Unnest
+	primary       bool
+	done          map[*algebra.KeyspaceTerm]bool
+	curIndexes    plan.AdvisedIndexes
+	indexes       plan.AdvisedIndexes
+	coverIndexes  plan.AdvisedIndexes
+}
+
+func newQueryInfo(stmtType string) *queryInfo {
+	return &queryInfo{
+		stmtType: stmtType,
+		done:     make(map[*algebra.KeyspaceTerm]bool, 1),
+	}
+}
+
+func (this *queryInfo) adviceInfo() *plan.IndexAdviceInfo {
+	return plan.NewIndexAdviceInfo(this.curIndexes, this.indexes, this.coverIndexes, this.primary)
+}
+
+// an index key, as a formalized expression
+type advKey struct {
+	expr   expression.Expression
+	rank   int
+	flavor expression.Expression // the equality with a string constant, for partial indexes
+}
+
+type advKeys []*advKey
+
+func (this advKeys) find(expr expression.Expression) *advKey {
+	for _, key := range this {
+		if key.expr.EquivalentTo(expr) {
+			return key
+		}
+	}
+	return nil
+}
+
+// add a key, keeping the best rank when the key is already there
+func (this advKeys) add(key *advKey) advKeys {
+	if old := this.find(key.expr); old != nil {
+		if key.rank < old.rank {
+			old.rank = key.rank
+		}
+		if key.flavor == nil {
+			old.flavor = nil
+		}
+		return this
+	}
+	return append(this, &advKey{expr: key.expr, rank: key.rank, flavor: key.flavor})
+}
+
+// ordered by rank, with a single array key, as indexes can't have more
+func (this advKeys) sorted() advKeys {
+	rv := make(advKeys, 0, len(this))
+	array := false
+	for _, key := range this {
+		if _, ok := key.expr.(*expression.All); ok {
+			if array {
+				continue
+			}
+			array = true
+		}
+		rv = append(rv, key)
+	}
+	sort.SliceStable(rv, func(i, j int) bool {
+		return rv[i].rank < rv[j].rank
+	})
+	return rv
+}
+
+func (this advKeys) exprs() expression.Expressions {
+	rv := make(expression.Expressions, len(this))
+	for i, key := range this {
+		rv[i] = key.expr
+	}
+	return rv
+}
+
+// a recommended index
+type advIndex struct {
+	keys    advKeys
+	flavors advKeys
+	common  int // leading keys common to all disjuncts
+}
+
+// the keys of a conjunction: string equalities become the index condition
+// if there is anything else to index
+func newAdvIndex(keys advKeys) *advIndex {
+	if len(keys) == 0 {
+		return nil
+	}
+	keys = keys.sorted()
+	rv := &advIndex{}
+	for _, key := range keys {
+		if key.flavor != nil {
+			rv.flavors = append(rv.flavors, key)
+		} else {
+			rv.keys = append(rv.keys, key)
+		}
+	}
+	if len(rv.keys) == 0 {
+		rv.keys = keys
+		rv.flavors = nil
+	}
+	return rv
+}
+
+func (this *advIndex) condition() expression.Expressions {
+	rv := make(expression.Expressions, len(this.flavors))
+	for i, flavor := range this.flavors {
+		rv[i] = flavor.flavor
+	}
+	return rv
+}
+
+func (this *advIndex) rule() string {
+	rules := make([]string, 0, len(this.keys)+1)
+	if this.common > 0 {
+		rules = append(rules, _ADVISE_COMMON_LEADING+" ("+strings.Join(rankRules(this.keys[:this.common]), ", ")+")")
+	}
+	rules = append(rules, rankRules(this.keys[this.common:])...)
+	if len(this.flavors) > 0 {
+		rules = append(rules, _RANK_RULES[_RANK_FLAVOR])
+	}
+	return _ADVISE_RULE_PREFIX + strings.Join(rules, ", ") + "."
+}
+
+func rankRules(keys advKeys) []string {
+	rv := make([]string, 0, len(keys))
+	last := 0
+	for _, key := range keys {
+		if key.rank != last {
+			rv = append(rv, _RANK_RULES[key.rank])
+			last = key.rank
+		}
+	}
+	return rv
+}
+
+/*
+Record the indexes the plan uses for a keyspace, and advise on the
+filters collected for it.
+*/
+func (this *queryInfo) addKeyspace(node *algebra.KeyspaceTerm, infos advKeyspaceInfos, indexes []datastore.Index, covering bool) {
+	// keyspaces can be planned more than once, as for joins, the first time counts
+	if this.done[node] || strings.ToLower(node.Namespace()) == "#system" {
+		return
+	}
+	this.done[node] = true
+
+	alias := node.Alias()
+	ksAlias := keyspaceAlias(node)
+	path := keyspacePath(node)
+
+	current := make(map[string]*plan.AdvisedIndex, len(indexes))
+	secondary := false
+	for _, index := range indexes {
+		current[index.Id()] = addAdvisedIndex(&this.curIndexes, &plan.AdvisedIndex{
+			Statement:     indexStatement(index, path),
+			KeyspaceAlias: ksAlias,
+		})
+		if !index.IsPrimary() {
+			secondary = true
+		}
+	}
+
+	var keyspace datastore.Keyspace
+	var recs []*advIndex
+	if len(infos) > 0 {
+		keyspace = infos[0].keyspace
+		recs = this.recommend(alias, infos)
+	}
+	if len(recs) == 0 {
+		if !secondary {
+			this.primary = true
+		}
+		return
+	}
+
+	for _, rec := range recs {
+		keys := rec.keys.exprs()
+		cond := rec.condition()
+		names := append(keys, rec.flavors.exprs()...)
+		entry := &plan.AdvisedIndex{
+			Statement:     advisedStatement(alias, path, names, keys, cond),
+			KeyspaceAlias: ksAlias,
+			Rule:          rec.rule(),
+		}
+		if this.existing(keyspace, alias, keys, cond, current, entry, _ADVISE_OPTIMAL) {
+			addAdvisedIndex(&this.indexes, entry)
+		}
+
+		extra, ok := this.coverKeys(alias, rec, infos)
+		if !ok {
+			continue
+		}
+		keys = append(keys, extra...)
+		names = append(names, extra...)
+		entry = &plan.AdvisedIndex{
+			Statement:     advisedStatement(alias, path, names, keys, cond),
+			KeyspaceAlias: ksAlias,
+		}
+		status := _ADVISE_OPTIMAL
+		if covering {
+			status = _ADVISE_OPTIMAL_COVER
+		}
+		if this.existing(keyspace, alias, keys, cond, current, entry, status) {
+			addAdvisedIndex(&this.coverIndexes, entry)
+		}
+	}
+}
+
+// flag a recommended index that exists already: if the plan uses it, it is
+// the current index which is marked, and there is nothing to recommend
+func (this *queryInfo) existing(keyspace datastore.Keyspace, alias string, keys, cond expression.Expressions,
+	current map[string]*plan.AdvisedIndex, entry *plan.AdvisedIndex, status string) bool {
+
+	index := findIndex(keyspace, alias, keys, cond)
+	if index == nil {
+		return true
+	}
+	if cur, ok := current[index.Id()]; ok {
+		if cur.Status != _ADVISE_OPTIMAL_COVER {
+			cur.Status = status
+		}
+		return false
+	}
+	state, _, _ := index.State()
+	entry.Status = fmt.Sprintf("SAME AS EXISTING INDEX %s (%s).", index.Name(), state)
+	return true
+}
+
+func addAdvisedIndex(indexes *plan.AdvisedIndexes, entry *plan.AdvisedIndex) *plan.AdvisedIndex {
+	for _, index := range *indexes {
+		if index.Statement == entry.Statement && index.KeyspaceAlias == entry.KeyspaceAlias {
+			return index
+		}
+	}
+	*indexes = append(*indexes, entry)
+	return entry
+}
+
+/*
+Recommend indexes for the filters of a keyspace
+*/
+func (this *queryInfo) recommend(alias string, infos advKeyspaceInfos) []*advIndex {
+	var rv []*advIndex
+	var disjuncts advKeyspaceInfos
+	for _, info := range infos {
+		if info.disjunct {
+			disjuncts = append(disjuncts, info)
+		} else if rec := newAdvIndex(this.filterKeys(alias, info.filters)); rec != nil {
+			rv = append(rv, rec)
+		}
+	}
+	if len(disjuncts) > 0 {
+		rv = append(rv, this.disjunctionIndexes(alias, disjuncts)...)
+	}
+	return rv
+}
+
+/*
+Disjuncts sharing keys are served by the same index, led by the keys
+they all have in common.
+*/
+func (this *queryInfo) disjunctionIndexes(alias string, infos advKeyspaceInfos) []*advIndex {
+	disjuncts := make([]advKeys, 0, len(infos))
+	for _, info := range infos {
+		keys := this.filterKeys(alias, info.filters)
+
+		// a disjunct no index can serve needs a primary scan
+		if len(keys) == 0 {
+			return nil
+		}
+		disjuncts = append(disjuncts, keys.sorted())
+	}
+
+	// group disjuncts that share keys
+	group := make([]int, len(disjuncts))
+	for i := range group {
+		group[i] = i
+	}
+	for i := range disjuncts {
+		for j := 0; j < i; j++ {
+			if group[i] != group[j] && shareKeys(disjuncts[i], disjuncts[j]) {
+				old := group[i]
+				for k := range group {
+					if group[k] == old {
+						group[k] = group[j]
+					}
+				}
+			}
+		}
+	}
+
+	rv := make([]*advIndex, 0, len(disjuncts))
+	for g := range disjuncts {
+		var members []advKeys
+		for i, d := range disjuncts {
+			if group[i] == g {
+				members = append(members, d)
+			}
+		}
+		switch len(members) {
+		case 0:
+		case 1:
+			rv = append(rv, newAdvIndex(members[0]))
+		default:
+			if rec := newDisjunctionIndex(members); rec != nil {
+				rv = append(rv, rec)
+			} else {
+				for _, m := range members {
+					rv = append(rv, newAdvIndex(m))
+				}
+			}
+		}
+	}
+	return rv
+}
+
+func shareKeys(keys1, keys2 advKeys) bool {
+	for _, key := range keys1 {
+		if keys2.find(key.expr) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func newDisjunctionIndex(disjuncts []advKeys) *advIndex {
+	var common advKeys
+	for _, key := range disjuncts[0] {
+		k := &advKey{expr: key.expr, rank: key.rank, flavor: key.flavor}
+		for _, d := range disjuncts[1:] {
+			other := d.find(key.expr)
+			if other == nil {
+				k = nil
+				break
+			}
+			if other.rank < k.rank {
+				k.rank = other.rank
+			}
+			if other.flavor == nil {
+				k.flavor = nil
+			}
+		}
+		if k != nil {
+			common = append(common, k)
+		}
+	}
+	if len(common) == 0 {
+		return nil
+	}
+
+	rv := &advIndex{}
+	var leading advKeys
+	for _, key := range common {
+		if key.flavor != nil {
+			rv.flavors = append(rv.flavors, key)
+		} else {
+			leading = append(leading, key)
+		}
+	}
+	if len(leading) == 0 {
+		leading = common
+		rv.flavors = nil
+	}
+	leading = leading.sorted()
+
+	var rest advKeys
+	for _, d := range disjuncts {
+		for _, key := range d {
+			if common.find(key.expr) == nil {
+				rest = rest.add(&advKey{expr: key.expr, rank: key.rank})
+			}
+		}
+	}
+
+	// still a single array key
+	for _, key := range leading {
+		if _, ok := key.expr.(*expression.All); ok {
+			rest = removeArrayKeys(rest)
+			break
+		}
+	}
+	rv.keys = append(leading, rest.sorted()...)
+	rv.common = len(leading)
+	return rv
+}
+
+func removeArrayKeys(keys advKeys) advKeys {
+	rv := make(advKeys, 0, len(keys))
+	for _, key := range keys {
+		if _, ok := key.expr.(*expression.All); !ok {
+			rv = append(rv, key)
+		}
+	}
+	return rv
+}
+
+/*
+The index keys of the sargable filters of a keyspace
+*/
+func (this *queryInfo) filterKeys(alias string, filters base.Filters) advKeys {
+	keys := make(advKeys, 0, len(filters))
+	for _, fl := range filters {
+		if fl.IsDerived() {
+			continue
+		}
+		allowed := map[string]bool{alias: true}
+		if fl.IsUnnest() {
+			for _, u := range this.unnests {
+				allowed[u.Alias()] = true
+			}
+		}
+		key := sargableKey(fl.FltrExpr(), alias, allowed, fl.IsJoin())
+		if key == nil || this.unnested(key.expr) {
+			continue
+		}
+
+		if fl.IsUnnest() && references(key.expr, this.unnestAliases()) {
+			key.expr = this.unnestKey(key.expr)
+			key.rank = _RANK_LEADING
+		} else if eq, ok := fl.FltrExpr().(*expression.Eq); ok && !fl.IsJoin() && isPath(key.expr) {
+			if isStringConstant(eq.First()) || isStringConstant(eq.Second()) {
+				key.flavor = eq
+			}
+		}
+		keys = keys.add(key)
+	}
+	return keys
+}
+
+func isStringConstant(expr expression.Expression) bool {
+	v := expr.Value()
+	return v != nil && v.Type() == value.STRING
+}
+
+/*
+The index key a filter can use, if any
+*/
+func sargableKey(expr expression.Expression, alias string, allowed map[string]bool, join bool) *advKey {
+	var key expression.Expression
+	rank := 0
+
+	switch expr := expr.(type) {
+	case *expression.Eq:
+		key, rank = keyOperand(expr.First(), expr.Second(), alias, allowed), _RANK_EQ
+	case *expression.IsNull:
+		key, rank = keyOperand(expr.Operand(), nil, alias, allowed), _RANK_EQ
+	case *expression.IsMissing:
+		key, rank = keyOperand(expr.Operand(), nil, alias, allowed), _RANK_EQ
+	case *expression.In:
+		key, rank = leftKeyOperand(expr.First(), expr.Second(), alias, allowed), _RANK_IN
+	case *expression.Within:
+		key, rank = leftKeyOperand(expr.First(), expr.Second(), alias, allowed), _RANK_IN
+	case *expression.LE:
+		key, rank = keyOperand(expr.First(), expr.Second(), alias, allowed), _RANK_INCL_RANGE
+	case *expression.LT:
+		key, rank = keyOperand(expr.First(), expr.Second(), alias, allowed), _RANK_RANGE
+	case *expression.IsNotNull:
+		key, rank = keyOperand(expr.Operand(), nil, alias, allowed), _RANK_NOT_NULL
+	case *expression.IsNotMissing:
+		key, rank = keyOperand(expr.Operand(), nil, alias, allowed), _RANK_NOT_NULL
+	case *expression.IsValued:
+		key, rank = keyOperand(expr.Operand(), nil, alias, allowed), _RANK_NOT_NULL
+	case *expression.Any:
+		return arrayKey(expr, alias, allowed, join)
+	case *expression.AnyEvery:
+		return arrayKey(expr, alias, allowed, join)
+	}
+
+	if key == nil {
+		return nil
+	}
+	if join {
+		rank = _RANK_JOIN
+	} else if !isPath(key) {
+		rank = _RANK_FUNCTION
+	}
+	return &advKey{expr: key, rank: rank}
+}
+
+// the operand that can be an index key, when the other doesn't depend on the keyspace
+func keyOperand(first, second expression.Expression, alias string, allowed map[string]bool) expression.Expression {
+	if key := leftKeyOperand(first, second, alias, allowed); key != nil {
+		return key
+	}
+	if second != nil {
+		return leftKeyOperand(second, first, alias, allowed)
+	}
+	return nil
+}
+
+func leftKeyOperand(first, second expression.Expression, alias string, allowed map[string]bool) expression.Expression {
+	if isKeyExpr(first, alias, allowed) && (second == nil || !references(second, allowed)) {
+		return first
+	}
+	return nil
+}
+
+/*
+ANY predicates on arrays of the keyspace use array index keys.
+When the array comes from elsewhere, as in joins, the SATISFIES
+clause may still relate it to a key of the keyspace.
+*/
+func arrayKey(pred expression.CollectionPredicate, alias string, allowed map[string]bool, join bool) *advKey {
+	bindings := pred.Bindings()
+	fromKeyspace := false
+	for _, b := range bindings {
+		if references(b.Expression(), allowed) {
+			if !isKeyExpr(b.Expression(), alias, allowed) {
+				return nil
+			}
+			fromKeyspace = true
+		}
+	}
+	if !fromKeyspace {
+		return satisfiesKey(pred.Satisfies(), alias, allowed, true)
+	}
+
+	inner := make(map[string]bool, len(bindings))
+	for _, b := range bindings {
+		inner[b.Variable()] = true
+	}
+	key := satisfiesKey(pred.Satisfies(), "", inner, false)
+	if key == nil {
+		return nil
+	}
+	array := expression.NewArray(key.expr, bindings.Copy(), nil)
+	return &advKey{expr: expression.NewAll(array, true), rank: _RANK_ARRAY}
+}
+
+// the best key of the SATISFIES clause, or the best key common to all its disjuncts
+func satisfiesKey(satisfies expression.Expression, alias string, allowed map[string]bool, join bool) *advKey {
+	terms := func(expr expression.Expression) advKeys {
+		var keys advKeys
+		ops := expression.Expressions{expr}
+		if and, ok := expr.(*expression.And); ok {
+			ops = and.Operands()
+		}
+		for _, op := range ops {
+			if key := sargableKey(op, alias, allowed, join); key != nil {
+				keys = keys.add(key)
+			}
+		}
+		return keys.sorted()
+	}
+
+	or, ok := satisfies.(*expression.Or)
+	if !ok {
+		keys := terms(satisfies)
+		if len(keys) == 0 {
+			return nil
+		}
+		return keys[0]
+	}
+
+	disjuncts := make([]advKeys, len(or.Operands()))
+	for i, op := range or.Operands() {
+		disjuncts[i] = terms(op)
+	}
+	for _, key := range disjuncts[0] {
+		common := true
+		for _, d := range disjuncts[1:] {
+			if d.find(key.expr) == nil {
+				common = false
+				break
+			}
+		}
+		if common {
+			return key
+		}
+	}
+	return nil
+}
+
+// the IS ARRAY inferred for an UNNEST is served by its array key
+func (this *queryInfo) unnested(expr expression.Expression) bool {
+	for _, u := range this.unnests {
+		if expr.EquivalentTo(u.Expression()) {
+			return true
+		}
+	}
+	return false
+}
+
+func (this *queryInfo) unnestFilter(fl *base.Filter, alias string) bool {
+	key := sargableKey(fl.FltrExpr(), alias, map[string]bool{alias: true}, false)
+	return key != nil && this.unnested(key.expr)
+}
+
+func (this *queryInfo) unnestAliases() map[string]bool {
+	rv := make(map[string]bool, len(this.unnests))
+	for _, u := range this.unnests {
+		rv[u.Alias()] = true
+	}
+	return rv
+}
+
+// keys on UNNEST aliases are array keys on what is unnested, innermost first
+func (this *queryInfo) unnestKey(key expression.Expression) expression.Expression {
+	for i := len(this.unnests) - 1; i >= 0; i-- {
+		u := this.unnests[i]
+		if !references(key, map[string]bool{u.Alias(): true}) {
+			continue
+		}
+		var array expression.Expression
+		if id, ok := key.(*expression.Identifier); ok && id.Identifier() == u.Alias() {
+			array = u.Expression().Copy()
+		} else {
+			array = expression.NewArray(key, expression.Bindings{expression.NewSimpleBinding(u.Alias(), u.Expression().Copy())}, nil)
+		}
+		key = expression.NewAll(array, false)
+	}
+	return key
+}
+
+/*
+The keys a covering index needs in addition to those recommended, if the
+query block can be covered.
+*/
+func (this *queryInfo) coverKeys(alias string, rec *advIndex, infos advKeyspaceInfos) (expression.Expressions, bool) {
+	if this.stmtType != "SELECT" {
+		return nil, false
+	}
+
+	keys := rec.keys.exprs()
+	var extra expression.Expressions
+	add := func(expr expression.Expression) {
+		for _, key := range append(keys, extra...) {
+			if key.EquivalentTo(expr) {
+				return
+			}
+		}
+		extra = append(extra, expr)
+	}
+
+	unnests := this.unnestAliases()
+	ok := true
+	for _, term := range this.projection {
+		if term.Star() {
+			if _, ok := term.Expression().(*expression.Self); ok || term.Expression() == nil ||
+				references(term.Expression(), map[string]bool{alias: true}) {
+				return nil, false
+			}
+		}
+		ok = ok && this.coverExpr(term.Expression(), alias, unnests, keys, add, false)
+	}
+	for _, term := range this.order {
+		ok = ok && this.coverExpr(term.Expression(), alias, unnests, keys, add, false)
+	}
+	for _, expr := range this.groupBy {
+		ok = ok && this.coverExpr(expr, alias, unnests, keys, add, false)
+	}
+	for _, bindings := range []expression.Bindings{this.let, this.letting} {
+		for _, b := range bindings {
+			ok = ok && this.coverExpr(b.Expression(), alias, unnests, keys, add, false)
+		}
+	}
+
+	// filters the index keys don't serve need the fields they refer to
+	for _, info := range infos {
+		for _, filters := range []base.Filters{info.filters, info.joinFilters} {
+			for _, fl := range filters {
+				if rec.serves(this.filterKeys(alias, base.Filters{fl})) || this.unnestFilter(fl, alias) {
+					continue
+				}
+				ok = ok && this.coverExpr(fl.FltrExpr(), alias, unnests, keys, add, true)
+			}
+		}
+	}
+	if !ok {
+		return nil, false
+	}
+	return extra, true
+}
+
+func (this *advIndex) serves(keys advKeys) bool {
+	if len(keys) == 0 {
+		return false
+	}
+	for _, key := range keys {
+		if this.keys.find(key.expr) == nil && this.flavors.find(key.expr) == nil {
+			return false
+		}
+	}
+	return true
+}
+
+/*
+Add the index keys covering an expression, false if it can't be covered.
+Filters only need the fields they refer to.
+*/
+func (this *queryInfo) coverExpr(expr expression.Expression, alias string, unnests map[string]bool,
+	keys expression.Expressions, add func(expression.Expression), fields bool) bool {
+
+	if expr == nil {
+		return true
+	}
+	keyspace := map[string]bool{alias: true}
+
+	// an unnested value is covered by the array key on what is unnested
+	if id, ok := expr.(*expression.Identifier); ok && unnests[id.Identifier()] {
+		for _, u := range this.unnests {
+			if u.Alias() != id.Identifier() {
+				continue
+			}
+			for _, key := range keys {
+				if all, ok := key.(*expression.All); ok && all.Array().EquivalentTo(u.Expression()) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	if references(expr, unnests) && !references(expr, keyspace) {
+		return false
+	}
+	if !references(expr, keyspace) {
+		return true
+	}
+
+	switch e := expr.(type) {
+	case expression.Subquery:
+		return false
+	case *expression.Identifier:
+		return false
+	case *expression.Meta:
+		return false
+	case *expression.Field:
+		if _, ok := e.First().(*expression.Meta); ok {
+			name, _ := e.Second().Value().Actual().(string)
+			return name == "id"
+		}
+	}
+
+	if _, ok := expr.(algebra.Aggregate); !ok && isKeyExpr(expr, alias, keyspace) && (!fields || isPath(expr)) {
+		add(expr)
+		return true
+	}
+	for _, child := range expr.Children() {
+		if !this.coverExpr(child, alias, unnests, keys, add, fields) {
+			return false
+		}
+	}
+	return true
+}
+
+/*
+Whether an expression can be an index key of the keyspace: it refers to the
+keyspace, and to nothing but the allowed aliases.
+*/
+func isKeyExpr(expr expression.Expression, alias string, allowed map[string]bool) bool {
+	if expr.Value() != nil || !expr.Indexable() {
+		return false
+	}
+	if id, ok := expr.(*expression.Identifier); ok && id.Identifier() == alias {
+		return false
+	}
+	found := false
+	ok := walkIdentifiers(expr, nil, func(id string) bool {
+		if !allowed[id] {
+			return false
+		}
+		found = true
+		return true
+	})
+	return ok && found
+}
+
+// whether an expression refers to any of the aliases; subqueries might
+func references(expr expression.Expression, aliases map[string]bool) bool {
+	found := false
+	ok := walkIdentifiers(expr, nil, func(id string) bool {
+		if aliases[id] {
+			found = true
+		}
+		return true
+	})
+	return found || !ok
+}
+
+// visit the free identifiers of an expression, false for subqueries,
+// aggregates and META(), or when f returns false
+func walkIdentifiers(expr expression.Expression, bound map[string]bool, f func(string) bool) bool {
+	switch e := expr.(type) {
+	case *expression.Identifier:
+		return bound[e.Identifier()] || f(e.Identifier())
+	case expression.Subquery, *expression.Meta, algebra.Aggregate:
+		return false
+	}
+
+	var bindings expression.Bindings
+	switch e := expr.(type) {
+	case expression.CollectionPredicate:
+		bindings = e.Bindings()
+	case interface{ Bindings() expression.Bindings }:
+		bindings = e.Bindings()
+	}
+	if len(bindings) > 0 {
+		inner := make(map[string]bool, len(bound)+len(bindings))
+		for id := range bound {
+			inner[id] = true
+		}
+		for _, b := range bindings {
+			inner[b.Variable()] = true
+			if b.NameVariable() != "" {
+				inner[b.NameVariable()] = true
+			}
+		}
+
+		// binding expressions are in the outer scope
+		for _, b := range bindings {
+			if !walkIdentifiers(b.Expression(), bound, f) {
+				return false
+			}
+		}
+		for _, child := range expr.Children() {
+			isBinding := false
+			for _, b := range bindings {
+				if child == b.Expression() {
+					isBinding = true
+					break
+				}
+			}
+			if !isBinding && !walkIdentifiers(child, inner, f) {
+				return false
+			}
+		}
+		return true
+	}
+
+	for _, child := range expr.Children() {
+		if !walkIdentifiers(child, bound, f) {
+			return false
+		}
+	}
+	return true
+}
+
+// a field of the document, or an UNNEST alias
+func isPath(expr expression.Expression) bool {
+	switch expr := expr.(type) {
+	case *expression.Identifier:
+		return true
+	case *expression.Field:
+		return expr.Second().Value() != nil && isPath(expr.First())
+	}
+	return false
+}
+
+// the indexes a scan uses
+func scanIndexes(op plan.Operator, rv []datastore.Index) []datastore.Index {
+	switch op := op.(type) {
+	case *plan.IndexScan:
+		rv = append(rv, op.Index())
+	case *plan.IndexScan2:
+		rv = append(rv, op.Index())
+	case *plan.IndexScan3:
+		rv = append(rv, op.Index())
+	case *plan.IndexCountScan:
+		rv = append(rv, op.Index())
+	case *plan.IndexCountScan2:
+		rv = append(rv, op.Index())
+	case *plan.IndexCountDistinctScan2:
+		rv = append(rv, op.Index())
+	case *plan.IndexFtsSearch:
+		rv = append(rv, op.Index())
+	case *plan.PrimaryScan:
+		rv = append(rv, op.Index())
+	case *plan.PrimaryScan3:
+		rv = append(rv, op.Index())
+	case *plan.DistinctScan:
+		rv = scanIndexes(op.Scan(), rv)
+	case *plan.IntersectScan:
+		for _, scan := range op.Scans() {
+			rv = scanIndexes(scan, rv)
+		}
+	case *plan.OrderedIntersectScan:
+		for _, scan := range op.Scans() {
+			rv = scanIndexes(scan, rv)
+		}
+	case *plan.UnionScan:
+		for _, scan := range op.Scans() {
+			rv = scanIndexes(scan, rv)
+		}
+	}
+	return rv
+}
+
+/*
+An index of the keyspace with the same keys and condition
+*/
+func findIndex(keyspace datastore.Keyspace, alias string, keys, cond expression.Expressions) datastore.Index {
+	if keyspace == nil {
+		return nil
+	}
+	indexers, err := keyspace.Indexers()
+	if err != nil {
+		return nil
+	}
+
+	for _, indexer := range indexers {
+		indexes, err := indexer.Indexes()
+		if err != nil {
+			continue
+		}
+
+	indexes:
+		for _, index := range indexes {
+			if index.IsPrimary() || len(index.RangeKey()) != len(keys) {
+				continue
+			}
+			rangeKeys := formalizeIndexKeys(alias, index.RangeKey())
+			if rangeKeys == nil {
+				continue
+			}
+			for i, key := range rangeKeys {
+				if !key.EquivalentTo(keys[i]) {
+					continue indexes
+				}
+			}
+
+			var indexCond expression.Expressions
+			if index.Condition() != nil {
+				c, err := formalizeExpr(expression.NewSelfFormalizer(alias, nil), index.Condition())
+				if err != nil {
+					continue
+				}
+				indexCond = conjuncts(c)
+			}
+			if len(indexCond) != len(cond) {
+				continue
+			}
+			for _, c := range cond {
+				found := false
+				for _, ic := range indexCond {
+					if c.EquivalentTo(ic) {
+						found = true
+						break
+					}
+				}
+				if !found {
+					continue indexes
+				}
+			}
+			return index
+		}
+	}
+	return nil
+}
+
+func conjuncts(expr expression.Expression) expression.Expressions {
+	if and, ok := expr.(*expression.And); ok {
+		rv := make(expression.Expressions, 0, len(and.Operands()))
+		for _, op := range and.Operands() {
+			rv = append(rv, conjuncts(op)...)
+		}
+		return rv
+	}
+	return expression.Expressions{expr}
+}
+
+func formalizeIndexKeys(alias string, keys expression.Expressions) expression.Expressions {
+	formalizer := expression.NewSelfFormalizer(alias, nil)
+	keys = keys.Copy()
+
+	for i, key := range keys {
+		expr, err := formalizeExpr(formalizer, key)
+		if err != nil {
+			return nil
+		}
+		keys[i] = expr
+	}
+	return keys
+}
+
+func formalizeExpr(formalizer *expression.Formalizer, key expression.Expression) (expression.Expression, error) {
+	key = key.Copy()
+
+	formalizer.SetIndexScope()
+	key, err := formalizer.Map(key)
+	formalizer.ClearIndexScope()
+	if err != nil {
+		return nil, err
+	}
+
+	dnf := NewDNF(key, true, true)
+	key, err = dnf.Map(key)
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+/*
+The CREATE INDEX statement of an existing index
+*/
+func indexStatement(index datastore.Index, path string) string {
+	name := quoteIndexName(index.Name())
+	if index.IsPrimary() {
+		return "CREATE PRIMARY INDEX " + name + " ON " + path
+	}
+
+	stmt := "CREATE INDEX " + name + " ON " + path + "(" + strings.Join(getIndexKeyStringArray(index), ",") + ")"
+	if index.Condition() != nil {
+		stmt += " WHERE " + expression.NewStringer().Visit(index.Condition())
+	}
+	return stmt
+}
+
+func getIndexKeyStringArray(index datastore.Index) (rv []string) {
+	if index2, ok2 := index.(datastore.Index2); ok2 {
+		keys := index2.RangeKey2()
+		rv = make([]string, len(keys))
+		for i, kp := range keys {
+			s := expression.NewStringer().Visit(kp.Expr)
+			if kp.Desc {
+				s += " DESC"
+			}
+			rv[i] = s
+		}
+
+	} else {
+		rv = make([]string, len(index.RangeKey()))
+		for i, kp := range index.RangeKey() {
+			rv[i] = expression.NewStringer().Visit(kp)
+		}
+	}
+	return
+}
+
+var _SIMPLE_NAME = regexp.MustCompile("^[A-Za-z][A-Za-z0-9_]*$")
+
+func quoteIndexName(name string) string {
+	if _SIMPLE_NAME.MatchString(name) {
+		return name
+	}
+	return "`" + strings.Replace(name, "`", "``", -1) + "`"
+}
+
+/*
+The CREATE INDEX statement of a recommended index, named after its keys
+*/
+func advisedStatement(alias, path string, names, keys, cond expression.Expressions) string {
+	stringer := expression.NewStringer()
+	unformalizer := newUnformalizer(alias)
+	unformalize := func(expr expression.Expression) expression.Expression {
+		rv, err := unformalizer.Map(expr.Copy())
+		if err != nil {
+			return expr
+		}
+		return rv
+	}
+
+	parts := []string{}
+	for _, name := range names {
+		parts = keyNameParts(unformalize(name), nil, parts)
+	}
+	name := _ADVISE_INDEX_PREFIX + strings.Join(parts, "_")
+	if len(name) > _ADVISE_INDEX_NAME_SIZE {
+		name = fmt.Sprintf("%s%d", name[:_ADVISE_INDEX_NAME_SIZE-10], crc32.ChecksumIEEE([]byte(name)))
+	}
+
+	keyStrings := make([]string, len(keys))
+	for i, key := range keys {
+		keyStrings[i] = stringer.Visit(unformalize(key))
+	}
+	stmt := "CREATE INDEX " + name + " ON " + path + "(" + strings.Join(keyStrings, ",") + ")"
+
+	if len(cond) > 0 {
+		condStrings := make([]string, len(cond))
+		for i, c := range cond {
+			condStrings[i] = stringer.Visit(unformalize(c))
+		}
+		stmt += " WHERE " + strings.Join(condStrings, " AND ")
+	}
+	return stmt
+}
+
+var _NAME_SEPARATORS = regexp.MustCompile("[^A-Za-z0-9_]+")
+
+// the words of an index key, for the index name: fields, functions and constants
+func keyNameParts(expr expression.Expression, bound map[string]bool, parts []string) []string {
+	word := func(s string) {
+		s = strings.Trim(_NAME_SEPARATORS.ReplaceAllString(s, "_"), "_")
+		if s != "" {
+			parts = append(parts, s)
+		}
+	}
+
+	switch e := expr.(type) {
+	case *expression.All:
+		if e.Distinct() {
+			word("DISTINCT")
+		} else {
+			word("ALL")
+		}
+		return keyNameParts(e.Array(), bound, parts)
+	case *expression.Array:
+		inner := make(map[string]bool, len(bound)+len(e.Bindings()))
+		for id := range bound {
+			inner[id] = true
+		}
+		for _, b := range e.Bindings() {
+			parts = keyNameParts(b.Expression(), bound, parts)
+			inner[b.Variable()] = true
+		}
+		return keyNameParts(e.ValueMapping(), inner, parts)
+	case *expression.Identifier:
+		if !bound[e.Identifier()] {
+			word(e.Identifier())
+		}
+		return parts
+	case *expression.FieldName:
+		word(e.Alias())
+		return parts
+	case *expression.Constant:
+		if v := e.Value(); v.Type() == value.STRING || v.Type() == value.NUMBER {
+			word(fmt.Sprintf("%v", v.Actual()))
+		}
+		return parts
+	case *expression.ArrayConstruct, *expression.ObjectConstruct, *expression.Field, *expression.Element:
+	case *expression.Add, *expression.Sub, *expression.Mult, *expression.Div, *expression.Mod, *expression.Concat:
+		for i, op := range e.(expression.Function).Operands() {
+			if i > 0 {
+				word(e.(expression.Function).Name())
+			}
+			parts = keyNameParts(op, bound, parts)
+		}
+		return parts
+	case expression.Function:
+		word(e.Name())
+	}
+
+	for _, child := range expr.Children() {
+		parts = keyNameParts(child, bound, parts)
+	}
+	return parts
+}
+
+/*
+Remove the keyspace alias from formalized expressions, as index keys don't have it
+*/
+type unformalizer struct {
+	expression.MapperBase
+
+	alias string
+}
+
+func newUnformalizer(alias string) *unformalizer {
+	rv := &unformalizer{
+		alias: alias,
+	}
+
+	rv.SetMapper(rv)
+	rv.SetMapFunc(func(expr expression.Expression) (expression.Expression, error) {
+		switch expr := expr.(type) {
+		case *expression.Field:
+			if id, ok := expr.First().(*expression.Identifier); ok && id.Identifier() == rv.alias {
+				if name, ok := expr.Second().(*expression.FieldName); ok {
+					return expression.NewIdentifier(name.Alias()), nil
+				}
+			}
+		case *expression.Meta:
+			if len(expr.Operands()) == 1 {
+				if id, ok := expr.Operands()[0].(*expression.Identifier); ok && id.Identifier() == rv.alias {
+					return expression.NewMeta(), nil
+				}
+			}
+		}
+		return expr, expr.MapChildren(rv)
+	})
+	return rv
+}
+
+func keyspaceAlias(node *algebra.KeyspaceTerm) string {
+	if node.As() != "" && node.As() != node.Keyspace() {
+		return node.Keyspace() + "_" + node.As()
+	}
+	return node.Keyspace()
+}
+
+func keyspacePath(node *algebra.KeyspaceTerm) string {
+	path := node.Path()
+	if path == nil {
+		return "`" + node.Keyspace() + "`"
+	}
+	if path.IsCollection() {
+		return "`" + path.Bucket() + "`.`" + path.Scope() + "`.`" + path.Keyspace() + "`"
+	}
+	return "`" + path.Keyspace() + "`"
+}
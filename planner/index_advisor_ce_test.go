@@ -0,0 +1,185 @@
+//  Copyright (c) 2020 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+//
+// +build !enterprise
+
+package planner
+
+import (
+	"testing"
+
+	"github.com/couchbase/query/algebra"
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/parser/n1ql"
+	base "github.com/couchbase/query/plannerbase"
+)
+
+func parseExprs(t *testing.T, texts ...string) expression.Expressions {
+	rv := make(expression.Expressions, len(texts))
+	for i, text := range texts {
+		expr, err := n1ql.ParseExpression(text)
+		if err != nil {
+			t.Fatalf("unexpected error %v parsing %v", err, text)
+		}
+		rv[i] = expr
+	}
+	return rv
+}
+
+func testFilters(t *testing.T, texts ...string) base.Filters {
+	keyspaces := map[string]string{"t": "t"}
+	exprs := parseExprs(t, texts...)
+	rv := make(base.Filters, len(exprs))
+	for i, expr := range exprs {
+		rv[i] = base.NewFilter(expr, expr, keyspaces, false, false)
+	}
+	return rv
+}
+
+func checkExprs(t *testing.T, what string, actual expression.Expressions, expected ...string) {
+	exprs := parseExprs(t, expected...)
+	if len(actual) != len(exprs) {
+		t.Errorf("%v: expected %v, got %v", what, exprs, actual)
+		return
+	}
+	for i, expr := range exprs {
+		if !actual[i].EquivalentTo(expr) {
+			t.Errorf("%v: expected %v, got %v", what, exprs, actual)
+			return
+		}
+	}
+}
+
+func TestAdvisorKeyOrder(t *testing.T) {
+	info := newQueryInfo("SELECT")
+
+	// keys are ordered by predicate type, a key keeps its best rank,
+	// and equalities with string constants go in the index condition
+	keys := info.filterKeys("t", testFilters(t, "LOWER(t.f) = 'y'", "t.d IS NOT NULL", "t.c < 10",
+		"t.a = 'x'", "t.b IN [1, 2]", "t.e = 5", "t.c <= 5"))
+	rec := newAdvIndex(keys)
+	checkExprs(t, "keys", rec.keys.exprs(), "t.e", "t.b", "t.c", "t.d", "LOWER(t.f)")
+	checkExprs(t, "condition", rec.condition(), "t.a = 'x'")
+	if rec.keys[2].rank != _RANK_INCL_RANGE {
+		t.Errorf("expected t.c to have rank %v, got %v", _RANK_INCL_RANGE, rec.keys[2].rank)
+	}
+
+	// with nothing else to index, string equalities are keys
+	rec = newAdvIndex(info.filterKeys("t", testFilters(t, "t.a = 'x'", "t.b = 'z'")))
+	checkExprs(t, "keys", rec.keys.exprs(), "t.a", "t.b")
+	checkExprs(t, "condition", rec.condition())
+
+	// a key used with another predicate is not part of the condition
+	rec = newAdvIndex(info.filterKeys("t", testFilters(t, "t.a = 'x'", "t.a > 'w'", "t.b = 1")))
+	checkExprs(t, "keys", rec.keys.exprs(), "t.a", "t.b")
+	checkExprs(t, "condition", rec.condition())
+
+	// non sargable filters and filters on other keyspaces give no keys
+	keys = info.filterKeys("t", testFilters(t, "t.a LIKE '%x'", "u.b = 1", "t.a = t.b"))
+	if len(keys) != 0 {
+		t.Errorf("expected no keys, got %v", keys.exprs())
+	}
+	if newAdvIndex(keys) != nil {
+		t.Errorf("expected no index without keys")
+	}
+
+	// join filters come last
+	join := parseExprs(t, "t.c = u.d")[0]
+	keys = info.filterKeys("t", append(testFilters(t, "t.a < 1"),
+		base.NewFilter(join, join, map[string]string{"t": "t", "u": "u"}, true, true)))
+	rec = newAdvIndex(keys)
+	checkExprs(t, "keys", rec.keys.exprs(), "t.a", "t.c")
+	if rec.keys[1].rank != _RANK_JOIN {
+		t.Errorf("expected t.c to have rank %v, got %v", _RANK_JOIN, rec.keys[1].rank)
+	}
+
+	// indexes only have one array key
+	keys = info.filterKeys("t", testFilters(t, "ANY v IN t.arr1 SATISFIES v = 1 END",
+		"ANY w IN t.arr2 SATISFIES w = 2 END", "t.a = 3"))
+	rec = newAdvIndex(keys)
+	arrays := 0
+	for _, key := range rec.keys {
+		if _, ok := key.expr.(*expression.All); ok {
+			arrays++
+		}
+	}
+	if len(keys) != 3 || arrays != 1 || !rec.keys[0].expr.EquivalentTo(parseExprs(t, "t.a")[0]) {
+		t.Errorf("expected t.a and one array key, got %v", rec.keys.exprs())
+	}
+
+	// disjunctions lead with the keys they have in common
+	rec = newDisjunctionIndex([]advKeys{
+		info.filterKeys("t", testFilters(t, "t.c > 1", "t.a = 1")),
+		info.filterKeys("t", testFilters(t, "t.c = 2", "t.b = 2")),
+	})
+	checkExprs(t, "keys", rec.keys.exprs(), "t.c", "t.a", "t.b")
+	if rec.common != 1 || rec.keys[0].rank != _RANK_EQ {
+		t.Errorf("expected a common leading t.c with rank %v, got %v with rank %v", _RANK_EQ, rec.common, rec.keys[0].rank)
+	}
+	if newDisjunctionIndex([]advKeys{
+		info.filterKeys("t", testFilters(t, "t.a = 1")),
+		info.filterKeys("t", testFilters(t, "t.b = 2")),
+	}) != nil {
+		t.Errorf("expected no index for disjuncts without common keys")
+	}
+}
+
+func TestAdvisorCovering(t *testing.T) {
+	filters := testFilters(t, "t.a = 1", "t.x LIKE 'a%'")
+	infos := advKeyspaceInfos{newAdvKeyspaceInfo(nil, filters, nil, false)}
+	projection := func(texts ...string) algebra.ResultTerms {
+		rv := make(algebra.ResultTerms, len(texts))
+		for i, expr := range parseExprs(t, texts...) {
+			rv[i] = algebra.NewResultTerm(expr, false, "")
+		}
+		return rv
+	}
+
+	// the projection, ORDER BY, GROUP BY and filters the keys don't serve add keys
+	info := newQueryInfo("SELECT")
+	rec := newAdvIndex(info.filterKeys("t", filters))
+	info.projection = projection("t.a", "t.g + 1", "META(t).id")
+	info.order = algebra.SortTerms{algebra.NewSortTerm(parseExprs(t, "t.h")[0], false, false)}
+	info.groupBy = parseExprs(t, "t.g + 1")
+	extra, ok := info.coverKeys("t", rec, infos)
+	if !ok {
+		t.Fatalf("expected the query to be covered")
+	}
+	checkExprs(t, "cover keys", extra, "t.g + 1", "t.h", "t.x")
+
+	// filters only need the fields they refer to
+	info = newQueryInfo("SELECT")
+	info.projection = projection("t.a")
+	extra, ok = info.coverKeys("t", rec, advKeyspaceInfos{newAdvKeyspaceInfo(nil,
+		testFilters(t, "t.a = 1", "LOWER(t.y) LIKE 'a%'"), nil, false)})
+	if !ok {
+		t.Fatalf("expected the query to be covered")
+	}
+	checkExprs(t, "cover keys", extra, "t.y")
+
+	// the whole document, or its metadata, can't be covered
+	for _, terms := range []algebra.ResultTerms{
+		algebra.ResultTerms{algebra.NewResultTerm(nil, true, "")},
+		projection("t"),
+		projection("META(t).cas"),
+	} {
+		info = newQueryInfo("SELECT")
+		info.projection = terms
+		if _, ok := info.coverKeys("t", rec, infos); ok {
+			t.Errorf("expected %v not to be covered", terms[0].Expression())
+		}
+	}
+
+	// only SELECT statements are covered
+	info = newQueryInfo("DELETE")
+	info.projection = projection("t.a")
+	if _, ok := info.coverKeys("t", rec, infos); ok {
+		t.Errorf("expected DELETE not to be covered")
+	}
+}
@@ -21,13 +21,15 @@ import (
 // gather keyspace references in a FROM clause (by walking the algebra AST tree)
 type keyspaceFinder struct {
 	baseKeyspaces    map[string]*base.BaseKeyspace
+	namespace        string
 	pushableOnclause expression.Expression
 	unnestDepends    map[string]*expression.Identifier
 }
 
-func newKeyspaceFinder(baseKeyspaces map[string]*base.BaseKeyspace, primary string) *keyspaceFinder {
+func newKeyspaceFinder(baseKeyspaces map[string]*base.BaseKeyspace, primary, namespace string) *keyspaceFinder {
 	rv := &keyspaceFinder{
 		baseKeyspaces: baseKeyspaces,
+		namespace:     namespace,
 	}
 	rv.unnestDepends = make(map[string]*expression.Identifier, len(baseKeyspaces))
 	rv.unnestDepends[primary] = expression.NewIdentifier(primary)
@@ -83,7 +85,7 @@ func (this *keyspaceFinder) VisitSubselect(node *algebra.Subselect) (interface{}
 }
 
 func (this *keyspaceFinder) VisitKeyspaceTerm(node *algebra.KeyspaceTerm) (interface{}, error) {
-	return nil, this.addKeyspaceAlias(node.Alias(), node.Keyspace())
+	return nil, this.addKeyspaceAlias(node.Alias(), qualifiedKeyspace(node.Path(), this.namespace))
 }
 
 func (this *keyspaceFinder) VisitExpressionTerm(node *algebra.ExpressionTerm) (interface{}, error) {
@@ -108,7 +110,7 @@ func (this *keyspaceFinder) VisitIndexJoin(node *algebra.IndexJoin) (interface{}
 
 func (this *keyspaceFinder) VisitAnsiJoin(node *algebra.AnsiJoin) (interface{}, error) {
 	// if this is inner join, gather ON-clause
-	if !node.Outer() {
+	if node.JoinType() == algebra.JOIN_INNER {
 		this.addOnclause(node.Onclause())
 	}
 	return nil, this.visitJoin(node.Left(), node.Right())
@@ -12,96 +12,484 @@
 package planner
 
 import (
+	"math"
+	"sort"
+
 	"github.com/couchbase/query/algebra"
 	"github.com/couchbase/query/datastore"
 	"github.com/couchbase/query/errors"
 	"github.com/couchbase/query/expression"
 	"github.com/couchbase/query/plan"
 	base "github.com/couchbase/query/plannerbase"
+	"github.com/couchbase/query/value"
+)
+
+/*
+Cost-based optimizer for the community edition.
+
+Selectivities come from the histograms in the statistics dictionary
+(see datastore.Dictionary). Keyspaces without statistics get no cost,
+in which case the planner falls back to its rules.
+
+Costs are expressed in an arbitrary unit, roughly the cost of
+fetching a document of average size, and are cumulative: the cost
+of an operator includes the cost of its inputs.
+*/
+const (
+	_COST_INDEX_ENTRY   = 0.005
+	_COST_PRIMARY_ENTRY = 0.002
+	_COST_FETCH         = 0.1
+	_COST_FETCH_SIZE    = 4096 // documents larger than this cost proportionally more to fetch
+	_COST_EXPR          = 0.0005
+	_COST_SORT          = 0.0005
+	_COST_DISTINCT      = 0.001
+	_COST_COUNT         = 0.01
 )
 
 func optCalcSelectivity(filter *base.Filter) {
-	return
+	sel := float64(OPT_SELEC_NOT_AVAIL)
+	arrSel := float64(OPT_SELEC_NOT_AVAIL)
+
+	// no selectivity unless all keyspaces involved have statistics
+	keyspaces := filter.Keyspaces()
+	for _, keyspace := range keyspaces {
+		if keyspaceInfo(keyspace) == nil {
+			filter.SetSelec(sel)
+			filter.SetArraySelec(arrSel)
+			return
+		}
+	}
+
+	var def bool
+	sel, arrSel, def = exprSelec(keyspaces, filter.FltrExpr())
+	if def {
+		filter.SetDefSelec()
+	}
+	filter.SetSelec(sel)
+	filter.SetArraySelec(arrSel)
+	filter.SetSelecDone()
 }
 
 func optExprSelec(keyspaces map[string]string, pred expression.Expression) (
 	float64, float64) {
-	return OPT_SELEC_NOT_AVAIL, OPT_SELEC_NOT_AVAIL
+	sel, arrSel, def := exprSelec(keyspaces, pred)
+	if def {
+		return OPT_SELEC_NOT_AVAIL, OPT_SELEC_NOT_AVAIL
+	}
+	return sel, arrSel
 }
 
 func optDefInSelec(keyspace, key string) float64 {
-	return OPT_SELEC_NOT_AVAIL
+	if keyspaceInfo(keyspace) == nil {
+		return OPT_SELEC_NOT_AVAIL
+	}
+	return _SELEC_DEF_IN
 }
 
 func optDefLikeSelec(keyspace, key string) float64 {
-	return OPT_SELEC_NOT_AVAIL
+	if keyspaceInfo(keyspace) == nil {
+		return OPT_SELEC_NOT_AVAIL
+	}
+	return _SELEC_DEF_LIKE
 }
 
+/*
+Flag the filters that are applied by the index spans, or implied by the
+index condition, so that their selectivity is not applied twice.
+*/
 func optMarkIndexFilters(keys expression.Expressions, spans plan.Spans2,
 	condition expression.Expression, filters base.Filters) {
-	// no-op
+
+	for _, fltr := range filters {
+		fltrExpr := fltr.FltrExpr()
+		if condition != nil && SubsetOf(condition, fltrExpr) {
+			fltr.SetIndexFlag()
+			continue
+		}
+
+		for i, key := range keys {
+			if fltrExpr.DependsOn(key) && boundedSpans(spans, i) {
+				fltr.SetIndexFlag()
+				break
+			}
+		}
+	}
+}
+
+func boundedSpans(spans plan.Spans2, pos int) bool {
+	if len(spans) == 0 {
+		return false
+	}
+
+	for _, span := range spans {
+		if pos >= len(span.Ranges) {
+			return false
+		}
+		rg := span.Ranges[pos]
+		if rg.HasFlag(plan.RANGE_FULL_SPAN|plan.RANGE_WHOLE_SPAN) || (rg.Low == nil && rg.High == nil) {
+			return false
+		}
+	}
+
+	return true
 }
 
-func primaryIndexScanCost(primary datastore.PrimaryIndex, requestId string) (cost, cardinality float64) {
-	return OPT_COST_NOT_AVAIL, OPT_CARD_NOT_AVAIL
+func primaryIndexScanCost(primary datastore.PrimaryIndex, keyspace, requestId string) (cost, cardinality float64) {
+	info := keyspaceInfo(keyspace)
+	if info == nil {
+		return OPT_COST_NOT_AVAIL, OPT_CARD_NOT_AVAIL
+	}
+
+	cardinality = docCount(info)
+	return cardinality * _COST_PRIMARY_ENTRY, cardinality
 }
 
 func indexScanCost(index datastore.Index, sargKeys expression.Expressions, requestId string,
-	spans SargSpans, alias string) (cost float64, sel float64, card float64, err error) {
-	return OPT_COST_NOT_AVAIL, OPT_SELEC_NOT_AVAIL, OPT_CARD_NOT_AVAIL, errors.NewPlanInternalError("indexScanCost: unexpected in community edition")
+	spans SargSpans, alias, keyspace string) (cost float64, sel float64, card float64, err error) {
+	switch spans := spans.(type) {
+	case *TermSpans:
+		return termScanCost(index, sargKeys, spans.spans, alias, keyspace)
+	case *IntersectSpans:
+		return multiIndexCost(index, sargKeys, requestId, spans.spans, alias, keyspace, false)
+	case *UnionSpans:
+		return multiIndexCost(index, sargKeys, requestId, spans.spans, alias, keyspace, true)
+	}
+
+	return OPT_COST_NOT_AVAIL, OPT_SELEC_NOT_AVAIL, OPT_CARD_NOT_AVAIL, errors.NewPlanInternalError("indexScanCost: unexpected span type")
+}
+
+func multiIndexCost(index datastore.Index, sargKeys expression.Expressions, requestId string,
+	spans []SargSpans, alias, keyspace string, union bool) (cost float64, sel float64, card float64, err error) {
+	var nrows float64
+	for i, span := range spans {
+		tcost, tsel, tcard, e := indexScanCost(index, sargKeys, requestId, span, alias, keyspace)
+		if e != nil || tcost <= 0.0 || tsel <= 0.0 {
+			return tcost, tsel, tcard, e
+		}
+		cost += tcost
+		tnrows := tcard / tsel
+		if i == 0 {
+			sel = tsel
+			nrows = tnrows
+		} else {
+			tsel = tsel * (tnrows / nrows)
+			if union {
+				sel = sel + tsel - (sel * tsel)
+			} else {
+				sel = sel * tsel
+			}
+		}
+	}
+
+	return cost, sel, (sel * nrows), nil
+}
+
+/*
+Spans of a single index scan are disjoint, so the selectivity of the scan
+is the sum of the selectivities of its spans, and the selectivity of a
+span is the product of the selectivities of its ranges.
+*/
+func termScanCost(index datastore.Index, sargKeys expression.Expressions, spans plan.Spans2,
+	alias, keyspace string) (cost float64, sel float64, card float64, err error) {
+
+	info := keyspaceInfo(keyspace)
+	if info == nil {
+		return OPT_COST_NOT_AVAIL, OPT_SELEC_NOT_AVAIL, OPT_CARD_NOT_AVAIL, nil
+	}
+
+	for _, span := range spans {
+		spanSel := 1.0
+		for i, rg := range span.Ranges {
+			var key expression.Expression
+			if i < len(sargKeys) {
+				key = sargKeys[i]
+			}
+			spanSel *= rangeSelec(rg, key, alias, keyspace)
+		}
+		sel += spanSel
+	}
+	sel = clampSelec(sel)
+
+	card = sel * docCount(info)
+	nkeys := len(index.RangeKey())
+	if index.IsPrimary() {
+		nkeys = 1
+	}
+	cost = card * _COST_INDEX_ENTRY * (1.0 + 0.1*float64(nkeys))
+	return cost, sel, card, nil
+}
+
+func rangeSelec(rg *plan.Range2, key expression.Expression, alias, keyspace string) float64 {
+	switch {
+	case rg.HasFlag(plan.RANGE_EMPTY_SPAN):
+		return _SELEC_MIN
+	case rg.HasFlag(plan.RANGE_FULL_SPAN | plan.RANGE_WHOLE_SPAN):
+		return 1.0
+	case rg.Low == nil && rg.High == nil:
+		return 1.0
+	}
+
+	if rg.EqualRange() {
+		if rg.Selec1 > 0.0 {
+			return rg.Selec1
+		}
+	} else if rg.Selec1 > 0.0 && rg.Selec2 > 0.0 {
+		return clampSelec(rg.Selec1 + rg.Selec2 - 1.0)
+	} else if rg.Selec1 > 0.0 {
+		return rg.Selec1
+	} else if rg.Selec2 > 0.0 {
+		return rg.Selec2
+	}
+
+	var hist *datastore.Histogram
+	if key != nil {
+		hist = keyHistogram(key, alias, keyspace)
+	}
+
+	var low, high value.Value
+	if rg.Low != nil {
+		low = rg.Low.Value()
+	}
+	if rg.High != nil {
+		high = rg.High.Value()
+	}
+
+	if rg.EqualRange() {
+		switch {
+		case hist != nil && low != nil:
+			return histEqSelec(hist, low)
+		case hist != nil && hist.Fdistincts() > 0.0 && hist.DocCount() > 0:
+			// join or parameterized key
+			return clampSelec(1.0 / (hist.Fdistincts() * float64(hist.DocCount())))
+		}
+		return _SELEC_DEF_EQ
+	}
+
+	if hist != nil && (rg.Low == nil || low != nil) && (rg.High == nil || high != nil) {
+		return clampSelec(histRangeSelec(hist, low, high,
+			(rg.Inclusion&datastore.LOW) != 0, (rg.Inclusion&datastore.HIGH) != 0))
+	}
+	return _SELEC_DEF_RANGE
 }
 
 func getFetchCost(keyspace datastore.Keyspace, cardinality float64) float64 {
-	return OPT_COST_NOT_AVAIL
+	info := keyspaceInfo(keyspace.QualifiedName())
+	if info == nil || cardinality <= 0.0 {
+		return OPT_COST_NOT_AVAIL
+	}
+
+	size := float64(info.AvgDocSize) / _COST_FETCH_SIZE
+	if size < 1.0 {
+		size = 1.0
+	}
+	return cardinality * _COST_FETCH * size
 }
 
 func getDistinctScanCost(index datastore.Index, cardinality float64) (float64, float64) {
-	return OPT_COST_NOT_AVAIL, OPT_CARD_NOT_AVAIL
+	return cardinality * _COST_DISTINCT, cardinality
 }
 
 func getExpressionScanCost(expr expression.Expression, keyspaces map[string]string) (float64, float64) {
-	return OPT_COST_NOT_AVAIL, OPT_CARD_NOT_AVAIL
+	val := expr.Value()
+	if val == nil {
+		return OPT_COST_NOT_AVAIL, OPT_CARD_NOT_AVAIL
+	}
+
+	cardinality := 1.0
+	if val.Type() == value.ARRAY {
+		if n := len(val.Actual().([]interface{})); n > 1 {
+			cardinality = float64(n)
+		}
+	}
+	return cardinality * _COST_EXPR, cardinality
 }
 
+/*
+Selectivity of the ON-clause filters that are not applied by the index spans
+of the right-hand side.
+*/
+func joinFiltersSelec(filters base.Filters) float64 {
+	sel := 1.0
+	for _, fltr := range filters {
+		if fltr.IsOnclause() && !fltr.HasIndexFlag() && fltr.Selec() > 0.0 {
+			sel *= fltr.Selec()
+		}
+	}
+	return sel
+}
+
+/*
+The right-hand side of a nested-loop join is executed once for every
+document on the left-hand side.
+*/
 func getNLJoinCost(left, right plan.Operator, filters base.Filters) (float64, float64) {
-	return OPT_COST_NOT_AVAIL, OPT_CARD_NOT_AVAIL
+	if left == nil || right == nil {
+		return OPT_COST_NOT_AVAIL, OPT_CARD_NOT_AVAIL
+	}
+
+	lcost, lcard := left.Cost(), left.Cardinality()
+	rcost, rcard := right.Cost(), right.Cardinality()
+	if lcost <= 0.0 || lcard <= 0.0 || rcost <= 0.0 || rcard <= 0.0 {
+		return OPT_COST_NOT_AVAIL, OPT_CARD_NOT_AVAIL
+	}
+
+	return lcost + lcard*rcost, lcard * rcard * joinFiltersSelec(filters)
 }
 
+// hash joins are not available in the open build
 func getHashJoinCost(left, right plan.Operator, buildExprs, probeExprs expression.Expressions,
 	buildRight, force bool, filters base.Filters) (float64, float64, bool) {
 	return OPT_COST_NOT_AVAIL, OPT_CARD_NOT_AVAIL, false
 }
 
 func getSimpleFromTermCost(left, right plan.Operator, filters base.Filters) (float64, float64) {
-	return OPT_COST_NOT_AVAIL, OPT_CARD_NOT_AVAIL
+	return getNLJoinCost(left, right, filters)
 }
 
+/*
+The filter applies the predicates that have not been applied by index spans
+or joins.
+*/
 func getFilterCost(lastOp plan.Operator, expr expression.Expression,
 	baseKeyspaces map[string]*base.BaseKeyspace) (float64, float64) {
-	return OPT_COST_NOT_AVAIL, OPT_CARD_NOT_AVAIL
+	if lastOp == nil {
+		return OPT_COST_NOT_AVAIL, OPT_CARD_NOT_AVAIL
+	}
+
+	cost, cardinality := lastOp.Cost(), lastOp.Cardinality()
+	if cost <= 0.0 || cardinality <= 0.0 {
+		return OPT_COST_NOT_AVAIL, OPT_CARD_NOT_AVAIL
+	}
+
+	sel := 1.0
+	for _, baseKeyspace := range baseKeyspaces {
+		for _, fltr := range baseKeyspace.Filters() {
+			if !fltr.IsOnclause() && !fltr.HasIndexFlag() && fltr.Selec() > 0.0 {
+				sel *= fltr.Selec()
+			}
+		}
+	}
+
+	cost += cardinality * _COST_EXPR * float64(exprTerms(expr))
+	return cost, clampSelec(sel) * cardinality
+}
+
+func exprTerms(expr expression.Expression) int {
+	if and, ok := expr.(*expression.And); ok {
+		return len(and.Operands())
+	}
+	return 1
 }
 
 func getLetCost(lastOp plan.Operator) (float64, float64) {
-	return OPT_COST_NOT_AVAIL, OPT_CARD_NOT_AVAIL
+	if lastOp == nil {
+		return OPT_COST_NOT_AVAIL, OPT_CARD_NOT_AVAIL
+	}
+
+	cost, cardinality := lastOp.Cost(), lastOp.Cardinality()
+	if cost <= 0.0 || cardinality <= 0.0 {
+		return OPT_COST_NOT_AVAIL, OPT_CARD_NOT_AVAIL
+	}
+	return cost + cardinality*_COST_EXPR, cardinality
 }
 
 func getUnnestPredSelec(pred expression.Expression, alias, variable string, mapping expression.Expression,
 	keyspaces map[string]string) float64 {
-	return OPT_SELEC_NOT_AVAIL
+	if keyspaceInfo(keyspaces[alias]) == nil {
+		return OPT_SELEC_NOT_AVAIL
+	}
+
+	// no statistics are kept on unnested values
+	sel, _, _ := exprSelec(keyspaces, pred)
+	return sel
 }
 
+/*
+Start from the cheapest index, and intersect further indexes as long
+as the reduction in documents fetched outweighs the cost of the scan.
+*/
 func optChooseIntersectScan(keyspace datastore.Keyspace, indexes map[datastore.Index]*base.IndexCost) map[datastore.Index]*base.IndexCost {
-	return indexes
+	if len(indexes) <= 1 {
+		return indexes
+	}
+
+	sorted := make([]datastore.Index, 0, len(indexes))
+	for index, _ := range indexes {
+		sorted = append(sorted, index)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		ci, cj := indexes[sorted[i]], indexes[sorted[j]]
+		if ci.Cost() != cj.Cost() {
+			return ci.Cost() < cj.Cost()
+		}
+		return sorted[i].Name() < sorted[j].Name()
+	})
+
+	best := indexes[sorted[0]]
+	chosen := map[datastore.Index]*base.IndexCost{sorted[0]: best}
+	if best.Selectivity() <= 0.0 {
+		return chosen
+	}
+
+	docs := best.Cardinality() / best.Selectivity()
+	scanCost := best.Cost()
+	sel := best.Selectivity()
+	totalCost := scanCost + getFetchCost(keyspace, sel*docs)
+	if totalCost <= scanCost {
+		return chosen
+	}
+
+	for _, index := range sorted[1:] {
+		ic := indexes[index]
+		if ic.Selectivity() <= 0.0 {
+			continue
+		}
+		newSel := sel * ic.Selectivity()
+		newCost := scanCost + ic.Cost() + getFetchCost(keyspace, math.Max(newSel*docs, 1.0))
+		if newCost < totalCost {
+			chosen[index] = ic
+			scanCost += ic.Cost()
+			sel = newSel
+			totalCost = newCost
+		}
+	}
+
+	return chosen
+}
+
+/*
+Index scan costs are computed from the index spans, so the intersect
+scan is chosen among the shortest indexes, once they are sarged.
+*/
+func optIntersectAfterSarg() bool {
+	return true
 }
 
 func getSortCost(nterms int, cardinality float64, limit, offset int64) (float64, float64) {
-	return OPT_COST_NOT_AVAIL, OPT_CARD_NOT_AVAIL
+	if cardinality <= 0.0 {
+		return OPT_COST_NOT_AVAIL, OPT_CARD_NOT_AVAIL
+	}
+
+	// with a limit, only the top (offset + limit) documents are kept sorted
+	sorted := cardinality
+	if limit > 0 && float64(limit+offset) < cardinality {
+		sorted = float64(limit + offset)
+	}
+
+	cost := cardinality * math.Log2(sorted+1.0) * float64(nterms) * _COST_SORT
+	if offset > 0 {
+		cardinality = math.Max(cardinality-float64(offset), 1.0)
+	}
+	if limit > 0 && float64(limit) < cardinality {
+		cardinality = float64(limit)
+	}
+	return cost, cardinality
 }
 
 func getInitialProjectCost(projection *algebra.Projection, cardinality float64) (float64, float64) {
-	return OPT_COST_NOT_AVAIL, OPT_CARD_NOT_AVAIL
+	return cardinality * _COST_EXPR * float64(len(projection.Terms())), cardinality
 }
 
 func getIndexCountProjectCost(projection *algebra.Projection, cardinality float64) (float64, float64) {
-	return OPT_COST_NOT_AVAIL, OPT_CARD_NOT_AVAIL
+	return _COST_COUNT, cardinality
 }
@@ -0,0 +1,457 @@
+//  Copyright (c) 2019 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+//
+// +build !enterprise
+
+package planner
+
+import (
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/value"
+)
+
+// default selectivities, used when no histogram is available for a predicate
+const (
+	_SELEC_DEF_EQ    = 0.005
+	_SELEC_DEF_RANGE = 0.33
+	_SELEC_DEF_LIKE  = 0.25
+	_SELEC_DEF_NULL  = 0.05
+	_SELEC_DEF_ANY   = 0.1
+	_SELEC_DEF       = 0.5
+	_SELEC_DEF_IN    = 5 * _SELEC_DEF_EQ
+	_SELEC_MIN       = 0.000001
+)
+
+/*
+Optimizer statistics for a keyspace, or nil if UPDATE STATISTICS has
+not been run on it.
+*/
+func keyspaceInfo(keyspace string) *datastore.KeyspaceInfo {
+	dictionary := datastore.GetDictionary()
+	if dictionary == nil {
+		return nil
+	}
+	return dictionary.KeyspaceInfo(keyspace)
+}
+
+func docCount(info *datastore.KeyspaceInfo) float64 {
+	if info.DocCount <= 0 {
+		return 1.0
+	}
+	return float64(info.DocCount)
+}
+
+/*
+Histogram for an expression that references alias, an alias of keyspace.
+Histograms are keyed on expressions formalized against the qualified
+keyspace path, so alias is renamed before the lookup.
+*/
+func keyHistogram(key expression.Expression, alias, keyspace string) *datastore.Histogram {
+	dictionary := datastore.GetDictionary()
+	if dictionary == nil {
+		return nil
+	}
+
+	if alias != keyspace {
+		from := expression.Bindings{expression.NewSimpleBinding(alias, expression.TRUE_EXPR)}
+		to := expression.Bindings{expression.NewSimpleBinding(keyspace, expression.TRUE_EXPR)}
+		renamed, err := expression.NewRenamer(from, to).Map(key.Copy())
+		if err != nil {
+			return nil
+		}
+		key = renamed
+	}
+
+	return dictionary.Histogram(keyspace, key.String())
+}
+
+/*
+Histogram for an expression that references exactly one of keyspaces,
+a map of aliases to qualified keyspace paths.
+*/
+func exprHistogram(keyspaces map[string]string, expr expression.Expression) *datastore.Histogram {
+	refs, err := expression.CountKeySpaces(expr, keyspaces)
+	if err != nil || len(refs) != 1 {
+		return nil
+	}
+
+	for alias, keyspace := range refs {
+		return keyHistogram(expr, alias, keyspace)
+	}
+	return nil
+}
+
+/*
+Selectivity of a predicate, and its selectivity against array index
+entries. def is set when no statistics were used.
+*/
+func exprSelec(keyspaces map[string]string, pred expression.Expression) (sel, arrSel float64, def bool) {
+	switch pred := pred.(type) {
+	case *expression.And:
+		sel, arrSel, def = 1.0, 1.0, true
+		for _, op := range pred.Operands() {
+			s, as, d := exprSelec(keyspaces, op)
+			sel *= s
+			arrSel *= as
+			def = def && d
+		}
+	case *expression.Or:
+		sel, arrSel, def = 0.0, 0.0, true
+		for _, op := range pred.Operands() {
+			s, as, d := exprSelec(keyspaces, op)
+			sel = sel + s - (sel * s)
+			arrSel = arrSel + as - (arrSel * as)
+			def = def && d
+		}
+	case *expression.Not:
+		s, as, d := exprSelec(keyspaces, pred.Operand())
+		sel, arrSel, def = 1.0-s, 1.0-as, d
+	case *expression.Eq:
+		sel, def = compareSelec(keyspaces, pred.First(), pred.Second(), _CMP_EQ)
+	case *expression.LT:
+		sel, def = compareSelec(keyspaces, pred.First(), pred.Second(), _CMP_LT)
+	case *expression.LE:
+		sel, def = compareSelec(keyspaces, pred.First(), pred.Second(), _CMP_LE)
+	case *expression.Between:
+		sel, def = betweenSelec(keyspaces, pred)
+	case *expression.In:
+		sel, def = inSelec(keyspaces, pred)
+	case *expression.Like:
+		sel, def = likeSelec(keyspaces, pred)
+	case *expression.IsNull:
+		sel, def = nullSelec(keyspaces, pred.Operand(), value.NULL_VALUE, false)
+	case *expression.IsNotNull:
+		sel, def = nullSelec(keyspaces, pred.Operand(), value.NULL_VALUE, true)
+	case *expression.IsMissing:
+		sel, def = nullSelec(keyspaces, pred.Operand(), value.MISSING_VALUE, false)
+	case *expression.IsNotMissing:
+		sel, def = nullSelec(keyspaces, pred.Operand(), value.MISSING_VALUE, true)
+	case *expression.IsValued:
+		sel, def = valuedSelec(keyspaces, pred.Operand(), true)
+	case *expression.IsNotValued:
+		sel, def = valuedSelec(keyspaces, pred.Operand(), false)
+	case *expression.Any, *expression.AnyEvery:
+		sel, def = _SELEC_DEF_ANY, true
+	default:
+		if val := pred.Value(); val != nil {
+			if val.Truth() {
+				sel = 1.0
+			} else {
+				sel = 0.0
+			}
+		} else {
+			sel, def = _SELEC_DEF, true
+		}
+	}
+
+	switch pred.(type) {
+	case *expression.And, *expression.Or, *expression.Not:
+	default:
+		arrSel = sel
+	}
+
+	return clampSelec(sel), clampSelec(arrSel), def
+}
+
+func clampSelec(sel float64) float64 {
+	if sel < _SELEC_MIN {
+		return _SELEC_MIN
+	} else if sel > 1.0 {
+		return 1.0
+	}
+	return sel
+}
+
+const (
+	_CMP_EQ = iota
+	_CMP_LT
+	_CMP_LE
+)
+
+func compareSelec(keyspaces map[string]string, first, second expression.Expression, op int) (float64, bool) {
+	firstVal := first.Value()
+	secondVal := second.Value()
+
+	switch {
+	case firstVal == nil && secondVal == nil:
+		if op == _CMP_EQ {
+			return joinSelec(keyspaces, first, second)
+		}
+		return _SELEC_DEF_RANGE, true
+	case firstVal != nil && secondVal != nil:
+		return _SELEC_DEF, true
+	}
+
+	key := first
+	val := secondVal
+	if firstVal != nil {
+		key = second
+		val = firstVal
+	}
+
+	hist := exprHistogram(keyspaces, key)
+	if hist == nil {
+		if op == _CMP_EQ {
+			return _SELEC_DEF_EQ, true
+		}
+		return _SELEC_DEF_RANGE, true
+	}
+
+	switch op {
+	case _CMP_EQ:
+		return histEqSelec(hist, val), false
+	case _CMP_LT, _CMP_LE:
+		incl := op == _CMP_LE
+		if firstVal == nil {
+			// key < val
+			return histRangeSelec(hist, value.NULL_VALUE, val, false, incl), false
+		}
+		// val < key
+		return histRangeSelec(hist, val, nil, incl, false), false
+	}
+
+	return _SELEC_DEF, true
+}
+
+/*
+Equi-join selectivity: 1 / max(distinct values) of the two sides.
+*/
+func joinSelec(keyspaces map[string]string, first, second expression.Expression) (float64, bool) {
+	ndv := 0.0
+	for _, expr := range []expression.Expression{first, second} {
+		hist := exprHistogram(keyspaces, expr)
+		if hist == nil {
+			continue
+		}
+		if n := hist.Fdistincts() * float64(hist.DocCount()); n > ndv {
+			ndv = n
+		}
+	}
+
+	if ndv < 1.0 {
+		return _SELEC_DEF_EQ, true
+	}
+	return 1.0 / ndv, false
+}
+
+func betweenSelec(keyspaces map[string]string, pred *expression.Between) (float64, bool) {
+	low := pred.Second().Value()
+	high := pred.Third().Value()
+	hist := exprHistogram(keyspaces, pred.First())
+	if hist == nil || low == nil || high == nil {
+		return _SELEC_DEF_RANGE, true
+	}
+	return histRangeSelec(hist, low, high, true, true), false
+}
+
+func inSelec(keyspaces map[string]string, pred *expression.In) (float64, bool) {
+	hist := exprHistogram(keyspaces, pred.First())
+	list := pred.Second().Value()
+	if list == nil || list.Type() != value.ARRAY {
+		return _SELEC_DEF_IN, true
+	}
+
+	elems := list.Actual().([]interface{})
+	if hist == nil {
+		return float64(len(elems)) * _SELEC_DEF_EQ, true
+	}
+
+	sel := 0.0
+	seen := make(map[string]bool, len(elems))
+	for _, elem := range elems {
+		val := value.NewValue(elem)
+		if s := val.String(); !seen[s] {
+			seen[s] = true
+			sel += histEqSelec(hist, val)
+		}
+	}
+	return sel, false
+}
+
+func likeSelec(keyspaces map[string]string, pred *expression.Like) (float64, bool) {
+	hist := exprHistogram(keyspaces, pred.First())
+	re := pred.Regexp()
+	if hist == nil || re == nil {
+		return _SELEC_DEF_LIKE, true
+	}
+
+	prefix, complete := re.LiteralPrefix()
+	if complete {
+		return histEqSelec(hist, value.NewValue(prefix)), false
+	}
+
+	// strings sort before arrays
+	low := value.NewValue(prefix)
+	high := value.EMPTY_ARRAY_VALUE
+	last := len(prefix) - 1
+	if last >= 0 && prefix[last] < 0xff {
+		bytes := []byte(prefix)
+		bytes[last]++
+		high = value.NewValue(string(bytes))
+	}
+
+	// only part of the values in range match the pattern
+	return histRangeSelec(hist, low, high, true, false) * (1.0 - _SELEC_DEF_LIKE), false
+}
+
+func nullSelec(keyspaces map[string]string, operand expression.Expression, val value.Value, not bool) (float64, bool) {
+	hist := exprHistogram(keyspaces, operand)
+	sel := _SELEC_DEF_NULL
+	def := true
+	if hist != nil {
+		sel = histEqSelec(hist, val)
+		def = false
+	}
+	if not {
+		sel = 1.0 - sel
+	}
+	return sel, def
+}
+
+func valuedSelec(keyspaces map[string]string, operand expression.Expression, valued bool) (float64, bool) {
+	hist := exprHistogram(keyspaces, operand)
+	if hist == nil {
+		if valued {
+			return 1.0 - _SELEC_DEF_NULL, true
+		}
+		return _SELEC_DEF_NULL, true
+	}
+
+	sel := histRangeSelec(hist, value.NULL_VALUE, nil, false, false)
+	if !valued {
+		sel = 1.0 - sel
+	}
+	return sel, false
+}
+
+/*
+Selectivity of key = val. Frequent values are kept in overflow bins;
+otherwise the values of a distribution bin are assumed to be uniformly
+distributed.
+*/
+func histEqSelec(hist *datastore.Histogram, val value.Value) float64 {
+	for _, ob := range hist.Ovrflow() {
+		if ob.Val().Collate(val) == 0 {
+			return ob.Size()
+		}
+	}
+
+	docs := float64(hist.DocCount())
+	if docs < 1.0 {
+		docs = 1.0
+	}
+
+	for _, db := range hist.Distrib() {
+		if val.Collate(db.Max()) <= 0 {
+			ndv := db.Distinct() * docs
+			if ndv < 1.0 {
+				ndv = 1.0
+			}
+			return db.Size() / ndv
+		}
+	}
+
+	// value is beyond the histogram
+	return 1.0 / docs
+}
+
+/*
+Selectivity of a range. A nil bound is unbounded.
+*/
+func histRangeSelec(hist *datastore.Histogram, low, high value.Value, lowIncl, highIncl bool) float64 {
+	sel := 0.0
+	for _, ob := range hist.Ovrflow() {
+		if inRange(ob.Val(), low, high, lowIncl, highIncl) {
+			sel += ob.Size()
+		}
+	}
+
+	var prev value.Value
+	for _, db := range hist.Distrib() {
+		sel += db.Size() * binFraction(prev, db.Max(), low, high, lowIncl, highIncl)
+		prev = db.Max()
+	}
+
+	return sel
+}
+
+func inRange(val, low, high value.Value, lowIncl, highIncl bool) bool {
+	if low != nil {
+		cmp := val.Collate(low)
+		if cmp < 0 || (cmp == 0 && !lowIncl) {
+			return false
+		}
+	}
+	if high != nil {
+		cmp := val.Collate(high)
+		if cmp > 0 || (cmp == 0 && !highIncl) {
+			return false
+		}
+	}
+	return true
+}
+
+/*
+Fraction of the distribution bin (prev, max] that falls within a range.
+Numeric bins are interpolated; other partially covered bins count for half.
+*/
+func binFraction(prev, max, low, high value.Value, lowIncl, highIncl bool) float64 {
+	if low != nil {
+		cmp := max.Collate(low)
+		if cmp < 0 || (cmp == 0 && !lowIncl) {
+			return 0.0
+		}
+	}
+	if high != nil && prev != nil && prev.Collate(high) >= 0 {
+		return 0.0
+	}
+
+	// the lower bound of the first bin is not known; assume it holds
+	// values of a single type
+	lowCovered := low == nil || (prev != nil && prev.Collate(low) >= 0) ||
+		(prev == nil && low.Type() < max.Type())
+	highCovered := high == nil
+	if !highCovered {
+		cmp := max.Collate(high)
+		highCovered = cmp < 0 || (cmp == 0 && highIncl)
+	}
+	if lowCovered && highCovered {
+		return 1.0
+	}
+
+	if prev == nil || prev.Type() != value.NUMBER || max.Type() != value.NUMBER {
+		return 0.5
+	}
+
+	from := value.AsNumberValue(prev).Float64()
+	to := value.AsNumberValue(max).Float64()
+	if to <= from {
+		return 0.5
+	}
+
+	lo, hi := from, to
+	if !lowCovered {
+		if low.Type() != value.NUMBER {
+			return 0.5
+		}
+		lo = value.AsNumberValue(low).Float64()
+	}
+	if !highCovered {
+		if high.Type() != value.NUMBER {
+			return 0.5
+		}
+		hi = value.AsNumberValue(high).Float64()
+	}
+
+	if hi <= lo {
+		return 0.0
+	}
+	return (hi - lo) / (to - from)
+}
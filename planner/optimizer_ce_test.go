@@ -0,0 +1,153 @@
+//  Copyright (c) 2019 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+//
+// +build !enterprise
+
+package planner
+
+import (
+	"math"
+	"testing"
+
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/parser/n1ql"
+	base "github.com/couchbase/query/plannerbase"
+	"github.com/couchbase/query/value"
+)
+
+type testDictionary struct {
+	info       map[string]*datastore.KeyspaceInfo
+	histograms map[string]*datastore.Histogram
+}
+
+func (this *testDictionary) KeyspaceInfo(keyspace string) *datastore.KeyspaceInfo {
+	return this.info[keyspace]
+}
+
+func (this *testDictionary) Histogram(keyspace, key string) *datastore.Histogram {
+	return this.histograms[keyspace+":"+key]
+}
+
+func (this *testDictionary) Keyspaces() []string {
+	return nil
+}
+
+func (this *testDictionary) Histograms(keyspace string) []*datastore.Histogram {
+	return nil
+}
+
+/*
+people has 1000 documents, with age 30 as a frequent value, and
+ages up to 60 spread over three bins of 20 distinct values each.
+*/
+func setTestDictionary(t *testing.T) {
+	key, err := n1ql.ParseExpression("`default:people`.age")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	hist := &datastore.Histogram{}
+	hist.SetHistogram(datastore.HISTOGRAM_VERSION, "default:people", key, 1000, 1000, 1.0, 0.06, 0.0,
+		datastore.DistBins{
+			datastore.NewDistBin(0.2, 0.02, value.NewValue(20)),
+			datastore.NewDistBin(0.3, 0.019, value.NewValue(40)),
+			datastore.NewDistBin(0.3, 0.02, value.NewValue(60)),
+		},
+		datastore.OverflowBins{
+			datastore.NewOverflowBin(0.2, value.NewValue(30)),
+		})
+
+	datastore.SetDictionary(&testDictionary{
+		info: map[string]*datastore.KeyspaceInfo{
+			"default:people": &datastore.KeyspaceInfo{DocCount: 1000, AvgDocSize: 512},
+		},
+		histograms: map[string]*datastore.Histogram{
+			"default:people:" + key.String(): hist,
+		},
+	})
+}
+
+func testSelec(t *testing.T, text string, keyspaces map[string]string, expected float64, expectedDef bool) {
+	pred, err := n1ql.ParseExpression(text)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	sel, _, def := exprSelec(keyspaces, pred)
+	if math.Abs(sel-expected) > 1e-9 || def != expectedDef {
+		t.Errorf("%s: expected selectivity %v (default %v), got %v (default %v)", text, expected, expectedDef, sel, def)
+	}
+}
+
+func TestHistogramSelectivity(t *testing.T) {
+	setTestDictionary(t)
+	defer datastore.SetDictionary(nil)
+
+	keyspaces := map[string]string{"p": "default:people"}
+
+	testSelec(t, "p.age = 30", keyspaces, 0.2, false)
+	testSelec(t, "p.age = 50", keyspaces, 0.3/20, false)
+	testSelec(t, "p.age = 100", keyspaces, 0.001, false)
+	testSelec(t, "p.age < 30", keyspaces, 0.2+0.15, false)
+	testSelec(t, "30 <= p.age", keyspaces, 0.2+0.15+0.3, false)
+	testSelec(t, "p.age BETWEEN 40 AND 60", keyspaces, 0.3, false)
+	testSelec(t, "p.age IN [30, 50, 30]", keyspaces, 0.2+0.015, false)
+	testSelec(t, "NOT (p.age = 30)", keyspaces, 0.8, false)
+	testSelec(t, "p.age = 30 OR p.age = 50", keyspaces, 0.2+0.015-0.2*0.015, false)
+	testSelec(t, "p.age = 30 AND p.name = \"ann\"", keyspaces, 0.2*_SELEC_DEF_EQ, false)
+	testSelec(t, "p.name = \"ann\"", keyspaces, _SELEC_DEF_EQ, true)
+	testSelec(t, "p.age IS NOT VALUED", keyspaces, _SELEC_MIN, false)
+
+	// a keyspace without statistics
+	testSelec(t, "q.age = 30", map[string]string{"q": "default:places"}, _SELEC_DEF_EQ, true)
+
+	// join selectivity uses the larger number of distinct values
+	testSelec(t, "p.age = q.age", map[string]string{"p": "default:people", "q": "default:places"}, 1.0/60, false)
+}
+
+func TestFilterSelectivity(t *testing.T) {
+	setTestDictionary(t)
+	defer datastore.SetDictionary(nil)
+
+	pred, err := n1ql.ParseExpression("p.age = 30")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	filter := base.NewFilter(pred, pred, map[string]string{"p": "default:people"}, false, false)
+	optCalcSelectivity(filter)
+	if filter.Selec() != 0.2 || !filter.IsSelecDone() || filter.HasDefSelec() {
+		t.Errorf("expected selectivity 0.2, got %v", filter.Selec())
+	}
+
+	// no selectivity without statistics
+	filter = base.NewFilter(pred, pred, map[string]string{"p": "default:places"}, false, false)
+	optCalcSelectivity(filter)
+	if filter.Selec() != OPT_SELEC_NOT_AVAIL {
+		t.Errorf("expected no selectivity, got %v", filter.Selec())
+	}
+
+	sel, _ := optExprSelec(map[string]string{"p": "default:people"}, expression.NewIsNotNull(pred))
+	if sel != OPT_SELEC_NOT_AVAIL {
+		t.Errorf("expected no selectivity for default estimate, got %v", sel)
+	}
+}
+
+func TestSortCost(t *testing.T) {
+	cost, card := getSortCost(1, 1000, 10, 5)
+	if card != 10 {
+		t.Errorf("expected cardinality 10, got %v", card)
+	}
+
+	fullCost, fullCard := getSortCost(1, 1000, 0, 0)
+	if fullCard != 1000 || fullCost <= cost {
+		t.Errorf("expected full sort to cost more than %v, got %v", cost, fullCost)
+	}
+}
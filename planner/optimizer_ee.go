@@ -48,29 +48,29 @@ func optMarkIndexFilters(keys expression.Expressions, spans plan.Spans2,
 	optimizer.MarkIndexFilters(keys, spans, condition, filters)
 }
 
-func primaryIndexScanCost(primary datastore.PrimaryIndex, requestId string) (cost, cardinality float64) {
+func primaryIndexScanCost(primary datastore.PrimaryIndex, keyspace, requestId string) (cost, cardinality float64) {
 	return optimizer.CalcPrimaryIndexScanCost(primary, requestId)
 }
 
 func indexScanCost(index datastore.Index, sargKeys expression.Expressions, requestId string,
-	spans SargSpans, alias string) (cost float64, sel float64, card float64, err error) {
+	spans SargSpans, alias, keyspace string) (cost float64, sel float64, card float64, err error) {
 	switch spans := spans.(type) {
 	case *TermSpans:
 		return optimizer.CalcIndexScanCost(index, sargKeys, requestId, spans.spans, alias)
 	case *IntersectSpans:
-		return multiIndexCost(index, sargKeys, requestId, spans.spans, alias, false)
+		return multiIndexCost(index, sargKeys, requestId, spans.spans, alias, keyspace, false)
 	case *UnionSpans:
-		return multiIndexCost(index, sargKeys, requestId, spans.spans, alias, true)
+		return multiIndexCost(index, sargKeys, requestId, spans.spans, alias, keyspace, true)
 	}
 
 	return OPT_COST_NOT_AVAIL, OPT_SELEC_NOT_AVAIL, OPT_CARD_NOT_AVAIL, errors.NewPlanInternalError("indexScanCost: unexpected span type")
 }
 
 func multiIndexCost(index datastore.Index, sargKeys expression.Expressions, requestId string,
-	spans []SargSpans, alias string, union bool) (cost float64, sel float64, card float64, err error) {
+	spans []SargSpans, alias, keyspace string, union bool) (cost float64, sel float64, card float64, err error) {
 	var nrows float64
 	for i, span := range spans {
-		tcost, tsel, tcard, e := indexScanCost(index, sargKeys, requestId, span, alias)
+		tcost, tsel, tcard, e := indexScanCost(index, sargKeys, requestId, span, alias, keyspace)
 		if e != nil {
 			return tcost, tsel, tcard, e
 		}
@@ -145,6 +145,10 @@ func optChooseIntersectScan(keyspace datastore.Keyspace, indexes map[datastore.I
 	return optimizer.ChooseIntersectScan(keyspace, indexes)
 }
 
+func optIntersectAfterSarg() bool {
+	return false
+}
+
 func getSortCost(nterms int, cardinality float64, limit, offset int64) (float64, float64) {
 	return optimizer.CalcSortCost(nterms, cardinality, limit, offset)
 }
@@ -347,6 +347,23 @@ func (this *scanIdxCol) VisitBuildIndexes(op *plan.BuildIndexes) (interface{}, e
 	return nil, nil
 }
 
+// Scope and collection DDL
+func (this *scanIdxCol) VisitCreateScope(op *plan.CreateScope) (interface{}, error) {
+	return nil, nil
+}
+
+func (this *scanIdxCol) VisitDropScope(op *plan.DropScope) (interface{}, error) {
+	return nil, nil
+}
+
+func (this *scanIdxCol) VisitCreateCollection(op *plan.CreateCollection) (interface{}, error) {
+	return nil, nil
+}
+
+func (this *scanIdxCol) VisitDropCollection(op *plan.DropCollection) (interface{}, error) {
+	return nil, nil
+}
+
 // Roles
 func (this *scanIdxCol) VisitGrantRole(op *plan.GrantRole) (interface{}, error) {
 	return nil, nil
@@ -402,6 +419,26 @@ func (this *scanIdxCol) VisitUpdateStatistics(op *plan.UpdateStatistics) (interf
 	return nil, nil
 }
 
+func (this *scanIdxCol) VisitDeleteStatistics(op *plan.DeleteStatistics) (interface{}, error) {
+	return nil, nil
+}
+
+func (this *scanIdxCol) VisitStartTransaction(op *plan.StartTransaction) (interface{}, error) {
+	return nil, nil
+}
+
+func (this *scanIdxCol) VisitCommitTransaction(op *plan.CommitTransaction) (interface{}, error) {
+	return nil, nil
+}
+
+func (this *scanIdxCol) VisitRollbackTransaction(op *plan.RollbackTransaction) (interface{}, error) {
+	return nil, nil
+}
+
+func (this *scanIdxCol) VisitSavepoint(op *plan.Savepoint) (interface{}, error) {
+	return nil, nil
+}
+
 func formalizeIndexKeys(alias string, keys expression.Expressions) expression.Expressions {
 	formalizer := expression.NewSelfFormalizer(alias, nil)
 	keys = keys.Copy()
@@ -0,0 +1,227 @@
+//  Copyright (c) 2020 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package prepareds
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	json "github.com/couchbase/go_json"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/logging"
+	"github.com/couchbase/query/plan"
+	"github.com/couchbase/query/util"
+)
+
+// on disk store of named prepared statements
+// each statement is kept in its own file, so that adding and deleting
+// statements does not require rewriting the whole store.
+// only what is needed to build the plan again is kept: plans are not
+// persisted, since indexes and keyspaces might have changed by the time
+// the engine restarts
+
+const _PERSIST_SUFFIX = ".json"
+
+type persistedPrepared struct {
+	Name            string `json:"name"`
+	Text            string `json:"text"`
+	Namespace       string `json:"namespace"`
+	IndexApiVersion int    `json:"indexApiVersion"`
+	FeatureControls uint64 `json:"featureControls"`
+}
+
+type preparedStore struct {
+	sync.Mutex
+	dir       string
+	persisted map[string]persistedPrepared
+}
+
+var persistence preparedStore
+
+// enable the prepared store and load the statements it contains
+// the statements are only prepared again on first use
+// an empty directory disables the store
+func PreparedsPersistInit(dir string) errors.Error {
+	if dir == "" {
+		persistence.Lock()
+		persistence.dir = ""
+		persistence.persisted = nil
+		persistence.Unlock()
+		return nil
+	}
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return errors.NewPreparedsPersistError(err, dir)
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return errors.NewPreparedsPersistError(err, dir)
+	}
+
+	// most recently written first, so that if there are more
+	// statements than the cache can hold, we keep the newest
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].ModTime().After(files[j].ModTime())
+	})
+	entries := make([]persistedPrepared, 0, len(files))
+	limit := PreparedsLimit()
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), _PERSIST_SUFFIX) {
+			continue
+		}
+		path := filepath.Join(dir, f.Name())
+		if limit > 0 && len(entries) >= limit {
+			os.Remove(path)
+			continue
+		}
+		bytes, err := ioutil.ReadFile(path)
+		var entry persistedPrepared
+		if err == nil {
+			err = json.Unmarshal(bytes, &entry)
+		}
+		if err == nil && (entry.Name == "" || persistFile(entry.Name) != f.Name()) {
+			err = fmt.Errorf("name does not match file")
+		}
+		if err != nil {
+			logging.Errorf("Ignoring invalid persisted prepared statement %v: %v", path, err)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	persistence.Lock()
+	persistence.dir = dir
+	persistence.persisted = make(map[string]persistedPrepared, len(entries))
+	for _, entry := range entries {
+		persistence.persisted[entry.Name] = entry
+	}
+	persistence.Unlock()
+
+	// oldest first, to preserve the LRU order
+	for i := len(entries) - 1; i >= 0; i-- {
+		restorePrepared(entries[i])
+	}
+	if len(entries) > 0 {
+		logging.Infof("Restored %v prepared statements from %v", len(entries), dir)
+	}
+	return nil
+}
+
+// whether the prepared store is enabled
+func PreparedsPersisting() bool {
+	persistence.Lock()
+	rv := persistence.dir != ""
+	persistence.Unlock()
+	return rv
+}
+
+// whether a prepared statement is in the store
+func PreparedPersisted(name string) bool {
+	persistence.Lock()
+	_, rv := persistence.persisted[name]
+	persistence.Unlock()
+	return rv
+}
+
+// add a stub cache entry for a persisted statement
+// the stub carries no plan: the statement is prepared again when first requested
+func restorePrepared(entry persistedPrepared) {
+	prepared := plan.NewPrepared(nil, nil)
+	prepared.SetName(entry.Name)
+	prepared.SetText(entry.Text)
+	prepared.SetNamespace(entry.Namespace)
+	prepared.SetIndexApiVersion(entry.IndexApiVersion)
+	prepared.SetFeatureControls(entry.FeatureControls)
+	ce := &CacheEntry{
+		Prepared:       prepared,
+		MinServiceTime: math.MaxUint64,
+		MinRequestTime: math.MaxUint64,
+		restored:       true,
+	}
+
+	// whatever is in the cache already is more current than what we have
+	prepareds.cache.Add(ce, entry.Name, func(interface{}) util.Operation {
+		return util.IGNORE
+	})
+}
+
+func persistPrepared(prepared *plan.Prepared) {
+	entry := persistedPrepared{
+		Name:            prepared.Name(),
+		Text:            prepared.Text(),
+		Namespace:       prepared.Namespace(),
+		IndexApiVersion: prepared.IndexApiVersion(),
+		FeatureControls: prepared.FeatureControls(),
+	}
+
+	persistence.Lock()
+	defer persistence.Unlock()
+	if persistence.dir == "" {
+		return
+	}
+
+	// the statement may have been ejected from the cache already, even as it was added
+	if prepareds.cache.Get(entry.Name, nil) == nil {
+		return
+	}
+
+	// plans are prepared again every time metadata changes, the statement doesn't
+	old, ok := persistence.persisted[entry.Name]
+	if ok && old == entry {
+		return
+	}
+	bytes, err := json.Marshal(entry)
+	if err == nil {
+		path := filepath.Join(persistence.dir, persistFile(entry.Name))
+		tmp := path + ".tmp"
+		err = ioutil.WriteFile(tmp, bytes, 0600)
+		if err == nil {
+			err = os.Rename(tmp, path)
+		}
+		if err != nil {
+			os.Remove(tmp)
+		}
+	}
+	if err != nil {
+		logging.Errorf("Unable to persist prepared statement <ud>%v</ud>: %v", entry.Name, err)
+		return
+	}
+	persistence.persisted[entry.Name] = entry
+}
+
+func unpersistPrepared(name string) {
+	persistence.Lock()
+	defer persistence.Unlock()
+	if persistence.dir == "" {
+		return
+	}
+	if _, ok := persistence.persisted[name]; !ok {
+		return
+	}
+	delete(persistence.persisted, name)
+	err := os.Remove(filepath.Join(persistence.dir, persistFile(name)))
+	if err != nil && !os.IsNotExist(err) {
+		logging.Errorf("Unable to remove persisted prepared statement <ud>%v</ud>: %v", name, err)
+	}
+}
+
+// statement names can be of any length and contain anything, including path separators
+func persistFile(name string) string {
+	sum := sha256.Sum256([]byte(name))
+	return hex.EncodeToString(sum[:]) + _PERSIST_SUFFIX
+}
@@ -67,6 +67,13 @@ type CacheEntry struct {
 
 	sync.Mutex // for concurrent checking
 	populated  bool
+	restored   bool // loaded from the prepared store, not prepared yet
+}
+
+// whether the entry was loaded from the prepared store and still
+// has to be prepared again
+func (this *CacheEntry) Restored() bool {
+	return this.restored
 }
 
 var prepareds = &preparedCache{}
@@ -76,6 +83,11 @@ var systemstore datastore.Datastore
 // init prepareds cache
 func PreparedsInit(limit int) {
 	prepareds.cache = util.NewGenCache(limit)
+
+	// statements that don't fit in the cache don't stay in the store either
+	prepareds.cache.SetEjected(func(name string, entry interface{}) {
+		unpersistPrepared(name)
+	})
 	planner.SetPlanCache(prepareds)
 }
 
@@ -271,6 +283,7 @@ func (this *preparedCache) add(prepared *plan.Prepared, populated bool, track bo
 		if cont {
 			oldEntry.Prepared = prepared
 			oldEntry.populated = false
+			oldEntry.restored = false
 			if track {
 				atomic.AddInt32(&oldEntry.Uses, 1)
 
@@ -408,6 +421,7 @@ func AddPrepared(prepared *plan.Prepared) errors.Error {
 		return errors.NewPreparedNameError(
 			fmt.Sprintf("duplicate name: %s", prepared.Name()))
 	} else {
+		persistPrepared(prepared)
 		distributePrepared(prepared.Name(), prepared.EncodedPlan())
 		return nil
 	}
@@ -415,6 +429,7 @@ func AddPrepared(prepared *plan.Prepared) errors.Error {
 
 func DeletePrepared(name string) errors.Error {
 	if prepareds.cache.Delete(name, nil) {
+		unpersistPrepared(name)
 		return nil
 	}
 	return errors.NewNoSuchPreparedError(name)
@@ -438,7 +453,14 @@ func (prepareds *preparedCache) getPrepared(prepared_stmt value.Value, options u
 		host, name := distributed.RemoteAccess().SplitKey(prepared_stmt.Actual().(string))
 		ce := prepareds.get(value.NewValue(name), track)
 		if ce != nil {
-			prepared = ce.Prepared
+			if ce.restored {
+				prepared, err = prepareds.restore(ce, phaseTime)
+				if err != nil {
+					return nil, err
+				}
+			} else {
+				prepared = ce.Prepared
+			}
 		}
 		if prepared == nil && remote && host != "" && host != distributed.RemoteAccess().WhoAmI() {
 			distributed.RemoteAccess().GetRemoteDoc(host, name, "prepareds", "GET",
@@ -512,6 +534,26 @@ func (prepareds *preparedCache) getPrepared(prepared_stmt value.Value, options u
 	}
 }
 
+// prepare a statement loaded from the prepared store
+// only one request prepares it, the others wait for the plan
+func (prepareds *preparedCache) restore(ce *CacheEntry, phaseTime *time.Duration) (*plan.Prepared, errors.Error) {
+	ce.Lock()
+	defer ce.Unlock()
+
+	// somebody might have done it in the interim
+	if !ce.restored {
+		return ce.Prepared, nil
+	}
+	prepared, err := reprepare(ce.Prepared, phaseTime)
+	if err != nil {
+		return nil, err
+	}
+	prepareds.add(prepared, false, false, func(oldEntry *CacheEntry) bool {
+		return oldEntry.Prepared.Text() == prepared.Text()
+	})
+	return prepared, nil
+}
+
 func RecordPreparedMetrics(prepared *plan.Prepared, requestTime, serviceTime time.Duration) {
 	if prepared == nil {
 		return
@@ -606,6 +648,7 @@ func DecodePrepared(prepared_name string, prepared_stmt string, track bool, dist
 		})
 
 	if added {
+		persistPrepared(prepared)
 		if distribute {
 			distributePrepared(prepared.Name(), prepared_stmt)
 		}
@@ -650,6 +693,16 @@ func distributePrepared(name, plan string) {
 }
 
 func reprepare(prepared *plan.Prepared, phaseTime *time.Duration) (*plan.Prepared, errors.Error) {
+	return buildPrepared(prepared, phaseTime, false)
+}
+
+// TransactionPlan builds a plan for the prepared statement that takes into
+// account the mutations staged by a transaction. The plan is not cached.
+func TransactionPlan(prepared *plan.Prepared, phaseTime *time.Duration) (*plan.Prepared, errors.Error) {
+	return buildPrepared(prepared, phaseTime, true)
+}
+
+func buildPrepared(prepared *plan.Prepared, phaseTime *time.Duration, inTransaction bool) (*plan.Prepared, errors.Error) {
 	parse := time.Now()
 
 	// TODO switch to collections scope
@@ -665,11 +718,15 @@ func reprepare(prepared *plan.Prepared, phaseTime *time.Duration) (*plan.Prepare
 
 	// since this is a reprepare, no need to check semantics again after parsing.
 	// TODO switch to collections scope
+	prepareStmt, ok := stmt.(*algebra.Prepare)
+	if !ok {
+		return nil, errors.NewReprepareError(fmt.Errorf("%v is not a PREPARE statement", prepared.Name()))
+	}
 	prep := time.Now()
-	pl, err := planner.BuildPrepared(stmt.(*algebra.Prepare).Statement(), store, systemstore, prepared.Namespace(), false, true,
+	pl, err := planner.BuildPrepared(prepareStmt.Statement(), store, systemstore, prepared.Namespace(), false, true,
 
 		// building prepared statements should not depend on args
-		nil, nil, prepared.IndexApiVersion(), prepared.FeatureControls())
+		nil, nil, prepared.IndexApiVersion(), prepared.FeatureControls(), inTransaction)
 	if phaseTime != nil {
 		*phaseTime += time.Since(prep)
 	}
@@ -679,7 +736,13 @@ func reprepare(prepared *plan.Prepared, phaseTime *time.Duration) (*plan.Prepare
 
 	pl.SetName(prepared.Name())
 	pl.SetText(prepared.Text())
-	pl.SetType(prepared.Type())
+
+	// statements loaded from the prepared store don't carry a type
+	if prepared.Type() != "" {
+		pl.SetType(prepared.Type())
+	} else {
+		pl.SetType(prepareStmt.Type())
+	}
 	pl.SetIndexApiVersion(prepared.IndexApiVersion())
 	pl.SetFeatureControls(prepared.FeatureControls())
 	pl.SetNamespace(prepared.Namespace()) // TODO switch to collections scope
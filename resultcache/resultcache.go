@@ -0,0 +1,266 @@
+//  Copyright (c) 2020 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+/*
+Package resultcache keeps the results of SELECT statements, so that identical
+requests can be answered without executing the statement again.
+
+Entries are keyed by statement, prepared name, arguments, namespace and the users
+the request was authorized for.
+Each entry records the keyspaces the statement reads from: every time the engine
+mutates one of those, the entry is invalidated. Mutations made outside the engine
+are not seen, which is why entries also have a time to live.
+*/
+package resultcache
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	atomic "github.com/couchbase/go-couchbase/platform"
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/util"
+	"github.com/couchbase/query/value"
+)
+
+const (
+	DEF_LIMIT = 1024
+	DEF_TTL   = time.Minute
+
+	// results larger than this are not cached
+	MAX_RESULT_SIZE = 1 << 20
+)
+
+type Entry struct {
+	Key       string
+	Statement string
+	Name      string
+	Namespace string
+	Users     []string
+	Keyspaces []string
+	Results   [][]byte
+	Size      int
+	Created   time.Time
+	Expires   time.Time
+	LastUse   time.Time
+	Hits      int32
+
+	generations []uint64
+}
+
+type resultCache struct {
+	cache *util.GenCache
+	ttl   atomic.AlignedInt64
+
+	// keyspace generations, bumped every time a keyspace is mutated
+	sync.RWMutex
+	generations map[string]uint64
+}
+
+var results = &resultCache{
+	cache:       util.NewGenCache(DEF_LIMIT),
+	ttl:         atomic.AlignedInt64(DEF_TTL),
+	generations: make(map[string]uint64, 64),
+}
+
+// configure the result cache
+
+func ResultCacheInit(limit int, ttl time.Duration) {
+	ResultCacheSetLimit(limit)
+	ResultCacheSetTTL(ttl)
+}
+
+func ResultCacheLimit() int {
+	return results.cache.Limit()
+}
+
+func ResultCacheSetLimit(limit int) {
+	results.cache.SetLimit(limit)
+}
+
+func ResultCacheTTL() time.Duration {
+	return time.Duration(atomic.LoadInt64(&results.ttl))
+}
+
+func ResultCacheSetTTL(ttl time.Duration) {
+	atomic.StoreInt64(&results.ttl, int64(ttl))
+}
+
+// the cache key for a request
+func Key(statement, name, namespace string, namedArgs map[string]value.Value, positionalArgs value.Values,
+	users []string) (string, errors.Error) {
+
+	buf := make([]byte, 0, 256)
+	buf = strconv.AppendQuote(buf, name)
+	buf = append(buf, '_')
+	buf = strconv.AppendQuote(buf, namespace)
+	buf = append(buf, '_')
+	sorted := append([]string{}, users...)
+	sort.Strings(sorted)
+	buf = strconv.AppendQuote(buf, strings.Join(sorted, ","))
+	if len(namedArgs) > 0 {
+		args, err := json.Marshal(namedArgs)
+		if err != nil {
+			return "", errors.NewResultCacheKeyError(err)
+		}
+		buf = append(buf, args...)
+	}
+	buf = append(buf, '_')
+	if len(positionalArgs) > 0 {
+		args, err := json.Marshal(positionalArgs)
+		if err != nil {
+			return "", errors.NewResultCacheKeyError(err)
+		}
+		buf = append(buf, args...)
+	}
+	key, err := util.UUIDV5(string(buf), statement)
+	if err != nil {
+		return "", errors.NewResultCacheKeyError(err)
+	}
+	return key, nil
+}
+
+// a new entry, to be filled with the results of the statement
+// the generations of its keyspaces are taken now, so that mutations
+// that happen while the statement executes invalidate it
+func NewEntry(key, statement, name, namespace string, users, keyspaces []string) *Entry {
+	rv := &Entry{
+		Key:         key,
+		Statement:   statement,
+		Name:        name,
+		Namespace:   namespace,
+		Users:       users,
+		Keyspaces:   keyspaces,
+		generations: make([]uint64, len(keyspaces)),
+	}
+	results.RLock()
+	for i, k := range keyspaces {
+		rv.generations[i] = results.generations[k]
+	}
+	results.RUnlock()
+	return rv
+}
+
+// add a result to the entry
+// false if the entry has become too large to be cached
+func (this *Entry) AddResult(result []byte) bool {
+	if this.Size+len(result) > MAX_RESULT_SIZE {
+		this.Results = nil
+		this.Size = MAX_RESULT_SIZE + 1
+		return false
+	}
+	this.Results = append(this.Results, result)
+	this.Size += len(result)
+	return true
+}
+
+func (this *Entry) valid(now time.Time) bool {
+	if now.After(this.Expires) {
+		return false
+	}
+	results.RLock()
+	defer results.RUnlock()
+	for i, k := range this.Keyspaces {
+		if results.generations[k] != this.generations[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// the entry has been invalidated, or its time to live has expired
+func (this *Entry) Stale() bool {
+	return !this.valid(time.Now())
+}
+
+// cache the entry, unless a mutation has invalidated it in the interim
+func Add(entry *Entry) {
+	if entry.Size > MAX_RESULT_SIZE {
+		return
+	}
+	now := time.Now()
+	entry.Created = now
+	entry.Expires = now.Add(ResultCacheTTL())
+	if !entry.valid(now) {
+		return
+	}
+	results.cache.Add(entry, entry.Key, nil)
+}
+
+// a valid entry for the key, if there is one
+func Get(key string) *Entry {
+	cv := results.cache.Use(key, nil)
+	entry, ok := cv.(*Entry)
+	if !ok {
+		return nil
+	}
+	now := time.Now()
+	if !entry.valid(now) {
+		results.cache.Delete(key, nil)
+		return nil
+	}
+	atomic.AddInt32(&entry.Hits, 1)
+
+	// as for prepared statements, inaccurate but cheap
+	entry.LastUse = now
+	return entry
+}
+
+// invalidate all the entries reading from a keyspace
+func Invalidate(keyspace datastore.Keyspace) {
+	name := KeyspaceName(keyspace)
+	results.Lock()
+	results.generations[name]++
+	results.Unlock()
+}
+
+// the name keyspaces are tracked under, as in privileges:
+// collections are tracked as their bucket
+func KeyspaceName(keyspace datastore.Keyspace) string {
+	scope := keyspace.Scope()
+	if scope != nil && scope.Bucket() != nil {
+		bucket := scope.Bucket()
+		return bucket.NamespaceId() + ":" + bucket.Name()
+	}
+	if keyspace.NamespaceId() != "" {
+		return keyspace.NamespaceId() + ":" + keyspace.Name()
+	}
+	return keyspace.Name()
+}
+
+func Delete(key string) bool {
+	return results.cache.Delete(key, nil)
+}
+
+func Count() int {
+	return results.cache.Size()
+}
+
+func ResultCacheForeach(nonBlocking func(string, *Entry) bool, blocking func() bool) {
+	dummyF := func(id string, r interface{}) bool {
+		return nonBlocking(id, r.(*Entry))
+	}
+	results.cache.ForEach(dummyF, blocking)
+}
+
+func ResultCacheDo(key string, f func(*Entry)) {
+	var process func(interface{}) = nil
+
+	if f != nil {
+		process = func(entry interface{}) {
+			f(entry.(*Entry))
+		}
+	}
+	_ = results.cache.Get(key, process)
+}
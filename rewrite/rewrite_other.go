@@ -44,3 +44,23 @@ func (this *Rewrite) VisitInferKeyspace(stmt *algebra.InferKeyspace) (interface{
 func (this *Rewrite) VisitUpdateStatistics(stmt *algebra.UpdateStatistics) (interface{}, error) {
 	return stmt, stmt.MapExpressions(this)
 }
+
+func (this *Rewrite) VisitDeleteStatistics(stmt *algebra.DeleteStatistics) (interface{}, error) {
+	return stmt, stmt.MapExpressions(this)
+}
+
+func (this *Rewrite) VisitStartTransaction(stmt *algebra.StartTransaction) (interface{}, error) {
+	return stmt, nil
+}
+
+func (this *Rewrite) VisitCommitTransaction(stmt *algebra.CommitTransaction) (interface{}, error) {
+	return stmt, nil
+}
+
+func (this *Rewrite) VisitRollbackTransaction(stmt *algebra.RollbackTransaction) (interface{}, error) {
+	return stmt, nil
+}
+
+func (this *Rewrite) VisitSavepoint(stmt *algebra.Savepoint) (interface{}, error) {
+	return stmt, nil
+}
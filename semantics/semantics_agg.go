@@ -10,6 +10,7 @@
 package semantics
 
 import (
+	"fmt"
 	"strings"
 
 	"github.com/couchbase/query/algebra"
@@ -51,6 +52,52 @@ func (this *SemChecker) visitAggregateFunction(agg algebra.Aggregate) (err error
 			"semantics.visit_aggregate_function.filter")
 	}
 
+	// Aggregate syntax has ORDER BY, but aggregate doesn't support it
+	if oby := agg.OrderBy(); oby != nil {
+		if !algebra.AggregateHasProperty(aggName, algebra.AGGREGATE_ALLOWS_ORDER_BY) {
+			return errors.NewSemanticsError(nil, fmt.Sprintf("%s ORDER BY clause is not allowed.", aggName))
+		}
+
+		// DISTINCT values can only be ordered on themselves
+		if agg.HasFlags(algebra.AGGREGATE_DISTINCT) {
+			for _, term := range oby.Terms() {
+				if !term.Expression().EquivalentTo(agg.Operands()[0]) {
+					return errors.NewSemanticsError(nil,
+						fmt.Sprintf("%s ORDER BY clause must order on the aggregated expression with DISTINCT.", aggName))
+				}
+			}
+		}
+	}
+
+	// STRING_AGG separator must be a constant
+	if algebra.AggregateHasProperty(aggName, algebra.AGGREGATE_ALLOWS_ORDER_BY) && len(agg.Operands()) > 1 {
+		op := agg.Operands()[1]
+		ok := op != nil && op.Static() != nil
+		if ok {
+			val := op.Value()
+			ok = (val == nil || val.Type() == value.STRING)
+		}
+
+		if !ok {
+			return errors.NewSemanticsError(nil, fmt.Sprintf("%s separator must be a constant string.", aggName))
+		}
+	}
+
+	// Ordered-set aggregate fraction must be a constant number between 0 and 1
+	if algebra.AggregateHasProperty(aggName, algebra.AGGREGATE_WITHIN_GROUP) && len(agg.Operands()) > 1 {
+		op := agg.Operands()[1]
+		ok := op != nil && op.Static() != nil
+		if ok {
+			val := op.Value()
+			ok = (val == nil || (val.Type() == value.NUMBER && val.(value.NumberValue).Float64() >= 0.0 &&
+				val.(value.NumberValue).Float64() <= 1.0))
+		}
+
+		if !ok {
+			return errors.NewSemanticsError(nil, fmt.Sprintf("%s fraction must be a constant number between 0 and 1.", aggName))
+		}
+	}
+
 	wTerm := agg.WindowTerm()
 	if wTerm == nil {
 		if algebra.AggregateHasProperty(aggName, algebra.AGGREGATE_ALLOWS_REGULAR) {
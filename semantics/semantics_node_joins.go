@@ -10,6 +10,8 @@
 package semantics
 
 import (
+	"strings"
+
 	"github.com/couchbase/query/algebra"
 	"github.com/couchbase/query/errors"
 )
@@ -88,6 +90,16 @@ func (this *SemChecker) VisitAnsiJoin(node *algebra.AnsiJoin) (r interface{}, er
 		return nil, err
 	}
 
+	// a RIGHT OUTER JOIN done by hash join builds on the left-hand side
+	if node.RightOuter() {
+		right := node.Right()
+		if !node.Outer() && right.JoinHint() == algebra.USE_HASH_BUILD {
+			op := strings.ToUpper(node.JoinType().String()) + " JOIN"
+			return nil, errors.NewOuterJoinJoinHintError(op, right.Alias(), "USE HASH(BUILD)",
+				"semantics.visit_ansi_join.outer_join_hint")
+		}
+	}
+
 	if !this.hasSemFlag(_SEM_ENTERPRISE) {
 		ksterm := algebra.GetKeyspaceTerm(node.Right())
 		if ksterm != nil && ksterm.PreferHash() {
@@ -11,6 +11,7 @@ package semantics
 
 import (
 	"github.com/couchbase/query/algebra"
+	"github.com/couchbase/query/errors"
 	"github.com/couchbase/query/expression"
 )
 
@@ -26,6 +27,9 @@ func (this *SemChecker) VisitSubselect(node *algebra.Subselect) (r interface{},
 		if err = node.With().MapExpressions(this); err != nil {
 			return nil, err
 		}
+		if err = checkRecursiveWith(node.With()); err != nil {
+			return nil, err
+		}
 	}
 
 	if node.From() != nil {
@@ -66,3 +70,25 @@ func (this *SemChecker) VisitSubquery(expr expression.Subquery) (r interface{},
 	}
 	return expr, err
 }
+
+// A recursive WITH term must be of the form anchor UNION [ALL] recursive,
+// with an anchor that does not refer to the term itself, and the term as a
+// whole cannot be ordered or limited, as it is only complete once the
+// recursion is over
+func checkRecursiveWith(with expression.Bindings) error {
+	for _, b := range with {
+		rw, err := algebra.NewRecursiveWith(b)
+		if err != nil {
+			return err
+		} else if rw == nil {
+			continue
+		}
+
+		query := rw.Query()
+		if query.Order() != nil || query.Limit() != nil || query.Offset() != nil {
+			return errors.NewRecursiveWithSemanticError(b.Variable(), "cannot have ORDER BY, LIMIT or OFFSET.",
+				"semantics.with.recursive_order_limit")
+		}
+	}
+	return nil
+}
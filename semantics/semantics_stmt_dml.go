@@ -11,6 +11,7 @@ package semantics
 
 import (
 	"github.com/couchbase/query/algebra"
+	"github.com/couchbase/query/datastore"
 	"github.com/couchbase/query/errors"
 )
 
@@ -108,6 +109,9 @@ func (this *SemChecker) VisitUpdate(stmt *algebra.Update) (r interface{}, err er
 		if err = stmt.Set().MapExpressions(this); err != nil {
 			return nil, err
 		}
+		if err = checkSetMeta(stmt.Set()); err != nil {
+			return nil, err
+		}
 	}
 
 	if stmt.Unset() != nil {
@@ -140,6 +144,16 @@ func (this *SemChecker) VisitUpdate(stmt *algebra.Update) (r interface{}, err er
 	return nil, nil
 }
 
+// The expiration is the only meta data field passed on to the datastore
+func checkSetMeta(set *algebra.Set) error {
+	for _, t := range set.Terms() {
+		if field := t.MetaField(); field != "" && field != datastore.OPT_EXPIRATION {
+			return errors.NewUpdateMetaFieldError(field, "semantics.update.meta_field")
+		}
+	}
+	return nil
+}
+
 func (this *SemChecker) VisitMerge(stmt *algebra.Merge) (r interface{}, err error) {
 
 	actions := stmt.Actions()
@@ -157,6 +171,12 @@ func (this *SemChecker) VisitMerge(stmt *algebra.Merge) (r interface{}, err erro
 		}
 	}
 
+	if actions.Update() != nil && actions.Update().Set() != nil {
+		if err = checkSetMeta(actions.Update().Set()); err != nil {
+			return nil, err
+		}
+	}
+
 	source := stmt.Source()
 	if stmt.IsOnKey() {
 		if source.SubqueryTerm() != nil {
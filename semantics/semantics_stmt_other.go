@@ -28,9 +28,6 @@ func (this *SemChecker) VisitExplain(stmt *algebra.Explain) (interface{}, error)
 }
 
 func (this *SemChecker) VisitAdvise(stmt *algebra.Advise) (interface{}, error) {
-	if !this.hasSemFlag(_SEM_ENTERPRISE) {
-		return nil, errors.NewEnterpriseFeature("Advise", "semantics.visit_advise")
-	}
 	switch stmt.Statement().Type() {
 	case "SELECT", "DELETE", "MERGE", "UPDATE":
 		return stmt.Statement().Accept(this)
@@ -51,12 +48,33 @@ func (this *SemChecker) VisitInferKeyspace(stmt *algebra.InferKeyspace) (interfa
 	return nil, stmt.MapExpressions(this)
 }
 
+// whether statistics are supported is up to the datastore's StatUpdater
 func (this *SemChecker) VisitUpdateStatistics(stmt *algebra.UpdateStatistics) (interface{}, error) {
-	if !this.hasSemFlag(_SEM_ENTERPRISE) {
-		return nil, errors.NewEnterpriseFeature("Update Statistics", "semantics.visit_update_statistics")
-	}
 	if !distributed.RemoteAccess().Enabled(distributed.NEW_OPTIMIZER) {
 		return nil, errors.NewMHDPOnlyFeature("Update Statistics", "semantics.visit_update_statistics")
 	}
 	return nil, stmt.MapExpressions(this)
 }
+
+func (this *SemChecker) VisitDeleteStatistics(stmt *algebra.DeleteStatistics) (interface{}, error) {
+	if !distributed.RemoteAccess().Enabled(distributed.NEW_OPTIMIZER) {
+		return nil, errors.NewMHDPOnlyFeature("Delete Statistics", "semantics.visit_delete_statistics")
+	}
+	return nil, stmt.MapExpressions(this)
+}
+
+func (this *SemChecker) VisitStartTransaction(stmt *algebra.StartTransaction) (interface{}, error) {
+	return nil, nil
+}
+
+func (this *SemChecker) VisitCommitTransaction(stmt *algebra.CommitTransaction) (interface{}, error) {
+	return nil, nil
+}
+
+func (this *SemChecker) VisitRollbackTransaction(stmt *algebra.RollbackTransaction) (interface{}, error) {
+	return nil, nil
+}
+
+func (this *SemChecker) VisitSavepoint(stmt *algebra.Savepoint) (interface{}, error) {
+	return nil, nil
+}
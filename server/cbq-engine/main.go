@@ -35,9 +35,12 @@ import (
 	"github.com/couchbase/query/logging"
 	log_resolver "github.com/couchbase/query/logging/resolver"
 	"github.com/couchbase/query/prepareds"
+	"github.com/couchbase/query/resultcache"
 	"github.com/couchbase/query/scheduler"
 	"github.com/couchbase/query/server"
 	"github.com/couchbase/query/server/http"
+	"github.com/couchbase/query/tracing"
+	"github.com/couchbase/query/transactions"
 	"github.com/couchbase/query/util"
 )
 
@@ -52,6 +55,7 @@ const (
 	_DEF_FUNCTIONS_LIMIT        = 16384
 	_DEF_DICTIONARY_CACHE_LIMIT = 16384
 	_DEF_TASKS_LIMIT            = 16384
+	_DEF_RECURSION_DEPTH        = 1000
 )
 
 var DATASTORE = flag.String("datastore", "", "Datastore address (http://URL or dir:PATH or mock:)")
@@ -84,6 +88,12 @@ var KEEP_ALIVE_LENGTH = flag.Int("keep-alive-length", server.KEEP_ALIVE_DEFAULT,
 var STATIC_PATH = flag.String("static-path", "static", "Path to static content")
 var PIPELINE_CAP = flag.Int64("pipeline-cap", _DEF_PIPELINE_CAP, "Maximum number of items each execution operator can buffer")
 var PIPELINE_BATCH = flag.Int("pipeline-batch", _DEF_PIPELINE_BATCH, "Number of items execution operators can batch")
+var SPILL_THRESHOLD = flag.Int64("spill-threshold", 0, "Memory in bytes a request can use sorting, grouping and hash joining before spilling to disk; use zero or negative value to disable")
+var MEMORY_QUOTA = flag.Uint64("memory-quota", 0, "Default maximum memory in MB a request can use to buffer values; zero means no limit")
+var MAX_RECURSION_DEPTH = flag.Int("max-recursion-depth", _DEF_RECURSION_DEPTH, "Default maximum number of iterations of WITH RECURSIVE terms")
+var TXTIMEOUT = flag.Duration("txtimeout", transactions.DEF_TIMEOUT, "Default transaction timeout, e.g. 500ms or 2s")
+var TMP_SPACE_DIR = flag.String("tmp-space-dir", "", "Directory for spill files; the system temporary directory if empty")
+var OTLP_ENDPOINT = flag.String("otlp-endpoint", "", "OTLP/HTTP URL request traces are exported to, e.g. http://localhost:4318/v1/traces; tracing is off if empty")
 var ENTERPRISE = flag.Bool("enterprise", true, "Enterprise mode")
 var MAX_INDEX_API = flag.Int("max-index-api", datastore_package.INDEX_API_MAX, "Max Index API")
 var N1QL_FEAT_CTRL = flag.Uint64("n1ql-feat-ctrl", util.DEF_N1QL_FEAT_CTRL, "N1QL Feature Controls")
@@ -97,6 +107,9 @@ var COMPLETED_THRESHOLD = flag.Int("completed-threshold", _DEF_COMPLETED_THRESHO
 var COMPLETED_LIMIT = flag.Int("completed-limit", _DEF_COMPLETED_LIMIT, "maximum number of completed requests")
 
 var PREPARED_LIMIT = flag.Int("prepared-limit", _DEF_PREPARED_LIMIT, "maximum number of prepared statements")
+var PREPAREDS_DIR = flag.String("prepareds-dir", "", "Directory named prepared statements are persisted to and restored from at startup; not persisted if empty")
+var RESULT_CACHE_LIMIT = flag.Int("result-cache-limit", resultcache.DEF_LIMIT, "maximum number of cached results")
+var RESULT_CACHE_TTL = flag.Duration("result-cache-ttl", resultcache.DEF_TTL, "Time cached results are served for, e.g. 30s or 5m")
 var AUTO_PREPARE = flag.Bool("auto-prepare", false, "Silently prepare ad hoc statements if possible")
 
 var FUNCTIONS_LIMIT = flag.Int("functions-limit", _DEF_FUNCTIONS_LIMIT, "maximum number of cached functions")
@@ -228,7 +241,9 @@ func main() {
 	}
 	prepareds.PreparedsInit(*PREPARED_LIMIT)
 	functions.FunctionsSetLimit(*FUNCTIONS_LIMIT)
+	resultcache.ResultCacheInit(*RESULT_CACHE_LIMIT, *RESULT_CACHE_TTL)
 	scheduler.SchedulerSetLimit(*TASKS_LIMIT)
+	transactions.SetDefaultTimeout(*TXTIMEOUT)
 
 	if *DICTIONARY_CACHE_LIMIT <= 0 {
 		logging.Errorp("Ignoring invalid dictionary cache size",
@@ -257,6 +272,13 @@ func main() {
 
 	datastore_package.SetSystemstore(server.Systemstore())
 	prepareds.PreparedsReprepareInit(datastore, sys)
+	if *PREPAREDS_DIR != "" {
+		err := prepareds.PreparedsPersistInit(*PREPAREDS_DIR)
+		if err != nil {
+			logging.Errorp("Prepared statements will not be persisted",
+				logging.Pair{"error", err})
+		}
+	}
 
 	server.SetCpuProfile(*CPU_PROFILE)
 	server.SetKeepAlive(*KEEP_ALIVE_LENGTH)
@@ -264,6 +286,10 @@ func main() {
 	server.SetScanCap(*SCAN_CAP)
 	server.SetPipelineCap(*PIPELINE_CAP)
 	server.SetPipelineBatch(*PIPELINE_BATCH)
+	server.SetSpillThreshold(*SPILL_THRESHOLD)
+	server.SetTmpSpaceDir(*TMP_SPACE_DIR)
+	server.SetMemoryQuota(*MEMORY_QUOTA)
+	server.SetMaxRecursionDepth(*MAX_RECURSION_DEPTH)
 	server.SetRequestSizeCap(*REQUEST_SIZE_CAP)
 	server.SetScanCap(*SCAN_CAP)
 	server.SetMaxIndexAPI(*MAX_INDEX_API)
@@ -276,6 +302,11 @@ func main() {
 
 	audit.StartAuditService(*DATASTORE, *SERVICERS+*PLUS_SERVICERS)
 
+	if *OTLP_ENDPOINT != "" {
+		hostname, _ := os.Hostname()
+		tracing.SetExporter(tracing.NewOTLPExporter(*OTLP_ENDPOINT, hostname))
+	}
+
 	logging.Infop("cbq-engine started",
 		logging.Pair{"version", util.VERSION},
 		logging.Pair{"datastore", *DATASTORE},
@@ -286,6 +317,11 @@ func main() {
 		logging.Pair{"scan-cap", server.ScanCap()},
 		logging.Pair{"pipeline-cap", server.PipelineCap()},
 		logging.Pair{"pipeline-batch", server.PipelineBatch()},
+		logging.Pair{"spill-threshold", server.SpillThreshold()},
+		logging.Pair{"tmp-space-dir", server.TmpSpaceDir()},
+		logging.Pair{"memory-quota", server.MemoryQuota()},
+		logging.Pair{"max-recursion-depth", server.MaxRecursionDepth()},
+		logging.Pair{"txtimeout", transactions.DefaultTimeout()},
 		logging.Pair{"request-cap", *REQUEST_CAP},
 		logging.Pair{"request-size-cap", server.RequestSizeCap()},
 		logging.Pair{"max-index-api", server.MaxIndexAPI()},
@@ -40,6 +40,14 @@ const (
 	MUTEXPROFILE    = "mutexprofile"
 	FUNCLIMIT       = "functions-limit"
 	TASKLIMIT       = "tasks-limit"
+	SPILLTHRESHOLD  = "spill-threshold"
+	TMPSPACEDIR     = "tmp-space-dir"
+	MEMORYQUOTA     = "memory-quota"
+	RECURSIONDEPTH  = "max-recursion-depth"
+	TXTIMEOUT       = "txtimeout"
+	WORKLOADGROUPS  = "workload-groups"
+	RESCACHELIMIT   = "result-cache-limit"
+	RESCACHETTL     = "result-cache-ttl"
 )
 
 type Checker func(interface{}) (bool, errors.Error)
@@ -70,6 +78,14 @@ var CHECKERS = map[string]Checker{
 	MUTEXPROFILE:    checkBool,
 	FUNCLIMIT:       checkPositiveInteger,
 	TASKLIMIT:       checkPositiveInteger,
+	SPILLTHRESHOLD:  checkNumber,
+	MEMORYQUOTA:     checkNumber,
+	RECURSIONDEPTH:  checkPositiveInteger,
+	TXTIMEOUT:       checkNumber,
+	TMPSPACEDIR:     checkString,
+	WORKLOADGROUPS:  checkWorkloadGroups,
+	RESCACHELIMIT:   checkPositiveInteger,
+	RESCACHETTL:     checkNumber,
 }
 
 func checkBool(val interface{}) (bool, errors.Error) {
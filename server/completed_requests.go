@@ -53,6 +53,9 @@ type RequestLogEntry struct {
 	ErrorCount      int
 	Errors          []errors.Error
 	Mutations       uint64
+	UsedMemory      uint64
+	WorkloadGroup   string
+	QueueWaitTime   time.Duration
 	PreparedName    string
 	PreparedText    string
 	Time            time.Time
@@ -102,6 +105,10 @@ func RequestsInit(threshold int, limit int) {
 	if aErr == nil {
 		requestLog.qualifiers = append(requestLog.qualifiers, aq)
 	}
+	rq, rErr := newRejected(nil)
+	if rErr == nil {
+		requestLog.qualifiers = append(requestLog.qualifiers, rq)
+	}
 	requestLog.taggedQualifiers = make(map[string][]qualifier)
 
 	requestLog.cache = util.NewGenCache(limit)
@@ -167,6 +174,8 @@ func RequestsCheckQualifier(name string, op RequestsOp, condition interface{}, t
 		_, err = newTimeThreshold(condition)
 	case "aborted":
 		_, err = newAborted(condition)
+	case "rejected":
+		_, err = newRejected(condition)
 	case "error":
 		_, err = newReqError(condition)
 	case "user":
@@ -205,6 +214,8 @@ func RequestsAddQualifier(name string, condition interface{}, tag string) errors
 		q, err = newTimeThreshold(condition)
 	case "aborted":
 		q, err = newAborted(condition)
+	case "rejected":
+		q, err = newRejected(condition)
 	case "error":
 		q, err = newReqError(condition)
 	case "user":
@@ -453,6 +464,9 @@ func LogRequest(request_time time.Duration, service_time time.Duration,
 		Time:            time.Now(),
 		ScanConsistency: string(request.ScanConsistency()),
 		Mutations:       request.MutationCount(),
+		UsedMemory:      request.UsedMemory(),
+		WorkloadGroup:   request.WorkloadGroup(),
+		QueueWaitTime:   request.QueueWaitTime(),
 	}
 	stmt := request.Statement()
 	if stmt != "" {
@@ -809,3 +823,40 @@ func (this *context) checkCondition(c interface{}) errors.Error {
 func (this *context) evaluate(request *BaseRequest, req *http.Request) bool {
 	return this.id == request.ClientContextId()
 }
+
+// 7- rejected by workload management
+type rejected struct {
+}
+
+func newRejected(c interface{}) (*rejected, errors.Error) {
+	return &rejected{}, nil
+}
+
+func (this *rejected) name() string {
+	return "rejected"
+}
+
+func (this *rejected) unique() bool {
+	return true
+}
+
+func (this *rejected) condition() interface{} {
+	return nil
+}
+
+func (this *rejected) isCondition(c interface{}) bool {
+	return true
+}
+
+func (this *rejected) checkCondition(c interface{}) errors.Error {
+	return nil
+}
+
+func (this *rejected) evaluate(request *BaseRequest, req *http.Request) bool {
+	for _, e := range request.Errors() {
+		if e.Code() == errors.WORKLOAD_QUEUE_FULL || e.Code() == errors.WORKLOAD_QUEUE_TIMEOUT {
+			return true
+		}
+	}
+	return false
+}
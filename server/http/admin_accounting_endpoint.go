@@ -105,6 +105,9 @@ func (this *HttpEndpoint) registerAccountingHandlers() {
 	tasksHandler := func(w http.ResponseWriter, req *http.Request) {
 		this.wrapAPI(w, req, doTasks)
 	}
+	metricsHandler := func(w http.ResponseWriter, req *http.Request) {
+		this.wrapAPI(w, req, doMetrics)
+	}
 	routeMap := map[string]struct {
 		handler handlerFunc
 		methods []string
@@ -127,6 +130,7 @@ func (this *HttpEndpoint) registerAccountingHandlers() {
 		indexesPrefix + "/completed_requests": {handler: completedIndexHandler, methods: []string{"GET"}},
 		indexesPrefix + "/functions_cache":    {handler: functionsIndexHandler, methods: []string{"GET"}},
 		indexesPrefix + "/tasks_cache":        {handler: tasksIndexHandler, methods: []string{"GET"}},
+		metricsRoute:                          {handler: metricsHandler, methods: []string{"GET"}},
 	}
 
 	for route, h := range routeMap {
@@ -331,6 +335,12 @@ func doPrepared(endpoint *HttpEndpoint, w http.ResponseWriter, req *http.Request
 			if entry.Prepared.EncodedPlan() != "" {
 				itemMap["encoded_plan"] = entry.Prepared.EncodedPlan()
 			}
+			if prepareds.PreparedsPersisting() {
+				itemMap["persisted"] = prepareds.PreparedPersisted(name)
+			}
+			if entry.Restored() {
+				itemMap["restored"] = true
+			}
 			if req.Method == "POST" {
 				itemMap["plan"] = entry.Prepared.Operator
 			}
@@ -380,6 +390,9 @@ func doPrepareds(endpoint *HttpEndpoint, w http.ResponseWriter, req *http.Reques
 			}
 			data[i]["statement"] = d.Prepared.Text()
 			data[i]["uses"] = d.Uses
+			if prepareds.PreparedsPersisting() {
+				data[i]["persisted"] = prepareds.PreparedPersisted(name)
+			}
 			if d.Uses > 0 {
 				data[i]["lastUse"] = d.LastUse.String()
 			}
@@ -653,6 +666,16 @@ func activeRequestWorkHorse(endpoint *HttpEndpoint, requestId string, profiling
 		if p != nil {
 			reqMap["phaseCounts"] = p
 		}
+		usedMemory := request.UsedMemory()
+		if usedMemory != 0 {
+			reqMap["usedMemory"] = usedMemory
+		}
+		if request.WorkloadGroup() != "" {
+			reqMap["workloadGroup"] = request.WorkloadGroup()
+		}
+		if request.QueueWaitTime() != 0 {
+			reqMap["queueWaitTime"] = request.QueueWaitTime().String()
+		}
 		p = request.Output().FmtPhaseOperators()
 		if p != nil {
 			reqMap["phaseOperators"] = p
@@ -756,6 +779,16 @@ func doActiveRequests(endpoint *HttpEndpoint, w http.ResponseWriter, req *http.R
 		if p != nil {
 			requests[i]["phaseCounts"] = p
 		}
+		usedMemory := request.UsedMemory()
+		if usedMemory != 0 {
+			requests[i]["usedMemory"] = usedMemory
+		}
+		if request.WorkloadGroup() != "" {
+			requests[i]["workloadGroup"] = request.WorkloadGroup()
+		}
+		if request.QueueWaitTime() != 0 {
+			requests[i]["queueWaitTime"] = request.QueueWaitTime().String()
+		}
 		p = request.Output().FmtPhaseOperators()
 		if p != nil {
 			requests[i]["phaseOperators"] = p
@@ -828,6 +861,15 @@ func completedRequestWorkHorse(requestId string, profiling bool) map[string]inte
 		if request.Mutations != 0 {
 			reqMap["mutations"] = request.Mutations
 		}
+		if request.UsedMemory != 0 {
+			reqMap["usedMemory"] = request.UsedMemory
+		}
+		if request.WorkloadGroup != "" {
+			reqMap["workloadGroup"] = request.WorkloadGroup
+		}
+		if request.QueueWaitTime != 0 {
+			reqMap["queueWaitTime"] = request.QueueWaitTime.String()
+		}
 		if request.PhaseCounts != nil {
 			reqMap["phaseCounts"] = request.PhaseCounts
 		}
@@ -912,6 +954,15 @@ func doCompletedRequests(endpoint *HttpEndpoint, w http.ResponseWriter, req *htt
 		if request.Mutations != 0 {
 			requests[i]["mutations"] = request.Mutations
 		}
+		if request.UsedMemory != 0 {
+			requests[i]["usedMemory"] = request.UsedMemory
+		}
+		if request.WorkloadGroup != "" {
+			requests[i]["workloadGroup"] = request.WorkloadGroup
+		}
+		if request.QueueWaitTime != 0 {
+			requests[i]["queueWaitTime"] = request.QueueWaitTime.String()
+		}
 		if request.PhaseCounts != nil {
 			requests[i]["phaseCounts"] = request.PhaseCounts
 		}
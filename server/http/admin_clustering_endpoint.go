@@ -26,7 +26,9 @@ import (
 	"github.com/couchbase/query/functions"
 	"github.com/couchbase/query/logging"
 	"github.com/couchbase/query/prepareds"
+	"github.com/couchbase/query/resultcache"
 	"github.com/couchbase/query/server"
+	"github.com/couchbase/query/transactions"
 	"github.com/couchbase/query/util"
 	"github.com/gorilla/mux"
 )
@@ -466,6 +468,14 @@ func fillSettings(settings map[string]interface{}, srvr *server.Server) map[stri
 	settings[server.AUTOPREPARE] = srvr.AutoPrepare()
 	settings[server.MUTEXPROFILE] = srvr.MutexProfile()
 	settings[server.FUNCLIMIT] = functions.FunctionsLimit()
+	settings[server.SPILLTHRESHOLD] = srvr.SpillThreshold()
+	settings[server.TMPSPACEDIR] = srvr.TmpSpaceDir()
+	settings[server.MEMORYQUOTA] = srvr.MemoryQuota()
+	settings[server.RECURSIONDEPTH] = srvr.MaxRecursionDepth()
+	settings[server.TXTIMEOUT] = transactions.DefaultTimeout()
+	settings[server.WORKLOADGROUPS] = srvr.WorkloadGroups()
+	settings[server.RESCACHELIMIT] = resultcache.ResultCacheLimit()
+	settings[server.RESCACHETTL] = resultcache.ResultCacheTTL()
 	return settings
 }
 
@@ -53,6 +53,16 @@ func (this *HttpEndpoint) wrapAPI(w http.ResponseWriter, req *http.Request, f ap
 		return
 	}
 
+	if raw, ok := obj.(*rawResponse); ok {
+		w.Header().Set("Content-Type", raw.contentType)
+		w.WriteHeader(http.StatusOK)
+		w.Write(raw.body)
+
+		auditFields.HttpResultCode = http.StatusOK
+		audit.SubmitApiRequest(&auditFields)
+		return
+	}
+
 	buf, json_err := json.Marshal(obj)
 	if json_err != nil {
 		e := errors.NewAdminDecodingError(json_err)
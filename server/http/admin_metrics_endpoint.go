@@ -0,0 +1,311 @@
+//  Copyright (c) 2020 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package http
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	atomic "github.com/couchbase/go-couchbase/platform"
+	"github.com/couchbase/query/accounting"
+	"github.com/couchbase/query/audit"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/functions"
+	"github.com/couchbase/query/prepareds"
+	"github.com/couchbase/query/server"
+)
+
+// The metrics endpoint exposes the accounting registry, the prepared statements
+// cache, the functions cache and the workload groups in the Prometheus text
+// exposition format.
+
+const (
+	metricsRoute = "/metrics"
+
+	_METRICS_PREFIX  = "n1ql_"
+	_METRICS_CONTENT = "text/plain; version=0.0.4; charset=utf-8"
+
+	// maximum number of prepared statements and functions that get their own series,
+	// unless overridden with the limit parameter.
+	// The least used entries past the limit are summed up under the _OTHER_LABEL series
+	_METRICS_LABEL_LIMIT = 100
+	_OTHER_LABEL         = "_other"
+)
+
+var metricsQuantiles = []float64{0.5, 0.75, 0.95, 0.99, 0.999}
+
+// rawResponse is returned by admin APIs whose payload is not JSON
+type rawResponse struct {
+	contentType string
+	body        []byte
+}
+
+func doMetrics(endpoint *HttpEndpoint, w http.ResponseWriter, req *http.Request, af *audit.ApiAuditFields) (interface{}, errors.Error) {
+	af.EventTypeId = audit.API_ADMIN_STATS
+
+	switch req.Method {
+	case "GET":
+
+		// series are labelled with prepared and function names
+		err := verifyCredentialsFromRequest("prepareds", req, af)
+		if err != nil {
+			return nil, err
+		}
+		err = verifyCredentialsFromRequest("functions_cache", req, af)
+		if err != nil {
+			return nil, err
+		}
+
+		limit := _METRICS_LABEL_LIMIT
+		if l := req.FormValue("limit"); l != "" {
+			n, e := strconv.Atoi(l)
+			if e != nil || n < 0 {
+				return nil, errors.NewServiceErrorBadValue(fmt.Errorf("invalid label limit %v", l), "limit")
+			}
+			limit = n
+		}
+
+		buf := &bytes.Buffer{}
+		writeRegistryMetrics(buf, endpoint.server.AccountingStore().MetricRegistry())
+		writeLatencyMetrics(buf, "prepared", preparedLatencies(), limit)
+		writeLatencyMetrics(buf, "function", functionLatencies(), limit)
+		writeWorkloadMetrics(buf, endpoint.server.WorkloadStats())
+		return &rawResponse{contentType: _METRICS_CONTENT, body: buf.Bytes()}, nil
+	default:
+		return nil, errors.NewServiceErrorHttpMethod(req.Method)
+	}
+}
+
+func writeRegistryMetrics(buf *bytes.Buffer, reg accounting.MetricRegistry) {
+	counters := reg.Counters()
+	for _, name := range sortedNames(counters) {
+		metricName := metricsName(name) + "_total"
+		writeMetricType(buf, metricName, "counter")
+		writeSample(buf, metricName, "", float64(counters[name].Count()))
+	}
+
+	gauges := reg.Gauges()
+	for _, name := range sortedNames(gauges) {
+		metricName := metricsName(name)
+		writeMetricType(buf, metricName, "gauge")
+		writeSample(buf, metricName, "", float64(gauges[name].Value()))
+	}
+
+	meters := reg.Meters()
+	for _, name := range sortedNames(meters) {
+		metricName := metricsName(name) + "_total"
+		writeMetricType(buf, metricName, "counter")
+		writeSample(buf, metricName, "", float64(meters[name].Count()))
+	}
+
+	// timers sample durations, which are reported in seconds
+	timers := reg.Timers()
+	for _, name := range sortedNames(timers) {
+		metric := timers[name]
+		metricName := metricsName(name) + "_seconds"
+		writeMetricType(buf, metricName, "summary")
+		ps := metric.Percentiles(metricsQuantiles)
+		for i, q := range metricsQuantiles {
+			writeSample(buf, metricName, quantileLabel(q), ps[i]/float64(time.Second))
+		}
+		writeSample(buf, metricName+"_sum", "", float64(metric.Sum())/float64(time.Second))
+		writeSample(buf, metricName+"_count", "", float64(metric.Count()))
+	}
+
+	histograms := reg.Histograms()
+	for _, name := range sortedNames(histograms) {
+		metric := histograms[name]
+		metricName := metricsName(name)
+		writeMetricType(buf, metricName, "summary")
+		ps := metric.Percentiles(metricsQuantiles)
+		for i, q := range metricsQuantiles {
+			writeSample(buf, metricName, quantileLabel(q), ps[i])
+		}
+		writeSample(buf, metricName+"_sum", "", float64(metric.Sum()))
+		writeSample(buf, metricName+"_count", "", float64(metric.Count()))
+	}
+}
+
+// latency figures for a single cache entry
+type latency struct {
+	name           string
+	uses           int64
+	serviceTime    uint64
+	minServiceTime uint64
+	maxServiceTime uint64
+}
+
+func (this *latency) timed() bool {
+	return this.maxServiceTime > 0
+}
+
+func preparedLatencies() []*latency {
+	latencies := make([]*latency, 0, prepareds.CountPrepareds())
+	snapshot := func(name string, d *prepareds.CacheEntry) bool {
+		latencies = append(latencies, &latency{
+			name:           d.Prepared.Name(),
+			uses:           int64(d.Uses),
+			serviceTime:    atomic.LoadUint64(&d.ServiceTime),
+			minServiceTime: atomic.LoadUint64(&d.MinServiceTime),
+			maxServiceTime: atomic.LoadUint64(&d.MaxServiceTime),
+		})
+		return true
+	}
+	prepareds.PreparedsForeach(snapshot, nil)
+	return latencies
+}
+
+func functionLatencies() []*latency {
+	latencies := make([]*latency, 0, functions.CountFunctions())
+	snapshot := func(name string, d *functions.FunctionEntry) bool {
+		latencies = append(latencies, &latency{
+			name:           d.Key(),
+			uses:           int64(d.Uses),
+			serviceTime:    atomic.LoadUint64(&d.ServiceTime),
+			minServiceTime: atomic.LoadUint64(&d.MinServiceTime),
+			maxServiceTime: atomic.LoadUint64(&d.MaxServiceTime),
+		})
+		return true
+	}
+	functions.FunctionsForeach(snapshot, nil)
+	return latencies
+}
+
+// only the most used entries get their own series, so that the number of
+// series stays bounded no matter how many statements are prepared
+func writeLatencyMetrics(buf *bytes.Buffer, kind string, latencies []*latency, limit int) {
+	sort.Slice(latencies, func(i, j int) bool {
+		if latencies[i].uses != latencies[j].uses {
+			return latencies[i].uses > latencies[j].uses
+		}
+		return latencies[i].name < latencies[j].name
+	})
+	if len(latencies) > limit {
+		other := &latency{name: _OTHER_LABEL}
+		for _, l := range latencies[limit:] {
+			other.uses += l.uses
+			other.serviceTime += l.serviceTime
+			if l.timed() && (!other.timed() || l.minServiceTime < other.minServiceTime) {
+				other.minServiceTime = l.minServiceTime
+			}
+			if l.maxServiceTime > other.maxServiceTime {
+				other.maxServiceTime = l.maxServiceTime
+			}
+		}
+		latencies = append(latencies[:limit], other)
+	}
+
+	prefix := _METRICS_PREFIX + kind + "_"
+	writeMetricType(buf, prefix+"service_time_seconds", "summary")
+	for _, l := range latencies {
+		label := "name=\"" + escapeLabel(l.name) + "\""
+		writeSample(buf, prefix+"service_time_seconds_sum", label, float64(l.serviceTime)/float64(time.Second))
+		writeSample(buf, prefix+"service_time_seconds_count", label, float64(l.uses))
+	}
+
+	// minima and maxima are only meaningful once an execution has been timed
+	writeMetricType(buf, prefix+"min_service_time_seconds", "gauge")
+	for _, l := range latencies {
+		if l.timed() {
+			label := "name=\"" + escapeLabel(l.name) + "\""
+			writeSample(buf, prefix+"min_service_time_seconds", label, float64(l.minServiceTime)/float64(time.Second))
+		}
+	}
+	writeMetricType(buf, prefix+"max_service_time_seconds", "gauge")
+	for _, l := range latencies {
+		if l.timed() {
+			label := "name=\"" + escapeLabel(l.name) + "\""
+			writeSample(buf, prefix+"max_service_time_seconds", label, float64(l.maxServiceTime)/float64(time.Second))
+		}
+	}
+}
+
+// workload groups are few and configured, so they are never folded
+func writeWorkloadMetrics(buf *bytes.Buffer, stats []server.WorkloadStats) {
+	if len(stats) == 0 {
+		return
+	}
+	prefix := _METRICS_PREFIX + "workload_"
+	writeMetricType(buf, prefix+"running_requests", "gauge")
+	for _, s := range stats {
+		writeSample(buf, prefix+"running_requests", "group=\""+escapeLabel(s.Name)+"\"", float64(s.Running))
+	}
+	writeMetricType(buf, prefix+"queued_requests", "gauge")
+	for _, s := range stats {
+		writeSample(buf, prefix+"queued_requests", "group=\""+escapeLabel(s.Name)+"\"", float64(s.Queued))
+	}
+	writeMetricType(buf, prefix+"rejected_requests_total", "counter")
+	for _, s := range stats {
+		writeSample(buf, prefix+"rejected_requests_total", "group=\""+escapeLabel(s.Name)+"\"", float64(s.Rejected))
+	}
+}
+
+func writeMetricType(buf *bytes.Buffer, name, metricType string) {
+	buf.WriteString("# TYPE " + name + " " + metricType + "\n")
+}
+
+func writeSample(buf *bytes.Buffer, name, labels string, val float64) {
+	buf.WriteString(name)
+	if labels != "" {
+		buf.WriteString("{" + labels + "}")
+	}
+	buf.WriteString(" " + strconv.FormatFloat(val, 'g', -1, 64) + "\n")
+}
+
+func quantileLabel(q float64) string {
+	return "quantile=\"" + strconv.FormatFloat(q, 'g', -1, 64) + "\""
+}
+
+// metric names can only contain letters, digits, underscores and colons
+func metricsName(name string) string {
+	return _METRICS_PREFIX + strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' || r == ':' {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+func escapeLabel(label string) string {
+	return strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "\n", "\\n").Replace(label)
+}
+
+func sortedNames(metrics interface{}) []string {
+	var names []string
+	switch metrics := metrics.(type) {
+	case map[string]accounting.Counter:
+		for name, _ := range metrics {
+			names = append(names, name)
+		}
+	case map[string]accounting.Gauge:
+		for name, _ := range metrics {
+			names = append(names, name)
+		}
+	case map[string]accounting.Meter:
+		for name, _ := range metrics {
+			names = append(names, name)
+		}
+	case map[string]accounting.Timer:
+		for name, _ := range metrics {
+			names = append(names, name)
+		}
+	case map[string]accounting.Histogram:
+		for name, _ := range metrics {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -32,6 +32,7 @@ import (
 	"github.com/couchbase/query/prepareds"
 	"github.com/couchbase/query/server"
 	"github.com/couchbase/query/timestamp"
+	"github.com/couchbase/query/tracing"
 	"github.com/couchbase/query/util"
 	"github.com/couchbase/query/value"
 )
@@ -59,6 +60,10 @@ type httpRequest struct {
 
 	stmtCnt int
 	consCnt int
+
+	format   Format
+	columns  delimitedColumns
+	encoding string // content coding of the response, if compressed
 }
 
 var zeroScanVectorSource = &ZeroScanVectorSource{}
@@ -88,7 +93,13 @@ func newHttpRequest(rv *httpRequest, resp http.ResponseWriter, req *http.Request
 		err = errors.NewServiceErrorHTTPMethod(req.Method)
 	}
 
-	err = contentNegotiation(resp, req)
+	err = contentNegotiation(rv, resp, req)
+
+	// compress if the client is happy to receive compressed data;
+	// the compression parameter can override this
+	if encoding, ok := negotiateEncoding(req.Header["Accept-Encoding"]); ok {
+		rv.encoding = encoding
+	}
 
 	if err == nil {
 		httpArgs, err = getRequestParams(req, &urlArgs, &jsonArgs)
@@ -99,6 +110,9 @@ func newHttpRequest(rv *httpRequest, resp http.ResponseWriter, req *http.Request
 	server.NewBaseRequest(&rv.BaseRequest)
 	rv.SetRequestTime(reqTime)
 
+	// join the caller's trace, if any
+	rv.SetTrace(tracing.NewTrace(req.URL.Path, req.Header.Get("traceparent")))
+
 	// for GET method, only readonly access
 	if req.Method == "GET" {
 		rv.SetReadonly(value.TRUE)
@@ -259,6 +273,27 @@ func handleTimeout(rv *httpRequest, httpArgs httpRequestArgs, parm string, val i
 	return err
 }
 
+func handleTxId(rv *httpRequest, httpArgs httpRequestArgs, parm string, val interface{}) errors.Error {
+	txId, err := httpArgs.getStringVal(parm, val)
+	if err == nil {
+		rv.SetTxId(txId)
+	}
+	return err
+}
+
+func handleTxTimeout(rv *httpRequest, httpArgs httpRequestArgs, parm string, val interface{}) errors.Error {
+	var timeout time.Duration
+
+	t, err := httpArgs.getStringVal(parm, val)
+	if err == nil && t != "" {
+		timeout, err = newDuration(t)
+		if err == nil {
+			rv.SetTxTimeout(timeout)
+		}
+	}
+	return err
+}
+
 func handleMaxParallelism(rv *httpRequest, httpArgs httpRequestArgs, parm string, val interface{}) errors.Error {
 	param, err := httpArgs.getStringVal(parm, val)
 	if err == nil && param != "" {
@@ -301,6 +336,32 @@ func handlePipelineCap(rv *httpRequest, httpArgs httpRequestArgs, parm string, v
 	return err
 }
 
+func handleMemoryQuota(rv *httpRequest, httpArgs httpRequestArgs, parm string, val interface{}) errors.Error {
+	param, err := httpArgs.getStringVal(parm, val)
+	if err == nil && param != "" {
+		memory_quota, e := strconv.ParseUint(param, 10, 64)
+		if e != nil {
+			err = errors.NewServiceErrorBadValue(go_errors.New("memory_quota is invalid"), "memory quota")
+		} else {
+			rv.SetMemoryQuota(memory_quota)
+		}
+	}
+	return err
+}
+
+func handleMaxRecursionDepth(rv *httpRequest, httpArgs httpRequestArgs, parm string, val interface{}) errors.Error {
+	param, err := httpArgs.getStringVal(parm, val)
+	if err == nil && param != "" {
+		depth, e := strconv.Atoi(param)
+		if e != nil || depth < 0 {
+			err = errors.NewServiceErrorBadValue(go_errors.New("max_recursion_depth is invalid"), "max recursion depth")
+		} else {
+			rv.SetMaxRecursionDepth(depth)
+		}
+	}
+	return err
+}
+
 func handlePipelineBatch(rv *httpRequest, httpArgs httpRequestArgs, parm string, val interface{}) errors.Error {
 	param, err := httpArgs.getStringVal(parm, val)
 	if err == nil && param != "" {
@@ -341,8 +402,8 @@ func handleFormat(rv *httpRequest, httpArgs httpRequestArgs, parm string, val in
 		format := newFormat(format_field)
 		if format == UNDEFINED_FORMAT {
 			err = errors.NewServiceErrorUnrecognizedValue(FORMAT, format_field)
-		} else if format != JSON {
-			err = errors.NewServiceErrorNotImplemented(FORMAT, format_field)
+		} else {
+			rv.setFormat(format)
 		}
 	}
 	return err
@@ -360,9 +421,22 @@ func handleCompression(rv *httpRequest, httpArgs httpRequestArgs, parm string, v
 	compression_field, err := httpArgs.getStringVal(parm, val)
 	if err == nil && compression_field != "" {
 		compression := newCompression(compression_field)
-		if compression == UNDEFINED_COMPRESSION {
+		switch compression {
+		case UNDEFINED_COMPRESSION:
 			err = errors.NewServiceErrorUnrecognizedValue(COMPRESSION, compression_field)
-		} else if compression != NONE {
+		case NONE:
+			rv.encoding = ""
+		case ZIP:
+			// explicitly requested: use gzip unless the client prefers deflate,
+			// and don't compress if the client refuses both
+			// no Accept-Encoding header means any coding is acceptable
+			acceptEncoding := rv.req.Header["Accept-Encoding"]
+			if encoding, ok := negotiateEncoding(acceptEncoding); ok || len(acceptEncoding) == 0 {
+				rv.encoding = encoding
+			} else {
+				rv.encoding = ""
+			}
+		default:
 			err = errors.NewServiceErrorNotImplemented(COMPRESSION, compression_field)
 		}
 	}
@@ -414,6 +488,14 @@ func handleAutoExecute(rv *httpRequest, httpArgs httpRequestArgs, parm string, v
 	return err
 }
 
+func handleResultCache(rv *httpRequest, httpArgs httpRequestArgs, parm string, val interface{}) errors.Error {
+	resultCache, err := httpArgs.getTristateVal(parm, val)
+	if err == nil {
+		rv.SetResultCache(resultCache)
+	}
+	return err
+}
+
 func handleConsistency(rv *httpRequest, httpArgs httpRequestArgs, parm string, val interface{}) errors.Error {
 	rv.consCnt++
 	return nil
@@ -532,6 +614,8 @@ const ( // Request argument names
 	SCAN_CAP          = "scan_cap"
 	PIPELINE_CAP      = "pipeline_cap"
 	PIPELINE_BATCH    = "pipeline_batch"
+	MEMORY_QUOTA      = "memory_quota"
+	RECURSION_DEPTH   = "max_recursion_depth"
 	READONLY          = "readonly"
 	METRICS           = "metrics"
 	NAMESPACE         = "namespace"
@@ -557,6 +641,9 @@ const ( // Request argument names
 	MAX_INDEX_API     = "max_index_api"
 	AUTO_PREPARE      = "auto_prepare"
 	AUTO_EXECUTE      = "auto_execute"
+	TXID              = "txid"
+	TXTIMEOUT         = "txtimeout"
+	RESULT_CACHE      = "result_cache"
 )
 
 var _PARAMETERS = map[string]func(rv *httpRequest, httpArgs httpRequestArgs, parm string, val interface{}) errors.Error{
@@ -574,6 +661,8 @@ var _PARAMETERS = map[string]func(rv *httpRequest, httpArgs httpRequestArgs, par
 	SCAN_CAP:          handleScanCap,
 	PIPELINE_CAP:      handlePipelineCap,
 	PIPELINE_BATCH:    handlePipelineBatch,
+	MEMORY_QUOTA:      handleMemoryQuota,
+	RECURSION_DEPTH:   handleMaxRecursionDepth,
 	READONLY:          handleReadonly,
 	METRICS:           handleMetrics,
 	NAMESPACE:         handleNamespace,
@@ -589,6 +678,9 @@ var _PARAMETERS = map[string]func(rv *httpRequest, httpArgs httpRequestArgs, par
 	MAX_INDEX_API:     handleMaxIndexAPI,
 	AUTO_PREPARE:      handleAutoPrepare,
 	AUTO_EXECUTE:      handleAutoExecute,
+	TXID:              handleTxId,
+	TXTIMEOUT:         handleTxTimeout,
+	RESULT_CACHE:      handleResultCache,
 }
 
 func isValidParameter(a string) bool {
@@ -771,7 +863,7 @@ const acceptType = "application/json"
 const versionTag = "version="
 const version = acceptType + "; " + versionTag + util.VERSION
 
-func contentNegotiation(resp http.ResponseWriter, req *http.Request) errors.Error {
+func contentNegotiation(rv *httpRequest, resp http.ResponseWriter, req *http.Request) errors.Error {
 	// set content type to current version
 	resp.Header().Set("Content-Type", version)
 	accept := req.Header["Accept"]
@@ -780,6 +872,14 @@ func contentNegotiation(resp http.ResponseWriter, req *http.Request) errors.Erro
 		return nil
 	}
 	desiredContent := accept[0]
+	// other formats can be requested via the media type as well as the format parameter
+	for format, content := range _FORMAT_CONTENT {
+		if strings.HasPrefix(desiredContent, content) {
+			rv.format = format
+			resp.Header().Set("Content-Type", content+"; charset=utf-8")
+			return nil
+		}
+	}
 	// media type must be application/json at least
 	if !strings.HasPrefix(desiredContent, acceptType) {
 		return errors.NewServiceErrorMediaType(desiredContent)
@@ -1382,6 +1482,23 @@ func newFormat(s string) Format {
 	}
 }
 
+// media types of the non JSON formats
+var _FORMAT_CONTENT = map[Format]string{
+	XML: "application/xml",
+	CSV: "text/csv",
+	TSV: "text/tab-separated-values",
+}
+
+func (this *httpRequest) setFormat(format Format) {
+	this.format = format
+	content, ok := _FORMAT_CONTENT[format]
+	if ok {
+		this.resp.Header().Set("Content-Type", content+"; charset=utf-8")
+	} else {
+		this.resp.Header().Set("Content-Type", version)
+	}
+}
+
 func (f Format) String() string {
 	var s string
 	switch f {
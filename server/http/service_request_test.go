@@ -11,7 +11,9 @@ package http
 
 import (
 	"bytes"
+	"compress/gzip"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -28,6 +30,7 @@ import (
 	"github.com/couchbase/query/errors"
 	"github.com/couchbase/query/logging"
 	"github.com/couchbase/query/prepareds"
+	"github.com/couchbase/query/resultcache"
 	"github.com/couchbase/query/timestamp"
 	"github.com/couchbase/query/value"
 
@@ -259,6 +262,132 @@ func TestRequestWithTimeout(t *testing.T) {
 	}
 }
 
+func TestRequestFormats(t *testing.T) {
+	expected := map[string][]string{
+		"CSV": {"a,b,c\r\n", "1,\"x,\"\"y\"\"\",\"{\"\"d\"\":[1,2]}\"\r\n"},
+		"TSV": {"a\tb\tc\r\n", "1\tx,\"y\"\\t\t{\"d\":[1,2]}\r\n"},
+		"XML": {"<response>", "<result type=\"object\"><a type=\"number\">1</a><b>x,&#34;y&#34;</b>" +
+			"<c type=\"object\"><d type=\"array\"><item type=\"number\">1</item><item type=\"number\">2</item></d></c></result>",
+			"<status>success</status>"},
+	}
+	statement := `select 1 as a, 'x,"y"' || (case when $tab then "\t" else "" end) as b, {"d": [1, 2]} as c`
+	for format, parts := range expected {
+		payload := url.Values{}
+		payload.Set("statement", statement)
+		payload.Set("format", format)
+		payload.Set("pretty", "false")
+		payload.Set("$tab", strings.ToLower(fmt.Sprint(format == "TSV")))
+		res, err := doUrlEncodedPost(payload)
+		if err != nil {
+			t.Errorf("Unexpected error in HTTP request: %v", err)
+			continue
+		}
+		body, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		for _, part := range parts {
+			if !strings.Contains(string(body), part) {
+				t.Errorf("Format %v: expected %q in response, actual: %q", format, part, body)
+			}
+		}
+		if format != "XML" && res.Header.Get(HEADER_STATUS) != "success" {
+			t.Errorf("Format %v: expected status header success, actual: %v", format, res.Header)
+		}
+	}
+}
+
+func TestRequestCompression(t *testing.T) {
+	for _, size := range []int{10, 4000} {
+		payload := url.Values{}
+		payload.Set("statement", fmt.Sprintf("select repeat('x', %d) as a", size))
+		payload.Set("compression", "ZIP")
+		req, err := http.NewRequest("POST", test_server.URL()+"/", bytes.NewBufferString(payload.Encode()))
+		if err != nil {
+			t.Fatalf("Unexpected error creating HTTP request: %v", err)
+		}
+		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+
+		// handle the encoding ourselves
+		client := &http.Client{Transport: &http.Transport{DisableCompression: true}}
+		res, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("Unexpected error in HTTP request: %v", err)
+		}
+		var body []byte
+		if size < _MIN_COMPRESS_SIZE {
+			if res.Header.Get("Content-Encoding") != "" {
+				t.Errorf("Expected no content encoding for %v bytes, actual: %v", size, res.Header.Get("Content-Encoding"))
+			}
+			body, err = ioutil.ReadAll(res.Body)
+		} else {
+			if res.Header.Get("Content-Encoding") != GZIP_ENCODING {
+				t.Errorf("Expected content encoding: %v, actual: %v", GZIP_ENCODING, res.Header.Get("Content-Encoding"))
+			}
+			var r *gzip.Reader
+
+			r, err = gzip.NewReader(res.Body)
+			if err == nil {
+				body, err = ioutil.ReadAll(r)
+			}
+		}
+		res.Body.Close()
+		if err != nil {
+			t.Errorf("Unexpected error reading response: %v", err)
+		}
+		if !strings.Contains(string(body), strings.Repeat("x", size)) || !strings.Contains(string(body), "\"status\": \"success\"") {
+			t.Errorf("Unexpected response: %s", body)
+		}
+	}
+}
+
+func TestRequestCompressionRefused(t *testing.T) {
+	for _, accept := range []string{"gzip;q=0, deflate;q=0", "identity"} {
+		payload := url.Values{}
+		payload.Set("statement", fmt.Sprintf("select repeat('x', %d) as a", 4000))
+		payload.Set("compression", "ZIP")
+		req, err := http.NewRequest("POST", test_server.URL()+"/", bytes.NewBufferString(payload.Encode()))
+		if err != nil {
+			t.Fatalf("Unexpected error creating HTTP request: %v", err)
+		}
+		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Add("Accept-Encoding", accept)
+
+		client := &http.Client{Transport: &http.Transport{DisableCompression: true}}
+		res, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("Unexpected error in HTTP request: %v", err)
+		}
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if res.Header.Get("Content-Encoding") != "" {
+			t.Errorf("Expected no content encoding for Accept-Encoding %q, actual: %v", accept, res.Header.Get("Content-Encoding"))
+		}
+		if err != nil || !strings.Contains(string(body), "\"status\": \"success\"") {
+			t.Errorf("Unexpected response: %s %v", body, err)
+		}
+	}
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		accept   string
+		encoding string
+		ok       bool
+	}{
+		{"", GZIP_ENCODING, false},
+		{"gzip", GZIP_ENCODING, true},
+		{"deflate, gzip;q=0.5", DEFLATE_ENCODING, true},
+		{"gzip;q=0, deflate", DEFLATE_ENCODING, true},
+		{"*", GZIP_ENCODING, true},
+		{"br, identity", GZIP_ENCODING, false},
+	}
+	for _, test := range tests {
+		encoding, ok := negotiateEncoding([]string{test.accept})
+		if encoding != test.encoding || ok != test.ok {
+			t.Errorf("Accept-Encoding %q: expected %v %v, actual %v %v", test.accept, test.encoding, test.ok, encoding, ok)
+		}
+	}
+}
+
 func TestPrepareStatements(t *testing.T) {
 	preparedSequence(t, "doSelect", "SELECT b FROM p0:b0 LIMIT 5")
 	preparedSequence(t, "doInsert", "INSERT INTO p0:b0 VALUES ($1, $2)")
@@ -483,3 +612,328 @@ func doJsonEncodedPost(payload map[string]interface{}) (*http.Response, error) {
 
 	return res, nil
 }
+
+func TestMetricsLabelLimit(t *testing.T) {
+	latencies := []*latency{
+		&latency{name: "p1", uses: 1, serviceTime: 1000000000, minServiceTime: 1000000000, maxServiceTime: 1000000000},
+		&latency{name: "p2", uses: 5, serviceTime: 2000000000, minServiceTime: 100000000, maxServiceTime: 1000000000},
+		&latency{name: "p\"3", uses: 3, serviceTime: 3000000000, minServiceTime: 500000000, maxServiceTime: 1500000000},
+		&latency{name: "p4", uses: 2, serviceTime: 500000000, minServiceTime: 200000000, maxServiceTime: 300000000},
+	}
+	buf := &bytes.Buffer{}
+	writeLatencyMetrics(buf, "prepared", latencies, 2)
+	out := buf.String()
+
+	expected := []string{
+		"# TYPE n1ql_prepared_service_time_seconds summary\n",
+		"n1ql_prepared_service_time_seconds_count{name=\"p2\"} 5\n",
+		"n1ql_prepared_service_time_seconds_count{name=\"p\\\"3\"} 3\n",
+		"n1ql_prepared_service_time_seconds_count{name=\"_other\"} 3\n",
+		"n1ql_prepared_service_time_seconds_sum{name=\"_other\"} 1.5\n",
+		"n1ql_prepared_min_service_time_seconds{name=\"_other\"} 0.2\n",
+		"n1ql_prepared_max_service_time_seconds{name=\"_other\"} 1\n",
+	}
+	for _, e := range expected {
+		if !strings.Contains(out, e) {
+			t.Errorf("Expected %q in metrics, got:\n%v", e, out)
+		}
+	}
+	if strings.Contains(out, "\"p1\"") || strings.Contains(out, "\"p4\"") {
+		t.Errorf("Expected least used entries to be folded, got:\n%v", out)
+	}
+
+	if name := metricsName("request.time-ms"); name != "n1ql_request_time_ms" {
+		t.Errorf("Expected n1ql_request_time_ms, got %v", name)
+	}
+}
+
+func TestWorkloadGroups(t *testing.T) {
+	groups := []interface{}{
+		map[string]interface{}{"name": "paused", "context-prefixes": []interface{}{"paused-"},
+			"servicers": int64(0), "queue-size": int64(0)},
+		map[string]interface{}{"name": "held", "context-prefixes": []interface{}{"held-"},
+			"servicers": int64(0), "queue-size": int64(1)},
+		map[string]interface{}{"name": "api", "context-prefixes": []interface{}{"api-"},
+			"servicers": int64(2), "queue-size": int64(4), "timeout": int64(time.Minute), "priority": int64(10)},
+	}
+	err := server.ProcessSettings(map[string]interface{}{server.WORKLOADGROUPS: groups}, test_server.query_server)
+	if err != nil {
+		t.Fatalf("Unexpected error setting workload groups: %v", err)
+	}
+	defer server.ProcessSettings(map[string]interface{}{server.WORKLOADGROUPS: []interface{}{}}, test_server.query_server)
+
+	settings := test_server.query_server.WorkloadGroups()
+	if len(settings) != 3 || settings[0].(map[string]interface{})["name"] != "api" {
+		t.Errorf("Expected groups in priority order, got %v", settings)
+	}
+
+	// requests in a group get the group timeout cap
+	doUrlRequest(t, map[string]string{"statement": "select 1", "client_context_id": "api-1"})
+	if test_server.request().WorkloadGroup() != "api" || test_server.request().Timeout() != time.Minute {
+		t.Errorf("Expected request in group api with timeout 1m, got %v %v",
+			test_server.request().WorkloadGroup(), test_server.request().Timeout())
+	}
+	doUrlRequest(t, map[string]string{"statement": "select 1", "client_context_id": "other-1"})
+	if test_server.request().WorkloadGroup() != "" {
+		t.Errorf("Expected request in no group, got %v", test_server.request().WorkloadGroup())
+	}
+
+	// full queues turn requests away
+	payload := url.Values{}
+	payload.Set("statement", "select 1")
+	payload.Set("client_context_id", "paused-1")
+	res, e := doUrlEncodedPost(payload)
+	if e != nil {
+		t.Fatalf("Unexpected error in HTTP request: %v", e)
+	}
+	res.Body.Close()
+	errs := test_server.request().Errors()
+	if res.StatusCode != http.StatusServiceUnavailable || len(errs) != 1 || errs[0].Code() != errors.WORKLOAD_QUEUE_FULL {
+		t.Errorf("Expected queue full rejection, got %v %v", res.StatusCode, errs)
+	}
+
+	// queued requests give up when they time out
+	payload.Set("client_context_id", "held-1")
+	payload.Set("timeout", "50ms")
+	res, e = doUrlEncodedPost(payload)
+	if e != nil {
+		t.Fatalf("Unexpected error in HTTP request: %v", e)
+	}
+	res.Body.Close()
+	errs = test_server.request().Errors()
+	if res.StatusCode != http.StatusServiceUnavailable || len(errs) != 1 || errs[0].Code() != errors.WORKLOAD_QUEUE_TIMEOUT {
+		t.Errorf("Expected queue timeout rejection, got %v %v", res.StatusCode, errs)
+	}
+	if test_server.request().QueueWaitTime() < 50*time.Millisecond {
+		t.Errorf("Expected queue wait of at least 50ms, got %v", test_server.request().QueueWaitTime())
+	}
+
+	for _, stat := range test_server.query_server.WorkloadStats() {
+		if (stat.Name == "api" && stat.Rejected != 0) || (stat.Name != "api" && stat.Rejected != 1) {
+			t.Errorf("Unexpected rejections for group %v: %v", stat.Name, stat.Rejected)
+		}
+	}
+
+	duplicate := append(groups, map[string]interface{}{"name": "api"})
+	if server.ProcessSettings(map[string]interface{}{server.WORKLOADGROUPS: duplicate}, test_server.query_server) == nil {
+		t.Errorf("Expected duplicate group names to be rejected")
+	}
+}
+
+func TestPersistedPrepareds(t *testing.T) {
+	dir, err := ioutil.TempDir("", "prepareds")
+	if err != nil {
+		t.Fatalf("Unable to create directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	defer prepareds.PreparedsPersistInit("")
+
+	if err := prepareds.PreparedsPersistInit(dir); err != nil {
+		t.Fatalf("Unexpected error enabling the prepared store: %v", err)
+	}
+	doPrepare(t, "doPersisted", "SELECT b FROM p0:b0 LIMIT 5")
+	doPrepare(t, "doDropped", "SELECT b FROM p0:b0 LIMIT 1")
+	prepareds.DeletePrepared("doDropped")
+	if !prepareds.PreparedPersisted("doPersisted") || prepareds.PreparedPersisted("doDropped") {
+		t.Fatalf("Expected only doPersisted to be persisted")
+	}
+
+	// restart with an empty cache
+	prepareds.PreparedsInit(1024)
+	if err := prepareds.PreparedsPersistInit(dir); err != nil {
+		t.Fatalf("Unexpected error loading the prepared store: %v", err)
+	}
+	if prepareds.CountPrepareds() != 1 {
+		t.Fatalf("Expected 1 restored prepared statement, got %v", prepareds.NamePrepareds())
+	}
+	restored := false
+	prepareds.PreparedDo("doPersisted", func(entry *prepareds.CacheEntry) {
+		restored = entry.Restored() && entry.Prepared.Operator == nil
+	})
+	if !restored {
+		t.Errorf("Expected doPersisted to be restored without a plan")
+	}
+
+	// first use prepares the statement again
+	doPreparedNameOnly(t, "doPersisted")
+	if errs := test_server.request().Errors(); len(errs) > 0 {
+		t.Errorf("Unexpected errors executing restored statement: %v", errs)
+	}
+	prepareds.PreparedDo("doPersisted", func(entry *prepareds.CacheEntry) {
+		if entry.Restored() || entry.Prepared.Operator == nil || entry.Prepared.Type() != "SELECT" {
+			t.Errorf("Expected doPersisted to be prepared again")
+		}
+	})
+
+	// statements ejected from the cache leave the store
+	doPrepare(t, "doEjected", "SELECT b FROM p0:b0 LIMIT 2")
+	limit := prepareds.PreparedsLimit()
+	prepareds.PreparedsSetLimit(1)
+	prepareds.PreparedsSetLimit(limit)
+	for _, name := range []string{"doPersisted", "doEjected"} {
+		cached := false
+		prepareds.PreparedDo(name, func(entry *prepareds.CacheEntry) {
+			cached = true
+		})
+		if prepareds.PreparedPersisted(name) != cached {
+			t.Errorf("Expected %v to be persisted only if cached, cached: %v", name, cached)
+		}
+	}
+	if prepareds.CountPrepareds() != 1 {
+		t.Errorf("Expected 1 prepared statement, got %v", prepareds.NamePrepareds())
+	}
+
+	prepareds.DeletePrepared("doPersisted")
+	prepareds.DeletePrepared("doEjected")
+	files, _ := ioutil.ReadDir(dir)
+	if len(files) != 0 {
+		t.Errorf("Expected the prepared store to be empty, found %v files", len(files))
+	}
+}
+
+func TestResultCache(t *testing.T) {
+	statement := "SELECT b FROM p0:b0 LIMIT 5"
+	hits := func() int32 {
+		var rv int32
+		resultcache.ResultCacheForeach(func(key string, entry *resultcache.Entry) bool {
+			if entry.Statement == statement {
+				rv = entry.Hits
+			}
+			return true
+		}, nil)
+		return rv
+	}
+	body := func(params map[string]string) string {
+		payload := url.Values{}
+		for param, value := range params {
+			payload.Set(param, value)
+		}
+		res, err := doUrlEncodedPost(payload)
+		if err != nil {
+			t.Fatalf("Unexpected error in HTTP request: %v", err)
+		}
+		defer res.Body.Close()
+		bytes, _ := ioutil.ReadAll(res.Body)
+		start := strings.Index(string(bytes), "\"results\"")
+		end := strings.Index(string(bytes), "\"status\"")
+		if start < 0 || end < start {
+			t.Fatalf("Unexpected response: %s", bytes)
+		}
+		return string(bytes[start:end])
+	}
+
+	// requests not opting in are not cached
+	doUrlRequest(t, map[string]string{"statement": statement})
+	if resultcache.Count() != 0 {
+		t.Fatalf("Expected an empty result cache, found %v entries", resultcache.Count())
+	}
+
+	first := body(map[string]string{"statement": statement, "result_cache": "true"})
+	second := body(map[string]string{"statement": statement, "result_cache": "true"})
+	if resultcache.Count() != 1 || hits() != 1 {
+		t.Errorf("Expected 1 entry with 1 hit, got %v entries, %v hits", resultcache.Count(), hits())
+	}
+	if first != second {
+		t.Errorf("Expected cached results to match, got %v and %v", first, second)
+	}
+	listed := body(map[string]string{"statement": "SELECT RAW statement FROM system:result_cache"})
+	if !strings.Contains(listed, statement) {
+		t.Errorf("Expected system:result_cache to list the entry, got %v", listed)
+	}
+
+	// mutations invalidate the entries reading from the keyspace
+	namespace, _ := test_server.query_server.Datastore().NamespaceByName("p0")
+	keyspace, _ := namespace.KeyspaceByName("b0")
+	resultcache.Invalidate(keyspace)
+	third := body(map[string]string{"statement": statement, "result_cache": "true"})
+	if hits() != 0 || third != first {
+		t.Errorf("Expected invalidated entry to be replaced, got %v hits", hits())
+	}
+
+	// the hint opts in statements, the parameter opts them out
+	doUrlRequest(t, map[string]string{"statement": "SELECT b FROM p0:b0 USE CACHE LIMIT 3"})
+	if resultcache.Count() != 2 {
+		t.Errorf("Expected the hinted statement to be cached, found %v entries", resultcache.Count())
+	}
+	doUrlRequest(t, map[string]string{"statement": "SELECT b FROM p0:b0 USE CACHE LIMIT 2", "result_cache": "false"})
+	if resultcache.Count() != 2 {
+		t.Errorf("Expected the statement not to be cached, found %v entries", resultcache.Count())
+	}
+
+	// system keyspaces can't be invalidated
+	doUrlRequest(t, map[string]string{"statement": "SELECT * FROM system:keyspaces", "result_cache": "true"})
+	doUrlRequest(t, map[string]string{"statement": "SELECT b, (SELECT RAW name FROM system:indexes) AS i " +
+		"FROM p0:b0 LIMIT 1", "result_cache": "true"})
+	if resultcache.Count() != 2 {
+		t.Errorf("Expected system keyspaces not to be cached, found %v entries", resultcache.Count())
+	}
+
+	// nor can statements not reading keyspaces, or giving different results on each run
+	doUrlRequest(t, map[string]string{"statement": "SELECT 1 AS one", "result_cache": "true"})
+	doUrlRequest(t, map[string]string{"statement": "SELECT b, NOW_STR() AS n FROM p0:b0 LIMIT 1", "result_cache": "true"})
+	doUrlRequest(t, map[string]string{"statement": "SELECT b FROM p0:b0 WHERE RANDOM() < 2 LIMIT 1", "result_cache": "true"})
+	doUrlRequest(t, map[string]string{"statement": "SELECT b, (SELECT RAW UUID()) AS u FROM p0:b0 LIMIT 1", "result_cache": "true"})
+	if resultcache.Count() != 2 {
+		t.Errorf("Expected non deterministic statements not to be cached, found %v entries", resultcache.Count())
+	}
+
+	// only hints in the outermost query block opt in
+	doUrlRequest(t, map[string]string{"statement": "SELECT s.b FROM (SELECT b FROM p0:b0 USE CACHE LIMIT 1) AS s"})
+	if resultcache.Count() != 2 {
+		t.Errorf("Expected the subquery hint to be ignored, found %v entries", resultcache.Count())
+	}
+	doUrlRequest(t, map[string]string{"statement": "SELECT b, (SELECT RAW 1) AS s FROM p0:b0 USE CACHE LIMIT 1"})
+	if resultcache.Count() != 3 {
+		t.Errorf("Expected the hinted statement to be cached, found %v entries", resultcache.Count())
+	}
+
+	// mutations of a collection invalidate the entries reading from its bucket
+	body(map[string]string{"statement": statement, "result_cache": "true"})
+	if hits() != 1 {
+		t.Errorf("Expected 1 hit, got %v hits", hits())
+	}
+	bucket := &testBucket{namespace: "p0", name: "b0"}
+	resultcache.Invalidate(&testCollection{Keyspace: keyspace, scope: &testScope{bucket: bucket}})
+	body(map[string]string{"statement": statement, "result_cache": "true"})
+	if hits() != 0 {
+		t.Errorf("Expected the collection mutation to invalidate the entry, got %v hits", hits())
+	}
+}
+
+// a collection of bucket p0:b0, with a namespace of its own as in the couchbase
+// and file datastores
+type testCollection struct {
+	datastore.Keyspace
+	scope datastore.Scope
+}
+
+func (this *testCollection) Name() string {
+	return "c0"
+}
+
+func (this *testCollection) Scope() datastore.Scope {
+	return this.scope
+}
+
+type testScope struct {
+	datastore.Scope
+	bucket datastore.Bucket
+}
+
+func (this *testScope) Bucket() datastore.Bucket {
+	return this.bucket
+}
+
+type testBucket struct {
+	datastore.Bucket
+	namespace string
+	name      string
+}
+
+func (this *testBucket) NamespaceId() string {
+	return this.namespace
+}
+
+func (this *testBucket) Name() string {
+	return this.name
+}
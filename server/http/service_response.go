@@ -71,6 +71,8 @@ func mapErrorToHttpResponse(err errors.Error, def int) int {
 		return http.StatusBadRequest
 	case 1120:
 		return http.StatusNotAcceptable
+	case errors.WORKLOAD_QUEUE_FULL, errors.WORKLOAD_QUEUE_TIMEOUT:
+		return http.StatusServiceUnavailable
 	case 13014:
 		return http.StatusUnauthorized
 	case 3000: // parse error range
@@ -104,6 +106,15 @@ func (this *httpRequest) setHttpCode(httpRespCode int) {
 }
 
 func (this *httpRequest) Failed(srvr *server.Server) {
+	switch this.format {
+	case CSV, TSV:
+		this.delimitedFailed(srvr)
+		return
+	case XML:
+		this.xmlFailed(srvr)
+		return
+	}
+
 	prefix, indent := this.prettyStrings(srvr.Pretty(), false)
 	this.writeString("{\n")
 	this.writeRequestID(prefix)
@@ -173,6 +184,12 @@ func (this *httpRequest) stopAndAlert(state server.State) {
 }
 
 func (this *httpRequest) writePrefix(srvr *server.Server, signature value.Value, prefix, indent string) bool {
+	switch this.format {
+	case CSV, TSV:
+		return this.writeDelimitedPrefix(srvr, signature)
+	case XML:
+		return this.writeXMLPrefix(srvr, signature)
+	}
 	return this.writeString("{\n") &&
 		this.writeRequestID(prefix) &&
 		this.writeClientContextID(prefix) &&
@@ -233,6 +250,13 @@ func (this *httpRequest) SetUp() {
 func (this *httpRequest) Result(item value.AnnotatedValue) bool {
 	var success bool
 
+	switch this.format {
+	case CSV, TSV:
+		return this.delimitedResult(item)
+	case XML:
+		return this.xmlResult(item)
+	}
+
 	if this.Halted() {
 		return false
 	}
@@ -286,6 +310,12 @@ func (this *httpRequest) writeValue(item value.Value, prefix, indent string) boo
 }
 
 func (this *httpRequest) writeSuffix(srvr *server.Server, state server.State, prefix, indent string) bool {
+	switch this.format {
+	case CSV, TSV:
+		return this.writeDelimitedSuffix(srvr, state)
+	case XML:
+		return this.writeXMLSuffix(srvr, state)
+	}
 	return this.writeString("\n") && this.writeString(prefix) && this.writeString("]") &&
 		this.writeErrors(prefix, indent) &&
 		this.writeWarnings(prefix, indent) &&
@@ -462,6 +492,10 @@ func (this *httpRequest) writeMetrics(metrics bool, prefix, indent string) bool
 		fmt.Fprintf(buf, ",%s\"sortCount\": %d", newPrefix, this.SortCount())
 	}
 
+	if this.UsedMemory() > 0 {
+		fmt.Fprintf(buf, ",%s\"usedMemory\": %d", newPrefix, this.UsedMemory())
+	}
+
 	if this.errorCount > 0 {
 		fmt.Fprintf(buf, ",%s\"errorCount\": %d", newPrefix, this.errorCount)
 	}
@@ -625,6 +659,7 @@ type bufferedWriter struct {
 	closed      bool
 	header      bool // headers required
 	lastFlush   util.Time
+	compressor  compressor // compresses the response, if required
 }
 
 const _PRINTF_THRESHOLD = 128
@@ -636,6 +671,15 @@ func NewBufferedWriter(w *bufferedWriter, r *httpRequest, bp BufferPool) {
 	w.closed = false
 	w.header = true
 	w.lastFlush = util.Now()
+	w.compressor = nil
+	if r.encoding != "" {
+		w.compressor = getCompressor(r.encoding, r.resp)
+		if w.compressor != nil {
+			header := r.resp.Header()
+			header.Set("Content-Encoding", r.encoding)
+			header.Add("Vary", "Accept-Encoding")
+		}
+	}
 }
 
 func (this *bufferedWriter) writeBytes(s []byte) bool {
@@ -648,21 +692,7 @@ func (this *bufferedWriter) writeBytes(s []byte) bool {
 
 	// threshold exceeded
 	if len(s)+this.buffer.Len() > this.buffer_pool.BufferCapacity() {
-		w := this.req.resp // our request's response writer
-
-		// write response header and data buffered so far using request's response writer:
-		if this.header {
-			w.WriteHeader(this.req.httpCode())
-			this.header = false
-		}
-
-		// write out and empty the buffer
-		io.Copy(w, this.buffer)
-		this.buffer.Reset()
-
-		// do the flushing
-		this.lastFlush = util.Now()
-		w.(http.Flusher).Flush()
+		this.flush()
 	}
 
 	// under threshold - write the string to our buffer
@@ -677,21 +707,7 @@ func (this *bufferedWriter) printf(f string, args ...interface{}) bool {
 
 	// threshold exceeded
 	if _PRINTF_THRESHOLD+this.buffer.Len() > this.buffer_pool.BufferCapacity() {
-		w := this.req.resp // our request's response writer
-
-		// write response header and data buffered so far using request's response writer:
-		if this.header {
-			w.WriteHeader(this.req.httpCode())
-			this.header = false
-		}
-
-		// write out and empty the buffer
-		io.Copy(w, this.buffer)
-		this.buffer.Reset()
-
-		// do the flushing
-		this.lastFlush = util.Now()
-		w.(http.Flusher).Flush()
+		this.flush()
 	}
 
 	// under threshold - write the string to our buffer
@@ -716,21 +732,7 @@ func (this *bufferedWriter) timeFlush() {
 
 	// flush only if time has exceeded
 	if util.Since(this.lastFlush) > 100*time.Millisecond {
-		w := this.req.resp // our request's response writer
-
-		// write response header and data buffered so far using request's response writer:
-		if this.header {
-			w.WriteHeader(this.req.httpCode())
-			this.header = false
-		}
-
-		// write out and empty the buffer
-		io.Copy(w, this.buffer)
-		this.buffer.Reset()
-
-		// do the flushing
-		this.lastFlush = util.Now()
-		w.(http.Flusher).Flush()
+		this.flush()
 	}
 }
 
@@ -742,22 +744,35 @@ func (this *bufferedWriter) sizeFlush() {
 
 	// beyond capacity
 	if this.buffer.Len() > this.buffer_pool.BufferCapacity() {
-		w := this.req.resp // our request's response writer
+		this.flush()
+	}
+}
 
-		// write response header and data buffered so far using request's response writer:
-		if this.header {
-			w.WriteHeader(this.req.httpCode())
-			this.header = false
-		}
+// send the response headers, if needed, and write out and empty the buffer
+func (this *bufferedWriter) flush() {
+	w := this.req.resp // our request's response writer
 
-		// write out and empty the buffer
-		io.Copy(w, this.buffer)
-		this.buffer.Reset()
+	// write response header and data buffered so far using request's response writer:
+	if this.header {
+		w.WriteHeader(this.req.httpCode())
+		this.header = false
+	}
 
-		// do the flushing
-		this.lastFlush = util.Now()
-		w.(http.Flusher).Flush()
+	// write out and empty the buffer
+	if this.compressor != nil {
+		io.Copy(this.compressor, this.buffer)
+
+		// emit what has been compressed so far, so that streaming
+		// clients can decode rows as they arrive
+		this.compressor.Flush()
+	} else {
+		io.Copy(w, this.buffer)
 	}
+	this.buffer.Reset()
+
+	// do the flushing
+	this.lastFlush = util.Now()
+	w.(http.Flusher).Flush()
 }
 
 // mark the current write position
@@ -773,6 +788,19 @@ func (this bufferedWriter) buf() io.Writer {
 	return this.buffer
 }
 
+// set a response field which is only known once the request has completed:
+// a header if the response has not started yet, or a trailer otherwise
+func (this *bufferedWriter) trailer(name, value string) {
+	if this.closed {
+		return
+	}
+	if this.header {
+		this.req.resp.Header().Set(name, value)
+	} else {
+		this.req.resp.Header().Set(http.TrailerPrefix+name, value)
+	}
+}
+
 // empty and dispose of writer
 func (this *bufferedWriter) noMoreData() {
 	if this.closed {
@@ -783,6 +811,27 @@ func (this *bufferedWriter) noMoreData() {
 	r := this.req.req  // our request's http request
 
 	if this.header {
+		if this.compressor != nil {
+
+			// not worth compressing
+			if this.buffer.Len() < _MIN_COMPRESS_SIZE {
+				w.Header().Del("Content-Encoding")
+				putCompressor(this.req.encoding, this.compressor)
+				this.compressor = nil
+			} else {
+
+				// compress the whole response, so that the Content-Length can be set
+				out := this.buffer_pool.GetBuffer()
+				this.compressor.Reset(out)
+				io.Copy(this.compressor, this.buffer)
+				this.compressor.Close()
+				putCompressor(this.req.encoding, this.compressor)
+				this.compressor = nil
+				this.buffer_pool.PutBuffer(this.buffer)
+				this.buffer = out
+			}
+		}
+
 		// calculate and set the Content-Length header:
 		content_len := strconv.Itoa(len(this.buffer.Bytes()))
 		w.Header().Set("Content-Length", content_len)
@@ -791,7 +840,14 @@ func (this *bufferedWriter) noMoreData() {
 		this.header = false
 	}
 
-	io.Copy(w, this.buffer)
+	if this.compressor != nil {
+		io.Copy(this.compressor, this.buffer)
+		this.compressor.Close()
+		putCompressor(this.req.encoding, this.compressor)
+		this.compressor = nil
+	} else {
+		io.Copy(w, this.buffer)
+	}
 	// no more data in the response => return buffer to pool:
 	this.buffer_pool.PutBuffer(this.buffer)
 	r.Body.Close()
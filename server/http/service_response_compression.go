@@ -0,0 +1,118 @@
+//  Copyright (c) 2019 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package http
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/couchbase/query/util"
+)
+
+// HTTP content codings used for ZIP compression
+const (
+	GZIP_ENCODING    = "gzip"
+	DEFLATE_ENCODING = "deflate"
+)
+
+// responses smaller than this are sent uncompressed
+const _MIN_COMPRESS_SIZE = 1024
+
+// both gzip and zlib writers satisfy this
+type compressor interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+var gzipPool util.FastPool
+var deflatePool util.FastPool
+
+func init() {
+	util.NewFastPool(&gzipPool, func() interface{} {
+		return gzip.NewWriter(nil)
+	})
+	util.NewFastPool(&deflatePool, func() interface{} {
+		return zlib.NewWriter(nil)
+	})
+}
+
+func getCompressor(encoding string, w io.Writer) compressor {
+	var rv compressor
+
+	switch encoding {
+	case GZIP_ENCODING:
+		rv = gzipPool.Get().(*gzip.Writer)
+	case DEFLATE_ENCODING:
+		rv = deflatePool.Get().(*zlib.Writer)
+	default:
+		return nil
+	}
+	rv.Reset(w)
+	return rv
+}
+
+func putCompressor(encoding string, c compressor) {
+	switch encoding {
+	case GZIP_ENCODING:
+		gzipPool.Put(c)
+	case DEFLATE_ENCODING:
+		deflatePool.Put(c)
+	}
+}
+
+// negotiateEncoding picks the content coding for ZIP compression
+// from an Accept-Encoding header, preferring gzip over deflate.
+// The second return value is false if the client accepts neither.
+func negotiateEncoding(acceptEncoding []string) (string, bool) {
+	gzipQ, deflateQ, anyQ := -1.0, -1.0, -1.0
+
+	for _, header := range acceptEncoding {
+		for _, coding := range strings.Split(header, ",") {
+			q := 1.0
+			parts := strings.Split(coding, ";")
+			for _, param := range parts[1:] {
+				param = strings.TrimSpace(param)
+				if strings.HasPrefix(param, "q=") {
+					f, err := strconv.ParseFloat(param[2:], 64)
+					if err == nil {
+						q = f
+					}
+				}
+			}
+			switch strings.ToLower(strings.TrimSpace(parts[0])) {
+			case GZIP_ENCODING, "x-gzip":
+				gzipQ = q
+			case DEFLATE_ENCODING:
+				deflateQ = q
+			case "*":
+				anyQ = q
+			}
+		}
+	}
+
+	if gzipQ < 0 {
+		gzipQ = anyQ
+	}
+	if deflateQ < 0 {
+		deflateQ = anyQ
+	}
+	switch {
+	case gzipQ > 0 && gzipQ >= deflateQ:
+		return GZIP_ENCODING, true
+	case deflateQ > 0:
+		return DEFLATE_ENCODING, true
+	default:
+		return GZIP_ENCODING, false
+	}
+}
@@ -0,0 +1,342 @@
+//  Copyright (c) 2019 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/server"
+	"github.com/couchbase/query/value"
+)
+
+// CSV and TSV responses only carry the result rows in the body.
+// Everything else travels as response headers, or as trailers
+// if the body has already started streaming by the time the
+// request completes.
+const (
+	HEADER_REQUEST_ID        = "CB-Request-ID"
+	HEADER_CLIENT_CONTEXT_ID = "CB-Client-Context-ID"
+	HEADER_STATUS            = "CB-Status"
+	HEADER_ERRORS            = "CB-Errors"
+	HEADER_WARNINGS          = "CB-Warnings"
+	HEADER_METRICS           = "CB-Metrics"
+)
+
+// name of the single column used for non object results, eg SELECT RAW
+const _RAW_COLUMN = "$1"
+
+// the column layout of a delimited response
+type delimitedColumns struct {
+	names    []string
+	infer    bool // complete the layout from the first result
+	resolved bool // the header row has been written
+}
+
+// Columns are determined from the signature where possible.
+// A star projection, or a missing signature, means that the
+// columns will be completed with the fields of the first result.
+// Columns are always sorted by name, so that the layout does not
+// depend on the order in which fields happened to be produced.
+func (this *delimitedColumns) fromSignature(signature value.Value) {
+	this.names = nil
+	this.infer = false
+	this.resolved = false
+
+	if signature == nil {
+		this.infer = true
+		return
+	}
+	if signature.Type() != value.OBJECT {
+		this.names = []string{_RAW_COLUMN}
+		return
+	}
+	for _, name := range signature.FieldNames(nil) {
+		if name == "*" {
+			this.infer = true
+		} else {
+			this.names = append(this.names, name)
+		}
+	}
+}
+
+func (this *delimitedColumns) fromResult(item value.Value) {
+	if !this.infer {
+		return
+	}
+	this.infer = false
+	if item.Type() != value.OBJECT {
+		if len(this.names) == 0 {
+			this.names = []string{_RAW_COLUMN}
+		}
+		return
+	}
+
+	seen := make(map[string]bool, len(this.names))
+	for _, name := range this.names {
+		seen[name] = true
+	}
+	for _, name := range item.FieldNames(nil) {
+		if !seen[name] {
+			this.names = append(this.names, name)
+		}
+	}
+	sort.Strings(this.names)
+}
+
+func (this *httpRequest) delimiter() byte {
+	if this.format == TSV {
+		return '\t'
+	}
+	return ','
+}
+
+func (this *httpRequest) writeDelimitedPrefix(srvr *server.Server, signature value.Value) bool {
+	header := this.resp.Header()
+	header.Set(HEADER_REQUEST_ID, this.Id().String())
+	if this.ClientID().IsValid() {
+		header.Set(HEADER_CLIENT_CONTEXT_ID, this.ClientID().String())
+	}
+	this.columns.fromSignature(signature)
+	return true
+}
+
+// the header row is written lazily, so that star projections
+// can be resolved against the first result
+func (this *httpRequest) writeDelimitedHeader() bool {
+	if this.columns.resolved {
+		return true
+	}
+	this.columns.resolved = true
+	if len(this.columns.names) == 0 {
+		return true
+	}
+
+	sep := this.delimiter()
+	buf := make([]byte, 0, 128)
+	for i, name := range this.columns.names {
+		if i > 0 {
+			buf = append(buf, sep)
+		}
+		buf = this.appendDelimitedField(buf, name)
+	}
+	buf = append(buf, '\r', '\n')
+	return this.writer.write(string(buf))
+}
+
+func (this *httpRequest) delimitedResult(item value.AnnotatedValue) bool {
+	if this.Halted() {
+		return false
+	}
+
+	this.writer.timeFlush()
+	beforeWrites := this.writer.mark()
+
+	this.columns.fromResult(item)
+	success := this.writeDelimitedHeader()
+	beforeResult := this.writer.mark()
+
+	if success {
+		sep := this.delimiter()
+		buf := make([]byte, 0, 256)
+		if item.Type() != value.OBJECT {
+			buf = this.appendDelimitedValue(buf, item)
+		} else {
+			for i, name := range this.columns.names {
+				if i > 0 {
+					buf = append(buf, sep)
+				}
+				field, ok := item.Field(name)
+				if ok {
+					buf = this.appendDelimitedValue(buf, field)
+				}
+			}
+		}
+		buf = append(buf, '\r', '\n')
+		success = this.writer.write(string(buf))
+	}
+
+	if success {
+		this.resultSize += (this.writer.mark() - beforeResult)
+		this.resultCount++
+		this.writer.sizeFlush()
+	} else {
+		this.SetState(server.CLOSED)
+
+		// remove partial row so that the output stays well formed
+		this.writer.truncate(beforeWrites)
+	}
+	return success
+}
+
+// Strings are written verbatim, nested objects and arrays as JSON text,
+// null and missing values as empty fields.
+func (this *httpRequest) appendDelimitedValue(buf []byte, item value.Value) []byte {
+	switch item.Type() {
+	case value.MISSING, value.NULL:
+		return buf
+	case value.STRING:
+		s, _ := item.Actual().(string)
+		return this.appendDelimitedField(buf, s)
+	default:
+		bytes, err := item.MarshalJSON()
+		if err != nil {
+			return buf
+		}
+		return this.appendDelimitedField(buf, string(bytes))
+	}
+}
+
+// CSV fields are quoted as per RFC 4180 when they contain the separator,
+// quotes or line breaks. TSV does not allow quoting, so tabs, line breaks
+// and backslashes are escaped instead.
+func (this *httpRequest) appendDelimitedField(buf []byte, s string) []byte {
+	if this.format == TSV {
+		for i := 0; i < len(s); i++ {
+			switch s[i] {
+			case '\t':
+				buf = append(buf, '\\', 't')
+			case '\n':
+				buf = append(buf, '\\', 'n')
+			case '\r':
+				buf = append(buf, '\\', 'r')
+			case '\\':
+				buf = append(buf, '\\', '\\')
+			default:
+				buf = append(buf, s[i])
+			}
+		}
+		return buf
+	}
+
+	if !strings.ContainsAny(s, ",\"\r\n") {
+		return append(buf, s...)
+	}
+	buf = append(buf, '"')
+	for i := 0; i < len(s); i++ {
+		if s[i] == '"' {
+			buf = append(buf, '"')
+		}
+		buf = append(buf, s[i])
+	}
+	return append(buf, '"')
+}
+
+func (this *httpRequest) writeDelimitedSuffix(srvr *server.Server, state server.State) bool {
+	if !this.writeDelimitedHeader() {
+		return false
+	}
+	return this.setDelimitedTrailers(srvr, state)
+}
+
+func (this *httpRequest) delimitedFailed(srvr *server.Server) {
+	header := this.resp.Header()
+	header.Set(HEADER_REQUEST_ID, this.Id().String())
+	if this.ClientID().IsValid() {
+		header.Set(HEADER_CLIENT_CONTEXT_ID, this.ClientID().String())
+	}
+
+	this.markTimeOfCompletion(time.Now())
+	this.setDelimitedTrailers(srvr, this.State())
+	this.writer.noMoreData()
+
+	this.stopAndAlert(server.FATAL)
+}
+
+func (this *httpRequest) setDelimitedTrailers(srvr *server.Server, state server.State) bool {
+	errs := this.collectErrors(this.Errors(), false)
+	if len(errs) > 0 {
+		this.errorCount = len(errs)
+		if this.State() != server.FATAL {
+			this.setHttpCode(mapErrorToHttpResponse(this.Errors()[0], http.StatusOK))
+		}
+		this.writer.trailer(HEADER_ERRORS, marshalTrailer(errs))
+	}
+
+	warnings := this.collectErrors(this.Warnings(), true)
+	if len(warnings) > 0 {
+		this.warningCount = len(warnings)
+		this.writer.trailer(HEADER_WARNINGS, marshalTrailer(warnings))
+	}
+
+	if state == server.COMPLETED {
+		if this.errorCount == 0 {
+			state = server.SUCCESS
+		} else {
+			state = server.ERRORS
+		}
+	}
+	this.writer.trailer(HEADER_STATUS, state.StateName())
+
+	m := this.Metrics()
+	if m == value.TRUE || (m == value.NONE && srvr.Metrics()) {
+		this.writer.trailer(HEADER_METRICS, marshalTrailer(this.metricsMap()))
+	}
+	return true
+}
+
+func (this *httpRequest) collectErrors(errs []errors.Error, onceOnly bool) []map[string]interface{} {
+	var rv []map[string]interface{}
+	alreadySeen := make(map[string]bool)
+
+	for _, err := range errs {
+		if onceOnly && err.OnceOnly() && alreadySeen[err.Error()] {
+			continue
+		}
+		alreadySeen[err.Error()] = true
+		m := map[string]interface{}{
+			"code": err.Code(),
+			"msg":  err.Error(),
+		}
+		if err.Retry() {
+			m["retry"] = true
+		}
+		rv = append(rv, m)
+	}
+	return rv
+}
+
+func (this *httpRequest) metricsMap() map[string]interface{} {
+	m := map[string]interface{}{
+		"elapsedTime":   this.elapsedTime.String(),
+		"executionTime": this.executionTime.String(),
+		"resultCount":   this.resultCount,
+		"resultSize":    this.resultSize,
+	}
+	if this.MutationCount() > 0 {
+		m["mutationCount"] = this.MutationCount()
+	}
+	if this.SortCount() > 0 {
+		m["sortCount"] = this.SortCount()
+	}
+	if this.UsedMemory() > 0 {
+		m["usedMemory"] = this.UsedMemory()
+	}
+	if this.errorCount > 0 {
+		m["errorCount"] = this.errorCount
+	}
+	if this.warningCount > 0 {
+		m["warningCount"] = this.warningCount
+	}
+	return m
+}
+
+func marshalTrailer(v interface{}) string {
+	bytes, err := json.Marshal(v)
+	if err != nil {
+		return strconv.Quote(err.Error())
+	}
+	return string(bytes)
+}
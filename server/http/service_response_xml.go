@@ -0,0 +1,298 @@
+//  Copyright (c) 2019 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package http
+
+import (
+	"bytes"
+	"encoding/xml"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/server"
+	"github.com/couchbase/query/value"
+)
+
+// The XML response mirrors the JSON one: a <response> root element
+// holding requestID, signature, results, errors, status and metrics.
+// Values map onto elements as follows
+//   - object fields become child elements named after the field,
+//     or <field name="..."> if the name is not a valid XML name
+//   - array elements become <item> child elements
+//   - non string scalars carry a type attribute, so that they can be told
+//     apart from strings: <age type="number">42</age>
+//   - null becomes an empty element with type="null", missing is omitted
+const _XML_HEADER = "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n"
+
+func (this *httpRequest) xmlNewLine(depth int) string {
+	if this.indent == "" {
+		return ""
+	}
+	return "\n" + strings.Repeat(this.indent, depth)
+}
+
+func (this *httpRequest) writeXMLPrefix(srvr *server.Server, signature value.Value) bool {
+	if !(this.writeString(_XML_HEADER) && this.writeString("<response>") &&
+		this.writeXMLElement("requestID", this.Id().String(), 1)) {
+		return false
+	}
+	if this.ClientID().IsValid() && !this.writeXMLElement("clientContextID", this.ClientID().String(), 1) {
+		return false
+	}
+	if prepared := this.Prepared(); this.AutoExecute() == value.TRUE && prepared != nil {
+		if !this.writeXMLElement("prepared", prepared.Name(), 1) {
+			return false
+		}
+	}
+	s := this.Signature()
+	if signature != nil && (s == value.TRUE || (s == value.NONE && srvr.Signature())) {
+		var buf bytes.Buffer
+
+		buf.WriteString(this.xmlNewLine(1))
+		this.appendXMLValue(&buf, "signature", signature, 1)
+		if !this.writer.writeBytes(buf.Bytes()) {
+			return false
+		}
+	}
+	return this.writeString(this.xmlNewLine(1)) && this.writeString("<results>")
+}
+
+func (this *httpRequest) xmlResult(item value.AnnotatedValue) bool {
+	if this.Halted() {
+		return false
+	}
+
+	this.writer.timeFlush()
+	beforeWrites := this.writer.mark()
+
+	success := this.writer.write(this.xmlNewLine(2))
+	beforeResult := this.writer.mark()
+
+	if success {
+		var buf bytes.Buffer
+
+		this.appendXMLValue(&buf, "result", item, 2)
+		success = this.writer.write(buf.String())
+	}
+
+	if success {
+		this.resultSize += (this.writer.mark() - beforeResult)
+		this.resultCount++
+		this.writer.sizeFlush()
+	} else {
+		this.SetState(server.CLOSED)
+
+		// did not work out: remove last writes so that we have a well formed document
+		this.writer.truncate(beforeWrites)
+	}
+	return success
+}
+
+func (this *httpRequest) writeXMLSuffix(srvr *server.Server, state server.State) bool {
+	return this.writeString(this.xmlNewLine(1)) && this.writeString("</results>") &&
+		this.writeXMLTrailer(srvr, state)
+}
+
+func (this *httpRequest) xmlFailed(srvr *server.Server) {
+	this.prefix, this.indent = this.prettyStrings(srvr.Pretty(), false)
+	this.writeString(_XML_HEADER)
+	this.writeString("<response>")
+	this.writeXMLElement("requestID", this.Id().String(), 1)
+	if this.ClientID().IsValid() {
+		this.writeXMLElement("clientContextID", this.ClientID().String(), 1)
+	}
+
+	this.markTimeOfCompletion(time.Now())
+
+	this.writeXMLTrailer(srvr, this.State())
+	this.writer.noMoreData()
+
+	this.stopAndAlert(server.FATAL)
+}
+
+func (this *httpRequest) writeXMLTrailer(srvr *server.Server, state server.State) bool {
+	if !(this.writeXMLErrors("errors", this.Errors(), false) &&
+		this.writeXMLErrors("warnings", this.Warnings(), true)) {
+		return false
+	}
+
+	if state == server.COMPLETED {
+		if this.errorCount == 0 {
+			state = server.SUCCESS
+		} else {
+			state = server.ERRORS
+		}
+	}
+	if !this.writeXMLElement("status", state.StateName(), 1) {
+		return false
+	}
+
+	m := this.Metrics()
+	if m == value.TRUE || (m == value.NONE && srvr.Metrics()) {
+		if !this.writeXMLMetrics() {
+			return false
+		}
+	}
+	return this.writeString(this.xmlNewLine(0)) && this.writeString("</response>\n")
+}
+
+func (this *httpRequest) writeXMLErrors(name string, errs []errors.Error, warnings bool) bool {
+	var buf bytes.Buffer
+
+	count := 0
+	alreadySeen := make(map[string]bool)
+	for _, err := range errs {
+		if warnings && err.OnceOnly() && alreadySeen[err.Error()] {
+			continue
+		}
+		alreadySeen[err.Error()] = true
+		if count == 0 {
+			buf.WriteString(this.xmlNewLine(1))
+			buf.WriteString("<" + name + ">")
+			if !warnings && this.State() != server.FATAL {
+				this.setHttpCode(mapErrorToHttpResponse(err, http.StatusOK))
+			}
+		}
+		buf.WriteString(this.xmlNewLine(2))
+		buf.WriteString("<error>")
+		buf.WriteString(this.xmlNewLine(3))
+		buf.WriteString("<code>" + strconv.Itoa(int(err.Code())) + "</code>")
+		buf.WriteString(this.xmlNewLine(3))
+		buf.WriteString("<msg>")
+		xml.EscapeText(&buf, []byte(err.Error()))
+		buf.WriteString("</msg>")
+		if err.Retry() {
+			buf.WriteString(this.xmlNewLine(3))
+			buf.WriteString("<retry>true</retry>")
+		}
+		buf.WriteString(this.xmlNewLine(2))
+		buf.WriteString("</error>")
+		count++
+	}
+	if count == 0 {
+		return true
+	}
+	if warnings {
+		this.warningCount = count
+	} else {
+		this.errorCount = count
+	}
+	buf.WriteString(this.xmlNewLine(1))
+	buf.WriteString("</" + name + ">")
+	return this.writer.writeBytes(buf.Bytes())
+}
+
+func (this *httpRequest) writeXMLMetrics() bool {
+	var buf bytes.Buffer
+
+	metrics := this.metricsMap()
+	names := make([]string, 0, len(metrics))
+	for name := range metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	buf.WriteString(this.xmlNewLine(1))
+	buf.WriteString("<metrics>")
+	for _, name := range names {
+		buf.WriteString(this.xmlNewLine(2))
+		this.appendXMLValue(&buf, name, value.NewValue(metrics[name]), 2)
+	}
+	buf.WriteString(this.xmlNewLine(1))
+	buf.WriteString("</metrics>")
+	return this.writer.writeBytes(buf.Bytes())
+}
+
+func (this *httpRequest) writeXMLElement(name, text string, depth int) bool {
+	var buf bytes.Buffer
+
+	buf.WriteString(this.xmlNewLine(depth))
+	buf.WriteString("<" + name + ">")
+	xml.EscapeText(&buf, []byte(text))
+	buf.WriteString("</" + name + ">")
+	return this.writer.writeBytes(buf.Bytes())
+}
+
+func (this *httpRequest) appendXMLValue(buf *bytes.Buffer, name string, item value.Value, depth int) {
+	typ := item.Type()
+	if typ == value.MISSING {
+		return
+	}
+
+	// field names are not necessarily valid XML names
+	start, end := name, name
+	if !isXMLName(name) {
+		var attr bytes.Buffer
+
+		xml.EscapeText(&attr, []byte(name))
+		start = "field name=\"" + attr.String() + "\""
+		end = "field"
+	}
+
+	switch typ {
+	case value.NULL:
+		buf.WriteString("<" + start + " type=\"null\"/>")
+	case value.STRING:
+		s, _ := item.Actual().(string)
+		buf.WriteString("<" + start + ">")
+		xml.EscapeText(buf, []byte(s))
+		buf.WriteString("</" + end + ">")
+	case value.OBJECT:
+		buf.WriteString("<" + start + " type=\"object\">")
+		names := item.FieldNames(nil)
+		for _, n := range names {
+			field, _ := item.Field(n)
+			buf.WriteString(this.xmlNewLine(depth + 1))
+			this.appendXMLValue(buf, n, field, depth+1)
+		}
+		if len(names) > 0 {
+			buf.WriteString(this.xmlNewLine(depth))
+		}
+		buf.WriteString("</" + end + ">")
+	case value.ARRAY:
+		buf.WriteString("<" + start + " type=\"array\">")
+		elems, _ := item.Actual().([]interface{})
+		for _, e := range elems {
+			buf.WriteString(this.xmlNewLine(depth + 1))
+			this.appendXMLValue(buf, "item", value.NewValue(e), depth+1)
+		}
+		if len(elems) > 0 {
+			buf.WriteString(this.xmlNewLine(depth))
+		}
+		buf.WriteString("</" + end + ">")
+	default:
+		b, _ := item.MarshalJSON()
+		buf.WriteString("<" + start + " type=\"" + typ.String() + "\">")
+		xml.EscapeText(buf, b)
+		buf.WriteString("</" + end + ">")
+	}
+}
+
+// a conservative check for XML names: letters, digits, '_', '-' and '.',
+// not starting with a digit, '-', '.', or the reserved "xml" prefix
+func isXMLName(name string) bool {
+	if name == "" || strings.HasPrefix(strings.ToLower(name), "xml") {
+		return false
+	}
+	for i, r := range name {
+		switch {
+		case r == '_' || unicode.IsLetter(r):
+		case i > 0 && (r == '-' || r == '.' || unicode.IsDigit(r)):
+		default:
+			return false
+		}
+	}
+	return utf8.ValidString(name)
+}
@@ -22,6 +22,7 @@ import (
 	"github.com/couchbase/query/execution"
 	"github.com/couchbase/query/plan"
 	"github.com/couchbase/query/timestamp"
+	"github.com/couchbase/query/tracing"
 	"github.com/couchbase/query/util"
 	"github.com/couchbase/query/value"
 )
@@ -83,6 +84,15 @@ type Request interface {
 	SetPipelineCap(pipelineCap int64)
 	PipelineBatch() int
 	SetPipelineBatch(pipelineBatch int)
+	MemoryQuota() uint64
+	SetMemoryQuota(memoryQuota uint64)
+	MaxRecursionDepth() int
+	SetMaxRecursionDepth(depth int)
+	TxId() string
+	SetTxId(txId string)
+	TxTimeout() time.Duration
+	SetTxTimeout(txTimeout time.Duration)
+	UsedMemory() uint64
 	Readonly() value.Tristate
 	SetReadonly(readonly value.Tristate)
 	Metrics() value.Tristate
@@ -106,12 +116,16 @@ type Request interface {
 	SetAutoPrepare(a value.Tristate)
 	AutoExecute() value.Tristate
 	SetAutoExecute(a value.Tristate)
+	ResultCache() value.Tristate
+	SetResultCache(c value.Tristate)
 	SetExecTime(time time.Time)
 	RequestTime() time.Time
 	ServiceTime() time.Time
 	Output() execution.Output
 	Servicing()
 	Fail(err errors.Error)
+	Errors() []errors.Error
+	Warnings() []errors.Error
 	Execute(server *Server, signature value.Value)
 	NotifyStop(stop execution.Operator)
 	Failed(server *Server)
@@ -129,6 +143,12 @@ type Request interface {
 	GetTimings() execution.Operator
 	OriginalHttpRequest() *http.Request
 	IsAdHoc() bool
+	Trace() *tracing.Trace
+	SetTrace(trace *tracing.Trace)
+	WorkloadGroup() string
+	SetWorkloadGroup(group string)
+	QueueWaitTime() time.Duration
+	SetQueueWaitTime(wait time.Duration)
 
 	setSleep() // internal methods for load control
 	sleep()
@@ -209,6 +229,7 @@ type BaseRequest struct {
 	// of the struct to avoid alignment issues on x86 platforms
 	mutationCount atomic.AlignedUint64
 	sortCount     atomic.AlignedUint64
+	usedMemory    atomic.AlignedUint64
 	phaseStats    [execution.PHASES]phaseStat
 
 	sync.RWMutex
@@ -227,6 +248,10 @@ type BaseRequest struct {
 	scanCap         int64
 	pipelineCap     int64
 	pipelineBatch   int
+	memoryQuota     uint64
+	recursionDepth  int
+	txId            string
+	txTimeout       time.Duration
 	readonly        value.Tristate
 	signature       value.Tristate
 	metrics         value.Tristate
@@ -254,6 +279,10 @@ type BaseRequest struct {
 	featureControls uint64 // feature bit controls
 	autoPrepare     value.Tristate
 	autoExecute     value.Tristate
+	resultCache     value.Tristate
+	trace           *tracing.Trace
+	workloadGroup   string
+	queueWaitTime   time.Duration
 }
 
 type requestIDImpl struct {
@@ -298,6 +327,7 @@ func NewBaseRequest(rv *BaseRequest) {
 	rv.profile = ProfUnset
 	rv.controls = value.NONE
 	rv.autoPrepare = value.NONE
+	rv.resultCache = value.NONE
 	rv.indexApiVersion = util.GetMaxIndexAPI()
 	rv.featureControls = util.GetN1qlFeatureControl()
 	rv.id.id, _ = util.UUIDV3()
@@ -415,6 +445,56 @@ func (this *BaseRequest) SetPipelineBatch(pipelineBatch int) {
 	this.pipelineBatch = pipelineBatch
 }
 
+// in MB, zero means the server default
+func (this *BaseRequest) MemoryQuota() uint64 {
+	return this.memoryQuota
+}
+
+func (this *BaseRequest) SetMemoryQuota(memoryQuota uint64) {
+	this.memoryQuota = memoryQuota
+}
+
+// zero means the server default
+func (this *BaseRequest) MaxRecursionDepth() int {
+	return this.recursionDepth
+}
+
+func (this *BaseRequest) SetMaxRecursionDepth(depth int) {
+	this.recursionDepth = depth
+}
+
+// the transaction the request belongs to, if any
+func (this *BaseRequest) TxId() string {
+	return this.txId
+}
+
+func (this *BaseRequest) SetTxId(txId string) {
+	this.txId = txId
+}
+
+// the timeout of transactions started by the request, zero means the server default
+func (this *BaseRequest) TxTimeout() time.Duration {
+	return this.txTimeout
+}
+
+func (this *BaseRequest) SetTxTimeout(txTimeout time.Duration) {
+	this.txTimeout = txTimeout
+}
+
+// The most memory, in bytes, used by the request at any one time
+func (this *BaseRequest) UsedMemory() uint64 {
+	return atomic.LoadUint64(&this.usedMemory)
+}
+
+func (this *BaseRequest) TrackMemory(size uint64) {
+	for {
+		used := atomic.LoadUint64(&this.usedMemory)
+		if size <= used || atomic.CompareAndSwapUint64(&this.usedMemory, used, size) {
+			return
+		}
+	}
+}
+
 func (this *BaseRequest) Readonly() value.Tristate {
 	return this.readonly
 }
@@ -732,6 +812,40 @@ func (this *BaseRequest) AutoExecute() value.Tristate {
 	return this.autoExecute
 }
 
+func (this *BaseRequest) SetResultCache(c value.Tristate) {
+	this.resultCache = c
+}
+
+func (this *BaseRequest) ResultCache() value.Tristate {
+	return this.resultCache
+}
+
+func (this *BaseRequest) Trace() *tracing.Trace {
+	return this.trace
+}
+
+func (this *BaseRequest) SetTrace(trace *tracing.Trace) {
+	this.trace = trace
+}
+
+// the workload group the request was scheduled in, if any
+func (this *BaseRequest) WorkloadGroup() string {
+	return this.workloadGroup
+}
+
+func (this *BaseRequest) SetWorkloadGroup(group string) {
+	this.workloadGroup = group
+}
+
+// how long the request waited for a servicer
+func (this *BaseRequest) QueueWaitTime() time.Duration {
+	return this.queueWaitTime
+}
+
+func (this *BaseRequest) SetQueueWaitTime(wait time.Duration) {
+	this.queueWaitTime = wait
+}
+
 func (this *BaseRequest) Results() chan bool {
 	return this.stopResult
 }
@@ -804,6 +918,24 @@ func (this *BaseRequest) CompleteRequest(requestTime time.Duration, serviceTime
 	LogRequest(requestTime, serviceTime, resultCount,
 		resultSize, errorCount, req, this, server)
 
+	if this.trace != nil {
+		root := this.trace.Root()
+		root.SetAttribute("requestId", this.Id().String())
+		if this.ClientID().IsValid() {
+			root.SetAttribute("clientContextId", this.ClientID().String())
+		}
+		root.SetAttribute("statement", this.EventStatement())
+		root.SetAttribute("state", this.State().StateName())
+		root.SetAttribute("resultCount", resultCount)
+		root.SetAttribute("resultSize", resultSize)
+		root.SetAttribute("errorCount", errorCount)
+		if errs := this.Errors(); len(errs) > 0 {
+			root.SetError(errs[0])
+		}
+		this.trace.Finish()
+		this.trace = nil
+	}
+
 	// Request Profiling - signal that request has completed and
 	// resources can be pooled / released as necessary
 	if this.timings != nil {
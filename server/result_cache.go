@@ -0,0 +1,127 @@
+//  Copyright (c) 2020 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package server
+
+import (
+	"time"
+
+	"github.com/couchbase/query/accounting"
+	"github.com/couchbase/query/auth"
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/execution"
+	"github.com/couchbase/query/plan"
+	"github.com/couchbase/query/resultcache"
+	"github.com/couchbase/query/value"
+)
+
+// resultCapture passes results on to the request output, keeping
+// a copy for the result cache
+type resultCapture struct {
+	execution.Output
+	entry *resultcache.Entry
+	full  bool
+}
+
+func (this *resultCapture) Result(item value.AnnotatedValue) bool {
+	if !this.full {
+		bytes, err := item.MarshalJSON()
+		if err != nil || !this.entry.AddResult(bytes) {
+			this.full = true
+		}
+	}
+	return this.Output.Result(item)
+}
+
+// cachedResults serves the request from the result cache, if it can be cached
+// and there are current results, or returns the output that will collect
+// the results for the cache
+func (this *Server) cachedResults(request Request, prepared *plan.Prepared, namespace string) (
+	bool, *resultCapture) {
+
+	if request.ResultCache() == value.FALSE ||
+		(request.ResultCache() == value.NONE && !prepared.UseCache()) {
+		return false, nil
+	}
+	if request.Type() != "SELECT" || !prepared.Cacheable() || request.TxId() != "" ||
+		request.AutoExecute() == value.TRUE || request.ScanConsistency() != datastore.UNBOUNDED {
+		return false, nil
+	}
+	authorize, ok := prepared.Operator.(*plan.Authorize)
+	if !ok {
+		return false, nil
+	}
+
+	// statements needing anything other than reading keyspaces, such as executing
+	// functions, can't be invalidated
+	privs := authorize.Privileges()
+	keyspaces := make([]string, 0, privs.Num())
+	for _, pair := range privs.List {
+		if pair.Priv != auth.PRIV_QUERY_SELECT {
+			return false, nil
+		}
+		keyspaces = append(keyspaces, pair.Target)
+	}
+
+	// nor can statements not reading any keyspace
+	if len(keyspaces) == 0 {
+		return false, nil
+	}
+
+	// results are only shared by requests authorized for the same users
+	ds := datastore.GetDatastore()
+	if ds == nil {
+		return false, nil
+	}
+	users, err := ds.Authorize(privs, request.Credentials(), request.OriginalHttpRequest())
+	if err != nil {
+		return false, nil
+	}
+	key, err := resultcache.Key(prepared.Text(), prepared.Name(), namespace, request.NamedArgs(),
+		request.PositionalArgs(), users)
+	if err != nil {
+		return false, nil
+	}
+
+	entry := resultcache.Get(key)
+	if entry != nil {
+		accounting.UpdateCounter(accounting.RESULT_CACHE_HITS)
+		this.replayResults(request, prepared, entry)
+		return true, nil
+	}
+	accounting.UpdateCounter(accounting.RESULT_CACHE_MISSES)
+	return false, &resultCapture{
+		Output: request.Output(),
+		entry:  resultcache.NewEntry(key, prepared.Text(), prepared.Name(), namespace, users, keyspaces),
+	}
+}
+
+func (this *Server) replayResults(request Request, prepared *plan.Prepared, entry *resultcache.Entry) {
+	output := request.Output()
+	request.SetExecTime(time.Now())
+	go func() {
+		output.SetUp()
+		for _, r := range entry.Results {
+			if !output.Result(value.NewAnnotatedValue(value.NewValue(r))) {
+				break
+			}
+		}
+		output.CloseResults()
+	}()
+	request.Execute(this, prepared.Signature())
+}
+
+// only complete results are cached
+func (this *resultCapture) done(request Request) {
+	if this.full || request.State() != COMPLETED ||
+		len(request.Errors()) > 0 || len(request.Warnings()) > 0 {
+		return
+	}
+	resultcache.Add(this.entry)
+}
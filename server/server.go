@@ -35,6 +35,7 @@ import (
 	"github.com/couchbase/query/rewrite"
 	"github.com/couchbase/query/semantics"
 	queryMetakv "github.com/couchbase/query/server/settings/couchbase"
+	"github.com/couchbase/query/transactions"
 	"github.com/couchbase/query/util"
 	"github.com/couchbase/query/value"
 )
@@ -97,10 +98,12 @@ type Server struct {
 	maxParallelism atomic.AlignedInt64
 	keepAlive      atomic.AlignedInt64
 	requestSize    atomic.AlignedInt64
+	memoryQuota    atomic.AlignedUint64
 
 	sync.RWMutex
 	unboundQueue runQueue
 	plusQueue    runQueue
+	workloads    workloadScheduler
 	datastore    datastore.Datastore
 	systemstore  datastore.Datastore
 	configstore  clustering.ConfigurationStore
@@ -335,6 +338,40 @@ func (this *Server) SetPipelineCap(pipeline_cap int64) {
 	execution.SetPipelineCap(pipeline_cap)
 }
 
+func (this *Server) SpillThreshold() int64 {
+	return execution.GetSpillThreshold()
+}
+
+func (this *Server) SetSpillThreshold(threshold int64) {
+	execution.SetSpillThreshold(threshold)
+}
+
+func (this *Server) TmpSpaceDir() string {
+	return execution.GetTmpSpaceDir()
+}
+
+func (this *Server) SetTmpSpaceDir(dir string) {
+	execution.SetTmpSpaceDir(dir)
+}
+
+// Default memory quota of requests, in MB; zero means no quota
+func (this *Server) MemoryQuota() uint64 {
+	return atomic.LoadUint64(&this.memoryQuota)
+}
+
+func (this *Server) SetMemoryQuota(memoryQuota uint64) {
+	atomic.StoreUint64(&this.memoryQuota, memoryQuota)
+}
+
+// Default maximum number of iterations of WITH RECURSIVE terms
+func (this *Server) MaxRecursionDepth() int {
+	return execution.MaxRecursionDepth()
+}
+
+func (this *Server) SetMaxRecursionDepth(depth int) {
+	execution.SetMaxRecursionDepth(depth)
+}
+
 func (this *Server) PipelineBatch() int {
 	return execution.PipelineBatchSize()
 }
@@ -463,6 +500,19 @@ func (this *Server) PlusServiceRequest(request Request) bool {
 }
 
 func (this *Server) handleRequest(request Request, queue *runQueue) bool {
+
+	// requests in workload groups are admitted by their group first
+	group := this.workloads.match(request)
+	if group != nil {
+		return this.handleWorkloadRequest(request, group, queue)
+	}
+
+	return this.runRequest(request, queue, time.Now())
+}
+
+// runRequest services a request on one of the servicers of its run queue,
+// queued is when the request started waiting, for the queue wait time
+func (this *Server) runRequest(request Request, queue *runQueue, queued time.Time) bool {
 	runCnt := int(atomic.AddInt32(&queue.runCnt, 1))
 
 	// if servicers exceeded, reserve a spot in the queue
@@ -479,6 +529,7 @@ func (this *Server) handleRequest(request Request, queue *runQueue) bool {
 
 		// (RC #1) wait
 		queue.addRequest(request)
+		request.SetQueueWaitTime(time.Since(queued))
 	}
 
 	// service
@@ -630,6 +681,20 @@ func (this *Server) serviceRequest(request Request) {
 		namespace = this.namespace
 	}
 
+	// statements of a transaction are executed one at a time
+	var txn *transactions.Transaction
+	if request.TxId() != "" {
+		var err errors.Error
+
+		txn, err = transactions.Acquire(request.TxId())
+		if err != nil {
+			request.Fail(err)
+			request.Failed(this)
+			return
+		}
+		defer txn.Release()
+	}
+
 	prepared, err := this.getPrepared(request, namespace)
 	if err != nil {
 		request.Fail(err)
@@ -646,6 +711,15 @@ func (this *Server) serviceRequest(request Request) {
 		return
 	}
 
+	cached, capture := this.cachedResults(request, prepared, namespace)
+	if cached {
+		return
+	}
+	output := request.Output()
+	if capture != nil {
+		output = capture
+	}
+
 	maxParallelism := request.MaxParallelism()
 	if maxParallelism <= 0 {
 		maxParallelism = this.MaxParallelism()
@@ -654,11 +728,20 @@ func (this *Server) serviceRequest(request Request) {
 	context := execution.NewContext(request.Id().String(), this.datastore, this.systemstore, namespace,
 		this.readonly, maxParallelism, request.ScanCap(), request.PipelineCap(), request.PipelineBatch(),
 		request.NamedArgs(), request.PositionalArgs(), request.Credentials(), request.ScanConsistency(),
-		request.ScanVectorSource(), request.Output(), request.OriginalHttpRequest(),
+		request.ScanVectorSource(), output, request.OriginalHttpRequest(),
 		prepared, request.IndexApiVersion(), request.FeatureControls())
 
 	context.SetWhitelist(this.whitelist)
 
+	memoryQuota := request.MemoryQuota()
+	if memoryQuota == 0 {
+		memoryQuota = this.MemoryQuota()
+	}
+	context.SetMemoryQuota(memoryQuota)
+	context.SetMaxRecursionDepth(request.MaxRecursionDepth())
+	context.SetTransaction(txn)
+	context.SetTxTimeout(request.TxTimeout())
+
 	if request.AutoExecute() == value.TRUE {
 		res, _, er := context.EvaluatePrepared(prepared, false)
 		if er != nil {
@@ -721,6 +804,7 @@ func (this *Server) serviceRequest(request Request) {
 	operator.SetRoot()
 	request.SetTimings(operator)
 	request.Output().AddPhaseTime(execution.INSTANTIATE, time.Since(build))
+	request.Trace().AddSpan(execution.INSTANTIATE.String(), nil, build, time.Now())
 
 	if request.State() == FATAL {
 		request.Failed(this)
@@ -743,9 +827,16 @@ func (this *Server) serviceRequest(request Request) {
 
 	request.NotifyStop(operator)
 	request.SetExecTime(time.Now())
+	runSpan := request.Trace().StartSpan(execution.RUN.String(), nil)
+	context.SetTraceSpan(runSpan)
+	execution.TraceOperator(operator, context)
 	operator.RunOnce(context, nil)
 
 	request.Execute(this, prepared.Signature())
+	runSpan.End()
+	if capture != nil {
+		capture.done(request)
+	}
 }
 
 func (this *Server) getPrepared(request Request, namespace string) (*plan.Prepared, errors.Error) {
@@ -764,7 +855,8 @@ func (this *Server) getPrepared(request Request, namespace string) (*plan.Prepar
 	namedArgs := request.NamedArgs()
 	positionalArgs := request.PositionalArgs()
 	autoExecute := request.AutoExecute() == value.TRUE
-	if len(namedArgs) > 0 || len(positionalArgs) > 0 || autoExecute {
+	inTransaction := request.TxId() != ""
+	if len(namedArgs) > 0 || len(positionalArgs) > 0 || autoExecute || inTransaction {
 		autoPrepare = false
 	}
 
@@ -782,6 +874,7 @@ func (this *Server) getPrepared(request Request, namespace string) (*plan.Prepar
 		parse := time.Now()
 		stmt, err := n1ql.ParseStatement2(request.Statement(), namespace) // TODO switch to collections scope
 		request.Output().AddPhaseTime(execution.PARSE, time.Since(parse))
+		request.Trace().AddSpan(execution.PARSE.String(), nil, parse, time.Now())
 		if err != nil {
 			return nil, errors.NewParseSyntaxError(err, "")
 		}
@@ -796,6 +889,10 @@ func (this *Server) getPrepared(request Request, namespace string) (*plan.Prepar
 			return nil, errors.NewSemanticsError(err, "")
 		}
 
+		if inTransaction && !transactionStatement(stmt.Type()) {
+			return nil, errors.NewTransactionStatementError(stmt.Type())
+		}
+
 		isPrepare := false
 		if _, ok := stmt.(*algebra.Prepare); ok {
 			isPrepare = true
@@ -813,8 +910,9 @@ func (this *Server) getPrepared(request Request, namespace string) (*plan.Prepar
 		}
 
 		prepared, err = planner.BuildPrepared(stmt, this.datastore, this.systemstore, namespace, autoExecute, !autoExecute,
-			namedArgs, positionalArgs, request.IndexApiVersion(), request.FeatureControls())
+			namedArgs, positionalArgs, request.IndexApiVersion(), request.FeatureControls(), inTransaction)
 		request.Output().AddPhaseTime(execution.PLAN, time.Since(prep))
+		request.Trace().AddSpan(execution.PLAN.String(), nil, prep, time.Now())
 		if err != nil {
 			return nil, errors.NewPlanError(err, "")
 		}
@@ -835,6 +933,12 @@ func (this *Server) getPrepared(request Request, namespace string) (*plan.Prepar
 				if err != nil {
 					return nil, err
 				}
+				if inTransaction {
+					prepared, err = this.transactionPlan(request, prepared)
+					if err != nil {
+						return nil, err
+					}
+				}
 				request.SetPrepared(prepared)
 
 				// when executing prepared statements, we set the type to that
@@ -918,6 +1022,15 @@ func (this *Server) getPrepared(request Request, namespace string) (*plan.Prepar
 
 		// ditto
 		request.SetType(prepared.Type())
+		if inTransaction {
+			var err errors.Error
+
+			prepared, err = this.transactionPlan(request, prepared)
+			if err != nil {
+				return nil, err
+			}
+			request.SetPrepared(prepared)
+		}
 	}
 
 	if logging.LogLevel() >= logging.DEBUG {
@@ -928,6 +1041,29 @@ func (this *Server) getPrepared(request Request, namespace string) (*plan.Prepar
 	return prepared, nil
 }
 
+// statements that can be executed within a transaction
+func transactionStatement(stmtType string) bool {
+	switch stmtType {
+	case "SELECT", "INSERT", "UPSERT", "UPDATE", "DELETE", "MERGE", "EXPLAIN", "EXECUTE",
+		"START_TRANSACTION", "COMMIT", "ROLLBACK", "SAVEPOINT":
+		return true
+	}
+	return false
+}
+
+// cached plans do not account for the mutations staged by the transaction
+func (this *Server) transactionPlan(request Request, prepared *plan.Prepared) (*plan.Prepared, errors.Error) {
+	if !transactionStatement(prepared.Type()) {
+		return nil, errors.NewTransactionStatementError(prepared.Type())
+	}
+
+	var reprepTime time.Duration
+
+	prepared, err := prepareds.TransactionPlan(prepared, &reprepTime)
+	request.Output().AddPhaseTime(execution.REPREPARE, reprepTime)
+	return prepared, err
+}
+
 func logExplain(prepared *plan.Prepared) {
 	var pl plan.Operator = prepared
 	explain, err := json.MarshalIndent(pl, "", "    ")
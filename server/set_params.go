@@ -18,8 +18,10 @@ import (
 	"github.com/couchbase/query/functions"
 	"github.com/couchbase/query/logging"
 	"github.com/couchbase/query/prepareds"
+	"github.com/couchbase/query/resultcache"
 	"github.com/couchbase/query/scheduler"
 	queryMetakv "github.com/couchbase/query/server/settings/couchbase"
+	"github.com/couchbase/query/transactions"
 	"github.com/couchbase/query/util"
 )
 
@@ -142,6 +144,45 @@ var _SETTERS = map[string]Setter{
 		scheduler.SchedulerSetLimit(int(value))
 		return nil
 	},
+	SPILLTHRESHOLD: func(s *Server, o interface{}) errors.Error {
+		value := getNumber(o)
+		s.SetSpillThreshold(int64(value))
+		return nil
+	},
+	TMPSPACEDIR: func(s *Server, o interface{}) errors.Error {
+		value, _ := o.(string)
+		s.SetTmpSpaceDir(value)
+		return nil
+	},
+	MEMORYQUOTA: func(s *Server, o interface{}) errors.Error {
+		value := getNumber(o)
+		if value < 0 {
+			value = 0
+		}
+		s.SetMemoryQuota(uint64(value))
+		return nil
+	},
+	RECURSIONDEPTH: func(s *Server, o interface{}) errors.Error {
+		value := getNumber(o)
+		s.SetMaxRecursionDepth(int(value))
+		return nil
+	},
+	TXTIMEOUT: func(s *Server, o interface{}) errors.Error {
+		value := getNumber(o)
+		transactions.SetDefaultTimeout(time.Duration(value))
+		return nil
+	},
+	WORKLOADGROUPS: setWorkloadGroups,
+	RESCACHELIMIT: func(s *Server, o interface{}) errors.Error {
+		value := getNumber(o)
+		resultcache.ResultCacheSetLimit(int(value))
+		return nil
+	},
+	RESCACHETTL: func(s *Server, o interface{}) errors.Error {
+		value := getNumber(o)
+		resultcache.ResultCacheSetTTL(time.Duration(value))
+		return nil
+	},
 }
 
 func getNumber(o interface{}) float64 {
@@ -0,0 +1,429 @@
+//  Copyright (c) 2020 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package server
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	atomic "github.com/couchbase/go-couchbase/platform"
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+)
+
+/*
+Workload groups give classes of requests their own servicers and queue, so
+that heavy requests from one class cannot starve the requests of another.
+
+A request belongs to the first group, in descending priority order, that lists
+one of its users or a prefix of its client context id. Requests that don't
+belong to any group go through the unbound and plus run queues as before.
+
+Unlike the run queues, the workload scheduler is lock based: grouped requests
+are expected to be a minority, and the lock lets us pick waiters by priority.
+Each group runs up to its servicers requests at once, and queues up to its
+queue size more. All groups together run up to the server servicers: when one
+of their requests completes, the oldest waiter of the highest priority group
+that has spare servicers goes first. Priority only orders groups against each
+other.
+Once admitted by its group, a request still takes a servicer from the run queue
+it came in on, like any other request, so that grouped and ungrouped requests
+together never exceed the servicers of the run queue.
+Groups with no servicers hold on to their requests until servicers are
+assigned, or the requests time out in the queue.
+*/
+
+const (
+	WLG_NAME      = "name"
+	WLG_USERS     = "users"
+	WLG_CONTEXTS  = "context-prefixes"
+	WLG_SERVICERS = "servicers"
+	WLG_QUEUE     = "queue-size"
+	WLG_TIMEOUT   = "timeout"
+	WLG_PRIORITY  = "priority"
+)
+
+type WorkloadGroup struct {
+	name      string
+	users     []string
+	prefixes  []string
+	servicers int
+	queueSize int
+	timeout   time.Duration
+	priority  int
+
+	// scheduling state, protected by the scheduler lock
+	running  int
+	waiters  []*workloadWaiter
+	rejected uint64
+}
+
+type workloadWaiter struct {
+	wake   chan bool
+	queued time.Time
+}
+
+type workloadScheduler struct {
+	sync.Mutex
+	count   int32 // number of groups, checked without the lock
+	groups  []*WorkloadGroup
+	running int
+}
+
+// the settings representation of a group
+func (this *WorkloadGroup) object() map[string]interface{} {
+	rv := map[string]interface{}{
+		WLG_NAME:      this.name,
+		WLG_SERVICERS: this.servicers,
+		WLG_QUEUE:     this.queueSize,
+		WLG_PRIORITY:  this.priority,
+	}
+	if len(this.users) > 0 {
+		rv[WLG_USERS] = this.users
+	}
+	if len(this.prefixes) > 0 {
+		rv[WLG_CONTEXTS] = this.prefixes
+	}
+	if this.timeout > 0 {
+		rv[WLG_TIMEOUT] = this.timeout
+	}
+	return rv
+}
+
+func (this *WorkloadGroup) matchUser(users string) bool {
+	if len(this.users) == 0 || users == "" {
+		return false
+	}
+	for _, u := range strings.Split(users, ",") {
+		for _, user := range this.users {
+			if u == user {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (this *WorkloadGroup) matchContext(clientContextId string) bool {
+	if clientContextId == "" {
+		return false
+	}
+	for _, prefix := range this.prefixes {
+		if strings.HasPrefix(clientContextId, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewWorkloadGroups decodes the workload-groups setting, an array of group objects
+func NewWorkloadGroups(val interface{}) ([]*WorkloadGroup, errors.Error) {
+	objects, ok := val.([]interface{})
+	if !ok {
+		return nil, errors.NewAdminSettingTypeError(WORKLOADGROUPS, val)
+	}
+
+	names := make(map[string]bool, len(objects))
+	groups := make([]*WorkloadGroup, 0, len(objects))
+	for _, o := range objects {
+		object, ok := o.(map[string]interface{})
+		if !ok {
+			return nil, errors.NewAdminSettingTypeError(WORKLOADGROUPS, o)
+		}
+		group := &WorkloadGroup{}
+		for n, v := range object {
+			var err errors.Error
+
+			switch n {
+			case WLG_NAME:
+				group.name, ok = v.(string)
+				if !ok || group.name == "" {
+					err = errors.NewAdminSettingTypeError(WLG_NAME, v)
+				}
+			case WLG_USERS:
+				group.users, err = workloadStrings(n, v)
+			case WLG_CONTEXTS:
+				group.prefixes, err = workloadStrings(n, v)
+			case WLG_SERVICERS:
+				group.servicers, err = workloadNumber(n, v)
+			case WLG_QUEUE:
+				group.queueSize, err = workloadNumber(n, v)
+			case WLG_TIMEOUT:
+				var timeout int
+				timeout, err = workloadNumber(n, v)
+				group.timeout = time.Duration(timeout)
+			case WLG_PRIORITY:
+				group.priority, err = workloadNumber(n, v)
+			default:
+				err = errors.NewAdminUnknownSettingError(WORKLOADGROUPS + "." + n)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		if group.name == "" {
+			return nil, errors.NewAdminSettingTypeError(WLG_NAME, nil)
+		}
+		if names[group.name] {
+			return nil, errors.NewAdminSettingTypeError(WLG_NAME, group.name)
+		}
+		names[group.name] = true
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+func workloadStrings(name string, val interface{}) ([]string, errors.Error) {
+	array, ok := val.([]interface{})
+	if !ok {
+		return nil, errors.NewAdminSettingTypeError(name, val)
+	}
+	rv := make([]string, len(array))
+	for i, v := range array {
+		rv[i], ok = v.(string)
+		if !ok || rv[i] == "" {
+			return nil, errors.NewAdminSettingTypeError(name, val)
+		}
+	}
+	return rv, nil
+}
+
+func workloadNumber(name string, val interface{}) (int, errors.Error) {
+	ok, _ := checkNumber(val)
+	if !ok || getNumber(val) < 0 {
+		return 0, errors.NewAdminSettingTypeError(name, val)
+	}
+	return int(getNumber(val)), nil
+}
+
+func checkWorkloadGroups(val interface{}) (bool, errors.Error) {
+	_, err := NewWorkloadGroups(val)
+	return err == nil, err
+}
+
+func setWorkloadGroups(s *Server, o interface{}) errors.Error {
+	groups, err := NewWorkloadGroups(o)
+	if err != nil {
+		return err
+	}
+	s.SetWorkloadGroups(groups)
+	return nil
+}
+
+func (this *Server) WorkloadGroups() []interface{} {
+	this.workloads.Lock()
+	defer this.workloads.Unlock()
+	rv := make([]interface{}, len(this.workloads.groups))
+	for i, group := range this.workloads.groups {
+		rv[i] = group.object()
+	}
+	return rv
+}
+
+type WorkloadStats struct {
+	Name     string
+	Running  int
+	Queued   int
+	Rejected uint64
+}
+
+// WorkloadStats reports the scheduling state of each group
+func (this *Server) WorkloadStats() []WorkloadStats {
+	this.workloads.Lock()
+	defer this.workloads.Unlock()
+	rv := make([]WorkloadStats, len(this.workloads.groups))
+	for i, group := range this.workloads.groups {
+		rv[i] = WorkloadStats{
+			Name:     group.name,
+			Running:  group.running,
+			Queued:   len(group.waiters),
+			Rejected: group.rejected,
+		}
+	}
+	return rv
+}
+
+// SetWorkloadGroups replaces the current groups.
+// Groups that are kept retain their running and waiting requests and their statistics;
+// the waiters of groups that go away are let through straight away, as there is
+// nothing left to schedule them by.
+func (this *Server) SetWorkloadGroups(groups []*WorkloadGroup) {
+	this.workloads.Lock()
+	defer this.workloads.Unlock()
+
+	old := make(map[string]*WorkloadGroup, len(this.workloads.groups))
+	for _, group := range this.workloads.groups {
+		old[group.name] = group
+	}
+	for i, group := range groups {
+		oldGroup, ok := old[group.name]
+		if !ok {
+			continue
+		}
+		delete(old, group.name)
+		oldGroup.users = group.users
+		oldGroup.prefixes = group.prefixes
+		oldGroup.servicers = group.servicers
+		oldGroup.queueSize = group.queueSize
+		oldGroup.timeout = group.timeout
+		oldGroup.priority = group.priority
+		groups[i] = oldGroup
+	}
+	for _, group := range old {
+		for _, waiter := range group.waiters {
+			group.running++
+			this.workloads.running++
+			close(waiter.wake)
+		}
+		group.waiters = nil
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i].priority > groups[j].priority
+	})
+	this.workloads.groups = groups
+	atomic.StoreInt32(&this.workloads.count, int32(len(groups)))
+
+	// new servicers may be available
+	this.workloads.dispatch(this.Servicers())
+}
+
+// the group a request belongs to, if any
+func (this *workloadScheduler) match(request Request) *WorkloadGroup {
+	if atomic.LoadInt32(&this.count) == 0 {
+		return nil
+	}
+
+	users := datastore.CredsString(request.Credentials(), request.OriginalHttpRequest())
+	clientContextId := request.ClientID().String()
+	this.Lock()
+	defer this.Unlock()
+	for _, group := range this.groups {
+		if group.matchUser(users) || group.matchContext(clientContextId) {
+			return group
+		}
+	}
+	return nil
+}
+
+// admit runs a request straight away if a servicer is available, or else queues it
+// and returns the waiter to wait on
+func (this *workloadScheduler) admit(group *WorkloadGroup, servicers int) (*workloadWaiter, time.Duration, errors.Error) {
+	this.Lock()
+	defer this.Unlock()
+	if group.running < group.servicers && this.running < servicers && len(group.waiters) == 0 {
+		group.running++
+		this.running++
+		return nil, group.timeout, nil
+	}
+	if len(group.waiters) >= group.queueSize {
+		group.rejected++
+		return nil, group.timeout, errors.NewServiceErrorWorkloadQueueFull(group.name)
+	}
+	waiter := &workloadWaiter{wake: make(chan bool), queued: time.Now()}
+	group.waiters = append(group.waiters, waiter)
+	return waiter, group.timeout, nil
+}
+
+// wait waits for a queued request's turn, giving up after timeout, if set
+func (this *workloadScheduler) wait(group *WorkloadGroup, waiter *workloadWaiter, timeout time.Duration) errors.Error {
+	if timeout <= 0 {
+		<-waiter.wake
+		return nil
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-waiter.wake:
+		return nil
+	case <-timer.C:
+	}
+
+	this.Lock()
+	defer this.Unlock()
+	for i, w := range group.waiters {
+		if w == waiter {
+			group.waiters = append(group.waiters[:i], group.waiters[i+1:]...)
+			group.rejected++
+			return errors.NewServiceErrorWorkloadQueueTimeout(group.name, timeout)
+		}
+	}
+
+	// woken up while timing out: take the servicer
+	return nil
+}
+
+func (this *workloadScheduler) release(group *WorkloadGroup, servicers int) {
+	this.Lock()
+	defer this.Unlock()
+	group.running--
+	this.running--
+	this.dispatch(servicers)
+}
+
+// wake waiters for as long as there are servicers available
+// the scheduler lock must be held
+func (this *workloadScheduler) dispatch(servicers int) {
+	for this.running < servicers {
+		var next *WorkloadGroup
+
+		for _, group := range this.groups {
+			if len(group.waiters) == 0 || group.running >= group.servicers {
+				continue
+			}
+			if next == nil || (group.priority == next.priority && group.waiters[0].queued.Before(next.waiters[0].queued)) {
+				next = group
+			}
+		}
+		if next == nil {
+			return
+		}
+		waiter := next.waiters[0]
+		next.waiters = next.waiters[1:]
+		next.running++
+		this.running++
+		close(waiter.wake)
+	}
+}
+
+func (this *Server) handleWorkloadRequest(request Request, group *WorkloadGroup, queue *runQueue) bool {
+	request.SetWorkloadGroup(group.name)
+	queued := time.Now()
+	waiter, timeout, err := this.workloads.admit(group, this.Servicers())
+	if err == nil && waiter != nil {
+
+		// queued requests give up once they would have timed out
+		waitTimeout := request.Timeout()
+		if timeout > 0 && (waitTimeout <= 0 || waitTimeout > timeout) {
+			waitTimeout = timeout
+		}
+		if this.timeout > 0 && (waitTimeout <= 0 || waitTimeout > this.timeout) {
+			waitTimeout = this.timeout
+		}
+		err = this.workloads.wait(group, waiter, waitTimeout)
+		request.SetQueueWaitTime(time.Since(queued))
+	}
+	if err != nil {
+		request.Servicing()
+		request.Fail(err)
+		request.Failed(this)
+		return true
+	}
+
+	if timeout > 0 && (request.Timeout() <= 0 || request.Timeout() > timeout) {
+		request.SetTimeout(timeout)
+	}
+	rv := this.runRequest(request, queue, queued)
+	this.workloads.release(group, this.Servicers())
+	return rv
+}
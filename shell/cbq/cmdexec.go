@@ -79,7 +79,7 @@ func command_shell(line string, w io.Writer, interactive bool, liner *liner.Stat
 	return 0, ""
 }
 
-func command_query(line string, w io.Writer, liner *liner.State) (int, string) {
+func command_query(line string, w io.Writer, interactive bool, liner *liner.State) (int, string) {
 	//This block handles N1QL statements
 	// If connected to a query service then noQueryService == false.
 	if noQueryService {
@@ -117,7 +117,7 @@ func command_query(line string, w io.Writer, liner *liner.State) (int, string) {
 					return 0, ""
 				}
 
-				err_code, err_str := ExecN1QLStmt(line, dBn1ql, w)
+				err_code, err_str := execN1QLStmt(line, dBn1ql, w, pager(w, interactive, liner))
 				if err_code != 0 {
 					return err_code, err_str
 				}
@@ -182,7 +182,7 @@ func dispatch_command(line string, w io.Writer, interactive bool, liner *liner.S
 
 	} else {
 		// handles input queries, both n1ql and asterix
-		errCode, errStr := command_query(line, w, liner)
+		errCode, errStr := command_query(line, w, interactive, liner)
 		if errCode != 0 {
 			return errCode, errStr
 		}
@@ -193,6 +193,10 @@ func dispatch_command(line string, w io.Writer, interactive bool, liner *liner.S
 }
 
 func ExecN1QLStmt(line string, dBn1ql n1ql.N1qlDB, w io.Writer) (int, string) {
+	return execN1QLStmt(line, dBn1ql, w, nil)
+}
+
+func execN1QLStmt(line string, dBn1ql n1ql.N1qlDB, w io.Writer, more func() bool) (int, string) {
 
 	// Add back the ; for queries to support fully qualified
 	// asterix queries along with N1QL queries.
@@ -202,7 +206,7 @@ func ExecN1QLStmt(line string, dBn1ql n1ql.N1qlDB, w io.Writer) (int, string) {
 	if rows != nil {
 		// We have output. That is what we want.
 
-		_, werr := io.Copy(w, rows)
+		werr := command.WriteResults(w, rows, more)
 
 		// For any captured write error
 		if werr != nil {
@@ -222,6 +226,18 @@ func ExecN1QLStmt(line string, dBn1ql n1ql.N1qlDB, w io.Writer) (int, string) {
 	return 0, ""
 }
 
+// Long formatted results are paged, but only when someone is
+// reading them at the prompt.
+func pager(w io.Writer, interactive bool, liner *liner.State) func() bool {
+	if !interactive || liner == nil || w != io.Writer(os.Stdout) {
+		return nil
+	}
+	return func() bool {
+		line, err := liner.Prompt(command.MOREMSG)
+		return err == nil && strings.ToLower(strings.TrimSpace(line)) != "q"
+	}
+}
+
 //Function to remove extra space in between words in a string.
 func trimSpaceInStr(inputStr string) (outputStr string) {
 	whiteSpace := false
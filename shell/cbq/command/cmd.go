@@ -72,6 +72,8 @@ var (
 	BATCH = "off"
 	//Output File open in append mode
 	FILE_APPEND_MODE = false
+	//Format to show query results in
+	OUTPUT_FORMAT = JSON_FORMAT
 )
 
 /* Value to store sorted list of keys for shell commands */
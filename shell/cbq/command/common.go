@@ -392,7 +392,12 @@ func PushOrSet(args []string, pushvalue bool) (int, string) {
 
 		args_str := strings.Join(args[1:], " ")
 
-		err_code, err_str := PushValue_Helper(pushvalue, QueryParam, vble, args_str)
+		err_code, err_str := checkOutputFormat(vble, args_str)
+		if err_code != 0 {
+			return err_code, err_str
+		}
+
+		err_code, err_str = PushValue_Helper(pushvalue, QueryParam, vble, args_str)
 
 		if err_code != 0 {
 			return err_code, err_str
@@ -438,7 +443,7 @@ func PushOrSet(args []string, pushvalue bool) (int, string) {
 				val = ValToStr(v)
 			}
 
-			n1ql.SetQueryParams(vble, val)
+			SetQueryParam(vble, val)
 
 		}
 
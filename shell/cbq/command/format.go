@@ -0,0 +1,380 @@
+//  Copyright (c) 2020 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/couchbase/godbc/n1ql"
+	"github.com/couchbase/query/errors"
+)
+
+const (
+	JSON_FORMAT     = "json"
+	TABLE_FORMAT    = "table"
+	CSV_FORMAT      = "csv"
+	TSV_FORMAT      = "tsv"
+	VERTICAL_FORMAT = "vertical"
+)
+
+/* The output format is a shell setting, set like a query parameter
+   with \SET -output_format, but never sent to the query service.
+*/
+const OUTPUT_FORMAT_PARAM = "output_format"
+
+/* Number of rows shown at a time, when the output is paged */
+const PAGE_ROWS = 25
+
+/* Column name for results that are not objects, such as SELECT RAW */
+const VALUE_COLUMN = "value"
+
+func ValidOutputFormat(format string) bool {
+	switch strings.ToLower(format) {
+	case JSON_FORMAT, TABLE_FORMAT, CSV_FORMAT, TSV_FORMAT, VERTICAL_FORMAT:
+		return true
+	}
+	return false
+}
+
+/* Pass a query parameter on to godbc/n1ql, unless it is a shell setting */
+func SetQueryParam(name, val string) {
+	if name == OUTPUT_FORMAT_PARAM {
+		OUTPUT_FORMAT = strings.ToLower(strings.Trim(val, "\""))
+		return
+	}
+	n1ql.SetQueryParams(name, val)
+}
+
+func UnsetQueryParam(name string) {
+	if name == OUTPUT_FORMAT_PARAM {
+		OUTPUT_FORMAT = JSON_FORMAT
+		return
+	}
+	n1ql.UnsetQueryParams(name)
+}
+
+/* The parts of the query service response that are formatted */
+type queryResponse struct {
+	Signature json.RawMessage   `json:"signature"`
+	Results   []json.RawMessage `json:"results"`
+	Status    string            `json:"status"`
+	Errors    []responseMessage `json:"errors"`
+	Warnings  []responseMessage `json:"warnings"`
+	Metrics   json.RawMessage   `json:"metrics"`
+}
+
+type responseMessage struct {
+	Code int    `json:"code"`
+	Msg  string `json:"msg"`
+}
+
+/* A result row: the values of each column, in column order */
+type row []string
+
+/* WriteResults writes the query service response to w in the current
+   output format. For the table and vertical formats, if more is not nil,
+   it is called after each page of rows and the output stops when it
+   returns false.
+*/
+func WriteResults(w io.Writer, body io.Reader, more func() bool) error {
+	if OUTPUT_FORMAT == JSON_FORMAT || OUTPUT_FORMAT == "" {
+		_, err := io.Copy(w, body)
+		return err
+	}
+
+	buf, err := ioutil.ReadAll(body)
+	if err != nil {
+		return err
+	}
+
+	// Anything that doesn't look like a query response is shown as is.
+	var response queryResponse
+	decoder := json.NewDecoder(bytes.NewReader(buf))
+	decoder.UseNumber()
+	if decoder.Decode(&response) != nil {
+		_, err = w.Write(buf)
+		return err
+	}
+
+	columns, rows := tabulate(response.Signature, response.Results)
+	switch OUTPUT_FORMAT {
+	case CSV_FORMAT:
+		err = writeDelimited(w, columns, rows, ',')
+	case TSV_FORMAT:
+		err = writeDelimited(w, columns, rows, '\t')
+	case VERTICAL_FORMAT:
+		err = writeVertical(w, columns, rows, more)
+	default:
+		err = writeTable(w, columns, rows, more)
+	}
+	if err != nil {
+		return err
+	}
+	return writeSummary(w, &response)
+}
+
+/* Work out the columns from the signature, falling back on the result
+   fields in order of appearance when the signature has a *, and turn
+   each result into a row.
+*/
+func tabulate(signature json.RawMessage, results []json.RawMessage) ([]string, []row) {
+	columns, star := objectFields(signature)
+	objects := true
+	for _, r := range results {
+		fields, _ := objectFields(r)
+		if fields == nil {
+			objects = false
+			break
+		}
+		if star || columns == nil {
+			columns = addFields(columns, fields)
+		}
+	}
+	if !objects || (columns == nil && len(results) > 0) {
+		columns = []string{VALUE_COLUMN}
+	}
+
+	rows := make([]row, len(results))
+	for i, r := range results {
+		rows[i] = make(row, len(columns))
+		if !objects {
+			rows[i][0] = cellValue(r)
+			continue
+		}
+		var fields map[string]json.RawMessage
+		json.Unmarshal(r, &fields)
+		for j, c := range columns {
+			if v, ok := fields[c]; ok {
+				rows[i][j] = cellValue(v)
+			}
+		}
+	}
+	return columns, rows
+}
+
+/* The field names of a JSON object in order of appearance, nil if it is not
+   an object. Also reports whether there is a * field, as in signatures.
+*/
+func objectFields(obj json.RawMessage) ([]string, bool) {
+	decoder := json.NewDecoder(bytes.NewReader(obj))
+	decoder.UseNumber()
+	t, err := decoder.Token()
+	if err != nil || t != json.Delim('{') {
+		return nil, false
+	}
+	fields := []string{}
+	star := false
+	for decoder.More() {
+		t, err = decoder.Token()
+		if err != nil {
+			return nil, false
+		}
+		name, _ := t.(string)
+		var skip json.RawMessage
+		if decoder.Decode(&skip) != nil {
+			return nil, false
+		}
+		if name == "*" {
+			star = true
+		} else {
+			fields = append(fields, name)
+		}
+	}
+	return fields, star
+}
+
+func addFields(columns, fields []string) []string {
+	for _, f := range fields {
+		found := false
+		for _, c := range columns {
+			if c == f {
+				found = true
+				break
+			}
+		}
+		if !found {
+			columns = append(columns, f)
+		}
+	}
+	return columns
+}
+
+/* Strings are shown without quotes, everything else as compact JSON */
+func cellValue(v json.RawMessage) string {
+	var s string
+	if json.Unmarshal(v, &s) == nil {
+		return s
+	}
+	var compact bytes.Buffer
+	if json.Compact(&compact, v) != nil {
+		return string(v)
+	}
+	return compact.String()
+}
+
+/* Control characters would break the alignment */
+func printable(s string) string {
+	return strings.NewReplacer("\n", "\\n", "\r", "\\r", "\t", "\\t").Replace(s)
+}
+
+func writeTable(w io.Writer, columns []string, rows []row, more func() bool) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	page := len(rows)
+	if more != nil {
+		page = PAGE_ROWS
+	}
+	for start := 0; start < len(rows); start += page {
+		end := start + page
+		if end > len(rows) {
+			end = len(rows)
+		}
+		if start > 0 && !more() {
+			return nil
+		}
+		err := writeTablePage(w, columns, rows[start:end])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeTablePage(w io.Writer, columns []string, rows []row) error {
+	widths := make([]int, len(columns))
+	for i, c := range columns {
+		widths[i] = utf8.RuneCountInString(printable(c))
+	}
+	for _, r := range rows {
+		for i, v := range r {
+			if l := utf8.RuneCountInString(printable(v)); l > widths[i] {
+				widths[i] = l
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	separator := func() {
+		for _, l := range widths {
+			buf.WriteString("+" + strings.Repeat("-", l+2))
+		}
+		buf.WriteString("+\n")
+	}
+	line := func(values []string) {
+		for i, v := range values {
+			v = printable(v)
+			buf.WriteString("| " + v + strings.Repeat(" ", widths[i]-utf8.RuneCountInString(v)) + " ")
+		}
+		buf.WriteString("|\n")
+	}
+
+	separator()
+	line(columns)
+	separator()
+	for _, r := range rows {
+		line(r)
+	}
+	separator()
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func writeDelimited(w io.Writer, columns []string, rows []row, comma rune) error {
+	writer := csv.NewWriter(w)
+	writer.Comma = comma
+	if len(rows) > 0 {
+		writer.Write(columns)
+	}
+	for _, r := range rows {
+		writer.Write(r)
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func writeVertical(w io.Writer, columns []string, rows []row, more func() bool) error {
+	width := 0
+	for _, c := range columns {
+		if l := utf8.RuneCountInString(c); l > width {
+			width = l
+		}
+	}
+	for i, r := range rows {
+		if more != nil && i > 0 && i%PAGE_ROWS == 0 && !more() {
+			return nil
+		}
+		var buf bytes.Buffer
+		buf.WriteString(fmt.Sprintf("%s %d. row %s\n", strings.Repeat("*", 27), i+1, strings.Repeat("*", 27)))
+		for j, c := range columns {
+			buf.WriteString(strings.Repeat(" ", width-utf8.RuneCountInString(c)) + c + ": " + r[j] + "\n")
+		}
+		_, err := w.Write(buf.Bytes())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/* The metrics shown, in order */
+var summaryMetrics = []string{"resultCount", "resultSize", "mutationCount", "sortCount",
+	"errorCount", "warningCount", "elapsedTime", "executionTime"}
+
+/* Errors, warnings and metrics, compactly, below the results */
+func writeSummary(w io.Writer, response *queryResponse) error {
+	var buf bytes.Buffer
+	for _, e := range response.Errors {
+		buf.WriteString(fmt.Sprintf("Error %d: %s\n", e.Code, e.Msg))
+	}
+	for _, e := range response.Warnings {
+		buf.WriteString(fmt.Sprintf("Warning %d: %s\n", e.Code, e.Msg))
+	}
+
+	var metrics map[string]interface{}
+	json.Unmarshal(response.Metrics, &metrics)
+	summary := []string{}
+	if response.Status != "" {
+		summary = append(summary, response.Status)
+	}
+	for _, m := range summaryMetrics {
+		if v, ok := metrics[m]; ok {
+			summary = append(summary, fmt.Sprintf("%s: %v", m, v))
+		}
+	}
+	if len(summary) > 0 {
+		buf.WriteString(strings.Join(summary, " | ") + "\n")
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+/* Check the value of the output format before setting it */
+func checkOutputFormat(vble, value_ip string) (int, string) {
+	if vble != OUTPUT_FORMAT_PARAM {
+		return 0, ""
+	}
+	v, err_code, err_str := Resolve(value_ip)
+	if err_code != 0 {
+		return err_code, err_str
+	}
+	format, ok := v.Actual().(string)
+	if !ok || !ValidOutputFormat(format) {
+		return errors.OUTPUT_FORMAT, ""
+	}
+	return 0, ""
+}
@@ -0,0 +1,141 @@
+//  Copyright (c) 2020 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/couchbase/query/errors"
+)
+
+const testResponse = `{
+	"requestID": "4b8a1e2c",
+	"signature": {"name": "json", "*": "*"},
+	"results": [
+	{"name": "ale", "abv": 5.2, "tags": ["pale"]},
+	{"name": "stout", "abv": 7, "brewery": "Ålesund"}
+	],
+	"status": "success",
+	"warnings": [{"code": 1080, "msg": "Timeout is too short"}],
+	"metrics": {"elapsedTime": "3.1ms", "executionTime": "2.9ms", "resultCount": 2, "resultSize": 120}
+}`
+
+func formatResponse(t *testing.T, format string, response string, more func() bool) string {
+	errCode, errStr := PushOrSet([]string{"-" + OUTPUT_FORMAT_PARAM, format}, true)
+	if errCode != 0 {
+		t.Fatalf("%s", HandleError(errCode, errStr))
+	}
+	var b bytes.Buffer
+	err := WriteResults(&b, strings.NewReader(response), more)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return b.String()
+}
+
+func TestOutputFormat(t *testing.T) {
+	defer PopValue_Helper(true, QueryParam, OUTPUT_FORMAT_PARAM)
+	defer UnsetQueryParam(OUTPUT_FORMAT_PARAM)
+
+	table := "+-------+-----+----------+---------+\n" +
+		"| name  | abv | tags     | brewery |\n" +
+		"+-------+-----+----------+---------+\n" +
+		"| ale   | 5.2 | [\"pale\"] |         |\n" +
+		"| stout | 7   |          | Ålesund |\n" +
+		"+-------+-----+----------+---------+\n" +
+		"Warning 1080: Timeout is too short\n" +
+		"success | resultCount: 2 | resultSize: 120 | elapsedTime: 3.1ms | executionTime: 2.9ms\n"
+	if out := formatResponse(t, "table", testResponse, nil); out != table {
+		t.Errorf("Unexpected table output:\n%s", out)
+	}
+
+	csv := "name,abv,tags,brewery\n" +
+		"ale,5.2,\"[\"\"pale\"\"]\",\n" +
+		"stout,7,,Ålesund\n"
+	if out := formatResponse(t, "csv", testResponse, nil); !strings.HasPrefix(out, csv) {
+		t.Errorf("Unexpected csv output:\n%s", out)
+	}
+
+	tsv := "name\tabv\ttags\tbrewery\n"
+	if out := formatResponse(t, "TSV", testResponse, nil); !strings.HasPrefix(out, tsv) {
+		t.Errorf("Unexpected tsv output:\n%s", out)
+	}
+
+	vertical := "*************************** 1. row ***************************\n" +
+		"   name: ale\n" +
+		"    abv: 5.2\n"
+	if out := formatResponse(t, "vertical", testResponse, nil); !strings.HasPrefix(out, vertical) {
+		t.Errorf("Unexpected vertical output:\n%s", out)
+	}
+
+	if out := formatResponse(t, "json", testResponse, nil); out != testResponse {
+		t.Errorf("Expected the response unchanged, got:\n%s", out)
+	}
+
+	errCode, _ := PushOrSet([]string{"-" + OUTPUT_FORMAT_PARAM, "xml"}, true)
+	if errCode != errors.OUTPUT_FORMAT {
+		t.Errorf("Expected invalid output format error, got %v", errCode)
+	}
+	if OUTPUT_FORMAT != JSON_FORMAT {
+		t.Errorf("Expected output format to remain json, got %v", OUTPUT_FORMAT)
+	}
+}
+
+func TestOutputFormatRaw(t *testing.T) {
+	defer PopValue_Helper(true, QueryParam, OUTPUT_FORMAT_PARAM)
+	defer UnsetQueryParam(OUTPUT_FORMAT_PARAM)
+
+	response := `{"signature": "json", "results": [1, "two", {"three": 3}], "status": "success"}`
+	table := "+-------------+\n" +
+		"| value       |\n" +
+		"+-------------+\n" +
+		"| 1           |\n" +
+		"| two         |\n" +
+		"| {\"three\":3} |\n" +
+		"+-------------+\n" +
+		"success\n"
+	if out := formatResponse(t, "table", response, nil); out != table {
+		t.Errorf("Unexpected table output:\n%s", out)
+	}
+
+	// anything that isn't a query response is left alone
+	if out := formatResponse(t, "table", "not json", nil); out != "not json" {
+		t.Errorf("Expected the response unchanged, got:\n%s", out)
+	}
+}
+
+func TestOutputFormatPaging(t *testing.T) {
+	defer PopValue_Helper(true, QueryParam, OUTPUT_FORMAT_PARAM)
+	defer UnsetQueryParam(OUTPUT_FORMAT_PARAM)
+
+	results := make([]string, 2*PAGE_ROWS+1)
+	for i := range results {
+		results[i] = "{\"a\": 1}"
+	}
+	response := `{"signature": {"a": "number"}, "results": [` + strings.Join(results, ",") + `]}`
+
+	pages := 0
+	out := formatResponse(t, "table", response, func() bool {
+		pages++
+		return true
+	})
+	if pages != 2 || strings.Count(out, "| a ") != 3 {
+		t.Errorf("Expected 3 pages, each with a header, got %v prompts:\n%s", pages, out)
+	}
+
+	out = formatResponse(t, "table", response, func() bool {
+		return false
+	})
+	if strings.Count(out, "| 1 ") != PAGE_ROWS {
+		t.Errorf("Expected output to stop after the first page:\n%s", out)
+	}
+}
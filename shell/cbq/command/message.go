@@ -54,6 +54,7 @@ const (
 	EXITONERR    = "\n Exiting on first error encountered.\n"
 	HISTORYMSG   = "\n Path to history file for the shell"
 	NOCONNMSG    = "\n Couchbase query shell not connected to any endpoint. Use \\CONNECT command to connect.\n"
+	MOREMSG      = "-- More -- (Enter to continue, q to stop) "
 
 	//Messages for each command
 
@@ -149,7 +150,8 @@ const (
 
 	DSET = "Set the value of the given parameter to the input value. parameter is a prefixed name " +
 		"(-creds, -$rate, $user, histfile).\nIf no arguments are given, list all the existing parameters.\n" +
-		"\tExample : \n\t        \\SET -$r 9.5 ;\n\t        \\SET $Val -$r ;\n"
+		"-output_format sets how query results are shown : json (default), table, csv, tsv or vertical.\n" +
+		"\tExample : \n\t        \\SET -$r 9.5 ;\n\t        \\SET $Val -$r ;\n\t        \\SET -output_format table ;\n"
 
 	DSOURCE = "Load input file into shell.\n\tExample : \n\t \\SOURCE temp1.txt ;\n"
 
@@ -113,7 +113,7 @@ func (this *Pop) ExecCommand(args []string) (int, string) {
 
 			if ok {
 				if QueryParam[vble].Len() == 0 {
-					n1ql.UnsetQueryParams(vble)
+					UnsetQueryParam(vble)
 				} else {
 					err_code, err_str := setNewParamPop(vble, st_val)
 					if err_code != 0 {
@@ -122,7 +122,7 @@ func (this *Pop) ExecCommand(args []string) (int, string) {
 				}
 
 			} else {
-				n1ql.UnsetQueryParams(vble)
+				UnsetQueryParam(vble)
 			}
 
 		} else if strings.HasPrefix(args[0], "$") {
@@ -247,7 +247,7 @@ func Popparam_Helper(param map[string]*Stack, isrestp bool, isnamep bool) (int,
 			if isnamep == true {
 				name = "$" + name
 			}
-			n1ql.UnsetQueryParams(name)
+			UnsetQueryParam(name)
 		}
 
 		if err_code != 0 {
@@ -296,7 +296,7 @@ func setNewParamPop(name string, paramst *Stack) (int, string) {
 		}
 		nval = string(ac)
 	}
-	n1ql.SetQueryParams(name, nval)
+	SetQueryParam(name, nval)
 	return 0, ""
 }
 
@@ -13,7 +13,6 @@ import (
 	"encoding/json"
 	"io"
 
-	"github.com/couchbase/godbc/n1ql"
 	"github.com/couchbase/query/errors"
 	"github.com/couchbase/query/value"
 )
@@ -144,7 +143,7 @@ func Pushparam_Helper(param map[string]*Stack, isrestp bool, isnamep bool) (int,
 					val = string(ac)
 				}
 			}
-			n1ql.SetQueryParams(name, val)
+			SetQueryParam(name, val)
 		}
 	}
 	return 0, ""
@@ -73,7 +73,7 @@ func (this *Unset) ExecCommand(args []string) (int, string) {
 			if err_code != 0 {
 				return err_code, err_str
 			}
-			n1ql.UnsetQueryParams(vble)
+			UnsetQueryParam(vble)
 
 		} else if strings.HasPrefix(args[0], "$") {
 			// For User defined session variables
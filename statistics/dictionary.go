@@ -0,0 +1,250 @@
+//  Copyright (c) 2019 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package statistics
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/parser/n1ql"
+	"github.com/couchbase/query/value"
+)
+
+// The DefaultStatUpdater is the Dictionary of the statistics it has gathered
+
+func (this *DefaultStatUpdater) KeyspaceInfo(keyspace string) *datastore.KeyspaceInfo {
+	this.RLock()
+	defer this.RUnlock()
+
+	stats, ok := this.keyspaces[keyspace]
+	if !ok {
+		return nil
+	}
+	return stats.info
+}
+
+func (this *DefaultStatUpdater) Histogram(keyspace, key string) *datastore.Histogram {
+	this.RLock()
+	defer this.RUnlock()
+
+	stats, ok := this.keyspaces[keyspace]
+	if !ok {
+		return nil
+	}
+	return stats.histograms[key]
+}
+
+func (this *DefaultStatUpdater) Keyspaces() []string {
+	this.RLock()
+	defer this.RUnlock()
+
+	rv := make([]string, 0, len(this.keyspaces))
+	for name, _ := range this.keyspaces {
+		rv = append(rv, name)
+	}
+	sort.Strings(rv)
+	return rv
+}
+
+func (this *DefaultStatUpdater) Histograms(keyspace string) []*datastore.Histogram {
+	this.RLock()
+	defer this.RUnlock()
+
+	stats, ok := this.keyspaces[keyspace]
+	if !ok {
+		return nil
+	}
+
+	keys := make([]string, 0, len(stats.histograms))
+	for key, _ := range stats.histograms {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	rv := make([]*datastore.Histogram, 0, len(keys))
+	for _, key := range keys {
+		rv = append(rv, stats.histograms[key])
+	}
+	return rv
+}
+
+// Persisted form of the statistics
+type keyspaceDefinition struct {
+	Keyspace   string                 `json:"keyspace"`
+	DocCount   int64                  `json:"docCount"`
+	AvgDocSize int64                  `json:"avgDocSize"`
+	Histograms []*histogramDefinition `json:"histograms"`
+}
+
+type histogramDefinition struct {
+	Key         string           `json:"key"`
+	DocCount    int64            `json:"docCount"`
+	SampleSize  int64            `json:"sampleSize"`
+	Resolution  float32          `json:"resolution"`
+	Fdistincts  float64          `json:"fdistincts"`
+	AvgArrayLen float32          `json:"avgArrayLen,omitempty"`
+	Distrib     []*binDefinition `json:"distribution"`
+	Ovrflow     []*binDefinition `json:"overflow"`
+}
+
+// MISSING has no JSON representation: it is left out
+type binDefinition struct {
+	Size     float64         `json:"size"`
+	Distinct float64         `json:"distinct,omitempty"`
+	Val      json.RawMessage `json:"val,omitempty"`
+}
+
+func newBinDefinition(size, distinct float64, val value.Value) (*binDefinition, error) {
+	rv := &binDefinition{Size: size, Distinct: distinct}
+	if val.Type() != value.MISSING {
+		bytes, err := val.MarshalJSON()
+		if err != nil {
+			return nil, err
+		}
+		rv.Val = bytes
+	}
+	return rv, nil
+}
+
+func (this *binDefinition) value() value.Value {
+	if len(this.Val) == 0 {
+		return value.MISSING_VALUE
+	}
+	return value.NewValue([]byte(this.Val))
+}
+
+func (this *DefaultStatUpdater) load() errors.Error {
+	if this.path == "" {
+		return nil
+	}
+
+	bytes, er := ioutil.ReadFile(this.path)
+	if er != nil {
+		if os.IsNotExist(er) {
+			return nil
+		}
+		return errors.NewUpdateStatisticsError("Cannot read statistics: " + er.Error())
+	}
+
+	var defs []*keyspaceDefinition
+	er = json.Unmarshal(bytes, &defs)
+	if er != nil {
+		return errors.NewUpdateStatisticsError("Invalid statistics " + this.path + ": " + er.Error())
+	}
+
+	for _, def := range defs {
+		stats := &keyspaceStats{
+			info:       &datastore.KeyspaceInfo{DocCount: def.DocCount, AvgDocSize: def.AvgDocSize},
+			histograms: make(map[string]*datastore.Histogram, len(def.Histograms)),
+		}
+		for _, hdef := range def.Histograms {
+			key, er := n1ql.ParseExpression(hdef.Key)
+			if er != nil {
+				return errors.NewUpdateStatisticsError("Invalid statistics key " + hdef.Key + ": " + er.Error())
+			}
+
+			distrib := make(datastore.DistBins, 0, len(hdef.Distrib))
+			for _, bin := range hdef.Distrib {
+				distrib = append(distrib, datastore.NewDistBin(bin.Size, bin.Distinct, bin.value()))
+			}
+			ovrflow := make(datastore.OverflowBins, 0, len(hdef.Ovrflow))
+			for _, bin := range hdef.Ovrflow {
+				ovrflow = append(ovrflow, datastore.NewOverflowBin(bin.Size, bin.value()))
+			}
+
+			hist := &datastore.Histogram{}
+			hist.SetHistogram(datastore.HISTOGRAM_VERSION, def.Keyspace, key, hdef.DocCount, hdef.SampleSize,
+				hdef.Resolution, hdef.Fdistincts, hdef.AvgArrayLen, distrib, ovrflow)
+			stats.histograms[key.String()] = hist
+		}
+		this.keyspaces[def.Keyspace] = stats
+	}
+	return nil
+}
+
+// save persists the statistics, it must be called with the updater locked
+func (this *DefaultStatUpdater) save() errors.Error {
+	if this.path == "" {
+		return nil
+	}
+
+	if len(this.keyspaces) == 0 {
+		if er := os.Remove(this.path); er != nil && !os.IsNotExist(er) {
+			return errors.NewUpdateStatisticsError("Cannot remove statistics: " + er.Error())
+		}
+		return nil
+	}
+
+	names := make([]string, 0, len(this.keyspaces))
+	for name, _ := range this.keyspaces {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	defs := make([]*keyspaceDefinition, 0, len(names))
+	for _, name := range names {
+		stats := this.keyspaces[name]
+		def := &keyspaceDefinition{
+			Keyspace:   name,
+			DocCount:   stats.info.DocCount,
+			AvgDocSize: stats.info.AvgDocSize,
+			Histograms: make([]*histogramDefinition, 0, len(stats.histograms)),
+		}
+
+		keys := make([]string, 0, len(stats.histograms))
+		for key, _ := range stats.histograms {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			hist := stats.histograms[key]
+			hdef := &histogramDefinition{
+				Key:         key,
+				DocCount:    hist.DocCount(),
+				SampleSize:  hist.SampleSize(),
+				Resolution:  hist.Resolution(),
+				Fdistincts:  hist.Fdistincts(),
+				AvgArrayLen: hist.AvgArrayLen(),
+				Distrib:     make([]*binDefinition, 0, len(hist.Distrib())),
+				Ovrflow:     make([]*binDefinition, 0, len(hist.Ovrflow())),
+			}
+			for _, bin := range hist.Distrib() {
+				bdef, er := newBinDefinition(bin.Size(), bin.Distinct(), bin.Max())
+				if er != nil {
+					return errors.NewUpdateStatisticsError("Cannot save statistics: " + er.Error())
+				}
+				hdef.Distrib = append(hdef.Distrib, bdef)
+			}
+			for _, bin := range hist.Ovrflow() {
+				bdef, er := newBinDefinition(bin.Size(), 0.0, bin.Val())
+				if er != nil {
+					return errors.NewUpdateStatisticsError("Cannot save statistics: " + er.Error())
+				}
+				hdef.Ovrflow = append(hdef.Ovrflow, bdef)
+			}
+			def.Histograms = append(def.Histograms, hdef)
+		}
+		defs = append(defs, def)
+	}
+
+	bytes, er := json.MarshalIndent(defs, "", "    ")
+	if er == nil {
+		er = ioutil.WriteFile(this.path, bytes, 0666)
+	}
+	if er != nil {
+		return errors.NewUpdateStatisticsError("Cannot save statistics: " + er.Error())
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+//  Copyright (c) 2019 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package statistics
+
+import (
+	"math"
+	"sort"
+
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/value"
+)
+
+/*
+buildHistogram summarizes the sampled values of a key.
+
+Values are sorted and split into distribution bins each holding about
+resolution percent of the values. Values occurring at least that often
+get an overflow bin of their own and are left out of the distribution
+bins, so that a frequent value does not distort the bin it falls in.
+
+Bin sizes are fractions of the sampled values. The number of distinct
+values, both per bin and overall, is stored as a fraction of the number
+of documents in the keyspace: distinct values are not scaled up from the
+sample, on the assumption that the sample sees most of them.
+*/
+func buildHistogram(keyspace string, key expression.Expression, docCount, sampleSize int64,
+	resolution float64, vals value.Values, avgArrayLen float64) *datastore.Histogram {
+
+	sort.SliceStable(vals, func(i, j int) bool { return vals[i].Collate(vals[j]) < 0 })
+
+	n := float64(len(vals))
+	docs := float64(docCount)
+	if docs < 1.0 {
+		docs = 1.0
+	}
+	binSize := int(math.Ceil(n * resolution / 100.0))
+	if binSize < 1 {
+		binSize = 1
+	}
+
+	distrib := make(datastore.DistBins, 0, len(vals)/binSize+1)
+	ovrflow := make(datastore.OverflowBins, 0, 4)
+
+	ndv := 0
+	binCount := 0
+	binDistinct := 0
+	var binMax value.Value
+
+	for i := 0; i < len(vals); {
+		j := i + 1
+		for j < len(vals) && vals[j].Collate(vals[i]) == 0 {
+			j++
+		}
+		count := j - i
+		ndv++
+
+		if count >= binSize {
+			ovrflow = append(ovrflow, datastore.NewOverflowBin(float64(count)/n, vals[i]))
+		} else {
+			binCount += count
+			binDistinct++
+			binMax = vals[i]
+			if binCount >= binSize {
+				distrib = append(distrib, datastore.NewDistBin(float64(binCount)/n,
+					float64(binDistinct)/docs, binMax))
+				binCount = 0
+				binDistinct = 0
+			}
+		}
+		i = j
+	}
+	if binCount > 0 {
+		distrib = append(distrib, datastore.NewDistBin(float64(binCount)/n,
+			float64(binDistinct)/docs, binMax))
+	}
+
+	hist := &datastore.Histogram{}
+	hist.SetHistogram(datastore.HISTOGRAM_VERSION, keyspace, key, docCount, sampleSize,
+		float32(resolution), float64(ndv)/docs, float32(avgArrayLen), distrib, ovrflow)
+	return hist
+}
@@ -0,0 +1,375 @@
+//  Copyright (c) 2019 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+/*
+
+Package statistics provides a StatUpdater for datastores that do not
+have one of their own, such as the file and mock datastores.
+
+The statistics gathered by UPDATE STATISTICS are kept in memory, and
+optionally persisted to a file, and are made available to the optimizer
+as the statistics Dictionary.
+
+*/
+package statistics
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"sync"
+
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/value"
+)
+
+const (
+	_DEF_RESOLUTION = 1.0 // percentage of the sampled values in a distribution bin
+	_MIN_RESOLUTION = 0.02
+	_MAX_RESOLUTION = 5.0
+	_FETCH_BATCH    = 256
+)
+
+type keyspaceStats struct {
+	info       *datastore.KeyspaceInfo
+	histograms map[string]*datastore.Histogram
+}
+
+type DefaultStatUpdater struct {
+	sync.RWMutex
+	store     datastore.Datastore
+	path      string // where statistics are persisted, if anywhere
+	keyspaces map[string]*keyspaceStats
+}
+
+// path is the file statistics are persisted to, or "" to keep them in memory only
+func NewDefaultStatUpdater(store datastore.Datastore, path string) (*DefaultStatUpdater, errors.Error) {
+	rv := &DefaultStatUpdater{
+		store:     store,
+		path:      path,
+		keyspaces: make(map[string]*keyspaceStats),
+	}
+
+	err := rv.load()
+	if err != nil {
+		return nil, err
+	}
+	return rv, nil
+}
+
+func (this *DefaultStatUpdater) Name() datastore.StatUpdaterType {
+	return datastore.UPDSTAT_DEFAULT
+}
+
+/*
+Gather statistics on the terms. Supported options are sample_size, the number of documents to
+sample, all of them by default, and resolution, the percentage of the
+sampled values in each distribution bin.
+*/
+func (this *DefaultStatUpdater) UpdateStatistics(ks datastore.Keyspace, terms expression.Expressions,
+	with value.Value, conn *datastore.ValueConnection, exContext interface{}) {
+
+	defer close(conn.ValueChannel())
+
+	sampleSize, resolution, err := getOptions(with)
+	if err != nil {
+		conn.Error(err)
+		return
+	}
+
+	// statistics are kept by the fully qualified keyspace path, so that
+	// keyspaces of the same name in different namespaces or scopes don't mix
+	name := ks.QualifiedName()
+	terms, err = qualifyTerms(name, terms)
+	if err != nil {
+		conn.Error(err)
+		return
+	}
+
+	context, ok := exContext.(datastore.QueryContext)
+	if !ok {
+		context = datastore.NULL_QUERY_CONTEXT
+	}
+
+	docCount, err := ks.Count(context)
+	if err != nil {
+		conn.Error(errors.NewUpdateStatisticsError(err.Error()))
+		return
+	}
+
+	docs, err := sampleDocuments(ks, sampleSize, conn, context)
+	if err != nil {
+		conn.Error(err)
+		return
+	}
+	if docs == nil {
+		// stopped
+		return
+	}
+
+	info := &datastore.KeyspaceInfo{DocCount: docCount}
+	if len(docs) > 0 {
+		size := 0
+		for _, doc := range docs {
+			bytes, _ := doc.MarshalJSON()
+			size += len(bytes)
+		}
+		info.AvgDocSize = int64(size / len(docs))
+	}
+
+	histograms := make(map[string]*datastore.Histogram, len(terms))
+	for _, term := range terms {
+		vals, avgArrayLen, err := evaluateTerm(name, term, docs)
+		if err != nil {
+			conn.Error(errors.NewUpdateStatisticsError(fmt.Sprintf("Error evaluating %v: %v",
+				term.String(), err)))
+			return
+		}
+		histograms[term.String()] = buildHistogram(name, term, docCount, int64(len(docs)),
+			resolution, vals, avgArrayLen)
+	}
+
+	this.Lock()
+	defer this.Unlock()
+
+	stats, ok := this.keyspaces[name]
+	if !ok {
+		stats = &keyspaceStats{histograms: make(map[string]*datastore.Histogram, len(histograms))}
+		this.keyspaces[name] = stats
+	}
+	stats.info = info
+	for key, hist := range histograms {
+		stats.histograms[key] = hist
+	}
+
+	err = this.save()
+	if err != nil {
+		conn.Error(err)
+	}
+}
+
+func (this *DefaultStatUpdater) DeleteStatistics(ks datastore.Keyspace, terms expression.Expressions,
+	conn *datastore.ValueConnection, exContext interface{}) {
+
+	defer close(conn.ValueChannel())
+
+	name := ks.QualifiedName()
+	terms, err := qualifyTerms(name, terms)
+	if err != nil {
+		conn.Error(err)
+		return
+	}
+
+	this.Lock()
+	defer this.Unlock()
+
+	stats, ok := this.keyspaces[name]
+	if !ok {
+		return
+	}
+
+	if len(terms) == 0 {
+		delete(this.keyspaces, name)
+	} else {
+		for _, term := range terms {
+			delete(stats.histograms, term.String())
+		}
+	}
+
+	err = this.save()
+	if err != nil {
+		conn.Error(err)
+	}
+}
+
+// Terms come relative to the document, as index keys do: the statistics
+// are kept on the terms qualified by the keyspace path, as the optimizer
+// looks them up
+func qualifyTerms(name string, terms expression.Expressions) (expression.Expressions, errors.Error) {
+	formalizer := expression.NewSelfFormalizer(name, nil)
+	formalizer.SetIndexScope()
+	defer formalizer.ClearIndexScope()
+
+	rv := make(expression.Expressions, len(terms))
+	for i, term := range terms {
+		expr, err := formalizer.Map(term.Copy())
+		if err != nil {
+			return nil, errors.NewUpdateStatisticsError(fmt.Sprintf("Invalid term %v: %v", term, err))
+		}
+		rv[i] = expr
+	}
+	return rv, nil
+}
+
+func getOptions(with value.Value) (sampleSize int, resolution float64, err errors.Error) {
+	resolution = _DEF_RESOLUTION
+	if with == nil {
+		return
+	}
+
+	if with.Type() != value.OBJECT {
+		err = errors.NewUpdateStatisticsError(fmt.Sprintf("Options must be an object, not %v", with))
+		return
+	}
+
+	for name, _ := range with.Fields() {
+		val, _ := with.Field(name)
+		switch strings.ToLower(name) {
+		case "sample_size":
+			size, ok := val.Actual().(float64)
+			if !ok || size < 0 || size != math.Trunc(size) {
+				err = errors.NewUpdateStatisticsError(fmt.Sprintf("Invalid sample_size %v", val))
+				return
+			}
+			sampleSize = int(size)
+		case "resolution":
+			res, ok := val.Actual().(float64)
+			if !ok || res < _MIN_RESOLUTION || res > _MAX_RESOLUTION {
+				err = errors.NewUpdateStatisticsError(fmt.Sprintf(
+					"Invalid resolution %v, it must be between %v and %v",
+					val, _MIN_RESOLUTION, _MAX_RESOLUTION))
+				return
+			}
+			resolution = res
+		default:
+			err = errors.NewUpdateStatisticsError(fmt.Sprintf("Unrecognized option '%v'", name))
+			return
+		}
+	}
+	return
+}
+
+// sampleDocuments returns sampleSize random documents of the keyspace, or all
+// of them if sampleSize is 0, or nil if the request was stopped
+func sampleDocuments(ks datastore.Keyspace, sampleSize int, conn *datastore.ValueConnection,
+	context datastore.QueryContext) ([]value.AnnotatedValue, errors.Error) {
+
+	keys, err := primaryKeys(ks, conn)
+	if err != nil || keys == nil {
+		return nil, err
+	}
+
+	if sampleSize > 0 && sampleSize < len(keys) {
+		perm := rand.Perm(len(keys))
+		sample := make([]string, sampleSize)
+		for i := range sample {
+			sample[i] = keys[perm[i]]
+		}
+		keys = sample
+	}
+
+	docs := make([]value.AnnotatedValue, 0, len(keys))
+	for len(keys) > 0 {
+		select {
+		case <-conn.StopChannel():
+			return nil, nil
+		default:
+		}
+
+		batch := keys
+		if len(batch) > _FETCH_BATCH {
+			batch = batch[:_FETCH_BATCH]
+		}
+		keys = keys[len(batch):]
+
+		fetched := make(map[string]value.AnnotatedValue, len(batch))
+		errs := ks.Fetch(batch, fetched, context, nil)
+		if len(errs) > 0 {
+			return nil, errs[0]
+		}
+		for _, key := range batch {
+			if doc, ok := fetched[key]; ok && doc != nil {
+				docs = append(docs, doc)
+			}
+		}
+	}
+	return docs, nil
+}
+
+// primaryKeys returns the keys of all the documents in the keyspace,
+// as given by its first online primary index
+func primaryKeys(ks datastore.Keyspace, conn *datastore.ValueConnection) ([]string, errors.Error) {
+	indexers, err := ks.Indexers()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, indexer := range indexers {
+		primaries, err := indexer.PrimaryIndexes()
+		if err != nil {
+			continue
+		}
+
+		for _, primary := range primaries {
+			state, _, err := primary.State()
+			if err != nil || state != datastore.ONLINE {
+				continue
+			}
+
+			iconn := datastore.NewIndexConnection(datastore.NULL_CONTEXT)
+			go primary.ScanEntries("update_statistics", math.MaxInt64, datastore.UNBOUNDED, nil, iconn)
+
+			keys := make([]string, 0, 1024)
+			for {
+				select {
+				case <-conn.StopChannel():
+					iconn.SendStop()
+					return nil, nil
+				default:
+				}
+
+				entry, ok := iconn.Sender().GetEntry()
+				if !ok || entry == nil {
+					break
+				}
+				keys = append(keys, entry.PrimaryKey)
+			}
+			return keys, nil
+		}
+	}
+
+	return nil, errors.NewUpdateStatisticsError("No online primary index on " + ks.Name())
+}
+
+// evaluateTerm returns the values of the term over the documents; each
+// element of an array index key counts as a value of its own
+func evaluateTerm(name string, term expression.Expression, docs []value.AnnotatedValue) (
+	value.Values, float64, error) {
+
+	context := expression.NewIndexContext()
+	vals := make(value.Values, 0, len(docs))
+	arrays := 0
+	elements := 0
+
+	for _, doc := range docs {
+		item := value.NewAnnotatedValue(make(map[string]interface{}, 1))
+		item.SetField(name, doc)
+
+		v, evals, err := term.EvaluateForIndex(item, context)
+		if err != nil {
+			return nil, 0.0, err
+		}
+		if evals == nil {
+			vals = append(vals, v)
+		} else {
+			arrays++
+			elements += len(evals)
+			vals = append(vals, evals...)
+		}
+	}
+
+	avgArrayLen := 0.0
+	if arrays > 0 {
+		avgArrayLen = float64(elements) / float64(arrays)
+	}
+	return vals, avgArrayLen, nil
+}
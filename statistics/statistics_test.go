@@ -0,0 +1,141 @@
+//  Copyright (c) 2019 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package statistics
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/parser/n1ql"
+	"github.com/couchbase/query/value"
+)
+
+func testHistogram(t *testing.T) *datastore.Histogram {
+	key, err := n1ql.ParseExpression("`default:people`.age")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	// 100 values: 30 appears 40 times, 0..59 otherwise once, and a missing age
+	vals := make(value.Values, 0, 100)
+	for i := 0; i < 40; i++ {
+		vals = append(vals, value.NewValue(30))
+	}
+	for i := 0; len(vals) < 99; i++ {
+		if i != 30 {
+			vals = append(vals, value.NewValue(i))
+		}
+	}
+	vals = append(vals, value.MISSING_VALUE)
+
+	return buildHistogram("default:people", key, 200, 100, 5.0, vals, 0.0)
+}
+
+func TestBuildHistogram(t *testing.T) {
+	hist := testHistogram(t)
+
+	if len(hist.Ovrflow()) != 1 || hist.Ovrflow()[0].Size() != 0.4 ||
+		hist.Ovrflow()[0].Val().Collate(value.NewValue(30)) != 0 {
+		t.Errorf("expected 30 as the only overflow value, got %v", hist.Ovrflow())
+	}
+
+	size := hist.Ovrflow()[0].Size()
+	distinct := 0.0
+	for i, bin := range hist.Distrib() {
+		size += bin.Size()
+		distinct += bin.Distinct()
+		if i < len(hist.Distrib())-1 && bin.Size() != 0.05 {
+			t.Errorf("expected bin %v to hold 5 values, got %v", i, bin.Size())
+		}
+	}
+	if math.Abs(size-1.0) > 1e-9 {
+		t.Errorf("expected bins to cover all values, got %v", size)
+	}
+
+	// 60 distinct values and MISSING, out of 200 documents
+	if math.Abs(distinct-60.0/200.0) > 1e-9 || math.Abs(hist.Fdistincts()-61.0/200.0) > 1e-9 {
+		t.Errorf("unexpected distinct values %v, %v", distinct, hist.Fdistincts())
+	}
+
+	first := hist.Distrib()[0].Max()
+	if first.Type() != value.NUMBER || first.Actual() != float64(3) {
+		t.Errorf("expected the first bin to end at 3, got %v", first)
+	}
+}
+
+func TestPersistence(t *testing.T) {
+	dir, err := ioutil.TempDir("", "statistics")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "statistics.json")
+	updater, e := NewDefaultStatUpdater(nil, path)
+	if e != nil {
+		t.Fatalf("unexpected error %v", e)
+	}
+
+	hist := testHistogram(t)
+	updater.keyspaces["default:people"] = &keyspaceStats{
+		info:       &datastore.KeyspaceInfo{DocCount: 200, AvgDocSize: 64},
+		histograms: map[string]*datastore.Histogram{hist.Key().String(): hist},
+	}
+	if e = updater.save(); e != nil {
+		t.Fatalf("unexpected error %v", e)
+	}
+
+	loaded, e := NewDefaultStatUpdater(nil, path)
+	if e != nil {
+		t.Fatalf("unexpected error %v", e)
+	}
+
+	if info := loaded.KeyspaceInfo("default:people"); info == nil || *info != (datastore.KeyspaceInfo{DocCount: 200, AvgDocSize: 64}) {
+		t.Errorf("unexpected keyspace info %v", info)
+	}
+
+	other := loaded.Histogram("default:people", "(`default:people`.`age`)")
+	if other == nil {
+		t.Fatalf("expected histogram on %v, got %v", hist.Key(), loaded.Keyspaces())
+	}
+	if other.Fdistincts() != hist.Fdistincts() || len(other.Distrib()) != len(hist.Distrib()) ||
+		len(other.Ovrflow()) != len(hist.Ovrflow()) {
+		t.Errorf("histogram not preserved")
+	}
+	for i, bin := range other.Distrib() {
+		if bin.Max().Collate(hist.Distrib()[i].Max()) != 0 || bin.Size() != hist.Distrib()[i].Size() {
+			t.Errorf("bin %v not preserved: %v", i, bin.Max())
+		}
+	}
+}
+
+func TestOptions(t *testing.T) {
+	sampleSize, resolution, err := getOptions(value.NewValue(map[string]interface{}{
+		"sample_size": 50, "resolution": 0.5}))
+	if err != nil || sampleSize != 50 || resolution != 0.5 {
+		t.Errorf("unexpected options %v, %v, %v", sampleSize, resolution, err)
+	}
+
+	for _, with := range []map[string]interface{}{
+		{"sample_size": 1.5},
+		{"sample_size": -1},
+		{"resolution": 10},
+		{"bogus": 1},
+	} {
+		_, _, err = getOptions(value.NewValue(with))
+		if err == nil {
+			t.Errorf("expected error for %v", with)
+		}
+	}
+}
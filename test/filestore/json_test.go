@@ -196,6 +196,32 @@ func TestSimpleSelect(t *testing.T) {
 	}
 }
 
+func TestExplainAnalyze(t *testing.T) {
+	qc := start()
+
+	r, _, err := Run(qc, true, "explain analyze select o.id from default:orders o", nil, nil, "json")
+	if err != nil {
+		t.Fatalf("did not expect err %s", err.Error())
+	}
+	if len(r) != 1 {
+		t.Fatalf("expected one plan, got %v", r)
+	}
+
+	explain := r[0].(map[string]interface{})
+	if explain["analyze"] != true || explain["text"] != "select o.id from default:orders o" {
+		t.Errorf("unexpected explain %v", explain)
+	}
+
+	// the scan reports the documents it found
+	fileInfos, _ := ioutil.ReadDir("json/default/orders")
+	plan := explain["plan"].(map[string]interface{})
+	scan := plan["~children"].([]interface{})[0].(map[string]interface{})
+	stats, _ := scan["#stats"].(map[string]interface{})
+	if scan["#operator"] != "PrimaryScan" || stats == nil || stats["#itemsOut"] != float64(len(fileInfos)) {
+		t.Errorf("unexpected scan %v", scan)
+	}
+}
+
 func TestAllCaseFiles(t *testing.T) {
 	qc := start()
 	matches, err := filepath.Glob("json/default/cases/case_*.json")
@@ -13,6 +13,8 @@ import (
 	"fmt"
 	"path/filepath"
 	"testing"
+
+	"github.com/couchbase/query/execution"
 )
 
 /*
@@ -60,6 +62,17 @@ func TestAllCaseFiles(t *testing.T) {
 	}
 }
 
+/*
+Run the case files again, with every operator that can spill to
+temporary files doing so.
+*/
+func TestSpillCaseFiles(t *testing.T) {
+	execution.SetSpillThreshold(1)
+	defer execution.SetSpillThreshold(0)
+
+	TestAllCaseFiles(t)
+}
+
 func TestCleanupData(t *testing.T) {
 	qc := start()
 
@@ -59,6 +59,9 @@ func TestHashJoin(t *testing.T) {
 	// test OUTER HASH JOIN
 	runMatch("case_hashjoin_outer.json", false, true, qc, t)
 
+	// test RIGHT and FULL OUTER HASH JOIN
+	runMatch("case_hashjoin_rightfull.json", false, true, qc, t)
+
 	// test HASH JOIN with index hints
 	runMatch("case_hashjoin_hints.json", false, true, qc, t)
 
@@ -0,0 +1,242 @@
+//  Copyright (c) 2020 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package tracing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/couchbase/query/logging"
+)
+
+// The OTLP exporter posts spans as OTLP/JSON over HTTP, as accepted on
+// the /v1/traces endpoint of an OpenTelemetry collector.
+// Traces are queued and sent in batches by a background goroutine: if the
+// collector can't keep up, traces are dropped rather than holding up requests.
+
+const (
+	_OTLP_SERVICE_NAME = "n1ql"
+	_OTLP_SCOPE_NAME   = "github.com/couchbase/query"
+
+	_OTLP_QUEUE_SIZE     = 1024
+	_OTLP_BATCH_SPANS    = 512
+	_OTLP_FLUSH_INTERVAL = time.Second
+	_OTLP_TIMEOUT        = 10 * time.Second
+
+	_OTLP_STATUS_ERROR = 2
+)
+
+type otlpExporter struct {
+	dropped  int64 // first, for alignment
+	url      string
+	client   *http.Client
+	queue    chan []*Span
+	stop     chan bool
+	stopped  sync.WaitGroup
+	hostname string
+}
+
+func NewOTLPExporter(url string, hostname string) Exporter {
+	rv := &otlpExporter{
+		url:      url,
+		client:   &http.Client{Timeout: _OTLP_TIMEOUT},
+		queue:    make(chan []*Span, _OTLP_QUEUE_SIZE),
+		stop:     make(chan bool),
+		hostname: hostname,
+	}
+	rv.stopped.Add(1)
+	go rv.run()
+	return rv
+}
+
+func (this *otlpExporter) Export(spans []*Span) {
+	select {
+	case this.queue <- spans:
+	default:
+		atomic.AddInt64(&this.dropped, 1)
+	}
+}
+
+// Shutdown sends whatever is queued, and stops the exporter
+func (this *otlpExporter) Shutdown() {
+	close(this.stop)
+	this.stopped.Wait()
+}
+
+func (this *otlpExporter) run() {
+	defer this.stopped.Done()
+
+	ticker := time.NewTicker(_OTLP_FLUSH_INTERVAL)
+	defer ticker.Stop()
+
+	batch := make([]*Span, 0, _OTLP_BATCH_SPANS)
+	for {
+		select {
+		case spans := <-this.queue:
+			batch = append(batch, spans...)
+			if len(batch) < _OTLP_BATCH_SPANS {
+				continue
+			}
+		case <-ticker.C:
+		case <-this.stop:
+			for len(this.queue) > 0 {
+				batch = append(batch, <-this.queue...)
+			}
+			this.send(batch)
+			return
+		}
+		this.send(batch)
+		batch = batch[:0]
+	}
+}
+
+func (this *otlpExporter) send(spans []*Span) {
+	if len(spans) == 0 {
+		return
+	}
+	dropped := atomic.SwapInt64(&this.dropped, 0)
+	if dropped > 0 {
+		logging.Warnf("OTLP exporter: %v traces dropped, queue full", dropped)
+	}
+
+	body, err := json.Marshal(this.encode(spans))
+	if err != nil {
+		logging.Errorf("OTLP exporter: cannot encode spans: %v", err)
+		return
+	}
+	resp, err := this.client.Post(this.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		logging.Errorf("OTLP exporter: cannot send spans to %v: %v", this.url, err)
+		return
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		logging.Errorf("OTLP exporter: spans rejected by %v: %v", this.url, resp.Status)
+	}
+}
+
+// OTLP/JSON encoding
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceId           string          `json:"traceId"`
+	SpanId            string          `json:"spanId"`
+	ParentSpanId      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              SpanKind        `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            *otlpStatus     `json:"status,omitempty"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string                 `json:"key"`
+	Value map[string]interface{} `json:"value"`
+}
+
+func (this *otlpExporter) encode(spans []*Span) *otlpRequest {
+	resource := []otlpAttribute{newOtlpAttribute("service.name", _OTLP_SERVICE_NAME)}
+	if this.hostname != "" {
+		resource = append(resource, newOtlpAttribute("host.name", this.hostname))
+	}
+
+	encoded := make([]otlpSpan, len(spans))
+	for i, span := range spans {
+		s := &encoded[i]
+		s.TraceId = span.TraceId().String()
+		s.SpanId = span.Id().String()
+		if span.ParentId().IsValid() {
+			s.ParentSpanId = span.ParentId().String()
+		}
+		s.Name = span.Name()
+		s.Kind = span.Kind()
+		s.StartTimeUnixNano = strconv.FormatInt(span.StartTime().UnixNano(), 10)
+		s.EndTimeUnixNano = strconv.FormatInt(span.EndTime().UnixNano(), 10)
+		for k, v := range span.Attributes() {
+			s.Attributes = append(s.Attributes, newOtlpAttribute(k, v))
+		}
+		if span.Error() != "" {
+			s.Status = &otlpStatus{Code: _OTLP_STATUS_ERROR, Message: span.Error()}
+		}
+	}
+
+	return &otlpRequest{
+		ResourceSpans: []otlpResourceSpans{
+			otlpResourceSpans{
+				Resource: otlpResource{Attributes: resource},
+				ScopeSpans: []otlpScopeSpans{
+					otlpScopeSpans{
+						Scope: otlpScope{Name: _OTLP_SCOPE_NAME},
+						Spans: encoded,
+					},
+				},
+			},
+		},
+	}
+}
+
+func newOtlpAttribute(key string, val interface{}) otlpAttribute {
+	var value map[string]interface{}
+	switch val := val.(type) {
+	case string:
+		value = map[string]interface{}{"stringValue": val}
+	case bool:
+		value = map[string]interface{}{"boolValue": val}
+	case int:
+		value = map[string]interface{}{"intValue": strconv.FormatInt(int64(val), 10)}
+	case int64:
+		value = map[string]interface{}{"intValue": strconv.FormatInt(val, 10)}
+	case uint64:
+		value = map[string]interface{}{"intValue": strconv.FormatUint(val, 10)}
+	case float64:
+		value = map[string]interface{}{"doubleValue": val}
+	case time.Duration:
+		value = map[string]interface{}{"stringValue": val.String()}
+	default:
+		value = map[string]interface{}{"stringValue": fmt.Sprint(val)}
+	}
+	return otlpAttribute{Key: key, Value: value}
+}
@@ -0,0 +1,343 @@
+//  Copyright (c) 2020 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+// Package tracing records requests as trees of timed spans, and hands them over
+// to an exporter once the request completes.
+// A request joins the trace of its caller when it carries a W3C traceparent header.
+//
+// Tracing is off until an exporter is set: with no exporter, no trace is created,
+// and all the Trace and Span methods are no-ops on nil receivers, so that callers
+// never need to check whether tracing is on.
+package tracing
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+)
+
+type TraceId [16]byte
+type SpanId [8]byte
+
+func (this TraceId) String() string {
+	return hex.EncodeToString(this[:])
+}
+
+func (this TraceId) IsValid() bool {
+	return this != TraceId{}
+}
+
+func (this SpanId) String() string {
+	return hex.EncodeToString(this[:])
+}
+
+func (this SpanId) IsValid() bool {
+	return this != SpanId{}
+}
+
+type SpanKind int
+
+const (
+	SPAN_INTERNAL SpanKind = iota + 1
+	SPAN_SERVER
+	SPAN_CLIENT
+)
+
+// the number of spans a single trace can hold: spans past this are dropped,
+// so that requests with many operators or many datastore calls stay bounded
+const _MAX_SPANS = 1024
+
+const _TRACEPARENT_VERSION = "00"
+const _SAMPLED = 0x01
+
+// Exporters ship the spans of completed traces to their destination.
+// Export is called once per trace and must not block.
+type Exporter interface {
+	Export(spans []*Span)
+	Shutdown()
+}
+
+var exporter struct {
+	sync.RWMutex
+	exporter Exporter
+}
+
+func SetExporter(e Exporter) {
+	exporter.Lock()
+	old := exporter.exporter
+	exporter.exporter = e
+	exporter.Unlock()
+	if old != nil {
+		old.Shutdown()
+	}
+}
+
+func GetExporter() Exporter {
+	exporter.RLock()
+	defer exporter.RUnlock()
+	return exporter.exporter
+}
+
+type Trace struct {
+	sync.Mutex
+	id       TraceId
+	root     *Span
+	spans    []*Span
+	dropped  int
+	finished bool
+}
+
+// NewTrace starts a trace and its root span.
+// The trace continues the caller's if traceparent holds a valid W3C trace context,
+// and is not started at all if the caller has chosen not to sample it, or if no
+// exporter is set.
+func NewTrace(name string, traceparent string) *Trace {
+	if GetExporter() == nil {
+		return nil
+	}
+
+	var parent SpanId
+	rv := &Trace{}
+	traceId, parentId, flags, ok := ParseTraceparent(traceparent)
+	if ok {
+		if flags&_SAMPLED == 0 {
+			return nil
+		}
+		rv.id = traceId
+		parent = parentId
+	} else {
+		rand.Read(rv.id[:])
+	}
+	rv.root = rv.newSpan(name, SPAN_SERVER, parent, time.Now())
+	return rv
+}
+
+// ParseTraceparent decodes a traceparent header, of the form
+// version "-" trace-id "-" parent-id "-" trace-flags
+func ParseTraceparent(traceparent string) (TraceId, SpanId, byte, bool) {
+	var traceId TraceId
+	var spanId SpanId
+	var flags [1]byte
+
+	fields := strings.Split(strings.TrimSpace(traceparent), "-")
+	if len(fields) < 4 || len(fields[0]) != 2 || fields[0] == "ff" {
+		return traceId, spanId, 0, false
+	}
+
+	// later versions may append fields, but not for the version we know
+	if fields[0] == _TRACEPARENT_VERSION && len(fields) != 4 {
+		return traceId, spanId, 0, false
+	}
+	if !decodeHex(traceId[:], fields[1]) || !decodeHex(spanId[:], fields[2]) || !decodeHex(flags[:], fields[3]) ||
+		!traceId.IsValid() || !spanId.IsValid() {
+		return traceId, spanId, 0, false
+	}
+	return traceId, spanId, flags[0], true
+}
+
+// only lower case hex digits are allowed
+func decodeHex(dest []byte, s string) bool {
+	if len(s) != 2*len(dest) || strings.ToLower(s) != s {
+		return false
+	}
+	_, err := hex.Decode(dest, []byte(s))
+	return err == nil
+}
+
+func (this *Trace) Id() TraceId {
+	return this.id
+}
+
+func (this *Trace) Root() *Span {
+	if this == nil {
+		return nil
+	}
+	return this.root
+}
+
+// Traceparent returns the trace context that identifies the root span to downstream services
+func (this *Trace) Traceparent() string {
+	if this == nil {
+		return ""
+	}
+	return _TRACEPARENT_VERSION + "-" + this.id.String() + "-" + this.root.id.String() + "-01"
+}
+
+// StartSpan starts a span as a child of parent, or of the root span if parent is nil
+func (this *Trace) StartSpan(name string, parent *Span) *Span {
+	return this.AddSpan(name, parent, time.Now(), time.Time{})
+}
+
+// AddSpan records a span that has already started, and possibly ended
+func (this *Trace) AddSpan(name string, parent *Span, start time.Time, end time.Time) *Span {
+	if this == nil {
+		return nil
+	}
+	if parent == nil {
+		parent = this.root
+	}
+	span := this.newSpan(name, SPAN_INTERNAL, parent.id, start)
+	if span != nil && !end.IsZero() {
+		span.Lock()
+		span.end = end
+		span.Unlock()
+	}
+	return span
+}
+
+func (this *Trace) newSpan(name string, kind SpanKind, parent SpanId, start time.Time) *Span {
+	this.Lock()
+	defer this.Unlock()
+	if this.finished {
+		return nil
+	}
+	if len(this.spans) >= _MAX_SPANS {
+		this.dropped++
+		return nil
+	}
+	rv := &Span{trace: this, name: name, kind: kind, parentId: parent, start: start}
+	rand.Read(rv.id[:])
+	this.spans = append(this.spans, rv)
+	return rv
+}
+
+// Finish ends the root span, and any span still open, and exports the trace
+func (this *Trace) Finish() {
+	if this == nil {
+		return
+	}
+
+	e := GetExporter()
+	now := time.Now()
+	this.Lock()
+	if this.finished {
+		this.Unlock()
+		return
+	}
+	if this.dropped > 0 {
+		this.root.SetAttribute("droppedSpans", this.dropped)
+	}
+	this.finished = true
+	spans := this.spans
+	this.spans = nil
+	this.Unlock()
+
+	// spans are frozen from now on, as the exporter may be reading them
+	for _, span := range spans {
+		span.Lock()
+		if span.end.IsZero() {
+			span.end = now
+		}
+		span.frozen = true
+		span.Unlock()
+	}
+	if e != nil {
+		e.Export(spans)
+	}
+}
+
+type Span struct {
+	sync.Mutex
+	trace      *Trace
+	id         SpanId
+	parentId   SpanId
+	name       string
+	kind       SpanKind
+	start      time.Time
+	end        time.Time
+	attributes map[string]interface{}
+	err        string
+	frozen     bool
+}
+
+// StartChild starts a span as a child of this one
+func (this *Span) StartChild(name string, kind SpanKind) *Span {
+	if this == nil {
+		return nil
+	}
+	return this.trace.newSpan(name, kind, this.id, time.Now())
+}
+
+// End marks the end of the span; only the first call has any effect
+func (this *Span) End() {
+	if this == nil {
+		return
+	}
+	this.Lock()
+	if this.end.IsZero() {
+		this.end = time.Now()
+	}
+	this.Unlock()
+}
+
+func (this *Span) SetAttribute(name string, val interface{}) {
+	if this == nil {
+		return
+	}
+	this.Lock()
+	defer this.Unlock()
+	if this.frozen {
+		return
+	}
+	if this.attributes == nil {
+		this.attributes = make(map[string]interface{})
+	}
+	this.attributes[name] = val
+}
+
+func (this *Span) SetError(err error) {
+	if this == nil || err == nil {
+		return
+	}
+	this.Lock()
+	if !this.frozen {
+		this.err = err.Error()
+	}
+	this.Unlock()
+}
+
+// accessors for exporters, which only see spans once the trace is finished
+
+func (this *Span) TraceId() TraceId {
+	return this.trace.id
+}
+
+func (this *Span) Id() SpanId {
+	return this.id
+}
+
+func (this *Span) ParentId() SpanId {
+	return this.parentId
+}
+
+func (this *Span) Name() string {
+	return this.name
+}
+
+func (this *Span) Kind() SpanKind {
+	return this.kind
+}
+
+func (this *Span) StartTime() time.Time {
+	return this.start
+}
+
+func (this *Span) EndTime() time.Time {
+	return this.end
+}
+
+func (this *Span) Attributes() map[string]interface{} {
+	return this.attributes
+}
+
+func (this *Span) Error() string {
+	return this.err
+}
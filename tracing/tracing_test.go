@@ -0,0 +1,192 @@
+//  Copyright (c) 2020 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package tracing
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type testExporter struct {
+	spans [][]*Span
+}
+
+func (this *testExporter) Export(spans []*Span) {
+	this.spans = append(this.spans, spans)
+}
+
+func (this *testExporter) Shutdown() {
+}
+
+func TestParseTraceparent(t *testing.T) {
+	traceId, spanId, flags, ok := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if !ok || traceId.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || spanId.String() != "00f067aa0ba902b7" || flags != 1 {
+		t.Errorf("Unexpected parse result %v %v %v %v", traceId, spanId, flags, ok)
+	}
+
+	// later versions can carry more fields
+	_, _, _, ok = ParseTraceparent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra")
+	if !ok {
+		t.Errorf("Expected future version to parse")
+	}
+
+	invalid := []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e47-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902bz-01",
+	}
+	for _, s := range invalid {
+		if _, _, _, ok := ParseTraceparent(s); ok {
+			t.Errorf("Expected %q to be rejected", s)
+		}
+	}
+}
+
+func TestTrace(t *testing.T) {
+	if NewTrace("request", "") != nil {
+		t.Errorf("Expected no trace without an exporter")
+	}
+
+	exporter := &testExporter{}
+	SetExporter(exporter)
+	defer SetExporter(nil)
+
+	// not sampled by the caller
+	if NewTrace("request", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00") != nil {
+		t.Errorf("Expected no trace for unsampled caller")
+	}
+
+	trace := NewTrace("request", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if trace == nil {
+		t.Fatalf("Expected trace")
+	}
+	if trace.Id().String() != "4bf92f3577b34da6a3ce929d0e0e4736" || trace.Root().ParentId().String() != "00f067aa0ba902b7" {
+		t.Errorf("Expected trace to continue caller's, got %v %v", trace.Id(), trace.Root().ParentId())
+	}
+
+	run := trace.StartSpan("run", nil)
+	op := run.StartChild("Fetch", SPAN_INTERNAL)
+	call := op.StartChild("Fetch", SPAN_CLIENT)
+	call.SetAttribute("keys", 3)
+	call.End()
+	run.End()
+	trace.Finish()
+
+	if len(exporter.spans) != 1 || len(exporter.spans[0]) != 4 {
+		t.Fatalf("Expected one trace of 4 spans, got %v", exporter.spans)
+	}
+	spans := exporter.spans[0]
+	if spans[1].ParentId() != spans[0].Id() || spans[2].ParentId() != spans[1].Id() ||
+		spans[3].ParentId() != spans[2].Id() {
+		t.Errorf("Unexpected span tree")
+	}
+	for _, span := range spans {
+		if span.EndTime().IsZero() || span.EndTime().Before(span.StartTime()) {
+			t.Errorf("Span %v not ended properly", span.Name())
+		}
+	}
+
+	// spans are frozen once the trace is finished
+	op.SetAttribute("late", true)
+	if op.Attributes() != nil {
+		t.Errorf("Expected finished span not to change")
+	}
+	if trace.StartSpan("late", nil) != nil {
+		t.Errorf("Expected no spans after finish")
+	}
+	trace.Finish()
+	if len(exporter.spans) != 1 {
+		t.Errorf("Expected trace to be exported once")
+	}
+
+	// nil traces and spans are no-ops
+	var nilTrace *Trace
+	nilSpan := nilTrace.StartSpan("run", nil)
+	nilSpan.StartChild("Fetch", SPAN_CLIENT).End()
+	nilSpan.SetAttribute("keys", 1)
+	nilTrace.Finish()
+}
+
+func TestMaxSpans(t *testing.T) {
+	exporter := &testExporter{}
+	SetExporter(exporter)
+	defer SetExporter(nil)
+
+	trace := NewTrace("request", "")
+	for i := 0; i < _MAX_SPANS+10; i++ {
+		trace.StartSpan("op", nil).End()
+	}
+	trace.Finish()
+	if len(exporter.spans[0]) != _MAX_SPANS {
+		t.Errorf("Expected %v spans, got %v", _MAX_SPANS, len(exporter.spans[0]))
+	}
+	if trace.Root().Attributes()["droppedSpans"] != 11 {
+		t.Errorf("Expected 11 dropped spans, got %v", trace.Root().Attributes()["droppedSpans"])
+	}
+}
+
+func TestOTLPExporter(t *testing.T) {
+	received := make(chan map[string]interface{}, 1)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+		var payload map[string]interface{}
+		json.Unmarshal(body, &payload)
+		received <- payload
+	}))
+	defer collector.Close()
+
+	SetExporter(NewOTLPExporter(collector.URL+"/v1/traces", "node1"))
+	trace := NewTrace("/query/service", "")
+	span := trace.StartSpan("run", nil)
+	span.SetAttribute("keys", 3)
+	span.SetError(errorString("failed"))
+	trace.Finish()
+
+	// shutting down flushes the queue
+	SetExporter(nil)
+
+	payload := <-received
+	resourceSpans := payload["resourceSpans"].([]interface{})[0].(map[string]interface{})
+	scopeSpans := resourceSpans["scopeSpans"].([]interface{})[0].(map[string]interface{})
+	spans := scopeSpans["spans"].([]interface{})
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 spans, got %v", spans)
+	}
+	root := spans[0].(map[string]interface{})
+	run := spans[1].(map[string]interface{})
+	if root["traceId"] != trace.Id().String() || root["kind"] != float64(SPAN_SERVER) || root["parentSpanId"] != nil {
+		t.Errorf("Unexpected root span %v", root)
+	}
+	if run["parentSpanId"] != root["spanId"] || run["name"] != "run" {
+		t.Errorf("Unexpected run span %v", run)
+	}
+	attribute := run["attributes"].([]interface{})[0].(map[string]interface{})
+	if attribute["key"] != "keys" || attribute["value"].(map[string]interface{})["intValue"] != "3" {
+		t.Errorf("Unexpected attribute %v", attribute)
+	}
+	if run["status"].(map[string]interface{})["message"] != "failed" {
+		t.Errorf("Unexpected status %v", run["status"])
+	}
+}
+
+type errorString string
+
+func (this errorString) Error() string {
+	return string(this)
+}
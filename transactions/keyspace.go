@@ -0,0 +1,279 @@
+//  Copyright (c) 2020 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package transactions
+
+import (
+	"sort"
+
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/value"
+)
+
+// keyspace stages the mutations of a transaction in front of a keyspace,
+// and merges them into what is read from it
+type keyspace struct {
+	datastore.Keyspace
+	txn  *Transaction
+	name string
+}
+
+// Keyspace returns the keyspace as seen by the transaction.
+// System keyspaces are not transactional.
+func (this *Transaction) Keyspace(ks datastore.Keyspace) datastore.Keyspace {
+	if ks == nil || ks.NamespaceId() == "#system" {
+		return ks
+	}
+	if _, ok := ks.(*keyspace); ok {
+		return ks
+	}
+	return &keyspace{
+		Keyspace: ks,
+		txn:      this,
+		name:     ks.QualifiedName(),
+	}
+}
+
+func (this *keyspace) Count(context datastore.QueryContext) (int64, errors.Error) {
+	count, err := this.Keyspace.Count(context)
+	if err != nil {
+		return count, err
+	}
+
+	this.txn.Lock()
+	defer this.txn.Unlock()
+	for _, d := range this.txn.docs[this.name] {
+		if d.existed && d.value == nil {
+			count--
+		} else if !d.existed && d.value != nil {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (this *keyspace) Fetch(keys []string, keysMap map[string]value.AnnotatedValue,
+	context datastore.QueryContext, subPaths []string) []errors.Error {
+
+	this.txn.Lock()
+	staged := this.txn.docs[this.name]
+	fetch := make([]string, 0, len(keys))
+	for _, k := range keys {
+		d, ok := staged[k]
+		if !ok {
+			fetch = append(fetch, k)
+			continue
+		}
+		if d.value == nil {
+
+			// deleted within the transaction => ignore it
+			continue
+		}
+		item := value.NewAnnotatedValue(d.value.CopyForUpdate())
+		item.SetAttachment("meta", map[string]interface{}{
+			"id": k,
+		})
+		item.SetId(k)
+		keysMap[k] = item
+	}
+	this.txn.Unlock()
+
+	if len(fetch) == 0 {
+		return nil
+	}
+	return this.Keyspace.Fetch(fetch, keysMap, context, subPaths)
+}
+
+func (this *keyspace) Insert(inserts []value.Pair) ([]value.Pair, errors.Error) {
+	var returnErr errors.Error
+
+	return this.stage(inserts, func(key string, exists bool) bool {
+		if exists {
+			returnErr = errors.NewTransactionError(nil, "Duplicate key "+key)
+		}
+		return !exists
+	}, false, &returnErr)
+}
+
+func (this *keyspace) Update(updates []value.Pair) ([]value.Pair, errors.Error) {
+	return this.stage(updates, func(key string, exists bool) bool {
+		return exists
+	}, true, nil)
+}
+
+func (this *keyspace) Upsert(upserts []value.Pair) ([]value.Pair, errors.Error) {
+	return this.stage(upserts, func(key string, exists bool) bool {
+		return true
+	}, false, nil)
+}
+
+func (this *keyspace) Delete(deletes []string, context datastore.QueryContext) ([]string, errors.Error) {
+	pairs := make([]value.Pair, len(deletes))
+	for i, k := range deletes {
+		pairs[i].Name = k
+	}
+	pairs, err := this.stage(pairs, func(key string, exists bool) bool {
+		return exists
+	}, false, nil)
+
+	var deleted []string
+	if len(pairs) > 0 {
+		deleted = make([]string, len(pairs))
+		for i, p := range pairs {
+			deleted[i] = p.Name
+		}
+	}
+	return deleted, err
+}
+
+var _NO_EXPIRATION = value.NewValue(map[string]interface{}{datastore.OPT_EXPIRATION: 0})
+
+// stage records the new values of the documents for which apply returns
+// true, given whether the document currently exists; a nil value deletes
+// the document.
+// Updates keep the write options of the document, unless they set new ones.
+func (this *keyspace) stage(pairs []value.Pair, apply func(string, bool) bool, keepOptions bool,
+	returnErr *errors.Error) ([]value.Pair, errors.Error) {
+
+	// documents the transaction has not written yet are read upfront,
+	// to know whether they exist
+	this.txn.Lock()
+	if err := this.txn.checkActive(); err != nil {
+		this.txn.Unlock()
+		return nil, err
+	}
+	staged := this.txn.docs[this.name]
+	keys := make([]string, 0, len(pairs))
+	for _, p := range pairs {
+		if _, ok := staged[p.Name]; !ok {
+			keys = append(keys, p.Name)
+		}
+	}
+	this.txn.Unlock()
+
+	// documents that cannot be read are taken as missing: the commit
+	// checks again whether they exist before applying the mutations
+	current := make(map[string]value.AnnotatedValue, len(keys))
+	if len(keys) > 0 {
+		this.Keyspace.Fetch(keys, current, datastore.NULL_QUERY_CONTEXT, nil)
+	}
+
+	this.txn.Lock()
+	defer this.txn.Unlock()
+	if err := this.txn.checkActive(); err != nil {
+		return nil, err
+	}
+	staged = this.txn.docs[this.name]
+	if staged == nil {
+		staged = make(stagedDocs, len(pairs))
+		this.txn.docs[this.name] = staged
+	}
+	if _, ok := this.txn.keyspaces[this.name]; !ok {
+		this.txn.keyspaces[this.name] = this.Keyspace
+	}
+	if !this.txn.written(this.name) {
+		this.txn.order = append(this.txn.order, this.name)
+	}
+
+	done := make([]value.Pair, 0, len(pairs))
+	for _, p := range pairs {
+		d, ok := staged[p.Name]
+		if !ok {
+			d = &stagedDoc{existed: current[p.Name] != nil}
+			if d.existed {
+				d.value = current[p.Name].GetValue()
+			}
+		}
+		if !apply(p.Name, d.value != nil) {
+			continue
+		}
+		if p.Value != nil {
+			d.value = p.Value.CopyForUpdate()
+		} else {
+			d.value = nil
+		}
+		if p.Options != nil || !keepOptions {
+			d.options = p.Options
+
+			// documents that existed are committed as updates, which
+			// would keep their expiration
+			if d.options == nil && d.existed {
+				d.options = _NO_EXPIRATION
+			}
+		}
+		staged[p.Name] = d
+		done = append(done, p)
+	}
+
+	if returnErr != nil {
+		return done, *returnErr
+	}
+	return done, nil
+}
+
+// the caller must hold the transaction lock
+func (this *Transaction) written(name string) bool {
+	for _, n := range this.order {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Index scans do not see the mutations staged by a transaction: they skip
+// the documents the transaction has written, and return the ones it has
+// not deleted once the index is done. The scans are followed by a fetch,
+// which reads the staged documents, and by filters, which evaluate them.
+type ScanKeys struct {
+	written map[string]bool
+	live    []string
+	next    int
+}
+
+// ScanKeys returns the keys an index scan of the keyspace must adjust,
+// or nil if the transaction has not written to the keyspace.
+// Bucket and scope are empty for keyspaces directly under a namespace.
+func (this *Transaction) ScanKeys(namespace, bucket, scope, keyspace string) *ScanKeys {
+	this.Lock()
+	defer this.Unlock()
+	staged := this.docs[datastore.QualifiedKeyspaceName(namespace, bucket, scope, keyspace)]
+	if len(staged) == 0 {
+		return nil
+	}
+
+	rv := &ScanKeys{
+		written: make(map[string]bool, len(staged)),
+	}
+	for k, d := range staged {
+		rv.written[k] = true
+		if d.value != nil {
+			rv.live = append(rv.live, k)
+		}
+	}
+	sort.Strings(rv.live)
+	return rv
+}
+
+// Skip returns true for the index entries of documents written by the
+// transaction.
+func (this *ScanKeys) Skip(key string) bool {
+	return this.written[key]
+}
+
+// Next returns the next document written by the transaction, once the
+// index has been scanned.
+func (this *ScanKeys) Next() (string, bool) {
+	if this.next >= len(this.live) {
+		return "", false
+	}
+	this.next++
+	return this.live[this.next-1], true
+}
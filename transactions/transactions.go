@@ -0,0 +1,542 @@
+//  Copyright (c) 2020 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+/*
+Package transactions implements multi statement transactions.
+
+Mutations executed within a transaction are staged in memory, in front
+of the keyspaces, and are only visible to the statements of the same
+transaction. On commit they are applied to each keyspace atomically,
+when the keyspace supports it, and reverted on all keyspaces should any
+fail. On rollback, or on timeout, they are just discarded.
+
+A transaction is identified by its id, which requests pass as the txid
+parameter, and can only be used by one request at a time.
+*/
+package transactions
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	atomic "github.com/couchbase/go-couchbase/platform"
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/logging"
+	"github.com/couchbase/query/resultcache"
+	"github.com/couchbase/query/util"
+	"github.com/couchbase/query/value"
+)
+
+const DEF_TIMEOUT = 15 * time.Second
+
+type State string
+
+const (
+	ACTIVE     State = "active"
+	COMMITTED  State = "committed"
+	ROLLEDBACK State = "rolled back"
+	EXPIRED    State = "expired"
+)
+
+type transactionsCache struct {
+	active         *util.GenCache
+	defaultTimeout atomic.AlignedInt64
+}
+
+var transactions = &transactionsCache{}
+
+func init() {
+	transactions.active = util.NewGenCache(-1)
+	atomic.StoreInt64(&transactions.defaultTimeout, int64(DEF_TIMEOUT))
+}
+
+// configure transactions
+
+func DefaultTimeout() time.Duration {
+	return time.Duration(atomic.LoadInt64(&transactions.defaultTimeout))
+}
+
+func SetDefaultTimeout(timeout time.Duration) {
+	if timeout <= 0 {
+		timeout = DEF_TIMEOUT
+	}
+	atomic.StoreInt64(&transactions.defaultTimeout, int64(timeout))
+}
+
+// A document as staged by a transaction
+type stagedDoc struct {
+	existed bool        // the document existed when the transaction first wrote it
+	value   value.Value // nil if deleted
+	options value.Value // write options, nil to keep those of the document
+}
+
+type stagedDocs map[string]*stagedDoc
+
+type savepoint struct {
+	name string
+	docs map[string]stagedDocs
+}
+
+type Transaction struct {
+	sync.Mutex
+	id         string
+	state      State
+	startTime  time.Time
+	lastUse    time.Time
+	timeout    time.Duration
+	statements int
+	inUse      bool
+	timer      *time.Timer
+	keyspaces  map[string]datastore.Keyspace
+	docs       map[string]stagedDocs // staged documents by keyspace
+	order      []string              // keyspaces in order of first write
+	savepoints []*savepoint
+}
+
+// transactions primitives
+
+// Start starts a new transaction, which will be rolled back if it is
+// not committed within timeout, or the default timeout if not positive.
+func Start(timeout time.Duration) (*Transaction, errors.Error) {
+	id, err := util.UUIDV3()
+	if err != nil {
+		return nil, errors.NewTransactionError(err, "cannot generate transaction id")
+	}
+	if timeout <= 0 {
+		timeout = DefaultTimeout()
+	}
+
+	now := time.Now()
+	txn := &Transaction{
+		id:        id,
+		state:     ACTIVE,
+		startTime: now,
+		lastUse:   now,
+		timeout:   timeout,
+		keyspaces: make(map[string]datastore.Keyspace),
+		docs:      make(map[string]stagedDocs),
+	}
+	transactions.active.FastAdd(txn, id)
+	txn.timer = time.AfterFunc(timeout, func() { expire(id) })
+	return txn, nil
+}
+
+// Acquire marks the transaction as in use by a request, which must
+// release it once done.
+func Acquire(id string) (*Transaction, errors.Error) {
+	var err errors.Error
+
+	entry := transactions.active.Get(id, func(ce interface{}) {
+		txn := ce.(*Transaction)
+		txn.Lock()
+		defer txn.Unlock()
+		switch {
+		case txn.state == EXPIRED:
+			err = errors.NewTransactionExpiredError(id)
+		case txn.state != ACTIVE:
+			err = errors.NewTransactionNotFoundError(id)
+		case txn.inUse:
+			err = errors.NewTransactionInUseError(id)
+		default:
+			txn.inUse = true
+			txn.lastUse = time.Now()
+			txn.statements++
+		}
+	})
+	if entry == nil {
+		return nil, errors.NewTransactionNotFoundError(id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return entry.(*Transaction), nil
+}
+
+// Release makes the transaction available to other requests, and
+// disposes of it if it has ended.
+func (this *Transaction) Release() {
+	this.Lock()
+	this.inUse = false
+	ended := this.state != ACTIVE
+	if this.state == EXPIRED {
+		this.discard()
+	}
+	this.Unlock()
+	if ended {
+		this.dispose()
+	}
+}
+
+// a transaction not in use is rolled back as soon as it expires,
+// otherwise it is rolled back when the request using it releases it
+func expire(id string) {
+	var txn *Transaction
+	var dispose bool
+
+	transactions.active.Get(id, func(ce interface{}) {
+		txn = ce.(*Transaction)
+		txn.Lock()
+		defer txn.Unlock()
+		if txn.state != ACTIVE {
+			return
+		}
+		txn.state = EXPIRED
+		if !txn.inUse {
+			txn.discard()
+			dispose = true
+		}
+	})
+	if dispose {
+		txn.dispose()
+	}
+}
+
+func (this *Transaction) dispose() {
+	if this.timer != nil {
+		this.timer.Stop()
+	}
+	transactions.active.Delete(this.id, nil)
+}
+
+// the caller must hold the transaction lock
+func (this *Transaction) discard() {
+	this.docs = make(map[string]stagedDocs)
+	this.order = nil
+	this.savepoints = nil
+}
+
+func (this *Transaction) Id() string {
+	return this.id
+}
+
+func (this *Transaction) State() State {
+	this.Lock()
+	defer this.Unlock()
+	return this.state
+}
+
+func (this *Transaction) StartTime() time.Time {
+	return this.startTime
+}
+
+func (this *Transaction) Timeout() time.Duration {
+	return this.timeout
+}
+
+func (this *Transaction) LastUse() time.Time {
+	this.Lock()
+	defer this.Unlock()
+	return this.lastUse
+}
+
+func (this *Transaction) Statements() int {
+	this.Lock()
+	defer this.Unlock()
+	return this.statements
+}
+
+func (this *Transaction) InUse() bool {
+	this.Lock()
+	defer this.Unlock()
+	return this.inUse
+}
+
+// Mutations returns the number of staged mutations by keyspace.
+func (this *Transaction) Mutations() map[string]int {
+	this.Lock()
+	defer this.Unlock()
+	rv := make(map[string]int, len(this.order))
+	for _, name := range this.order {
+		rv[name] = len(this.docs[name].mutations())
+	}
+	return rv
+}
+
+func (this *Transaction) Savepoints() []string {
+	this.Lock()
+	defer this.Unlock()
+	rv := make([]string, len(this.savepoints))
+	for i, s := range this.savepoints {
+		rv[i] = s.name
+	}
+	return rv
+}
+
+// checkActive returns an error if the transaction has ended.
+// The caller must hold the transaction lock.
+func (this *Transaction) checkActive() errors.Error {
+	switch this.state {
+	case ACTIVE:
+		return nil
+	case EXPIRED:
+		return errors.NewTransactionExpiredError(this.id)
+	default:
+		return errors.NewTransactionNotFoundError(this.id)
+	}
+}
+
+// SetSavepoint records the current state of the transaction under name,
+// replacing any savepoint with the same name.
+func (this *Transaction) SetSavepoint(name string) errors.Error {
+	this.Lock()
+	defer this.Unlock()
+	if err := this.checkActive(); err != nil {
+		return err
+	}
+
+	docs := make(map[string]stagedDocs, len(this.docs))
+	for ks, staged := range this.docs {
+		docs[ks] = staged.copy()
+	}
+	for i, s := range this.savepoints {
+		if s.name == name {
+			this.savepoints = append(this.savepoints[:i], this.savepoints[i+1:]...)
+			break
+		}
+	}
+	this.savepoints = append(this.savepoints, &savepoint{name: name, docs: docs})
+	return nil
+}
+
+// RollbackTo restores the state of the transaction to the savepoint,
+// dropping the savepoints set after it.
+func (this *Transaction) RollbackTo(name string) errors.Error {
+	this.Lock()
+	defer this.Unlock()
+	if err := this.checkActive(); err != nil {
+		return err
+	}
+
+	for i := len(this.savepoints) - 1; i >= 0; i-- {
+		s := this.savepoints[i]
+		if s.name == name {
+			this.docs = make(map[string]stagedDocs, len(s.docs))
+			for ks, staged := range s.docs {
+				this.docs[ks] = staged.copy()
+			}
+			this.savepoints = this.savepoints[:i+1]
+			return nil
+		}
+	}
+	return errors.NewSavepointNotFoundError(name)
+}
+
+// Rollback discards all the staged mutations, and ends the transaction.
+func (this *Transaction) Rollback() errors.Error {
+	this.Lock()
+	defer this.Unlock()
+	if err := this.checkActive(); err != nil {
+		return err
+	}
+	this.discard()
+	this.state = ROLLEDBACK
+	return nil
+}
+
+type applied struct {
+	keyspace datastore.Keyspace
+	undo     datastore.Mutations
+}
+
+// Commit applies the staged mutations, and ends the transaction.
+// Keyspaces are committed in the order the transaction first wrote
+// them: should one fail, the mutations already applied to the others
+// are reverted, and the transaction is rolled back.
+func (this *Transaction) Commit() errors.Error {
+	this.Lock()
+	defer this.Unlock()
+	if err := this.checkActive(); err != nil {
+		return err
+	}
+
+	done := make([]applied, 0, len(this.order))
+	for _, name := range this.order {
+		keyspace := this.keyspaces[name]
+		mutations := this.docs[name].mutations()
+		if len(mutations) == 0 {
+			continue
+		}
+
+		undo, err := applyMutations(keyspace, mutations)
+		resultcache.Invalidate(keyspace)
+		if err != nil {
+			for i := len(done) - 1; i >= 0; i-- {
+				_, er := applyMutations(done[i].keyspace, done[i].undo)
+				if er != nil {
+					logging.Errorf("Transaction %v: cannot revert the commit of keyspace %v: %v",
+						this.id, done[i].keyspace.Name(), er)
+				}
+			}
+			this.discard()
+			this.state = ROLLEDBACK
+			return errors.NewTransactionCommitError(err, this.id)
+		}
+		done = append(done, applied{keyspace: keyspace, undo: undo})
+	}
+
+	this.discard()
+	this.state = COMMITTED
+	return nil
+}
+
+func applyMutations(keyspace datastore.Keyspace, mutations datastore.Mutations) (datastore.Mutations, errors.Error) {
+	if atomicKeyspace, ok := keyspace.(datastore.AtomicKeyspace); ok {
+		return atomicKeyspace.ApplyMutations(mutations)
+	}
+	return applyEach(keyspace, mutations)
+}
+
+// applyEach applies the mutations one by one, for keyspaces that do not
+// support atomic application, and reverts them on failure.
+// Other writers may observe an intermediate state.
+func applyEach(keyspace datastore.Keyspace, mutations datastore.Mutations) (datastore.Mutations, errors.Error) {
+	keys := make([]string, 0, len(mutations))
+	for _, m := range mutations {
+		if m.Op != datastore.MUTATE_INSERT {
+			keys = append(keys, m.Key)
+		}
+	}
+	before := make(map[string]value.AnnotatedValue, len(keys))
+	if len(keys) > 0 {
+		errs := keyspace.Fetch(keys, before, datastore.NULL_QUERY_CONTEXT, nil)
+		if len(errs) > 0 {
+			return nil, errs[0]
+		}
+	}
+
+	undo := make(datastore.Mutations, 0, len(mutations))
+	for _, m := range mutations {
+		var err errors.Error
+		var ok bool
+
+		switch m.Op {
+		case datastore.MUTATE_INSERT:
+			ok, err = applyPairs(keyspace.Insert, m.Key, m.Value, m.Options)
+			if ok {
+				undo = append(undo, datastore.Mutation{Op: datastore.MUTATE_DELETE, Key: m.Key})
+			}
+		case datastore.MUTATE_UPDATE:
+			if before[m.Key] == nil {
+				break
+			}
+			ok, err = applyPairs(keyspace.Update, m.Key, m.Value, m.Options)
+			if ok {
+				undo = append(undo, datastore.Mutation{Op: datastore.MUTATE_UPDATE, Key: m.Key,
+					Value: before[m.Key].GetValue(), Options: datastore.ExpirationOptions(before[m.Key])})
+			}
+		case datastore.MUTATE_DELETE:
+			if before[m.Key] == nil {
+				break
+			}
+			var deleted []string
+			deleted, err = keyspace.Delete([]string{m.Key}, datastore.NULL_QUERY_CONTEXT)
+			ok = len(deleted) == 1
+			if ok {
+				undo = append(undo, datastore.Mutation{Op: datastore.MUTATE_INSERT, Key: m.Key,
+					Value: before[m.Key].GetValue(), Options: datastore.ExpirationOptions(before[m.Key])})
+			}
+		}
+
+		if !ok {
+			if err == nil {
+				err = errors.NewTransactionConflictError(keyspace.Name(), m.Key, m.Op.String())
+			}
+			if len(undo) > 0 {
+				if _, er := applyEach(keyspace, reverse(undo)); er != nil {
+					logging.Errorf("Cannot revert the mutations of keyspace %v: %v", keyspace.Name(), er)
+				}
+			}
+			return nil, err
+		}
+	}
+	return reverse(undo), nil
+}
+
+func applyPairs(op func([]value.Pair) ([]value.Pair, errors.Error), key string, val, options value.Value) (
+	bool, errors.Error) {
+	done, err := op([]value.Pair{value.Pair{Name: key, Value: val, Options: options}})
+	return len(done) == 1, err
+}
+
+func reverse(mutations datastore.Mutations) datastore.Mutations {
+	rv := make(datastore.Mutations, len(mutations))
+	for i, m := range mutations {
+		rv[len(mutations)-1-i] = m
+	}
+	return rv
+}
+
+func (this stagedDocs) copy() stagedDocs {
+	rv := make(stagedDocs, len(this))
+	for k, d := range this {
+		rv[k] = &stagedDoc{existed: d.existed, value: d.value, options: d.options}
+	}
+	return rv
+}
+
+// mutations returns the mutations to apply to the keyspace, sorted by key
+func (this stagedDocs) mutations() datastore.Mutations {
+	rv := make(datastore.Mutations, 0, len(this))
+	for k, d := range this {
+		switch {
+		case d.existed && d.value == nil:
+			rv = append(rv, datastore.Mutation{Op: datastore.MUTATE_DELETE, Key: k})
+		case d.existed:
+			rv = append(rv, datastore.Mutation{Op: datastore.MUTATE_UPDATE, Key: k, Value: d.value,
+				Options: d.options})
+		case d.value != nil:
+			rv = append(rv, datastore.Mutation{Op: datastore.MUTATE_INSERT, Key: k, Value: d.value,
+				Options: d.options})
+		}
+	}
+	sort.Slice(rv, func(i, j int) bool { return rv[i].Key < rv[j].Key })
+	return rv
+}
+
+// utilities for system keyspaces
+
+func CountTransactions() int {
+	return transactions.active.Size()
+}
+
+func NameTransactions() []string {
+	return transactions.active.Names()
+}
+
+func TransactionsForeach(nonBlocking func(string, *Transaction) bool,
+	blocking func() bool) {
+	dummyF := func(id string, r interface{}) bool {
+		return nonBlocking(id, r.(*Transaction))
+	}
+	transactions.active.ForEach(dummyF, blocking)
+}
+
+func TransactionDo(id string, f func(*Transaction)) {
+	var process func(interface{}) = nil
+
+	if f != nil {
+		process = func(entry interface{}) {
+			f(entry.(*Transaction))
+		}
+	}
+	_ = transactions.active.Get(id, process)
+}
+
+// RollbackTransaction rolls back a transaction that is not in use.
+func RollbackTransaction(id string) errors.Error {
+	txn, err := Acquire(id)
+	if err != nil {
+		return err
+	}
+	err = txn.Rollback()
+	txn.Release()
+	return err
+}
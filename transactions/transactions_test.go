@@ -0,0 +1,178 @@
+//  Copyright (c) 2020 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package transactions
+
+import (
+	"testing"
+	"time"
+
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/datastore/mock"
+	"github.com/couchbase/query/value"
+)
+
+func mockKeyspace(t *testing.T) datastore.Keyspace {
+	s, err := mock.NewDatastore("mock:items=10")
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	p, err := s.NamespaceById("p0")
+	if err != nil {
+		t.Fatalf("expected namespace p0")
+	}
+	b, err := p.KeyspaceById("b0")
+	if err != nil {
+		t.Fatalf("expected keyspace b0")
+	}
+	return b
+}
+
+func fetch(ks datastore.Keyspace, keys ...string) map[string]value.AnnotatedValue {
+	rv := make(map[string]value.AnnotatedValue, len(keys))
+	ks.Fetch(keys, rv, datastore.NULL_QUERY_CONTEXT, nil)
+	return rv
+}
+
+func TestTransaction(t *testing.T) {
+	b := mockKeyspace(t)
+
+	txn, err := Start(0)
+	if err != nil {
+		t.Fatalf("unexpected error starting transaction: %v", err)
+	}
+	if txn.Timeout() != DefaultTimeout() {
+		t.Fatalf("expected default timeout, got %v", txn.Timeout())
+	}
+
+	_, err = Acquire(txn.Id())
+	if err != nil {
+		t.Fatalf("unexpected error acquiring transaction: %v", err)
+	}
+	_, err = Acquire(txn.Id())
+	if err == nil {
+		t.Fatalf("expected transaction in use")
+	}
+
+	ks := txn.Keyspace(b)
+	_, err = ks.Insert([]value.Pair{value.Pair{Name: "new", Value: value.NewValue(map[string]interface{}{"a": 1})}})
+	if err != nil {
+		t.Fatalf("unexpected error in insert: %v", err)
+	}
+	_, err = ks.Delete([]string{"1"}, datastore.NULL_QUERY_CONTEXT)
+	if err != nil {
+		t.Fatalf("unexpected error in delete: %v", err)
+	}
+
+	// the transaction reads its own writes, others do not
+	docs := fetch(ks, "new", "1")
+	if docs["new"] == nil || docs["1"] != nil {
+		t.Fatalf("unexpected transaction view: %v", docs)
+	}
+	docs = fetch(b, "new", "1")
+	if docs["new"] != nil || docs["1"] == nil {
+		t.Fatalf("unexpected keyspace view: %v", docs)
+	}
+	c, _ := ks.Count(datastore.NULL_QUERY_CONTEXT)
+	if c != 10 {
+		t.Fatalf("expected 10 documents, got %v", c)
+	}
+
+	scan := txn.ScanKeys(b.NamespaceId(), "", "", b.Id())
+	if scan == nil || !scan.Skip("1") || scan.Skip("2") {
+		t.Fatalf("unexpected scan keys")
+	}
+	if k, ok := scan.Next(); !ok || k != "new" {
+		t.Fatalf("expected staged key new, got %v", k)
+	}
+	if _, ok := scan.Next(); ok {
+		t.Fatalf("expected no more staged keys")
+	}
+
+	// savepoints
+	err = txn.SetSavepoint("s1")
+	if err != nil {
+		t.Fatalf("unexpected error setting savepoint: %v", err)
+	}
+	_, err = ks.Update([]value.Pair{value.Pair{Name: "new", Value: value.NewValue(map[string]interface{}{"a": 2})}})
+	if err != nil {
+		t.Fatalf("unexpected error in update: %v", err)
+	}
+	err = txn.RollbackTo("s1")
+	if err != nil {
+		t.Fatalf("unexpected error rolling back to savepoint: %v", err)
+	}
+	a, _ := fetch(ks, "new")["new"].Field("a")
+	if !a.Equals(value.NewValue(1)).Truth() {
+		t.Fatalf("expected savepoint value, got %v", a)
+	}
+	if txn.RollbackTo("s2") == nil {
+		t.Fatalf("expected savepoint not found")
+	}
+
+	err = txn.Commit()
+	if err != nil {
+		t.Fatalf("unexpected error in commit: %v", err)
+	}
+	docs = fetch(b, "new", "1")
+	if docs["new"] == nil || docs["1"] != nil {
+		t.Fatalf("unexpected keyspace view after commit: %v", docs)
+	}
+
+	txn.Release()
+	if _, err = Acquire(txn.Id()); err == nil {
+		t.Fatalf("expected committed transaction to be gone")
+	}
+}
+
+func TestTransactionConflict(t *testing.T) {
+	b := mockKeyspace(t)
+
+	txn, _ := Start(0)
+	ks := txn.Keyspace(b)
+	_, err := ks.Update([]value.Pair{value.Pair{Name: "2", Value: value.NewValue(2)}})
+	if err != nil {
+		t.Fatalf("unexpected error in update: %v", err)
+	}
+	_, err = ks.Insert([]value.Pair{value.Pair{Name: "new", Value: value.NewValue(1)}})
+	if err != nil {
+		t.Fatalf("unexpected error in insert: %v", err)
+	}
+
+	// a concurrent writer gets there first
+	_, err = b.(datastore.AtomicKeyspace).ApplyMutations(datastore.Mutations{
+		datastore.Mutation{Op: datastore.MUTATE_INSERT, Key: "new", Value: value.NewValue(0)},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error in insert: %v", err)
+	}
+
+	if txn.Commit() == nil {
+		t.Fatalf("expected commit to fail")
+	}
+	if txn.State() != ROLLEDBACK {
+		t.Fatalf("expected rolled back transaction, got %v", txn.State())
+	}
+	v := fetch(b, "2")["2"]
+	if v == nil || v.Equals(value.NewValue(2)).Truth() {
+		t.Fatalf("expected update not to be applied, got %v", v)
+	}
+	txn.Release()
+}
+
+func TestTransactionTimeout(t *testing.T) {
+	txn, _ := Start(10 * time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	if txn.State() != EXPIRED {
+		t.Fatalf("expected expired transaction, got %v", txn.State())
+	}
+	if _, err := Acquire(txn.Id()); err == nil {
+		t.Fatalf("expected expired transaction to be gone")
+	}
+}
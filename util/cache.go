@@ -83,6 +83,9 @@ type GenCache struct {
 	// max size, for LRU lists
 	limit   int
 	curSize int32
+
+	// called outside of the locks on the entries ejected to honour the limit
+	ejected func(id string, entry interface{})
 }
 
 func NewGenCache(l int) *GenCache {
@@ -140,6 +143,7 @@ func (this *GenCache) Add(entry interface{}, id string, process func(interface{}
 		this.locks[cacheNum].Unlock()
 
 	} else {
+		var victim *genElem
 		ditchOther := false
 
 		// In order not to have to acquire a different lock
@@ -150,6 +154,7 @@ func (this *GenCache) Add(entry interface{}, id string, process func(interface{}
 		if this.limit > 0 && int(this.curSize) >= this.limit {
 			if elem != nil {
 				this.remove(elem, cacheNum)
+				victim = elem
 			} else {
 
 				// if we had nothing locally, we'll drop
@@ -189,6 +194,7 @@ func (this *GenCache) Add(entry interface{}, id string, process func(interface{}
 				elem = this.lists[newCacheNum][_LRU].prev
 				if elem != nil {
 					this.remove(elem, newCacheNum)
+					victim = elem
 					ditchOther = false
 				}
 				this.locks[newCacheNum].Unlock()
@@ -201,6 +207,9 @@ func (this *GenCache) Add(entry interface{}, id string, process func(interface{}
 				atomic.AddInt32(&this.curSize, 1)
 			}
 		}
+		if victim != nil && this.ejected != nil {
+			this.ejected(victim.ID, victim.contents)
+		}
 	}
 }
 
@@ -275,6 +284,12 @@ func (this *GenCache) Limit() int {
 	return this.limit
 }
 
+// Set the function called on the entries ejected when the cache is over its limit
+// Not to be changed once the cache is in use
+func (this *GenCache) SetEjected(ejected func(id string, entry interface{})) {
+	this.ejected = ejected
+}
+
 // Set the list limit
 func (this *GenCache) SetLimit(limit int) {
 
@@ -295,6 +310,9 @@ func (this *GenCache) SetLimit(limit int) {
 			atomic.AddInt32(&this.curSize, -1)
 		}
 		this.locks[c].Unlock()
+		if elem != nil && this.ejected != nil {
+			this.ejected(elem.ID, elem.contents)
+		}
 		c = (c + 1) % _CACHES
 	}
 }
@@ -113,3 +113,33 @@ func TestCache(t *testing.T) {
 
 	c.SetLimit(sz)
 }
+
+func TestCacheEjected(t *testing.T) {
+	c := NewGenCache(10)
+	ejected := make(map[string]bool)
+	c.SetEjected(func(id string, entry interface{}) {
+		if strconv.Itoa(entry.(testCache).value) != id {
+			t.Errorf("Eject test: unexpected entry %v for %v", entry, id)
+		}
+		ejected[id] = true
+	})
+
+	for i := 1; i <= 20; i++ {
+		c.Add(testCache{value: i}, strconv.Itoa(i), nil)
+	}
+	if c.Size() != 10 || len(ejected) != 10 {
+		t.Errorf("Eject test: expected 10 elements and 10 ejected, got %v and %v", c.Size(), len(ejected))
+	}
+	for id, _ := range ejected {
+		if c.Get(id, nil) != nil {
+			t.Errorf("Eject test: ejected element %v still cached", id)
+		}
+	}
+
+	// deletes are not ejections
+	c.Delete("20", nil)
+	c.SetLimit(5)
+	if c.Size() != 5 || len(ejected) != 14 || ejected["20"] {
+		t.Errorf("Eject test: expected 5 elements and 14 ejected, got %v and %v", c.Size(), len(ejected))
+	}
+}
@@ -33,6 +33,7 @@ func newAnnotatedValue() *annotatedValue {
 	rv.bit = 0
 	rv.self = false
 	rv.isOrigCopy = false
+	rv.size = 0
 	return rv
 }
 
@@ -105,6 +106,7 @@ type annotatedValue struct {
 	original      Value
 	annotatedOrig AnnotatedValue
 	isOrigCopy    bool
+	size          uint64
 }
 
 func (this *annotatedValue) String() string {
@@ -189,12 +191,14 @@ func (this *annotatedValue) SetCover(key string, val Value) {
 	}
 
 	this.covers.SetField(key, val)
+	this.size = 0
 }
 
 func (this *annotatedValue) InheritCovers(val Value) {
 	if this.covers != nil || val == nil {
 		return
 	}
+	this.size = 0
 
 	switch val := val.(type) {
 	case AnnotatedValue:
@@ -219,6 +223,7 @@ func (this *annotatedValue) InheritCovers(val Value) {
 func (this *annotatedValue) SetAnnotations(av AnnotatedValue) {
 	this.attachments = av.Attachments()
 	this.covers = av.Covers()
+	this.size = 0
 }
 
 func (this *annotatedValue) Bit() uint8 {
@@ -248,6 +253,7 @@ func (this *annotatedValue) SetId(id interface{}) {
 func (this *annotatedValue) SetProjection(proj Value) {
 	this.original = this.Value
 	this.Value = proj
+	this.size = 0
 }
 
 // Originals are not to be recycled
@@ -281,6 +287,39 @@ func (this *annotatedValue) Original() AnnotatedValue {
 	return av
 }
 
+func (this *annotatedValue) SetField(field string, val interface{}) error {
+	this.size = 0
+	return this.Value.SetField(field, val)
+}
+
+func (this *annotatedValue) UnsetField(field string) error {
+	this.size = 0
+	return this.Value.UnsetField(field)
+}
+
+func (this *annotatedValue) SetIndex(index int, val interface{}) error {
+	this.size = 0
+	return this.Value.SetIndex(index, val)
+}
+
+// A projection also holds the original value it was projected from.
+// The size is kept until the value is changed through its setters, so
+// that passing a value along the operators doesn't walk it every time
+func (this *annotatedValue) Size() uint64 {
+	if this.size != 0 {
+		return this.size
+	}
+	size := this.Value.Size()
+	if this.original != nil {
+		size += this.original.Size()
+	}
+	if this.covers != nil {
+		size += this.covers.Size()
+	}
+	this.size = size
+	return size
+}
+
 func (this *annotatedValue) Track() {
 	atomic.AddInt32(&this.refCnt, 1)
 }
@@ -334,6 +334,14 @@ func (this sliceValue) Successor() Value {
 	return sliceValue(append(this, nil))
 }
 
+func (this sliceValue) Size() uint64 {
+	size := uint64(0)
+	for _, e := range this {
+		size += _INTERFACE_SIZE + anySize(e)
+	}
+	return size
+}
+
 func (this sliceValue) Track() {
 }
 
@@ -492,6 +500,10 @@ func (this *listValue) Successor() Value {
 	return this.slice.Successor()
 }
 
+func (this *listValue) Size() uint64 {
+	return this.slice.Size()
+}
+
 func (this *listValue) Track() {
 }
 
@@ -156,6 +156,10 @@ func (this binaryValue) Successor() Value {
 	return binaryValue(append(this, byte(0)))
 }
 
+func (this binaryValue) Size() uint64 {
+	return uint64(len(this))
+}
+
 func (this binaryValue) Track() {
 }
 
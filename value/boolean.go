@@ -231,6 +231,10 @@ func (this boolValue) Successor() Value {
 	}
 }
 
+func (this boolValue) Size() uint64 {
+	return 1
+}
+
 func (this boolValue) Track() {
 }
 
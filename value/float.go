@@ -304,6 +304,10 @@ func (this floatValue) Successor() Value {
 	return floatValue(math.Nextafter(t, math.MaxFloat64))
 }
 
+func (this floatValue) Size() uint64 {
+	return 8
+}
+
 func (this floatValue) Track() {
 }
 
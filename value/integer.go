@@ -236,6 +236,10 @@ func (this intValue) Successor() Value {
 	}
 }
 
+func (this intValue) Size() uint64 {
+	return 8
+}
+
 func (this intValue) Track() {
 }
 
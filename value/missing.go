@@ -209,6 +209,10 @@ func (this missingValue) Successor() Value {
 	return NULL_VALUE
 }
 
+func (this missingValue) Size() uint64 {
+	return 0
+}
+
 func (this missingValue) Track() {
 }
 
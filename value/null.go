@@ -202,6 +202,10 @@ func (this *nullValue) Successor() Value {
 	return FALSE_VALUE
 }
 
+func (this *nullValue) Size() uint64 {
+	return 0
+}
+
 func (this *nullValue) Track() {
 }
 
@@ -423,6 +423,14 @@ func (this objectValue) Successor() Value {
 	return objectValue(s)
 }
 
+func (this objectValue) Size() uint64 {
+	size := uint64(0)
+	for n, v := range this {
+		size += uint64(len(n)) + _INTERFACE_SIZE + anySize(v)
+	}
+	return size
+}
+
 func (this objectValue) Track() {
 }
 
@@ -424,6 +424,15 @@ func (this *parsedValue) Successor() Value {
 	return this.unwrap().Successor()
 }
 
+// Until it is parsed, the value only holds its raw bytes
+func (this *parsedValue) Size() uint64 {
+	raw := this.raw
+	if raw != nil {
+		return uint64(len(raw))
+	}
+	return this.parsed.Size()
+}
+
 func (this *parsedValue) Track() {
 	atomic.AddInt32(&this.refCnt, 1)
 }
@@ -499,3 +499,11 @@ func (this *Set) Intersect(other *Set) {
 func (this *Set) ObjectCap() int {
 	return this.objectCap
 }
+
+func (this *Set) Collect() bool {
+	return this.collect
+}
+
+func (this *Set) Numeric() bool {
+	return this.numeric
+}
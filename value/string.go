@@ -245,6 +245,10 @@ func (this stringValue) Successor() Value {
 	return stringValue(string(this) + " ")
 }
 
+func (this stringValue) Size() uint64 {
+	return uint64(len(this))
+}
+
 func (this stringValue) Track() {
 }
 
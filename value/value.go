@@ -339,6 +339,12 @@ type Value interface {
 	*/
 	Track()
 
+	/*
+	   Approximate size in bytes of the data held by this value,
+	   used to account for the memory used by requests.
+	*/
+	Size() uint64
+
 	/*
 	   MB-20850. List all the atomic tokens of a value.
 	*/
@@ -477,3 +483,34 @@ func marshalString(v Value) string {
 	}
 	return string(bytes)
 }
+
+/*
+Estimated overhead of each element of an array or field of an
+object, on top of the data it holds.
+*/
+const _INTERFACE_SIZE = 16
+
+/*
+Size of the elements of arrays and fields of objects, which may be
+Values or any of the types a Value wraps.
+*/
+func anySize(val interface{}) uint64 {
+	switch val := val.(type) {
+	case Value:
+		return val.Size()
+	case string:
+		return uint64(len(val))
+	case []byte:
+		return uint64(len(val))
+	case []interface{}:
+		return sliceValue(val).Size()
+	case map[string]interface{}:
+		return objectValue(val).Size()
+	case nil:
+		return 0
+	case bool:
+		return 1
+	default:
+		return 8
+	}
+}
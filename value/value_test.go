@@ -616,3 +616,41 @@ func TestActualForIndex(t *testing.T) {
 		t.Errorf("Expected int64, got %v of type %T", i, i)
 	}
 }
+
+func TestSize(t *testing.T) {
+	raw := []byte(`{"name":"marty","age":10}`)
+	val := NewValue(raw)
+	if val.Size() != uint64(len(raw)) {
+		t.Errorf("Expected the raw size %v, got %v", len(raw), val.Size())
+	}
+
+	// name and age, with their overheads
+	expected := uint64(len("name") + len("marty") + len("age") + 8 + 2*_INTERFACE_SIZE)
+	val.Type()
+	_ = val.Actual()
+	if val.Size() != expected {
+		t.Errorf("Expected parsed size %v, got %v", expected, val.Size())
+	}
+
+	av := NewAnnotatedValue(map[string]interface{}{"name": "marty", "age": 10})
+	if av.Size() != expected {
+		t.Errorf("Expected annotated size %v, got %v", expected, av.Size())
+	}
+	av.SetProjection(NewValue(map[string]interface{}{"name": "marty"}))
+	projected := expected + uint64(len("name")+len("marty")+_INTERFACE_SIZE)
+	if av.Size() != projected {
+		t.Errorf("Expected projected size %v, got %v", projected, av.Size())
+	}
+
+	// the size is kept, until the value is changed
+	av.SetField("age", 10)
+	projected += uint64(len("age") + 8 + _INTERFACE_SIZE)
+	if av.Size() != projected {
+		t.Errorf("Expected updated size %v, got %v", projected, av.Size())
+	}
+
+	arr := NewValue([]interface{}{"a", 1, nil, true})
+	if arr.Size() != 4*_INTERFACE_SIZE+1+8+0+1 {
+		t.Errorf("Unexpected array size %v", arr.Size())
+	}
+}